@@ -1,10 +1,15 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -34,12 +39,14 @@ type MCPHTTPHandler interface {
 	UnregisterScript(string)
 	ServeSteamableHTTP(http.ResponseWriter, *http.Request)
 	GetSSEServer() *server.SSEServer
+	GetSessionManager() *executor2.SessionManager
 }
 
 type Handler struct {
 	db             *storage.BoltDB
 	browserManager *browser.Manager
-	executor       *executor2.Executor // Executor 实例
+	executor       *executor2.Executor       // Executor 实例
+	sessions       *executor2.SessionManager // 执行器会话（与 MCP 服务器共享）
 	config         *config.Config
 	llmManager     *llm.Manager
 	mcpServer      MCPHTTPHandler // MCP 服务器（使用 interface{} 避免循环依赖）
@@ -58,6 +65,7 @@ func NewHandler(
 		db:             db,
 		browserManager: browserMgr,
 		executor:       executor2.NewExecutor(browserMgr), // 初始化 Executor
+		sessions:       executor2.NewSessionManager(browserMgr),
 		config:         cfg,
 		llmManager:     llmMgr,
 		mcpServer:      nil, // 将在主程序中设置
@@ -290,6 +298,110 @@ func (h *Handler) GetCookies(c *gin.Context) {
 	c.JSON(http.StatusOK, cookieStore)
 }
 
+// ListStorageStates 列出保存的存储状态
+func (h *Handler) ListStorageStates(c *gin.Context) {
+	states, err := h.db.ListStorageStates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error.getStorageStatesFailed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"storage_states": states})
+}
+
+// GetStorageState 获取保存的存储状态
+func (h *Handler) GetStorageState(c *gin.Context) {
+	state, err := h.db.GetStorageState(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "error.storageStateNotFound"})
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// CaptureStorageState 从运行中的实例捕获存储状态并保存
+func (h *Handler) CaptureStorageState(c *gin.Context) {
+	var req struct {
+		ID               string   `json:"id"` // 可选，覆盖已有的存储状态
+		Name             string   `json:"name" binding:"required"`
+		Description      string   `json:"description"`
+		InstanceID       string   `json:"instance_id"`        // 空字符串表示当前实例
+		Origins          []string `json:"origins"`            // 除当前页面外需要捕获的源
+		IncludeIndexedDB bool     `json:"include_indexed_db"` // 是否捕获 IndexedDB
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams"})
+		return
+	}
+
+	if !h.browserManager.IsInstanceRunning(req.InstanceID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.browserNotRunning"})
+		return
+	}
+
+	state, err := h.browserManager.CaptureInstanceStorageState(c.Request.Context(), req.InstanceID, browser.StorageCaptureOptions{
+		Origins:   req.Origins,
+		IndexedDB: req.IncludeIndexedDB,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error.captureStorageStateFailed", "detail": err.Error()})
+		return
+	}
+	state.ID = req.ID
+	state.Name = req.Name
+	state.Description = req.Description
+
+	if err := h.browserManager.SaveStorageState(state); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error.saveStorageStateFailed"})
+		return
+	}
+
+	logger.Info(c.Request.Context(), "Captured storage state %s: %d cookies, %d origins", state.Name, len(state.Cookies), len(state.Origins))
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "success.storageStateSaved",
+		"storage_state": state,
+	})
+}
+
+// ImportStorageState 导入存储状态（与 Playwright storageState 相同的 cookies + origins 结构）
+func (h *Handler) ImportStorageState(c *gin.Context) {
+	var state models.StorageState
+	if err := c.ShouldBindJSON(&state); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams"})
+		return
+	}
+	if state.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams", "detail": "name is required"})
+		return
+	}
+	if err := browser.ValidateStorageState(&state); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams", "detail": err.Error()})
+		return
+	}
+
+	if err := h.browserManager.SaveStorageState(&state); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error.saveStorageStateFailed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "success.storageStateSaved",
+		"storage_state": &state,
+	})
+}
+
+// DeleteStorageState 删除保存的存储状态
+func (h *Handler) DeleteStorageState(c *gin.Context) {
+	if err := h.db.DeleteStorageState(c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error.deleteStorageStateFailed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success.storageStateDeleted"})
+}
+
 // DeleteCookie 删除单个Cookie
 func (h *Handler) DeleteCookie(c *gin.Context) {
 	var req struct {
@@ -464,24 +576,51 @@ func (h *Handler) ClearInPageRecordingState(c *gin.Context) {
 // SaveScript 保存脚本
 func (h *Handler) SaveScript(c *gin.Context) {
 	var req struct {
-		ID                    string                  `json:"id"` // 可选，更新时使用
-		Name                  string                  `json:"name" binding:"required"`
-		Description           string                  `json:"description"`
-		URL                   string                  `json:"url" binding:"required"`
-		Actions               []models.ScriptAction   `json:"actions" binding:"required"`
-		DownloadedFiles       []models.DownloadedFile `json:"downloaded_files"` // 下载的文件列表
-		Tags                  []string                `json:"tags"`
-		IsMCPCommand          *bool                   `json:"is_mcp_command"`
-		MCPCommandName        string                  `json:"mcp_command_name"`
-		MCPCommandDescription string                  `json:"mcp_command_description"`
-		MCPInputSchema        map[string]interface{}  `json:"mcp_input_schema"`
-		Variables             map[string]string       `json:"variables"`
+		ID                    string                      `json:"id"` // 可选，更新时使用
+		Name                  string                      `json:"name" binding:"required"`
+		Description           string                      `json:"description"`
+		URL                   string                      `json:"url" binding:"required"`
+		Actions               []models.ScriptAction       `json:"actions" binding:"required"`
+		DownloadedFiles       []models.DownloadedFile     `json:"downloaded_files"` // 下载的文件列表
+		Tags                  []string                    `json:"tags"`
+		IsMCPCommand          *bool                       `json:"is_mcp_command"`
+		MCPCommandName        string                      `json:"mcp_command_name"`
+		MCPCommandDescription string                      `json:"mcp_command_description"`
+		MCPInputSchema        map[string]interface{}      `json:"mcp_input_schema"`
+		Variables             map[string]string           `json:"variables"`
+		VariableDefs          []models.VariableDefinition `json:"variable_defs"`
+		RouteRules            []models.RouteRule          `json:"route_rules"`
+		DefaultOnError        string                      `json:"default_on_error"`
+		DefaultTimeoutMs      int                         `json:"default_timeout_ms"`
+		ScreenshotOnFailure   bool                        `json:"screenshot_on_failure"`
+		SaveHealedSelectors   bool                        `json:"save_healed_selectors"`
+		StorageStateID        string                      `json:"storage_state_id"`
+		Emulation             *models.EmulationProfile    `json:"emulation"`
+		ChangeNote            string                      `json:"change_note"` // 版本修改说明
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams"})
 		return
 	}
+	for i, rule := range req.RouteRules {
+		if err := browser.ValidateRouteRule(rule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams", "detail": fmt.Sprintf("route rule %d: %s", i, err.Error())})
+			return
+		}
+	}
+	if err := validateScriptPolicies("", req.Actions, req.DefaultOnError); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams", "detail": err.Error()})
+		return
+	}
+	if err := models.ValidateVariableDefs(req.VariableDefs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams", "detail": err.Error()})
+		return
+	}
+	if err := browser.ValidateEmulationProfile(req.Emulation); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams", "detail": err.Error()})
+		return
+	}
 
 	// 计算录制时长
 	var duration int64
@@ -495,17 +634,25 @@ func (h *Handler) SaveScript(c *gin.Context) {
 	}
 
 	script := &models.Script{
-		ID:              id,
-		Name:            req.Name,
-		Description:     req.Description,
-		URL:             req.URL,
-		Actions:         req.Actions,
-		DownloadedFiles: req.DownloadedFiles, // 保存下载文件信息
-		Tags:            req.Tags,
-		Duration:        duration,
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
-		Variables:       req.Variables,
+		ID:                  id,
+		Name:                req.Name,
+		Description:         req.Description,
+		URL:                 req.URL,
+		Actions:             req.Actions,
+		DownloadedFiles:     req.DownloadedFiles, // 保存下载文件信息
+		Tags:                req.Tags,
+		Duration:            duration,
+		CreatedAt:           time.Now(),
+		UpdatedAt:           time.Now(),
+		Variables:           req.Variables,
+		VariableDefs:        req.VariableDefs,
+		RouteRules:          req.RouteRules,
+		DefaultOnError:      req.DefaultOnError,
+		DefaultTimeoutMs:    req.DefaultTimeoutMs,
+		ScreenshotOnFailure: req.ScreenshotOnFailure,
+		SaveHealedSelectors: req.SaveHealedSelectors,
+		StorageStateID:      req.StorageStateID,
+		Emulation:           req.Emulation,
 	}
 
 	// 如果提供了 MCP 相关字段，则设置
@@ -521,8 +668,12 @@ func (h *Handler) SaveScript(c *gin.Context) {
 	if req.MCPInputSchema != nil {
 		script.MCPInputSchema = req.MCPInputSchema
 	}
+	// 声明了变量时，MCP 输入参数 schema 由变量声明生成
+	if len(script.VariableDefs) > 0 {
+		script.MCPInputSchema = script.BuildInputSchema()
+	}
 
-	if err := h.db.SaveScript(script); err != nil {
+	if err := h.db.SaveScriptWithRevision(script, revisionInfo(c, models.RevisionSourceAPI, req.ChangeNote)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error.saveScriptFailed"})
 		return
 	}
@@ -635,22 +786,53 @@ func (h *Handler) UpdateScript(c *gin.Context) {
 	}
 
 	var req struct {
-		Name                  string                 `json:"name"`
-		Description           string                 `json:"description"`
-		URL                   string                 `json:"url"`
-		Actions               []models.ScriptAction  `json:"actions"`
-		Tags                  []string               `json:"tags"`
-		IsMCPCommand          *bool                  `json:"is_mcp_command"`
-		MCPCommandName        *string                `json:"mcp_command_name"`
-		MCPCommandDescription *string                `json:"mcp_command_description"`
-		MCPInputSchema        map[string]interface{} `json:"mcp_input_schema"`
-		Variables             map[string]string      `json:"variables"`
+		Name                  string                      `json:"name"`
+		Description           string                      `json:"description"`
+		URL                   string                      `json:"url"`
+		Actions               []models.ScriptAction       `json:"actions"`
+		Tags                  []string                    `json:"tags"`
+		IsMCPCommand          *bool                       `json:"is_mcp_command"`
+		MCPCommandName        *string                     `json:"mcp_command_name"`
+		MCPCommandDescription *string                     `json:"mcp_command_description"`
+		MCPInputSchema        map[string]interface{}      `json:"mcp_input_schema"`
+		Variables             map[string]string           `json:"variables"`
+		VariableDefs          []models.VariableDefinition `json:"variable_defs"`
+		RouteRules            []models.RouteRule          `json:"route_rules"`
+		DefaultOnError        *string                     `json:"default_on_error"`
+		DefaultTimeoutMs      *int                        `json:"default_timeout_ms"`
+		ScreenshotOnFailure   *bool                       `json:"screenshot_on_failure"`
+		SaveHealedSelectors   *bool                       `json:"save_healed_selectors"`
+		StorageStateID        *string                     `json:"storage_state_id"`
+		Emulation             *models.EmulationProfile    `json:"emulation"`   // 传入空对象时清除
+		ChangeNote            string                      `json:"change_note"` // 版本修改说明
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams"})
 		return
 	}
+	for i, rule := range req.RouteRules {
+		if err := browser.ValidateRouteRule(rule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams", "detail": fmt.Sprintf("route rule %d: %s", i, err.Error())})
+			return
+		}
+	}
+	defaultOnError := ""
+	if req.DefaultOnError != nil {
+		defaultOnError = *req.DefaultOnError
+	}
+	if err := validateScriptPolicies(script.ID, req.Actions, defaultOnError); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams", "detail": err.Error()})
+		return
+	}
+	if err := models.ValidateVariableDefs(req.VariableDefs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams", "detail": err.Error()})
+		return
+	}
+	if err := browser.ValidateEmulationProfile(req.Emulation); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams", "detail": err.Error()})
+		return
+	}
 
 	// 更新字段
 	if req.Name != "" {
@@ -672,6 +854,33 @@ func (h *Handler) UpdateScript(c *gin.Context) {
 	if req.Variables != nil {
 		script.Variables = req.Variables
 	}
+	if req.VariableDefs != nil {
+		script.VariableDefs = req.VariableDefs
+	}
+	if req.RouteRules != nil {
+		script.RouteRules = req.RouteRules
+	}
+	if req.DefaultOnError != nil {
+		script.DefaultOnError = *req.DefaultOnError
+	}
+	if req.DefaultTimeoutMs != nil {
+		script.DefaultTimeoutMs = *req.DefaultTimeoutMs
+	}
+	if req.ScreenshotOnFailure != nil {
+		script.ScreenshotOnFailure = *req.ScreenshotOnFailure
+	}
+	if req.SaveHealedSelectors != nil {
+		script.SaveHealedSelectors = *req.SaveHealedSelectors
+	}
+	if req.StorageStateID != nil {
+		script.StorageStateID = *req.StorageStateID
+	}
+	if req.Emulation != nil {
+		script.Emulation = req.Emulation
+		if *req.Emulation == (models.EmulationProfile{}) {
+			script.Emulation = nil
+		}
+	}
 	if req.Tags != nil {
 		script.Tags = req.Tags
 	}
@@ -689,8 +898,12 @@ func (h *Handler) UpdateScript(c *gin.Context) {
 	if req.MCPInputSchema != nil {
 		script.MCPInputSchema = req.MCPInputSchema
 	}
+	// 声明了变量时，MCP 输入参数 schema 由变量声明生成
+	if len(script.VariableDefs) > 0 {
+		script.MCPInputSchema = script.BuildInputSchema()
+	}
 
-	if err := h.db.UpdateScript(script); err != nil {
+	if err := h.db.UpdateScriptWithRevision(script, revisionInfo(c, models.RevisionSourceAPI, req.ChangeNote)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error.updateScriptFailed"})
 		return
 	}
@@ -704,6 +917,31 @@ func (h *Handler) UpdateScript(c *gin.Context) {
 	})
 }
 
+// validateScriptPolicies 校验脚本及各步骤的失败处理策略、循环和子脚本调用配置
+func validateScriptPolicies(scriptID string, actions []models.ScriptAction, defaultOnError string) error {
+	if err := browser.ValidateActionPolicy(defaultOnError); err != nil {
+		return fmt.Errorf("default_on_error: %w", err)
+	}
+	for i, action := range actions {
+		if err := browser.ValidateActionPolicy(action.OnError); err != nil {
+			return fmt.Errorf("action %d: %w", i+1, err)
+		}
+		if err := browser.ValidateLoopAction(action); err != nil {
+			return fmt.Errorf("action %d: %w", i+1, err)
+		}
+		if err := browser.ValidateCallScriptAction(scriptID, action); err != nil {
+			return fmt.Errorf("action %d: %w", i+1, err)
+		}
+		if err := browser.ValidatePDFOptions(action.PDF); err != nil {
+			return fmt.Errorf("action %d: %w", i+1, err)
+		}
+		if err := browser.ValidateAssertAction(action, true); err != nil {
+			return fmt.Errorf("action %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
 // DeleteScript 删除脚本
 func (h *Handler) DeleteScript(c *gin.Context) {
 	id := c.Param("id")
@@ -721,6 +959,482 @@ func (h *Handler) DeleteScript(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "success.scriptDeleted"})
 }
 
+// revisionInfo 根据当前登录用户（或 API Key）构建脚本版本信息
+func revisionInfo(c *gin.Context, source, note string) models.RevisionInfo {
+	author := c.GetString("username")
+	if author == "" {
+		if keyID := c.GetString("api_key_id"); keyID != "" {
+			author = "api_key:" + keyID
+		}
+	}
+	return models.RevisionInfo{Author: author, Note: note, Source: source}
+}
+
+// ListScriptRevisions 列出脚本的历史版本
+func (h *Handler) ListScriptRevisions(c *gin.Context) {
+	id := c.Param("id")
+	script, err := h.db.GetScript(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "error.scriptNotFound"})
+		return
+	}
+
+	revisions, err := h.db.ListScriptRevisions(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error.listScriptRevisionsFailed", "detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"revisions":        revisions,
+		"current_revision": script.Revision,
+	})
+}
+
+// GetScriptRevision 获取脚本的指定版本
+func (h *Handler) GetScriptRevision(c *gin.Context) {
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil || revision < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams", "detail": "revision must be a positive integer"})
+		return
+	}
+
+	result, err := h.db.GetScriptRevision(c.Param("id"), revision)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "error.scriptRevisionNotFound", "detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// DiffScriptRevisions 逐个步骤比较脚本的两个版本
+// to 默认为当前版本，from 默认为 to 的上一个版本
+func (h *Handler) DiffScriptRevisions(c *gin.Context) {
+	id := c.Param("id")
+	script, err := h.db.GetScript(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "error.scriptNotFound"})
+		return
+	}
+
+	to := script.Revision
+	if value := c.Query("to"); value != "" {
+		if to, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams", "detail": "to must be an integer"})
+			return
+		}
+	}
+	from := to - 1
+	if value := c.Query("from"); value != "" {
+		if from, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams", "detail": "from must be an integer"})
+			return
+		}
+	}
+	if from < 1 || to < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams", "detail": "both from and to must be existing revisions (>= 1)"})
+		return
+	}
+
+	fromRevision, err := h.db.GetScriptRevision(id, from)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "error.scriptRevisionNotFound", "detail": err.Error()})
+		return
+	}
+	toRevision, err := h.db.GetScriptRevision(id, to)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "error.scriptRevisionNotFound", "detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.DiffScripts(fromRevision.Script, toRevision.Script))
+}
+
+// RestoreScriptRevision 将脚本回滚到指定版本（作为一个新版本保存，历史版本保持不变）
+func (h *Handler) RestoreScriptRevision(c *gin.Context) {
+	id := c.Param("id")
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil || revision < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams", "detail": "revision must be a positive integer"})
+		return
+	}
+
+	var req struct {
+		Note string `json:"note"`
+	}
+	_ = c.ShouldBindJSON(&req)
+
+	current, err := h.db.GetScript(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "error.scriptNotFound"})
+		return
+	}
+	target, err := h.db.GetScriptRevision(id, revision)
+	if err != nil || target.Script == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "error.scriptRevisionNotFound"})
+		return
+	}
+
+	script := target.Script
+	script.ID = current.ID
+	script.CreatedAt = current.CreatedAt
+	note := req.Note
+	if note == "" {
+		note = fmt.Sprintf("restored from revision %d", revision)
+	}
+	if err := h.db.UpdateScriptWithRevision(script, revisionInfo(c, models.RevisionSourceRestore, note)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error.updateScriptFailed", "detail": err.Error()})
+		return
+	}
+
+	// 同步 MCP 注册状态
+	h.syncMCPRegistration(c, script)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success.scriptRestored",
+		"script":  script,
+	})
+}
+
+// ImportScripts 从 Chrome DevTools Recorder JSON 或 Selenium IDE .side 文件导入脚本
+// content 为文件内容（JSON 对象或 JSON 字符串），format 为空时自动识别；dry_run 时只返回转换结果不保存
+func (h *Handler) ImportScripts(c *gin.Context) {
+	var req struct {
+		Format  string          `json:"format"` // chrome_recorder, selenium_ide
+		Content json.RawMessage `json:"content" binding:"required"`
+		Group   string          `json:"group"`
+		Tags    []string        `json:"tags"`
+		DryRun  bool            `json:"dry_run"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams"})
+		return
+	}
+
+	// 允许以字符串形式直接传入文件内容
+	content := []byte(req.Content)
+	var text string
+	if err := json.Unmarshal(req.Content, &text); err == nil {
+		content = []byte(text)
+	}
+
+	result, err := browser.ImportScripts(content, req.Format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.importScriptsFailed", "detail": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	for _, script := range result.Scripts {
+		script.ID = uuid.New().String()
+		script.Group = req.Group
+		script.Tags = req.Tags
+		script.CreatedAt = now
+		script.UpdatedAt = now
+	}
+
+	if !req.DryRun {
+		note := "imported from " + result.Format
+		for _, script := range result.Scripts {
+			if err := h.db.SaveScriptWithRevision(script, revisionInfo(c, models.RevisionSourceImport, note)); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error.saveScriptFailed", "detail": err.Error()})
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "success.scriptsImported",
+		"format":      result.Format,
+		"scripts":     result.Scripts,
+		"unsupported": result.Unsupported,
+		"saved":       !req.DryRun,
+	})
+}
+
+// ExportScriptCode 将脚本导出为可独立运行的 Playwright / Puppeteer / go-rod 代码
+// target 默认 playwright-ts；format=json 时返回 JSON（包含需要手动补充的 TODO 数量），否则作为文件下载
+func (h *Handler) ExportScriptCode(c *gin.Context) {
+	script, err := h.db.GetScript(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "error.scriptNotFound"})
+		return
+	}
+
+	target := c.DefaultQuery("target", browser.ExportTargetPlaywrightTS)
+	export, err := browser.ExportScriptCode(script, target)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.exportScriptCodeFailed", "detail": err.Error(), "targets": browser.ExportTargets})
+		return
+	}
+
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, export)
+		return
+	}
+
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+export.FileName)
+	c.Header("X-Export-TODOs", strconv.Itoa(export.TODOs))
+	c.String(http.StatusOK, export.Content)
+}
+
+// maxBundleUploadSize 脚本包上传大小限制
+const maxBundleUploadSize = 512 << 20
+
+// ExportScriptBundle 将脚本连同引用的文件、关联的工具配置、MCP 命令设置和定时任务打包为 zip
+func (h *Handler) ExportScriptBundle(c *gin.Context) {
+	var req struct {
+		ScriptIDs []string `json:"script_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.ScriptIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest"})
+		return
+	}
+
+	bundle := &browser.ScriptBundle{
+		Manifest: browser.BundleManifest{CreatedAt: time.Now(), Source: c.Request.Host},
+		Files:    map[string][]byte{},
+	}
+	scriptIDs := make(map[string]bool)
+	for _, id := range req.ScriptIDs {
+		script, err := h.db.GetScript(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "error.scriptNotFound", "detail": id})
+			return
+		}
+		bundle.Scripts = append(bundle.Scripts, script)
+		scriptIDs[script.ID] = true
+	}
+
+	// 上传文件和录制时下载的文件，读取失败的记录到清单中
+	for _, path := range browser.BundleFilePaths(bundle.Scripts) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			logger.Warn(c.Request.Context(), "Failed to read bundle file %s: %v", path, err)
+			bundle.Manifest.Missing = append(bundle.Manifest.Missing, path)
+			continue
+		}
+		bundle.Files[path] = data
+	}
+
+	// 关联的工具配置和定时任务
+	toolConfigs, err := h.db.ListToolConfigs()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error.exportBundleFailed", "detail": err.Error()})
+		return
+	}
+	for _, toolConfig := range toolConfigs {
+		if scriptIDs[toolConfig.ScriptID] {
+			bundle.ToolConfigs = append(bundle.ToolConfigs, toolConfig)
+		}
+	}
+	tasks, err := h.db.ListScheduledTasks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error.exportBundleFailed", "detail": err.Error()})
+		return
+	}
+	for i := range tasks {
+		if tasks[i].ExecutionType == models.ExecutionTypeScript && scriptIDs[tasks[i].ScriptID] {
+			bundle.Tasks = append(bundle.Tasks, &tasks[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := browser.WriteScriptBundle(&buf, bundle); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error.exportBundleFailed", "detail": err.Error()})
+		return
+	}
+
+	fileName := fmt.Sprintf("browserwing_bundle_%s.zip", time.Now().Format("20060102150405"))
+	c.Header("Content-Disposition", "attachment; filename="+fileName)
+	c.Header("X-Bundle-Checksum", bundle.Manifest.Checksum)
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// ImportScriptBundle 导入脚本包：校验清单和校验值，重新分配 ID，按 on_conflict（rename/skip/overwrite）处理同名脚本和任务
+// 包文件通过 multipart 的 file 字段或直接作为请求体上传；定时任务默认以禁用状态导入，enable_tasks=true 时保留原启用状态
+func (h *Handler) ImportScriptBundle(c *gin.Context) {
+	param := func(name string) string {
+		if value := c.Query(name); value != "" {
+			return value
+		}
+		return c.PostForm(name)
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBundleUploadSize)
+	var data []byte
+	var err error
+	if file, formErr := c.FormFile("file"); formErr == nil {
+		var f io.ReadCloser
+		if f, err = file.Open(); err == nil {
+			data, err = io.ReadAll(f)
+			f.Close()
+		}
+	} else {
+		data, err = io.ReadAll(c.Request.Body)
+	}
+	if err != nil || len(data) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest"})
+		return
+	}
+
+	bundle, err := browser.ReadScriptBundle(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.importBundleFailed", "detail": err.Error()})
+		return
+	}
+
+	onConflict := param("on_conflict")
+	switch onConflict {
+	case "":
+		onConflict = browser.BundleConflictRename
+	case browser.BundleConflictRename, browser.BundleConflictSkip, browser.BundleConflictOverwrite:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest", "detail": "on_conflict must be rename, skip or overwrite"})
+		return
+	}
+	assetsDir := "./data"
+	if h.config != nil && h.config.AssetsDir != "" {
+		assetsDir = h.config.AssetsDir
+	}
+
+	scripts, err := h.db.ListScripts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error.listScriptsFailed"})
+		return
+	}
+	tasks, err := h.db.ListScheduledTasks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error.importBundleFailed", "detail": err.Error()})
+		return
+	}
+	plan := browser.PlanBundleImport(bundle, browser.BundleImportState{Scripts: scripts, Tasks: tasks}, browser.BundleImportOptions{
+		OnConflict:  onConflict,
+		EnableTasks: param("enable_tasks") == "true",
+		Group:       param("group"),
+		FilesDir:    filepath.Join(assetsDir, "bundle_files"),
+	})
+	if err := validateBundleImport(plan); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.importBundleFailed", "detail": err.Error()})
+		return
+	}
+
+	dryRun := param("dry_run") == "true"
+	if !dryRun {
+		if err := h.applyBundleImport(c, bundle, plan); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error.importBundleFailed", "detail": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":               "success.bundleImported",
+		"manifest":              bundle.Manifest,
+		"scripts":               plan.Scripts,
+		"tool_configs":          plan.ToolConfigs,
+		"scheduled_tasks":       plan.Tasks,
+		"id_map":                plan.IDMap,
+		"conflicts":             plan.Conflicts,
+		"unresolved_references": plan.Unresolved,
+		"saved":                 !dryRun,
+	})
+}
+
+// validateBundleImport 保存前校验导入计划中的全部脚本和定时任务，任何一项无效时不写入任何数据
+func validateBundleImport(plan *browser.BundleImportPlan) error {
+	for _, script := range plan.Scripts {
+		for i, rule := range script.RouteRules {
+			if err := browser.ValidateRouteRule(rule); err != nil {
+				return fmt.Errorf("script %s: route rule %d: %w", script.Name, i, err)
+			}
+		}
+		if err := validateScriptPolicies(script.ID, script.Actions, script.DefaultOnError); err != nil {
+			return fmt.Errorf("script %s: %w", script.Name, err)
+		}
+		if err := models.ValidateVariableDefs(script.VariableDefs); err != nil {
+			return fmt.Errorf("script %s: %w", script.Name, err)
+		}
+		if err := browser.ValidateEmulationProfile(script.Emulation); err != nil {
+			return fmt.Errorf("script %s: %w", script.Name, err)
+		}
+	}
+	for _, task := range plan.Tasks {
+		switch {
+		case task.Name == "":
+			return fmt.Errorf("scheduled task has no name")
+		case task.ScheduleType == "" || task.ScheduleConfig == "":
+			return fmt.Errorf("task %s: schedule is required", task.Name)
+		case task.ExecutionType == models.ExecutionTypeScript && task.ScriptID == "":
+			return fmt.Errorf("task %s: script_id is required", task.Name)
+		case task.ExecutionType == models.ExecutionTypeAgent && task.AgentPrompt == "":
+			return fmt.Errorf("task %s: agent_prompt is required", task.Name)
+		}
+	}
+	return nil
+}
+
+// applyBundleImport 写入附带文件并在同一事务中保存导入计划中的脚本、工具配置和定时任务
+// 任一步骤失败时删除本次写入的文件，数据库中不会留下部分导入的数据
+func (h *Handler) applyBundleImport(c *gin.Context, bundle *browser.ScriptBundle, plan *browser.BundleImportPlan) error {
+	var written []string
+	rollback := func() {
+		for _, path := range written {
+			_ = os.Remove(path)
+		}
+	}
+	for path, content := range plan.Files {
+		// 目标路径按内容哈希生成，已存在的文件内容相同，无需重写（回滚时也不能删除）
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			rollback()
+			return fmt.Errorf("failed to create directory for %s: %w", path, err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			_ = os.Remove(path)
+			rollback()
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		written = append(written, path)
+	}
+
+	note := "imported from bundle " + bundle.Manifest.Checksum[:12]
+	if err := h.db.ImportScripts(plan.Scripts, revisionInfo(c, models.RevisionSourceImport, note), plan.ToolConfigs, plan.Tasks, plan.Replaces); err != nil {
+		rollback()
+		return err
+	}
+
+	// 数据已保存，同步 MCP 注册和调度器
+	for _, script := range plan.Scripts {
+		h.syncMCPRegistration(c, script)
+	}
+	type Scheduler interface {
+		AddTask(*models.ScheduledTask) error
+		ReloadTask(string) error
+	}
+	scheduler, _ := h.scheduler.(Scheduler)
+	if scheduler == nil {
+		return nil
+	}
+	for _, task := range plan.Tasks {
+		if plan.Replaces[task.ID] {
+			if err := scheduler.ReloadTask(task.ID); err != nil {
+				logger.Warn(c.Request.Context(), "Failed to reload task in scheduler: %v", err)
+			}
+			continue
+		}
+		if task.Enabled {
+			if err := scheduler.AddTask(task); err != nil {
+				logger.Warn(c.Request.Context(), "Failed to add task to scheduler: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
 // PlayScript 回放脚本
 func (h *Handler) PlayScript(c *gin.Context) {
 	id := c.Param("id")
@@ -731,8 +1445,10 @@ func (h *Handler) PlayScript(c *gin.Context) {
 
 	// 解析请求体中的参数
 	var req struct {
-		Params     map[string]string `json:"params"`
-		InstanceID string            `json:"instance_id"` // 指定实例ID，空字符串表示使用当前实例
+		Params         map[string]string  `json:"params"`
+		InstanceID     string             `json:"instance_id"`      // 指定实例ID，空字符串表示使用当前实例
+		RouteRules     []models.RouteRule `json:"route_rules"`      // 本次回放的请求拦截规则
+		StorageStateID string             `json:"storage_state_id"` // 回放前恢复的存储状态，覆盖脚本设置
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		// 如果没有请求体或解析失败,使用空参数
@@ -742,16 +1458,6 @@ func (h *Handler) PlayScript(c *gin.Context) {
 		instanceID = req.InstanceID
 	}
 
-	// 检查浏览器是否运行
-	if !h.browserManager.IsInstanceRunning(instanceID) {
-		logger.Info(c, "Browser not running, starting...")
-		if err := h.browserManager.StartInstance(c, instanceID); err != nil {
-			logger.Error(c.Request.Context(), "Failed to start browser: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "error.playScriptFailed"})
-			return
-		}
-	}
-
 	// 获取脚本
 	script, err := h.db.GetScript(id)
 	if err != nil {
@@ -759,64 +1465,28 @@ func (h *Handler) PlayScript(c *gin.Context) {
 		return
 	}
 
-	// 创建脚本副本并合并参数
-	scriptToRun := script.Copy()
-
-	// 合并参数：先使用脚本预设变量，再用外部传入的参数覆盖
-	mergedParams := make(map[string]string)
-
-	// 1. 首先添加脚本的预设变量
-	if scriptToRun.Variables != nil {
-		for key, value := range scriptToRun.Variables {
-			mergedParams[key] = value
-		}
-		for key := range scriptToRun.Variables {
-			if _, ok := req.Params[key]; ok {
-				scriptToRun.Variables[key] = req.Params[key]
-			}
-		}
-	}
-
-	// 2. 外部传入的参数会覆盖预设变量
-	for key, value := range req.Params {
-		mergedParams[key] = value
+	// 校验参数并替换占位符（先使用脚本预设变量，外部传入的参数会覆盖），无效参数在启动浏览器前拒绝
+	scriptToRun, err := script.WithParams(req.Params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidScriptParams", "detail": err.Error()})
+		return
 	}
 
-	// 如果有参数（包括预设变量和外部参数），替换占位符
-	if len(mergedParams) > 0 {
-
-		// 如果用户提供了 url 参数,使用它;否则替换 URL 中的占位符
-		if urlParam, ok := mergedParams["url"]; ok && urlParam != "" {
-			scriptToRun.URL = urlParam
-		} else {
-			scriptToRun.URL = replacePlaceholders(scriptToRun.URL, mergedParams)
-		}
-
-		// 复制 actions 数组以避免修改原始数据
-		scriptToRun.Actions = make([]models.ScriptAction, len(script.Actions))
-		copy(scriptToRun.Actions, script.Actions)
-
-		// 替换所有 action 中的占位符
-		for i := range scriptToRun.Actions {
-			scriptToRun.Actions[i].Selector = replacePlaceholders(scriptToRun.Actions[i].Selector, mergedParams)
-			scriptToRun.Actions[i].XPath = replacePlaceholders(scriptToRun.Actions[i].XPath, mergedParams)
-			scriptToRun.Actions[i].Value = replacePlaceholders(scriptToRun.Actions[i].Value, mergedParams)
-			scriptToRun.Actions[i].URL = replacePlaceholders(scriptToRun.Actions[i].URL, mergedParams)
-			scriptToRun.Actions[i].JSCode = replacePlaceholders(scriptToRun.Actions[i].JSCode, mergedParams)
-
-			// 替换文件路径中的占位符
-			if len(scriptToRun.Actions[i].FilePaths) > 0 {
-				newFilePaths := make([]string, len(scriptToRun.Actions[i].FilePaths))
-				for j, path := range scriptToRun.Actions[i].FilePaths {
-					newFilePaths[j] = replacePlaceholders(path, mergedParams)
-				}
-				scriptToRun.Actions[i].FilePaths = newFilePaths
-			}
+	// 检查浏览器是否运行
+	if !h.browserManager.IsInstanceRunning(instanceID) {
+		logger.Info(c, "Browser not running, starting...")
+		if err := h.browserManager.StartInstance(c, instanceID); err != nil {
+			logger.Error(c.Request.Context(), "Failed to start browser: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error.playScriptFailed"})
+			return
 		}
 	}
 
 	// 执行回放
-	result, page, err := h.browserManager.PlayScript(c.Request.Context(), scriptToRun, req.InstanceID)
+	result, page, err := h.browserManager.PlayScriptWithOptions(c.Request.Context(), scriptToRun, req.InstanceID, &browser.PlayOptions{
+		RouteRules:     req.RouteRules,
+		StorageStateID: req.StorageStateID,
+	})
 	if err != nil {
 		logger.Error(c.Request.Context(), "Failed to play script: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -1095,6 +1765,11 @@ func (h *Handler) CreateBrowserConfig(c *gin.Context) {
 		return
 	}
 
+	if err := browser.ValidateEmulationProfile(config.Emulation); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 生成ID
 	config.ID = fmt.Sprintf("config_%d", time.Now().Unix())
 
@@ -1119,6 +1794,11 @@ func (h *Handler) UpdateBrowserConfig(c *gin.Context) {
 		return
 	}
 
+	if err := browser.ValidateEmulationProfile(config.Emulation); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
 	config.ID = id
 
 	if err := h.db.SaveBrowserConfig(&config); err != nil {
@@ -1149,6 +1829,8 @@ func (h *Handler) DeleteBrowserConfig(c *gin.Context) {
 // SetMCPServer 设置 MCP 服务器实例
 func (h *Handler) SetMCPServer(mcpServer MCPHTTPHandler) {
 	h.mcpServer = mcpServer
+	// HTTP API 与 MCP 工具共用会话，通过任一方式创建的会话都可以在另一方使用
+	h.sessions = mcpServer.GetSessionManager()
 }
 
 // SetAgentManager 设置 Agent 管理器实例
@@ -1247,8 +1929,11 @@ func (h *Handler) ToggleScriptMCPCommand(c *gin.Context) {
 	script.MCPCommandName = req.MCPCommandName
 	script.MCPCommandDescription = req.MCPCommandDescription
 	script.MCPInputSchema = req.MCPInputSchema
+	if len(script.VariableDefs) > 0 {
+		script.MCPInputSchema = script.BuildInputSchema()
+	}
 
-	if err := h.db.UpdateScript(script); err != nil {
+	if err := h.db.UpdateScriptWithRevision(script, revisionInfo(c, models.RevisionSourceMCP, "")); err != nil {
 		c.JSON(500, gin.H{"error": "error.updateScriptFailed"})
 		return
 	}
@@ -1527,7 +2212,7 @@ func (h *Handler) BatchSetGroup(c *gin.Context) {
 		}
 		script.Group = req.Group
 		script.UpdatedAt = time.Now()
-		if err := h.db.UpdateScript(script); err != nil {
+		if err := h.db.UpdateScriptWithRevision(script, revisionInfo(c, models.RevisionSourceBatch, "set group")); err != nil {
 			continue
 		}
 		successCount++
@@ -1574,7 +2259,7 @@ func (h *Handler) BatchAddTags(c *gin.Context) {
 
 		script.Tags = newTags
 		script.UpdatedAt = time.Now()
-		if err := h.db.UpdateScript(script); err != nil {
+		if err := h.db.UpdateScriptWithRevision(script, revisionInfo(c, models.RevisionSourceBatch, "add tags")); err != nil {
 			continue
 		}
 		successCount++
@@ -1704,6 +2389,31 @@ func (h *Handler) GetScriptExecution(c *gin.Context) {
 	c.JSON(http.StatusOK, execution)
 }
 
+// GetScriptExecutionStepScreenshot 获取执行轨迹中某个步骤的失败截图
+func (h *Handler) GetScriptExecutionStepScreenshot(c *gin.Context) {
+	id := c.Param("id")
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidParams", "detail": "invalid step index"})
+		return
+	}
+
+	execution, err := h.db.GetScriptExecution(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "error.executionRecordNotFound"})
+		return
+	}
+
+	for _, step := range execution.Steps {
+		if step.Index == index && step.ScreenshotPath != "" {
+			c.File(step.ScreenshotPath)
+			return
+		}
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "error.screenshotNotFound"})
+}
+
 // DeleteScriptExecution 删除执行记录
 func (h *Handler) DeleteScriptExecution(c *gin.Context) {
 	id := c.Param("id")
@@ -1793,24 +2503,6 @@ func (h *Handler) UpdateRecordingConfig(c *gin.Context) {
 
 // ============= 辅助函数 =============
 
-// replacePlaceholders 替换字符串中的占位符
-// 支持 ${field} 格式，例如 ${keyword}, ${page}, ${category} 等
-func replacePlaceholders(text string, params map[string]string) string {
-	if text == "" {
-		return text
-	}
-
-	// 替换所有占位符
-	result := text
-	for key, value := range params {
-		placeholder := fmt.Sprintf("${%s}", key)
-		result = strings.ReplaceAll(result, placeholder, value)
-	}
-
-	// 注意：这里不移除未替换的占位符，保留它们以便调试
-	return result
-}
-
 // syncMCPRegistration 同步 MCP 命令注册状态
 // 如果脚本是 MCP 命令则注册，否则取消注册
 func (h *Handler) syncMCPRegistration(ctx context.Context, script *models.Script) {
@@ -3515,6 +4207,12 @@ func (h *Handler) ExecutorHelp(c *gin.Context) {
 					"description": "Timeout in seconds",
 					"default":     10,
 				},
+				"snapshot_diff": map[string]interface{}{
+					"type":        "boolean",
+					"required":    false,
+					"description": "Return only the changes since the previous snapshot (snapshot_diff) instead of the full semantic tree",
+					"default":     false,
+				},
 			},
 			"example": map[string]interface{}{
 				"identifier":   "#login-button",
@@ -3546,6 +4244,12 @@ func (h *Handler) ExecutorHelp(c *gin.Context) {
 					"description": "Clear existing content first",
 					"default":     true,
 				},
+				"snapshot_diff": map[string]interface{}{
+					"type":        "boolean",
+					"required":    false,
+					"description": "Return only the changes since the previous snapshot (snapshot_diff) instead of the full semantic tree",
+					"default":     false,
+				},
 			},
 			"example": map[string]interface{}{
 				"identifier": "#email-input",
@@ -3570,6 +4274,12 @@ func (h *Handler) ExecutorHelp(c *gin.Context) {
 					"required":    true,
 					"description": "Option value or text to select",
 				},
+				"snapshot_diff": map[string]interface{}{
+					"type":        "boolean",
+					"required":    false,
+					"description": "Return only the changes since the previous snapshot (snapshot_diff) instead of the full semantic tree",
+					"default":     false,
+				},
 			},
 			"example": map[string]interface{}{
 				"identifier": "#country-select",
@@ -3745,14 +4455,117 @@ func (h *Handler) ExecutorHelp(c *gin.Context) {
 			},
 			"returns": "Input value",
 		},
+		{
+			"name":        "assert",
+			"method":      "POST",
+			"endpoint":    "/api/v1/executor/assert",
+			"description": "Assert page state: element visible/hidden, text, attribute, element count, URL, title or a variable",
+			"parameters": map[string]interface{}{
+				"kind": map[string]interface{}{
+					"type":        "string",
+					"required":    true,
+					"description": "visible, hidden, text_equals, text_contains, text_matches, attribute, url_matches, title_matches, count or variable",
+				},
+				"identifier": map[string]interface{}{
+					"type":        "string",
+					"required":    false,
+					"description": "Element identifier (required for element assertions)",
+				},
+				"expected": map[string]interface{}{
+					"type":        "string",
+					"required":    false,
+					"description": "Expected text, regular expression, count or value (${var} is replaced from variables)",
+				},
+				"attribute": map[string]interface{}{
+					"type":        "string",
+					"required":    false,
+					"description": "Attribute name for attribute assertions",
+				},
+				"variable": map[string]interface{}{
+					"type":        "string",
+					"required":    false,
+					"description": "Variable name for variable assertions",
+				},
+				"variables": map[string]interface{}{
+					"type":        "object",
+					"required":    false,
+					"description": "Variables for variable assertions and ${var} substitution",
+				},
+				"operator": map[string]interface{}{
+					"type":        "string",
+					"required":    false,
+					"description": "Comparison for attribute, count and variable: =, !=, >, <, >=, <=, contains, not_contains, matches, in, not_in, exists, not_exists (default =)",
+				},
+				"mode": map[string]interface{}{
+					"type":        "string",
+					"required":    false,
+					"description": "hard (default, HTTP 422 on failure) or soft (HTTP 200 with success=false)",
+				},
+				"message": map[string]interface{}{
+					"type":        "string",
+					"required":    false,
+					"description": "Custom failure message",
+				},
+				"wait_ms": map[string]interface{}{
+					"type":        "number",
+					"required":    false,
+					"description": "Keep re-checking for up to this many milliseconds before failing",
+				},
+			},
+			"example": map[string]interface{}{
+				"kind":       "text_contains",
+				"identifier": "h1",
+				"expected":   "Dashboard",
+				"wait_ms":    5000,
+			},
+			"returns": "passed, actual and expected values and a failure message",
+		},
 		{
 			"name":        "snapshot",
 			"method":      "GET",
 			"endpoint":    "/api/v1/executor/snapshot",
 			"description": "Get the accessibility snapshot of the current page (all interactive elements)",
-			"parameters":  map[string]interface{}{},
-			"returns":     "Accessibility snapshot with all clickable and input elements",
-			"note":        "Use this first to understand page structure and get element indices. The accessibility tree is cleaner than raw DOM.",
+			"parameters": map[string]interface{}{
+				"diff": map[string]interface{}{
+					"type":        "boolean",
+					"required":    false,
+					"description": "Query parameter. Re-read the page and return only elements added, removed or changed since the previous snapshot; unchanged elements keep their RefIDs",
+					"default":     false,
+				},
+				"max_tokens": map[string]interface{}{
+					"type":        "number",
+					"required":    false,
+					"description": "Query parameter. Approximate token budget (4 chars per token, at least 100); inputs are kept first, then buttons, links and headings",
+				},
+				"max_chars": map[string]interface{}{
+					"type":        "number",
+					"required":    false,
+					"description": "Query parameter. Character budget, at least 400 (the smaller of max_chars and max_tokens applies)",
+				},
+				"viewport_only": map[string]interface{}{
+					"type":        "boolean",
+					"required":    false,
+					"description": "Query parameter. Only include elements intersecting the current viewport",
+					"default":     false,
+				},
+				"root": map[string]interface{}{
+					"type":        "string",
+					"required":    false,
+					"description": "Query parameter. Only include the subtree under a RefID (@e12) or landmark role, optionally with a name (main, navigation, form:Login), or the n-th node of a role (list#2)",
+				},
+				"list_limit": map[string]interface{}{
+					"type":        "number",
+					"required":    false,
+					"description": "Query parameter. List at most this many items per list/grid/table and collapse the rest into \"N more items\"",
+				},
+			},
+			"example": map[string]interface{}{
+				"max_tokens":    1500,
+				"viewport_only": true,
+				"list_limit":    10,
+			},
+			"returns": "Accessibility snapshot with all clickable and input elements (or the diff when diff=true)",
+			"note":    "Use this first to understand page structure and get element indices. The accessibility tree is cleaner than raw DOM.",
 		},
 		{
 			"name":        "clickable-elements",
@@ -3812,8 +4625,41 @@ func (h *Handler) ExecutorHelp(c *gin.Context) {
 					"description": "Image format: png or jpeg",
 					"default":     "png",
 				},
+				"annotate": map[string]interface{}{
+					"type":        "boolean",
+					"required":    false,
+					"description": "Draw numbered boxes labelled with snapshot RefIDs over interactive elements (set-of-marks)",
+					"default":     false,
+				},
+			},
+			"returns": "Base64 encoded image data; with annotate, also marks (ref, role, name, box) and a text legend",
+		},
+		{
+			"name":        "pdf",
+			"method":      "POST",
+			"endpoint":    "/api/v1/executor/pdf",
+			"description": "Print the page to PDF (requires a headless browser) and save it under pdfs/",
+			"parameters": map[string]interface{}{
+				"paper_format":     map[string]interface{}{"type": "string", "required": false, "description": "A3, A4, A5, Letter, Legal or Tabloid", "default": "A4"},
+				"paper_width":      map[string]interface{}{"type": "number", "required": false, "description": "Custom paper width in inches"},
+				"paper_height":     map[string]interface{}{"type": "number", "required": false, "description": "Custom paper height in inches"},
+				"landscape":        map[string]interface{}{"type": "boolean", "required": false, "description": "Landscape orientation"},
+				"scale":            map[string]interface{}{"type": "number", "required": false, "description": "Scale between 0.1 and 2", "default": 1},
+				"margin_top":       map[string]interface{}{"type": "number", "required": false, "description": "Top margin in inches (also margin_bottom, margin_left, margin_right)"},
+				"print_background": map[string]interface{}{"type": "boolean", "required": false, "description": "Print background colors and images"},
+				"page_ranges":      map[string]interface{}{"type": "string", "required": false, "description": "Pages to print, e.g. '1-5, 8'"},
+				"header_template":  map[string]interface{}{"type": "string", "required": false, "description": "Header HTML template (classes: date, title, url, pageNumber, totalPages)"},
+				"footer_template":  map[string]interface{}{"type": "string", "required": false, "description": "Footer HTML template"},
 			},
-			"returns": "Base64 encoded image data",
+			"returns": "Base64 encoded PDF data, saved file path, size, SHA-256, page URL and title",
+		},
+		{
+			"name":        "archive",
+			"method":      "POST",
+			"endpoint":    "/api/v1/executor/archive",
+			"description": "Save the page with its styles, images and iframes as a single MHTML archive under archives/",
+			"parameters":  map[string]interface{}{},
+			"returns":     "Base64 encoded MHTML data, saved file path, size, SHA-256, page URL and title",
 		},
 		{
 			"name":        "evaluate",
@@ -3952,19 +4798,112 @@ func (h *Handler) ExecutorHelp(c *gin.Context) {
 			"name":        "console-messages",
 			"method":      "GET",
 			"endpoint":    "/api/v1/executor/console-messages",
-			"description": "Get console messages from the browser (logs, warnings, errors)",
-			"parameters":  map[string]interface{}{},
-			"returns":     "Array of console messages with type, text, and timestamp",
-			"note":        "Useful for debugging JavaScript errors or monitoring console output",
+			"description": "Get console messages buffered since the page was created (console calls, uncaught exceptions, browser logs)",
+			"parameters": map[string]interface{}{
+				"level": map[string]interface{}{
+					"type":        "string",
+					"required":    false,
+					"description": "Comma-separated levels to include: debug, log, info, warning, error",
+					"example":     "warning,error",
+				},
+				"since": map[string]interface{}{
+					"type":        "string",
+					"required":    false,
+					"description": "Only messages after this time (RFC3339 or unix milliseconds)",
+				},
+				"cursor": map[string]interface{}{
+					"type":        "number",
+					"required":    false,
+					"description": "Only messages after this cursor (returned by the previous call)",
+				},
+				"limit": map[string]interface{}{
+					"type":        "number",
+					"required":    false,
+					"description": "Maximum number of messages to return (newest kept)",
+				},
+				"clear": map[string]interface{}{
+					"type":        "boolean",
+					"required":    false,
+					"description": "Clear the buffer after reading",
+					"default":     false,
+				},
+			},
+			"returns": "Console messages with seq, source, level, text, location, stack trace and timestamp, plus a cursor for incremental reads",
+			"note":    "Pass the returned cursor on the next call to only get new messages",
 		},
 		{
 			"name":        "network-requests",
 			"method":      "GET",
 			"endpoint":    "/api/v1/executor/network-requests",
-			"description": "Get network requests made by the page (XHR, Fetch, etc.)",
-			"parameters":  map[string]interface{}{},
-			"returns":     "Array of network requests with URL, method, status, and response",
-			"note":        "Useful for API monitoring and debugging network issues",
+			"description": "Get the network journal of the current page (recorded since the page was created)",
+			"parameters": map[string]interface{}{
+				"url": map[string]interface{}{
+					"type":        "string",
+					"required":    false,
+					"description": "Only requests whose URL contains this text",
+					"example":     "/api/",
+				},
+				"method": map[string]interface{}{
+					"type":        "string",
+					"required":    false,
+					"description": "Only requests with this HTTP method",
+				},
+				"status": map[string]interface{}{
+					"type":        "string",
+					"required":    false,
+					"description": "Status filter: exact code (404), class (4xx) or range (400-499)",
+				},
+				"resource_type": map[string]interface{}{
+					"type":        "string",
+					"required":    false,
+					"description": "Comma-separated resource types: Document, XHR, Fetch, Script, Image ...",
+				},
+				"state": map[string]interface{}{
+					"type":        "string",
+					"required":    false,
+					"description": "Request state: pending, finished, failed",
+				},
+				"cursor": map[string]interface{}{
+					"type":        "number",
+					"required":    false,
+					"description": "Only requests after this cursor (returned by the previous call)",
+				},
+				"limit": map[string]interface{}{
+					"type":        "number",
+					"required":    false,
+					"description": "Maximum number of requests to return (newest kept)",
+				},
+				"include_body": map[string]interface{}{
+					"type":        "boolean",
+					"required":    false,
+					"description": "Include response bodies",
+					"default":     false,
+				},
+			},
+			"returns": "Network requests with URL, method, status, headers, timings and state, plus a cursor for incremental reads",
+			"note":    "Use GET /network-requests/body?request_id=... to fetch a single response body, GET /network-requests/har to export HAR 1.2",
+		},
+		{
+			"name":        "route-rules",
+			"method":      "POST",
+			"endpoint":    "/api/v1/executor/route-rules",
+			"description": "Intercept requests of the current page: block, fulfill with a canned response, modify headers or delay (replaces existing rules)",
+			"parameters": map[string]interface{}{
+				"rules": map[string]interface{}{
+					"type":        "array",
+					"required":    true,
+					"description": "Rules matched in order, first match wins. Fields: url_pattern (* and ? wildcards), method, resource_type, action (block|fulfill|modify_headers|delay), error_reason, status, headers, content_type, body, body_base64, request_headers, response_headers (empty value removes a header), delay_ms",
+				},
+			},
+			"example": map[string]interface{}{
+				"rules": []map[string]interface{}{
+					{"url_pattern": "*google-analytics.com*", "action": "block"},
+					{"url_pattern": "*/api/user*", "action": "fulfill", "status": 200, "content_type": "application/json", "body": `{"name":"test"}`},
+					{"url_pattern": "*/api/*", "action": "modify_headers", "request_headers": map[string]string{"X-Debug": "1"}},
+				},
+			},
+			"returns": "Active rules with hit counts",
+			"note":    "GET /route-rules lists active rules with hit counts, DELETE /route-rules disables interception",
 		},
 		{
 			"name":        "handle-dialog",
@@ -4051,6 +4990,75 @@ func (h *Handler) ExecutorHelp(c *gin.Context) {
 			"returns":     "Operation result",
 			"note":        "Use with caution. After closing, you may need to switch to another tab.",
 		},
+		{
+			"name":        "emulate",
+			"method":      "POST",
+			"endpoint":    "/api/v1/executor/emulate",
+			"description": "Emulate device, locale, timezone, geolocation, media features or network conditions on the current page (only the given fields change)",
+			"parameters": map[string]interface{}{
+				"device":              map[string]interface{}{"type": "string", "required": false, "description": "Preset device name, e.g. 'iPhone X', 'Pixel 2', 'iPad'"},
+				"width":               map[string]interface{}{"type": "number", "required": false, "description": "Viewport width in CSS pixels"},
+				"height":              map[string]interface{}{"type": "number", "required": false, "description": "Viewport height in CSS pixels"},
+				"device_scale_factor": map[string]interface{}{"type": "number", "required": false, "description": "Device pixel ratio"},
+				"mobile":              map[string]interface{}{"type": "boolean", "required": false, "description": "Emulate a mobile device"},
+				"touch":               map[string]interface{}{"type": "boolean", "required": false, "description": "Enable touch events"},
+				"locale":              map[string]interface{}{"type": "string", "required": false, "description": "Locale such as de-DE (also sets Accept-Language)"},
+				"timezone":            map[string]interface{}{"type": "string", "required": false, "description": "IANA timezone such as Europe/Berlin"},
+				"geolocation":         map[string]interface{}{"type": "object", "required": false, "description": "{latitude, longitude, accuracy}"},
+				"color_scheme":        map[string]interface{}{"type": "string", "required": false, "description": "light, dark or no-preference"},
+				"reduced_motion":      map[string]interface{}{"type": "string", "required": false, "description": "reduce or no-preference"},
+				"network":             map[string]interface{}{"type": "string", "required": false, "description": "online, offline, slow-3g, fast-3g or 4g"},
+				"reset":               map[string]interface{}{"type": "boolean", "required": false, "description": "Clear previous emulation first (User-Agent is kept)"},
+			},
+			"example": map[string]interface{}{
+				"device":      "iPhone X",
+				"locale":      "de-DE",
+				"timezone":    "Europe/Berlin",
+				"geolocation": map[string]interface{}{"latitude": 52.52, "longitude": 13.405},
+			},
+			"returns": "Operation result with the applied profile",
+			"note":    "Reload the page for locale, timezone and Accept-Language changes to take full effect",
+		},
+		{
+			"name":        "storage-state",
+			"method":      "GET",
+			"endpoint":    "/api/v1/executor/storage-state",
+			"description": "Capture cookies plus localStorage, sessionStorage and optionally IndexedDB of the current page's browser context",
+			"parameters": map[string]interface{}{
+				"origins":            map[string]interface{}{"type": "string", "required": false, "description": "Comma-separated additional origins to capture (localStorage and IndexedDB)"},
+				"include_indexed_db": map[string]interface{}{"type": "boolean", "required": false, "description": "Also capture IndexedDB databases"},
+				"save_as":            map[string]interface{}{"type": "string", "required": false, "description": "Save the state under this name (replaces a state with the same name)"},
+			},
+			"returns": "storage_state: {cookies, origins: [{origin, local_storage, session_storage, indexed_db}]}",
+			"note":    "POST /storage-state with {\"state\": {...}} or {\"id\": \"...\"} restores a state; reload the page afterwards",
+		},
+		{
+			"name":        "sessions",
+			"method":      "POST",
+			"endpoint":    "/api/v1/executor/sessions",
+			"description": "Create an isolated executor session with its own page (or incognito context), RefID cache and recorder",
+			"parameters": map[string]interface{}{
+				"instance_id": map[string]interface{}{
+					"type":        "string",
+					"required":    false,
+					"description": "Browser instance for the session (default: current instance)",
+				},
+				"isolation": map[string]interface{}{
+					"type":        "string",
+					"required":    false,
+					"description": "page (new tab, shares cookies) or incognito (separate cookies and storage)",
+					"default":     "page",
+				},
+				"idle_timeout": map[string]interface{}{
+					"type":        "number",
+					"required":    false,
+					"description": "Close the session after this many idle seconds",
+					"default":     1800,
+				},
+			},
+			"returns": "session_id to pass on other executor calls (?session_id=, X-Session-ID header or session_id in the JSON body)",
+			"note":    "GET /sessions lists sessions, DELETE /sessions/:id closes a session",
+		},
 	}
 
 	// 如果指定了特定命令，只返回该命令的信息
@@ -4165,7 +5173,7 @@ func (h *Handler) ExecutorNavigate(c *gin.Context) {
 	}
 
 	// 创建 executor 实例
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 
 	// 设置选项
 	var opts *executor2.NavigateOptions
@@ -4201,6 +5209,8 @@ func (h *Handler) ExecutorClick(c *gin.Context) {
 		Timeout     int    `json:"timeout"` // 秒
 		Button      string `json:"button"`  // left, right, middle
 		ClickCount  int    `json:"click_count"`
+		// 返回相对上一次快照的差异，而非完整快照
+		SnapshotDiff bool `json:"snapshot_diff"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -4208,13 +5218,14 @@ func (h *Handler) ExecutorClick(c *gin.Context) {
 		return
 	}
 
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 
 	opts := &executor2.ClickOptions{
-		WaitVisible: req.WaitVisible,
-		WaitEnabled: req.WaitEnabled,
-		Button:      req.Button,
-		ClickCount:  req.ClickCount,
+		WaitVisible:  req.WaitVisible,
+		WaitEnabled:  req.WaitEnabled,
+		Button:       req.Button,
+		ClickCount:   req.ClickCount,
+		SnapshotDiff: req.SnapshotDiff,
 	}
 	if req.Timeout > 0 {
 		opts.Timeout = time.Duration(req.Timeout) * time.Second
@@ -4241,6 +5252,8 @@ func (h *Handler) ExecutorType(c *gin.Context) {
 		WaitVisible bool   `json:"wait_visible"`
 		Timeout     int    `json:"timeout"` // 秒
 		Delay       int    `json:"delay"`   // 毫秒
+		// 返回相对上一次快照的差异，而非完整快照
+		SnapshotDiff bool `json:"snapshot_diff"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -4248,11 +5261,12 @@ func (h *Handler) ExecutorType(c *gin.Context) {
 		return
 	}
 
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 
 	opts := &executor2.TypeOptions{
-		Clear:       req.Clear,
-		WaitVisible: req.WaitVisible,
+		Clear:        req.Clear,
+		WaitVisible:  req.WaitVisible,
+		SnapshotDiff: req.SnapshotDiff,
 	}
 	if req.Timeout > 0 {
 		opts.Timeout = time.Duration(req.Timeout) * time.Second
@@ -4280,6 +5294,8 @@ func (h *Handler) ExecutorSelect(c *gin.Context) {
 		Value       string `json:"value" binding:"required"`
 		WaitVisible bool   `json:"wait_visible"`
 		Timeout     int    `json:"timeout"` // 秒
+		// 返回相对上一次快照的差异，而非完整快照
+		SnapshotDiff bool `json:"snapshot_diff"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -4287,10 +5303,11 @@ func (h *Handler) ExecutorSelect(c *gin.Context) {
 		return
 	}
 
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 
 	opts := &executor2.SelectOptions{
-		WaitVisible: req.WaitVisible,
+		WaitVisible:  req.WaitVisible,
+		SnapshotDiff: req.SnapshotDiff,
 	}
 	if req.Timeout > 0 {
 		opts.Timeout = time.Duration(req.Timeout) * time.Second
@@ -4319,7 +5336,7 @@ func (h *Handler) ExecutorGetText(c *gin.Context) {
 		return
 	}
 
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 	result, err := executor.GetText(c.Request.Context(), req.Identifier)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -4343,7 +5360,7 @@ func (h *Handler) ExecutorGetValue(c *gin.Context) {
 		return
 	}
 
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 	result, err := executor.GetValue(c.Request.Context(), req.Identifier)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -4369,7 +5386,7 @@ func (h *Handler) ExecutorWaitFor(c *gin.Context) {
 		return
 	}
 
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 
 	opts := &executor2.WaitForOptions{
 		State: req.State,
@@ -4390,6 +5407,46 @@ func (h *Handler) ExecutorWaitFor(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// ExecutorAssert 断言页面状态（元素可见性、文本、属性、数量、URL、标题或变量）
+// 软断言未通过时返回 200 且 success 为 false；硬断言未通过时返回 422
+func (h *Handler) ExecutorAssert(c *gin.Context) {
+	var req struct {
+		models.AssertOptions
+		Identifier string            `json:"identifier"` // 元素类断言的目标（RefID、CSS、XPath）
+		Variables  map[string]string `json:"variables"`  // variable 断言使用的变量，也用于替换 expected 中的 ${变量}
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest"})
+		return
+	}
+	if err := browser.ValidateAssertOptions(&req.AssertOptions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest", "detail": err.Error()})
+		return
+	}
+	if browser.AssertionNeedsElement(req.Kind) && req.Identifier == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest", "detail": req.Kind + " assertion requires identifier"})
+		return
+	}
+
+	executor := h.sessionExecutor(c)
+	result, err := executor.Assert(c.Request.Context(), req.Identifier, &req.AssertOptions, req.Variables)
+	if err != nil {
+		var assertErr *browser.AssertionError
+		if errors.As(err, &assertErr) {
+			c.JSON(http.StatusUnprocessableEntity, result)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "error.assertFailed",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // ExecutorExtract 提取数据
 func (h *Handler) ExecutorExtract(c *gin.Context) {
 	var req struct {
@@ -4405,7 +5462,7 @@ func (h *Handler) ExecutorExtract(c *gin.Context) {
 		return
 	}
 
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 
 	opts := &executor2.ExtractOptions{
 		Selector: req.Selector,
@@ -4440,7 +5497,7 @@ func (h *Handler) ExecutorHover(c *gin.Context) {
 		return
 	}
 
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 
 	opts := &executor2.HoverOptions{
 		WaitVisible: req.WaitVisible,
@@ -4463,7 +5520,7 @@ func (h *Handler) ExecutorHover(c *gin.Context) {
 
 // ExecutorScrollToBottom 滚动到底部
 func (h *Handler) ExecutorScrollToBottom(c *gin.Context) {
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 	result, err := executor.ScrollToBottom(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -4478,7 +5535,7 @@ func (h *Handler) ExecutorScrollToBottom(c *gin.Context) {
 
 // ExecutorGoBack 后退
 func (h *Handler) ExecutorGoBack(c *gin.Context) {
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 	result, err := executor.GoBack(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -4493,7 +5550,7 @@ func (h *Handler) ExecutorGoBack(c *gin.Context) {
 
 // ExecutorGoForward 前进
 func (h *Handler) ExecutorGoForward(c *gin.Context) {
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 	result, err := executor.GoForward(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -4508,7 +5565,7 @@ func (h *Handler) ExecutorGoForward(c *gin.Context) {
 
 // ExecutorReload 刷新页面
 func (h *Handler) ExecutorReload(c *gin.Context) {
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 	result, err := executor.Reload(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -4525,8 +5582,9 @@ func (h *Handler) ExecutorReload(c *gin.Context) {
 func (h *Handler) ExecutorScreenshot(c *gin.Context) {
 	var req struct {
 		FullPage bool   `json:"full_page"`
-		Quality  int    `json:"quality"` // 1-100
-		Format   string `json:"format"`  // png, jpeg
+		Quality  int    `json:"quality"`  // 1-100
+		Format   string `json:"format"`   // png, jpeg
+		Annotate bool   `json:"annotate"` // 标注可交互元素的 RefID（Set-of-Marks）
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -4534,12 +5592,13 @@ func (h *Handler) ExecutorScreenshot(c *gin.Context) {
 		return
 	}
 
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 
 	opts := &executor2.ScreenshotOptions{
 		FullPage: req.FullPage,
 		Quality:  req.Quality,
 		Format:   req.Format,
+		Annotate: req.Annotate,
 	}
 
 	result, err := executor.Screenshot(c.Request.Context(), opts)
@@ -4554,6 +5613,46 @@ func (h *Handler) ExecutorScreenshot(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// ExecutorPDF 将当前页面打印为 PDF
+func (h *Handler) ExecutorPDF(c *gin.Context) {
+	var req models.PDFOptions
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest"})
+		return
+	}
+	if err := browser.ValidatePDFOptions(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest", "detail": err.Error()})
+		return
+	}
+
+	executor := h.sessionExecutor(c)
+	result, err := executor.PDF(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "error.pdfFailed",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExecutorArchive 将当前页面保存为 MHTML 存档
+func (h *Handler) ExecutorArchive(c *gin.Context) {
+	executor := h.sessionExecutor(c)
+	result, err := executor.Archive(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "error.archiveFailed",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // ExecutorEvaluate 执行 JavaScript
 func (h *Handler) ExecutorEvaluate(c *gin.Context) {
 	var req struct {
@@ -4565,7 +5664,7 @@ func (h *Handler) ExecutorEvaluate(c *gin.Context) {
 		return
 	}
 
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 	result, err := executor.Evaluate(c.Request.Context(), req.Script)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -4593,7 +5692,7 @@ func (h *Handler) ExecutorPressKey(c *gin.Context) {
 		return
 	}
 
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 
 	opts := &executor2.PressKeyOptions{
 		Ctrl:  req.Ctrl,
@@ -4626,7 +5725,7 @@ func (h *Handler) ExecutorResize(c *gin.Context) {
 		return
 	}
 
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 	result, err := executor.Resize(c.Request.Context(), req.Width, req.Height)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -4639,9 +5738,91 @@ func (h *Handler) ExecutorResize(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// ExecutorEmulate 切换当前页面的仿真配置
+func (h *Handler) ExecutorEmulate(c *gin.Context) {
+	var req struct {
+		models.EmulationProfile
+		Reset bool `json:"reset"` // 先清除页面已有的仿真设置
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest"})
+		return
+	}
+
+	var profile *models.EmulationProfile
+	if req.EmulationProfile != (models.EmulationProfile{}) {
+		profile = &req.EmulationProfile
+	}
+
+	executor := h.sessionExecutor(c)
+	result, err := executor.Emulate(c.Request.Context(), profile, req.Reset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "error.emulateFailed",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExecutorGetStorageState 捕获当前页面所在上下文的存储状态
+// 查询参数: origins（逗号分隔的其他源）、include_indexed_db、save_as（以该名称保存）
+func (h *Handler) ExecutorGetStorageState(c *gin.Context) {
+	opts := browser.StorageCaptureOptions{
+		IndexedDB: c.Query("include_indexed_db") == "true",
+	}
+	if origins := c.Query("origins"); origins != "" {
+		opts.Origins = strings.Split(origins, ",")
+	}
+
+	executor := h.sessionExecutor(c)
+	result, err := executor.GetStorageState(c.Request.Context(), opts, c.Query("save_as"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "error.getStorageStateFailed",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExecutorSetStorageState 将存储状态写入当前页面所在的上下文
+func (h *Handler) ExecutorSetStorageState(c *gin.Context) {
+	var req struct {
+		ID    string               `json:"id"`    // 已保存的存储状态 ID
+		State *models.StorageState `json:"state"` // 直接传入的存储状态，优先于 id
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest"})
+		return
+	}
+	if req.State == nil && req.ID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest", "detail": "state or id is required"})
+		return
+	}
+
+	executor := h.sessionExecutor(c)
+	result, err := executor.SetStorageState(c.Request.Context(), req.State, req.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "error.setStorageStateFailed",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // ExecutorGetPageInfo 获取页面信息
 func (h *Handler) ExecutorGetPageInfo(c *gin.Context) {
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 	result, err := executor.GetPageInfo(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -4656,7 +5837,7 @@ func (h *Handler) ExecutorGetPageInfo(c *gin.Context) {
 
 // ExecutorGetPageContent 获取页面内容
 func (h *Handler) ExecutorGetPageContent(c *gin.Context) {
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 	result, err := executor.GetPageContent(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -4671,7 +5852,7 @@ func (h *Handler) ExecutorGetPageContent(c *gin.Context) {
 
 // ExecutorGetPageText 获取页面文本
 func (h *Handler) ExecutorGetPageText(c *gin.Context) {
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 	result, err := executor.GetPageText(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -4685,8 +5866,46 @@ func (h *Handler) ExecutorGetPageText(c *gin.Context) {
 }
 
 // ExecutorGetAccessibilitySnapshot 获取可访问性快照
+// diff=true 时重新获取快照并返回相对该页面上一次快照的差异（未变化元素沿用原 RefID）
+// max_tokens / max_chars / viewport_only / root / list_limit 按预算和范围裁剪快照文本
 func (h *Handler) ExecutorGetAccessibilitySnapshot(c *gin.Context) {
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
+	if c.Query("diff") == "true" {
+		diff, _, err := executor.GetAccessibilitySnapshotDiff(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":  "error.getAccessibilitySnapshotFailed",
+				"detail": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":  true,
+			"diff":     diff,
+			"snapshot": diff.SerializeToText(),
+		})
+		return
+	}
+
+	opts := &executor2.SnapshotSerializeOptions{
+		ViewportOnly: c.Query("viewport_only") == "true",
+		Root:         c.Query("root"),
+	}
+	for name, target := range map[string]*int{"max_tokens": &opts.MaxTokens, "max_chars": &opts.MaxChars, "list_limit": &opts.ListLimit} {
+		if value := c.Query(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":  "error.invalidRequest",
+					"detail": fmt.Sprintf("%s must be a non-negative integer", name),
+				})
+				return
+			}
+			*target = n
+		}
+	}
+
 	snapshot, err := executor.GetAccessibilitySnapshot(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -4696,15 +5915,24 @@ func (h *Handler) ExecutorGetAccessibilitySnapshot(c *gin.Context) {
 		return
 	}
 
+	text, err := executor.SerializeSnapshot(c.Request.Context(), snapshot, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "error.getAccessibilitySnapshotFailed",
+			"detail": err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success":  true,
-		"snapshot": snapshot.SerializeToSimpleText(),
+		"snapshot": text,
 	})
 }
 
 // ExecutorGetClickableElements 获取可点击元素
 func (h *Handler) ExecutorGetClickableElements(c *gin.Context) {
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 	elements, err := executor.GetClickableElements(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -4723,7 +5951,7 @@ func (h *Handler) ExecutorGetClickableElements(c *gin.Context) {
 
 // ExecutorGetInputElements 获取输入元素
 func (h *Handler) ExecutorGetInputElements(c *gin.Context) {
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 	elements, err := executor.GetInputElements(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -4751,7 +5979,7 @@ func (h *Handler) ExecutorBatch(c *gin.Context) {
 		return
 	}
 
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 	result, err := executor.ExecuteBatch(c.Request.Context(), req.Operations)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -4777,7 +6005,7 @@ func (h *Handler) ExecutorTabs(c *gin.Context) {
 		return
 	}
 
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 
 	opts := &executor2.TabsOptions{
 		Action: executor2.TabsAction(req.Action),
@@ -4810,7 +6038,7 @@ func (h *Handler) ExecutorFillForm(c *gin.Context) {
 		return
 	}
 
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 
 	opts := &executor2.FillFormOptions{
 		Fields: req.Fields,
@@ -4833,9 +6061,35 @@ func (h *Handler) ExecutorFillForm(c *gin.Context) {
 }
 
 // ExecutorConsoleMessages 获取控制台消息
+// 查询参数: level（逗号分隔）、since（RFC3339 或 Unix 毫秒）、cursor、limit、clear
 func (h *Handler) ExecutorConsoleMessages(c *gin.Context) {
-	executor := h.executor.WithContext(c.Request.Context())
-	result, err := executor.GetConsoleMessages(c.Request.Context())
+	opts := &executor2.ConsoleMessagesOptions{
+		Clear: c.Query("clear") == "true",
+	}
+	if level := c.Query("level"); level != "" {
+		opts.Levels = strings.Split(level, ",")
+	}
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		since, err := executor2.ParseSinceParam(sinceStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest", "detail": err.Error()})
+			return
+		}
+		opts.Since = since
+	}
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		if cursor, err := strconv.ParseInt(cursorStr, 10, 64); err == nil && cursor > 0 {
+			opts.Cursor = cursor
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
+			opts.Limit = limit
+		}
+	}
+
+	executor := h.sessionExecutor(c)
+	result, err := executor.GetConsoleMessages(c.Request.Context(), opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":  "error.getConsoleMessagesFailed",
@@ -4848,9 +6102,13 @@ func (h *Handler) ExecutorConsoleMessages(c *gin.Context) {
 }
 
 // ExecutorNetworkRequests 获取网络请求
+// 查询参数: url、method、status（404/4xx/400-499）、resource_type（逗号分隔）、state、cursor、limit、include_body、clear
 func (h *Handler) ExecutorNetworkRequests(c *gin.Context) {
-	executor := h.executor.WithContext(c.Request.Context())
-	result, err := executor.GetNetworkRequests(c.Request.Context())
+	opts := networkOptionsFromQuery(c)
+	opts.Clear = c.Query("clear") == "true"
+
+	executor := h.sessionExecutor(c)
+	result, err := executor.GetNetworkRequests(c.Request.Context(), opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":  "error.getNetworkRequestsFailed",
@@ -4862,6 +6120,132 @@ func (h *Handler) ExecutorNetworkRequests(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// ExecutorNetworkResponseBody 按需获取某个请求的响应体
+func (h *Handler) ExecutorNetworkResponseBody(c *gin.Context) {
+	requestID := c.Query("request_id")
+	if requestID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest", "detail": "request_id is required"})
+		return
+	}
+
+	executor := h.sessionExecutor(c)
+	result, err := executor.GetNetworkResponseBody(c.Request.Context(), requestID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "error.getNetworkResponseBodyFailed",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExecutorNetworkHAR 将网络请求日志导出为 HAR 1.2（支持与 network-requests 相同的过滤参数）
+func (h *Handler) ExecutorNetworkHAR(c *gin.Context) {
+	opts := networkOptionsFromQuery(c)
+
+	executor := h.sessionExecutor(c)
+	har, err := executor.ExportHAR(c.Request.Context(), opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "error.exportHARFailed",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	if c.Query("download") == "true" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=network_%s.har", time.Now().Format("20060102_150405")))
+	}
+	c.JSON(http.StatusOK, har)
+}
+
+// networkOptionsFromQuery 从查询参数构建网络请求查询选项
+func networkOptionsFromQuery(c *gin.Context) *executor2.NetworkRequestsOptions {
+	opts := &executor2.NetworkRequestsOptions{
+		URL:         c.Query("url"),
+		Method:      c.Query("method"),
+		Status:      c.Query("status"),
+		State:       c.Query("state"),
+		IncludeBody: c.Query("include_body") == "true",
+	}
+	if resourceType := c.Query("resource_type"); resourceType != "" {
+		opts.ResourceTypes = strings.Split(resourceType, ",")
+	}
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		if cursor, err := strconv.ParseInt(cursorStr, 10, 64); err == nil && cursor > 0 {
+			opts.Cursor = cursor
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
+			opts.Limit = limit
+		}
+	}
+	return opts
+}
+
+// ExecutorGetRouteRules 获取当前页面的请求拦截规则
+func (h *Handler) ExecutorGetRouteRules(c *gin.Context) {
+	executor := h.sessionExecutor(c)
+	result, err := executor.GetRouteRules(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "error.getRouteRulesFailed",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExecutorSetRouteRules 设置当前页面的请求拦截规则（替换已有规则）
+func (h *Handler) ExecutorSetRouteRules(c *gin.Context) {
+	var req struct {
+		Rules []models.RouteRule `json:"rules"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest"})
+		return
+	}
+	for i, rule := range req.Rules {
+		if err := browser.ValidateRouteRule(rule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest", "detail": fmt.Sprintf("rule %d: %s", i, err.Error())})
+			return
+		}
+	}
+
+	executor := h.sessionExecutor(c)
+	result, err := executor.SetRouteRules(c.Request.Context(), req.Rules)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "error.setRouteRulesFailed",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExecutorClearRouteRules 清除当前页面的请求拦截规则
+func (h *Handler) ExecutorClearRouteRules(c *gin.Context) {
+	executor := h.sessionExecutor(c)
+	result, err := executor.SetRouteRules(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "error.setRouteRulesFailed",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // ExecutorHandleDialog 处理对话框
 func (h *Handler) ExecutorHandleDialog(c *gin.Context) {
 	var req struct {
@@ -4874,7 +6258,7 @@ func (h *Handler) ExecutorHandleDialog(c *gin.Context) {
 		return
 	}
 
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 	result, err := executor.HandleDialog(c.Request.Context(), req.Accept, req.Text)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -4899,7 +6283,7 @@ func (h *Handler) ExecutorFileUpload(c *gin.Context) {
 		return
 	}
 
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 	result, err := executor.FileUpload(c.Request.Context(), req.Identifier, req.FilePaths)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -4924,7 +6308,7 @@ func (h *Handler) ExecutorDrag(c *gin.Context) {
 		return
 	}
 
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 	result, err := executor.Drag(c.Request.Context(), req.FromIdentifier, req.ToIdentifier)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -4939,7 +6323,7 @@ func (h *Handler) ExecutorDrag(c *gin.Context) {
 
 // ExecutorClosePage 关闭当前页面
 func (h *Handler) ExecutorClosePage(c *gin.Context) {
-	executor := h.executor.WithContext(c.Request.Context())
+	executor := h.sessionExecutor(c)
 	result, err := executor.ClosePage(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -4952,6 +6336,108 @@ func (h *Handler) ExecutorClosePage(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// executorContextKey 会话中间件解析出的执行器在 gin.Context 中的键
+const executorContextKey = "executor"
+
+// ExecutorSessionMiddleware 按请求中的 session_id 选择执行器
+// session_id 可以放在查询参数、X-Session-ID 请求头或 JSON 请求体中，未指定时使用共享执行器
+func (h *Handler) ExecutorSessionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.Query("session_id")
+		if sessionID == "" {
+			sessionID = c.GetHeader("X-Session-ID")
+		}
+		if sessionID == "" && c.Request.Body != nil && strings.Contains(c.ContentType(), "json") {
+			body, err := io.ReadAll(c.Request.Body)
+			if err == nil {
+				var probe struct {
+					SessionID string `json:"session_id"`
+				}
+				_ = json.Unmarshal(body, &probe)
+				sessionID = probe.SessionID
+			}
+			// 还原请求体，供处理函数绑定参数
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		if sessionID != "" {
+			session, err := h.sessions.Get(sessionID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+					"error":  "error.sessionNotFound",
+					"detail": err.Error(),
+				})
+				return
+			}
+			c.Set(executorContextKey, session.Executor)
+		}
+		c.Next()
+	}
+}
+
+// sessionExecutor 返回当前请求使用的执行器（会话执行器或共享执行器）
+func (h *Handler) sessionExecutor(c *gin.Context) *executor2.Executor {
+	if exec, ok := c.Get(executorContextKey); ok {
+		return exec.(*executor2.Executor).WithContext(c.Request.Context())
+	}
+	return h.executor.WithContext(c.Request.Context())
+}
+
+// ExecutorCreateSession 创建执行器会话
+func (h *Handler) ExecutorCreateSession(c *gin.Context) {
+	var req struct {
+		InstanceID  string `json:"instance_id"`  // 实例 ID，为空时使用当前实例
+		Isolation   string `json:"isolation"`    // page 或 incognito
+		IdleTimeout int    `json:"idle_timeout"` // 空闲超时（秒），默认 1800
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest"})
+		return
+	}
+
+	session, err := h.sessions.Create(c.Request.Context(), &executor2.SessionOptions{
+		InstanceID:  req.InstanceID,
+		Isolation:   req.Isolation,
+		IdleTimeout: time.Duration(req.IdleTimeout) * time.Second,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "error.createSessionFailed",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id":   session.ID,
+		"instance_id":  session.InstanceID,
+		"isolation":    session.Isolation,
+		"idle_timeout": int(session.IdleTimeout / time.Second),
+	})
+}
+
+// ExecutorListSessions 列出执行器会话
+func (h *Handler) ExecutorListSessions(c *gin.Context) {
+	sessions := h.sessions.List()
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+		"count":    len(sessions),
+	})
+}
+
+// ExecutorCloseSession 关闭执行器会话
+func (h *Handler) ExecutorCloseSession(c *gin.Context) {
+	if err := h.sessions.Close(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":  "error.sessionNotFound",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success.sessionClosed"})
+}
+
 // ExportExecutorSkill 导出 Executor API 为 Claude Skills 的 SKILL.md 格式
 func (h *Handler) ExportExecutorSkill(c *gin.Context) {
 	// 获取服务端地址
@@ -5193,35 +6679,51 @@ func generateExecutorSkillMD(host string) string {
 	sb.WriteString("- `POST /extract` - Extract data from elements (supports multiple elements, custom fields)\n")
 	sb.WriteString("- `POST /get-text` - Get element text content\n")
 	sb.WriteString("- `POST /get-value` - Get input element value\n")
+	sb.WriteString("- `POST /assert` - Assert element visible/hidden, text, attribute, count, URL, title or variable (`mode`: hard returns 422 on failure, soft returns success=false)\n")
 	sb.WriteString("- `GET /page-info` - Get page URL and title\n")
 	sb.WriteString("- `GET /page-text` - Get all page text\n")
 	sb.WriteString("- `GET /page-content` - Get full HTML\n\n")
 
 	// 页面分析类
 	sb.WriteString("### Page Analysis\n")
-	sb.WriteString("- `GET /snapshot` - Get accessibility snapshot (⭐ **ALWAYS call after navigation**)\n")
+	sb.WriteString("- `GET /snapshot` - Get accessibility snapshot (⭐ **ALWAYS call after navigation**); `?diff=true` returns only elements added, removed or changed since the previous snapshot; `max_tokens` (at least 100), `viewport_only`, `root` (@e12, landmark like `main`, or `list#2`) and `list_limit` trim large pages\n")
 	sb.WriteString("- `GET /clickable-elements` - Get all clickable elements\n")
 	sb.WriteString("- `GET /input-elements` - Get all input elements\n\n")
 
 	// 高级功能类
 	sb.WriteString("### Advanced\n")
-	sb.WriteString("- `POST /screenshot` - Take page screenshot (base64 encoded)\n")
+	sb.WriteString("- `POST /screenshot` - Take page screenshot (base64 encoded); `annotate: true` labels interactive elements with their RefIDs and returns `marks` and `legend`\n")
+	sb.WriteString("- `POST /pdf` - Print page to PDF (paper format, margins, header/footer templates, background; headless only)\n")
+	sb.WriteString("- `POST /archive` - Save page as a single MHTML archive\n")
 	sb.WriteString("- `POST /evaluate` - Execute JavaScript code\n")
 	sb.WriteString("- `POST /batch` - Execute multiple operations in sequence\n")
 	sb.WriteString("- `POST /scroll-to-bottom` - Scroll to page bottom\n")
 	sb.WriteString("- `POST /resize` - Resize browser window\n")
+	sb.WriteString("- `POST /emulate` - Emulate device, locale, timezone, geolocation, color scheme, reduced motion or network conditions (`reset: true` clears previous emulation)\n")
+	sb.WriteString("- `GET /storage-state` - Capture cookies, localStorage, sessionStorage and optionally IndexedDB (`origins`, `include_indexed_db`, `save_as`)\n")
+	sb.WriteString("- `POST /storage-state` - Restore a storage state (`state` object or saved `id`), then reload the page\n")
 	sb.WriteString("- `POST /tabs` - Manage browser tabs (list, new, switch, close)\n")
 	sb.WriteString("- `POST /fill-form` - Intelligently fill multiple form fields at once\n\n")
 
 	// 调试和监控类
 	sb.WriteString("### Debug & Monitoring\n")
-	sb.WriteString("- `GET /console-messages` - Get browser console messages (logs, warnings, errors)\n")
-	sb.WriteString("- `GET /network-requests` - Get network requests made by the page\n")
+	sb.WriteString("- `GET /console-messages` - Get buffered console messages, exceptions and browser logs (filters: `level`, `since`, `cursor`, `limit`, `clear`)\n")
+	sb.WriteString("- `GET /network-requests` - Get the page's network journal (filters: `url`, `method`, `status`, `resource_type`, `state`, `cursor`, `limit`, `include_body`)\n")
+	sb.WriteString("- `GET /network-requests/body?request_id=...` - Get the response body of a request\n")
+	sb.WriteString("- `GET /network-requests/har` - Export the network journal as HAR 1.2\n")
+	sb.WriteString("- `POST /route-rules` - Block, fulfill, modify headers of or delay matching requests (`GET` lists rules with hit counts, `DELETE` clears)\n")
 	sb.WriteString("- `POST /handle-dialog` - Configure JavaScript dialog (alert, confirm, prompt) handling\n")
 	sb.WriteString("- `POST /file-upload` - Upload files to input elements\n")
 	sb.WriteString("- `POST /drag` - Drag and drop elements\n")
 	sb.WriteString("- `POST /close-page` - Close the current page/tab\n\n")
 
+	// 会话
+	sb.WriteString("### Sessions\n")
+	sb.WriteString("- `POST /sessions` - Create an isolated session (`instance_id`, `isolation`: page|incognito, `idle_timeout` seconds)\n")
+	sb.WriteString("- `GET /sessions` - List sessions\n")
+	sb.WriteString("- `DELETE /sessions/:id` - Close a session\n")
+	sb.WriteString("- Pass `session_id` (query parameter, `X-Session-ID` header or JSON body field) on any executor call to run it in that session\n\n")
+
 	// 元素定位方式
 	sb.WriteString("## Element Identification\n\n")
 	sb.WriteString("You can identify elements using:\n\n")
@@ -5251,7 +6753,7 @@ func generateExecutorSkillMD(host string) string {
 	sb.WriteString("**During automation:**\n")
 	sb.WriteString("- **Always call `/snapshot` after navigation** to get page structure and RefIDs\n")
 	sb.WriteString("- **Prefer RefIDs** (like `@e1`) over CSS selectors for reliability and stability\n")
-	sb.WriteString("- **Re-snapshot after page changes** to get updated RefIDs\n")
+	sb.WriteString("- **Re-snapshot after page changes** to get updated RefIDs (use `/snapshot?diff=true` or `snapshot_diff: true` on click/type/select to receive only what changed)\n")
 	sb.WriteString("- **Use `/wait`** for dynamic content that loads asynchronously\n")
 	sb.WriteString("- **Check element states** before interaction (visible, enabled)\n")
 	sb.WriteString("- **Use `/batch`** for multiple sequential operations to improve efficiency\n\n")
@@ -5478,6 +6980,15 @@ func (h *Handler) CreateBrowserInstance(c *gin.Context) {
 		return
 	}
 
+	if err := browser.ValidatePlaybackSettings(&instance); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest", "detail": err.Error()})
+		return
+	}
+	if err := browser.ValidateEmulationProfile(instance.Emulation); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest", "detail": err.Error()})
+		return
+	}
+
 	// 生成ID
 	if instance.ID == "" {
 		instance.ID = fmt.Sprintf("instance-%d", time.Now().UnixNano())
@@ -5554,6 +7065,15 @@ func (h *Handler) UpdateBrowserInstance(c *gin.Context) {
 		return
 	}
 
+	if err := browser.ValidatePlaybackSettings(&instance); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest", "detail": err.Error()})
+		return
+	}
+	if err := browser.ValidateEmulationProfile(instance.Emulation); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest", "detail": err.Error()})
+		return
+	}
+
 	instance.ID = id
 	if err := h.db.UpdateBrowserInstance(id, &instance); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error.updateFailed", "detail": err.Error()})
@@ -5586,6 +7106,13 @@ func (h *Handler) DeleteBrowserInstance(c *gin.Context) {
 	})
 }
 
+// GetPlaybackStats 获取各实例的回放并发状态（执行中和排队中的回放数量）
+func (h *Handler) GetPlaybackStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"playbacks": h.browserManager.GetPlaybackStats(),
+	})
+}
+
 // StartBrowserInstance 启动浏览器实例
 func (h *Handler) StartBrowserInstance(c *gin.Context) {
 	id := c.Param("id")
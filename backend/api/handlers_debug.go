@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/browserwing/browserwing/models"
+	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/browserwing/browserwing/services/browser"
+	"github.com/gin-gonic/gin"
+)
+
+// StartScriptDebug 以调试模式异步回放脚本，在断点、单步或失败时暂停等待调试命令
+func (h *Handler) StartScriptDebug(c *gin.Context) {
+	var req struct {
+		Params         map[string]string  `json:"params"`
+		InstanceID     string             `json:"instance_id"`
+		RouteRules     []models.RouteRule `json:"route_rules"`
+		StorageStateID string             `json:"storage_state_id"`
+		Breakpoints    []int              `json:"breakpoints"` // 步骤序号，从 1 开始
+		Step           bool               `json:"step"`        // 单步模式：第一步前即暂停
+	}
+	// 允许空请求体；请求体格式错误时拒绝，避免以空断点静默开始回放
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest", "detail": err.Error()})
+		return
+	}
+
+	script, err := h.db.GetScript(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "error.scriptNotFound"})
+		return
+	}
+
+	scriptToRun, err := script.WithParams(req.Params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidScriptParams", "detail": err.Error()})
+		return
+	}
+
+	if !h.browserManager.IsInstanceRunning(req.InstanceID) {
+		logger.Info(c, "Browser not running, starting...")
+		if err := h.browserManager.StartInstance(c, req.InstanceID); err != nil {
+			logger.Error(c.Request.Context(), "Failed to start browser: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error.playScriptFailed", "detail": err.Error()})
+			return
+		}
+	}
+
+	session := browser.NewDebugSession(script.ID, req.Breakpoints, req.Step)
+	// 回放独立于本次请求运行，由调试命令或 abort 结束
+	ctx, cancel := context.WithCancel(context.Background())
+	session.Bind(cancel)
+	h.browserManager.DebugSessions().Add(session)
+
+	go func() {
+		defer cancel()
+		result, page, err := h.browserManager.PlayScriptWithOptions(ctx, scriptToRun, req.InstanceID, &browser.PlayOptions{
+			RouteRules:     req.RouteRules,
+			StorageStateID: req.StorageStateID,
+			Debugger:       session,
+		})
+		if err != nil {
+			logger.Warn(ctx, "Debug playback of script %s ended with error: %v", script.Name, err)
+		}
+		session.Finish(result, err)
+		if page != nil {
+			if err := h.browserManager.CloseActivePage(ctx, page); err != nil {
+				logger.Warn(ctx, "Failed to close page: %v", err)
+			}
+		}
+	}()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success.debugSessionStarted",
+		"session": session.Info(),
+	})
+}
+
+// GetScriptDebugSession 获取调试会话状态（当前暂停位置、断点和调试中修改的步骤）
+func (h *Handler) GetScriptDebugSession(c *gin.Context) {
+	session, ok := h.browserManager.DebugSessions().Get(c.Param("session_id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "error.debugSessionNotFound"})
+		return
+	}
+	c.JSON(http.StatusOK, session.Info())
+}
+
+// SendScriptDebugCommand 发送调试命令（continue / step / skip / retry / abort / breakpoints）
+func (h *Handler) SendScriptDebugCommand(c *gin.Context) {
+	session, ok := h.browserManager.DebugSessions().Get(c.Param("session_id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "error.debugSessionNotFound"})
+		return
+	}
+
+	var cmd browser.DebugCommand
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error.invalidRequest", "detail": err.Error()})
+		return
+	}
+	if err := session.Send(cmd); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "error.debugCommandFailed", "detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success.debugCommandSent",
+		"session": session.Info(),
+	})
+}
+
+// StreamScriptDebugEvents 通过 SSE 推送调试事件，连接时先补发已发生的事件
+func (h *Handler) StreamScriptDebugEvents(c *gin.Context) {
+	session, ok := h.browserManager.DebugSessions().Get(c.Param("session_id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "error.debugSessionNotFound"})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "streaming not supported"})
+		return
+	}
+
+	history, events, unsubscribe := session.Subscribe()
+	defer unsubscribe()
+
+	writeEvent := func(event browser.DebugEvent) {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(c.Writer, "data: %s\n\n", data)
+		flusher.Flush()
+	}
+	for _, event := range history {
+		writeEvent(event)
+	}
+
+	clientGone := c.Request.Context().Done()
+	for {
+		select {
+		case <-clientGone:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			writeEvent(event)
+		case <-time.After(30 * time.Second):
+			fmt.Fprintf(c.Writer, ": keepalive\n\n")
+			flusher.Flush()
+		}
+	}
+}
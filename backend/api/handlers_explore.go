@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/browserwing/browserwing/models"
 	"github.com/gin-gonic/gin"
 )
 
@@ -167,7 +168,7 @@ func (h *Handler) SaveExplorationScript(c *gin.Context) {
 		}
 	}
 
-	if err := h.db.SaveScript(session.GeneratedScript); err != nil {
+	if err := h.db.SaveScriptWithRevision(session.GeneratedScript, revisionInfo(c, models.RevisionSourceExplore, "")); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to save script: %v", err)})
 		return
 	}
@@ -94,11 +94,22 @@ func SetupRouter(handler *Handler, agentHandler interface{}, frontendFS fs.FS, e
 			browserAPI.POST("/instances/:id/start", handler.StartBrowserInstance)
 			browserAPI.POST("/instances/:id/stop", handler.StopBrowserInstance)
 			browserAPI.POST("/instances/:id/switch", handler.SwitchBrowserInstance)
+			browserAPI.GET("/playbacks", handler.GetPlaybackStats) // 各实例的回放并发状态
 		}
 
 		// Cookie 管理
 		api.GET("/cookies/:id", handler.GetCookies)
 
+		// 存储状态管理（Cookie + localStorage/sessionStorage/IndexedDB）
+		storageStates := api.Group("/storage-states")
+		{
+			storageStates.GET("", handler.ListStorageStates)
+			storageStates.GET("/:id", handler.GetStorageState)
+			storageStates.POST("", handler.ImportStorageState)
+			storageStates.POST("/capture", handler.CaptureStorageState) // 从运行中的实例捕获并保存
+			storageStates.DELETE("/:id", handler.DeleteStorageState)
+		}
+
 		// 浏览器配置管理
 		browserConfigs := api.Group("/browser-configs")
 		{
@@ -118,6 +129,13 @@ func SetupRouter(handler *Handler, agentHandler interface{}, frontendFS fs.FS, e
 			scripts.PUT("/:id", handler.UpdateScript)
 			scripts.DELETE("/:id", handler.DeleteScript)
 			scripts.GET("/play/result", handler.GetPlayResult) // 获取回放抓取的数据
+			scripts.POST("/import", handler.ImportScripts)     // 从 Chrome Recorder / Selenium IDE 导入
+
+			// 版本历史相关
+			scripts.GET("/:id/revisions", handler.ListScriptRevisions)                      // 列出历史版本
+			scripts.GET("/:id/revisions/:revision", handler.GetScriptRevision)              // 获取指定版本
+			scripts.POST("/:id/revisions/:revision/restore", handler.RestoreScriptRevision) // 回滚到指定版本
+			scripts.GET("/:id/diff", handler.DiffScriptRevisions)                           // 比较两个版本（from, to）
 
 			// MCP 命令相关
 			scripts.POST("/:id/mcp/generate", handler.GenerateMCPConfig) // AI 生成 MCP 配置
@@ -131,6 +149,19 @@ func SetupRouter(handler *Handler, agentHandler interface{}, frontendFS fs.FS, e
 			// Claude Skills 导出
 			scripts.POST("/export/skill", handler.ExportScriptsSkill) // 导出 SKILL.md
 			scripts.GET("/summary", handler.GetScriptsSummary)        // 获取脚本摘要（用于 Claude Skills）
+
+			// 代码导出
+			scripts.GET("/:id/export/code", handler.ExportScriptCode) // 导出 Playwright / Puppeteer / go-rod 代码（target 参数）
+
+			// 脚本包（zip，包含引用文件、工具配置、MCP 设置和定时任务）
+			scripts.POST("/bundle/export", handler.ExportScriptBundle) // 导出脚本包
+			scripts.POST("/bundle/import", handler.ImportScriptBundle) // 导入脚本包（重新分配 ID 并报告冲突）
+
+			// 调试回放（断点、单步、失败后修改重试）
+			scripts.POST("/:id/debug", handler.StartScriptDebug)                       // 以调试模式开始回放
+			scripts.GET("/debug/:session_id", handler.GetScriptDebugSession)           // 获取调试会话状态
+			scripts.GET("/debug/:session_id/events", handler.StreamScriptDebugEvents)  // SSE 推送调试事件
+			scripts.POST("/debug/:session_id/command", handler.SendScriptDebugCommand) // 发送调试命令
 		}
 
 		// PlayScript接口使用JWT或ApiKey认证（支持内部和外部调用）
@@ -143,10 +174,11 @@ func SetupRouter(handler *Handler, agentHandler interface{}, frontendFS fs.FS, e
 		// 脚本执行记录相关
 		executions := api.Group("/script-executions")
 		{
-			executions.GET("", handler.ListScriptExecutions)                      // 列出执行记录（支持分页和搜索）
-			executions.GET("/:id", handler.GetScriptExecution)                    // 获取单个执行记录
-			executions.DELETE("/:id", handler.DeleteScriptExecution)              // 删除执行记录
-			executions.POST("/batch/delete", handler.BatchDeleteScriptExecutions) // 批量删除
+			executions.GET("", handler.ListScriptExecutions)                                         // 列出执行记录（支持分页和搜索）
+			executions.GET("/:id", handler.GetScriptExecution)                                       // 获取单个执行记录
+			executions.GET("/:id/steps/:index/screenshot", handler.GetScriptExecutionStepScreenshot) // 获取步骤失败截图
+			executions.DELETE("/:id", handler.DeleteScriptExecution)                                 // 删除执行记录
+			executions.POST("/batch/delete", handler.BatchDeleteScriptExecutions)                    // 批量删除
 		}
 
 		// MCP 服务相关（管理接口）
@@ -245,11 +277,17 @@ func SetupRouter(handler *Handler, agentHandler interface{}, frontendFS fs.FS, e
 		// Executor HTTP API（使用 JWT 或 ApiKey 认证，支持外部调用）
 		executorAPI := r.Group("/api/v1/executor")
 		executorAPI.Use(JWTOrApiKeyAuthenticationMiddleware(handler.config, handler.db))
+		executorAPI.Use(handler.ExecutorSessionMiddleware()) // 按 session_id 选择执行器会话
 		{
 			// 帮助和命令列表
 			executorAPI.GET("/help", handler.ExecutorHelp)                // 获取所有可用命令和使用说明
 			executorAPI.GET("/export/skill", handler.ExportExecutorSkill) // 导出 SKILL.md 文件
 
+			// 会话管理（每个会话拥有独立的页面和 RefID 缓存）
+			executorAPI.POST("/sessions", handler.ExecutorCreateSession)      // 创建会话
+			executorAPI.GET("/sessions", handler.ExecutorListSessions)        // 列出会话
+			executorAPI.DELETE("/sessions/:id", handler.ExecutorCloseSession) // 关闭会话
+
 			// 页面导航和操作
 			executorAPI.POST("/navigate", handler.ExecutorNavigate)               // 导航到 URL
 			executorAPI.POST("/click", handler.ExecutorClick)                     // 点击元素
@@ -263,6 +301,9 @@ func SetupRouter(handler *Handler, agentHandler interface{}, frontendFS fs.FS, e
 			executorAPI.POST("/reload", handler.ExecutorReload)                   // 刷新页面
 			executorAPI.POST("/press-key", handler.ExecutorPressKey)              // 按键
 			executorAPI.POST("/resize", handler.ExecutorResize)                   // 调整窗口大小
+			executorAPI.POST("/emulate", handler.ExecutorEmulate)                 // 设备、语言、时区、地理位置和网络仿真
+			executorAPI.GET("/storage-state", handler.ExecutorGetStorageState)    // 捕获 Cookie 和 Web Storage/IndexedDB
+			executorAPI.POST("/storage-state", handler.ExecutorSetStorageState)   // 恢复 Cookie 和 Web Storage/IndexedDB
 
 			// 数据提取和获取
 			executorAPI.POST("/get-text", handler.ExecutorGetText)           // 获取元素文本
@@ -271,6 +312,7 @@ func SetupRouter(handler *Handler, agentHandler interface{}, frontendFS fs.FS, e
 			executorAPI.GET("/page-info", handler.ExecutorGetPageInfo)       // 获取页面信息
 			executorAPI.GET("/page-content", handler.ExecutorGetPageContent) // 获取页面内容
 			executorAPI.GET("/page-text", handler.ExecutorGetPageText)       // 获取页面文本
+			executorAPI.POST("/assert", handler.ExecutorAssert)              // 断言页面状态
 
 			// 可访问性快照和元素查找
 			executorAPI.GET("/snapshot", handler.ExecutorGetAccessibilitySnapshot)       // 获取可访问性快照
@@ -280,6 +322,8 @@ func SetupRouter(handler *Handler, agentHandler interface{}, frontendFS fs.FS, e
 
 			// 高级功能
 			executorAPI.POST("/screenshot", handler.ExecutorScreenshot) // 截图
+			executorAPI.POST("/pdf", handler.ExecutorPDF)               // 打印为 PDF
+			executorAPI.POST("/archive", handler.ExecutorArchive)       // 保存为 MHTML 存档
 			executorAPI.POST("/evaluate", handler.ExecutorEvaluate)     // 执行 JavaScript
 			executorAPI.POST("/batch", handler.ExecutorBatch)           // 批量执行操作
 
@@ -288,12 +332,17 @@ func SetupRouter(handler *Handler, agentHandler interface{}, frontendFS fs.FS, e
 			executorAPI.POST("/fill-form", handler.ExecutorFillForm) // 批量填写表单
 
 			// 调试和监控
-			executorAPI.GET("/console-messages", handler.ExecutorConsoleMessages)     // 获取控制台消息
-			executorAPI.GET("/network-requests", handler.ExecutorNetworkRequests)     // 获取网络请求
-			executorAPI.POST("/handle-dialog", handler.ExecutorHandleDialog)          // 处理JavaScript对话框
-			executorAPI.POST("/file-upload", handler.ExecutorFileUpload)              // 文件上传
-			executorAPI.POST("/drag", handler.ExecutorDrag)                           // 拖拽元素
-			executorAPI.POST("/close-page", handler.ExecutorClosePage)                // 关闭当前页面
+			executorAPI.GET("/console-messages", handler.ExecutorConsoleMessages)          // 获取控制台消息
+			executorAPI.GET("/network-requests", handler.ExecutorNetworkRequests)          // 获取网络请求
+			executorAPI.GET("/network-requests/body", handler.ExecutorNetworkResponseBody) // 获取响应体
+			executorAPI.GET("/network-requests/har", handler.ExecutorNetworkHAR)           // 导出 HAR
+			executorAPI.GET("/route-rules", handler.ExecutorGetRouteRules)                 // 获取请求拦截规则
+			executorAPI.POST("/route-rules", handler.ExecutorSetRouteRules)                // 设置请求拦截规则
+			executorAPI.DELETE("/route-rules", handler.ExecutorClearRouteRules)            // 清除请求拦截规则
+			executorAPI.POST("/handle-dialog", handler.ExecutorHandleDialog)               // 处理JavaScript对话框
+			executorAPI.POST("/file-upload", handler.ExecutorFileUpload)                   // 文件上传
+			executorAPI.POST("/drag", handler.ExecutorDrag)                                // 拖拽元素
+			executorAPI.POST("/close-page", handler.ExecutorClosePage)                     // 关闭当前页面
 		}
 
 	// Admin Skill 导出
@@ -71,6 +71,7 @@ func GetAccessibilitySnapshot(ctx context.Context, page *rod.Page) (*Accessibili
 		Elements:     make(map[string]*AccessibilityNode),
 		AXNodeMap:    make(map[proto.AccessibilityAXNodeID]*proto.AccessibilityAXNode),
 		BackendIDMap: make(map[proto.DOMBackendNodeID]*AccessibilityNode),
+		sessionID:    page.SessionID,
 	}
 
 	// 构建 AX Node 映射
@@ -111,7 +112,7 @@ func GetAccessibilitySnapshot(ctx context.Context, page *rod.Page) (*Accessibili
 
 	// 检查 cursor: pointer 元素并标记为可点击
 	logger.Info(ctx, "[GetAccessibilitySnapshot] Checking cursor:pointer elements...")
-	err = markCursorPointerElements(ctx, page, snapshot)
+	err = markCursorPointerElements(ctx, page, snapshot, nil)
 	if err != nil {
 		logger.Warn(ctx, "[GetAccessibilitySnapshot] Failed to mark cursor:pointer elements: %v", err)
 		// 不返回错误，继续处理
@@ -122,6 +123,13 @@ func GetAccessibilitySnapshot(ctx context.Context, page *rod.Page) (*Accessibili
 		snapshot.Root = snapshot.Elements[string(axTree.Nodes[0].NodeID)]
 	}
 
+	// 遍历子框架（包括跨进程 iframe），框架内的节点带有框架路径
+	// 跨进程 iframe 的会话只在本次快照中使用，完成后分离
+	logger.Info(ctx, "[GetAccessibilitySnapshot] Walking child frames...")
+	targets := &frameTargets{}
+	addFrameSnapshots(ctx, page, snapshot, nil, targets)
+	targets.detach()
+
 	logger.Info(ctx, "[GetAccessibilitySnapshot] Accessibility snapshot extraction completed successfully")
 	return snapshot, nil
 }
@@ -308,7 +316,8 @@ func (tree *AccessibilitySnapshot) GetVisibleElements() []*AccessibilityNode {
 }
 
 // markCursorPointerElements 标记所有 cursor:pointer 的元素为可点击
-func markCursorPointerElements(ctx context.Context, page *rod.Page, tree *AccessibilitySnapshot) error {
+// page 为 framePath 对应的文档，只匹配同一框架内的节点
+func markCursorPointerElements(ctx context.Context, page *rod.Page, tree *AccessibilitySnapshot, framePath []int) error {
 	// 执行 JavaScript 获取所有 cursor:pointer 元素的信息
 	script := `
 	() => {
@@ -350,6 +359,7 @@ func markCursorPointerElements(ctx context.Context, page *rod.Page, tree *Access
 	logger.Info(ctx, "[markCursorPointerElements] Found %d elements with cursor:pointer", len(cursorPointerElements))
 
 	// 标记树中的节点
+	frameKey := framePathKey(framePath)
 	markedCount := 0
 	for _, elem := range cursorPointerElements {
 		text, _ := elem["text"].(string)
@@ -359,6 +369,10 @@ func markCursorPointerElements(ctx context.Context, page *rod.Page, tree *Access
 
 		// 尝试在语义树中找到匹配的节点
 		for _, node := range tree.Elements {
+			if framePathKey(node.FramePath) != frameKey {
+				continue
+			}
+
 			// 跳过已经被标记为可点击的节点
 			if clickable, ok := node.Metadata["cursor_pointer"].(bool); ok && clickable {
 				continue
@@ -520,6 +534,7 @@ func (tree *AccessibilitySnapshot) SerializeToSimpleText() string {
 				if node.Role != "" && node.Role != "StaticText" {
 					builder.WriteString(fmt.Sprintf(" (%s)", node.Role))
 				}
+				builder.WriteString(frameSuffix(node))
 				builder.WriteString("\n")
 			}
 		}
@@ -563,6 +578,7 @@ func (tree *AccessibilitySnapshot) SerializeToSimpleText() string {
 				if node.Value != "" {
 					builder.WriteString(fmt.Sprintf(" [value: %s]", node.Value))
 				}
+				builder.WriteString(frameSuffix(node))
 				builder.WriteString("\n")
 			}
 		}
@@ -575,10 +591,22 @@ func (tree *AccessibilitySnapshot) SerializeToSimpleText() string {
 	builder.WriteString("  • Type:  {\"identifier\": \"@e5\", \"text\": \"hello\"}  ✓ Correct\n")
 	builder.WriteString("  • DO NOT use text labels as identifiers  ✗ Wrong\n")
 	builder.WriteString("  • ALWAYS use the RefID format (@e1, @e2, etc.)  ✓ Required\n")
+	builder.WriteString("  • Elements marked [frame ...] are inside iframes; their RefIDs work the same way\n")
 
 	return builder.String()
 }
 
+// frameSuffix 返回 iframe 中元素的框架标注（主文档元素为空）
+func frameSuffix(node *AccessibilityNode) string {
+	if len(node.FramePath) == 0 {
+		return ""
+	}
+	if node.FrameURL != "" {
+		return fmt.Sprintf(" [frame %s: %s]", framePathKey(node.FramePath), node.FrameURL)
+	}
+	return fmt.Sprintf(" [frame %s]", framePathKey(node.FramePath))
+}
+
 // HighlightElement 在页面上高亮显示元素（用于调试）
 func HighlightElement(ctx context.Context, page *rod.Page, selector string) error {
 	elem, err := page.Element(selector)
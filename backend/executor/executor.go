@@ -10,6 +10,7 @@ import (
 	"github.com/browserwing/browserwing/pkg/logger"
 	"github.com/browserwing/browserwing/services/browser"
 	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
 )
 
 // Executor 提供通用的浏览器自动化能力
@@ -27,7 +28,20 @@ type Executor struct {
 	refIDTimestamp time.Time
 	refIDTTL       time.Duration
 
+	// 每个页面最近一次获取的快照，用于快照差异比较和跨快照复用 RefID（页面切换、导航后仍保留）
+	// 以 TargetID 为键，页面关闭后由 forgetSnapshot 移除
+	snapshotHistory map[proto.TargetTargetID]*snapshotHistoryEntry
+	snapshotSeq     uint64
+
+	// 定位 iframe 中的元素时附加的跨进程 iframe 会话（元素在操作完成前都要使用会话，因此跨调用复用）
+	frameTargets frameTargets
+
 	Recorder *OperationRecorder
+
+	// 会话执行器绑定的会话和页面（见 SessionManager），共享执行器的 session 为 nil，使用浏览器管理器的活动页面
+	session *Session
+	pageMu  sync.RWMutex
+	page    *rod.Page
 }
 
 // NewExecutor 创建 Executor 实例
@@ -51,7 +65,7 @@ func (e *Executor) WithContext(ctx context.Context) *Executor {
 
 // GetPage 获取当前活动页面
 func (e *Executor) GetPage() *Page {
-	rodPage := e.Browser.GetActivePage()
+	rodPage := e.activePage()
 	if rodPage == nil {
 		return nil
 	}
@@ -73,6 +87,7 @@ func (e *Executor) GetPage() *Page {
 
 // InvalidateSnapshotCache clears the cached accessibility snapshot.
 // This should be called when the active page changes (tab switch, new tab, navigation, etc.)
+// The per-page snapshot history is kept so that the next snapshot can still be diffed and reuse RefIDs.
 func (e *Executor) InvalidateSnapshotCache() {
 	e.refIDMutex.Lock()
 	defer e.refIDMutex.Unlock()
@@ -84,7 +99,7 @@ func (e *Executor) InvalidateSnapshotCache() {
 
 // GetAccessibilitySnapshot 获取页面的可访问性快照（带 RefID 缓存）
 func (e *Executor) GetAccessibilitySnapshot(ctx context.Context) (*AccessibilitySnapshot, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -103,6 +118,11 @@ func (e *Executor) GetAccessibilitySnapshot(ctx context.Context) (*Accessibility
 	}
 	e.refIDMutex.RUnlock()
 
+	return e.refreshSnapshot(ctx, page)
+}
+
+// refreshSnapshot 忽略缓存重新获取页面快照，生成 RefID 并更新缓存和页面的历史快照
+func (e *Executor) refreshSnapshot(ctx context.Context, page *rod.Page) (*AccessibilitySnapshot, error) {
 	// 获取新快照
 	logger.Info(ctx, "[GetAccessibilitySnapshot] Fetching new accessibility snapshot")
 	snapshot, err := GetAccessibilitySnapshot(ctx, page)
@@ -116,10 +136,11 @@ func (e *Executor) GetAccessibilitySnapshot(ctx context.Context) (*Accessibility
 
 	e.refIDMap = make(map[string]*RefData)
 	e.refIDCounter = 0
-	e.assignRefIDs(snapshot)
+	e.assignRefIDs(snapshot, e.previousSnapshot(page.TargetID))
 	e.refIDSnapshot = snapshot
 	e.refIDPage = page
 	e.refIDTimestamp = time.Now()
+	e.rememberSnapshot(page.TargetID, snapshot)
 
 	logger.Info(ctx, "[GetAccessibilitySnapshot] Cached new snapshot with %d refs (TTL: %v)",
 		len(e.refIDMap), e.refIDTTL)
@@ -129,9 +150,14 @@ func (e *Executor) GetAccessibilitySnapshot(ctx context.Context) (*Accessibility
 
 // assignRefIDs 为快照中的元素分配 RefID（参考 agent-browser 的实现）
 // 使用 role+name+nth 而非 BackendNodeID，以提高稳定性
-func (e *Executor) assignRefIDs(snapshot *AccessibilitySnapshot) {
+// previous 为同一页面上一次的快照，能匹配上的元素沿用原 RefID，新元素从原最大序号之后继续编号
+func (e *Executor) assignRefIDs(snapshot *AccessibilitySnapshot, previous *AccessibilitySnapshot) {
 	// 跟踪 role:name 组合，用于处理重复元素
 	roleNameCounter := make(map[string]int) // "button:Submit" -> 0, 1, 2...
+	reused := matchPreviousRefIDs(previous, snapshot)
+	if n := maxRefNumber(previous); n > e.refIDCounter {
+		e.refIDCounter = n
+	}
 	
 	// 为可点击元素分配 refID（e1, e2, e3...）
 	clickables := snapshot.GetClickableElements()
@@ -139,14 +165,17 @@ func (e *Executor) assignRefIDs(snapshot *AccessibilitySnapshot) {
 	clickableCount := 0
 	
 	for _, node := range clickables {
-		// 构建 role:name key
-		key := fmt.Sprintf("%s:%s", node.Role, node.Label)
+		// 构建 role:name key（nth 在同一框架内计数）
+		key := fmt.Sprintf("%s:%s:%s", framePathKey(node.FramePath), node.Role, node.Label)
 		nth := roleNameCounter[key]
 		roleNameCounter[key]++
 		
-		// 分配 RefID
-		e.refIDCounter++
-		refID := fmt.Sprintf("e%d", e.refIDCounter)
+		// 分配 RefID（优先沿用上一次快照中的 RefID）
+		refID, ok := reused[node]
+		if !ok {
+			e.refIDCounter++
+			refID = fmt.Sprintf("e%d", e.refIDCounter)
+		}
 		node.RefID = refID
 		
 		// 存储语义化定位器数据（参考 agent-browser）
@@ -157,6 +186,7 @@ func (e *Executor) assignRefIDs(snapshot *AccessibilitySnapshot) {
 			Nth:        nth,
 			BackendID:  int(node.BackendNodeID),
 			Attributes: make(map[string]string),
+			FramePath:  node.FramePath,
 		}
 		
 		// 对于链接，存储 href
@@ -199,14 +229,17 @@ func (e *Executor) assignRefIDs(snapshot *AccessibilitySnapshot) {
 			continue
 		}
 		
-		// 构建 role:name key
-		key := fmt.Sprintf("%s:%s", node.Role, node.Label)
+		// 构建 role:name key（nth 在同一框架内计数）
+		key := fmt.Sprintf("%s:%s:%s", framePathKey(node.FramePath), node.Role, node.Label)
 		nth := roleNameCounter[key]
 		roleNameCounter[key]++
 		
-		// 分配 RefID
-		e.refIDCounter++
-		refID := fmt.Sprintf("e%d", e.refIDCounter)
+		// 分配 RefID（优先沿用上一次快照中的 RefID）
+		refID, ok := reused[node]
+		if !ok {
+			e.refIDCounter++
+			refID = fmt.Sprintf("e%d", e.refIDCounter)
+		}
 		node.RefID = refID
 		
 		// 存储语义化定位器数据
@@ -216,6 +249,7 @@ func (e *Executor) assignRefIDs(snapshot *AccessibilitySnapshot) {
 			Nth:        nth,
 			BackendID:  int(node.BackendNodeID),
 			Attributes: make(map[string]string),
+			FramePath:  node.FramePath,
 		}
 		
 		// 存储 placeholder（对于输入元素）
@@ -362,7 +396,7 @@ func (e *Executor) SelectByLabel(ctx context.Context, label string, value string
 
 // GetPageInfo 获取页面信息（增强版，参考 playwright-mcp 和 agent-browser）
 func (e *Executor) GetPageInfo(ctx context.Context) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return &OperationResult{
 			Success:   false,
@@ -509,7 +543,7 @@ func (e *Executor) GetPageInfo(ctx context.Context) (*OperationResult, error) {
 
 // GetPageContent 获取页面内容
 func (e *Executor) GetPageContent(ctx context.Context) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return &OperationResult{
 			Success:   false,
@@ -539,7 +573,7 @@ func (e *Executor) GetPageContent(ctx context.Context) (*OperationResult, error)
 
 // GetPageText 获取页面文本
 func (e *Executor) GetPageText(ctx context.Context) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return &OperationResult{
 			Success:   false,
@@ -677,7 +711,7 @@ type BatchResult struct {
 
 // EnsurePageReady 确保页面就绪
 func (e *Executor) EnsurePageReady(ctx context.Context) error {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return fmt.Errorf("no active page")
 	}
@@ -702,7 +736,7 @@ func (e *Executor) HighlightElementByLabel(ctx context.Context, label string) er
 		return err
 	}
 
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return fmt.Errorf("no active page")
 	}
@@ -712,12 +746,12 @@ func (e *Executor) HighlightElementByLabel(ctx context.Context, label string) er
 
 // GetRodPage 获取 Rod Page（供内部使用）
 func (e *Executor) GetRodPage() *rod.Page {
-	return e.Browser.GetActivePage()
+	return e.activePage()
 }
 
 // IsReady 检查 Executor 是否就绪
 func (e *Executor) IsReady() bool {
-	return e.Browser.IsRunning() && e.Browser.GetActivePage() != nil
+	return e.Browser.IsRunning() && e.activePage() != nil
 }
 
 // WaitUntilReady 等待 Executor 就绪
@@ -800,40 +834,9 @@ func (e *Executor) recordOp(op OperationRecord) {
 }
 
 // getElementXPath 通过 JS 获取元素的稳定 Full XPath
+// 元素位于 Shadow DOM 中时返回带 ">>>" 的穿透路径
 func getElementXPath(elem *rod.Element) string {
-	result, err := elem.Eval(`() => {
-		function getXPath(el) {
-			if (!el || el.nodeType !== 1) return '';
-			if (el.id && document.querySelectorAll('#' + CSS.escape(el.id)).length === 1) {
-				return '//*[@id="' + el.id + '"]';
-			}
-			var parts = [];
-			while (el && el.nodeType === 1) {
-				var idx = 0;
-				var sib = el.previousSibling;
-				while (sib) {
-					if (sib.nodeType === 1 && sib.nodeName === el.nodeName) idx++;
-					sib = sib.previousSibling;
-				}
-				var part = el.nodeName.toLowerCase();
-				if (idx > 0 || el.nextSibling) {
-					var total = 0;
-					var next = el.nextSibling;
-					while (next) {
-						if (next.nodeType === 1 && next.nodeName === el.nodeName) total++;
-						next = next.nextSibling;
-					}
-					if (idx > 0 || total > 0) {
-						part += '[' + (idx + 1) + ']';
-					}
-				}
-				parts.unshift(part);
-				el = el.parentNode;
-			}
-			return '/' + parts.join('/');
-		}
-		return getXPath(this);
-	}`)
+	result, err := elem.Eval(browser.ShadowXPathScript)
 	if err != nil {
 		return ""
 	}
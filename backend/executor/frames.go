@@ -0,0 +1,252 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// maxSnapshotFrameDepth 快照遍历 iframe 的最大嵌套层数
+const maxSnapshotFrameDepth = 3
+
+// frameSelector 子框架元素（按文档顺序编号）
+const frameSelector = "iframe, frame"
+
+// framePathKey 将框架路径转换为字符串（如 [1 0] -> "1.0"），主文档为空字符串
+// 路径中的每一项是 iframe 在所在文档中的序号（按文档顺序，从 0 开始）
+func framePathKey(path []int) string {
+	if len(path) == 0 {
+		return ""
+	}
+	parts := make([]string, len(path))
+	for i, index := range path {
+		parts[i] = strconv.Itoa(index)
+	}
+	return strings.Join(parts, ".")
+}
+
+// childFramePath 返回子框架的路径（不与父路径共享底层数组）
+func childFramePath(parent []int, index int) []int {
+	path := make([]int, len(parent), len(parent)+1)
+	copy(path, parent)
+	return append(path, index)
+}
+
+// frameTargets 跨进程 iframe 附加的 CDP 会话，按 TargetID 缓存
+// 每次附加都会创建新的会话，缓存保证同一框架只附加一次；不再使用时由 detach 分离
+type frameTargets struct {
+	mu    sync.Mutex
+	pages map[proto.TargetTargetID]*rod.Page
+}
+
+// page 返回跨进程 iframe 对应的框架页面，首次访问时附加到其 target
+func (t *frameTargets) page(parent *rod.Page, targetID proto.TargetTargetID) (*rod.Page, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if frame, ok := t.pages[targetID]; ok {
+		return frame, nil
+	}
+
+	// 使用浏览器对象的副本关闭默认设备模拟（iframe target 不支持视口相关的模拟命令）
+	browser := *parent.Browser()
+	frame, err := browser.NoDefaultDevice().PageFromTarget(targetID)
+	if err != nil {
+		return nil, err
+	}
+	// rod 按 TargetID 缓存附加的页面，移出其缓存，由 frameTargets 管理会话的生命周期
+	browser.RemoveState(targetID)
+	if t.pages == nil {
+		t.pages = make(map[proto.TargetTargetID]*rod.Page)
+	}
+	t.pages[targetID] = frame
+	return frame, nil
+}
+
+// prune 分离已不存在的 iframe target 的会话（如导航或页面关闭后）
+func (t *frameTargets) prune(live map[proto.TargetTargetID]bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for targetID, frame := range t.pages {
+		if !live[targetID] {
+			detachFrame(frame)
+			delete(t.pages, targetID)
+		}
+	}
+}
+
+// detach 分离所有附加的会话
+func (t *frameTargets) detach() {
+	t.prune(nil)
+}
+
+// detachFrame 分离框架页面的会话（target 已销毁时忽略错误）
+func detachFrame(frame *rod.Page) {
+	_ = proto.TargetDetachFromTarget{SessionID: frame.SessionID}.Call(frame.Browser())
+}
+
+// childFrames 返回页面（或框架）中所有子框架对应的页面，下标即框架序号
+// 无法访问的框架（如尚未加载）在结果中为 nil，保证序号稳定
+// 跨进程 iframe 的会话缓存在 attached 中，已销毁的 target 的会话随之分离
+func childFrames(ctx context.Context, page *rod.Page, attached *frameTargets) ([]*rod.Page, error) {
+	elements, err := page.Context(ctx).Elements(frameSelector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list frames: %w", err)
+	}
+	if len(elements) == 0 {
+		return nil, nil
+	}
+
+	// 跨进程 iframe（OOPIF）是独立的 target，TargetID 与 FrameID 相同
+	oopifs := make(map[proto.TargetTargetID]bool)
+	if targets, err := (proto.TargetGetTargets{}).Call(page); err == nil {
+		for _, info := range targets.TargetInfos {
+			if info.Type == "iframe" {
+				oopifs[info.TargetID] = true
+			}
+		}
+		attached.prune(oopifs)
+	}
+
+	frames := make([]*rod.Page, len(elements))
+	for i, el := range elements {
+		frame, err := frameFromElement(page, el, oopifs, attached)
+		if err != nil {
+			logger.Warn(ctx, "[childFrames] Frame %d is not accessible: %v", i, err)
+			continue
+		}
+		frames[i] = frame.Context(ctx)
+	}
+	return frames, nil
+}
+
+// frameFromElement 获取 iframe 元素对应的框架页面
+// 同进程 iframe 与父页面共用会话，跨进程 iframe 需要附加到其独立的 target
+func frameFromElement(page *rod.Page, el *rod.Element, oopifs map[proto.TargetTargetID]bool, targets *frameTargets) (*rod.Page, error) {
+	node, err := el.Describe(1, false)
+	if err != nil {
+		return nil, err
+	}
+	if node.FrameID == "" {
+		return nil, fmt.Errorf("element has no content frame")
+	}
+
+	if oopifs[proto.TargetTargetID(node.FrameID)] {
+		return targets.page(page, proto.TargetTargetID(node.FrameID))
+	}
+	return el.Frame()
+}
+
+// resolveFramePage 按框架路径逐层定位到对应的框架页面
+func resolveFramePage(ctx context.Context, page *rod.Page, path []int, targets *frameTargets) (*rod.Page, error) {
+	current := page
+	for depth, index := range path {
+		frames, err := childFrames(ctx, current, targets)
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= len(frames) || frames[index] == nil {
+			return nil, fmt.Errorf("frame %s not found (page may have changed, run browser_snapshot again)", framePathKey(path[:depth+1]))
+		}
+		current = frames[index]
+	}
+	return current, nil
+}
+
+// frameURL 返回框架当前文档的地址
+func frameURL(frame *rod.Page) string {
+	res, err := frame.Eval(`() => location.href`)
+	if err != nil {
+		return ""
+	}
+	return res.Value.String()
+}
+
+// addFrameSnapshots 递归获取子框架的可访问性树并合并到快照中
+func addFrameSnapshots(ctx context.Context, page *rod.Page, snapshot *AccessibilitySnapshot, parentPath []int, targets *frameTargets) {
+	if len(parentPath) >= maxSnapshotFrameDepth {
+		return
+	}
+
+	frames, err := childFrames(ctx, page, targets)
+	if err != nil {
+		logger.Warn(ctx, "[GetAccessibilitySnapshot] %v", err)
+		return
+	}
+
+	for i, frame := range frames {
+		if frame == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		path := childFramePath(parentPath, i)
+		if err := addFrameSnapshot(ctx, page, frame, snapshot, path, targets); err != nil {
+			logger.Warn(ctx, "[GetAccessibilitySnapshot] Skipping frame %s: %v", framePathKey(path), err)
+		}
+	}
+}
+
+// addFrameSnapshot 获取单个框架（及其子框架）的可访问性树并合并到快照中
+func addFrameSnapshot(ctx context.Context, parent, frame *rod.Page, snapshot *AccessibilitySnapshot, path []int, targets *frameTargets) error {
+	req := proto.AccessibilityGetFullAXTree{}
+	sameSession := frame.SessionID == parent.SessionID
+	if sameSession {
+		// 同进程 iframe 与父文档共用会话（Accessibility 域已启用），按 FrameID 获取
+		req.FrameID = frame.FrameID
+	} else {
+		if err := (proto.AccessibilityEnable{}).Call(frame); err != nil {
+			return fmt.Errorf("failed to enable accessibility: %w", err)
+		}
+		defer func() { _ = proto.AccessibilityDisable{}.Call(frame) }()
+	}
+
+	axTree, err := req.Call(frame)
+	if err != nil {
+		return fmt.Errorf("failed to get accessibility tree: %w", err)
+	}
+
+	key := framePathKey(path)
+	url := frameURL(frame)
+	// BackendIDMap 只收录与主文档同一会话的节点，跨进程 iframe 的 BackendNodeID 属于独立会话
+	rootSession := frame.SessionID == snapshot.sessionID
+	count := 0
+	for _, axNode := range axTree.Nodes {
+		node := buildAccessibilityNodeFromAXNode(axNode)
+		if node == nil {
+			continue
+		}
+		if existing := snapshot.BackendIDMap[node.BackendNodeID]; rootSession && node.BackendNodeID > 0 && existing != nil {
+			// 已经出现在父文档的树中，只补充框架信息
+			existing.FramePath = path
+			existing.FrameURL = url
+			continue
+		}
+		// 不同文档的 AXNodeID 可能重复，框架内的节点 ID 带上框架路径
+		node.ID = "f" + key + ":" + node.ID
+		node.FramePath = path
+		node.FrameURL = url
+		snapshot.Elements[node.ID] = node
+		if rootSession && node.BackendNodeID > 0 {
+			snapshot.BackendIDMap[node.BackendNodeID] = node
+		}
+		count++
+	}
+	logger.Info(ctx, "[GetAccessibilitySnapshot] Added %d nodes from frame %s (%s)", count, key, url)
+
+	if err := markCursorPointerElements(ctx, frame, snapshot, path); err != nil {
+		logger.Warn(ctx, "[GetAccessibilitySnapshot] Failed to mark cursor:pointer elements in frame %s: %v", key, err)
+	}
+
+	addFrameSnapshots(ctx, frame, snapshot, path, targets)
+	return nil
+}
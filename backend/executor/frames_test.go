@@ -0,0 +1,169 @@
+package executor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// testNode 构造测试用的可访问性节点，children 为子节点的 AXNodeID（不带框架前缀）
+func testNode(id, role, label string, backendID int, children ...string) *AccessibilityNode {
+	node := &AccessibilityNode{
+		ID:            id,
+		AXNodeID:      proto.AccessibilityAXNodeID(id),
+		BackendNodeID: proto.DOMBackendNodeID(backendID),
+		Role:          role,
+		Label:         label,
+		Text:          label,
+		Attributes:    make(map[string]string),
+		Metadata:      make(map[string]interface{}),
+	}
+	if len(children) > 0 {
+		childIDs := make([]proto.AccessibilityAXNodeID, len(children))
+		for i, child := range children {
+			childIDs[i] = proto.AccessibilityAXNodeID(child)
+		}
+		node.Metadata["childIDs"] = childIDs
+	}
+	return node
+}
+
+// inFrame 将节点放入框架路径对应的 iframe（节点 ID 带框架前缀，与 addFrameSnapshot 一致）
+func inFrame(node *AccessibilityNode, path ...int) *AccessibilityNode {
+	node.ID = "f" + framePathKey(path) + ":" + node.ID
+	node.FramePath = path
+	return node
+}
+
+// testSnapshot 由节点构造快照，第一个节点为根节点
+func testSnapshot(nodes ...*AccessibilityNode) *AccessibilitySnapshot {
+	snapshot := &AccessibilitySnapshot{
+		Elements:     make(map[string]*AccessibilityNode),
+		BackendIDMap: make(map[proto.DOMBackendNodeID]*AccessibilityNode),
+	}
+	for _, node := range nodes {
+		snapshot.Elements[node.ID] = node
+		if node.BackendNodeID > 0 && len(node.FramePath) == 0 {
+			snapshot.BackendIDMap[node.BackendNodeID] = node
+		}
+	}
+	if len(nodes) > 0 {
+		snapshot.Root = nodes[0]
+	}
+	return snapshot
+}
+
+func newTestExecutor() *Executor {
+	logger.InitLogger(&logger.LoggerConfig{Level: "error"})
+	return &Executor{refIDMap: make(map[string]*RefData)}
+}
+
+func TestFramePathKey(t *testing.T) {
+	tests := []struct {
+		path []int
+		want string
+	}{
+		{path: nil, want: ""},
+		{path: []int{}, want: ""},
+		{path: []int{2}, want: "2"},
+		{path: []int{1, 0, 3}, want: "1.0.3"},
+	}
+	for _, tt := range tests {
+		if got := framePathKey(tt.path); got != tt.want {
+			t.Errorf("framePathKey(%v) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	parent := make([]int, 1, 4)
+	first := childFramePath(parent, 1)
+	second := childFramePath(parent, 2)
+	if !reflect.DeepEqual(first, []int{0, 1}) || !reflect.DeepEqual(second, []int{0, 2}) {
+		t.Errorf("child frame paths share storage: %v %v", first, second)
+	}
+}
+
+func TestAssignRefIDsFramePath(t *testing.T) {
+	e := newTestExecutor()
+	snapshot := testSnapshot(
+		testNode("1", "RootWebArea", "Main", 0),
+		testNode("2", "button", "Submit", 10),
+		inFrame(testNode("2", "button", "Submit", 20), 0),
+		inFrame(testNode("5", "textbox", "Email", 30), 1, 0),
+	)
+	e.assignRefIDs(snapshot, nil)
+
+	tests := []struct {
+		nodeID    string
+		wantFrame []int
+		wantNth   int
+	}{
+		{nodeID: "2", wantFrame: nil, wantNth: 0},
+		// 同名按钮在不同框架中各自从 0 计数
+		{nodeID: "f0:2", wantFrame: []int{0}, wantNth: 0},
+		{nodeID: "f1.0:5", wantFrame: []int{1, 0}, wantNth: 0},
+	}
+	for _, tt := range tests {
+		node := snapshot.Elements[tt.nodeID]
+		refData := e.refIDMap[node.RefID]
+		if refData == nil {
+			t.Fatalf("%s: no RefData for RefID %q", tt.nodeID, node.RefID)
+		}
+		if !reflect.DeepEqual(refData.FramePath, tt.wantFrame) {
+			t.Errorf("%s: frame path = %v, want %v", tt.nodeID, refData.FramePath, tt.wantFrame)
+		}
+		if refData.Nth != tt.wantNth {
+			t.Errorf("%s: nth = %d, want %d", tt.nodeID, refData.Nth, tt.wantNth)
+		}
+		if refData.BackendID != int(node.BackendNodeID) {
+			t.Errorf("%s: backend id = %d, want %d", tt.nodeID, refData.BackendID, node.BackendNodeID)
+		}
+	}
+	if len(e.refIDMap) != 3 {
+		t.Errorf("refIDMap has %d entries, want 3", len(e.refIDMap))
+	}
+}
+
+func TestResolveFramePageMainDocument(t *testing.T) {
+	// 主文档中的元素不需要进入框架，直接在页面上定位
+	page := &rod.Page{}
+	got, err := resolveFramePage(context.Background(), page, nil, &frameTargets{})
+	if err != nil || got != page {
+		t.Errorf("resolveFramePage(nil) = %p, %v; want the page itself", got, err)
+	}
+}
+
+func TestFrameTargetsReuseAttachedFrame(t *testing.T) {
+	// 已附加的跨进程 iframe 直接复用会话，不会再次附加
+	frame := &rod.Page{TargetID: "oopif-1"}
+	targets := &frameTargets{pages: map[proto.TargetTargetID]*rod.Page{"oopif-1": frame}}
+	for i := 0; i < 2; i++ {
+		got, err := targets.page(nil, "oopif-1")
+		if err != nil || got != frame {
+			t.Fatalf("page(oopif-1) = %p, %v; want the attached frame", got, err)
+		}
+	}
+	targets.prune(map[proto.TargetTargetID]bool{"oopif-1": true})
+	if len(targets.pages) != 1 {
+		t.Errorf("live frame was detached")
+	}
+}
+
+func TestFrameSuffix(t *testing.T) {
+	tests := []struct {
+		node *AccessibilityNode
+		want string
+	}{
+		{node: &AccessibilityNode{}, want: ""},
+		{node: &AccessibilityNode{FramePath: []int{1}}, want: " [frame 1]"},
+		{node: &AccessibilityNode{FramePath: []int{1, 2}, FrameURL: "https://example.com/pay"}, want: " [frame 1.2: https://example.com/pay]"},
+	}
+	for _, tt := range tests {
+		if got := frameSuffix(tt.node); got != tt.want {
+			t.Errorf("frameSuffix(%v) = %q, want %q", tt.node.FramePath, got, tt.want)
+		}
+	}
+}
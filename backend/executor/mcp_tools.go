@@ -2,12 +2,16 @@ package executor
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/browserwing/browserwing/models"
 	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/browserwing/browserwing/services/browser"
 	"github.com/go-rod/rod"
 	mcpgo "github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -16,6 +20,7 @@ import (
 // MCPToolRegistry MCP 工具注册表
 type MCPToolRegistry struct {
 	executor  *Executor
+	sessions  *SessionManager
 	mcpServer *server.MCPServer
 }
 
@@ -23,10 +28,53 @@ type MCPToolRegistry struct {
 func NewMCPToolRegistry(executor *Executor, mcpServer *server.MCPServer) *MCPToolRegistry {
 	return &MCPToolRegistry{
 		executor:  executor,
+		sessions:  NewSessionManager(executor.Browser),
 		mcpServer: mcpServer,
 	}
 }
 
+// Sessions 返回工具使用的会话管理器
+func (r *MCPToolRegistry) Sessions() *SessionManager {
+	return r.sessions
+}
+
+// executorContextKey 工具处理函数的 context 中保存会话执行器的键
+type executorContextKey struct{}
+
+// ExecutorForArgs 按工具参数中的 session_id 选择执行器，未指定时使用共享执行器
+func (r *MCPToolRegistry) ExecutorForArgs(args map[string]interface{}) (*Executor, error) {
+	sessionID, _ := args["session_id"].(string)
+	if sessionID == "" {
+		return r.executor, nil
+	}
+	session, err := r.sessions.Get(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return session.Executor, nil
+}
+
+// executorFrom 返回当前工具调用使用的执行器
+func (r *MCPToolRegistry) executorFrom(ctx context.Context) *Executor {
+	if exec, ok := ctx.Value(executorContextKey{}).(*Executor); ok {
+		return exec
+	}
+	return r.executor
+}
+
+// addTool 注册工具，所有工具都接受可选的 session_id 参数，在对应的执行器会话中执行
+func (r *MCPToolRegistry) addTool(tool mcpgo.Tool, handler server.ToolHandlerFunc) {
+	mcpgo.WithString("session_id", mcpgo.Description("Executor session ID from browser_session (optional, default: shared session)"))(&tool)
+
+	r.mcpServer.AddTool(tool, func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+		exec, err := r.ExecutorForArgs(request.GetArguments())
+		if err != nil {
+			return mcpgo.NewToolResultError(err.Error()), nil
+		}
+		return handler(context.WithValue(ctx, executorContextKey{}, exec), request)
+	})
+}
+
 // RegisterAllTools 注册所有工具到 MCP 服务器
 func (r *MCPToolRegistry) RegisterAllTools() error {
 	// 注册导航工具
@@ -69,6 +117,11 @@ func (r *MCPToolRegistry) RegisterAllTools() error {
 		return fmt.Errorf("failed to register wait tool: %w", err)
 	}
 
+	// 注册断言工具
+	if err := r.registerAssertTool(); err != nil {
+		return fmt.Errorf("failed to register assert tool: %w", err)
+	}
+
 	// 注册滚动工具
 	if err := r.registerScrollTool(); err != nil {
 		return fmt.Errorf("failed to register scroll tool: %w", err)
@@ -79,6 +132,14 @@ func (r *MCPToolRegistry) RegisterAllTools() error {
 		return fmt.Errorf("failed to register screenshot tool: %w", err)
 	}
 
+	// 注册 PDF 和 MHTML 存档工具
+	if err := r.registerPDFTool(); err != nil {
+		return fmt.Errorf("failed to register pdf tool: %w", err)
+	}
+	if err := r.registerArchiveTool(); err != nil {
+		return fmt.Errorf("failed to register archive tool: %w", err)
+	}
+
 	// 注册执行脚本工具
 	if err := r.registerEvaluateTool(); err != nil {
 		return fmt.Errorf("failed to register evaluate tool: %w", err)
@@ -124,6 +185,11 @@ func (r *MCPToolRegistry) RegisterAllTools() error {
 		return fmt.Errorf("failed to register network requests tool: %w", err)
 	}
 
+	// 注册请求拦截工具
+	if err := r.registerRouteTool(); err != nil {
+		return fmt.Errorf("failed to register route tool: %w", err)
+	}
+
 	// 注册标签页管理工具
 	if err := r.registerTabsTool(); err != nil {
 		return fmt.Errorf("failed to register tabs tool: %w", err)
@@ -134,6 +200,21 @@ func (r *MCPToolRegistry) RegisterAllTools() error {
 		return fmt.Errorf("failed to register fill form tool: %w", err)
 	}
 
+	// 注册仿真工具
+	if err := r.registerEmulateTool(); err != nil {
+		return fmt.Errorf("failed to register emulate tool: %w", err)
+	}
+
+	// 注册存储状态工具
+	if err := r.registerStorageStateTool(); err != nil {
+		return fmt.Errorf("failed to register storage state tool: %w", err)
+	}
+
+	// 注册会话管理工具
+	if err := r.registerSessionTool(); err != nil {
+		return fmt.Errorf("failed to register session tool: %w", err)
+	}
+
 	return nil
 }
 
@@ -172,7 +253,7 @@ func (r *MCPToolRegistry) registerNavigateTool() error {
 		logger.Info(ctx, "[MCP Handler] Options: WaitUntil=%s, Timeout=%v", opts.WaitUntil, opts.Timeout)
 
 		logger.Info(ctx, "[MCP Handler] Calling executor.Navigate...")
-		result, err := r.executor.Navigate(ctx, url, opts)
+		result, err := r.executorFrom(ctx).Navigate(ctx, url, opts)
 		if err != nil {
 			logger.Info(ctx, "[MCP Handler] Navigate failed: %v", err)
 			return mcpgo.NewToolResultError(err.Error()), nil
@@ -191,7 +272,7 @@ func (r *MCPToolRegistry) registerNavigateTool() error {
 		return mcpgo.NewToolResultText(responseText), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
 	return nil
 }
 
@@ -200,8 +281,9 @@ func (r *MCPToolRegistry) registerClickTool() error {
 	tool := mcpgo.NewTool(
 		"browser_click",
 		mcpgo.WithDescription("Click an element on the page. Returns success message and updated page snapshot with RefIDs. Can use RefID (@e1), CSS selector, XPath, or element label/text."),
-		mcpgo.WithString("identifier", mcpgo.Required(), mcpgo.Description("Element identifier: RefID (@e1 from snapshot), CSS selector, XPath, label, or text")),
+		mcpgo.WithString("identifier", mcpgo.Required(), mcpgo.Description("Element identifier: RefID (@e1 from snapshot), CSS selector, XPath, shadow DOM locator (host >>> inner, shadow:css), label, or text")),
 		mcpgo.WithBoolean("wait_visible", mcpgo.Description("Wait for element to be visible (default: true)")),
+		mcpgo.WithBoolean("snapshot_diff", mcpgo.Description("Return only the elements added, removed or changed since the previous snapshot instead of the full snapshot (default: false)")),
 	)
 
 	handler := func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
@@ -218,36 +300,43 @@ func (r *MCPToolRegistry) registerClickTool() error {
 		if waitVisible, ok := args["wait_visible"].(bool); ok {
 			opts.WaitVisible = waitVisible
 		}
+		opts.SnapshotDiff, _ = args["snapshot_diff"].(bool)
 
-		result, err := r.executor.Click(ctx, identifier, opts)
+		result, err := r.executorFrom(ctx).Click(ctx, identifier, opts)
 		if err != nil {
 			return mcpgo.NewToolResultError(err.Error()), nil
 		}
 
-		// 构建返回文本，包含消息和可访问性快照
-		var responseText string
-		responseText = result.Message
-
-		// 如果有可访问性快照数据，添加到响应中
-		if snapshot, ok := result.Data["semantic_tree"].(string); ok && snapshot != "" {
-			responseText += "\n\n" + snapshot
-		}
-
-		return mcpgo.NewToolResultText(responseText), nil
+		return mcpgo.NewToolResultText(operationResultText(result)), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
 	return nil
 }
 
+// operationResultText 构建点击、输入、选择工具的返回文本，包含消息和可访问性快照（或快照差异）
+func operationResultText(result *OperationResult) string {
+	responseText := result.Message
+
+	// 如果有可访问性快照数据，添加到响应中
+	if snapshot, ok := result.Data["semantic_tree"].(string); ok && snapshot != "" {
+		responseText += "\n\n" + snapshot
+	}
+	if diff, ok := result.Data["snapshot_diff_text"].(string); ok && diff != "" {
+		responseText += "\n\n" + diff
+	}
+	return responseText
+}
+
 // registerTypeTool 注册输入工具
 func (r *MCPToolRegistry) registerTypeTool() error {
 	tool := mcpgo.NewTool(
 		"browser_type",
 		mcpgo.WithDescription("Type text into an input field. Returns success message and updated page snapshot with RefIDs. Can use RefID (@e3), CSS selector, XPath, or element label."),
-		mcpgo.WithString("identifier", mcpgo.Required(), mcpgo.Description("Element identifier: RefID (@e3 from snapshot), CSS selector, XPath, label, or placeholder")),
+		mcpgo.WithString("identifier", mcpgo.Required(), mcpgo.Description("Element identifier: RefID (@e3 from snapshot), CSS selector, XPath, shadow DOM locator (host >>> inner, shadow:css), label, or placeholder")),
 		mcpgo.WithString("text", mcpgo.Required(), mcpgo.Description("Text to type")),
 		mcpgo.WithBoolean("clear", mcpgo.Description("Clear existing text before typing (default: true)")),
+		mcpgo.WithBoolean("snapshot_diff", mcpgo.Description("Return only the elements added, removed or changed since the previous snapshot instead of the full snapshot (default: false)")),
 	)
 
 	handler := func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
@@ -264,25 +353,17 @@ func (r *MCPToolRegistry) registerTypeTool() error {
 		if clear, ok := args["clear"].(bool); ok {
 			opts.Clear = clear
 		}
+		opts.SnapshotDiff, _ = args["snapshot_diff"].(bool)
 
-		result, err := r.executor.Type(ctx, identifier, text, opts)
+		result, err := r.executorFrom(ctx).Type(ctx, identifier, text, opts)
 		if err != nil {
 			return mcpgo.NewToolResultError(err.Error()), nil
 		}
 
-		// 构建返回文本，包含消息和可访问性快照
-		var responseText string
-		responseText = result.Message
-
-		// 如果有可访问性快照数据，添加到响应中
-		if snapshot, ok := result.Data["semantic_tree"].(string); ok && snapshot != "" {
-			responseText += "\n\n" + snapshot
-		}
-
-		return mcpgo.NewToolResultText(responseText), nil
+		return mcpgo.NewToolResultText(operationResultText(result)), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
 	return nil
 }
 
@@ -291,8 +372,9 @@ func (r *MCPToolRegistry) registerSelectTool() error {
 	tool := mcpgo.NewTool(
 		"browser_select",
 		mcpgo.WithDescription("Select an option from a dropdown menu. Returns success message and updated page snapshot with RefIDs."),
-		mcpgo.WithString("identifier", mcpgo.Required(), mcpgo.Description("Select element identifier: RefID (@e5 from snapshot), CSS selector, or XPath")),
+		mcpgo.WithString("identifier", mcpgo.Required(), mcpgo.Description("Select element identifier: RefID (@e5 from snapshot), CSS selector, XPath, or shadow DOM locator (host >>> inner, shadow:css)")),
 		mcpgo.WithString("value", mcpgo.Required(), mcpgo.Description("Option value or text to select")),
+		mcpgo.WithBoolean("snapshot_diff", mcpgo.Description("Return only the elements added, removed or changed since the previous snapshot instead of the full snapshot (default: false)")),
 	)
 
 	handler := func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
@@ -304,25 +386,17 @@ func (r *MCPToolRegistry) registerSelectTool() error {
 			WaitVisible: true,
 			Timeout:     10 * time.Second,
 		}
+		opts.SnapshotDiff, _ = args["snapshot_diff"].(bool)
 
-		result, err := r.executor.Select(ctx, identifier, value, opts)
+		result, err := r.executorFrom(ctx).Select(ctx, identifier, value, opts)
 		if err != nil {
 			return mcpgo.NewToolResultError(err.Error()), nil
 		}
 
-		// 构建返回文本，包含消息和可访问性快照
-		var responseText string
-		responseText = result.Message
-
-		// 如果有可访问性快照数据，添加到响应中
-		if snapshot, ok := result.Data["semantic_tree"].(string); ok && snapshot != "" {
-			responseText += "\n\n" + snapshot
-		}
-
-		return mcpgo.NewToolResultText(responseText), nil
+		return mcpgo.NewToolResultText(operationResultText(result)), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
 	return nil
 }
 
@@ -353,7 +427,7 @@ func (r *MCPToolRegistry) registerExtractTool() error {
 			opts.Multiple = multiple
 		}
 
-		result, err := r.executor.Extract(ctx, opts)
+		result, err := r.executorFrom(ctx).Extract(ctx, opts)
 		if err != nil {
 			return mcpgo.NewToolResultError(err.Error()), nil
 		}
@@ -363,7 +437,7 @@ func (r *MCPToolRegistry) registerExtractTool() error {
 		return mcpgo.NewToolResultText(string(data)), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
 	return nil
 }
 
@@ -371,9 +445,15 @@ func (r *MCPToolRegistry) registerExtractTool() error {
 func (r *MCPToolRegistry) registerAccessibilitySnapshotTool() error {
 	tool := mcpgo.NewTool(
 		"browser_snapshot",
-		mcpgo.WithDescription("Get the accessibility snapshot of the current page. Returns a tree structure representing the page's accessibility tree, which is cleaner than raw DOM and better for LLMs to understand."),
+		mcpgo.WithDescription("Get the accessibility snapshot of the current page. Returns a tree structure representing the page's accessibility tree, which is cleaner than raw DOM and better for LLMs to understand. Elements inside iframes are included and tagged with their frame; their RefIDs can be used directly."),
 		mcpgo.WithBoolean("simple", mcpgo.Description("Return simplified text format suitable for LLMs (default: true)")),
 		mcpgo.WithNumber("max_depth", mcpgo.Description("Maximum depth of the tree (default: unlimited)")),
+		mcpgo.WithBoolean("diff", mcpgo.Description("Re-read the page and return only elements added, removed or changed since the previous snapshot; unchanged elements keep their RefIDs (default: false)")),
+		mcpgo.WithNumber("max_tokens", mcpgo.Description("Approximate token budget for the text snapshot; inputs are kept first, then buttons, links and headings; budgets below 100 tokens are raised to 100 (default: unlimited)")),
+		mcpgo.WithNumber("max_chars", mcpgo.Description("Character budget for the text snapshot, at least 400 (default: unlimited)")),
+		mcpgo.WithBoolean("viewport_only", mcpgo.Description("Only include elements intersecting the current viewport (default: false)")),
+		mcpgo.WithString("root", mcpgo.Description("Only include the subtree under a RefID (@e12) or landmark role, optionally with a name (main, navigation, form:Login), or the n-th node of a role (list#2)")),
+		mcpgo.WithNumber("list_limit", mcpgo.Description("List at most this many items per list/grid/table and collapse the rest into \"N more items\" (default: no limit)")),
 	)
 
 	handler := func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
@@ -384,14 +464,29 @@ func (r *MCPToolRegistry) registerAccessibilitySnapshotTool() error {
 			simple = simpleArg
 		}
 
-		snapshot, err := r.executor.GetAccessibilitySnapshot(ctx)
+		if diff, _ := args["diff"].(bool); diff {
+			snapshotDiff, _, err := r.executorFrom(ctx).GetAccessibilitySnapshotDiff(ctx)
+			if err != nil {
+				return mcpgo.NewToolResultError(err.Error()), nil
+			}
+			if simple {
+				return mcpgo.NewToolResultText(snapshotDiff.SerializeToText()), nil
+			}
+			data, _ := json.Marshal(snapshotDiff)
+			return mcpgo.NewToolResultText(string(data)), nil
+		}
+
+		snapshot, err := r.executorFrom(ctx).GetAccessibilitySnapshot(ctx)
 		if err != nil {
 			return mcpgo.NewToolResultError(err.Error()), nil
 		}
 
 		if simple {
-			// 返回简化的文本格式
-			text := snapshot.SerializeToSimpleText()
+			// 返回简化的文本格式（可按预算和范围裁剪）
+			text, err := r.executorFrom(ctx).SerializeSnapshot(ctx, snapshot, SnapshotSerializeOptionsFromArgs(args))
+			if err != nil {
+				return mcpgo.NewToolResultError(err.Error()), nil
+			}
 			return mcpgo.NewToolResultText(text), nil
 		}
 
@@ -400,7 +495,7 @@ func (r *MCPToolRegistry) registerAccessibilitySnapshotTool() error {
 		return mcpgo.NewToolResultText(string(data)), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
 	return nil
 }
 
@@ -424,7 +519,7 @@ For interactive elements, use browser_snapshot to get refs for precise interacti
 	)
 
 	handler := func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
-		result, err := r.executor.GetPageInfo(ctx)
+		result, err := r.executorFrom(ctx).GetPageInfo(ctx)
 		if err != nil {
 			return mcpgo.NewToolResultError(err.Error()), nil
 		}
@@ -433,7 +528,7 @@ For interactive elements, use browser_snapshot to get refs for precise interacti
 		return mcpgo.NewToolResultText(string(data)), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
 	return nil
 }
 
@@ -464,7 +559,7 @@ func (r *MCPToolRegistry) registerWaitForTool() error {
 			opts.Timeout = time.Duration(timeout) * time.Second
 		}
 
-		result, err := r.executor.WaitFor(ctx, identifier, opts)
+		result, err := r.executorFrom(ctx).WaitFor(ctx, identifier, opts)
 		if err != nil {
 			return mcpgo.NewToolResultError(err.Error()), nil
 		}
@@ -472,10 +567,69 @@ func (r *MCPToolRegistry) registerWaitForTool() error {
 		return mcpgo.NewToolResultText(result.Message), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
+	return nil
+}
+
+// registerAssertTool 注册断言工具
+func (r *MCPToolRegistry) registerAssertTool() error {
+	tool := mcpgo.NewTool(
+		"browser_assert",
+		mcpgo.WithDescription("Assert the state of the current page: element visible/hidden, element text, attribute value, element count, URL, title or a variable. Hard assertions (default) return an error when they fail; soft assertions report the failure as a normal result."),
+		mcpgo.WithString("kind", mcpgo.Required(), mcpgo.Description("Assertion kind: visible, hidden, text_equals, text_contains, text_matches, attribute, url_matches, title_matches, count or variable")),
+		mcpgo.WithString("identifier", mcpgo.Description("Element identifier for element assertions (RefID like @e1, CSS selector or XPath)")),
+		mcpgo.WithString("expected", mcpgo.Description("Expected text, regular expression (text_matches, url_matches, title_matches), count or value; ${var} is replaced from variables")),
+		mcpgo.WithString("attribute", mcpgo.Description("Attribute name (kind=attribute)")),
+		mcpgo.WithString("variable", mcpgo.Description("Variable name (kind=variable)")),
+		mcpgo.WithObject("variables", mcpgo.Description("Variables available to kind=variable and ${var} substitution")),
+		mcpgo.WithString("operator", mcpgo.Description("Comparison for attribute, count and variable: =, !=, >, <, >=, <=, contains, not_contains, matches, in, not_in, exists, not_exists (default: =)")),
+		mcpgo.WithString("mode", mcpgo.Description("Failure mode: hard (default) or soft")),
+		mcpgo.WithString("message", mcpgo.Description("Custom failure message")),
+		mcpgo.WithNumber("wait_ms", mcpgo.Description("Keep re-checking for up to this many milliseconds before failing (default: 0, check once)")),
+	)
+
+	handler := func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+		args, _ := request.Params.Arguments.(map[string]interface{})
+		result, err := r.executorFrom(ctx).AssertFromArgs(ctx, args)
+		if result == nil {
+			return mcpgo.NewToolResultError(err.Error()), nil
+		}
+
+		data, _ := json.Marshal(result.Data)
+		text := result.Message + "\n" + string(data)
+		if err != nil {
+			return mcpgo.NewToolResultError(text), nil
+		}
+		return mcpgo.NewToolResultText(text), nil
+	}
+
+	r.addTool(tool, handler)
 	return nil
 }
 
+// AssertFromArgs 根据 MCP 工具参数检查断言
+func (e *Executor) AssertFromArgs(ctx context.Context, args map[string]interface{}) (*OperationResult, error) {
+	var req struct {
+		models.AssertOptions
+		Identifier string            `json:"identifier"`
+		Variables  map[string]string `json:"variables"`
+	}
+	fields := make(map[string]interface{}, len(args))
+	for key, value := range args {
+		if key != "session_id" {
+			fields[key] = value
+		}
+	}
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid assert options: %w", err)
+	}
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return nil, fmt.Errorf("invalid assert options: %w", err)
+	}
+	return e.Assert(ctx, req.Identifier, &req.AssertOptions, req.Variables)
+}
+
 // registerScrollTool 注册滚动工具
 func (r *MCPToolRegistry) registerScrollTool() error {
 	tool := mcpgo.NewTool(
@@ -493,10 +647,10 @@ func (r *MCPToolRegistry) registerScrollTool() error {
 
 		switch direction {
 		case "bottom":
-			result, err = r.executor.ScrollToBottom(ctx)
+			result, err = r.executorFrom(ctx).ScrollToBottom(ctx)
 		case "top":
 			// Scroll to top
-			page := r.executor.GetRodPage()
+			page := r.executorFrom(ctx).GetRodPage()
 			if page != nil {
 				// 使用安全的滚动操作,防止 panic
 				err = safeScrollToTop(ctx, page)
@@ -509,9 +663,9 @@ func (r *MCPToolRegistry) registerScrollTool() error {
 			}
 		default:
 			// 滚动到元素
-			page := r.executor.GetRodPage()
+			page := r.executorFrom(ctx).GetRodPage()
 			if page != nil {
-				elem, findErr := r.executor.findElement(ctx, page, direction)
+				elem, findErr := r.executorFrom(ctx).findElement(ctx, page, direction)
 				if findErr != nil {
 					return mcpgo.NewToolResultError(findErr.Error()), findErr
 				}
@@ -532,7 +686,7 @@ func (r *MCPToolRegistry) registerScrollTool() error {
 		return mcpgo.NewToolResultText(result.Message), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
 	return nil
 }
 
@@ -543,6 +697,7 @@ func (r *MCPToolRegistry) registerScreenshotTool() error {
 		mcpgo.WithDescription("Take a screenshot of the current page"),
 		mcpgo.WithBoolean("full_page", mcpgo.Description("Capture full page (default: false)")),
 		mcpgo.WithString("format", mcpgo.Description("Image format: png or jpeg (default: png)")),
+		mcpgo.WithBoolean("annotate", mcpgo.Description("Draw numbered boxes labelled with RefIDs (@e1, @e2...) over interactive elements and return the image together with the ref legend. Use the RefIDs as identifiers for click/type (default: false)")),
 	)
 
 	handler := func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
@@ -560,12 +715,24 @@ func (r *MCPToolRegistry) registerScreenshotTool() error {
 		if format, ok := args["format"].(string); ok && format != "" {
 			opts.Format = format
 		}
+		opts.Annotate, _ = args["annotate"].(bool)
 
-		result, err := r.executor.Screenshot(ctx, opts)
+		result, err := r.executorFrom(ctx).Screenshot(ctx, opts)
 		if err != nil {
 			return mcpgo.NewToolResultError(err.Error()), nil
 		}
 
+		// 标注模式直接返回图片和图例，便于多模态模型按编号选择元素
+		if opts.Annotate {
+			data, _ := result.Data["data"].([]byte)
+			legend, _ := result.Data["legend"].(string)
+			mimeType := "image/png"
+			if opts.Format == "jpeg" || opts.Format == "jpg" {
+				mimeType = "image/jpeg"
+			}
+			return mcpgo.NewToolResultImage(result.Message+"\n\nMARKS:\n"+legend, base64.StdEncoding.EncodeToString(data), mimeType), nil
+		}
+
 		// 构建返回消息，包含路径信息
 		message := result.Message
 		if path, ok := result.Data["path"].(string); ok && path != "" {
@@ -579,10 +746,102 @@ func (r *MCPToolRegistry) registerScreenshotTool() error {
 		return mcpgo.NewToolResultText(message), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
 	return nil
 }
 
+// registerPDFTool 注册打印 PDF 工具
+func (r *MCPToolRegistry) registerPDFTool() error {
+	tool := mcpgo.NewTool(
+		"browser_pdf",
+		mcpgo.WithDescription("Print the current page to PDF and save it to a file (requires a headless browser). Returns the file path, size, SHA-256, page URL and title."),
+		mcpgo.WithString("paper_format", mcpgo.Description("Paper format: A3, A4 (default), A5, Letter, Legal or Tabloid")),
+		mcpgo.WithNumber("paper_width", mcpgo.Description("Custom paper width in inches (overrides paper_format)")),
+		mcpgo.WithNumber("paper_height", mcpgo.Description("Custom paper height in inches (overrides paper_format)")),
+		mcpgo.WithBoolean("landscape", mcpgo.Description("Landscape orientation (default: false)")),
+		mcpgo.WithNumber("scale", mcpgo.Description("Scale between 0.1 and 2 (default: 1)")),
+		mcpgo.WithNumber("margin_top", mcpgo.Description("Top margin in inches")),
+		mcpgo.WithNumber("margin_bottom", mcpgo.Description("Bottom margin in inches")),
+		mcpgo.WithNumber("margin_left", mcpgo.Description("Left margin in inches")),
+		mcpgo.WithNumber("margin_right", mcpgo.Description("Right margin in inches")),
+		mcpgo.WithBoolean("print_background", mcpgo.Description("Print background colors and images (default: false)")),
+		mcpgo.WithString("page_ranges", mcpgo.Description("Pages to print, e.g. '1-5, 8' (default: all)")),
+		mcpgo.WithString("header_template", mcpgo.Description("Header HTML template; elements with class date, title, url, pageNumber or totalPages are filled in")),
+		mcpgo.WithString("footer_template", mcpgo.Description("Footer HTML template")),
+	)
+
+	handler := func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+		args, _ := request.Params.Arguments.(map[string]interface{})
+		result, err := r.executorFrom(ctx).PDFFromArgs(ctx, args)
+		if err != nil {
+			return mcpgo.NewToolResultError(err.Error()), nil
+		}
+		return pageCaptureToolResult(result), nil
+	}
+
+	r.addTool(tool, handler)
+	return nil
+}
+
+// registerArchiveTool 注册 MHTML 存档工具
+func (r *MCPToolRegistry) registerArchiveTool() error {
+	tool := mcpgo.NewTool(
+		"browser_archive",
+		mcpgo.WithDescription("Save the current page with its styles, images and iframes as a single MHTML archive. Returns the file path, size, SHA-256, page URL and title."),
+	)
+
+	handler := func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+		result, err := r.executorFrom(ctx).Archive(ctx)
+		if err != nil {
+			return mcpgo.NewToolResultError(err.Error()), nil
+		}
+		return pageCaptureToolResult(result), nil
+	}
+
+	r.addTool(tool, handler)
+	return nil
+}
+
+// pageCaptureToolResult 构建 PDF/存档工具的返回内容（不包含文件数据）
+func pageCaptureToolResult(result *OperationResult) *mcpgo.CallToolResult {
+	info := make(map[string]interface{}, len(result.Data))
+	for key, value := range result.Data {
+		if key != "data" {
+			info[key] = value
+		}
+	}
+	if path, ok := info["path"].(string); ok {
+		if absPath, err := filepath.Abs(path); err == nil {
+			info["path"] = absPath
+		}
+	}
+
+	data, _ := json.Marshal(info)
+	return mcpgo.NewToolResultText(result.Message + "\n" + string(data))
+}
+
+// PDFFromArgs 根据 MCP 工具参数将当前页面打印为 PDF
+func (e *Executor) PDFFromArgs(ctx context.Context, args map[string]interface{}) (*OperationResult, error) {
+	fields := make(map[string]interface{}, len(args))
+	for key, value := range args {
+		if key != "session_id" {
+			fields[key] = value
+		}
+	}
+	var opts models.PDFOptions
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pdf options: %w", err)
+	}
+	if err := json.Unmarshal(raw, &opts); err != nil {
+		return nil, fmt.Errorf("invalid pdf options: %w", err)
+	}
+	if err := browser.ValidatePDFOptions(&opts); err != nil {
+		return nil, err
+	}
+	return e.PDF(ctx, &opts)
+}
+
 // registerEvaluateTool 注册执行脚本工具
 func (r *MCPToolRegistry) registerEvaluateTool() error {
 	tool := mcpgo.NewTool(
@@ -613,7 +872,7 @@ Note: Always use 'return' to return values. The result will be serialized as JSO
 		args := request.Params.Arguments.(map[string]interface{})
 		script, _ := args["script"].(string)
 
-		result, err := r.executor.Evaluate(ctx, script)
+		result, err := r.executorFrom(ctx).Evaluate(ctx, script)
 		if err != nil {
 			return mcpgo.NewToolResultError(err.Error()), nil
 		}
@@ -625,7 +884,7 @@ Note: Always use 'return' to return values. The result will be serialized as JSO
 		return mcpgo.NewToolResultText(result.Message), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
 	return nil
 }
 
@@ -659,7 +918,7 @@ func (r *MCPToolRegistry) registerPressKeyTool() error {
 			opts.Meta = meta
 		}
 
-		result, err := r.executor.PressKey(ctx, key, opts)
+		result, err := r.executorFrom(ctx).PressKey(ctx, key, opts)
 		if err != nil {
 			return mcpgo.NewToolResultError(err.Error()), nil
 		}
@@ -667,7 +926,7 @@ func (r *MCPToolRegistry) registerPressKeyTool() error {
 		return mcpgo.NewToolResultText(result.Message), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
 	return nil
 }
 
@@ -697,7 +956,7 @@ func (r *MCPToolRegistry) registerResizeTool() error {
 			return mcpgo.NewToolResultError("Invalid width or height"), nil
 		}
 
-		result, err := r.executor.Resize(ctx, width, height)
+		result, err := r.executorFrom(ctx).Resize(ctx, width, height)
 		if err != nil {
 			return mcpgo.NewToolResultError(err.Error()), nil
 		}
@@ -705,7 +964,7 @@ func (r *MCPToolRegistry) registerResizeTool() error {
 		return mcpgo.NewToolResultText(result.Message), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
 	return nil
 }
 
@@ -723,7 +982,7 @@ func (r *MCPToolRegistry) registerDragTool() error {
 		fromIdentifier, _ := args["from_identifier"].(string)
 		toIdentifier, _ := args["to_identifier"].(string)
 
-		result, err := r.executor.Drag(ctx, fromIdentifier, toIdentifier)
+		result, err := r.executorFrom(ctx).Drag(ctx, fromIdentifier, toIdentifier)
 		if err != nil {
 			return mcpgo.NewToolResultError(err.Error()), nil
 		}
@@ -731,7 +990,7 @@ func (r *MCPToolRegistry) registerDragTool() error {
 		return mcpgo.NewToolResultText(result.Message), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
 	return nil
 }
 
@@ -743,7 +1002,7 @@ func (r *MCPToolRegistry) registerClosePageTool() error {
 	)
 
 	handler := func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
-		result, err := r.executor.ClosePage(ctx)
+		result, err := r.executorFrom(ctx).ClosePage(ctx)
 		if err != nil {
 			return mcpgo.NewToolResultError(err.Error()), nil
 		}
@@ -751,7 +1010,7 @@ func (r *MCPToolRegistry) registerClosePageTool() error {
 		return mcpgo.NewToolResultText(result.Message), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
 	return nil
 }
 
@@ -781,7 +1040,7 @@ func (r *MCPToolRegistry) registerFileUploadTool() error {
 			return mcpgo.NewToolResultError("No file paths provided"), nil
 		}
 
-		result, err := r.executor.FileUpload(ctx, identifier, filePaths)
+		result, err := r.executorFrom(ctx).FileUpload(ctx, identifier, filePaths)
 		if err != nil {
 			return mcpgo.NewToolResultError(err.Error()), nil
 		}
@@ -789,7 +1048,7 @@ func (r *MCPToolRegistry) registerFileUploadTool() error {
 		return mcpgo.NewToolResultText(result.Message), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
 	return nil
 }
 
@@ -814,7 +1073,7 @@ func (r *MCPToolRegistry) registerHandleDialogTool() error {
 			text = t
 		}
 
-		result, err := r.executor.HandleDialog(ctx, accept, text)
+		result, err := r.executorFrom(ctx).HandleDialog(ctx, accept, text)
 		if err != nil {
 			return mcpgo.NewToolResultError(err.Error()), nil
 		}
@@ -822,7 +1081,7 @@ func (r *MCPToolRegistry) registerHandleDialogTool() error {
 		return mcpgo.NewToolResultText(result.Message), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
 	return nil
 }
 
@@ -830,42 +1089,363 @@ func (r *MCPToolRegistry) registerHandleDialogTool() error {
 func (r *MCPToolRegistry) registerGetConsoleMessagesTool() error {
 	tool := mcpgo.NewTool(
 		"browser_console_messages",
-		mcpgo.WithDescription("Get console messages from the browser"),
+		mcpgo.WithDescription(`Get console messages from the current page.
+
+Messages are buffered from the moment the page was created, including console.* calls,
+uncaught exceptions and browser log entries (network errors, security warnings, etc.).
+Pass the returned cursor back to fetch only messages that arrived since the previous call.`),
+		mcpgo.WithString("level", mcpgo.Description("Comma-separated levels to include: debug, log, info, warning, error (default: all)")),
+		mcpgo.WithString("since", mcpgo.Description("Only messages after this time (RFC3339 or unix milliseconds)")),
+		mcpgo.WithNumber("cursor", mcpgo.Description("Only messages after this cursor (returned by the previous call)")),
+		mcpgo.WithNumber("limit", mcpgo.Description("Maximum number of messages to return, newest kept (default: all)")),
+		mcpgo.WithBoolean("clear", mcpgo.Description("Clear the buffer after reading (default: false)")),
 	)
 
 	handler := func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
-		result, err := r.executor.GetConsoleMessages(ctx)
+		args, _ := request.Params.Arguments.(map[string]interface{})
+		opts, err := ConsoleOptionsFromArgs(args)
 		if err != nil {
 			return mcpgo.NewToolResultError(err.Error()), nil
 		}
 
-		return mcpgo.NewToolResultText(result.Message), nil
+		result, err := r.executorFrom(ctx).GetConsoleMessages(ctx, opts)
+		if err != nil {
+			return mcpgo.NewToolResultError(err.Error()), nil
+		}
+
+		data, _ := json.Marshal(result.Data)
+		return mcpgo.NewToolResultText(string(data)), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
 	return nil
 }
 
+// ConsoleOptionsFromArgs 从 MCP 工具参数构建控制台查询选项
+func ConsoleOptionsFromArgs(args map[string]interface{}) (*ConsoleMessagesOptions, error) {
+	opts := &ConsoleMessagesOptions{}
+	if level, ok := args["level"].(string); ok && level != "" {
+		opts.Levels = strings.Split(level, ",")
+	}
+	if since, ok := args["since"].(string); ok && since != "" {
+		t, err := ParseSinceParam(since)
+		if err != nil {
+			return nil, err
+		}
+		opts.Since = t
+	}
+	if cursor, ok := args["cursor"].(float64); ok && cursor > 0 {
+		opts.Cursor = int64(cursor)
+	}
+	if limit, ok := args["limit"].(float64); ok && limit > 0 {
+		opts.Limit = int(limit)
+	}
+	if clear, ok := args["clear"].(bool); ok {
+		opts.Clear = clear
+	}
+	return opts, nil
+}
+
 // registerGetNetworkRequestsTool 注册网络请求工具
 func (r *MCPToolRegistry) registerGetNetworkRequestsTool() error {
 	tool := mcpgo.NewTool(
 		"browser_network_requests",
-		mcpgo.WithDescription("Get network requests made by the page"),
+		mcpgo.WithDescription(`Get network requests made by the current page.
+
+Requests are journaled from the moment the page was created, with status, headers and timings.
+Use filters to narrow the result, request_id to fetch a single response body,
+or format="har" to export the journal as HAR 1.2.`),
+		mcpgo.WithString("url", mcpgo.Description("Only requests whose URL contains this text")),
+		mcpgo.WithString("method", mcpgo.Description("Only requests with this HTTP method")),
+		mcpgo.WithString("status", mcpgo.Description("Status filter: exact code (404), class (4xx) or range (400-499)")),
+		mcpgo.WithString("resource_type", mcpgo.Description("Comma-separated resource types: Document, XHR, Fetch, Script, Stylesheet, Image ...")),
+		mcpgo.WithString("state", mcpgo.Description("Request state: pending, finished, failed")),
+		mcpgo.WithNumber("cursor", mcpgo.Description("Only requests after this cursor (returned by the previous call)")),
+		mcpgo.WithNumber("limit", mcpgo.Description("Maximum number of requests to return, newest kept (default: all)")),
+		mcpgo.WithBoolean("include_body", mcpgo.Description("Include response bodies (default: false)")),
+		mcpgo.WithString("request_id", mcpgo.Description("Fetch the response body of a single request")),
+		mcpgo.WithString("format", mcpgo.Description("Output format: json (default) or har")),
 	)
 
 	handler := func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
-		result, err := r.executor.GetNetworkRequests(ctx)
+		args, _ := request.Params.Arguments.(map[string]interface{})
+
+		if requestID, ok := args["request_id"].(string); ok && requestID != "" {
+			result, err := r.executorFrom(ctx).GetNetworkResponseBody(ctx, requestID)
+			if err != nil {
+				return mcpgo.NewToolResultError(err.Error()), nil
+			}
+			data, _ := json.Marshal(result.Data)
+			return mcpgo.NewToolResultText(string(data)), nil
+		}
+
+		opts := NetworkOptionsFromArgs(args)
+
+		if format, _ := args["format"].(string); strings.EqualFold(format, "har") {
+			har, err := r.executorFrom(ctx).ExportHAR(ctx, opts)
+			if err != nil {
+				return mcpgo.NewToolResultError(err.Error()), nil
+			}
+			data, _ := json.Marshal(har)
+			return mcpgo.NewToolResultText(string(data)), nil
+		}
+
+		result, err := r.executorFrom(ctx).GetNetworkRequests(ctx, opts)
 		if err != nil {
 			return mcpgo.NewToolResultError(err.Error()), nil
 		}
 
-		return mcpgo.NewToolResultText(result.Message), nil
+		data, _ := json.Marshal(result.Data)
+		return mcpgo.NewToolResultText(string(data)), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
+	return nil
+}
+
+// registerRouteTool 注册请求拦截工具
+func (r *MCPToolRegistry) registerRouteTool() error {
+	tool := mcpgo.NewTool(
+		"browser_route",
+		mcpgo.WithDescription(`Intercept network requests of the current page.
+
+Rules are matched in order and the first match wins. Each rule has:
+- url_pattern: wildcard pattern (* any characters, ? one character), e.g. "*://*.doubleclick.net/*"
+- method / resource_type (optional): restrict by HTTP method or resource type (Document, XHR, Fetch, Script, Image ...)
+- action: "block", "fulfill" (status, headers, content_type, body, body_base64), "modify_headers" (request_headers, response_headers; empty value removes a header) or "delay"
+- delay_ms (optional): delay before the action is applied
+
+Operations: "set" replaces the page's rules, "list" returns active rules with hit counts, "clear" disables interception.`),
+		mcpgo.WithString("operation", mcpgo.Description("Operation: set (default), list or clear")),
+		mcpgo.WithArray("rules", mcpgo.Description("Route rules (required for set)")),
+	)
+
+	handler := func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+		args, _ := request.Params.Arguments.(map[string]interface{})
+		result, err := r.executorFrom(ctx).RouteFromArgs(ctx, args)
+		if err != nil {
+			return mcpgo.NewToolResultError(err.Error()), nil
+		}
+
+		data, _ := json.Marshal(result.Data)
+		return mcpgo.NewToolResultText(result.Message + "\n" + string(data)), nil
+	}
+
+	r.addTool(tool, handler)
+	return nil
+}
+
+// RouteFromArgs 根据 MCP 工具参数执行请求拦截操作
+func (e *Executor) RouteFromArgs(ctx context.Context, args map[string]interface{}) (*OperationResult, error) {
+	operation, _ := args["operation"].(string)
+	switch operation {
+	case "list":
+		return e.GetRouteRules(ctx)
+	case "clear":
+		return e.SetRouteRules(ctx, nil)
+	case "", "set":
+		var rules []models.RouteRule
+		raw, err := json.Marshal(args["rules"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rules: %w", err)
+		}
+		if err := json.Unmarshal(raw, &rules); err != nil {
+			return nil, fmt.Errorf("invalid rules: %w", err)
+		}
+		if len(rules) == 0 {
+			return nil, fmt.Errorf("rules are required for set, use operation=clear to disable interception")
+		}
+		return e.SetRouteRules(ctx, rules)
+	default:
+		return nil, fmt.Errorf("unsupported operation: %s", operation)
+	}
+}
+
+// registerEmulateTool 注册仿真工具
+func (r *MCPToolRegistry) registerEmulateTool() error {
+	tool := mcpgo.NewTool(
+		"browser_emulate",
+		mcpgo.WithDescription(`Emulate a device, locale, timezone, geolocation, media features or network conditions on the current page.
+
+Only the parameters you pass are changed. Set reset=true to clear previous emulation first (User-Agent is kept).
+Locale, timezone and Accept-Language changes take full effect after the page is reloaded.
+Preset devices: `+strings.Join(browser.EmulationDeviceNames(), ", ")),
+		mcpgo.WithString("device", mcpgo.Description("Preset device name, e.g. 'iPhone X' or 'Pixel 2' (other parameters override the preset)")),
+		mcpgo.WithBoolean("landscape", mcpgo.Description("Use the preset device in landscape orientation")),
+		mcpgo.WithNumber("width", mcpgo.Description("Viewport width in CSS pixels")),
+		mcpgo.WithNumber("height", mcpgo.Description("Viewport height in CSS pixels")),
+		mcpgo.WithNumber("device_scale_factor", mcpgo.Description("Device pixel ratio (DPR)")),
+		mcpgo.WithBoolean("mobile", mcpgo.Description("Emulate a mobile device (meta viewport, overlay scrollbars)")),
+		mcpgo.WithBoolean("touch", mcpgo.Description("Enable touch events")),
+		mcpgo.WithString("user_agent", mcpgo.Description("User-Agent string")),
+		mcpgo.WithString("locale", mcpgo.Description("Locale such as de-DE (also sets Accept-Language unless accept_language is given)")),
+		mcpgo.WithString("accept_language", mcpgo.Description("Accept-Language header value")),
+		mcpgo.WithString("timezone", mcpgo.Description("IANA timezone such as Europe/Berlin")),
+		mcpgo.WithNumber("latitude", mcpgo.Description("Geolocation latitude (requires longitude)")),
+		mcpgo.WithNumber("longitude", mcpgo.Description("Geolocation longitude (requires latitude)")),
+		mcpgo.WithNumber("accuracy", mcpgo.Description("Geolocation accuracy in meters (default: 100)")),
+		mcpgo.WithString("color_scheme", mcpgo.Description("prefers-color-scheme: light, dark or no-preference")),
+		mcpgo.WithString("reduced_motion", mcpgo.Description("prefers-reduced-motion: reduce or no-preference")),
+		mcpgo.WithString("network", mcpgo.Description("Network conditions: online, offline, slow-3g, fast-3g or 4g")),
+		mcpgo.WithNumber("latency_ms", mcpgo.Description("Custom extra latency in milliseconds")),
+		mcpgo.WithNumber("download_kbps", mcpgo.Description("Custom download bandwidth in kbit/s")),
+		mcpgo.WithNumber("upload_kbps", mcpgo.Description("Custom upload bandwidth in kbit/s")),
+		mcpgo.WithBoolean("reset", mcpgo.Description("Clear previous emulation before applying (default: false)")),
+	)
+
+	handler := func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+		args, _ := request.Params.Arguments.(map[string]interface{})
+		result, err := r.executorFrom(ctx).EmulateFromArgs(ctx, args)
+		if err != nil {
+			return mcpgo.NewToolResultError(err.Error()), nil
+		}
+
+		data, _ := json.Marshal(result.Data)
+		return mcpgo.NewToolResultText(result.Message + "\n" + string(data)), nil
+	}
+
+	r.addTool(tool, handler)
+	return nil
+}
+
+// EmulateFromArgs 根据 MCP 工具参数切换页面的仿真配置
+func (e *Executor) EmulateFromArgs(ctx context.Context, args map[string]interface{}) (*OperationResult, error) {
+	reset, _ := args["reset"].(bool)
+
+	fields := make(map[string]interface{}, len(args))
+	for key, value := range args {
+		switch key {
+		case "reset", "session_id", "latitude", "longitude", "accuracy":
+		default:
+			fields[key] = value
+		}
+	}
+	var profile models.EmulationProfile
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid emulation settings: %w", err)
+	}
+	if err := json.Unmarshal(raw, &profile); err != nil {
+		return nil, fmt.Errorf("invalid emulation settings: %w", err)
+	}
+
+	latitude, hasLatitude := args["latitude"].(float64)
+	longitude, hasLongitude := args["longitude"].(float64)
+	if hasLatitude != hasLongitude {
+		return nil, fmt.Errorf("latitude and longitude must be provided together")
+	}
+	if hasLatitude {
+		accuracy, _ := args["accuracy"].(float64)
+		profile.Geolocation = &models.Geolocation{Latitude: latitude, Longitude: longitude, Accuracy: accuracy}
+	}
+
+	if profile == (models.EmulationProfile{}) {
+		return e.Emulate(ctx, nil, reset)
+	}
+	return e.Emulate(ctx, &profile, reset)
+}
+
+// registerStorageStateTool 注册存储状态工具
+func (r *MCPToolRegistry) registerStorageStateTool() error {
+	tool := mcpgo.NewTool(
+		"browser_storage_state",
+		mcpgo.WithDescription(`Capture or restore the storage state of the current page's browser context: cookies plus per-origin localStorage, sessionStorage and optionally IndexedDB.
+
+Use "get" after logging in (optionally with save_as to store it), and "set" with a saved id or a state object to restore the login later.
+Reload the page after "set" so the application picks up the restored state.`),
+		mcpgo.WithString("action", mcpgo.Description("Action: get (default) or set")),
+		mcpgo.WithString("origins", mcpgo.Description("get: comma-separated additional origins to capture (the current page's origin is always included)")),
+		mcpgo.WithBoolean("include_indexed_db", mcpgo.Description("get: also capture IndexedDB databases (default: false)")),
+		mcpgo.WithString("save_as", mcpgo.Description("get: save the captured state under this name")),
+		mcpgo.WithString("id", mcpgo.Description("set: ID of a saved storage state")),
+		mcpgo.WithObject("state", mcpgo.Description("set: storage state object {cookies, origins}")),
+	)
+
+	handler := func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+		args, _ := request.Params.Arguments.(map[string]interface{})
+		result, err := r.executorFrom(ctx).StorageStateFromArgs(ctx, args)
+		if err != nil {
+			return mcpgo.NewToolResultError(err.Error()), nil
+		}
+
+		data, _ := json.Marshal(result.Data)
+		return mcpgo.NewToolResultText(result.Message + "\n" + string(data)), nil
+	}
+
+	r.addTool(tool, handler)
 	return nil
 }
 
+// StorageStateFromArgs 根据 MCP 工具参数捕获或恢复存储状态
+func (e *Executor) StorageStateFromArgs(ctx context.Context, args map[string]interface{}) (*OperationResult, error) {
+	action, _ := args["action"].(string)
+	switch action {
+	case "", "get":
+		opts := browser.StorageCaptureOptions{}
+		if origins, ok := args["origins"].(string); ok && origins != "" {
+			opts.Origins = strings.Split(origins, ",")
+		}
+		opts.IndexedDB, _ = args["include_indexed_db"].(bool)
+		saveAs, _ := args["save_as"].(string)
+		return e.GetStorageState(ctx, opts, saveAs)
+	case "set":
+		id, _ := args["id"].(string)
+		var state *models.StorageState
+		if raw, ok := args["state"]; ok && raw != nil {
+			data, err := json.Marshal(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid state: %w", err)
+			}
+			state = &models.StorageState{}
+			if err := json.Unmarshal(data, state); err != nil {
+				return nil, fmt.Errorf("invalid state: %w", err)
+			}
+		}
+		return e.SetStorageState(ctx, state, id)
+	default:
+		return nil, fmt.Errorf("unsupported action: %s", action)
+	}
+}
+
+// NetworkOptionsFromArgs 从 MCP 工具参数构建网络请求查询选项
+func NetworkOptionsFromArgs(args map[string]interface{}) *NetworkRequestsOptions {
+	opts := &NetworkRequestsOptions{}
+	opts.URL, _ = args["url"].(string)
+	opts.Method, _ = args["method"].(string)
+	opts.Status, _ = args["status"].(string)
+	opts.State, _ = args["state"].(string)
+	if resourceType, ok := args["resource_type"].(string); ok && resourceType != "" {
+		opts.ResourceTypes = strings.Split(resourceType, ",")
+	}
+	if cursor, ok := args["cursor"].(float64); ok && cursor > 0 {
+		opts.Cursor = int64(cursor)
+	}
+	if limit, ok := args["limit"].(float64); ok && limit > 0 {
+		opts.Limit = int(limit)
+	}
+	if includeBody, ok := args["include_body"].(bool); ok {
+		opts.IncludeBody = includeBody
+	}
+	return opts
+}
+
+// SnapshotSerializeOptionsFromArgs 从 MCP 工具参数构建快照预算序列化选项
+func SnapshotSerializeOptionsFromArgs(args map[string]interface{}) *SnapshotSerializeOptions {
+	opts := &SnapshotSerializeOptions{}
+	if maxTokens, ok := args["max_tokens"].(float64); ok && maxTokens > 0 {
+		opts.MaxTokens = int(maxTokens)
+	}
+	if maxChars, ok := args["max_chars"].(float64); ok && maxChars > 0 {
+		opts.MaxChars = int(maxChars)
+	}
+	if listLimit, ok := args["list_limit"].(float64); ok && listLimit > 0 {
+		opts.ListLimit = int(listLimit)
+	}
+	opts.ViewportOnly, _ = args["viewport_only"].(bool)
+	opts.Root, _ = args["root"].(string)
+	return opts
+}
+
 // GetToolMetadata 获取所有工具的元数据（用于文档生成）
 func (r *MCPToolRegistry) GetToolMetadata() []ToolMetadata {
 	return GetExecutorToolsMetadata()
@@ -890,6 +1470,7 @@ func GetExecutorToolsMetadata() []ToolMetadata {
 			Parameters: []ToolParameter{
 				{Name: "identifier", Type: "string", Required: true, Description: "Element identifier"},
 				{Name: "wait_visible", Type: "boolean", Required: false, Description: "Wait for element to be visible"},
+				{Name: "snapshot_diff", Type: "boolean", Required: false, Description: "Return only the changes since the previous snapshot"},
 			},
 		},
 		{
@@ -900,6 +1481,7 @@ func GetExecutorToolsMetadata() []ToolMetadata {
 				{Name: "identifier", Type: "string", Required: true, Description: "Element identifier"},
 				{Name: "text", Type: "string", Required: true, Description: "Text to type"},
 				{Name: "clear", Type: "boolean", Required: false, Description: "Clear existing text"},
+				{Name: "snapshot_diff", Type: "boolean", Required: false, Description: "Return only the changes since the previous snapshot"},
 			},
 		},
 		{
@@ -909,6 +1491,7 @@ func GetExecutorToolsMetadata() []ToolMetadata {
 			Parameters: []ToolParameter{
 				{Name: "identifier", Type: "string", Required: true, Description: "Select element identifier"},
 				{Name: "value", Type: "string", Required: true, Description: "Option value or text"},
+				{Name: "snapshot_diff", Type: "boolean", Required: false, Description: "Return only the changes since the previous snapshot"},
 			},
 		},
 		{
@@ -923,10 +1506,16 @@ func GetExecutorToolsMetadata() []ToolMetadata {
 		},
 		{
 			Name:        "browser_snapshot",
-			Description: "Get the accessibility snapshot of the current page. Returns a tree structure representing the page's accessibility tree, which is cleaner than raw DOM and better for LLMs to understand.",
+			Description: "Get the accessibility snapshot of the current page. Returns a tree structure representing the page's accessibility tree, which is cleaner than raw DOM and better for LLMs to understand. Elements inside iframes are included and tagged with their frame; their RefIDs can be used directly.",
 			Category:    "Analysis",
 			Parameters: []ToolParameter{
 				{Name: "max_depth", Type: "number", Required: false, Description: "Maximum depth of the tree (default: unlimited)"},
+				{Name: "diff", Type: "boolean", Required: false, Description: "Return only elements added, removed or changed since the previous snapshot (default: false)"},
+				{Name: "max_tokens", Type: "number", Required: false, Description: "Approximate token budget for the text snapshot"},
+				{Name: "max_chars", Type: "number", Required: false, Description: "Character budget for the text snapshot"},
+				{Name: "viewport_only", Type: "boolean", Required: false, Description: "Only include elements in the current viewport"},
+				{Name: "root", Type: "string", Required: false, Description: "Limit to the subtree under a RefID or landmark (main, navigation, form:Login, list#2)"},
+				{Name: "list_limit", Type: "number", Required: false, Description: "Collapse lists after this many items"},
 			},
 		},
 		{
@@ -945,6 +1534,23 @@ func GetExecutorToolsMetadata() []ToolMetadata {
 				{Name: "timeout", Type: "number", Required: false, Description: "Timeout in seconds"},
 			},
 		},
+		{
+			Name:        "browser_assert",
+			Description: "Assert page state (element visible/hidden, text, attribute, count, URL, title or variable) in hard or soft mode",
+			Category:    "Assertion",
+			Parameters: []ToolParameter{
+				{Name: "kind", Type: "string", Required: true, Description: "visible, hidden, text_equals, text_contains, text_matches, attribute, url_matches, title_matches, count or variable"},
+				{Name: "identifier", Type: "string", Required: false, Description: "Element identifier for element assertions"},
+				{Name: "expected", Type: "string", Required: false, Description: "Expected text, regular expression, count or value"},
+				{Name: "attribute", Type: "string", Required: false, Description: "Attribute name (kind=attribute)"},
+				{Name: "variable", Type: "string", Required: false, Description: "Variable name (kind=variable)"},
+				{Name: "variables", Type: "object", Required: false, Description: "Variables for kind=variable and ${var} substitution"},
+				{Name: "operator", Type: "string", Required: false, Description: "Comparison for attribute, count and variable (default: =)"},
+				{Name: "mode", Type: "string", Required: false, Description: "hard (default) or soft"},
+				{Name: "message", Type: "string", Required: false, Description: "Custom failure message"},
+				{Name: "wait_ms", Type: "number", Required: false, Description: "Re-check for up to this many milliseconds"},
+			},
+		},
 		{
 			Name:        "browser_scroll",
 			Description: "Scroll the page",
@@ -960,8 +1566,30 @@ func GetExecutorToolsMetadata() []ToolMetadata {
 			Parameters: []ToolParameter{
 				{Name: "full_page", Type: "boolean", Required: false, Description: "Capture full page"},
 				{Name: "format", Type: "string", Required: false, Description: "Image format: png or jpeg"},
+				{Name: "annotate", Type: "boolean", Required: false, Description: "Label interactive elements with their RefIDs (set-of-marks) and return the legend"},
+			},
+		},
+		{
+			Name:        "browser_pdf",
+			Description: "Print the current page to PDF (headless only) and save it to a file",
+			Category:    "Capture",
+			Parameters: []ToolParameter{
+				{Name: "paper_format", Type: "string", Required: false, Description: "A3, A4 (default), A5, Letter, Legal or Tabloid"},
+				{Name: "landscape", Type: "boolean", Required: false, Description: "Landscape orientation"},
+				{Name: "scale", Type: "number", Required: false, Description: "Scale between 0.1 and 2"},
+				{Name: "margin_top", Type: "number", Required: false, Description: "Top margin in inches (also margin_bottom, margin_left, margin_right)"},
+				{Name: "print_background", Type: "boolean", Required: false, Description: "Print background colors and images"},
+				{Name: "page_ranges", Type: "string", Required: false, Description: "Pages to print, e.g. '1-5, 8'"},
+				{Name: "header_template", Type: "string", Required: false, Description: "Header HTML template"},
+				{Name: "footer_template", Type: "string", Required: false, Description: "Footer HTML template"},
 			},
 		},
+		{
+			Name:        "browser_archive",
+			Description: "Save the current page as a single MHTML archive",
+			Category:    "Capture",
+			Parameters:  []ToolParameter{},
+		},
 		{
 			Name:        "browser_evaluate",
 			Description: "Execute JavaScript code in the browser context. Scripts are automatically wrapped in a function if needed. Use 'return' to return values. Examples: 'return document.title;' or 'const x = 1; return x + 2;'",
@@ -1026,15 +1654,41 @@ func GetExecutorToolsMetadata() []ToolMetadata {
 		},
 		{
 			Name:        "browser_console_messages",
-			Description: "Get console messages from the browser",
+			Description: "Get buffered console messages, exceptions and browser logs from the current page",
 			Category:    "Debug",
-			Parameters:  []ToolParameter{},
+			Parameters: []ToolParameter{
+				{Name: "level", Type: "string", Required: false, Description: "Comma-separated levels: debug, log, info, warning, error"},
+				{Name: "since", Type: "string", Required: false, Description: "Only messages after this time (RFC3339 or unix ms)"},
+				{Name: "cursor", Type: "number", Required: false, Description: "Only messages after this cursor"},
+				{Name: "limit", Type: "number", Required: false, Description: "Maximum number of messages to return"},
+				{Name: "clear", Type: "boolean", Required: false, Description: "Clear the buffer after reading"},
+			},
 		},
 		{
 			Name:        "browser_network_requests",
-			Description: "Get network requests made by the page",
+			Description: "Get the page's network journal (status, headers, timings), fetch response bodies or export HAR 1.2",
 			Category:    "Debug",
-			Parameters:  []ToolParameter{},
+			Parameters: []ToolParameter{
+				{Name: "url", Type: "string", Required: false, Description: "Only requests whose URL contains this text"},
+				{Name: "method", Type: "string", Required: false, Description: "Only requests with this HTTP method"},
+				{Name: "status", Type: "string", Required: false, Description: "Status filter: 404, 4xx or 400-499"},
+				{Name: "resource_type", Type: "string", Required: false, Description: "Comma-separated resource types (XHR, Fetch, Document ...)"},
+				{Name: "state", Type: "string", Required: false, Description: "Request state: pending, finished, failed"},
+				{Name: "cursor", Type: "number", Required: false, Description: "Only requests after this cursor"},
+				{Name: "limit", Type: "number", Required: false, Description: "Maximum number of requests to return"},
+				{Name: "include_body", Type: "boolean", Required: false, Description: "Include response bodies"},
+				{Name: "request_id", Type: "string", Required: false, Description: "Fetch the response body of a single request"},
+				{Name: "format", Type: "string", Required: false, Description: "Output format: json or har"},
+			},
+		},
+		{
+			Name:        "browser_route",
+			Description: "Intercept requests of the current page: block, fulfill, modify headers or delay",
+			Category:    "Network",
+			Parameters: []ToolParameter{
+				{Name: "operation", Type: "string", Required: false, Description: "Operation: set (default), list or clear"},
+				{Name: "rules", Type: "array", Required: false, Description: "Route rules (url_pattern, method, resource_type, action, status, headers, body, request_headers, response_headers, delay_ms)"},
+			},
 		},
 		{
 			Name:        "browser_tabs",
@@ -1056,6 +1710,52 @@ func GetExecutorToolsMetadata() []ToolMetadata {
 				{Name: "timeout", Type: "number", Required: false, Description: "Timeout per field in seconds (default: 10)"},
 			},
 		},
+		{
+			Name:        "browser_emulate",
+			Description: "Emulate device, locale, timezone, geolocation, color scheme, reduced motion and network conditions on the current page",
+			Category:    "Window",
+			Parameters: []ToolParameter{
+				{Name: "device", Type: "string", Required: false, Description: "Preset device name (e.g. 'iPhone X', 'Pixel 2')"},
+				{Name: "width", Type: "number", Required: false, Description: "Viewport width in CSS pixels"},
+				{Name: "height", Type: "number", Required: false, Description: "Viewport height in CSS pixels"},
+				{Name: "device_scale_factor", Type: "number", Required: false, Description: "Device pixel ratio"},
+				{Name: "mobile", Type: "boolean", Required: false, Description: "Emulate a mobile device"},
+				{Name: "touch", Type: "boolean", Required: false, Description: "Enable touch events"},
+				{Name: "locale", Type: "string", Required: false, Description: "Locale such as de-DE (also sets Accept-Language)"},
+				{Name: "timezone", Type: "string", Required: false, Description: "IANA timezone such as Europe/Berlin"},
+				{Name: "latitude", Type: "number", Required: false, Description: "Geolocation latitude"},
+				{Name: "longitude", Type: "number", Required: false, Description: "Geolocation longitude"},
+				{Name: "color_scheme", Type: "string", Required: false, Description: "light, dark or no-preference"},
+				{Name: "reduced_motion", Type: "string", Required: false, Description: "reduce or no-preference"},
+				{Name: "network", Type: "string", Required: false, Description: "online, offline, slow-3g, fast-3g or 4g"},
+				{Name: "reset", Type: "boolean", Required: false, Description: "Clear previous emulation first"},
+			},
+		},
+		{
+			Name:        "browser_storage_state",
+			Description: "Capture or restore cookies, localStorage, sessionStorage and IndexedDB of the current browser context",
+			Category:    "Storage",
+			Parameters: []ToolParameter{
+				{Name: "action", Type: "string", Required: false, Description: "Action: 'get' (default) or 'set'"},
+				{Name: "origins", Type: "string", Required: false, Description: "Comma-separated additional origins to capture"},
+				{Name: "include_indexed_db", Type: "boolean", Required: false, Description: "Also capture IndexedDB"},
+				{Name: "save_as", Type: "string", Required: false, Description: "Save the captured state under this name"},
+				{Name: "id", Type: "string", Required: false, Description: "Saved storage state to restore (action='set')"},
+				{Name: "state", Type: "object", Required: false, Description: "Storage state object to restore (action='set')"},
+			},
+		},
+		{
+			Name:        "browser_session",
+			Description: "Manage isolated executor sessions (create, list, close); pass session_id to any browser_* tool to use a session",
+			Category:    "Window",
+			Parameters: []ToolParameter{
+				{Name: "action", Type: "string", Required: true, Description: "Action: 'create', 'list', or 'close'"},
+				{Name: "session_id", Type: "string", Required: false, Description: "Session to close (when action='close')"},
+				{Name: "instance_id", Type: "string", Required: false, Description: "Browser instance for the new session (default: current instance)"},
+				{Name: "isolation", Type: "string", Required: false, Description: "'page' (new tab) or 'incognito' (separate cookies and storage)"},
+				{Name: "idle_timeout", Type: "number", Required: false, Description: "Close the session after this many idle seconds (default: 1800)"},
+			},
+		},
 	}
 }
 
@@ -1115,7 +1815,7 @@ func (r *MCPToolRegistry) registerTabsTool() error {
 			opts.Index = int(indexFloat)
 		}
 
-		result, err := r.executor.Tabs(ctx, opts)
+		result, err := r.executorFrom(ctx).Tabs(ctx, opts)
 		if err != nil {
 			return mcpgo.NewToolResultError(err.Error()), nil
 		}
@@ -1153,7 +1853,7 @@ func (r *MCPToolRegistry) registerTabsTool() error {
 		return mcpgo.NewToolResultText(result.Message), nil
 	}
 
-	r.mcpServer.AddTool(tool, handler)
+	r.addTool(tool, handler)
 	return nil
 }
 
@@ -1208,7 +1908,7 @@ func (r *MCPToolRegistry) registerFillFormTool() error {
 			opts.Timeout = time.Duration(timeoutFloat) * time.Second
 		}
 
-		result, err := r.executor.FillForm(ctx, opts)
+		result, err := r.executorFrom(ctx).FillForm(ctx, opts)
 		if err != nil {
 			return mcpgo.NewToolResultError(err.Error()), nil
 		}
@@ -1237,6 +1937,88 @@ func (r *MCPToolRegistry) registerFillFormTool() error {
 		return mcpgo.NewToolResultText(responseText), nil
 	}
 
+	r.addTool(tool, handler)
+	return nil
+}
+
+// registerSessionTool 注册会话管理工具
+func (r *MCPToolRegistry) registerSessionTool() error {
+	tool := mcpgo.NewTool(
+		"browser_session",
+		mcpgo.WithDescription("Manage isolated executor sessions. Each session has its own page (or incognito context) and RefID cache, so multiple clients can work in parallel. Pass the returned session_id to other browser_* tools. Idle sessions are closed automatically."),
+		mcpgo.WithString("action", mcpgo.Required(), mcpgo.Description("Session action: 'create', 'list', or 'close'")),
+		mcpgo.WithString("session_id", mcpgo.Description("Session ID (required when action='close')")),
+		mcpgo.WithString("instance_id", mcpgo.Description("Browser instance for the new session (default: current instance)")),
+		mcpgo.WithString("isolation", mcpgo.Description("'page' opens a new tab sharing cookies, 'incognito' uses a separate browser context (default: page)")),
+		mcpgo.WithNumber("idle_timeout", mcpgo.Description("Close the session after this many idle seconds (default: 1800)")),
+	)
+
+	handler := func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+		result, err := r.SessionFromArgs(ctx, request.GetArguments())
+		if err != nil {
+			return mcpgo.NewToolResultError(err.Error()), nil
+		}
+
+		data, _ := json.MarshalIndent(result.Data, "", "  ")
+		return mcpgo.NewToolResultText(fmt.Sprintf("%s\n\n%s", result.Message, string(data))), nil
+	}
+
 	r.mcpServer.AddTool(tool, handler)
 	return nil
 }
+
+// SessionFromArgs 按工具参数执行会话管理操作（create, list, close）
+func (r *MCPToolRegistry) SessionFromArgs(ctx context.Context, args map[string]interface{}) (*OperationResult, error) {
+	action, _ := args["action"].(string)
+	switch action {
+	case "create":
+		opts := &SessionOptions{}
+		opts.InstanceID, _ = args["instance_id"].(string)
+		opts.Isolation, _ = args["isolation"].(string)
+		if idleTimeout, ok := args["idle_timeout"].(float64); ok {
+			opts.IdleTimeout = time.Duration(idleTimeout) * time.Second
+		}
+		session, err := r.sessions.Create(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &OperationResult{
+			Success:   true,
+			Message:   fmt.Sprintf("Created session %s", session.ID),
+			Timestamp: time.Now(),
+			Data: map[string]interface{}{
+				"session_id":   session.ID,
+				"instance_id":  session.InstanceID,
+				"isolation":    session.Isolation,
+				"idle_timeout": int(session.IdleTimeout / time.Second),
+			},
+		}, nil
+	case "list":
+		sessions := r.sessions.List()
+		return &OperationResult{
+			Success:   true,
+			Message:   fmt.Sprintf("Found %d sessions", len(sessions)),
+			Timestamp: time.Now(),
+			Data: map[string]interface{}{
+				"sessions": sessions,
+				"count":    len(sessions),
+			},
+		}, nil
+	case "close":
+		sessionID, _ := args["session_id"].(string)
+		if sessionID == "" {
+			return nil, fmt.Errorf("session_id is required for close action")
+		}
+		if err := r.sessions.Close(ctx, sessionID); err != nil {
+			return nil, err
+		}
+		return &OperationResult{
+			Success:   true,
+			Message:   fmt.Sprintf("Closed session %s", sessionID),
+			Timestamp: time.Now(),
+			Data:      map[string]interface{}{"session_id": sessionID},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown session action: %s (expected create, list or close)", action)
+	}
+}
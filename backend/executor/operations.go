@@ -2,13 +2,18 @@ package executor
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/browserwing/browserwing/models"
 	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/browserwing/browserwing/services/browser"
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/input"
 	"github.com/go-rod/rod/lib/proto"
@@ -41,7 +46,7 @@ func (e *Executor) Navigate(ctx context.Context, url string, opts *NavigateOptio
 
 	// 获取或创建页面
 	logger.Info(ctx, "[Navigate] Getting active page...")
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	
 	// 检查 page 是否有效
 	needNewPage := false
@@ -65,9 +70,8 @@ func (e *Executor) Navigate(ctx context.Context, url string, opts *NavigateOptio
 	
 	if needNewPage {
 		logger.Info(ctx, "[Navigate] Creating new page...")
-		// 通过 OpenPage 创建新页面（会自动导航）
-		// 使用当前实例（传空字符串），norecord=true
-		err := e.Browser.OpenPage(url, "", "", true)
+		// 创建新页面并导航（会话执行器在会话的浏览器上下文中创建）
+		err := e.openPage(ctx, url)
 		if err != nil {
 			logger.Error(ctx, "[Navigate] Failed to open page: %s", err.Error())
 			return &OperationResult{
@@ -78,7 +82,7 @@ func (e *Executor) Navigate(ctx context.Context, url string, opts *NavigateOptio
 		}
 		logger.Info(ctx, "[Navigate] Page opened successfully")
 
-		page = e.Browser.GetActivePage()
+		page = e.activePage()
 		if page == nil {
 			logger.Error(ctx, "[Navigate] Failed to get active page after opening")
 			return &OperationResult{
@@ -102,7 +106,7 @@ func (e *Executor) Navigate(ctx context.Context, url string, opts *NavigateOptio
 			// 如果是 session 错误，尝试重新创建 page
 			if isSessionError(err) {
 				logger.Warn(ctx, "[Navigate] Session error detected, retrying with new page...")
-				err := e.Browser.OpenPage(url, "", "", true)
+				err := e.openPage(ctx, url)
 				if err != nil {
 					return &OperationResult{
 						Success:   false,
@@ -110,7 +114,7 @@ func (e *Executor) Navigate(ctx context.Context, url string, opts *NavigateOptio
 						Timestamp: time.Now(),
 					}, err
 				}
-				page = e.Browser.GetActivePage()
+				page = e.activePage()
 				logger.Info(ctx, "[Navigate] Retry successful with new page")
 			} else {
 				return &OperationResult{
@@ -192,7 +196,7 @@ func (e *Executor) Navigate(ctx context.Context, url string, opts *NavigateOptio
 
 // Click 点击元素
 func (e *Executor) Click(ctx context.Context, identifier string, opts *ClickOptions) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		logger.Error(ctx, "Failed to get active page")
 		return nil, fmt.Errorf("no active page")
@@ -327,29 +331,17 @@ func (e *Executor) Click(ctx context.Context, identifier string, opts *ClickOpti
 		ElementInfo:   getElementInfo(elem),
 	})
 
-	// 同时返回当前的页面可访问性快照
-	snapshot, err := e.GetAccessibilitySnapshot(ctx)
-	if err != nil {
-		logger.Error(ctx, "Failed to get accessibility snapshot: %s", err.Error())
-	}
-	var accessibilitySnapshotText string
-	if snapshot != nil {
-		accessibilitySnapshotText = snapshot.SerializeToSimpleText()
-	}
-
 	return &OperationResult{
 		Success:   true,
 		Message:   fmt.Sprintf("Successfully clicked element: %s", identifier),
 		Timestamp: time.Now(),
-		Data: map[string]interface{}{
-			"semantic_tree": accessibilitySnapshotText,
-		},
+		Data:      e.withOperationSnapshot(ctx, map[string]interface{}{}, opts.SnapshotDiff),
 	}, nil
 }
 
 // Type 在元素中输入文本
 func (e *Executor) Type(ctx context.Context, identifier string, text string, opts *TypeOptions) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -436,30 +428,19 @@ func (e *Executor) Type(ctx context.Context, identifier string, text string, opt
 		ElementInfo:   getElementInfo(elem),
 	})
 
-	// 同时返回当前的页面可访问性快照
-	snapshot, err := e.GetAccessibilitySnapshot(ctx)
-	if err != nil {
-		logger.Error(ctx, "Failed to get accessibility snapshot: %s", err.Error())
-	}
-	var accessibilitySnapshotText string
-	if snapshot != nil {
-		accessibilitySnapshotText = snapshot.SerializeToSimpleText()
-	}
-
 	return &OperationResult{
 		Success:   true,
 		Message:   fmt.Sprintf("Successfully typed into element: %s", identifier),
 		Timestamp: time.Now(),
-		Data: map[string]interface{}{
-			"text":          text,
-			"semantic_tree": accessibilitySnapshotText,
-		},
+		Data: e.withOperationSnapshot(ctx, map[string]interface{}{
+			"text": text,
+		}, opts.SnapshotDiff),
 	}, nil
 }
 
 // Select 选择下拉框选项
 func (e *Executor) Select(ctx context.Context, identifier string, value string, opts *SelectOptions) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -513,30 +494,19 @@ func (e *Executor) Select(ctx context.Context, identifier string, value string,
 		ElementInfo:   getElementInfo(elem),
 	})
 
-	// 同时返回当前的页面可访问性快照
-	snapshot, err := e.GetAccessibilitySnapshot(ctx)
-	if err != nil {
-		logger.Error(ctx, "Failed to get accessibility snapshot: %s", err.Error())
-	}
-	var accessibilitySnapshotText string
-	if snapshot != nil {
-		accessibilitySnapshotText = snapshot.SerializeToSimpleText()
-	}
-
 	return &OperationResult{
 		Success:   true,
 		Message:   fmt.Sprintf("Successfully selected option: %s", value),
 		Timestamp: time.Now(),
-		Data: map[string]interface{}{
-			"value":         value,
-			"semantic_tree": accessibilitySnapshotText,
-		},
+		Data: e.withOperationSnapshot(ctx, map[string]interface{}{
+			"value": value,
+		}, opts.SnapshotDiff),
 	}, nil
 }
 
 // GetText 获取元素文本
 func (e *Executor) GetText(ctx context.Context, identifier string) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -572,7 +542,7 @@ func (e *Executor) GetText(ctx context.Context, identifier string) (*OperationRe
 
 // GetValue 获取元素值
 func (e *Executor) GetValue(ctx context.Context, identifier string) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -608,7 +578,7 @@ func (e *Executor) GetValue(ctx context.Context, identifier string) (*OperationR
 
 // WaitFor 等待元素
 func (e *Executor) WaitFor(ctx context.Context, identifier string, opts *WaitForOptions) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -660,7 +630,7 @@ func (e *Executor) WaitFor(ctx context.Context, identifier string, opts *WaitFor
 
 // Extract 提取数据
 func (e *Executor) Extract(ctx context.Context, opts *ExtractOptions) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -677,7 +647,13 @@ func (e *Executor) Extract(ctx context.Context, opts *ExtractOptions) (*Operatio
 
 	if opts.Multiple {
 		// 提取多个元素
-		elements, err := page.Elements(opts.Selector)
+		var elements rod.Elements
+		var err error
+		if browser.IsShadowLocator(opts.Selector) {
+			elements, err = browser.FindShadowElements(page, opts.Selector)
+		} else {
+			elements, err = page.Elements(opts.Selector)
+		}
 		if err != nil {
 			return &OperationResult{
 				Success:   false,
@@ -697,7 +673,13 @@ func (e *Executor) Extract(ctx context.Context, opts *ExtractOptions) (*Operatio
 		result = results
 	} else {
 		// 提取单个元素
-		elem, err := page.Element(opts.Selector)
+		var elem *rod.Element
+		var err error
+		if browser.IsShadowLocator(opts.Selector) {
+			elem, err = browser.FindShadowElement(page, opts.Selector)
+		} else {
+			elem, err = page.Element(opts.Selector)
+		}
 		if err != nil {
 			return &OperationResult{
 				Success:   false,
@@ -827,6 +809,15 @@ func (e *Executor) findElementWithTimeout(ctx context.Context, page *rod.Page, i
 		logger.Info(ctx, "[findElementWithTimeout] Detected 'css:' prefix, cleaned to: %s", identifier)
 	}
 
+	// 穿透 Shadow DOM 的定位器："host >>> inner" 或 "shadow:css"
+	if browser.IsShadowLocator(identifier) {
+		elem, err := browser.FindShadowElement(timeoutPage, identifier)
+		if err != nil {
+			return nil, fmt.Errorf("element not found in shadow DOM: %s: %w", identifier, err)
+		}
+		return elem, nil
+	}
+
 	// 0. 尝试 RefID 格式：@e1, @e2, e1, e2（优先级最高，最稳定）
 	if strings.HasPrefix(identifier, "@") || (len(identifier) > 0 && identifier[0] == 'e' && len(identifier) <= 10) {
 		refID := strings.TrimPrefix(identifier, "@")
@@ -928,6 +919,16 @@ func (e *Executor) findElementByRefID(ctx context.Context, page *rod.Page, refID
 	
 	logger.Info(ctx, "[findElementByRefID] Found refData for %s: role=%s, name=%s, backendID=%d, href=%s (cache age: %v)", 
 		refID, refData.Role, refData.Name, refData.BackendID, refData.Href, cacheAge)
+
+	// iframe 中的元素：先进入对应的框架，后续定位都在框架文档内进行
+	if len(refData.FramePath) > 0 {
+		framePage, err := resolveFramePage(ctx, page, refData.FramePath, &e.frameTargets)
+		if err != nil {
+			return nil, fmt.Errorf("refID %s: %w", refID, err)
+		}
+		logger.Info(ctx, "[findElementByRefID] RefID %s is inside frame %s", refID, framePathKey(refData.FramePath))
+		page = framePage
+	}
 	
 	// 策略 1：尝试使用 BackendNodeID（最快最准确）
 	if refData.BackendID != 0 {
@@ -1107,7 +1108,7 @@ func min(a, b int) int {
 
 // Hover 鼠标悬停
 func (e *Executor) Hover(ctx context.Context, identifier string, opts *HoverOptions) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -1159,7 +1160,7 @@ func (e *Executor) Hover(ctx context.Context, identifier string, opts *HoverOpti
 
 // ScrollToBottom 滚动到页面底部
 func (e *Executor) ScrollToBottom(ctx context.Context) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -1189,7 +1190,7 @@ func (e *Executor) ScrollToBottom(ctx context.Context) (*OperationResult, error)
 
 // GoBack 后退
 func (e *Executor) GoBack(ctx context.Context) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -1217,7 +1218,7 @@ func (e *Executor) GoBack(ctx context.Context) (*OperationResult, error) {
 
 // GoForward 前进
 func (e *Executor) GoForward(ctx context.Context) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -1245,7 +1246,7 @@ func (e *Executor) GoForward(ctx context.Context) (*OperationResult, error) {
 
 // Reload 刷新页面
 func (e *Executor) Reload(ctx context.Context) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -1273,7 +1274,7 @@ func (e *Executor) Reload(ctx context.Context) (*OperationResult, error) {
 
 // Screenshot 截图
 func (e *Executor) Screenshot(ctx context.Context, opts *ScreenshotOptions) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -1293,6 +1294,36 @@ func (e *Executor) Screenshot(ctx context.Context, opts *ScreenshotOptions) (*Op
 		format = proto.PageCaptureScreenshotFormatPng
 	}
 
+	// 标注模式：按可访问性快照的 RefID 在可交互元素上绘制编号框，截图后移除
+	var marks []Mark
+	if opts.Annotate {
+		snapshot, err := e.GetAccessibilitySnapshot(ctx)
+		if err != nil {
+			return &OperationResult{
+				Success:   false,
+				Error:     fmt.Sprintf("Failed to get accessibility snapshot: %s", err.Error()),
+				Timestamp: time.Now(),
+			}, err
+		}
+		marks, err = e.collectMarks(ctx, page, snapshot, opts.FullPage)
+		if err != nil {
+			return &OperationResult{
+				Success:   false,
+				Error:     err.Error(),
+				Timestamp: time.Now(),
+			}, err
+		}
+		removeMarks, err := drawMarks(ctx, page, marks)
+		if err != nil {
+			return &OperationResult{
+				Success:   false,
+				Error:     err.Error(),
+				Timestamp: time.Now(),
+			}, err
+		}
+		defer removeMarks()
+	}
+
 	var data []byte
 	var err error
 
@@ -1337,6 +1368,11 @@ func (e *Executor) Screenshot(ctx context.Context, opts *ScreenshotOptions) (*Op
 	if screenshotPath != "" {
 		message = fmt.Sprintf("Successfully captured screenshot (%d bytes) and saved to: %s", len(data), screenshotPath)
 	}
+	if opts.Annotate {
+		resultData["marks"] = marks
+		resultData["legend"] = marksLegend(marks)
+		message = fmt.Sprintf("%s with %d marks", message, len(marks))
+	}
 
 	return &OperationResult{
 		Success:   true,
@@ -1348,7 +1384,7 @@ func (e *Executor) Screenshot(ctx context.Context, opts *ScreenshotOptions) (*Op
 
 // Evaluate 执行 JavaScript 代码
 func (e *Executor) Evaluate(ctx context.Context, script string) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -1383,7 +1419,7 @@ func (e *Executor) Evaluate(ctx context.Context, script string) (*OperationResul
 
 // PressKey 按键
 func (e *Executor) PressKey(ctx context.Context, key string, opts *PressKeyOptions) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -1499,7 +1535,7 @@ func (e *Executor) PressKey(ctx context.Context, key string, opts *PressKeyOptio
 
 // Resize 调整浏览器窗口大小
 func (e *Executor) Resize(ctx context.Context, width, height int) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -1523,47 +1559,339 @@ func (e *Executor) Resize(ctx context.Context, width, height int) (*OperationRes
 	}, nil
 }
 
-// GetConsoleMessages 获取控制台消息
-func (e *Executor) GetConsoleMessages(ctx context.Context) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+// Emulate 切换当前页面的仿真配置（设备、语言、时区、地理位置、媒体特性、网络条件）
+// reset 为 true 时先清除页面已有的仿真设置
+func (e *Executor) Emulate(ctx context.Context, profile *models.EmulationProfile, reset bool) (*OperationResult, error) {
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
+	if profile == nil && !reset {
+		return nil, fmt.Errorf("no emulation settings provided")
+	}
+	if err := browser.ValidateEmulationProfile(profile); err != nil {
+		return nil, err
+	}
 
-	// 收集控制台消息
-	messages := []map[string]interface{}{}
+	if reset {
+		if err := browser.ResetEmulation(page); err != nil {
+			return &OperationResult{
+				Success:   false,
+				Error:     err.Error(),
+				Timestamp: time.Now(),
+			}, err
+		}
+	}
+	if err := browser.ApplyEmulation(ctx, page, profile); err != nil {
+		return &OperationResult{
+			Success:   false,
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		}, err
+	}
 
-	// 监听控制台事件
-	go page.EachEvent(func(e *proto.RuntimeConsoleAPICalled) {
-		msg := map[string]interface{}{
-			"type":      e.Type,
-			"timestamp": time.Now().Format(time.RFC3339),
-			"args":      []string{},
+	// 视口和设备变化后元素位置随之变化，使 snapshot 缓存失效
+	e.InvalidateSnapshotCache()
+
+	message := "Successfully applied emulation settings"
+	if profile == nil {
+		message = "Successfully reset emulation settings"
+	}
+	return &OperationResult{
+		Success:   true,
+		Message:   message,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"profile": profile,
+			"reset":   reset,
+			"note":    "Reload the page for locale, timezone and Accept-Language changes to take full effect",
+		},
+	}, nil
+}
+
+// PDF 将当前页面打印为 PDF 并保存到 pdfs 目录
+func (e *Executor) PDF(ctx context.Context, opts *models.PDFOptions) (*OperationResult, error) {
+	page := e.activePage()
+	if page == nil {
+		return nil, fmt.Errorf("no active page")
+	}
+
+	data, err := browser.PrintToPDF(ctx, page, opts)
+	if err != nil {
+		return &OperationResult{
+			Success:   false,
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		}, err
+	}
+	return e.pageCaptureResult(ctx, page, "pdfs", "page", "pdf", data)
+}
+
+// Archive 将当前页面（含样式、图片和 iframe）保存为 MHTML 存档并保存到 archives 目录
+func (e *Executor) Archive(ctx context.Context) (*OperationResult, error) {
+	page := e.activePage()
+	if page == nil {
+		return nil, fmt.Errorf("no active page")
+	}
+
+	data, err := browser.CaptureMHTML(ctx, page)
+	if err != nil {
+		return &OperationResult{
+			Success:   false,
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		}, err
+	}
+	return e.pageCaptureResult(ctx, page, "archives", "archive", "mhtml", data)
+}
+
+// Assert 在当前页面上检查断言（元素可见性、文本、属性、数量、URL、标题或变量）
+// 断言未通过时 Success 为 false；硬断言（默认）同时返回 browser.AssertionError，软断言只返回结果
+func (e *Executor) Assert(ctx context.Context, identifier string, opts *models.AssertOptions, variables map[string]string) (*OperationResult, error) {
+	page := e.activePage()
+	if page == nil {
+		return nil, fmt.Errorf("no active page")
+	}
+	if err := browser.ValidateAssertOptions(opts); err != nil {
+		return nil, err
+	}
+
+	var query browser.ElementQuery
+	if browser.AssertionNeedsElement(opts.Kind) {
+		if strings.TrimSpace(identifier) == "" {
+			return nil, fmt.Errorf("%s assertion requires identifier", opts.Kind)
 		}
-		for _, arg := range e.Args {
-			if arg.Value.Val() != nil {
-				msg["args"] = append(msg["args"].([]string), fmt.Sprintf("%v", arg.Value.Val()))
+		query = e.assertionQuery(ctx, identifier)
+	}
+
+	result, err := browser.EvaluateAssertion(ctx, page, opts, variables, query)
+	if err != nil {
+		return &OperationResult{
+			Success:   false,
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		}, err
+	}
+
+	opResult := &OperationResult{
+		Success:   result.Passed,
+		Message:   result.Message,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"passed":   result.Passed,
+			"kind":     result.Kind,
+			"mode":     result.Mode,
+			"expected": result.Expected,
+			"actual":   result.Actual,
+		},
+	}
+	if result.Passed {
+		return opResult, nil
+	}
+	opResult.Error = result.Message
+	if result.Mode == models.AssertModeSoft {
+		return opResult, nil
+	}
+	return opResult, &browser.AssertionError{Result: result}
+}
+
+// assertionQuery 构建断言的元素查询：快照中的 RefID 通过语义定位器查找（元素消失时视为无匹配），其余按 CSS / XPath 查询
+func (e *Executor) assertionQuery(ctx context.Context, identifier string) browser.ElementQuery {
+	identifier = strings.TrimSpace(identifier)
+	if strings.HasPrefix(strings.ToLower(identifier), "xpath:") {
+		return browser.LocatorQuery(strings.TrimSpace(identifier[6:]), true)
+	}
+	if strings.HasPrefix(strings.ToLower(identifier), "css:") {
+		return browser.LocatorQuery(strings.TrimSpace(identifier[4:]), false)
+	}
+
+	refID := strings.TrimPrefix(identifier, "@")
+	e.refIDMutex.RLock()
+	_, isRef := e.refIDMap[refID]
+	e.refIDMutex.RUnlock()
+	if isRef {
+		return func(page *rod.Page) (rod.Elements, error) {
+			elem, err := e.findElementByRefID(ctx, page, refID)
+			if err != nil {
+				return rod.Elements{}, nil
 			}
+			return rod.Elements{elem}, nil
 		}
-		messages = append(messages, msg)
-	})()
+	}
 
-	// 等待一小段时间以收集消息
-	time.Sleep(100 * time.Millisecond)
+	xpath := strings.HasPrefix(identifier, "/") || strings.HasPrefix(identifier, "(")
+	return browser.LocatorQuery(identifier, xpath)
+}
+
+// pageCaptureResult 保存 PDF 或存档文件，返回文件路径、大小、SHA-256 以及页面地址和标题，便于留存证据
+func (e *Executor) pageCaptureResult(ctx context.Context, page *rod.Page, dir, prefix, format string, data []byte) (*OperationResult, error) {
+	path, err := e.saveCaptureFile(ctx, dir, prefix, format, data)
+	if err != nil {
+		return &OperationResult{
+			Success:   false,
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		}, err
+	}
+
+	sum := sha256.Sum256(data)
+	resultData := map[string]interface{}{
+		"data":   data,
+		"path":   path,
+		"format": format,
+		"size":   len(data),
+		"sha256": hex.EncodeToString(sum[:]),
+	}
+	if info, err := page.Info(); err == nil {
+		resultData["url"] = info.URL
+		resultData["title"] = info.Title
+	}
+
+	return &OperationResult{
+		Success:   true,
+		Message:   fmt.Sprintf("Successfully saved %s (%d bytes) to: %s", format, len(data), path),
+		Timestamp: time.Now(),
+		Data:      resultData,
+	}, nil
+}
+
+// GetStorageState 捕获当前页面所在浏览器上下文的存储状态（Cookie、localStorage、sessionStorage、可选 IndexedDB）
+// saveAs 不为空时以该名称保存到数据库（覆盖同名状态），之后可在回放或 SetStorageState 中通过 ID 使用
+func (e *Executor) GetStorageState(ctx context.Context, opts browser.StorageCaptureOptions, saveAs string) (*OperationResult, error) {
+	page := e.activePage()
+	if page == nil {
+		return nil, fmt.Errorf("no active page")
+	}
+
+	state, err := browser.CaptureStorageState(ctx, page, opts)
+	if err != nil {
+		return &OperationResult{
+			Success:   false,
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		}, err
+	}
+
+	message := fmt.Sprintf("Captured %d cookies and storage of %d origins", len(state.Cookies), len(state.Origins))
+	if saveAs != "" {
+		state.Name = saveAs
+		if err := e.Browser.SaveStorageState(state); err != nil {
+			return nil, fmt.Errorf("failed to save storage state: %w", err)
+		}
+		message += fmt.Sprintf(", saved as %q (id: %s)", saveAs, state.ID)
+	}
+	logger.Info(ctx, "[GetStorageState] %s", message)
+
+	return &OperationResult{
+		Success:   true,
+		Message:   message,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"storage_state": state,
+		},
+	}, nil
+}
+
+// SetStorageState 将存储状态写入当前页面所在的浏览器上下文
+// state 为空时按 id 从数据库加载；写入后通常需要重新加载页面才能让应用读取到新的登录状态
+func (e *Executor) SetStorageState(ctx context.Context, state *models.StorageState, id string) (*OperationResult, error) {
+	page := e.activePage()
+	if page == nil {
+		return nil, fmt.Errorf("no active page")
+	}
+	if state == nil {
+		if id == "" {
+			return nil, fmt.Errorf("storage state or id is required")
+		}
+		loaded, err := e.Browser.LoadStorageState(id)
+		if err != nil {
+			return nil, err
+		}
+		state = loaded
+	}
+
+	if err := e.Browser.ApplyPageStorageState(ctx, page, state); err != nil {
+		return &OperationResult{
+			Success:   false,
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		}, err
+	}
+
+	message := fmt.Sprintf("Applied %d cookies and storage of %d origins", len(state.Cookies), len(state.Origins))
+	logger.Info(ctx, "[SetStorageState] %s", message)
+
+	return &OperationResult{
+		Success:   true,
+		Message:   message,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"cookies": len(state.Cookies),
+			"origins": len(state.Origins),
+			"note":    "Reload the page so the application picks up the restored state",
+		},
+	}, nil
+}
+
+// GetConsoleMessages 获取控制台消息
+func (e *Executor) GetConsoleMessages(ctx context.Context, opts *ConsoleMessagesOptions) (*OperationResult, error) {
+	page := e.activePage()
+	if page == nil {
+		return nil, fmt.Errorf("no active page")
+	}
+
+	if opts == nil {
+		opts = &ConsoleMessagesOptions{}
+	}
+
+	// 页面观测器从页面创建起持续采集，首次访问未挂载的页面时会从此刻开始采集
+	monitor := e.Browser.GetPageMonitor(page)
+	if monitor == nil {
+		return nil, fmt.Errorf("console monitor is not available for the active page")
+	}
+
+	messages, cursor := monitor.Console.Query(browser.ConsoleQuery{
+		Levels:   opts.Levels,
+		Since:    opts.Since,
+		AfterSeq: opts.Cursor,
+		Limit:    opts.Limit,
+		Clear:    opts.Clear,
+	})
+	buffered, dropped := monitor.Console.Stats()
 
 	return &OperationResult{
 		Success:   true,
 		Message:   fmt.Sprintf("Retrieved %d console messages", len(messages)),
 		Timestamp: time.Now(),
 		Data: map[string]interface{}{
-			"messages": messages,
+			"messages":      messages,
+			"count":         len(messages),
+			"cursor":        cursor,
+			"buffered":      buffered,
+			"dropped":       dropped,
+			"monitor_since": monitor.CreatedAt,
 		},
 	}, nil
 }
 
+// ParseSinceParam 解析时间过滤参数，支持 RFC3339 和 Unix 毫秒时间戳
+func ParseSinceParam(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: expected RFC3339 or unix milliseconds", value)
+}
+
 // HandleDialog 处理对话框（alert, confirm, prompt）
 func (e *Executor) HandleDialog(ctx context.Context, accept bool, text string) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -1591,7 +1919,7 @@ func (e *Executor) HandleDialog(ctx context.Context, accept bool, text string) (
 
 // FileUpload 上传文件
 func (e *Executor) FileUpload(ctx context.Context, identifier string, filePaths []string) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -1623,7 +1951,7 @@ func (e *Executor) FileUpload(ctx context.Context, identifier string, filePaths
 
 // Drag 拖拽元素
 func (e *Executor) Drag(ctx context.Context, fromIdentifier, toIdentifier string) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -1723,7 +2051,7 @@ func (e *Executor) Drag(ctx context.Context, fromIdentifier, toIdentifier string
 
 // ClosePage 关闭当前页面
 func (e *Executor) ClosePage(ctx context.Context) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -1736,6 +2064,13 @@ func (e *Executor) ClosePage(ctx context.Context) (*OperationResult, error) {
 			Timestamp: time.Now(),
 		}, err
 	}
+	e.forgetSnapshot(page.TargetID)
+	e.untrackPage(page.TargetID)
+	if e.session != nil {
+		// 会话页面已关闭，下次导航时在会话的浏览器上下文中重新创建
+		e.setActivePage(nil)
+		e.InvalidateSnapshotCache()
+	}
 
 	return &OperationResult{
 		Success:   true,
@@ -1744,49 +2079,218 @@ func (e *Executor) ClosePage(ctx context.Context) (*OperationResult, error) {
 	}, nil
 }
 
-// GetNetworkRequests 获取网络请求（需要先启用网络监控）
-func (e *Executor) GetNetworkRequests(ctx context.Context) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+// GetNetworkRequests 获取当前页面的网络请求日志（页面创建时即开始记录）
+func (e *Executor) GetNetworkRequests(ctx context.Context, opts *NetworkRequestsOptions) (*OperationResult, error) {
+	page := e.activePage()
+	if page == nil {
+		return nil, fmt.Errorf("no active page")
+	}
+
+	if opts == nil {
+		opts = &NetworkRequestsOptions{}
+	}
+
+	query, err := buildNetworkQuery(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	monitor := e.Browser.GetPageMonitor(page)
+	if monitor == nil {
+		return nil, fmt.Errorf("network journal is not available for the active page")
+	}
+
+	entries, cursor := monitor.Network.Query(query)
+	if opts.Clear {
+		monitor.Network.Clear()
+	}
+	buffered, dropped := monitor.Network.Stats()
+
+	requests := make([]map[string]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		item := map[string]interface{}{"request": entry}
+		if opts.IncludeBody && entry.State == browser.NetworkStateFinished {
+			body, err := browser.FetchResponseBody(page, entry.RequestID)
+			if err != nil {
+				item["body_error"] = err.Error()
+			} else {
+				item["body"] = body
+			}
+		}
+		requests = append(requests, item)
+	}
+
+	return &OperationResult{
+		Success:   true,
+		Message:   fmt.Sprintf("Retrieved %d network requests", len(requests)),
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"requests":      requests,
+			"count":         len(requests),
+			"cursor":        cursor,
+			"buffered":      buffered,
+			"dropped":       dropped,
+			"monitor_since": monitor.CreatedAt,
+		},
+	}, nil
+}
+
+// GetNetworkResponseBody 按需获取某个请求的响应体
+func (e *Executor) GetNetworkResponseBody(ctx context.Context, requestID string) (*OperationResult, error) {
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
 
-	// 启用网络监控
-	err := proto.NetworkEnable{}.Call(page)
+	monitor := e.Browser.GetPageMonitor(page)
+	if monitor == nil {
+		return nil, fmt.Errorf("network journal is not available for the active page")
+	}
+
+	entry, ok := monitor.Network.Get(requestID)
+	if !ok {
+		return nil, fmt.Errorf("network request not found: %s", requestID)
+	}
+
+	body, err := browser.FetchResponseBody(page, requestID)
 	if err != nil {
 		return &OperationResult{
 			Success:   false,
-			Error:     fmt.Sprintf("Failed to enable network monitoring: %s", err.Error()),
+			Error:     err.Error(),
 			Timestamp: time.Now(),
 		}, err
 	}
 
-	requests := []map[string]interface{}{}
+	return &OperationResult{
+		Success:   true,
+		Message:   fmt.Sprintf("Retrieved response body for %s %s", entry.Method, entry.URL),
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"request": entry,
+			"body":    body,
+		},
+	}, nil
+}
+
+// ExportHAR 将当前页面的网络日志导出为 HAR 1.2
+func (e *Executor) ExportHAR(ctx context.Context, opts *NetworkRequestsOptions) (*browser.HAR, error) {
+	page := e.activePage()
+	if page == nil {
+		return nil, fmt.Errorf("no active page")
+	}
 
-	// 监听网络请求
-	go page.EachEvent(func(e *proto.NetworkRequestWillBeSent) {
-		req := map[string]interface{}{
-			"url":       e.Request.URL,
-			"method":    e.Request.Method,
-			"timestamp": time.Now().Format(time.RFC3339),
-			"type":      e.Type,
+	if opts == nil {
+		opts = &NetworkRequestsOptions{}
+	}
+
+	query, err := buildNetworkQuery(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	monitor := e.Browser.GetPageMonitor(page)
+	if monitor == nil {
+		return nil, fmt.Errorf("network journal is not available for the active page")
+	}
+
+	entries, _ := monitor.Network.Query(query)
+
+	var bodies map[string]*browser.NetworkBody
+	if opts.IncludeBody {
+		bodies = make(map[string]*browser.NetworkBody)
+		for _, entry := range entries {
+			if entry.State != browser.NetworkStateFinished {
+				continue
+			}
+			if body, err := browser.FetchResponseBody(page, entry.RequestID); err == nil {
+				bodies[entry.RequestID] = body
+			}
 		}
-		requests = append(requests, req)
-	})()
+	}
+
+	harPage := &browser.HARPage{
+		StartedDateTime: monitor.CreatedAt.Format(time.RFC3339Nano),
+		ID:              string(monitor.TargetID),
+		PageTimings:     browser.HARPageTimings{OnContentLoad: -1, OnLoad: -1},
+	}
+	if info, err := page.Info(); err == nil {
+		harPage.Title = info.Title
+		if harPage.Title == "" {
+			harPage.Title = info.URL
+		}
+	}
+
+	logger.Info(ctx, "Exporting HAR with %d network entries", len(entries))
+	return browser.BuildHAR(entries, bodies, harPage), nil
+}
+
+// SetRouteRules 为当前页面设置请求拦截规则（替换已有规则，空列表表示关闭拦截）
+func (e *Executor) SetRouteRules(ctx context.Context, rules []models.RouteRule) (*OperationResult, error) {
+	page := e.activePage()
+	if page == nil {
+		return nil, fmt.Errorf("no active page")
+	}
 
-	// 等待一段时间收集请求
-	time.Sleep(100 * time.Millisecond)
+	if err := e.Browser.SetPageRouteRules(page, rules); err != nil {
+		return &OperationResult{
+			Success:   false,
+			Error:     fmt.Sprintf("Failed to set route rules: %s", err.Error()),
+			Timestamp: time.Now(),
+		}, err
+	}
+
+	message := fmt.Sprintf("Applied %d route rules to the current page", len(rules))
+	if len(rules) == 0 {
+		message = "Request interception disabled for the current page"
+	}
+	logger.Info(ctx, "[SetRouteRules] %s", message)
 
 	return &OperationResult{
 		Success:   true,
-		Message:   fmt.Sprintf("Retrieved %d network requests", len(requests)),
+		Message:   message,
 		Timestamp: time.Now(),
 		Data: map[string]interface{}{
-			"requests": requests,
+			"rules": e.Browser.GetPageRouteRules(page),
 		},
 	}, nil
 }
 
+// GetRouteRules 获取当前页面的请求拦截规则及命中次数
+func (e *Executor) GetRouteRules(ctx context.Context) (*OperationResult, error) {
+	page := e.activePage()
+	if page == nil {
+		return nil, fmt.Errorf("no active page")
+	}
+
+	rules := e.Browser.GetPageRouteRules(page)
+	return &OperationResult{
+		Success:   true,
+		Message:   fmt.Sprintf("%d route rules active on the current page", len(rules)),
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"rules": rules,
+		},
+	}, nil
+}
+
+// buildNetworkQuery 将查询选项转换为网络日志查询条件
+func buildNetworkQuery(opts *NetworkRequestsOptions) (browser.NetworkQuery, error) {
+	statusMin, statusMax, err := browser.ParseStatusFilter(opts.Status)
+	if err != nil {
+		return browser.NetworkQuery{}, err
+	}
+	return browser.NetworkQuery{
+		URL:           opts.URL,
+		Method:        opts.Method,
+		StatusMin:     statusMin,
+		StatusMax:     statusMax,
+		ResourceTypes: opts.ResourceTypes,
+		State:         opts.State,
+		AfterSeq:      opts.Cursor,
+		Limit:         opts.Limit,
+	}, nil
+}
+
 // isSessionError 检查是否是 CDP session 错误
 func isSessionError(err error) bool {
 	if err == nil {
@@ -1911,7 +2415,7 @@ type TabInfo struct {
 
 // Tabs 标签页管理
 func (e *Executor) Tabs(ctx context.Context, opts *TabsOptions) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -1958,7 +2462,7 @@ func (e *Executor) listTabs(ctx context.Context, browser *rod.Browser, currentPa
 		}
 
 		// 只列出 type="page" 的标签页，排除扩展、devtools 等
-		if info.Type != "page" {
+		if info.Type != "page" || !e.inSessionContext(info) {
 			continue
 		}
 
@@ -1987,7 +2491,8 @@ func (e *Executor) listTabs(ctx context.Context, browser *rod.Browser, currentPa
 func (e *Executor) newTab(ctx context.Context, browser *rod.Browser, url string) (*OperationResult, error) {
 	logger.Info(ctx, "Creating new tab with URL: %s", url)
 
-	newPage, err := browser.Page(proto.TargetCreateTarget{URL: url})
+	// 先创建空白页并挂载观测器，再导航，确保页面加载期间的控制台消息被保留
+	newPage, err := browser.Page(proto.TargetCreateTarget{})
 	if err != nil {
 		return &OperationResult{
 			Success:   false,
@@ -1995,6 +2500,18 @@ func (e *Executor) newTab(ctx context.Context, browser *rod.Browser, url string)
 			Timestamp: time.Now(),
 		}, err
 	}
+	e.Browser.MonitorPage(newPage)
+	e.trackPage(newPage)
+
+	if url != "" {
+		if err := newPage.Navigate(url); err != nil {
+			return &OperationResult{
+				Success:   false,
+				Error:     fmt.Sprintf("Failed to navigate new tab: %s", err.Error()),
+				Timestamp: time.Now(),
+			}, err
+		}
+	}
 
 	// 等待页面加载
 	if err := newPage.WaitLoad(); err != nil {
@@ -2002,7 +2519,7 @@ func (e *Executor) newTab(ctx context.Context, browser *rod.Browser, url string)
 	}
 
 	// 新标签页自动成为活动标签页
-	e.Browser.SetActivePage(newPage)
+	e.setActivePage(newPage)
 
 	// 页面已切换，使 snapshot 缓存失效
 	e.InvalidateSnapshotCache()
@@ -2053,7 +2570,7 @@ func (e *Executor) switchTab(ctx context.Context, browser *rod.Browser, index in
 		if err != nil {
 			continue
 		}
-		if info.Type == "page" {
+		if info.Type == "page" && e.inSessionContext(info) {
 			pageTabs = append(pageTabs, p)
 		}
 	}
@@ -2079,7 +2596,7 @@ func (e *Executor) switchTab(ctx context.Context, browser *rod.Browser, index in
 	}
 
 	// 更新 Manager 中的 activePage，确保后续操作使用新标签页
-	e.Browser.SetActivePage(targetPage)
+	e.setActivePage(targetPage)
 
 	// 页面已切换，使 snapshot 缓存失效
 	e.InvalidateSnapshotCache()
@@ -2119,7 +2636,7 @@ func (e *Executor) closeTab(ctx context.Context, browser *rod.Browser, index int
 		if err != nil {
 			continue
 		}
-		if info.Type == "page" {
+		if info.Type == "page" && e.inSessionContext(info) {
 			pageTabs = append(pageTabs, p)
 		}
 	}
@@ -2136,7 +2653,7 @@ func (e *Executor) closeTab(ctx context.Context, browser *rod.Browser, index int
 	info, _ := targetPage.Info()
 
 	// 检查是否关闭的是当前活动页面
-	currentPage := e.Browser.GetActivePage()
+	currentPage := e.activePage()
 	closingActivePage := (targetPage == currentPage)
 
 	// 关闭标签页
@@ -2148,6 +2665,8 @@ func (e *Executor) closeTab(ctx context.Context, browser *rod.Browser, index int
 			Timestamp: time.Now(),
 		}, err
 	}
+	e.forgetSnapshot(targetPage.TargetID)
+	e.untrackPage(targetPage.TargetID)
 
 	// 如果关闭的是活动页面，切换到剩余的第一个页面标签
 	if closingActivePage {
@@ -2157,8 +2676,8 @@ func (e *Executor) closeTab(ctx context.Context, browser *rod.Browser, index int
 			if pErr != nil {
 				continue
 			}
-			if pInfo.Type == "page" {
-				e.Browser.SetActivePage(p)
+			if pInfo.Type == "page" && e.inSessionContext(pInfo) {
+				e.setActivePage(p)
 				_, _ = p.Activate()
 				break
 			}
@@ -2198,7 +2717,7 @@ type FillFormOptions struct {
 
 // FillForm 批量填写表单
 func (e *Executor) FillForm(ctx context.Context, opts *FillFormOptions) (*OperationResult, error) {
-	page := e.Browser.GetActivePage()
+	page := e.activePage()
 	if page == nil {
 		return nil, fmt.Errorf("no active page")
 	}
@@ -2469,26 +2988,28 @@ func (e *Executor) submitForm(ctx context.Context, page *rod.Page) error {
 
 // saveScreenshot 将截图数据保存到文件
 func (e *Executor) saveScreenshot(ctx context.Context, data []byte, format string) (string, error) {
-	// 创建 screenshots 目录
-	screenshotsDir := "screenshots"
-	if err := os.MkdirAll(screenshotsDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create screenshots directory: %w", err)
-	}
-
-	// 生成文件名：screenshot_YYYYMMDD_HHMMSS.{format}
-	timestamp := time.Now().Format("20060102_150405")
 	extension := format
 	if extension == "jpg" {
 		extension = "jpeg"
 	}
-	filename := fmt.Sprintf("screenshot_%s.%s", timestamp, extension)
-	filepath := filepath.Join(screenshotsDir, filename)
+	return e.saveCaptureFile(ctx, "screenshots", "screenshot", extension, data)
+}
+
+// saveCaptureFile 将截图、PDF 或存档数据保存到 dir 目录，文件名为 {prefix}_YYYYMMDD_HHMMSS.{extension}
+func (e *Executor) saveCaptureFile(ctx context.Context, dir, prefix, extension string, data []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s directory: %w", dir, err)
+	}
+
+	timestamp := time.Now().Format("20060102_150405")
+	filename := fmt.Sprintf("%s_%s.%s", prefix, timestamp, extension)
+	filepath := filepath.Join(dir, filename)
 
 	// 保存文件
 	if err := os.WriteFile(filepath, data, 0644); err != nil {
-		return "", fmt.Errorf("failed to write screenshot file: %w", err)
+		return "", fmt.Errorf("failed to write %s file: %w", prefix, err)
 	}
 
-	logger.Info(ctx, "Screenshot saved to: %s", filepath)
+	logger.Info(ctx, "Saved %s file to: %s", prefix, filepath)
 	return filepath, nil
 }
@@ -0,0 +1,398 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/browserwing/browserwing/services/browser"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/google/uuid"
+)
+
+// 会话的隔离方式
+const (
+	SessionIsolationPage      = "page"      // 在实例的默认上下文中打开独立的标签页（共享 Cookie 和存储）
+	SessionIsolationIncognito = "incognito" // 在独立的无痕上下文中打开页面（不共享 Cookie 和存储）
+)
+
+const (
+	// defaultSessionIdleTimeout 会话默认的空闲超时时间
+	defaultSessionIdleTimeout = 30 * time.Minute
+	// sessionGCInterval 检查空闲会话的间隔
+	sessionGCInterval = time.Minute
+)
+
+// ErrSessionNotFound 会话不存在（或已因空闲超时被关闭）
+var ErrSessionNotFound = errors.New("executor session not found")
+
+// Session 执行器会话
+// 每个会话绑定到实例中独立的页面（或无痕上下文），拥有独立的 RefID 缓存和操作录制器，
+// 多个 MCP 客户端或 Agent 使用不同的会话时不会互相切换页面或使对方的 RefID 失效
+type Session struct {
+	ID          string
+	InstanceID  string
+	Isolation   string
+	IdleTimeout time.Duration
+	CreatedAt   time.Time
+	Executor    *Executor
+
+	browser  *rod.Browser // 会话页面所在的浏览器上下文，会话内的新页面在其中创建
+	rootPage *rod.Page    // 会话创建时的页面，关闭会话时据此销毁无痕上下文
+	lastUsed time.Time
+
+	// 会话拥有的标签页（会话页面、会话中新建的标签页及其打开的弹窗）
+	// page 隔离的会话与其他会话共用浏览器上下文，只能看到和操作这些标签页
+	targetsMu sync.Mutex
+	targets   map[proto.TargetTargetID]bool
+}
+
+// SessionOptions 创建会话的选项
+type SessionOptions struct {
+	InstanceID  string        // 实例 ID，空字符串表示当前实例
+	Isolation   string        // page 或 incognito，默认 page
+	IdleTimeout time.Duration // 空闲超时，默认 30 分钟
+}
+
+// SessionInfo 会话信息
+type SessionInfo struct {
+	ID          string    `json:"id"`
+	InstanceID  string    `json:"instance_id"`
+	Isolation   string    `json:"isolation"`
+	URL         string    `json:"url"`
+	Title       string    `json:"title"`
+	IdleTimeout int       `json:"idle_timeout"` // 空闲超时（秒）
+	CreatedAt   time.Time `json:"created_at"`
+	LastUsedAt  time.Time `json:"last_used_at"`
+}
+
+// SessionManager 管理执行器会话，超过空闲时间未被使用的会话会被自动关闭
+type SessionManager struct {
+	browser  *browser.Manager
+	mu       sync.Mutex
+	sessions map[string]*Session
+	gcOnce   sync.Once
+	done     chan struct{} // CloseAll 时关闭，停止空闲回收
+	doneOnce sync.Once
+}
+
+// NewSessionManager 创建会话管理器
+func NewSessionManager(browserMgr *browser.Manager) *SessionManager {
+	return &SessionManager{
+		browser:  browserMgr,
+		sessions: make(map[string]*Session),
+		done:     make(chan struct{}),
+	}
+}
+
+// normalizeSessionIsolation 校验并规范化会话的隔离方式
+func normalizeSessionIsolation(isolation string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(isolation)) {
+	case "", SessionIsolationPage:
+		return SessionIsolationPage, nil
+	case SessionIsolationIncognito:
+		return SessionIsolationIncognito, nil
+	default:
+		return "", fmt.Errorf("unsupported session isolation %q (expected page or incognito)", isolation)
+	}
+}
+
+// Create 创建会话：在实例中打开独立的页面，并创建绑定到该页面的执行器
+func (sm *SessionManager) Create(ctx context.Context, opts *SessionOptions) (*Session, error) {
+	if opts == nil {
+		opts = &SessionOptions{}
+	}
+	isolation, err := normalizeSessionIsolation(opts.Isolation)
+	if err != nil {
+		return nil, err
+	}
+	if opts.IdleTimeout < 0 {
+		return nil, fmt.Errorf("idle_timeout must not be negative")
+	}
+	idleTimeout := opts.IdleTimeout
+	if idleTimeout == 0 {
+		idleTimeout = defaultSessionIdleTimeout
+	}
+
+	instanceID := opts.InstanceID
+	if instanceID == "" {
+		if current := sm.browser.GetCurrentInstance(); current != nil {
+			instanceID = current.ID
+		}
+	}
+
+	page, err := sm.browser.NewSessionPage(ctx, instanceID, isolation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session page: %w", err)
+	}
+
+	now := time.Now()
+	session := &Session{
+		ID:          uuid.New().String(),
+		InstanceID:  instanceID,
+		Isolation:   isolation,
+		IdleTimeout: idleTimeout,
+		CreatedAt:   now,
+		browser:     page.Browser(),
+		rootPage:    page,
+		lastUsed:    now,
+	}
+	session.Executor = NewExecutor(sm.browser)
+	session.Executor.session = session
+	session.Executor.page = page
+	session.own(page.TargetID)
+
+	sm.mu.Lock()
+	sm.sessions[session.ID] = session
+	sm.mu.Unlock()
+
+	sm.gcOnce.Do(func() { go sm.gcLoop() })
+
+	logger.Info(ctx, "Created executor session %s (instance: %s, isolation: %s, idle timeout: %v)",
+		session.ID, instanceID, isolation, idleTimeout)
+	return session, nil
+}
+
+// Get 获取会话并刷新其最后使用时间
+func (sm *SessionManager) Get(id string) (*Session, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	session.lastUsed = time.Now()
+	return session, nil
+}
+
+// Close 关闭会话及其页面（无痕会话同时销毁其浏览器上下文）
+func (sm *SessionManager) Close(ctx context.Context, id string) error {
+	sm.mu.Lock()
+	session, ok := sm.sessions[id]
+	delete(sm.sessions, id)
+	sm.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	sm.closeSession(ctx, session)
+	return nil
+}
+
+// CloseAll 关闭所有会话并停止空闲回收
+func (sm *SessionManager) CloseAll(ctx context.Context) {
+	sm.doneOnce.Do(func() { close(sm.done) })
+
+	sm.mu.Lock()
+	sessions := make([]*Session, 0, len(sm.sessions))
+	for id, session := range sm.sessions {
+		sessions = append(sessions, session)
+		delete(sm.sessions, id)
+	}
+	sm.mu.Unlock()
+
+	for _, session := range sessions {
+		sm.closeSession(ctx, session)
+	}
+}
+
+// closeSession 关闭会话的页面并清理执行器缓存
+func (sm *SessionManager) closeSession(ctx context.Context, session *Session) {
+	exec := session.Executor
+	if page := exec.activePage(); page != nil && page != session.rootPage {
+		_ = page.Close()
+	}
+	exec.setActivePage(nil)
+	exec.InvalidateSnapshotCache()
+	exec.ClearSnapshotHistory()
+	exec.frameTargets.detach()
+
+	// 会话页面可能已被关闭（如 browser_close_page），此时只需销毁无痕上下文
+	if err := sm.browser.CloseSessionPage(session.rootPage); err != nil {
+		logger.Debug(ctx, "Session %s page already closed: %v", session.ID, err)
+	}
+	logger.Info(ctx, "Closed executor session %s", session.ID)
+}
+
+// List 列出所有会话
+func (sm *SessionManager) List() []SessionInfo {
+	sm.mu.Lock()
+	sessions := make([]*Session, 0, len(sm.sessions))
+	lastUsed := make(map[string]time.Time, len(sm.sessions))
+	for id, session := range sm.sessions {
+		sessions = append(sessions, session)
+		lastUsed[id] = session.lastUsed
+	}
+	sm.mu.Unlock()
+
+	infos := make([]SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		info := SessionInfo{
+			ID:          session.ID,
+			InstanceID:  session.InstanceID,
+			Isolation:   session.Isolation,
+			IdleTimeout: int(session.IdleTimeout / time.Second),
+			CreatedAt:   session.CreatedAt,
+			LastUsedAt:  lastUsed[session.ID],
+		}
+		if page := session.Executor.activePage(); page != nil {
+			if pageInfo, err := page.Info(); err == nil {
+				info.URL = pageInfo.URL
+				info.Title = pageInfo.Title
+			}
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].CreatedAt.Before(infos[j].CreatedAt) })
+	return infos
+}
+
+// gcLoop 定期关闭空闲超时的会话，直到 CloseAll 被调用
+func (sm *SessionManager) gcLoop() {
+	ticker := time.NewTicker(sessionGCInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sm.done:
+			return
+		case now := <-ticker.C:
+			for _, session := range sm.collectIdle(now) {
+				ctx := context.Background()
+				logger.Info(ctx, "Executor session %s idle for more than %v, closing", session.ID, session.IdleTimeout)
+				sm.closeSession(ctx, session)
+			}
+		}
+	}
+}
+
+// collectIdle 从会话表中移除并返回空闲超时的会话
+func (sm *SessionManager) collectIdle(now time.Time) []*Session {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	var idle []*Session
+	for id, session := range sm.sessions {
+		if now.Sub(session.lastUsed) > session.IdleTimeout {
+			idle = append(idle, session)
+			delete(sm.sessions, id)
+		}
+	}
+	return idle
+}
+
+// ========== 执行器的当前页面 ==========
+
+// activePage 返回执行器当前操作的页面
+// 会话执行器使用会话自己的页面，共享执行器使用浏览器管理器的活动页面
+func (e *Executor) activePage() *rod.Page {
+	if e.session == nil {
+		return e.Browser.GetActivePage()
+	}
+	e.pageMu.RLock()
+	defer e.pageMu.RUnlock()
+	return e.page
+}
+
+// setActivePage 切换执行器当前操作的页面
+func (e *Executor) setActivePage(page *rod.Page) {
+	if e.session == nil {
+		e.Browser.SetActivePage(page)
+		return
+	}
+	e.pageMu.Lock()
+	e.page = page
+	e.pageMu.Unlock()
+}
+
+// openPage 打开新页面并导航到 url，新页面成为执行器的当前页面
+func (e *Executor) openPage(ctx context.Context, url string) error {
+	if e.session == nil {
+		// 使用当前实例（传空字符串），norecord=true
+		return e.Browser.OpenPage(url, "", "", true)
+	}
+
+	page, err := e.session.browser.Page(proto.TargetCreateTarget{})
+	if err != nil {
+		return fmt.Errorf("failed to create page in session %s: %w", e.session.ID, err)
+	}
+	e.Browser.MonitorPage(page)
+	e.session.own(page.TargetID)
+	if err := page.Context(ctx).Navigate(url); err != nil {
+		return fmt.Errorf("failed to navigate to %s: %w", url, err)
+	}
+	e.setActivePage(page)
+	return nil
+}
+
+// inSessionContext 判断标签页是否对执行器可见
+// 共享执行器可见所有标签页；无痕会话可见自己上下文中的标签页；page 隔离的会话只可见自己拥有的标签页
+func (e *Executor) inSessionContext(info *proto.TargetTargetInfo) bool {
+	if e.session == nil {
+		return true
+	}
+	if contextID := e.session.browser.BrowserContextID; contextID != "" && info.BrowserContextID == contextID {
+		return true
+	}
+	return e.session.owns(info)
+}
+
+// trackPage 记录会话新建的标签页（共享执行器无需记录）
+func (e *Executor) trackPage(page *rod.Page) {
+	if e.session != nil {
+		e.session.own(page.TargetID)
+	}
+}
+
+// untrackPage 移除会话中已关闭的标签页
+func (e *Executor) untrackPage(targetID proto.TargetTargetID) {
+	if e.session != nil {
+		e.session.disown(targetID)
+	}
+}
+
+// own 记录会话拥有的标签页
+func (s *Session) own(targetID proto.TargetTargetID) {
+	s.targetsMu.Lock()
+	defer s.targetsMu.Unlock()
+	if s.targets == nil {
+		s.targets = make(map[proto.TargetTargetID]bool)
+	}
+	s.targets[targetID] = true
+}
+
+// disown 移除会话拥有的标签页
+func (s *Session) disown(targetID proto.TargetTargetID) {
+	s.targetsMu.Lock()
+	defer s.targetsMu.Unlock()
+	delete(s.targets, targetID)
+}
+
+// owns 标签页是否属于会话；由会话页面打开的弹窗（window.open、target=_blank）同样归属该会话
+func (s *Session) owns(info *proto.TargetTargetInfo) bool {
+	s.targetsMu.Lock()
+	defer s.targetsMu.Unlock()
+	if s.targets[info.TargetID] {
+		return true
+	}
+	if info.OpenerID != "" && s.targets[info.OpenerID] {
+		s.targets[info.TargetID] = true
+		return true
+	}
+	return false
+}
+
+// SessionID 返回执行器所属的会话 ID，共享执行器返回空字符串
+func (e *Executor) SessionID() string {
+	if e.session == nil {
+		return ""
+	}
+	return e.session.ID
+}
@@ -0,0 +1,130 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func TestSessionManagerCollectIdle(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	sm := NewSessionManager(nil)
+	sessions := []struct {
+		id          string
+		idleTimeout time.Duration
+		lastUsed    time.Time
+		wantClosed  bool
+	}{
+		{id: "fresh", idleTimeout: 30 * time.Minute, lastUsed: now.Add(-time.Minute)},
+		{id: "exactly-at-timeout", idleTimeout: 30 * time.Minute, lastUsed: now.Add(-30 * time.Minute)},
+		{id: "idle", idleTimeout: 30 * time.Minute, lastUsed: now.Add(-31 * time.Minute), wantClosed: true},
+		{id: "short-timeout", idleTimeout: 10 * time.Second, lastUsed: now.Add(-time.Minute), wantClosed: true},
+		{id: "long-timeout", idleTimeout: 2 * time.Hour, lastUsed: now.Add(-time.Hour)},
+	}
+	for _, s := range sessions {
+		sm.sessions[s.id] = &Session{ID: s.id, IdleTimeout: s.idleTimeout, lastUsed: s.lastUsed}
+	}
+
+	var got []string
+	for _, session := range sm.collectIdle(now) {
+		got = append(got, session.ID)
+	}
+	sort.Strings(got)
+	want := []string{"idle", "short-timeout"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("collectIdle = %v, want %v", got, want)
+	}
+
+	for _, s := range sessions {
+		_, err := sm.Get(s.id)
+		if closed := errors.Is(err, ErrSessionNotFound); closed != s.wantClosed {
+			t.Errorf("%s: removed = %v, want %v", s.id, closed, s.wantClosed)
+		}
+	}
+}
+
+func TestSessionManagerGetRefreshesLastUsed(t *testing.T) {
+	sm := NewSessionManager(nil)
+	stale := time.Now().Add(-time.Hour)
+	sm.sessions["s1"] = &Session{ID: "s1", IdleTimeout: 30 * time.Minute, lastUsed: stale}
+
+	if _, err := sm.Get("s1"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if idle := sm.collectIdle(time.Now()); len(idle) != 0 {
+		t.Errorf("session used just now was collected: %v", idle)
+	}
+}
+
+func TestSessionManagerCloseAllStopsGC(t *testing.T) {
+	sm := NewSessionManager(nil)
+	stopped := make(chan struct{})
+	go func() {
+		sm.gcLoop()
+		close(stopped)
+	}()
+
+	sm.CloseAll(context.Background())
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("gc loop still running after CloseAll")
+	}
+	// 重复关闭不应 panic
+	sm.CloseAll(context.Background())
+}
+
+func TestNormalizeSessionIsolation(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "", want: SessionIsolationPage},
+		{in: "page", want: SessionIsolationPage},
+		{in: " Incognito ", want: SessionIsolationIncognito},
+		{in: "window", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeSessionIsolation(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("normalizeSessionIsolation(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestSessionTabVisibility(t *testing.T) {
+	page := &Executor{session: &Session{ID: "a", browser: &rod.Browser{}}}
+	page.session.own("root-a")
+	page.session.own("tab-a")
+	page.untrackPage("tab-a")
+
+	incognito := &Executor{session: &Session{ID: "b", browser: &rod.Browser{BrowserContextID: "ctx-b"}}}
+	incognito.session.own("root-b")
+
+	tests := []struct {
+		name string
+		exec *Executor
+		info proto.TargetTargetInfo
+		want bool
+	}{
+		{name: "shared executor sees every tab", exec: &Executor{}, info: proto.TargetTargetInfo{TargetID: "other"}, want: true},
+		{name: "own page", exec: page, info: proto.TargetTargetInfo{TargetID: "root-a"}, want: true},
+		{name: "popup opened by own page", exec: page, info: proto.TargetTargetInfo{TargetID: "popup-a", OpenerID: "root-a"}, want: true},
+		{name: "popup stays owned after adoption", exec: page, info: proto.TargetTargetInfo{TargetID: "popup-a"}, want: true},
+		{name: "closed tab", exec: page, info: proto.TargetTargetInfo{TargetID: "tab-a"}, want: false},
+		{name: "another session's page in the shared context", exec: page, info: proto.TargetTargetInfo{TargetID: "root-c"}, want: false},
+		{name: "page in own incognito context", exec: incognito, info: proto.TargetTargetInfo{TargetID: "x", BrowserContextID: "ctx-b"}, want: true},
+		{name: "page outside incognito context", exec: incognito, info: proto.TargetTargetInfo{TargetID: "root-a"}, want: false},
+	}
+	for _, tt := range tests {
+		if got := tt.exec.inSessionContext(&tt.info); got != tt.want {
+			t.Errorf("%s: inSessionContext = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
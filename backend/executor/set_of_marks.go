@@ -0,0 +1,274 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/go-rod/rod"
+)
+
+// Mark 标注截图中的一个编号框，RefID 与可访问性快照中的 RefID 一致，可直接作为 identifier 使用
+type Mark struct {
+	RefID  string  `json:"ref"`
+	Role   string  `json:"role,omitempty"`
+	Name   string  `json:"name,omitempty"`
+	Frame  string  `json:"frame,omitempty"` // 所在 iframe 的框架路径（主文档为空）
+	X      float64 `json:"x"`               // 相对文档左上角的位置（CSS 像素）
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// marksOverlayID 标注层元素 ID，截图后移除
+const marksOverlayID = "__browserwing_marks__"
+
+// markLabelHeight 编号标签的高度（CSS 像素），与绘制脚本中的行高和边框一致
+const markLabelHeight = 16
+
+// elementRectScript 元素相对所在框架视口的位置
+const elementRectScript = `() => {
+	const r = this.getBoundingClientRect();
+	return {x: r.x, y: r.y, width: r.width, height: r.height};
+}`
+
+// frameContentOffsetScript iframe 内容区域相对所在视口的位置（去除边框和内边距）
+const frameContentOffsetScript = `() => {
+	const r = this.getBoundingClientRect();
+	const s = getComputedStyle(this);
+	return {x: r.x + this.clientLeft + parseFloat(s.paddingLeft || 0), y: r.y + this.clientTop + parseFloat(s.paddingTop || 0)};
+}`
+
+// viewportScript 主文档的滚动位置和视口大小
+const viewportScript = `() => ({scrollX: window.scrollX, scrollY: window.scrollY, width: window.innerWidth, height: window.innerHeight})`
+
+// drawMarksScript 在页面上绘制编号框（绝对定位于文档坐标，视口截图和整页截图都适用）
+const drawMarksScript = `(id, marks) => {
+	const old = document.getElementById(id);
+	if (old) old.remove();
+	const colors = ['#e6194b', '#3cb44b', '#4363d8', '#f58231', '#911eb4', '#008080', '#f032e6', '#9a6324'];
+	const layer = document.createElement('div');
+	layer.id = id;
+	layer.style.cssText = 'position:absolute;left:0;top:0;width:0;height:0;pointer-events:none;z-index:2147483647;';
+	marks.forEach((m, i) => {
+		const color = colors[i % colors.length];
+		const box = document.createElement('div');
+		box.style.cssText = 'position:absolute;box-sizing:border-box;border:2px solid ' + color + ';' +
+			'left:' + m.x + 'px;top:' + m.y + 'px;width:' + m.width + 'px;height:' + m.height + 'px;';
+		const label = document.createElement('div');
+		label.textContent = m.ref;
+		label.style.cssText = 'position:absolute;left:-2px;top:' + m.labelTop + 'px;' +
+			'background:' + color + ';color:#fff;font:bold 11px/14px monospace;padding:0 3px;border-radius:2px;white-space:nowrap;';
+		box.appendChild(label);
+		layer.appendChild(box);
+	});
+	document.documentElement.appendChild(layer);
+}`
+
+// removeMarksScript 移除标注层
+const removeMarksScript = `(id) => {
+	const layer = document.getElementById(id);
+	if (layer) layer.remove();
+}`
+
+type markRect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+type markViewport struct {
+	ScrollX float64 `json:"scrollX"`
+	ScrollY float64 `json:"scrollY"`
+	Width   float64 `json:"width"`
+	Height  float64 `json:"height"`
+}
+
+// drawnMark 传给绘制脚本的标注，附带编号标签相对编号框的位置
+type drawnMark struct {
+	Mark
+	LabelTop float64 `json:"labelTop"`
+}
+
+// markLabelTop 编号标签相对编号框顶部的偏移：默认放在框的上方，
+// 元素贴近文档顶部、上方放不下时放进框内左上角
+func markLabelTop(mark Mark) float64 {
+	if mark.Y >= markLabelHeight {
+		return -markLabelHeight
+	}
+	return -2
+}
+
+// markFrame 框架页面及其内容区域相对主文档视口的偏移
+type markFrame struct {
+	page *rod.Page
+	x, y float64
+}
+
+// collectMarks 计算快照中带 RefID 的可交互元素在主文档中的位置
+// fullPage 为 false 时只保留与当前视口相交的元素；无法定位、尺寸为 0 的元素会被跳过
+func (e *Executor) collectMarks(ctx context.Context, page *rod.Page, snapshot *AccessibilitySnapshot, fullPage bool) ([]Mark, error) {
+	viewport, err := readViewport(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := map[string]*markFrame{"": {page: page}}
+	marks := []Mark{}
+	seen := make(map[string]bool)
+	nodes := append(snapshot.GetClickableElements(), snapshot.GetInputElements()...)
+	for _, node := range nodes {
+		if node.RefID == "" || seen[node.RefID] || node.BackendNodeID == 0 {
+			continue
+		}
+		seen[node.RefID] = true
+
+		rect, ok := e.nodeViewportRect(ctx, page, node, frames)
+		if !ok || (!fullPage && !rect.inViewport(viewport)) {
+			continue
+		}
+
+		marks = append(marks, Mark{
+			RefID:  node.RefID,
+			Role:   node.Role,
+			Name:   markName(node),
+			Frame:  framePathKey(node.FramePath),
+			X:      rect.X + viewport.ScrollX,
+			Y:      rect.Y + viewport.ScrollY,
+			Width:  rect.Width,
+			Height: rect.Height,
+		})
+	}
+	return marks, nil
+}
+
+// nodeViewportRect 计算节点相对主文档视口的位置，无法定位或尺寸为 0 时返回 false
+func (e *Executor) nodeViewportRect(ctx context.Context, page *rod.Page, node *AccessibilityNode, frames map[string]*markFrame) (markRect, bool) {
+	var rect markRect
+	if node.BackendNodeID == 0 {
+		return rect, false
+	}
+	frame, err := e.markFrame(ctx, page, node.FramePath, frames)
+	if err != nil {
+		logger.Warn(ctx, "[nodeViewportRect] Skipping node %s: %v", node.ID, err)
+		return rect, false
+	}
+	elem, err := e.findByBackendNodeID(ctx, frame.page, int(node.BackendNodeID))
+	if err != nil {
+		return rect, false
+	}
+	res, err := elem.Eval(elementRectScript)
+	if err != nil {
+		return rect, false
+	}
+	if err := res.Value.Unmarshal(&rect); err != nil || rect.Width < 1 || rect.Height < 1 {
+		return rect, false
+	}
+
+	// 框架视口坐标 -> 主文档视口坐标
+	rect.X += frame.x
+	rect.Y += frame.y
+	return rect, true
+}
+
+// inViewport 位置（主文档视口坐标）是否与视口相交
+func (r markRect) inViewport(viewport markViewport) bool {
+	return r.X+r.Width > 0 && r.Y+r.Height > 0 && r.X < viewport.Width && r.Y < viewport.Height
+}
+
+// readViewport 读取主文档的滚动位置和视口大小
+func readViewport(ctx context.Context, page *rod.Page) (markViewport, error) {
+	var viewport markViewport
+	res, err := page.Context(ctx).Eval(viewportScript)
+	if err != nil {
+		return viewport, fmt.Errorf("failed to read viewport: %w", err)
+	}
+	if err := res.Value.Unmarshal(&viewport); err != nil {
+		return viewport, fmt.Errorf("failed to read viewport: %w", err)
+	}
+	return viewport, nil
+}
+
+// markFrame 逐层定位框架并累加 iframe 内容区域的偏移，结果按框架路径缓存
+func (e *Executor) markFrame(ctx context.Context, page *rod.Page, path []int, cache map[string]*markFrame) (*markFrame, error) {
+	key := framePathKey(path)
+	if frame, ok := cache[key]; ok {
+		return frame, nil
+	}
+
+	parent, err := e.markFrame(ctx, page, path[:len(path)-1], cache)
+	if err != nil {
+		return nil, err
+	}
+	index := path[len(path)-1]
+	elements, err := parent.page.Context(ctx).Elements(frameSelector)
+	if err != nil || index >= len(elements) {
+		return nil, fmt.Errorf("frame %s not found", key)
+	}
+	res, err := elements[index].Eval(frameContentOffsetScript)
+	if err != nil {
+		return nil, fmt.Errorf("frame %s: %w", key, err)
+	}
+	var offset markRect
+	if err := res.Value.Unmarshal(&offset); err != nil {
+		return nil, fmt.Errorf("frame %s: %w", key, err)
+	}
+	framePages, err := childFrames(ctx, parent.page, &e.frameTargets)
+	if err != nil || index >= len(framePages) || framePages[index] == nil {
+		return nil, fmt.Errorf("frame %s is not accessible", key)
+	}
+
+	frame := &markFrame{page: framePages[index], x: parent.x + offset.X, y: parent.y + offset.Y}
+	cache[key] = frame
+	return frame, nil
+}
+
+// markName 标注图例中显示的元素名称
+func markName(node *AccessibilityNode) string {
+	for _, name := range []string{node.Label, node.Text, node.Placeholder, node.Description} {
+		if name = strings.TrimSpace(name); name != "" {
+			if runes := []rune(name); len(runes) > 50 {
+				name = string(runes[:47]) + "..."
+			}
+			return name
+		}
+	}
+	return ""
+}
+
+// marksLegend 生成与标注截图对应的文本图例
+func marksLegend(marks []Mark) string {
+	var builder strings.Builder
+	for _, mark := range marks {
+		builder.WriteString("@" + mark.RefID)
+		if mark.Name != "" {
+			builder.WriteString(" - " + mark.Name)
+		}
+		if mark.Role != "" {
+			builder.WriteString(" (" + mark.Role + ")")
+		}
+		if mark.Frame != "" {
+			builder.WriteString(" [frame " + mark.Frame + "]")
+		}
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// drawMarks 在页面上绘制标注，返回移除标注的函数
+func drawMarks(ctx context.Context, page *rod.Page, marks []Mark) (func(), error) {
+	drawn := make([]drawnMark, len(marks))
+	for i, mark := range marks {
+		drawn[i] = drawnMark{Mark: mark, LabelTop: markLabelTop(mark)}
+	}
+	if _, err := page.Context(ctx).Eval(drawMarksScript, marksOverlayID, drawn); err != nil {
+		return nil, fmt.Errorf("failed to draw marks: %w", err)
+	}
+	return func() {
+		if _, err := page.Eval(removeMarksScript, marksOverlayID); err != nil {
+			logger.Warn(ctx, "[drawMarks] Failed to remove marks overlay: %v", err)
+		}
+	}, nil
+}
@@ -0,0 +1,84 @@
+package executor
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMarkLabelTop(t *testing.T) {
+	tests := []struct {
+		name string
+		y    float64
+		want float64
+	}{
+		{name: "above the box", y: 200, want: -markLabelHeight},
+		{name: "exactly fits above", y: markLabelHeight, want: -markLabelHeight},
+		{name: "inside the box near the top", y: 10, want: -2},
+		{name: "inside the box at the top", y: 0, want: -2},
+	}
+	for _, tt := range tests {
+		if got := markLabelTop(Mark{Y: tt.y}); got != tt.want {
+			t.Errorf("%s: markLabelTop(y=%v) = %v, want %v", tt.name, tt.y, got, tt.want)
+		}
+	}
+
+	// 绘制脚本读取的字段
+	data, err := json.Marshal(drawnMark{Mark: Mark{RefID: "e1", X: 1, Y: 2, Width: 3, Height: 4}, LabelTop: -2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"ref":"e1"`, `"x":1`, `"y":2`, `"width":3`, `"height":4`, `"labelTop":-2`} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("drawn mark %s missing %s", data, field)
+		}
+	}
+}
+
+func TestMarkRectInViewport(t *testing.T) {
+	viewport := markViewport{Width: 800, Height: 600}
+	tests := []struct {
+		name string
+		rect markRect
+		want bool
+	}{
+		{name: "inside", rect: markRect{X: 10, Y: 10, Width: 100, Height: 20}, want: true},
+		{name: "partially above", rect: markRect{X: 10, Y: -10, Width: 100, Height: 20}, want: true},
+		{name: "partially right", rect: markRect{X: 790, Y: 10, Width: 100, Height: 20}, want: true},
+		{name: "fully above", rect: markRect{X: 10, Y: -30, Width: 100, Height: 20}, want: false},
+		{name: "touching bottom edge", rect: markRect{X: 10, Y: 600, Width: 100, Height: 20}, want: false},
+		{name: "fully left", rect: markRect{X: -100, Y: 10, Width: 100, Height: 20}, want: false},
+	}
+	for _, tt := range tests {
+		if got := tt.rect.inViewport(viewport); got != tt.want {
+			t.Errorf("%s: inViewport = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMarkNameAndLegend(t *testing.T) {
+	tests := []struct {
+		node *AccessibilityNode
+		want string
+	}{
+		{node: &AccessibilityNode{Label: "  Submit "}, want: "Submit"},
+		{node: &AccessibilityNode{Placeholder: "Search"}, want: "Search"},
+		{node: &AccessibilityNode{Description: "Close dialog"}, want: "Close dialog"},
+		{node: &AccessibilityNode{Label: strings.Repeat("a", 60)}, want: strings.Repeat("a", 47) + "..."},
+		{node: &AccessibilityNode{}, want: ""},
+	}
+	for _, tt := range tests {
+		if got := markName(tt.node); got != tt.want {
+			t.Errorf("markName = %q, want %q", got, tt.want)
+		}
+	}
+
+	legend := marksLegend([]Mark{
+		{RefID: "e1", Role: "button", Name: "Submit"},
+		{RefID: "e2", Role: "textbox", Frame: "0.1"},
+	})
+	want := "@e1 - Submit (button)\n@e2 (textbox) [frame 0.1]\n"
+	if legend != want {
+		t.Errorf("marksLegend = %q, want %q", legend, want)
+	}
+}
@@ -0,0 +1,509 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// charsPerToken 估算 token 数时每个 token 对应的字符数
+const charsPerToken = 4
+
+// minCharBudget 字符预算下限（约 100 token），保证标题、用法提示和省略提示总能放下，更小的预算按下限处理
+const minCharBudget = 400
+
+// listContainerRoles 其中的元素在序列化时可按 ListLimit 折叠
+var listContainerRoles = map[string]bool{
+	"list":     true,
+	"listbox":  true,
+	"grid":     true,
+	"table":    true,
+	"treegrid": true,
+	"tree":     true,
+	"feed":     true,
+	"menu":     true,
+}
+
+// SnapshotSerializeOptions 快照的预算序列化选项
+type SnapshotSerializeOptions struct {
+	MaxTokens    int    // 近似 token 预算（按 4 字符/token 估算），0 表示不限制
+	MaxChars     int    // 字符预算，0 表示不限制；与 MaxTokens 同时设置时取较小者，不低于 minCharBudget
+	ViewportOnly bool   // 只保留与当前视口相交的元素
+	Root         string // 只保留该 RefID（@e12）、地标（main、navigation、form:Login）或第 n 个同角色节点（list#2）下的子树
+	ListLimit    int    // 同一列表中最多列出的元素数，其余折叠为 "N more items"，0 表示不折叠
+}
+
+// IsZero 是否未设置任何预算或过滤条件（此时使用完整的 SerializeToSimpleText）
+func (o *SnapshotSerializeOptions) IsZero() bool {
+	return o == nil || (o.MaxTokens <= 0 && o.MaxChars <= 0 && !o.ViewportOnly && o.Root == "" && o.ListLimit <= 0)
+}
+
+// charBudget 字符预算，0 表示不限制
+func (o *SnapshotSerializeOptions) charBudget() int {
+	budget := o.MaxChars
+	if o.MaxTokens > 0 && (budget <= 0 || o.MaxTokens*charsPerToken < budget) {
+		budget = o.MaxTokens * charsPerToken
+	}
+	if budget <= 0 {
+		return 0
+	}
+	if budget < minCharBudget {
+		return minCharBudget
+	}
+	return budget
+}
+
+// SerializeSnapshot 按预算和过滤条件序列化快照；ViewportOnly 需要读取页面中元素的位置
+func (e *Executor) SerializeSnapshot(ctx context.Context, snapshot *AccessibilitySnapshot, opts *SnapshotSerializeOptions) (string, error) {
+	if opts.IsZero() {
+		return snapshot.SerializeToSimpleText(), nil
+	}
+
+	var visible func(*AccessibilityNode) bool
+	if opts.ViewportOnly {
+		page := e.activePage()
+		if page == nil {
+			return "", fmt.Errorf("no active page")
+		}
+		viewport, err := readViewport(ctx, page)
+		if err != nil {
+			return "", err
+		}
+		frames := map[string]*markFrame{"": {page: page}}
+		visible = func(node *AccessibilityNode) bool {
+			rect, ok := e.nodeViewportRect(ctx, page, node, frames)
+			return ok && rect.inViewport(viewport)
+		}
+	}
+
+	return snapshot.SerializeWithBudget(opts, visible)
+}
+
+// budgetEntry 预算序列化中的一行
+type budgetEntry struct {
+	node     *AccessibilityNode
+	section  int // 0 输入，1 可点击，2 标题，3 折叠的列表
+	priority int // 预算不足时优先保留数值小的
+	order    int // 文档顺序
+	line     string
+	elements int // 该行代表的元素数（折叠的列表为隐藏的元素数），用于统计省略数
+}
+
+// collapsedList 被折叠的列表
+type collapsedList struct {
+	container *AccessibilityNode
+	hidden    int
+}
+
+// SerializeWithBudget 按预算序列化快照：按文档顺序输出，限定子树和列表长度，
+// 预算不足时优先保留输入元素、其次按钮等可点击元素、再次链接，最后是作为上下文的标题
+// visible 不为 nil 时只保留其返回 true 的元素
+func (tree *AccessibilitySnapshot) SerializeWithBudget(opts *SnapshotSerializeOptions, visible func(*AccessibilityNode) bool) (string, error) {
+	order, parents := tree.documentOrder()
+
+	var scope *AccessibilityNode
+	if opts.Root != "" {
+		scope = tree.findScope(order, opts.Root)
+		if scope == nil {
+			return "", fmt.Errorf("snapshot root not found: %s", opts.Root)
+		}
+	}
+
+	clickable := make(map[*AccessibilityNode]bool)
+	for _, node := range tree.GetClickableElements() {
+		clickable[node] = true
+	}
+	input := make(map[*AccessibilityNode]bool)
+	for _, node := range tree.GetInputElements() {
+		input[node] = true
+	}
+
+	var entries []budgetEntry
+	listCounts := make(map[*AccessibilityNode]int)
+	var collapsed []*collapsedList
+	collapsedByContainer := make(map[*AccessibilityNode]*collapsedList)
+	for i, node := range order {
+		entry := budgetEntry{node: node, order: i, elements: 1}
+		switch {
+		case input[node] && node.RefID != "":
+			entry.section, entry.priority, entry.line = 0, 0, budgetNodeLine(node, true)
+		case clickable[node] && node.RefID != "":
+			entry.section, entry.priority, entry.line = 1, 1, budgetNodeLine(node, false)
+			if node.Role == "link" {
+				entry.priority = 2
+			}
+		case node.Role == "heading" && strings.TrimSpace(node.Label) != "" && node.Metadata["ignored"] != true:
+			entry.section, entry.priority, entry.line = 2, 3, budgetHeadingLine(node)
+		default:
+			continue
+		}
+		if scope != nil && !isDescendant(node, scope, parents) {
+			continue
+		}
+		if visible != nil && !visible(node) {
+			continue
+		}
+		if opts.ListLimit > 0 && entry.section != 2 {
+			if container := listContainer(node, parents, scope); container != nil {
+				listCounts[container]++
+				if listCounts[container] > opts.ListLimit {
+					list := collapsedByContainer[container]
+					if list == nil {
+						list = &collapsedList{container: container}
+						collapsedByContainer[container] = list
+						collapsed = append(collapsed, list)
+					}
+					list.hidden++
+					continue
+				}
+			}
+		}
+		entries = append(entries, entry)
+	}
+	// 折叠的列表同样计入预算，优先级最低
+	total := len(entries)
+	for i, list := range collapsed {
+		entries = append(entries, budgetEntry{
+			node:     list.container,
+			section:  3,
+			priority: 4,
+			order:    len(order) + i,
+			line:     collapsedLine(list, order),
+			elements: list.hidden,
+		})
+		total += list.hidden
+	}
+
+	// 预算不足时按优先级保留；标题、用法提示和省略提示同样计入预算（minCharBudget 保证它们能放下）
+	budget := opts.charBudget()
+	header := budgetHeader(opts, scope)
+	footer := budgetFooter()
+	sections := []string{"INPUT:\n", "CLICKABLE:\n", "HEADINGS:\n", collapsedTitle}
+	used := utf8.RuneCountInString(header) + utf8.RuneCountInString(footer)
+	kept := entries
+	omitted := 0
+	if budget > 0 {
+		byPriority := make([]budgetEntry, len(entries))
+		copy(byPriority, entries)
+		sort.SliceStable(byPriority, func(i, j int) bool {
+			return byPriority[i].priority < byPriority[j].priority
+		})
+		// 还有元素未输出时为省略提示预留空间（按最大省略数估算）
+		notice := utf8.RuneCountInString(omittedLine(total))
+		opened := make(map[int]bool)
+		kept = nil
+		for i, entry := range byPriority {
+			// 分组的第一个元素同时计入分组标题和结尾的空行
+			cost := utf8.RuneCountInString(entry.line) + 1
+			if !opened[entry.section] {
+				cost += utf8.RuneCountInString(sections[entry.section]) + 1
+			}
+			reserve := 0
+			if i < len(byPriority)-1 {
+				reserve = notice
+			}
+			if used+cost+reserve > budget {
+				for _, rest := range byPriority[i:] {
+					omitted += rest.elements
+				}
+				break
+			}
+			used += cost
+			opened[entry.section] = true
+			kept = append(kept, entry)
+		}
+		sort.SliceStable(kept, func(i, j int) bool {
+			return kept[i].order < kept[j].order
+		})
+	}
+
+	var builder strings.Builder
+	builder.WriteString(header)
+	for section, title := range sections {
+		written := false
+		for _, entry := range kept {
+			if entry.section != section {
+				continue
+			}
+			if !written {
+				builder.WriteString(title)
+				written = true
+			}
+			builder.WriteString(entry.line + "\n")
+		}
+		if written {
+			builder.WriteString("\n")
+		}
+	}
+	if omitted > 0 {
+		builder.WriteString(omittedLine(omitted))
+	}
+	builder.WriteString(footer)
+	return builder.String(), nil
+}
+
+// documentOrder 按文档顺序遍历快照中的节点，返回节点顺序和父节点映射
+// 主文档从根节点开始遍历，框架中的节点（未挂在主文档树上）按框架路径依次遍历
+func (tree *AccessibilitySnapshot) documentOrder() ([]*AccessibilityNode, map[*AccessibilityNode]*AccessibilityNode) {
+	parents := make(map[*AccessibilityNode]*AccessibilityNode)
+	visited := make(map[*AccessibilityNode]bool)
+	var order []*AccessibilityNode
+
+	var walk func(node *AccessibilityNode)
+	walk = func(node *AccessibilityNode) {
+		visited[node] = true
+		order = append(order, node)
+		for _, child := range tree.childNodes(node) {
+			if child == nil || visited[child] {
+				continue
+			}
+			parents[child] = node
+			walk(child)
+		}
+	}
+
+	if tree.Root != nil {
+		walk(tree.Root)
+	}
+
+	// 其余的树根（框架文档的根节点），先收集所有被引用的子节点
+	referenced := make(map[*AccessibilityNode]bool)
+	for _, node := range tree.Elements {
+		for _, child := range tree.childNodes(node) {
+			referenced[child] = true
+		}
+	}
+	var roots []*AccessibilityNode
+	for _, node := range tree.Elements {
+		if !visited[node] && !referenced[node] {
+			roots = append(roots, node)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		return roots[i].ID < roots[j].ID
+	})
+	for _, root := range roots {
+		if !visited[root] {
+			walk(root)
+		}
+	}
+	return order, parents
+}
+
+// childNodes 节点的子节点（框架内节点的 ID 带有框架前缀）
+func (tree *AccessibilitySnapshot) childNodes(node *AccessibilityNode) []*AccessibilityNode {
+	childIDs, ok := node.Metadata["childIDs"].([]proto.AccessibilityAXNodeID)
+	if !ok {
+		return nil
+	}
+	prefix := ""
+	if strings.HasPrefix(node.ID, "f") {
+		prefix = node.ID[:strings.LastIndex(node.ID, ":")+1]
+	}
+	children := make([]*AccessibilityNode, 0, len(childIDs))
+	for _, id := range childIDs {
+		if child := tree.Elements[prefix+string(id)]; child != nil {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// findScope 查找 Root 指定的节点：RefID（@e12 / e12）、role / role:name（名称不区分大小写），
+// 或 role#n（文档顺序中该角色的第 n 个节点，从 1 开始）
+func (tree *AccessibilitySnapshot) findScope(order []*AccessibilityNode, root string) *AccessibilityNode {
+	root = strings.TrimSpace(root)
+	refID := strings.TrimPrefix(root, "@")
+	role, name, hasName := strings.Cut(root, ":")
+	role = strings.ToLower(strings.TrimSpace(role))
+	name = strings.TrimSpace(name)
+
+	for _, node := range order {
+		if node.RefID != "" && node.RefID == refID {
+			return node
+		}
+	}
+	if roleName, index, ok := strings.Cut(role, "#"); ok && !hasName {
+		n, err := strconv.Atoi(index)
+		if err != nil || n < 1 {
+			return nil
+		}
+		for _, node := range order {
+			if strings.ToLower(node.Role) == roleName {
+				if n--; n == 0 {
+					return node
+				}
+			}
+		}
+		return nil
+	}
+	for _, node := range order {
+		if strings.ToLower(node.Role) != role {
+			continue
+		}
+		if !hasName || strings.EqualFold(strings.TrimSpace(node.Label), name) {
+			return node
+		}
+	}
+	return nil
+}
+
+// isDescendant node 是否为 ancestor 本身或其后代
+func isDescendant(node, ancestor *AccessibilityNode, parents map[*AccessibilityNode]*AccessibilityNode) bool {
+	for current := node; current != nil; current = parents[current] {
+		if current == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// listContainer 节点所在的最近一层列表容器（不超出 scope）
+func listContainer(node *AccessibilityNode, parents map[*AccessibilityNode]*AccessibilityNode, scope *AccessibilityNode) *AccessibilityNode {
+	for current := parents[node]; current != nil; current = parents[current] {
+		if listContainerRoles[current.Role] {
+			return current
+		}
+		if current == scope {
+			return nil
+		}
+	}
+	return nil
+}
+
+// budgetHeader 预算序列化的标题，说明生效的过滤条件
+func budgetHeader(opts *SnapshotSerializeOptions, scope *AccessibilityNode) string {
+	var filters []string
+	if scope != nil {
+		filters = append(filters, "root "+scopeName(scope))
+	}
+	if opts.ViewportOnly {
+		filters = append(filters, "viewport only")
+	}
+	if opts.ListLimit > 0 {
+		filters = append(filters, fmt.Sprintf("lists limited to %d items", opts.ListLimit))
+	}
+	if budget := opts.charBudget(); budget > 0 {
+		filters = append(filters, fmt.Sprintf("budget %d chars (~%d tokens)", budget, budget/charsPerToken))
+	}
+
+	var builder strings.Builder
+	builder.WriteString("=== Interactive Elements ===\n")
+	builder.WriteString("Use RefIDs (e.g., @e1, @e2) as identifiers for interactions.\n")
+	if len(filters) > 0 {
+		builder.WriteString("Filtered: " + strings.Join(filters, ", ") + "\n")
+	}
+	builder.WriteString("\n")
+	return builder.String()
+}
+
+// budgetFooter 预算序列化的简短用法提示
+func budgetFooter() string {
+	return "USAGE: pass RefIDs as identifiers, e.g. {\"identifier\": \"@e1\"}\n"
+}
+
+// budgetNodeLine 可交互元素的一行，格式与 SerializeToSimpleText 保持一致
+func budgetNodeLine(node *AccessibilityNode, input bool) string {
+	candidates := []string{node.Label, node.Text, node.Description}
+	if input {
+		candidates = []string{node.Label, node.Placeholder, node.Description}
+	}
+	label := ""
+	for _, candidate := range candidates {
+		if candidate = strings.TrimSpace(candidate); candidate != "" {
+			label = truncateRunes(candidate, 50)
+			break
+		}
+	}
+	if label == "" {
+		label = fmt.Sprintf("<%s>", node.Role)
+	}
+
+	line := fmt.Sprintf("  @%s - %s", node.RefID, label)
+	if node.Role != "" && node.Role != "StaticText" {
+		line += fmt.Sprintf(" (%s)", node.Role)
+	}
+	if input {
+		if node.Placeholder != "" && node.Placeholder != label {
+			line += fmt.Sprintf(" [placeholder: %s]", truncateRunes(node.Placeholder, 50))
+		}
+		if node.Value != "" {
+			line += fmt.Sprintf(" [value: %s]", truncateRunes(node.Value, 50))
+		}
+	}
+	return line + frameSuffix(node)
+}
+
+// budgetHeadingLine 标题的一行（作为上下文，没有 RefID）
+func budgetHeadingLine(node *AccessibilityNode) string {
+	level := node.Attributes["level"]
+	if level == "" {
+		level = "?"
+	}
+	return fmt.Sprintf("  h%s %s%s", level, truncateRunes(strings.TrimSpace(node.Label), 80), frameSuffix(node))
+}
+
+// collapsedTitle 被折叠列表分组的标题
+const collapsedTitle = "COLLAPSED:\n"
+
+// omittedLine 因预算不足省略元素时的提示
+func omittedLine(omitted int) string {
+	return fmt.Sprintf("... %d more elements omitted to fit the budget; narrow with root, viewport_only or list_limit\n\n", omitted)
+}
+
+// collapsedLine 被折叠列表的一行，给出展开该列表可用的 root 参数
+func collapsedLine(list *collapsedList, order []*AccessibilityNode) string {
+	return fmt.Sprintf("  %d more items in %s (use root=%s to list them)", list.hidden, scopeName(list.container), scopeRoot(list.container, order))
+}
+
+// scopeName 节点的显示名称，如 list "Products"
+func scopeName(node *AccessibilityNode) string {
+	if node.RefID != "" {
+		return "@" + node.RefID
+	}
+	if name := strings.TrimSpace(node.Label); name != "" {
+		return fmt.Sprintf("%s %q", node.Role, truncateRunes(name, 50))
+	}
+	return node.Role
+}
+
+// scopeRoot 可用于 Root 选项唯一定位该节点的值：RefID、唯一的 role:name 或 role，否则为 role#n
+func scopeRoot(node *AccessibilityNode, order []*AccessibilityNode) string {
+	if node.RefID != "" {
+		return "@" + node.RefID
+	}
+	name := strings.TrimSpace(node.Label)
+	count, position, sameName := 0, 0, 0
+	for _, other := range order {
+		if !strings.EqualFold(other.Role, node.Role) {
+			continue
+		}
+		count++
+		if other == node {
+			position = count
+		}
+		if name == "" || strings.EqualFold(strings.TrimSpace(other.Label), name) {
+			sameName++
+		}
+	}
+	switch {
+	case sameName == 1 && name != "":
+		return node.Role + ":" + name
+	case sameName == 1:
+		return node.Role
+	}
+	return fmt.Sprintf("%s#%d", node.Role, position)
+}
+
+// truncateRunes 按字符截断过长的文本
+func truncateRunes(text string, max int) string {
+	if runes := []rune(text); len(runes) > max {
+		return string(runes[:max-3]) + "..."
+	}
+	return text
+}
@@ -0,0 +1,235 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// budgetTestSnapshot 商品页：导航链接、搜索框、4 个商品按钮的列表和登录表单
+func budgetTestSnapshot() *AccessibilitySnapshot {
+	heading := testNode("h", "heading", "Shop", 0)
+	heading.Attributes["level"] = "1"
+	return testSnapshot(
+		testNode("root", "RootWebArea", "Shop", 0, "h", "nav", "main"),
+		heading,
+		testNode("nav", "navigation", "Top", 0, "e1", "e2"),
+		refNode("e1", "link", "Home", 1),
+		refNode("e2", "link", "About", 2),
+		testNode("main", "main", "", 0, "e3", "list", "form"),
+		refNode("e3", "textbox", "Search", 3),
+		testNode("list", "list", "Products", 0, "e4", "e5", "e6", "e7"),
+		refNode("e4", "button", "Buy A", 4),
+		refNode("e5", "button", "Buy B", 5),
+		refNode("e6", "button", "Buy C", 6),
+		refNode("e7", "button", "Buy D", 7),
+		testNode("form", "form", "Login", 0, "e8", "e9"),
+		refNode("e8", "textbox", "User", 8),
+		refNode("e9", "button", "Sign in", 9),
+	)
+}
+
+func TestSerializeWithBudget(t *testing.T) {
+	snapshot := budgetTestSnapshot()
+
+	inViewport := map[string]bool{"e1": true, "e3": true, "h": true}
+	tests := []struct {
+		name      string
+		opts      SnapshotSerializeOptions
+		visible   func(*AccessibilityNode) bool
+		wantRefs  []string
+		wantLines []string
+	}{
+		{
+			name:      "list limit collapses the rest",
+			opts:      SnapshotSerializeOptions{ListLimit: 2},
+			wantRefs:  []string{"e1", "e2", "e3", "e4", "e5", "e8", "e9"},
+			wantLines: []string{`2 more items in list "Products" (use root=list:Products to list them)`, "h1 Shop", "lists limited to 2 items"},
+		},
+		{
+			name:      "root by role and name",
+			opts:      SnapshotSerializeOptions{Root: "form:login"},
+			wantRefs:  []string{"e8", "e9"},
+			wantLines: []string{`root form "Login"`},
+		},
+		{
+			name:     "root by RefID",
+			opts:     SnapshotSerializeOptions{Root: "@e3"},
+			wantRefs: []string{"e3"},
+		},
+		{
+			name:     "root list with limit",
+			opts:     SnapshotSerializeOptions{Root: "list:Products", ListLimit: 3},
+			wantRefs: []string{"e4", "e5", "e6"},
+		},
+		{
+			name:      "viewport only",
+			opts:      SnapshotSerializeOptions{ViewportOnly: true},
+			visible:   func(node *AccessibilityNode) bool { return inViewport[node.ID] },
+			wantRefs:  []string{"e1", "e3"},
+			wantLines: []string{"viewport only", "h1 Shop"},
+		},
+	}
+	for _, tt := range tests {
+		out, err := snapshot.SerializeWithBudget(&tt.opts, tt.visible)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		want := make(map[string]bool)
+		for _, ref := range tt.wantRefs {
+			want[ref] = true
+		}
+		for i := 1; i <= 9; i++ {
+			ref := "e" + string(rune('0'+i))
+			if got := strings.Contains(out, "@"+ref+" "); got != want[ref] {
+				t.Errorf("%s: %s listed = %v, want %v\n%s", tt.name, ref, got, want[ref], out)
+			}
+		}
+		for _, line := range tt.wantLines {
+			if !strings.Contains(out, line) {
+				t.Errorf("%s: output missing %q\n%s", tt.name, line, out)
+			}
+		}
+	}
+
+	if _, err := snapshot.SerializeWithBudget(&SnapshotSerializeOptions{Root: "dialog"}, nil); err == nil {
+		t.Error("unknown root should fail")
+	}
+}
+
+func TestSerializeWithBudgetTruncation(t *testing.T) {
+	snapshot := budgetTestSnapshot()
+
+	full, err := snapshot.SerializeWithBudget(&SnapshotSerializeOptions{MaxChars: 100000}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(full, "omitted") {
+		t.Fatalf("large budget should keep everything:\n%s", full)
+	}
+
+	// 预算不足时输入元素优先，其次按钮，再次链接，最后是标题；同一优先级按文档顺序保留
+	// 低于下限的预算按下限处理，输出（包括标题和用法提示）不超过生效的预算
+	tests := []struct {
+		budget   int
+		wantRefs []string
+	}{
+		{budget: 100, wantRefs: []string{"e3", "e8", "e4"}},
+		{budget: minCharBudget, wantRefs: []string{"e3", "e8", "e4"}},
+		{budget: 430, wantRefs: []string{"e3", "e8", "e4", "e5", "e6"}},
+		{budget: 480, wantRefs: []string{"e3", "e8", "e4", "e5", "e6", "e7", "e9"}},
+		{budget: 500, wantRefs: []string{"e3", "e8", "e4", "e5", "e6", "e7", "e9", "e1"}},
+	}
+	for _, tt := range tests {
+		out, err := snapshot.SerializeWithBudget(&SnapshotSerializeOptions{MaxChars: tt.budget}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := utf8.RuneCountInString(out); n > max(tt.budget, minCharBudget) {
+			t.Errorf("budget %d: output has %d chars", tt.budget, n)
+		}
+		want := make(map[string]bool)
+		for _, ref := range tt.wantRefs {
+			want[ref] = true
+		}
+		for i := 1; i <= 9; i++ {
+			ref := "e" + string(rune('0'+i))
+			if got := strings.Contains(out, "@"+ref+" "); got != want[ref] {
+				t.Errorf("budget %d: %s kept = %v, want %v\n%s", tt.budget, ref, got, want[ref], out)
+			}
+		}
+		omitted := fmt.Sprintf("... %d more elements omitted", 10-len(tt.wantRefs))
+		if !strings.Contains(out, omitted) {
+			t.Errorf("budget %d: missing %q\n%s", tt.budget, omitted, out)
+		}
+	}
+}
+
+func TestSnapshotSerializeOptionsBudget(t *testing.T) {
+	tests := []struct {
+		opts       *SnapshotSerializeOptions
+		wantBudget int
+		wantZero   bool
+	}{
+		{opts: nil, wantZero: true},
+		{opts: &SnapshotSerializeOptions{}, wantZero: true},
+		{opts: &SnapshotSerializeOptions{MaxTokens: 100}, wantBudget: 400},
+		{opts: &SnapshotSerializeOptions{MaxChars: 600}, wantBudget: 600},
+		{opts: &SnapshotSerializeOptions{MaxTokens: 200, MaxChars: 600}, wantBudget: 600},
+		{opts: &SnapshotSerializeOptions{MaxTokens: 120, MaxChars: 600}, wantBudget: 480},
+		{opts: &SnapshotSerializeOptions{MaxChars: 300}, wantBudget: minCharBudget},
+		{opts: &SnapshotSerializeOptions{MaxChars: -5, ListLimit: 3}, wantBudget: 0},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.IsZero(); got != tt.wantZero {
+			t.Errorf("%+v: IsZero = %v, want %v", tt.opts, got, tt.wantZero)
+		}
+		if tt.opts == nil {
+			continue
+		}
+		if got := tt.opts.charBudget(); got != tt.wantBudget {
+			t.Errorf("%+v: charBudget = %d, want %d", tt.opts, got, tt.wantBudget)
+		}
+	}
+}
+
+func TestCollapsedListRoot(t *testing.T) {
+	// 两个未命名的列表和两个同名的列表：折叠提示给出的 root 必须定位到被折叠的那一个
+	snapshot := testSnapshot(
+		testNode("root", "RootWebArea", "Shop", 0, "l1", "l2", "l3", "l4", "l5"),
+		testNode("l1", "list", "", 0, "e1", "e2"),
+		testNode("l2", "list", "", 0, "e3", "e4"),
+		testNode("l3", "list", "Results", 0, "e5", "e6"),
+		testNode("l4", "list", "Results", 0, "e7", "e8"),
+		testNode("l5", "list", "Filters", 0, "e9", "e10"),
+		refNode("e1", "button", "A1", 1),
+		refNode("e2", "button", "A2", 2),
+		refNode("e3", "button", "B1", 3),
+		refNode("e4", "button", "B2", 4),
+		refNode("e5", "button", "C1", 5),
+		refNode("e6", "button", "C2", 6),
+		refNode("e7", "button", "D1", 7),
+		refNode("e8", "button", "D2", 8),
+		refNode("e9", "button", "E1", 9),
+		refNode("e10", "button", "E2", 10),
+	)
+
+	out, err := snapshot.SerializeWithBudget(&SnapshotSerializeOptions{ListLimit: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		root     string
+		wantRefs []string
+	}{
+		{root: "list#1", wantRefs: []string{"e1", "e2"}},
+		{root: "list#2", wantRefs: []string{"e3", "e4"}},
+		{root: "list#3", wantRefs: []string{"e5", "e6"}},
+		{root: "list#4", wantRefs: []string{"e7", "e8"}},
+		{root: "list:Filters", wantRefs: []string{"e9", "e10"}},
+	}
+	for _, tt := range tests {
+		if !strings.Contains(out, "(use root="+tt.root+" to list them)") {
+			t.Errorf("output missing root %s\n%s", tt.root, out)
+		}
+		scoped, err := snapshot.SerializeWithBudget(&SnapshotSerializeOptions{Root: tt.root}, nil)
+		if err != nil {
+			t.Fatalf("root %s: %v", tt.root, err)
+		}
+		for _, ref := range tt.wantRefs {
+			if !strings.Contains(scoped, "@"+ref+" ") {
+				t.Errorf("root %s: %s not listed\n%s", tt.root, ref, scoped)
+			}
+		}
+		if n := strings.Count(scoped, "  @e"); n != len(tt.wantRefs) {
+			t.Errorf("root %s: listed %d elements, want %d", tt.root, n, len(tt.wantRefs))
+		}
+	}
+
+	for _, root := range []string{"list#0", "list#6", "list#x"} {
+		if _, err := snapshot.SerializeWithBudget(&SnapshotSerializeOptions{Root: root}, nil); err == nil {
+			t.Errorf("root %s should not match", root)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// maxSnapshotHistory 最多为多少个页面保留上一次快照（超出时丢弃最久未使用的页面记录）
+const maxSnapshotHistory = 16
+
+// snapshotHistoryEntry 某个页面最近一次获取的快照
+type snapshotHistoryEntry struct {
+	snapshot *AccessibilitySnapshot
+	seq      uint64 // 最近一次使用的序号，用于淘汰最久未使用的页面
+}
+
+// snapshotStateAttributes 参与差异比较的可访问性状态属性
+var snapshotStateAttributes = []string{"checked", "selected", "expanded", "pressed", "disabled", "readonly", "required", "invalid"}
+
+// SnapshotDiffNode 差异中的一个元素
+type SnapshotDiffNode struct {
+	RefID string `json:"ref"`
+	Role  string `json:"role,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Value string `json:"value,omitempty"`
+	Frame string `json:"frame,omitempty"` // 所在 iframe 的框架路径（主文档为空）
+}
+
+// SnapshotFieldChange 元素某个字段的变化
+type SnapshotFieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// SnapshotChangedNode 前后两次快照中 RefID 相同但内容变化的元素
+type SnapshotChangedNode struct {
+	SnapshotDiffNode
+	Changes []SnapshotFieldChange `json:"changes"`
+}
+
+// SnapshotDiff 同一页面前后两次可访问性快照之间的差异
+type SnapshotDiff struct {
+	Baseline  bool                  `json:"baseline"` // 是否存在可对比的上一次快照；为 false 时所有元素都记为新增
+	Added     []SnapshotDiffNode    `json:"added"`
+	Removed   []SnapshotDiffNode    `json:"removed"`
+	Changed   []SnapshotChangedNode `json:"changed"`
+	Unchanged int                   `json:"unchanged"`
+}
+
+// HasChanges 是否有任何元素变化
+func (d *SnapshotDiff) HasChanges() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0 || len(d.Changed) > 0
+}
+
+// SerializeToText 将差异序列化为简洁文本（用于 LLM）
+func (d *SnapshotDiff) SerializeToText() string {
+	var builder strings.Builder
+	builder.WriteString("=== Snapshot Diff ===\n")
+	if !d.Baseline {
+		builder.WriteString("No previous snapshot for this page; all elements are listed as added.\n")
+	}
+	builder.WriteString(fmt.Sprintf("%d added, %d removed, %d changed, %d unchanged\n",
+		len(d.Added), len(d.Removed), len(d.Changed), d.Unchanged))
+	if !d.HasChanges() {
+		builder.WriteString("\nNo changes since the previous snapshot. Existing RefIDs remain valid.\n")
+		return builder.String()
+	}
+
+	if len(d.Added) > 0 {
+		builder.WriteString("\nADDED:\n")
+		for _, node := range d.Added {
+			builder.WriteString("  + " + node.line() + "\n")
+		}
+	}
+	if len(d.Removed) > 0 {
+		builder.WriteString("\nREMOVED (RefIDs no longer valid):\n")
+		for _, node := range d.Removed {
+			builder.WriteString("  - " + node.line() + "\n")
+		}
+	}
+	if len(d.Changed) > 0 {
+		builder.WriteString("\nCHANGED:\n")
+		for _, node := range d.Changed {
+			builder.WriteString("  ~ " + node.line() + "\n")
+			for _, change := range node.Changes {
+				builder.WriteString(fmt.Sprintf("      %s: %q -> %q\n", change.Field, change.Before, change.After))
+			}
+		}
+	}
+	builder.WriteString("\nRefIDs not listed above are unchanged and still valid.\n")
+	return builder.String()
+}
+
+// line 单个元素的文本表示，格式与 SerializeToSimpleText 保持一致
+func (n SnapshotDiffNode) line() string {
+	text := "@" + n.RefID
+	if n.Name != "" {
+		text += " - " + n.Name
+	}
+	if n.Role != "" {
+		text += " (" + n.Role + ")"
+	}
+	if n.Value != "" {
+		text += " [value: " + n.Value + "]"
+	}
+	if n.Frame != "" {
+		text += " [frame " + n.Frame + "]"
+	}
+	return text
+}
+
+// GetAccessibilitySnapshotDiff 重新获取当前页面的可访问性快照，并与该页面上一次获取的快照比较
+// 能匹配到上一次快照的元素沿用原 RefID，因此未出现在差异中的 RefID 仍然有效
+func (e *Executor) GetAccessibilitySnapshotDiff(ctx context.Context) (*SnapshotDiff, *AccessibilitySnapshot, error) {
+	page := e.activePage()
+	if page == nil {
+		return nil, nil, fmt.Errorf("no active page")
+	}
+
+	e.refIDMutex.RLock()
+	previous := e.previousSnapshot(page.TargetID)
+	e.refIDMutex.RUnlock()
+
+	snapshot, err := e.refreshSnapshot(ctx, page)
+	if err != nil {
+		return nil, nil, err
+	}
+	return DiffSnapshots(previous, snapshot), snapshot, nil
+}
+
+// withOperationSnapshot 为点击、输入、选择等操作的结果附带页面快照
+// 默认附带完整快照文本（semantic_tree）；diff 为 true 时重新获取快照，附带相对上一次快照的差异（snapshot_diff / snapshot_diff_text）
+func (e *Executor) withOperationSnapshot(ctx context.Context, data map[string]interface{}, diff bool) map[string]interface{} {
+	if diff {
+		snapshotDiff, _, err := e.GetAccessibilitySnapshotDiff(ctx)
+		if err != nil {
+			logger.Error(ctx, "Failed to get accessibility snapshot diff: %s", err.Error())
+			return data
+		}
+		data["snapshot_diff"] = snapshotDiff
+		data["snapshot_diff_text"] = snapshotDiff.SerializeToText()
+		return data
+	}
+
+	snapshot, err := e.GetAccessibilitySnapshot(ctx)
+	if err != nil {
+		logger.Error(ctx, "Failed to get accessibility snapshot: %s", err.Error())
+	}
+	var accessibilitySnapshotText string
+	if snapshot != nil {
+		accessibilitySnapshotText = snapshot.SerializeToSimpleText()
+	}
+	data["semantic_tree"] = accessibilitySnapshotText
+	return data
+}
+
+// ClearSnapshotHistory 清除所有页面的历史快照（会话关闭时调用）
+func (e *Executor) ClearSnapshotHistory() {
+	e.refIDMutex.Lock()
+	defer e.refIDMutex.Unlock()
+	e.snapshotHistory = nil
+}
+
+// forgetSnapshot 丢弃已关闭页面的历史快照
+func (e *Executor) forgetSnapshot(targetID proto.TargetTargetID) {
+	e.refIDMutex.Lock()
+	defer e.refIDMutex.Unlock()
+	delete(e.snapshotHistory, targetID)
+}
+
+// previousSnapshot 返回页面上一次获取的快照，没有时返回 nil（调用方持有锁）
+func (e *Executor) previousSnapshot(targetID proto.TargetTargetID) *AccessibilitySnapshot {
+	if entry, ok := e.snapshotHistory[targetID]; ok {
+		return entry.snapshot
+	}
+	return nil
+}
+
+// rememberSnapshot 记录页面最近一次获取的快照，作为下一次差异比较和 RefID 复用的基准（调用方持有写锁）
+// 记录数达到上限时只淘汰最久未使用的一个页面
+func (e *Executor) rememberSnapshot(targetID proto.TargetTargetID, snapshot *AccessibilitySnapshot) {
+	if e.snapshotHistory == nil {
+		e.snapshotHistory = make(map[proto.TargetTargetID]*snapshotHistoryEntry)
+	}
+	if _, ok := e.snapshotHistory[targetID]; !ok && len(e.snapshotHistory) >= maxSnapshotHistory {
+		var oldest proto.TargetTargetID
+		var oldestSeq uint64
+		first := true
+		for id, entry := range e.snapshotHistory {
+			if first || entry.seq < oldestSeq {
+				oldest, oldestSeq, first = id, entry.seq, false
+			}
+		}
+		delete(e.snapshotHistory, oldest)
+	}
+	e.snapshotSeq++
+	e.snapshotHistory[targetID] = &snapshotHistoryEntry{snapshot: snapshot, seq: e.snapshotSeq}
+}
+
+// DiffSnapshots 比较两次快照中带 RefID 的元素，previous 为 nil 时所有元素都记为新增
+func DiffSnapshots(previous, current *AccessibilitySnapshot) *SnapshotDiff {
+	diff := &SnapshotDiff{
+		Baseline: previous != nil,
+		Added:    []SnapshotDiffNode{},
+		Removed:  []SnapshotDiffNode{},
+		Changed:  []SnapshotChangedNode{},
+	}
+
+	before := make(map[string]*AccessibilityNode)
+	if previous != nil {
+		for _, node := range refNodes(previous) {
+			before[node.RefID] = node
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, node := range refNodes(current) {
+		seen[node.RefID] = true
+		old, ok := before[node.RefID]
+		if !ok {
+			diff.Added = append(diff.Added, diffNode(node))
+			continue
+		}
+		if changes := nodeChanges(old, node); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, SnapshotChangedNode{SnapshotDiffNode: diffNode(node), Changes: changes})
+		} else {
+			diff.Unchanged++
+		}
+	}
+	for refID, node := range before {
+		if !seen[refID] {
+			diff.Removed = append(diff.Removed, diffNode(node))
+		}
+	}
+
+	sortDiffNodes(diff.Added)
+	sortDiffNodes(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		return refNumber(diff.Changed[i].RefID) < refNumber(diff.Changed[j].RefID)
+	})
+	return diff
+}
+
+// refNodes 快照中所有带 RefID 的元素
+func refNodes(snapshot *AccessibilitySnapshot) []*AccessibilityNode {
+	var nodes []*AccessibilityNode
+	for _, node := range interactiveNodes(snapshot) {
+		if node.RefID != "" {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// interactiveNodes 快照中可分配 RefID 的元素（可点击元素和输入元素，去重）
+func interactiveNodes(snapshot *AccessibilitySnapshot) []*AccessibilityNode {
+	seen := make(map[*AccessibilityNode]bool)
+	var nodes []*AccessibilityNode
+	for _, node := range append(snapshot.GetClickableElements(), snapshot.GetInputElements()...) {
+		if !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+func diffNode(node *AccessibilityNode) SnapshotDiffNode {
+	return SnapshotDiffNode{
+		RefID: node.RefID,
+		Role:  node.Role,
+		Name:  markName(node),
+		Value: node.Value,
+		Frame: framePathKey(node.FramePath),
+	}
+}
+
+// nodeChanges 比较同一 RefID 元素的名称、值和状态属性
+func nodeChanges(old, node *AccessibilityNode) []SnapshotFieldChange {
+	var changes []SnapshotFieldChange
+	add := func(field, before, after string) {
+		if before != after {
+			changes = append(changes, SnapshotFieldChange{Field: field, Before: before, After: after})
+		}
+	}
+	add("name", markName(old), markName(node))
+	add("value", old.Value, node.Value)
+	add("description", old.Description, node.Description)
+	for _, attr := range snapshotStateAttributes {
+		add(attr, old.Attributes[attr], node.Attributes[attr])
+	}
+	return changes
+}
+
+func sortDiffNodes(nodes []SnapshotDiffNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return refNumber(nodes[i].RefID) < refNumber(nodes[j].RefID)
+	})
+}
+
+// refNumber 解析 RefID 中的序号（e12 -> 12），无法解析时返回 0
+func refNumber(refID string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(refID, "e"))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// matchPreviousRefIDs 为新快照中的元素匹配上一次快照的 RefID
+// 先按同一框架内的 BackendNodeID 匹配（同一文档内最可靠，能识别名称变化），
+// 再按 框架+role+name 分组、组内按 BackendNodeID 顺序配对（适用于重新渲染或导航后的同类元素）
+func matchPreviousRefIDs(previous, current *AccessibilitySnapshot) map[*AccessibilityNode]string {
+	matched := make(map[*AccessibilityNode]string)
+	if previous == nil {
+		return matched
+	}
+
+	oldNodes := refNodes(previous)
+	newNodes := interactiveNodes(current)
+	sortByBackendID(oldNodes)
+	sortByBackendID(newNodes)
+
+	claimed := make(map[string]bool)
+	byBackendID := make(map[string]*AccessibilityNode, len(oldNodes))
+	for _, node := range oldNodes {
+		byBackendID[backendKey(node)] = node
+	}
+	for _, node := range newNodes {
+		if old, ok := byBackendID[backendKey(node)]; ok && old.Role == node.Role && !claimed[old.RefID] {
+			matched[node] = old.RefID
+			claimed[old.RefID] = true
+		}
+	}
+
+	remaining := make(map[string][]*AccessibilityNode)
+	for _, node := range oldNodes {
+		if !claimed[node.RefID] {
+			key := semanticKey(node)
+			remaining[key] = append(remaining[key], node)
+		}
+	}
+	for _, node := range newNodes {
+		if _, ok := matched[node]; ok {
+			continue
+		}
+		key := semanticKey(node)
+		if candidates := remaining[key]; len(candidates) > 0 {
+			matched[node] = candidates[0].RefID
+			remaining[key] = candidates[1:]
+		}
+	}
+	return matched
+}
+
+// maxRefNumber 快照中最大的 RefID 序号，新分配的 RefID 从其后继续编号以免与复用的 RefID 冲突
+func maxRefNumber(snapshot *AccessibilitySnapshot) int {
+	max := 0
+	if snapshot == nil {
+		return max
+	}
+	for _, node := range refNodes(snapshot) {
+		if n := refNumber(node.RefID); n > max {
+			max = n
+		}
+	}
+	return max
+}
+
+func sortByBackendID(nodes []*AccessibilityNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].BackendNodeID < nodes[j].BackendNodeID
+	})
+}
+
+func backendKey(node *AccessibilityNode) string {
+	return fmt.Sprintf("%s#%d", framePathKey(node.FramePath), node.BackendNodeID)
+}
+
+func semanticKey(node *AccessibilityNode) string {
+	return fmt.Sprintf("%s:%s:%s", framePathKey(node.FramePath), node.Role, node.Label)
+}
@@ -0,0 +1,194 @@
+package executor
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// refNode 构造已分配 RefID 的节点
+func refNode(refID, role, label string, backendID int) *AccessibilityNode {
+	node := testNode(refID, role, label, backendID)
+	node.RefID = refID
+	return node
+}
+
+func diffRefs(nodes []SnapshotDiffNode) []string {
+	refs := []string{}
+	for _, node := range nodes {
+		refs = append(refs, node.RefID)
+	}
+	return refs
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	checked := refNode("e3", "checkbox", "Remember me", 3)
+	checked.Attributes["checked"] = "true"
+	typed := refNode("e4", "textbox", "Email", 4)
+	typed.Value = "a@example.com"
+
+	previous := testSnapshot(
+		testNode("root", "RootWebArea", "", 0),
+		refNode("e1", "button", "Submit", 1),
+		refNode("e2", "link", "Help", 2),
+		refNode("e3", "checkbox", "Remember me", 3),
+		refNode("e4", "textbox", "Email", 4),
+		refNode("e10", "button", "Cancel", 10),
+	)
+
+	tests := []struct {
+		name          string
+		previous      *AccessibilitySnapshot
+		current       *AccessibilitySnapshot
+		wantBaseline  bool
+		wantAdded     []string
+		wantRemoved   []string
+		wantChanged   map[string][]string // RefID -> 变化的字段
+		wantUnchanged int
+	}{
+		{
+			name:      "no previous snapshot",
+			current:   testSnapshot(refNode("e2", "link", "Help", 2), refNode("e1", "button", "Submit", 1)),
+			wantAdded: []string{"e1", "e2"},
+		},
+		{
+			name:     "added, removed and changed",
+			previous: previous,
+			current: testSnapshot(
+				testNode("root", "RootWebArea", "", 0),
+				refNode("e1", "button", "Submit", 1),
+				refNode("e2", "link", "Help center", 2),
+				checked,
+				typed,
+				refNode("e11", "button", "Save draft", 11),
+			),
+			wantBaseline:  true,
+			wantAdded:     []string{"e11"},
+			wantRemoved:   []string{"e10"},
+			wantChanged:   map[string][]string{"e2": {"name"}, "e3": {"checked"}, "e4": {"value"}},
+			wantUnchanged: 1,
+		},
+		{
+			name:          "identical snapshots",
+			previous:      previous,
+			current:       previous,
+			wantBaseline:  true,
+			wantUnchanged: 5,
+		},
+	}
+	for _, tt := range tests {
+		diff := DiffSnapshots(tt.previous, tt.current)
+		if diff.Baseline != tt.wantBaseline {
+			t.Errorf("%s: baseline = %v, want %v", tt.name, diff.Baseline, tt.wantBaseline)
+		}
+		if got, want := diffRefs(diff.Added), append([]string{}, tt.wantAdded...); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: added = %v, want %v", tt.name, got, want)
+		}
+		if got, want := diffRefs(diff.Removed), append([]string{}, tt.wantRemoved...); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: removed = %v, want %v", tt.name, got, want)
+		}
+		changed := make(map[string][]string)
+		for _, node := range diff.Changed {
+			for _, change := range node.Changes {
+				changed[node.RefID] = append(changed[node.RefID], change.Field)
+			}
+		}
+		if len(changed) != len(tt.wantChanged) || (len(changed) > 0 && !reflect.DeepEqual(changed, tt.wantChanged)) {
+			t.Errorf("%s: changed = %v, want %v", tt.name, changed, tt.wantChanged)
+		}
+		if diff.Unchanged != tt.wantUnchanged {
+			t.Errorf("%s: unchanged = %d, want %d", tt.name, diff.Unchanged, tt.wantUnchanged)
+		}
+		if diff.HasChanges() != (len(tt.wantAdded)+len(tt.wantRemoved)+len(tt.wantChanged) > 0) {
+			t.Errorf("%s: HasChanges = %v", tt.name, diff.HasChanges())
+		}
+	}
+}
+
+func TestAssignRefIDsReusesPreviousRefIDs(t *testing.T) {
+	e := newTestExecutor()
+	previous := testSnapshot(
+		testNode("root", "RootWebArea", "", 0),
+		refNode("e1", "button", "Submit", 1),
+		refNode("e2", "link", "Help", 2),
+		refNode("e3", "textbox", "Email", 3),
+		refNode("e7", "button", "Delete", 7),
+		inFrame(refNode("e5", "button", "Pay", 5), 0),
+	)
+
+	current := testSnapshot(
+		testNode("root", "RootWebArea", "", 0),
+		// BackendNodeID 不变、名称变化：按 BackendNodeID 匹配
+		testNode("a", "button", "Send", 1),
+		// 重新渲染后 BackendNodeID 变化：按 role+name 匹配
+		testNode("b", "link", "Help", 20),
+		testNode("c", "textbox", "Email", 3),
+		// 同一 BackendNodeID 但角色变化，视为新元素
+		testNode("d", "checkbox", "Delete", 7),
+		// 另一框架中的同名按钮不能沿用主文档的 RefID
+		inFrame(testNode("e", "button", "Pay", 5), 1),
+		testNode("f", "button", "Pay", 30),
+	)
+	e.assignRefIDs(current, previous)
+
+	tests := []struct {
+		nodeID string
+		want   string
+	}{
+		{nodeID: "a", want: "e1"},
+		{nodeID: "b", want: "e2"},
+		{nodeID: "c", want: "e3"},
+	}
+	for _, tt := range tests {
+		if got := current.Elements[tt.nodeID].RefID; got != tt.want {
+			t.Errorf("node %s: RefID = %q, want %q", tt.nodeID, got, tt.want)
+		}
+	}
+
+	// 新元素从上一次快照的最大序号之后继续编号
+	for _, nodeID := range []string{"d", "f1:e", "f"} {
+		refID := current.Elements[nodeID].RefID
+		if refNumber(refID) <= 7 {
+			t.Errorf("node %s: new element got RefID %q, want a number after e7", nodeID, refID)
+		}
+	}
+	if len(e.refIDMap) != 6 {
+		t.Errorf("refIDMap has %d entries, want 6", len(e.refIDMap))
+	}
+}
+
+func TestRememberSnapshotEvictsOldest(t *testing.T) {
+	e := &Executor{}
+	target := func(i int) proto.TargetTargetID { return proto.TargetTargetID(fmt.Sprintf("page-%d", i)) }
+	for i := 0; i < maxSnapshotHistory; i++ {
+		e.rememberSnapshot(target(i), &AccessibilitySnapshot{})
+	}
+	// 再次使用最早记录的页面，下一个最久未使用的是 page-1
+	e.rememberSnapshot(target(0), &AccessibilitySnapshot{})
+	e.rememberSnapshot(target(maxSnapshotHistory), &AccessibilitySnapshot{})
+
+	if len(e.snapshotHistory) != maxSnapshotHistory {
+		t.Errorf("history has %d entries, want %d", len(e.snapshotHistory), maxSnapshotHistory)
+	}
+	tests := []struct {
+		target proto.TargetTargetID
+		want   bool
+	}{
+		{target: target(0), want: true},
+		{target: target(1), want: false},
+		{target: target(2), want: true},
+		{target: target(maxSnapshotHistory), want: true},
+	}
+	for _, tt := range tests {
+		if got := e.previousSnapshot(tt.target) != nil; got != tt.want {
+			t.Errorf("%s kept = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+
+	e.forgetSnapshot(target(0))
+	if e.previousSnapshot(target(0)) != nil {
+		t.Error("snapshot of closed page still remembered")
+	}
+}
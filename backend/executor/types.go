@@ -18,6 +18,7 @@ type RefData struct {
 	Href        string            // 链接地址（对于 link）
 	Attributes  map[string]string // 其他关键属性（id, class等）
 	Placeholder string            // 占位符（可选）
+	FramePath   []int             // 所在 iframe 的框架路径（主文档为空），查找时先进入对应框架
 }
 
 // Page 表示一个浏览器页面及其上下文
@@ -35,6 +36,8 @@ type AccessibilitySnapshot struct {
 	Elements     map[string]*AccessibilityNode                           // AXNodeID -> Node 映射
 	AXNodeMap    map[proto.AccessibilityAXNodeID]*proto.AccessibilityAXNode // AXNodeID -> AXNode 映射
 	BackendIDMap map[proto.DOMBackendNodeID]*AccessibilityNode           // BackendNodeID -> Node 映射
+
+	sessionID proto.TargetSessionID // 主文档的 CDP 会话（跨进程 iframe 使用独立会话）
 }
 
 // AccessibilityNode 表示页面中的一个可访问性节点（基于 Accessibility Node）
@@ -54,6 +57,8 @@ type AccessibilityNode struct {
 	IsEnabled     bool                          // 是否启用（非 disabled）
 	Children      []*AccessibilityNode          // 子节点
 	Metadata      map[string]interface{}        // 其他元数据
+	FramePath     []int                         // 所在 iframe 的框架路径（如 [1 0]），主文档为空
+	FrameURL      string                        // 所在 iframe 的文档地址
 	
 	// 保留兼容性字段
 	Type       string           // 保留，映射到 Role
@@ -88,25 +93,28 @@ type NavigateOptions struct {
 
 // ClickOptions 点击选项
 type ClickOptions struct {
-	WaitVisible bool          // 等待元素可见
-	WaitEnabled bool          // 等待元素可用
-	Timeout     time.Duration // 超时时间
-	Button      string        // 鼠标按钮：left, right, middle
-	ClickCount  int           // 点击次数
+	WaitVisible  bool          // 等待元素可见
+	WaitEnabled  bool          // 等待元素可用
+	Timeout      time.Duration // 超时时间
+	Button       string        // 鼠标按钮：left, right, middle
+	ClickCount   int           // 点击次数
+	SnapshotDiff bool          // 结果中返回相对上一次快照的差异，而非完整快照
 }
 
 // TypeOptions 输入选项
 type TypeOptions struct {
-	Clear       bool          // 是否先清空
-	WaitVisible bool          // 等待元素可见
-	Timeout     time.Duration // 超时时间
-	Delay       time.Duration // 每个字符之间的延迟
+	Clear        bool          // 是否先清空
+	WaitVisible  bool          // 等待元素可见
+	Timeout      time.Duration // 超时时间
+	Delay        time.Duration // 每个字符之间的延迟
+	SnapshotDiff bool          // 结果中返回相对上一次快照的差异，而非完整快照
 }
 
 // SelectOptions 选择选项
 type SelectOptions struct {
-	WaitVisible bool          // 等待元素可见
-	Timeout     time.Duration // 超时时间
+	WaitVisible  bool          // 等待元素可见
+	Timeout      time.Duration // 超时时间
+	SnapshotDiff bool          // 结果中返回相对上一次快照的差异，而非完整快照
 }
 
 // WaitForOptions 等待选项
@@ -120,6 +128,7 @@ type ScreenshotOptions struct {
 	FullPage bool   // 是否截取完整页面
 	Quality  int    // 质量 (0-100)
 	Format   string // 格式：png, jpeg
+	Annotate bool   // 在可交互元素上绘制带 RefID 的编号框（Set-of-Marks），并返回图例
 }
 
 // ExtractOptions 提取选项
@@ -145,3 +154,25 @@ type PressKeyOptions struct {
 	Meta  bool // Meta 键 (Command on Mac, Windows key on Windows)
 }
 
+
+// ConsoleMessagesOptions 控制台消息查询选项
+type ConsoleMessagesOptions struct {
+	Levels []string  // 级别过滤：debug, log, info, warning, error
+	Since  time.Time // 只返回该时间之后的消息
+	Cursor int64     // 只返回序号大于该游标的消息（上次返回的 cursor）
+	Limit  int       // 最多返回条数（保留最新的）
+	Clear  bool      // 读取后清空缓冲区
+}
+
+// NetworkRequestsOptions 网络请求查询选项
+type NetworkRequestsOptions struct {
+	URL           string   // URL 子串过滤
+	Method        string   // 请求方法过滤
+	Status        string   // 状态码过滤：404、4xx、400-499
+	ResourceTypes []string // 资源类型过滤：Document, XHR, Fetch, Script ...
+	State         string   // 请求状态过滤：pending, finished, failed
+	Cursor        int64    // 只返回序号大于该游标的请求
+	Limit         int      // 最多返回条数（保留最新的）
+	IncludeBody   bool     // 是否附带响应体
+	Clear         bool     // 读取后清空日志
+}
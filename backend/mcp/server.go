@@ -5,7 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -122,6 +122,7 @@ func (s *MCPServer) Start() error {
 
 // Stop 停止 MCP 服务
 func (s *MCPServer) Stop() {
+	s.toolRegistry.Sessions().CloseAll(s.ctx)
 	logger.Info(s.ctx, "MCP server stopped")
 	s.cancel()
 }
@@ -176,6 +177,21 @@ func (s *MCPServer) registerTool(script *models.Script) error {
 		debugSchema, _ := json.Marshal(script.MCPInputSchema)
 		logger.Info(s.ctx, "MCP input schema: %s", string(debugSchema))
 
+		// 必填参数列表
+		required := make(map[string]bool)
+		switch list := script.MCPInputSchema["required"].(type) {
+		case []interface{}:
+			for _, name := range list {
+				if str, ok := name.(string); ok {
+					required[str] = true
+				}
+			}
+		case []string:
+			for _, name := range list {
+				required[name] = true
+			}
+		}
+
 		if props, ok := script.MCPInputSchema["properties"].(map[string]interface{}); ok {
 			for propName, propDef := range props {
 				if propDefMap, ok := propDef.(map[string]interface{}); ok {
@@ -189,14 +205,38 @@ func (s *MCPServer) registerTool(script *models.Script) error {
 						propType = t
 					}
 
+					propOpts := []mcpgo.PropertyOption{mcpgo.Description(desc)}
+					if required[propName] {
+						propOpts = append(propOpts, mcpgo.Required())
+					}
+
 					// 根据类型添加参数
 					switch propType {
 					case "string":
-						opts = append(opts, mcpgo.WithString(propName, mcpgo.Description(desc)))
+						if pattern, ok := propDefMap["pattern"].(string); ok && pattern != "" {
+							propOpts = append(propOpts, mcpgo.Pattern(pattern))
+						}
+						if enum, ok := propDefMap["enum"].([]interface{}); ok && len(enum) > 0 {
+							values := make([]string, 0, len(enum))
+							for _, v := range enum {
+								values = append(values, argumentString(v))
+							}
+							propOpts = append(propOpts, mcpgo.Enum(values...))
+						}
+						if def, ok := propDefMap["default"].(string); ok {
+							propOpts = append(propOpts, mcpgo.DefaultString(def))
+						}
+						opts = append(opts, mcpgo.WithString(propName, propOpts...))
 					case "number", "integer":
-						opts = append(opts, mcpgo.WithNumber(propName, mcpgo.Description(desc)))
+						if def, ok := propDefMap["default"].(float64); ok {
+							propOpts = append(propOpts, mcpgo.DefaultNumber(def))
+						}
+						opts = append(opts, mcpgo.WithNumber(propName, propOpts...))
 					case "boolean":
-						opts = append(opts, mcpgo.WithBoolean(propName, mcpgo.Description(desc)))
+						if def, ok := propDefMap["default"].(bool); ok {
+							propOpts = append(propOpts, mcpgo.DefaultBool(def))
+						}
+						opts = append(opts, mcpgo.WithBoolean(propName, propOpts...))
 					}
 				}
 			}
@@ -217,62 +257,34 @@ func (s *MCPServer) registerTool(script *models.Script) error {
 func (s *MCPServer) createToolHandler(script *models.Script) func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
 	return func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
 		logger.Info(ctx, "Executing MCP command: %s (script: %s)", script.MCPCommandName, script.Name)
-		logger.Info(ctx, "MCP command arguments: %v", request.Params.Arguments)
-
-		// 检查浏览器是否运行
-		if !s.browserMgr.IsRunning() {
-			logger.Info(ctx, "Browser not running, starting...")
-			if err := s.browserMgr.Start(ctx); err != nil {
-				return mcpgo.NewToolResultError(fmt.Sprintf("Failed to start browser: %v", err)), nil
-			}
-			logger.Info(ctx, "Browser started successfully")
-		}
-
-		// 创建脚本副本并替换占位符
-		scriptToRun := script.Copy()
 
-		// 合并参数：先使用脚本预设变量，再用外部传入的参数覆盖
+		// 外部传入的参数会覆盖脚本预设变量和变量声明的默认值
 		params := make(map[string]string)
-
-		// 1. 首先添加脚本的预设变量
-		if scriptToRun.Variables != nil {
-			for key, value := range scriptToRun.Variables {
-				params[key] = value
-			}
-		}
-
-		// 2. 外部传入的参数会覆盖预设变量
 		if request.Params.Arguments != nil {
 			if argsMap, ok := request.Params.Arguments.(map[string]interface{}); ok {
 				for key, value := range argsMap {
-					params[key] = fmt.Sprintf("%v", value)
-				}
-				for key := range scriptToRun.Variables {
-					if _, ok := params[key]; ok {
-						scriptToRun.Variables[key] = params[key]
+					if value == nil {
+						continue // null 视为未传，使用默认值
 					}
+					params[key] = argumentString(value)
 				}
 			}
 		}
+		logger.Info(ctx, "MCP command arguments: %v", script.MaskParams(params))
 
-		// 替换 URL 中的占位符
-		if urlParam, ok := params["url"]; ok && urlParam != "" {
-			scriptToRun.URL = urlParam
-		} else {
-			scriptToRun.URL = s.replacePlaceholders(scriptToRun.URL, params)
+		// 校验参数并替换占位符（在启动浏览器之前拒绝无效参数）
+		scriptToRun, err := script.WithParams(params)
+		if err != nil {
+			return mcpgo.NewToolResultError(err.Error()), nil
 		}
 
-		// 替换所有 action 中的占位符
-		for i := range scriptToRun.Actions {
-			scriptToRun.Actions[i].Selector = s.replacePlaceholders(scriptToRun.Actions[i].Selector, params)
-			scriptToRun.Actions[i].XPath = s.replacePlaceholders(scriptToRun.Actions[i].XPath, params)
-			scriptToRun.Actions[i].Value = s.replacePlaceholders(scriptToRun.Actions[i].Value, params)
-			scriptToRun.Actions[i].URL = s.replacePlaceholders(scriptToRun.Actions[i].URL, params)
-			scriptToRun.Actions[i].JSCode = s.replacePlaceholders(scriptToRun.Actions[i].JSCode, params)
-
-			for j := range scriptToRun.Actions[i].FilePaths {
-				scriptToRun.Actions[i].FilePaths[j] = s.replacePlaceholders(scriptToRun.Actions[i].FilePaths[j], params)
+		// 检查浏览器是否运行
+		if !s.browserMgr.IsRunning() {
+			logger.Info(ctx, "Browser not running, starting...")
+			if err := s.browserMgr.Start(ctx); err != nil {
+				return mcpgo.NewToolResultError(fmt.Sprintf("Failed to start browser: %v", err)), nil
 			}
+			logger.Info(ctx, "Browser started successfully")
 		}
 
 		// 执行脚本（使用当前实例，传空字符串）
@@ -321,25 +333,6 @@ func getKeysFromMap(m map[string]interface{}) []string {
 	return keys
 }
 
-// replacePlaceholders 替换字符串中的占位符
-func (s *MCPServer) replacePlaceholders(text string, params map[string]string) string {
-	if text == "" {
-		return text
-	}
-
-	result := text
-	for key, value := range params {
-		placeholder := fmt.Sprintf("${%s}", key)
-		result = strings.ReplaceAll(result, placeholder, value)
-	}
-
-	// 清理未替换的占位符
-	re := regexp.MustCompile(`\$\{([^}]+)\}`)
-	result = re.ReplaceAllString(result, "")
-
-	return result
-}
-
 // RegisterScript 注册脚本为 MCP 命令
 func (s *MCPServer) RegisterScript(script *models.Script) error {
 	if !script.IsMCPCommand || script.MCPCommandName == "" {
@@ -417,49 +410,26 @@ func (s *MCPServer) CallTool(ctx context.Context, name string, arguments map[str
 		return nil, fmt.Errorf("command not found: %s", name)
 	}
 
-	logger.Info(ctx, "CallTool: Executing MCP command: %s (script: %s), arguments %+v", name, script.Name, arguments)
-
-	// 检查浏览器是否运行
-	if !s.browserMgr.IsRunning() {
-		logger.Info(ctx, "Browser not running, starting...")
-		if err := s.browserMgr.Start(ctx); err != nil {
-			return nil, fmt.Errorf("failed to start browser: %w", err)
-		}
-	}
-
-	// 创建脚本副本并替换占位符
-	scriptToRun := script.Copy()
 	params := make(map[string]string)
-	for key, value := range scriptToRun.Variables {
-		params[key] = value
-	}
-
 	for key, value := range arguments {
-		params[key] = fmt.Sprintf("%v", value)
-	}
-
-	for key := range scriptToRun.Variables {
-		if _, ok := params[key]; ok {
-			scriptToRun.Variables[key] = params[key]
+		if value == nil {
+			continue // null 视为未传，使用默认值
 		}
+		params[key] = argumentString(value)
 	}
+	logger.Info(ctx, "CallTool: Executing MCP command: %s (script: %s), arguments %+v", name, script.Name, script.MaskParams(params))
 
-	// 替换占位符
-	if urlParam, ok := params["url"]; ok && urlParam != "" {
-		scriptToRun.URL = urlParam
-	} else {
-		scriptToRun.URL = s.replacePlaceholders(scriptToRun.URL, params)
+	// 校验参数并替换占位符（在启动浏览器之前拒绝无效参数）
+	scriptToRun, err := script.WithParams(params)
+	if err != nil {
+		return nil, err
 	}
 
-	for i := range scriptToRun.Actions {
-		scriptToRun.Actions[i].Selector = s.replacePlaceholders(scriptToRun.Actions[i].Selector, params)
-		scriptToRun.Actions[i].XPath = s.replacePlaceholders(scriptToRun.Actions[i].XPath, params)
-		scriptToRun.Actions[i].Value = s.replacePlaceholders(scriptToRun.Actions[i].Value, params)
-		scriptToRun.Actions[i].URL = s.replacePlaceholders(scriptToRun.Actions[i].URL, params)
-		scriptToRun.Actions[i].JSCode = s.replacePlaceholders(scriptToRun.Actions[i].JSCode, params)
-
-		for j := range scriptToRun.Actions[i].FilePaths {
-			scriptToRun.Actions[i].FilePaths[j] = s.replacePlaceholders(scriptToRun.Actions[i].FilePaths[j], params)
+	// 检查浏览器是否运行
+	if !s.browserMgr.IsRunning() {
+		logger.Info(ctx, "Browser not running, starting...")
+		if err := s.browserMgr.Start(ctx); err != nil {
+			return nil, fmt.Errorf("failed to start browser: %w", err)
 		}
 	}
 
@@ -513,10 +483,34 @@ func (s *MCPServer) GetExecutor() *executor.Executor {
 	return s.executor
 }
 
+// GetSessionManager 返回执行器会话管理器（与 HTTP API 共享）
+func (s *MCPServer) GetSessionManager() *executor.SessionManager {
+	return s.toolRegistry.Sessions()
+}
+
 // callExecutorTool 调用 Executor 工具
 func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments map[string]interface{}) (interface{}, error) {
 	logger.Info(ctx, "CallTool: Executing Executor tool: %s, arguments %+v", name, arguments)
 
+	// 会话管理不依赖具体的执行器
+	if name == "browser_session" {
+		result, err := s.toolRegistry.SessionFromArgs(ctx, arguments)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"success": result.Success,
+			"message": result.Message,
+			"data":    result.Data,
+		}, nil
+	}
+
+	// 按 session_id 选择执行器会话，未指定时使用共享执行器
+	exec, err := s.toolRegistry.ExecutorForArgs(arguments)
+	if err != nil {
+		return nil, err
+	}
+
 	// 确保浏览器已启动
 	if !s.browserMgr.IsRunning() {
 		logger.Info(ctx, "Browser not running, starting...")
@@ -538,7 +532,7 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 			opts.WaitUntil = waitUntil
 		}
 
-		result, err := s.executor.Navigate(ctx, url, opts)
+		result, err := exec.Navigate(ctx, url, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -556,12 +550,15 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 		identifier, _ := arguments["identifier"].(string)
 		waitVisible, _ := arguments["wait_visible"].(bool)
 
+		snapshotDiff, _ := arguments["snapshot_diff"].(bool)
+
 		opts := &executor.ClickOptions{
-			WaitVisible: waitVisible,
-			Timeout:     30 * time.Second, // 设置默认超时为 30 秒
+			WaitVisible:  waitVisible,
+			Timeout:      30 * time.Second, // 设置默认超时为 30 秒
+			SnapshotDiff: snapshotDiff,
 		}
 
-		result, err := s.executor.Click(ctx, identifier, opts)
+		result, err := exec.Click(ctx, identifier, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -583,12 +580,15 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 			clear = clearArg
 		}
 
+		snapshotDiff, _ := arguments["snapshot_diff"].(bool)
+
 		opts := &executor.TypeOptions{
-			Clear:   clear,
-			Timeout: 30 * time.Second, // 设置默认超时为 30 秒
+			Clear:        clear,
+			Timeout:      30 * time.Second, // 设置默认超时为 30 秒
+			SnapshotDiff: snapshotDiff,
 		}
 
-		result, err := s.executor.Type(ctx, identifier, text, opts)
+		result, err := exec.Type(ctx, identifier, text, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -606,11 +606,14 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 		identifier, _ := arguments["identifier"].(string)
 		value, _ := arguments["value"].(string)
 
+		snapshotDiff, _ := arguments["snapshot_diff"].(bool)
+
 		opts := &executor.SelectOptions{
-			Timeout: 30 * time.Second, // 设置默认超时为 30 秒
+			Timeout:      30 * time.Second, // 设置默认超时为 30 秒
+			SnapshotDiff: snapshotDiff,
 		}
 
-		result, err := s.executor.Select(ctx, identifier, value, opts)
+		result, err := exec.Select(ctx, identifier, value, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -631,13 +634,44 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 			format = "png"
 		}
 
+		annotate, _ := arguments["annotate"].(bool)
+
 		opts := &executor.ScreenshotOptions{
 			FullPage: fullPage,
 			Format:   format,
 			Quality:  80,
+			Annotate: annotate,
+		}
+
+		result, err := exec.Screenshot(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		response := map[string]interface{}{
+			"success": result.Success,
+			"message": result.Message,
+		}
+		if len(result.Data) > 0 {
+			response["data"] = result.Data
+		}
+		return response, nil
+
+	case "browser_pdf":
+		result, err := exec.PDFFromArgs(ctx, arguments)
+		if err != nil {
+			return nil, err
+		}
+		response := map[string]interface{}{
+			"success": result.Success,
+			"message": result.Message,
 		}
+		if len(result.Data) > 0 {
+			response["data"] = result.Data
+		}
+		return response, nil
 
-		result, err := s.executor.Screenshot(ctx, opts)
+	case "browser_archive":
+		result, err := exec.Archive(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -664,7 +698,7 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 			Multiple: multiple,
 		}
 
-		result, err := s.executor.Extract(ctx, opts)
+		result, err := exec.Extract(ctx, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -683,7 +717,29 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 			simple = simpleArg
 		}
 
-		snapshot, err := s.executor.GetAccessibilitySnapshot(ctx)
+		if diff, _ := arguments["diff"].(bool); diff {
+			snapshotDiff, _, err := exec.GetAccessibilitySnapshotDiff(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			response := map[string]interface{}{
+				"success": true,
+				"message": "Successfully retrieved accessibility snapshot diff",
+			}
+			if simple {
+				response["data"] = map[string]interface{}{
+					"snapshot_diff": snapshotDiff.SerializeToText(),
+				}
+			} else {
+				response["data"] = map[string]interface{}{
+					"snapshot_diff": snapshotDiff,
+				}
+			}
+			return response, nil
+		}
+
+		snapshot, err := exec.GetAccessibilitySnapshot(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -694,8 +750,12 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 		}
 
 		if simple {
+			text, err := exec.SerializeSnapshot(ctx, snapshot, executor.SnapshotSerializeOptionsFromArgs(arguments))
+			if err != nil {
+				return nil, err
+			}
 			response["data"] = map[string]interface{}{
-				"accessibility_snapshot": snapshot.SerializeToSimpleText(),
+				"accessibility_snapshot": text,
 			}
 		} else {
 			response["data"] = map[string]interface{}{
@@ -712,7 +772,7 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 			simple = simpleArg
 		}
 
-		snapshot, err := s.executor.GetAccessibilitySnapshot(ctx)
+		snapshot, err := exec.GetAccessibilitySnapshot(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -735,7 +795,7 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 		return response, nil
 
 	case "browser_get_page_info":
-		result, err := s.executor.GetPageInfo(ctx)
+		result, err := exec.GetPageInfo(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -764,7 +824,7 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 			opts.Timeout = time.Duration(timeout) * time.Second
 		}
 
-		result, err := s.executor.WaitFor(ctx, identifier, opts)
+		result, err := exec.WaitFor(ctx, identifier, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -778,10 +838,25 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 		}
 		return response, nil
 
+	case "browser_assert":
+		result, err := exec.AssertFromArgs(ctx, arguments)
+		if result == nil {
+			return nil, err
+		}
+		// 断言未通过时同样返回结果，由 success 和 message 说明失败原因
+		response := map[string]interface{}{
+			"success": result.Success,
+			"message": result.Message,
+		}
+		if len(result.Data) > 0 {
+			response["data"] = result.Data
+		}
+		return response, nil
+
 	case "browser_scroll":
 		direction, _ := arguments["direction"].(string)
 		if direction == "" || direction == "bottom" {
-			result, err := s.executor.ScrollToBottom(ctx)
+			result, err := exec.ScrollToBottom(ctx)
 			if err != nil {
 				return nil, err
 			}
@@ -792,7 +867,7 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 		}
 
 		// 滚动到顶部或元素
-		page := s.executor.GetRodPage()
+		page := exec.GetRodPage()
 		if page != nil {
 			if direction == "top" {
 				_, err := page.Eval(`() => window.scrollTo(0, 0)`)
@@ -814,7 +889,7 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 	case "browser_evaluate":
 		script, _ := arguments["script"].(string)
 
-		result, err := s.executor.Evaluate(ctx, script)
+		result, err := exec.Evaluate(ctx, script)
 		if err != nil {
 			return nil, err
 		}
@@ -841,7 +916,7 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 			Meta:  meta,
 		}
 
-		result, err := s.executor.PressKey(ctx, key, opts)
+		result, err := exec.PressKey(ctx, key, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -870,7 +945,7 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 			return nil, fmt.Errorf("invalid width or height")
 		}
 
-		result, err := s.executor.Resize(ctx, width, height)
+		result, err := exec.Resize(ctx, width, height)
 		if err != nil {
 			return nil, err
 		}
@@ -888,7 +963,7 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 		fromIdentifier, _ := arguments["from_identifier"].(string)
 		toIdentifier, _ := arguments["to_identifier"].(string)
 
-		result, err := s.executor.Drag(ctx, fromIdentifier, toIdentifier)
+		result, err := exec.Drag(ctx, fromIdentifier, toIdentifier)
 		if err != nil {
 			return nil, err
 		}
@@ -903,7 +978,7 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 		return response, nil
 
 	case "browser_close":
-		result, err := s.executor.ClosePage(ctx)
+		result, err := exec.ClosePage(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -933,7 +1008,7 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 			return nil, fmt.Errorf("no file paths provided")
 		}
 
-		result, err := s.executor.FileUpload(ctx, identifier, filePaths)
+		result, err := exec.FileUpload(ctx, identifier, filePaths)
 		if err != nil {
 			return nil, err
 		}
@@ -958,7 +1033,7 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 			text = t
 		}
 
-		result, err := s.executor.HandleDialog(ctx, accept, text)
+		result, err := exec.HandleDialog(ctx, accept, text)
 		if err != nil {
 			return nil, err
 		}
@@ -973,7 +1048,11 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 		return response, nil
 
 	case "browser_console_messages":
-		result, err := s.executor.GetConsoleMessages(ctx)
+		opts, err := executor.ConsoleOptionsFromArgs(arguments)
+		if err != nil {
+			return nil, err
+		}
+		result, err := exec.GetConsoleMessages(ctx, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -987,7 +1066,72 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 		return response, nil
 
 	case "browser_network_requests":
-		result, err := s.executor.GetNetworkRequests(ctx)
+		if requestID, ok := arguments["request_id"].(string); ok && requestID != "" {
+			result, err := exec.GetNetworkResponseBody(ctx, requestID)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"success": result.Success,
+				"message": result.Message,
+				"data":    result.Data,
+			}, nil
+		}
+		opts := executor.NetworkOptionsFromArgs(arguments)
+		if format, _ := arguments["format"].(string); strings.EqualFold(format, "har") {
+			har, err := exec.ExportHAR(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"success": true,
+				"message": fmt.Sprintf("Exported %d network entries as HAR", len(har.Log.Entries)),
+				"data":    har,
+			}, nil
+		}
+		result, err := exec.GetNetworkRequests(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		response := map[string]interface{}{
+			"success": result.Success,
+			"message": result.Message,
+		}
+		if len(result.Data) > 0 {
+			response["data"] = result.Data
+		}
+		return response, nil
+
+	case "browser_route":
+		result, err := exec.RouteFromArgs(ctx, arguments)
+		if err != nil {
+			return nil, err
+		}
+		response := map[string]interface{}{
+			"success": result.Success,
+			"message": result.Message,
+		}
+		if len(result.Data) > 0 {
+			response["data"] = result.Data
+		}
+		return response, nil
+
+	case "browser_emulate":
+		result, err := exec.EmulateFromArgs(ctx, arguments)
+		if err != nil {
+			return nil, err
+		}
+		response := map[string]interface{}{
+			"success": result.Success,
+			"message": result.Message,
+		}
+		if len(result.Data) > 0 {
+			response["data"] = result.Data
+		}
+		return response, nil
+
+	case "browser_storage_state":
+		result, err := exec.StorageStateFromArgs(ctx, arguments)
 		if err != nil {
 			return nil, err
 		}
@@ -1017,7 +1161,7 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 			opts.Index = int(indexFloat)
 		}
 
-		result, err := s.executor.Tabs(ctx, opts)
+		result, err := exec.Tabs(ctx, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -1069,7 +1213,7 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 			opts.Timeout = time.Duration(timeoutFloat) * time.Second
 		}
 
-		result, err := s.executor.FillForm(ctx, opts)
+		result, err := exec.FillForm(ctx, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -1086,3 +1230,23 @@ func (s *MCPServer) callExecutorTool(ctx context.Context, name string, arguments
 		return nil, fmt.Errorf("unknown executor tool: %s", name)
 	}
 }
+
+// argumentString 将 MCP 工具参数转换为脚本变量文本
+// JSON 数字解码为 float64，%v 会把 1000000 输出为 1e+06，这里按十进制原样输出；其他非字符串值输出为 JSON
+func argumentString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
@@ -0,0 +1,52 @@
+package models
+
+// ActionTypeAssert 断言操作：检查页面状态或变量，失败时按断言模式处理
+const ActionTypeAssert = "assert"
+
+// 断言类型
+const (
+	AssertVisible      = "visible"       // 元素存在且可见
+	AssertHidden       = "hidden"        // 元素不存在或不可见
+	AssertTextEquals   = "text_equals"   // 元素文本（去除首尾空白）等于期望值
+	AssertTextContains = "text_contains" // 元素文本包含期望值
+	AssertTextMatches  = "text_matches"  // 元素文本匹配正则表达式
+	AssertAttribute    = "attribute"     // 元素属性值与期望值比较
+	AssertURLMatches   = "url_matches"   // 页面 URL 匹配正则表达式
+	AssertTitleMatches = "title_matches" // 页面标题匹配正则表达式
+	AssertCount        = "count"         // 匹配的元素数量与期望值比较
+	AssertVariable     = "variable"      // 变量（预设或抓取得到）与期望值比较
+)
+
+// 断言失败模式
+const (
+	AssertModeHard = "hard" // 断言失败后终止回放（默认）
+	AssertModeSoft = "soft" // 记录失败并继续执行，回放结束后整体判定为失败
+)
+
+// AssertOptions 断言配置
+// 元素类断言在脚本中使用步骤的 Selector / XPath 定位，在执行器中使用 identifier 定位
+type AssertOptions struct {
+	Kind      string `json:"kind"`                // 断言类型：visible, hidden, text_equals, text_contains, text_matches, attribute, url_matches, title_matches, count, variable
+	Expected  string `json:"expected,omitempty"`  // 期望值（文本、正则、数量或比较值），可以使用 ${变量}
+	Attribute string `json:"attribute,omitempty"` // 属性名（用于 attribute）
+	Variable  string `json:"variable,omitempty"`  // 变量名（用于 variable）
+	Operator  string `json:"operator,omitempty"`  // 比较操作符（用于 attribute、count、variable，默认 =）：=, !=, >, <, >=, <=, contains, not_contains, matches, in, not_in, exists, not_exists
+	Mode      string `json:"mode,omitempty"`      // 失败模式：hard（默认）, soft
+	Message   string `json:"message,omitempty"`   // 自定义失败信息
+	WaitMs    int    `json:"wait_ms,omitempty"`   // 等待断言成立的最长时间（毫秒），0 表示只检查一次
+}
+
+// IsSoft 断言是否为软断言
+func (o *AssertOptions) IsSoft() bool {
+	return o != nil && o.Mode == AssertModeSoft
+}
+
+// AssertResult 断言结果
+type AssertResult struct {
+	Passed   bool   `json:"passed"`             // 是否通过
+	Kind     string `json:"kind"`               // 断言类型
+	Mode     string `json:"mode"`               // 失败模式
+	Expected string `json:"expected,omitempty"` // 期望值（已替换变量）
+	Actual   string `json:"actual"`             // 实际值
+	Message  string `json:"message"`            // 结果描述（失败时为失败信息）
+}
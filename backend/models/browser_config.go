@@ -19,6 +19,9 @@ type BrowserConfig struct {
 	LaunchArgs []string `json:"launch_args"` // 启动参数，为空使用默认
 	Proxy      string   `json:"proxy"`       // 代理地址，为空使用默认
 
+	// 页面仿真配置，匹配的页面在实例仿真配置的基础上覆盖设置的字段
+	Emulation *EmulationProfile `json:"emulation,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -27,6 +27,19 @@ type BrowserInstance struct {
 	LaunchArgs []string `json:"launch_args,omitempty"` // 启动参数
 	Proxy      string   `json:"proxy,omitempty"`       // 代理地址
 
+	// 页面仿真配置（设备、语言、时区、地理位置等），实例中新打开的页面都会应用
+	Emulation *EmulationProfile `json:"emulation,omitempty"`
+
+	// 脚本回放并发配置
+	MaxConcurrentPlaybacks int    `json:"max_concurrent_playbacks,omitempty"` // 同时执行的回放数量上限（默认 4），超出的回放排队等待
+	PlaybackIsolation      string `json:"playback_isolation,omitempty"`       // page（默认，独立标签页）或 incognito（独立的无痕上下文，Cookie 等互不影响）
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// 回放隔离方式
+const (
+	PlaybackIsolationPage      = "page"      // 每次回放使用新的标签页，共享 Cookie 等浏览器状态
+	PlaybackIsolationIncognito = "incognito" // 每次回放使用独立的无痕浏览器上下文
+)
@@ -0,0 +1,46 @@
+package models
+
+// EmulationProfile 页面仿真配置（设备、语言、时区、地理位置、媒体特性、网络条件）
+// 所有字段均为可选，未设置的字段保持浏览器默认行为
+type EmulationProfile struct {
+	// 设备和视口
+	Device            string  `json:"device,omitempty"`              // 预设设备名称（如 "iPhone X"、"Pixel 2"），其余字段覆盖预设值
+	Landscape         bool    `json:"landscape,omitempty"`           // 预设设备使用横屏
+	Width             int     `json:"width,omitempty"`               // 视口宽度（CSS 像素）
+	Height            int     `json:"height,omitempty"`              // 视口高度（CSS 像素）
+	DeviceScaleFactor float64 `json:"device_scale_factor,omitempty"` // 设备像素比（DPR）
+	Mobile            *bool   `json:"mobile,omitempty"`              // 是否模拟移动端（影响 meta viewport 和滚动条等）
+	Touch             *bool   `json:"touch,omitempty"`               // 是否启用触摸事件
+	UserAgent         string  `json:"user_agent,omitempty"`          // User Agent，为空时使用预设设备或页面当前的 User Agent
+
+	// 语言和区域
+	Locale         string `json:"locale,omitempty"`          // 区域设置（如 de-DE），影响 Intl 和 navigator.language
+	AcceptLanguage string `json:"accept_language,omitempty"` // Accept-Language 请求头，为空时由 locale 推导
+	Timezone       string `json:"timezone,omitempty"`        // IANA 时区（如 Europe/Berlin）
+
+	// 地理位置（同时授予页面地理位置权限）
+	Geolocation *Geolocation `json:"geolocation,omitempty"`
+
+	// 媒体特性
+	ColorScheme   string `json:"color_scheme,omitempty"`   // prefers-color-scheme: light, dark, no-preference
+	ReducedMotion string `json:"reduced_motion,omitempty"` // prefers-reduced-motion: reduce, no-preference
+
+	// 网络条件
+	Network      string `json:"network,omitempty"`       // online（默认）、offline 或预设：slow-3g、fast-3g、4g
+	LatencyMs    int    `json:"latency_ms,omitempty"`    // 自定义额外延迟（毫秒），覆盖预设
+	DownloadKbps int    `json:"download_kbps,omitempty"` // 自定义下行带宽（kbit/s），覆盖预设
+	UploadKbps   int    `json:"upload_kbps,omitempty"`   // 自定义上行带宽（kbit/s），覆盖预设
+}
+
+// Geolocation 地理位置
+type Geolocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Accuracy  float64 `json:"accuracy,omitempty"` // 精度（米），默认 100
+}
+
+// 网络条件
+const (
+	EmulationNetworkOnline  = "online"
+	EmulationNetworkOffline = "offline"
+)
@@ -0,0 +1,25 @@
+package models
+
+// 页面存档相关的操作类型
+const (
+	ActionTypePDF     = "pdf"     // 打印页面为 PDF
+	ActionTypeArchive = "archive" // 将页面保存为 MHTML 存档
+)
+
+// PDFOptions 打印 PDF 的选项（对应 Page.printToPDF），所有字段均为可选
+type PDFOptions struct {
+	PaperFormat       string  `json:"paper_format,omitempty"`         // 纸张规格：A3、A4（默认）、A5、Letter、Legal、Tabloid
+	PaperWidth        float64 `json:"paper_width,omitempty"`          // 自定义纸张宽度（英寸），覆盖 paper_format
+	PaperHeight       float64 `json:"paper_height,omitempty"`         // 自定义纸张高度（英寸），覆盖 paper_format
+	Landscape         bool    `json:"landscape,omitempty"`            // 横向打印
+	Scale             float64 `json:"scale,omitempty"`                // 缩放比例（0.1 - 2，默认 1）
+	MarginTop         float64 `json:"margin_top,omitempty"`           // 上边距（英寸），未设置的边距使用 Chrome 默认值
+	MarginBottom      float64 `json:"margin_bottom,omitempty"`        // 下边距（英寸）
+	MarginLeft        float64 `json:"margin_left,omitempty"`          // 左边距（英寸）
+	MarginRight       float64 `json:"margin_right,omitempty"`         // 右边距（英寸）
+	PrintBackground   bool    `json:"print_background,omitempty"`     // 打印背景颜色和图片
+	PageRanges        string  `json:"page_ranges,omitempty"`          // 页码范围，如 "1-5, 8"，为空表示全部
+	HeaderTemplate    string  `json:"header_template,omitempty"`      // 页眉 HTML 模板，可使用 date、title、url、pageNumber、totalPages 类名
+	FooterTemplate    string  `json:"footer_template,omitempty"`      // 页脚 HTML 模板，设置页眉或页脚时自动显示页眉页脚
+	PreferCSSPageSize bool    `json:"prefer_css_page_size,omitempty"` // 优先使用页面 CSS @page 定义的纸张大小
+}
@@ -0,0 +1,36 @@
+package models
+
+// 请求拦截规则动作
+const (
+	RouteActionBlock         = "block"          // 阻止请求
+	RouteActionFulfill       = "fulfill"        // 使用预设响应直接返回
+	RouteActionModifyHeaders = "modify_headers" // 修改请求头/响应头后放行
+	RouteActionDelay         = "delay"          // 延迟后放行
+)
+
+// RouteRule 请求拦截规则（基于 CDP Fetch 域）
+// 多条规则按顺序匹配，第一条命中的规则生效
+type RouteRule struct {
+	ID           string `json:"id,omitempty"`            // 规则标识（可选，便于统计命中次数）
+	URLPattern   string `json:"url_pattern"`             // URL 通配符：* 匹配任意字符，? 匹配单个字符
+	Method       string `json:"method,omitempty"`        // 限定请求方法（为空表示全部）
+	ResourceType string `json:"resource_type,omitempty"` // 限定资源类型：Document, XHR, Fetch, Script, Image ...
+	Action       string `json:"action"`                  // block, fulfill, modify_headers, delay
+
+	// block
+	ErrorReason string `json:"error_reason,omitempty"` // 网络错误原因，默认 BlockedByClient
+
+	// fulfill
+	Status      int               `json:"status,omitempty"`       // 响应状态码，默认 200
+	Headers     map[string]string `json:"headers,omitempty"`      // 响应头
+	ContentType string            `json:"content_type,omitempty"` // 响应 Content-Type
+	Body        string            `json:"body,omitempty"`         // 响应体
+	BodyBase64  bool              `json:"body_base64,omitempty"`  // Body 是否为 base64 编码
+
+	// modify_headers（值为空字符串表示删除该头）
+	RequestHeaders  map[string]string `json:"request_headers,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+
+	// 延迟（毫秒），对所有动作生效，delay 动作延迟后原样放行
+	DelayMs int `json:"delay_ms,omitempty"`
+}
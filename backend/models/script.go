@@ -20,7 +20,7 @@ type ScriptAction struct {
 	// =========================
 	// 原有字段（保持不变）
 	// =========================
-	Type      string            `json:"type"`      // click, input, select, navigate, wait, sleep, extract_text, extract_attribute, extract_html, execute_js, upload_file, scroll, keyboard, open_tab, switch_tab, switch_active_tab, ai_control
+	Type      string            `json:"type"`      // click, input, select, navigate, wait, sleep, extract_text, extract_attribute, extract_html, execute_js, upload_file, scroll, keyboard, open_tab, switch_tab, switch_active_tab, ai_control, loop_elements, loop_list, loop_while, call_script, pdf, archive, assert
 	Timestamp int64             `json:"timestamp"` // 时间戳（毫秒）
 	Selector  string            `json:"selector"`  // CSS选择器
 	XPath     string            `json:"xpath"`     // XPath选择器（更可靠）
@@ -65,6 +65,12 @@ type ScriptAction struct {
 	ScreenshotWidth  int    `json:"screenshot_width,omitempty"`  // 截图区域宽度（region模式）
 	ScreenshotHeight int    `json:"screenshot_height,omitempty"` // 截图区域高度（region模式）
 
+	// PDF 打印选项（用于 pdf 类型，为空时使用 A4 纵向默认设置）
+	PDF *PDFOptions `json:"pdf,omitempty"`
+
+	// 断言配置（用于 assert 类型）
+	Assert *AssertOptions `json:"assert,omitempty"`
+
 	// AI控制相关字段（用于 ai_control 类型）
 	AIControlPrompt      string `json:"ai_control_prompt,omitempty"`       // AI控制的提示词
 	AIControlXPath       string `json:"ai_control_xpath,omitempty"`        // 可选的元素XPath（用于提示词上下文）
@@ -72,6 +78,19 @@ type ScriptAction struct {
 
 	Condition *ActionCondition `json:"condition,omitempty"`
 
+	// 循环配置（用于 loop_elements, loop_list, loop_while 类型）
+	Loop *ActionLoop `json:"loop,omitempty"`
+
+	// 子脚本调用（用于 call_script 类型）
+	ScriptID     string            `json:"script_id,omitempty"`     // 被调用脚本的 ID
+	ScriptParams map[string]string `json:"script_params,omitempty"` // 传入子脚本的变量，值中可以使用 ${变量} 引用当前变量
+
+	// 失败处理策略
+	OnError        string `json:"on_error,omitempty"`         // continue（默认）, abort, retry
+	RetryCount     int    `json:"retry_count,omitempty"`      // retry 策略的重试次数（默认 3）
+	RetryBackoffMs int    `json:"retry_backoff_ms,omitempty"` // 首次重试前的等待时间（毫秒，默认 1000，之后每次翻倍）
+	TimeoutMs      int    `json:"timeout_ms,omitempty"`       // 单步超时（毫秒），为 0 时使用脚本的默认超时
+
 	// =========================
 	// 新增字段（v2，自愈核心）
 	// =========================
@@ -123,13 +142,83 @@ func (a *ScriptAction) CopyWithoutSemanticInfo() *ScriptAction {
 		ScreenshotMode:       a.ScreenshotMode,
 		ScreenshotWidth:      a.ScreenshotWidth,
 		ScreenshotHeight:     a.ScreenshotHeight,
+		PDF:                  a.PDF,
+		Assert:               a.Assert,
 		AIControlPrompt:      a.AIControlPrompt,
 		AIControlXPath:       a.AIControlXPath,
 		AIControlLLMConfigID: a.AIControlLLMConfigID,
 		Condition:            a.Condition,
+		Loop:                 a.Loop.CopyWithoutSemanticInfo(),
+		ScriptID:             a.ScriptID,
+		ScriptParams:         a.ScriptParams,
+		OnError:              a.OnError,
+		RetryCount:           a.RetryCount,
+		RetryBackoffMs:       a.RetryBackoffMs,
+		TimeoutMs:            a.TimeoutMs,
 	}
 }
 
+// 操作失败处理策略
+const (
+	OnErrorContinue = "continue" // 记录失败并继续执行后续步骤
+	OnErrorAbort    = "abort"    // 立即终止回放
+	OnErrorRetry    = "retry"    // 按退避策略重试，重试耗尽后终止回放
+)
+
+// StepFailure 导致回放终止的步骤信息
+type StepFailure struct {
+	Index    int    `json:"index"`               // 步骤序号（从 1 开始）
+	Type     string `json:"type"`                // 操作类型
+	Selector string `json:"selector,omitempty"`  // 操作的选择器（XPath 优先）
+	Error    string `json:"error"`               // 最后一次失败的错误信息
+	Attempts int    `json:"attempts"`            // 实际尝试次数
+	TimedOut bool   `json:"timed_out,omitempty"` // 是否因超时失败
+}
+
+// 循环操作类型
+const (
+	ActionTypeLoopElements = "loop_elements" // 遍历选择器匹配的元素
+	ActionTypeLoopList     = "loop_list"     // 遍历列表变量
+	ActionTypeLoopWhile    = "loop_while"    // 条件满足时重复执行
+)
+
+// ActionTypeCallScript 调用另一个已保存的脚本（在同一页面中执行）
+const ActionTypeCallScript = "call_script"
+
+// IsLoopAction 判断操作类型是否为循环
+func IsLoopAction(actionType string) bool {
+	return actionType == ActionTypeLoopElements || actionType == ActionTypeLoopList || actionType == ActionTypeLoopWhile
+}
+
+// ActionLoop 循环配置
+// 循环体中可以使用 ${item} 和 ${index}（从 0 开始）引用当前项，变量名可通过 ItemVariable / IndexVariable 修改
+type ActionLoop struct {
+	// loop_list：列表变量名，值可以是 JSON 数组或逗号分隔的字符串
+	ListVariable string `json:"list_variable,omitempty"`
+	// loop_while：循环条件；未设置时，只要 Selector/XPath 能匹配到元素就继续循环
+	Condition *ActionCondition `json:"condition,omitempty"`
+
+	MaxIterations int    `json:"max_iterations,omitempty"` // 最大迭代次数（默认 100）
+	ItemVariable  string `json:"item_variable,omitempty"`  // 当前项变量名（默认 item）
+	IndexVariable string `json:"index_variable,omitempty"` // 当前序号变量名（默认 index）
+	Collect       bool   `json:"collect,omitempty"`        // 是否将循环体每次抓取的数据汇总为数组
+
+	Actions []ScriptAction `json:"actions"` // 循环体
+}
+
+// CopyWithoutSemanticInfo 复制循环配置，循环体去除语义信息
+func (l *ActionLoop) CopyWithoutSemanticInfo() *ActionLoop {
+	if l == nil {
+		return nil
+	}
+	copied := *l
+	copied.Actions = make([]ScriptAction, len(l.Actions))
+	for i, action := range l.Actions {
+		copied.Actions[i] = *action.CopyWithoutSemanticInfo()
+	}
+	return &copied
+}
+
 // ActionCondition 操作执行条件
 type ActionCondition struct {
 	Variable string `json:"variable"`          // 变量名
@@ -187,6 +276,31 @@ type Script struct {
 
 	// 预设变量（可以在脚本中使用 ${变量名} 引用，也可以在外部调用时传入覆盖）
 	Variables map[string]string `json:"variables,omitempty"` // 预设变量，key 为变量名，value 为默认值
+
+	// 变量声明（类型、必填、默认值、枚举、正则、敏感标记），回放前校验传入参数并自动生成 MCPInputSchema
+	VariableDefs []VariableDefinition `json:"variable_defs,omitempty"`
+
+	// 请求拦截规则（回放时生效，可用于屏蔽统计/广告或模拟后端响应）
+	RouteRules []RouteRule `json:"route_rules,omitempty"`
+
+	// 默认失败处理策略（步骤未单独设置时生效）
+	DefaultOnError   string `json:"default_on_error,omitempty"`   // continue（默认）, abort, retry
+	DefaultTimeoutMs int    `json:"default_timeout_ms,omitempty"` // 默认单步超时（毫秒），0 表示不限制
+
+	// 步骤失败时截图并记录到执行轨迹中
+	ScreenshotOnFailure bool `json:"screenshot_on_failure,omitempty"`
+
+	// 回放中通过语义信息自愈定位成功后，将新的 XPath 写回脚本
+	SaveHealedSelectors bool `json:"save_healed_selectors,omitempty"`
+
+	// 回放前恢复的存储状态 ID（Cookie、localStorage、sessionStorage、IndexedDB）
+	StorageStateID string `json:"storage_state_id,omitempty"`
+
+	// 回放时应用的仿真配置（视口、设备、语言等），按字段覆盖实例和网站配置
+	Emulation *EmulationProfile `json:"emulation,omitempty"`
+
+	// 当前版本号（每次保存内容变化时递增，历史版本见 ScriptRevision）
+	Revision int `json:"revision,omitempty"`
 }
 
 func (s *Script) GetActionsWithoutSemanticInfo() []ScriptAction {
@@ -220,6 +334,18 @@ func (s *Script) Copy() *Script {
 		variables[k] = v
 	}
 
+	var variableDefs []VariableDefinition
+	if len(s.VariableDefs) > 0 {
+		variableDefs = make([]VariableDefinition, len(s.VariableDefs))
+		copy(variableDefs, s.VariableDefs)
+	}
+
+	var routeRules []RouteRule
+	if len(s.RouteRules) > 0 {
+		routeRules = make([]RouteRule, len(s.RouteRules))
+		copy(routeRules, s.RouteRules)
+	}
+
 	return &Script{
 		ID:                    s.ID,
 		Name:                  s.Name,
@@ -239,13 +365,22 @@ func (s *Script) Copy() *Script {
 		MCPCommandDescription: s.MCPCommandDescription,
 		MCPInputSchema:        s.MCPInputSchema,
 		Variables:             variables,
+		VariableDefs:          variableDefs,
+		RouteRules:            routeRules,
+		DefaultOnError:        s.DefaultOnError,
+		DefaultTimeoutMs:      s.DefaultTimeoutMs,
+		ScreenshotOnFailure:   s.ScreenshotOnFailure,
+		SaveHealedSelectors:   s.SaveHealedSelectors,
+		StorageStateID:        s.StorageStateID,
+		Revision:              s.Revision,
 	}
 }
 
 // PlayResult 脚本回放结果
 type PlayResult struct {
-	Success       bool                   `json:"success"`        // 是否成功
-	Message       string                 `json:"message"`        // 结果消息
-	ExtractedData map[string]interface{} `json:"extracted_data"` // 抓取到的数据，key 为变量名或 action 索引
-	Errors        []string               `json:"errors"`         // 错误信息列表
+	Success       bool                   `json:"success"`                // 是否成功
+	Message       string                 `json:"message"`                // 结果消息
+	ExtractedData map[string]interface{} `json:"extracted_data"`         // 抓取到的数据，key 为变量名或 action 索引
+	Errors        []string               `json:"errors"`                 // 错误信息列表
+	AbortedStep   *StepFailure           `json:"aborted_step,omitempty"` // 导致回放终止的步骤（按失败策略终止时）
 }
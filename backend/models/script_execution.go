@@ -9,6 +9,7 @@ type ScriptExecution struct {
 	ID          string    `json:"id"`           // 执行记录 ID
 	ScriptID    string    `json:"script_id"`    // 关联的脚本 ID
 	ScriptName  string    `json:"script_name"`  // 脚本名称（冗余，方便查询）
+	ScriptRevision int    `json:"script_revision,omitempty"` // 执行时的脚本版本号
 	InstanceID  string    `json:"instance_id"`  // 浏览器实例 ID
 	InstanceName string   `json:"instance_name,omitempty"` // 浏览器实例名称（冗余，方便查询）
 	StartTime   time.Time `json:"start_time"`   // 开始时间
@@ -22,6 +23,12 @@ type ScriptExecution struct {
 	TotalSteps   int `json:"total_steps"`   // 总步骤数
 	SuccessSteps int `json:"success_steps"` // 成功步骤数
 	FailedSteps  int `json:"failed_steps"`  // 失败步骤数
+
+	// 按失败策略终止回放时，记录导致终止的步骤
+	AbortedStep *StepFailure `json:"aborted_step,omitempty"`
+
+	// 步骤级执行轨迹
+	Steps []StepTrace `json:"steps,omitempty"`
 	
 	// 抓取数据
 	ExtractedData map[string]interface{} `json:"extracted_data,omitempty"` // 抓取到的数据
@@ -31,3 +38,30 @@ type ScriptExecution struct {
 	
 	CreatedAt time.Time `json:"created_at"` // 记录创建时间
 }
+
+// 步骤执行状态
+const (
+	StepStatusSuccess = "success"
+	StepStatusFailed  = "failed"
+	StepStatusSkipped = "skipped" // 条件不满足而跳过
+)
+
+// StepTrace 单个步骤的执行轨迹
+type StepTrace struct {
+	Index           int       `json:"index"`                      // 步骤序号（从 1 开始）
+	Type            string    `json:"type"`                       // 操作类型
+	Remark          string    `json:"remark,omitempty"`           // 操作备注
+	Status          string    `json:"status"`                     // success, failed, skipped
+	MatchedBy       string    `json:"matched_by,omitempty"`       // 实际命中的定位方式：xpath, css, css_fallback, iframe_xpath, iframe_css, semantic
+	MatchedLocator  string    `json:"matched_locator,omitempty"`  // 实际命中的定位表达式
+	MatchConfidence float64   `json:"match_confidence,omitempty"` // 语义自愈的匹配置信度（0-1）
+	Attempts        int       `json:"attempts,omitempty"`         // 尝试次数（含重试）
+	Iterations      int       `json:"iterations,omitempty"`       // 循环步骤实际执行的迭代次数
+	StartTime       time.Time `json:"start_time"`                 // 开始时间
+	EndTime         time.Time `json:"end_time"`                   // 结束时间
+	Duration        int64     `json:"duration"`                   // 耗时（毫秒）
+	Error           string    `json:"error,omitempty"`            // 错误信息
+	URLBefore       string    `json:"url_before,omitempty"`       // 执行前的页面 URL
+	URLAfter        string    `json:"url_after,omitempty"`        // 执行后的页面 URL
+	ScreenshotPath  string    `json:"screenshot_path,omitempty"`  // 失败截图路径（脚本开启 screenshot_on_failure 时）
+}
@@ -0,0 +1,305 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// 脚本版本来源
+const (
+	RevisionSourceAPI      = "api"       // 编辑器或 REST 接口保存
+	RevisionSourceBatch    = "batch"     // 批量设置分组、标签
+	RevisionSourceMCP      = "mcp"       // 设置 MCP 命令
+	RevisionSourceSelfHeal = "self_heal" // 回放中语义自愈写回新的 XPath
+	RevisionSourceRestore  = "restore"   // 回滚到历史版本
+	RevisionSourceExplore  = "explore"   // AI 探索生成
+	RevisionSourceImport   = "import"    // 从 Chrome Recorder、Selenium IDE 导入
+	RevisionSourceBaseline = "baseline"  // 启用版本历史前已保存的内容，首次修改时补记为版本 1
+)
+
+// ScriptRevision 脚本的一个不可变版本，每次保存脚本内容发生变化时创建
+type ScriptRevision struct {
+	ScriptID    string    `json:"script_id"`
+	Revision    int       `json:"revision"`         // 版本号，从 1 开始递增
+	Author      string    `json:"author,omitempty"` // 修改人（用户名，或 self-heal 等系统来源）
+	Note        string    `json:"note,omitempty"`   // 修改说明
+	Source      string    `json:"source,omitempty"` // 版本来源，见 RevisionSource*
+	ActionCount int       `json:"action_count"`     // 该版本的步骤数
+	Script      *Script   `json:"script,omitempty"` // 该版本的完整脚本内容（列表接口中省略）
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// RevisionInfo 保存脚本时附带的版本信息
+type RevisionInfo struct {
+	Author string
+	Note   string
+	Source string
+}
+
+// scriptVolatileFields 比较脚本内容时忽略的字段（每次保存都会变化）
+var scriptVolatileFields = []string{"created_at", "updated_at", "revision"}
+
+// SameScriptContent 判断两个脚本除时间戳和版本号外的内容是否相同（相同则不创建新版本）
+func SameScriptContent(a, b *Script) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	fieldsA, errA := scriptFields(a)
+	fieldsB, errB := scriptFields(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return reflect.DeepEqual(fieldsA, fieldsB)
+}
+
+// 脚本差异中步骤的变化类型
+const (
+	ActionChangeAdded     = "added"
+	ActionChangeRemoved   = "removed"
+	ActionChangeModified  = "modified"
+	ActionChangeUnchanged = "unchanged"
+)
+
+// ScriptDiff 两个脚本版本之间的差异
+type ScriptDiff struct {
+	FromRevision int                 `json:"from_revision"`
+	ToRevision   int                 `json:"to_revision"`
+	Fields       []ScriptFieldChange `json:"fields"`  // 步骤以外的脚本字段变化
+	Actions      []ActionChange      `json:"actions"` // 逐个步骤的对比结果（按新版本顺序，删除的步骤插在原位置）
+	Added        int                 `json:"added"`
+	Removed      int                 `json:"removed"`
+	Modified     int                 `json:"modified"`
+	Unchanged    int                 `json:"unchanged"`
+}
+
+// ScriptFieldChange 脚本字段的变化
+type ScriptFieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// ActionChange 单个步骤的变化
+type ActionChange struct {
+	Change    string        `json:"change"`               // added, removed, modified, unchanged
+	Type      string        `json:"type"`                 // 操作类型（修改时为新版本的类型）
+	FromIndex int           `json:"from_index,omitempty"` // 在旧版本中的序号（从 1 开始），新增的步骤为 0
+	ToIndex   int           `json:"to_index,omitempty"`   // 在新版本中的序号（从 1 开始），删除的步骤为 0
+	Fields    []string      `json:"fields,omitempty"`     // 修改的字段（JSON 字段名）
+	Before    *ScriptAction `json:"before,omitempty"`
+	After     *ScriptAction `json:"after,omitempty"`
+}
+
+// DiffScripts 逐个步骤比较两个脚本版本
+// 步骤按最长公共子序列对齐；两个对齐点之间被删除和新增的步骤按顺序配对，类型相同的记为修改
+func DiffScripts(from, to *Script) *ScriptDiff {
+	diff := &ScriptDiff{
+		FromRevision: from.Revision,
+		ToRevision:   to.Revision,
+		Fields:       []ScriptFieldChange{},
+		Actions:      []ActionChange{},
+	}
+
+	fromFields, _ := scriptFields(from)
+	toFields, _ := scriptFields(to)
+	delete(fromFields, "actions")
+	delete(toFields, "actions")
+	for _, name := range unionKeys(fromFields, toFields) {
+		if !reflect.DeepEqual(fromFields[name], toFields[name]) {
+			diff.Fields = append(diff.Fields, ScriptFieldChange{Field: name, Before: fromFields[name], After: toFields[name]})
+		}
+	}
+
+	before := actionFieldsList(from.Actions)
+	after := actionFieldsList(to.Actions)
+	var removed, added []int
+	flush := func() {
+		paired := 0
+		for ; paired < len(removed) && paired < len(added); paired++ {
+			i, j := removed[paired], added[paired]
+			if from.Actions[i].Type != to.Actions[j].Type {
+				break
+			}
+			diff.addAction(ActionChange{
+				Change:    ActionChangeModified,
+				Type:      to.Actions[j].Type,
+				FromIndex: i + 1,
+				ToIndex:   j + 1,
+				Fields:    changedKeys(before[i], after[j]),
+				Before:    &from.Actions[i],
+				After:     &to.Actions[j],
+			})
+		}
+		for _, i := range removed[paired:] {
+			diff.addAction(ActionChange{Change: ActionChangeRemoved, Type: from.Actions[i].Type, FromIndex: i + 1, Before: &from.Actions[i]})
+		}
+		for _, j := range added[paired:] {
+			diff.addAction(ActionChange{Change: ActionChangeAdded, Type: to.Actions[j].Type, ToIndex: j + 1, After: &to.Actions[j]})
+		}
+		removed, added = nil, nil
+	}
+
+	for _, step := range alignActions(before, after) {
+		switch {
+		case step.from >= 0 && step.to >= 0:
+			flush()
+			diff.addAction(ActionChange{Change: ActionChangeUnchanged, Type: to.Actions[step.to].Type, FromIndex: step.from + 1, ToIndex: step.to + 1})
+		case step.from >= 0:
+			removed = append(removed, step.from)
+		default:
+			added = append(added, step.to)
+		}
+	}
+	flush()
+	return diff
+}
+
+func (d *ScriptDiff) addAction(change ActionChange) {
+	d.Actions = append(d.Actions, change)
+	switch change.Change {
+	case ActionChangeAdded:
+		d.Added++
+	case ActionChangeRemoved:
+		d.Removed++
+	case ActionChangeModified:
+		d.Modified++
+	default:
+		d.Unchanged++
+	}
+}
+
+// alignStep 对齐结果中的一步，from / to 为 -1 表示该侧没有对应步骤
+type alignStep struct {
+	from, to int
+}
+
+// alignActions 按最长公共子序列对齐两个版本的步骤
+func alignActions(before, after []map[string]interface{}) []alignStep {
+	n, m := len(before), len(after)
+	equal := func(i, j int) bool {
+		return reflect.DeepEqual(before[i], after[j])
+	}
+
+	// lcs[i][j] 为 before[i:] 与 after[j:] 的最长公共子序列长度
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if equal(i, j) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var steps []alignStep
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case equal(i, j):
+			steps = append(steps, alignStep{i, j})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			steps = append(steps, alignStep{i, -1})
+			i++
+		default:
+			steps = append(steps, alignStep{-1, j})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		steps = append(steps, alignStep{i, -1})
+	}
+	for ; j < m; j++ {
+		steps = append(steps, alignStep{-1, j})
+	}
+	return steps
+}
+
+// scriptFields 脚本的 JSON 字段（去除每次保存都会变化的字段）
+func scriptFields(script *Script) (map[string]interface{}, error) {
+	fields, err := jsonFields(script)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range scriptVolatileFields {
+		delete(fields, name)
+	}
+	return fields, nil
+}
+
+// actionFieldsList 步骤的 JSON 字段（回放时填充的 extracted_data 不参与比较）
+func actionFieldsList(actions []ScriptAction) []map[string]interface{} {
+	list := make([]map[string]interface{}, len(actions))
+	for i := range actions {
+		fields, err := jsonFields(&actions[i])
+		if err != nil {
+			fields = map[string]interface{}{}
+		}
+		delete(fields, "extracted_data")
+		list[i] = fields
+	}
+	return list
+}
+
+// jsonFields 将结构体转为 JSON 字段表（省略空值）
+func jsonFields(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	// null 与空数组、空对象视为相同（Copy 等操作可能把 nil 变成空切片）
+	for key, value := range fields {
+		switch v := value.(type) {
+		case nil:
+			delete(fields, key)
+		case []interface{}:
+			if len(v) == 0 {
+				delete(fields, key)
+			}
+		case map[string]interface{}:
+			if len(v) == 0 {
+				delete(fields, key)
+			}
+		}
+	}
+	return fields, nil
+}
+
+// changedKeys 两组字段中值不同的字段名（排序）
+func changedKeys(a, b map[string]interface{}) []string {
+	var keys []string
+	for _, key := range unionKeys(a, b) {
+		if !reflect.DeepEqual(a[key], b[key]) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var keys []string
+	for _, fields := range []map[string]interface{}{a, b} {
+		for key := range fields {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSameScriptContent(t *testing.T) {
+	base := &Script{
+		ID:      "s1",
+		Name:    "login",
+		Actions: []ScriptAction{{Type: "navigate", URL: "https://example.com"}},
+	}
+
+	touched := base.Copy()
+	touched.UpdatedAt = time.Now()
+	touched.Revision = 7
+	if !SameScriptContent(base, touched) {
+		t.Error("timestamps and revision should not count as content changes")
+	}
+
+	renamed := base.Copy()
+	renamed.Name = "sign in"
+	if SameScriptContent(base, renamed) {
+		t.Error("renamed script reported as unchanged")
+	}
+}
+
+func TestDiffScripts(t *testing.T) {
+	open := ScriptAction{Type: "navigate", URL: "https://example.com"}
+	user := ScriptAction{Type: "input", XPath: "//input[@name='user']", Value: "alice"}
+	submit := ScriptAction{Type: "click", XPath: "//button"}
+	wait := ScriptAction{Type: "sleep", Duration: 500}
+
+	tests := []struct {
+		name    string
+		from    []ScriptAction
+		to      []ScriptAction
+		changes []string
+		fields  [][]string
+	}{
+		{
+			name:    "unchanged",
+			from:    []ScriptAction{open, submit},
+			to:      []ScriptAction{open, submit},
+			changes: []string{"unchanged", "unchanged"},
+		},
+		{
+			name:    "insert and remove",
+			from:    []ScriptAction{open, wait, submit},
+			to:      []ScriptAction{open, user, submit},
+			changes: []string{"unchanged", "removed", "added", "unchanged"},
+		},
+		{
+			name:    "modify value",
+			from:    []ScriptAction{open, user},
+			to:      []ScriptAction{open, {Type: "input", XPath: user.XPath, Value: "bob"}},
+			changes: []string{"unchanged", "modified"},
+			fields:  [][]string{nil, {"value"}},
+		},
+		{
+			name:    "extracted data ignored",
+			from:    []ScriptAction{submit},
+			to:      []ScriptAction{{Type: "click", XPath: "//button", ExtractedData: "v"}},
+			changes: []string{"unchanged"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffScripts(&Script{Revision: 1, Actions: tt.from}, &Script{Revision: 2, Actions: tt.to})
+			var changes []string
+			for i, action := range diff.Actions {
+				changes = append(changes, action.Change)
+				if tt.fields != nil && !reflect.DeepEqual(action.Fields, tt.fields[i]) {
+					t.Errorf("action %d fields = %v, want %v", i, action.Fields, tt.fields[i])
+				}
+			}
+			if !reflect.DeepEqual(changes, tt.changes) {
+				t.Errorf("changes = %v, want %v", changes, tt.changes)
+			}
+			if len(diff.Fields) != 0 {
+				t.Errorf("unexpected field changes: %+v", diff.Fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 变量类型
+const (
+	VariableTypeString  = "string"
+	VariableTypeNumber  = "number"
+	VariableTypeInteger = "integer"
+	VariableTypeBoolean = "boolean"
+)
+
+// secretMask 敏感变量在日志中的显示值
+const secretMask = "******"
+
+// VariableDefinition 脚本变量声明
+type VariableDefinition struct {
+	Name        string   `json:"name"`                  // 变量名（脚本中通过 ${变量名} 引用）
+	Type        string   `json:"type,omitempty"`        // string（默认）, number, integer, boolean
+	Description string   `json:"description,omitempty"` // 变量说明（用于 MCP 参数描述）
+	Required    bool     `json:"required,omitempty"`    // 是否必填（有默认值时视为已提供）
+	Default     string   `json:"default,omitempty"`     // 默认值
+	Enum        []string `json:"enum,omitempty"`        // 可选值列表
+	Pattern     string   `json:"pattern,omitempty"`     // 取值需要匹配的正则表达式
+	Secret      bool     `json:"secret,omitempty"`      // 敏感变量（如密码），日志中不输出明文
+}
+
+// ParamError 回放参数校验失败
+type ParamError struct {
+	Issues []string
+}
+
+func (e *ParamError) Error() string {
+	return "invalid parameters: " + strings.Join(e.Issues, "; ")
+}
+
+// normalizedType 返回变量类型，未设置时为 string
+func (d VariableDefinition) normalizedType() string {
+	t := strings.ToLower(strings.TrimSpace(d.Type))
+	if t == "" {
+		return VariableTypeString
+	}
+	return t
+}
+
+// CheckValue 按声明校验取值，返回规范化后的值（例如布尔值统一为 true/false）
+func (d VariableDefinition) CheckValue(value string) (string, error) {
+	switch d.normalizedType() {
+	case VariableTypeNumber:
+		if _, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil {
+			return "", fmt.Errorf("%s must be a number", d.Name)
+		}
+		value = strings.TrimSpace(value)
+	case VariableTypeInteger:
+		if _, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err != nil {
+			return "", fmt.Errorf("%s must be an integer", d.Name)
+		}
+		value = strings.TrimSpace(value)
+	case VariableTypeBoolean:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return "", fmt.Errorf("%s must be a boolean", d.Name)
+		}
+		value = strconv.FormatBool(b)
+	}
+
+	if len(d.Enum) > 0 {
+		allowed := false
+		for _, option := range d.Enum {
+			if option == value {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return "", fmt.Errorf("%s must be one of [%s]", d.Name, strings.Join(d.Enum, ", "))
+		}
+	}
+
+	if d.Pattern != "" {
+		re, err := regexp.Compile(d.Pattern)
+		if err != nil {
+			return "", fmt.Errorf("%s has an invalid pattern: %v", d.Name, err)
+		}
+		if !re.MatchString(value) {
+			return "", fmt.Errorf("%s does not match pattern %s", d.Name, d.Pattern)
+		}
+	}
+
+	return value, nil
+}
+
+// ValidateVariableDefs 校验变量声明本身（保存脚本时使用）
+func ValidateVariableDefs(defs []VariableDefinition) error {
+	seen := make(map[string]bool, len(defs))
+	for i, def := range defs {
+		name := strings.TrimSpace(def.Name)
+		if name == "" {
+			return fmt.Errorf("variable %d: name is required", i+1)
+		}
+		if seen[name] {
+			return fmt.Errorf("variable %s is declared more than once", name)
+		}
+		seen[name] = true
+
+		switch def.normalizedType() {
+		case VariableTypeString, VariableTypeNumber, VariableTypeInteger, VariableTypeBoolean:
+		default:
+			return fmt.Errorf("variable %s: unsupported type %q", name, def.Type)
+		}
+		if def.Pattern != "" {
+			if _, err := regexp.Compile(def.Pattern); err != nil {
+				return fmt.Errorf("variable %s: invalid pattern: %v", name, err)
+			}
+		}
+		if def.Default != "" {
+			if _, err := def.CheckValue(def.Default); err != nil {
+				return fmt.Errorf("variable %s: invalid default: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveParams 合并预设变量、声明默认值和外部传入的参数，并按变量声明校验
+// 所有问题会一次性通过 *ParamError 返回，调用方应在打开浏览器页面前调用
+func (s *Script) ResolveParams(params map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(s.Variables)+len(params))
+	for k, v := range s.Variables {
+		resolved[k] = v
+	}
+	for _, def := range s.VariableDefs {
+		if def.Default != "" {
+			resolved[def.Name] = def.Default
+		}
+	}
+	for k, v := range params {
+		resolved[k] = v
+	}
+	// 已声明但未提供的参数替换为空字符串，未声明的占位符（如回放中抓取的变量）保持不变
+	for _, def := range s.VariableDefs {
+		if _, ok := resolved[def.Name]; !ok {
+			resolved[def.Name] = ""
+		}
+	}
+	if props, ok := s.MCPInputSchema["properties"].(map[string]interface{}); ok {
+		for name := range props {
+			if _, ok := resolved[name]; !ok {
+				resolved[name] = ""
+			}
+		}
+	}
+
+	var issues []string
+	for _, def := range s.VariableDefs {
+		value := resolved[def.Name]
+		if value == "" {
+			if def.Required {
+				issues = append(issues, fmt.Sprintf("%s is required", def.Name))
+			}
+			continue
+		}
+		normalized, err := def.CheckValue(value)
+		if err != nil {
+			issues = append(issues, err.Error())
+			continue
+		}
+		resolved[def.Name] = normalized
+	}
+	if len(issues) > 0 {
+		return nil, &ParamError{Issues: issues}
+	}
+	return resolved, nil
+}
+
+// WithParams 校验参数并返回替换了 ${变量} 占位符的脚本副本
+// 未定义的占位符保持不变（例如回放中抓取的变量、循环变量）
+func (s *Script) WithParams(params map[string]string) (*Script, error) {
+	resolved, err := s.ResolveParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	script := s.Copy()
+	script.Variables = resolved
+
+	// 传入 url 参数时直接作为起始 URL
+	if urlParam, ok := params["url"]; ok && urlParam != "" {
+		script.URL = urlParam
+	} else {
+		script.URL = SubstituteVariables(script.URL, resolved)
+	}
+	for i := range script.Actions {
+		script.Actions[i] = script.Actions[i].WithVariables(resolved)
+	}
+	return script, nil
+}
+
+// IsSecretVariable 判断变量是否声明为敏感变量
+func (s *Script) IsSecretVariable(name string) bool {
+	for _, def := range s.VariableDefs {
+		if def.Name == name {
+			return def.Secret
+		}
+	}
+	return false
+}
+
+// MaskVariable 返回用于日志输出的变量值（敏感变量隐藏）
+func (s *Script) MaskVariable(name, value string) string {
+	if s.IsSecretVariable(name) {
+		return secretMask
+	}
+	return value
+}
+
+// MaskParams 返回用于日志输出的参数副本（敏感变量隐藏）
+func (s *Script) MaskParams(params map[string]string) map[string]string {
+	masked := make(map[string]string, len(params))
+	for k, v := range params {
+		masked[k] = s.MaskVariable(k, v)
+	}
+	return masked
+}
+
+// MaskSecrets 将文本中出现的敏感变量值替换为掩码（用于日志输出）
+func (s *Script) MaskSecrets(text string) string {
+	for _, def := range s.VariableDefs {
+		if !def.Secret {
+			continue
+		}
+		if value := s.Variables[def.Name]; value != "" {
+			text = strings.ReplaceAll(text, value, secretMask)
+		}
+	}
+	return text
+}
+
+// BuildInputSchema 根据变量声明生成 MCP 输入参数的 JSON Schema
+func (s *Script) BuildInputSchema() map[string]interface{} {
+	properties := make(map[string]interface{}, len(s.VariableDefs))
+	required := make([]interface{}, 0)
+
+	for _, def := range s.VariableDefs {
+		varType := def.normalizedType()
+		prop := map[string]interface{}{"type": varType}
+		if def.Description != "" {
+			prop["description"] = def.Description
+		}
+		if def.Default != "" {
+			prop["default"] = schemaValue(varType, def.Default)
+		}
+		if len(def.Enum) > 0 {
+			options := make([]interface{}, len(def.Enum))
+			for i, option := range def.Enum {
+				options[i] = schemaValue(varType, option)
+			}
+			prop["enum"] = options
+		}
+		if def.Pattern != "" {
+			prop["pattern"] = def.Pattern
+		}
+		if def.Secret {
+			prop["writeOnly"] = true
+		}
+		properties[def.Name] = prop
+
+		if def.Required && def.Default == "" {
+			required = append(required, def.Name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// schemaValue 将字符串值转换为 JSON Schema 中对应类型的值
+func schemaValue(varType, value string) interface{} {
+	switch varType {
+	case VariableTypeNumber:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case VariableTypeInteger:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return float64(n)
+		}
+	case VariableTypeBoolean:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// SubstituteVariables 替换文本中的 ${变量}，未定义的占位符保持不变
+func SubstituteVariables(text string, vars map[string]string) string {
+	if text == "" || !strings.Contains(text, "${") {
+		return text
+	}
+	for key, value := range vars {
+		text = strings.ReplaceAll(text, "${"+key+"}", value)
+	}
+	return text
+}
+
+// WithVariables 返回替换了 ${变量} 的操作副本
+// 嵌套循环的循环体不在这里替换，回放执行到该循环时会使用当时的变量作用域
+func (a ScriptAction) WithVariables(vars map[string]string) ScriptAction {
+	a.Selector = SubstituteVariables(a.Selector, vars)
+	a.XPath = SubstituteVariables(a.XPath, vars)
+	a.Value = SubstituteVariables(a.Value, vars)
+	a.URL = SubstituteVariables(a.URL, vars)
+	a.JSCode = SubstituteVariables(a.JSCode, vars)
+	a.Key = SubstituteVariables(a.Key, vars)
+	a.VariableName = SubstituteVariables(a.VariableName, vars)
+	a.AIControlPrompt = SubstituteVariables(a.AIControlPrompt, vars)
+	if len(a.FilePaths) > 0 {
+		paths := make([]string, len(a.FilePaths))
+		for i, path := range a.FilePaths {
+			paths[i] = SubstituteVariables(path, vars)
+		}
+		a.FilePaths = paths
+	}
+	if len(a.ScriptParams) > 0 {
+		params := make(map[string]string, len(a.ScriptParams))
+		for k, v := range a.ScriptParams {
+			params[k] = SubstituteVariables(v, vars)
+		}
+		a.ScriptParams = params
+	}
+	if a.Assert != nil {
+		assert := *a.Assert
+		assert.Expected = SubstituteVariables(assert.Expected, vars)
+		a.Assert = &assert
+	}
+	if a.Condition != nil {
+		cond := *a.Condition
+		cond.Value = SubstituteVariables(cond.Value, vars)
+		a.Condition = &cond
+	}
+	return a
+}
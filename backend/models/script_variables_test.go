@@ -0,0 +1,144 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestResolveParams(t *testing.T) {
+	script := &Script{
+		Variables: map[string]string{"keyword": "golang"},
+		VariableDefs: []VariableDefinition{
+			{Name: "keyword", Required: true},
+			{Name: "page", Type: VariableTypeInteger, Default: "1"},
+			{Name: "sort", Enum: []string{"new", "hot"}},
+			{Name: "headless", Type: VariableTypeBoolean},
+			{Name: "email", Pattern: `^[^@\s]+@[^@\s]+$`},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		params     map[string]string
+		want       map[string]string
+		wantIssues int
+	}{
+		{
+			name:   "defaults",
+			params: nil,
+			want:   map[string]string{"keyword": "golang", "page": "1", "sort": "", "headless": "", "email": ""},
+		},
+		{
+			name:   "override and normalize",
+			params: map[string]string{"keyword": "rod", "page": " 3 ", "sort": "hot", "headless": "1", "email": "a@b.c", "extra": "x"},
+			want:   map[string]string{"keyword": "rod", "page": "3", "sort": "hot", "headless": "true", "email": "a@b.c", "extra": "x"},
+		},
+		{
+			name:       "all issues reported",
+			params:     map[string]string{"keyword": "", "page": "2.5", "sort": "old", "headless": "maybe", "email": "nope"},
+			wantIssues: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := script.ResolveParams(tt.params)
+			if tt.wantIssues > 0 {
+				var paramErr *ParamError
+				if !errors.As(err, &paramErr) || len(paramErr.Issues) != tt.wantIssues {
+					t.Fatalf("ResolveParams() error = %v, want %d issues", err, tt.wantIssues)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveParams() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveParams() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithParams(t *testing.T) {
+	script := &Script{
+		URL:          "https://example.com/search?q=${keyword}",
+		VariableDefs: []VariableDefinition{{Name: "keyword"}, {Name: "password", Secret: true}},
+		Actions: []ScriptAction{
+			{Type: "input", XPath: "//input[@name='q']", Value: "${keyword} ${title}"},
+			{Type: "input", Value: "${password}", Condition: &ActionCondition{Variable: "title", Operator: "=", Value: "${keyword}"}},
+			{Type: "loop_list", Loop: &ActionLoop{ListVariable: "ids", Actions: []ScriptAction{{Type: "navigate", URL: "${item}"}}}},
+		},
+	}
+
+	got, err := script.WithParams(map[string]string{"keyword": "rod", "password": "s3cret"})
+	if err != nil {
+		t.Fatalf("WithParams() error = %v", err)
+	}
+	if got.URL != "https://example.com/search?q=rod" {
+		t.Errorf("URL = %q", got.URL)
+	}
+	// 未声明的占位符（回放中抓取的变量）保持不变
+	if got.Actions[0].Value != "rod ${title}" {
+		t.Errorf("action value = %q", got.Actions[0].Value)
+	}
+	if got.Actions[1].Condition.Value != "rod" || script.Actions[1].Condition.Value != "${keyword}" {
+		t.Error("condition should be substituted on the copy only")
+	}
+	if got.Actions[2].Loop.Actions[0].URL != "${item}" {
+		t.Error("loop bodies should be substituted at playback time")
+	}
+	if masked := got.MaskSecrets("typing s3cret"); masked != "typing "+secretMask {
+		t.Errorf("MaskSecrets() = %q", masked)
+	}
+
+	if _, err := script.WithParams(map[string]string{"url": "https://example.org"}); err != nil {
+		t.Fatalf("WithParams() error = %v", err)
+	}
+}
+
+func TestBuildInputSchema(t *testing.T) {
+	script := &Script{VariableDefs: []VariableDefinition{
+		{Name: "keyword", Description: "Search keyword", Required: true},
+		{Name: "page", Type: VariableTypeInteger, Required: true, Default: "1"},
+		{Name: "token", Secret: true, Pattern: "^[a-z]+$"},
+	}}
+
+	schema := script.BuildInputSchema()
+	if !reflect.DeepEqual(schema["required"], []interface{}{"keyword"}) {
+		t.Errorf("required = %v", schema["required"])
+	}
+	props := schema["properties"].(map[string]interface{})
+	page := props["page"].(map[string]interface{})
+	if page["type"] != "integer" || page["default"] != float64(1) {
+		t.Errorf("page = %v", page)
+	}
+	token := props["token"].(map[string]interface{})
+	if token["writeOnly"] != true || token["pattern"] != "^[a-z]+$" {
+		t.Errorf("token = %v", token)
+	}
+}
+
+func TestValidateVariableDefs(t *testing.T) {
+	tests := []struct {
+		name    string
+		defs    []VariableDefinition
+		wantErr bool
+	}{
+		{name: "valid", defs: []VariableDefinition{{Name: "a"}, {Name: "b", Type: VariableTypeNumber, Default: "1.5"}}},
+		{name: "missing name", defs: []VariableDefinition{{Type: VariableTypeString}}, wantErr: true},
+		{name: "duplicate", defs: []VariableDefinition{{Name: "a"}, {Name: "a"}}, wantErr: true},
+		{name: "unknown type", defs: []VariableDefinition{{Name: "a", Type: "date"}}, wantErr: true},
+		{name: "bad pattern", defs: []VariableDefinition{{Name: "a", Pattern: "("}}, wantErr: true},
+		{name: "default outside enum", defs: []VariableDefinition{{Name: "a", Enum: []string{"x"}, Default: "y"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateVariableDefs(tt.defs); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateVariableDefs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// StorageState 存储状态：Cookie 加上按源（origin）划分的 localStorage、sessionStorage 和 IndexedDB
+// 与 CookieStore 相比可以恢复把登录令牌保存在 Web Storage 或 IndexedDB 中的单页应用的登录状态
+type StorageState struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Cookies     []*proto.NetworkCookie `json:"cookies"`
+	Origins     []OriginStorage        `json:"origins"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// OriginStorage 单个源的存储数据
+type OriginStorage struct {
+	Origin         string              `json:"origin"` // 如 https://example.com
+	LocalStorage   []StorageItem       `json:"local_storage,omitempty"`
+	SessionStorage []StorageItem       `json:"session_storage,omitempty"` // 只能从当前页面捕获，恢复到回放/执行器的页面
+	IndexedDB      []IndexedDBDatabase `json:"indexed_db,omitempty"`
+}
+
+// StorageItem Web Storage 中的键值对
+type StorageItem struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// IndexedDBDatabase IndexedDB 数据库
+// 记录的键和值以 JSON 保存，Date、Blob、ArrayBuffer 等无法 JSON 序列化的值不会被完整恢复
+type IndexedDBDatabase struct {
+	Name    string           `json:"name"`
+	Version int              `json:"version"`
+	Stores  []IndexedDBStore `json:"stores"`
+}
+
+// IndexedDBStore IndexedDB 对象仓库
+type IndexedDBStore struct {
+	Name          string            `json:"name"`
+	KeyPath       interface{}       `json:"key_path,omitempty"` // 字符串或字符串数组，为空表示使用外部键
+	AutoIncrement bool              `json:"auto_increment,omitempty"`
+	Indexes       []IndexedDBIndex  `json:"indexes,omitempty"`
+	Records       []IndexedDBRecord `json:"records"`
+}
+
+// IndexedDBIndex IndexedDB 索引
+type IndexedDBIndex struct {
+	Name       string      `json:"name"`
+	KeyPath    interface{} `json:"key_path"`
+	Unique     bool        `json:"unique,omitempty"`
+	MultiEntry bool        `json:"multi_entry,omitempty"`
+}
+
+// IndexedDBRecord IndexedDB 记录
+type IndexedDBRecord struct {
+	Key   interface{} `json:"key,omitempty"` // 仅在使用外部键（无 key_path）时需要
+	Value interface{} `json:"value"`
+}
+
+// FindOrigin 返回指定源的存储数据，不存在时返回 nil
+func (s *StorageState) FindOrigin(origin string) *OriginStorage {
+	for i := range s.Origins {
+		if s.Origins[i].Origin == origin {
+			return &s.Origins[i]
+		}
+	}
+	return nil
+}
@@ -150,6 +150,12 @@ func (p *RealScriptPlayer) PlayScript(scriptID string, variables map[string]stri
 		return nil, fmt.Errorf("invalid browser manager type: %T", p.browserManager)
 	}
 
+	// 校验参数并替换占位符（在启动浏览器之前拒绝无效参数）
+	scriptToRun, err := script.WithParams(variables)
+	if err != nil {
+		return nil, err
+	}
+
 	// 确保浏览器正在运行
 	if !bm.IsRunning() {
 		log.Printf("[RealScriptPlayer] Browser not running, starting...")
@@ -158,43 +164,6 @@ func (p *RealScriptPlayer) PlayScript(scriptID string, variables map[string]stri
 		}
 	}
 
-	// 创建脚本副本并替换变量
-	scriptToRun := script.Copy()
-
-	// 合并参数：先使用脚本预设变量，再用外部传入的参数覆盖
-	mergedParams := make(map[string]string)
-	if scriptToRun.Variables != nil {
-		for key, value := range scriptToRun.Variables {
-			mergedParams[key] = value
-		}
-	}
-	for key, value := range variables {
-		mergedParams[key] = value
-	}
-
-	// 替换占位符
-	if len(mergedParams) > 0 {
-		scriptToRun.URL = replacePlaceholders(scriptToRun.URL, mergedParams)
-		scriptToRun.Actions = make([]models.ScriptAction, len(script.Actions))
-		copy(scriptToRun.Actions, script.Actions)
-
-		for i := range scriptToRun.Actions {
-			scriptToRun.Actions[i].Selector = replacePlaceholders(scriptToRun.Actions[i].Selector, mergedParams)
-			scriptToRun.Actions[i].XPath = replacePlaceholders(scriptToRun.Actions[i].XPath, mergedParams)
-			scriptToRun.Actions[i].Value = replacePlaceholders(scriptToRun.Actions[i].Value, mergedParams)
-			scriptToRun.Actions[i].URL = replacePlaceholders(scriptToRun.Actions[i].URL, mergedParams)
-			scriptToRun.Actions[i].JSCode = replacePlaceholders(scriptToRun.Actions[i].JSCode, mergedParams)
-
-			if len(scriptToRun.Actions[i].FilePaths) > 0 {
-				newFilePaths := make([]string, len(scriptToRun.Actions[i].FilePaths))
-				for j, path := range scriptToRun.Actions[i].FilePaths {
-					newFilePaths[j] = replacePlaceholders(path, mergedParams)
-				}
-				scriptToRun.Actions[i].FilePaths = newFilePaths
-			}
-		}
-	}
-
 	// 执行脚本
 	result, page, err := bm.PlayScript(ctx, scriptToRun, instanceID)
 	if err != nil {
@@ -209,20 +178,6 @@ func (p *RealScriptPlayer) PlayScript(scriptID string, variables map[string]stri
 	return result, nil
 }
 
-// replacePlaceholders 替换占位符 ${xxx}
-func replacePlaceholders(text string, params map[string]string) string {
-	if text == "" || len(params) == 0 {
-		return text
-	}
-
-	result := text
-	for key, value := range params {
-		placeholder := fmt.Sprintf("${%s}", key)
-		result = strings.ReplaceAll(result, placeholder, value)
-	}
-	return result
-}
-
 // RealAgentExecutor 真实 Agent 执行器（使用 Agent 管理器）
 type RealAgentExecutor struct {
 	agentManager interface{} // 使用 interface{} 避免循环依赖
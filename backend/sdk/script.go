@@ -171,14 +171,15 @@ func (sc *ScriptClient) Delete(ctx context.Context, scriptID string) error {
 
 // Play 执行脚本
 func (sc *ScriptClient) Play(ctx context.Context, scriptID string) (*ScriptExecution, error) {
+	return sc.PlayWithParams(ctx, scriptID, nil)
+}
+
+// PlayWithParams 传入变量执行脚本，参数按脚本的变量声明校验
+func (sc *ScriptClient) PlayWithParams(ctx context.Context, scriptID string, params map[string]string) (*ScriptExecution, error) {
 	if sc.client.browserManager == nil {
 		return nil, fmt.Errorf("browser manager not initialized")
 	}
 
-	if !sc.client.browserManager.IsRunning() {
-		return nil, fmt.Errorf("browser is not running, please start browser first")
-	}
-
 	// 获取脚本
 	dbScript, err := sc.client.db.GetScript(scriptID)
 	if err != nil {
@@ -189,8 +190,18 @@ func (sc *ScriptClient) Play(ctx context.Context, scriptID string) (*ScriptExecu
 		return nil, fmt.Errorf("script not found: %s", scriptID)
 	}
 
+	// 校验参数并替换占位符
+	scriptToRun, err := dbScript.WithParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if !sc.client.browserManager.IsRunning() {
+		return nil, fmt.Errorf("browser is not running, please start browser first")
+	}
+
 	// 执行脚本（使用当前实例，传空字符串）
-	result, page, err := sc.client.browserManager.PlayScript(ctx, dbScript, "")
+	result, page, err := sc.client.browserManager.PlayScript(ctx, scriptToRun, "")
 	if err != nil {
 		return nil, fmt.Errorf("failed to play script: %w", err)
 	}
@@ -0,0 +1,138 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/browserwing/browserwing/models"
+	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/go-rod/rod"
+)
+
+const (
+	defaultRetryCount     = 3
+	defaultRetryBackoffMs = 1000
+	maxRetryBackoff       = 30 * time.Second
+)
+
+// actionPolicy 单个步骤实际生效的失败处理策略
+type actionPolicy struct {
+	OnError  string        // continue, abort, retry
+	Attempts int           // 总尝试次数（含首次执行）
+	Backoff  time.Duration // 首次重试前的等待时间，之后每次翻倍
+	Timeout  time.Duration // 单次尝试的超时时间，0 表示不限制
+}
+
+// resolveActionPolicy 合并步骤与脚本的失败处理配置
+// 步骤配置优先，未设置时使用脚本默认值，都未设置时保持原有行为（失败后继续）
+func resolveActionPolicy(script *models.Script, action models.ScriptAction) actionPolicy {
+	policy := actionPolicy{OnError: models.OnErrorContinue, Attempts: 1}
+
+	onError := strings.ToLower(strings.TrimSpace(action.OnError))
+	if onError == "" && script != nil {
+		onError = strings.ToLower(strings.TrimSpace(script.DefaultOnError))
+	}
+	switch onError {
+	case models.OnErrorAbort:
+		policy.OnError = models.OnErrorAbort
+	case models.OnErrorRetry:
+		policy.OnError = models.OnErrorRetry
+		retries := action.RetryCount
+		if retries <= 0 {
+			retries = defaultRetryCount
+		}
+		backoffMs := action.RetryBackoffMs
+		if backoffMs <= 0 {
+			backoffMs = defaultRetryBackoffMs
+		}
+		policy.Attempts = retries + 1
+		policy.Backoff = time.Duration(backoffMs) * time.Millisecond
+	}
+
+	// 脚本默认超时针对单个步骤，不作用于整个循环（循环体中的步骤各自生效）
+	timeoutMs := action.TimeoutMs
+	if timeoutMs <= 0 && script != nil && !models.IsLoopAction(action.Type) {
+		timeoutMs = script.DefaultTimeoutMs
+	}
+	if timeoutMs > 0 {
+		policy.Timeout = time.Duration(timeoutMs) * time.Millisecond
+	}
+
+	return policy
+}
+
+// retryDelay 计算第 attempt 次尝试（从 2 开始）前的等待时间，指数退避并设置上限
+func (policy actionPolicy) retryDelay(attempt int) time.Duration {
+	delay := policy.Backoff
+	for i := 2; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	return delay
+}
+
+// ValidateActionPolicy 校验步骤或脚本上配置的失败处理策略
+func ValidateActionPolicy(onError string) error {
+	switch strings.ToLower(strings.TrimSpace(onError)) {
+	case "", models.OnErrorContinue, models.OnErrorAbort, models.OnErrorRetry:
+		return nil
+	default:
+		return fmt.Errorf("unsupported on_error policy %q (expected continue, abort or retry)", onError)
+	}
+}
+
+// runActionWithPolicy 按策略执行单个步骤，返回实际尝试次数、是否超时以及最后一次的错误
+func (p *Player) runActionWithPolicy(ctx context.Context, page *rod.Page, action models.ScriptAction, policy actionPolicy) (attempts int, timedOut bool, err error) {
+	for attempt := 1; attempt <= policy.Attempts; attempt++ {
+		if attempt > 1 {
+			delay := policy.retryDelay(attempt)
+			logger.Info(ctx, "Retrying action %s in %v (attempt %d/%d)", action.Type, delay, attempt, policy.Attempts)
+			select {
+			case <-ctx.Done():
+				return attempt - 1, timedOut, ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		attempts = attempt
+		timedOut, err = p.executeActionWithTimeout(ctx, page, action, policy.Timeout)
+		if err == nil {
+			return attempts, false, nil
+		}
+		if attempt < policy.Attempts {
+			logger.Warn(ctx, "Action %s failed on attempt %d/%d: %v", action.Type, attempt, policy.Attempts, err)
+		}
+	}
+	return attempts, timedOut, err
+}
+
+// executeActionWithTimeout 在超时限制内执行步骤
+// 超时通过页面上下文传递给 rod，页面上的等待、查找和 CDP 调用都会在截止时间后返回
+func (p *Player) executeActionWithTimeout(ctx context.Context, page *rod.Page, action models.ScriptAction, timeout time.Duration) (bool, error) {
+	if timeout <= 0 {
+		return false, p.executeAction(ctx, page, action)
+	}
+
+	stepCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// 当前标签页也需要绑定超时上下文，执行结束后恢复为原始页面对象
+	originalPage := p.currentPage
+	if originalPage != nil {
+		p.currentPage = originalPage.Context(stepCtx)
+	}
+	err := p.executeAction(stepCtx, page.Context(stepCtx), action)
+	if originalPage != nil && p.currentPage != nil && p.currentPage.TargetID == originalPage.TargetID {
+		p.currentPage = originalPage
+	}
+
+	if err != nil && ctx.Err() == nil && errors.Is(stepCtx.Err(), context.DeadlineExceeded) {
+		return true, fmt.Errorf("timed out after %v: %w", timeout, err)
+	}
+	return false, err
+}
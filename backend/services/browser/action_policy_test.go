@@ -0,0 +1,74 @@
+package browser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/browserwing/browserwing/models"
+)
+
+func TestResolveActionPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		script *models.Script
+		action models.ScriptAction
+		want   actionPolicy
+	}{
+		{
+			name:   "defaults keep continuing",
+			script: &models.Script{},
+			action: models.ScriptAction{Type: "click"},
+			want:   actionPolicy{OnError: models.OnErrorContinue, Attempts: 1},
+		},
+		{
+			name:   "script default applies",
+			script: &models.Script{DefaultOnError: "abort", DefaultTimeoutMs: 5000},
+			action: models.ScriptAction{Type: "click"},
+			want:   actionPolicy{OnError: models.OnErrorAbort, Attempts: 1, Timeout: 5 * time.Second},
+		},
+		{
+			name:   "action overrides script",
+			script: &models.Script{DefaultOnError: "abort", DefaultTimeoutMs: 5000},
+			action: models.ScriptAction{Type: "click", OnError: "continue", TimeoutMs: 200},
+			want:   actionPolicy{OnError: models.OnErrorContinue, Attempts: 1, Timeout: 200 * time.Millisecond},
+		},
+		{
+			name:   "retry with defaults",
+			script: &models.Script{},
+			action: models.ScriptAction{Type: "click", OnError: "Retry"},
+			want:   actionPolicy{OnError: models.OnErrorRetry, Attempts: 4, Backoff: time.Second},
+		},
+		{
+			name:   "retry with explicit count and backoff",
+			script: nil,
+			action: models.ScriptAction{Type: "input", OnError: "retry", RetryCount: 2, RetryBackoffMs: 250},
+			want:   actionPolicy{OnError: models.OnErrorRetry, Attempts: 3, Backoff: 250 * time.Millisecond},
+		},
+		{
+			name:   "unknown policy falls back to continue",
+			script: &models.Script{},
+			action: models.ScriptAction{Type: "click", OnError: "ignore"},
+			want:   actionPolicy{OnError: models.OnErrorContinue, Attempts: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveActionPolicy(tt.script, tt.action); got != tt.want {
+				t.Errorf("resolveActionPolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActionPolicyRetryDelay(t *testing.T) {
+	policy := actionPolicy{OnError: models.OnErrorRetry, Attempts: 10, Backoff: 5 * time.Second}
+
+	want := []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, maxRetryBackoff, maxRetryBackoff}
+	for i, w := range want {
+		attempt := i + 2
+		if got := policy.retryDelay(attempt); got != w {
+			t.Errorf("retryDelay(%d) = %v, want %v", attempt, got, w)
+		}
+	}
+}
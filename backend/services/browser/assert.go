@@ -0,0 +1,381 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/browserwing/browserwing/models"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// assertPollInterval 等待断言成立时的检查间隔
+const assertPollInterval = 200 * time.Millisecond
+
+// assertionTextScript 读取元素文本，表单控件读取当前值
+const assertionTextScript = `() => {
+	if (['INPUT', 'TEXTAREA', 'SELECT'].includes(this.tagName)) return String(this.value);
+	return this.innerText || this.textContent || '';
+}`
+
+// AssertionError 断言未通过时返回的错误，回放器据此区分软断言和硬断言
+type AssertionError struct {
+	Result *models.AssertResult
+}
+
+func (e *AssertionError) Error() string {
+	return "assertion failed: " + e.Result.Message
+}
+
+// IsSoft 是否为软断言失败（记录后继续执行）
+func (e *AssertionError) IsSoft() bool {
+	return e.Result.Mode == models.AssertModeSoft
+}
+
+// asAssertionError 判断错误是否由断言失败引起
+func asAssertionError(err error) (*AssertionError, bool) {
+	var assertErr *AssertionError
+	if errors.As(err, &assertErr) {
+		return assertErr, true
+	}
+	return nil, false
+}
+
+// assertOperators 断言支持的比较操作符
+var assertOperators = map[string]bool{
+	"=": true, "==": true, "!=": true, ">": true, "<": true, ">=": true, "<=": true,
+	"contains": true, "not_contains": true, "matches": true, "in": true, "not_in": true,
+	"exists": true, "not_exists": true,
+}
+
+// AssertionNeedsElement 判断断言类型是否需要定位元素
+func AssertionNeedsElement(kind string) bool {
+	switch kind {
+	case models.AssertVisible, models.AssertHidden, models.AssertTextEquals, models.AssertTextContains,
+		models.AssertTextMatches, models.AssertAttribute, models.AssertCount:
+		return true
+	}
+	return false
+}
+
+// ValidateAssertOptions 校验断言配置（包含 ${变量} 的期望值在回放时替换后再校验）
+func ValidateAssertOptions(opts *models.AssertOptions) error {
+	if opts == nil {
+		return fmt.Errorf("assert options are required")
+	}
+
+	switch opts.Kind {
+	case models.AssertVisible, models.AssertHidden, models.AssertTextEquals, models.AssertTextContains:
+	case models.AssertTextMatches, models.AssertURLMatches, models.AssertTitleMatches:
+		if !strings.Contains(opts.Expected, "${") {
+			if _, err := regexp.Compile(opts.Expected); err != nil {
+				return fmt.Errorf("invalid regular expression %q: %w", opts.Expected, err)
+			}
+		}
+	case models.AssertAttribute:
+		if strings.TrimSpace(opts.Attribute) == "" {
+			return fmt.Errorf("attribute assertion requires attribute")
+		}
+	case models.AssertCount:
+		if !strings.Contains(opts.Expected, "${") {
+			if _, err := strconv.Atoi(strings.TrimSpace(opts.Expected)); err != nil {
+				return fmt.Errorf("count assertion requires an integer expected value, got %q", opts.Expected)
+			}
+		}
+	case models.AssertVariable:
+		if strings.TrimSpace(opts.Variable) == "" {
+			return fmt.Errorf("variable assertion requires variable")
+		}
+	default:
+		return fmt.Errorf("unsupported assertion kind %q (expected visible, hidden, text_equals, text_contains, text_matches, attribute, url_matches, title_matches, count or variable)", opts.Kind)
+	}
+
+	switch opts.Mode {
+	case "", models.AssertModeHard, models.AssertModeSoft:
+	default:
+		return fmt.Errorf("unsupported assertion mode %q (expected hard or soft)", opts.Mode)
+	}
+	if opts.Operator != "" && !assertOperators[opts.Operator] {
+		return fmt.Errorf("unsupported assertion operator %q", opts.Operator)
+	}
+	if opts.WaitMs < 0 {
+		return fmt.Errorf("wait_ms must not be negative")
+	}
+	return nil
+}
+
+// ValidateAssertAction 校验 assert 类型的步骤
+// requireLocator 为 false 时允许元素类断言不设置定位器（loop_elements 循环体中作用于当前元素）
+func ValidateAssertAction(action models.ScriptAction, requireLocator bool) error {
+	if action.Type != models.ActionTypeAssert {
+		return nil
+	}
+	if err := ValidateAssertOptions(action.Assert); err != nil {
+		return err
+	}
+	if requireLocator && AssertionNeedsElement(action.Assert.Kind) && action.Selector == "" && action.XPath == "" {
+		return fmt.Errorf("%s assertion requires selector or xpath", action.Assert.Kind)
+	}
+	return nil
+}
+
+// ElementQuery 查询断言目标的所有匹配元素（不等待元素出现）
+type ElementQuery func(page *rod.Page) (rod.Elements, error)
+
+// LocatorQuery 按定位器查询元素：支持穿透 Shadow DOM 的语法、XPath 和 CSS
+func LocatorQuery(locator string, xpath bool) ElementQuery {
+	return func(page *rod.Page) (rod.Elements, error) {
+		if IsShadowLocator(locator) {
+			return FindShadowElements(page, locator)
+		}
+		if xpath {
+			return page.ElementsX(locator)
+		}
+		return page.Elements(locator)
+	}
+}
+
+// assertObservation 断言求值时从页面读取到的状态
+type assertObservation struct {
+	found     bool
+	visible   bool
+	text      string
+	attribute *string
+	count     int
+	url       string
+	title     string
+}
+
+// observeAssertion 读取断言需要的页面状态
+func observeAssertion(page *rod.Page, opts *models.AssertOptions, query ElementQuery) (assertObservation, error) {
+	var obs assertObservation
+
+	switch opts.Kind {
+	case models.AssertURLMatches, models.AssertTitleMatches:
+		info, err := page.Info()
+		if err != nil {
+			return obs, fmt.Errorf("failed to get page info: %w", err)
+		}
+		obs.url = info.URL
+		obs.title = info.Title
+		return obs, nil
+	case models.AssertVariable:
+		return obs, nil
+	}
+
+	if query == nil {
+		return obs, fmt.Errorf("%s assertion requires a selector", opts.Kind)
+	}
+	elements, err := query(page)
+	if err != nil {
+		return obs, fmt.Errorf("failed to query elements: %w", err)
+	}
+	obs.count = len(elements)
+	obs.found = len(elements) > 0
+	if !obs.found || opts.Kind == models.AssertCount {
+		return obs, nil
+	}
+
+	element := elements[0]
+	switch opts.Kind {
+	case models.AssertVisible, models.AssertHidden:
+		obs.visible, err = element.Visible()
+	case models.AssertAttribute:
+		obs.attribute, err = element.Attribute(opts.Attribute)
+	default:
+		var res *proto.RuntimeRemoteObject
+		res, err = element.Eval(assertionTextScript)
+		if err == nil {
+			obs.text = res.Value.Str()
+		}
+	}
+	if err != nil {
+		return obs, fmt.Errorf("failed to read element state: %w", err)
+	}
+	return obs, nil
+}
+
+// EvaluateAssertion 在页面上求值断言，设置了 wait_ms 时轮询直到通过或超时
+// 断言未通过不作为错误返回（由调用方根据结果和模式处理），只有页面读取失败时返回错误
+func EvaluateAssertion(ctx context.Context, page *rod.Page, opts *models.AssertOptions, variables map[string]string, query ElementQuery) (*models.AssertResult, error) {
+	resolved := *opts
+	resolved.Expected = models.SubstituteVariables(opts.Expected, variables)
+
+	deadline := time.Now().Add(time.Duration(opts.WaitMs) * time.Millisecond)
+	for {
+		obs, err := observeAssertion(page.Context(ctx), &resolved, query)
+		if err != nil {
+			return nil, err
+		}
+		result := checkAssertion(&resolved, obs, variables)
+		if result.Passed || !time.Now().Before(deadline) {
+			return result, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return result, nil
+		case <-time.After(assertPollInterval):
+		}
+	}
+}
+
+// checkAssertion 将读取到的页面状态与期望值比较，生成断言结果
+func checkAssertion(opts *models.AssertOptions, obs assertObservation, variables map[string]string) *models.AssertResult {
+	result := &models.AssertResult{
+		Kind:     opts.Kind,
+		Mode:     opts.Mode,
+		Expected: opts.Expected,
+	}
+	if result.Mode == "" {
+		result.Mode = models.AssertModeHard
+	}
+	operator := opts.Operator
+	if operator == "" {
+		operator = "="
+	}
+
+	var detail string
+	var err error
+	switch opts.Kind {
+	case models.AssertVisible:
+		result.Actual = elementState(obs)
+		result.Passed = obs.found && obs.visible
+		detail = fmt.Sprintf("expected element to be visible, but it was %s", result.Actual)
+	case models.AssertHidden:
+		result.Actual = elementState(obs)
+		result.Passed = !obs.found || !obs.visible
+		detail = "expected element to be hidden, but it was visible"
+	case models.AssertTextEquals, models.AssertTextContains, models.AssertTextMatches:
+		if !obs.found {
+			result.Actual = "not found"
+			detail = "element not found"
+			break
+		}
+		result.Actual = strings.TrimSpace(obs.text)
+		switch opts.Kind {
+		case models.AssertTextEquals:
+			result.Passed = result.Actual == strings.TrimSpace(opts.Expected)
+			detail = fmt.Sprintf("expected text to equal %q, got %q", opts.Expected, result.Actual)
+		case models.AssertTextContains:
+			result.Passed = strings.Contains(result.Actual, opts.Expected)
+			detail = fmt.Sprintf("expected text to contain %q, got %q", opts.Expected, result.Actual)
+		default:
+			result.Passed, err = compareAssertValue(result.Actual, "matches", opts.Expected)
+			detail = fmt.Sprintf("expected text to match /%s/, got %q", opts.Expected, result.Actual)
+		}
+	case models.AssertAttribute:
+		detail = fmt.Sprintf("expected attribute %q %s %q", opts.Attribute, operator, opts.Expected)
+		switch {
+		case !obs.found:
+			result.Actual = "not found"
+			detail = "element not found"
+		case obs.attribute == nil:
+			result.Actual = "absent"
+			result.Passed = operator == "not_exists"
+			detail += ", but the attribute is absent"
+		default:
+			result.Actual = *obs.attribute
+			result.Passed, err = compareAssertValue(result.Actual, operator, opts.Expected)
+			detail += fmt.Sprintf(", got %q", result.Actual)
+		}
+	case models.AssertURLMatches:
+		result.Actual = obs.url
+		result.Passed, err = compareAssertValue(obs.url, "matches", opts.Expected)
+		detail = fmt.Sprintf("expected URL to match /%s/, got %q", opts.Expected, obs.url)
+	case models.AssertTitleMatches:
+		result.Actual = obs.title
+		result.Passed, err = compareAssertValue(obs.title, "matches", opts.Expected)
+		detail = fmt.Sprintf("expected title to match /%s/, got %q", opts.Expected, obs.title)
+	case models.AssertCount:
+		result.Actual = strconv.Itoa(obs.count)
+		result.Passed, err = compareAssertValue(result.Actual, operator, strings.TrimSpace(opts.Expected))
+		detail = fmt.Sprintf("expected element count %s %s, got %d", operator, opts.Expected, obs.count)
+	case models.AssertVariable:
+		value, ok := variables[opts.Variable]
+		detail = fmt.Sprintf("expected variable %q %s %q", opts.Variable, operator, opts.Expected)
+		switch {
+		case operator == "not_exists":
+			result.Actual = value
+			result.Passed = !ok
+			detail = fmt.Sprintf("expected variable %q not to be set, got %q", opts.Variable, value)
+		case !ok:
+			result.Actual = "unset"
+			detail += ", but the variable is not set"
+		default:
+			result.Actual = value
+			result.Passed, err = compareAssertValue(value, operator, opts.Expected)
+			detail += fmt.Sprintf(", got %q", value)
+		}
+	default:
+		err = fmt.Errorf("unsupported assertion kind %q", opts.Kind)
+	}
+
+	if err != nil {
+		result.Passed = false
+		detail = err.Error()
+	}
+	switch {
+	case result.Passed:
+		result.Message = "assertion passed"
+	case opts.Message != "":
+		result.Message = opts.Message + ": " + detail
+	default:
+		result.Message = detail
+	}
+	return result
+}
+
+// elementState 描述元素的可见状态
+func elementState(obs assertObservation) string {
+	switch {
+	case !obs.found:
+		return "not found"
+	case obs.visible:
+		return "visible"
+	default:
+		return "hidden"
+	}
+}
+
+// compareAssertValue 按操作符比较实际值和期望值
+func compareAssertValue(actual, operator, expected string) (bool, error) {
+	switch operator {
+	case "", "=", "==":
+		return actual == expected, nil
+	case "!=":
+		return actual != expected, nil
+	case ">", "<", ">=", "<=":
+		return compareNumeric(actual, expected, operator)
+	case "contains":
+		return strings.Contains(actual, expected), nil
+	case "not_contains":
+		return !strings.Contains(actual, expected), nil
+	case "matches":
+		re, err := regexp.Compile(expected)
+		if err != nil {
+			return false, fmt.Errorf("invalid regular expression %q: %w", expected, err)
+		}
+		return re.MatchString(actual), nil
+	case "in", "not_in":
+		found := false
+		for _, v := range strings.Split(expected, ",") {
+			if strings.TrimSpace(v) == actual {
+				found = true
+				break
+			}
+		}
+		return found == (operator == "in"), nil
+	case "exists":
+		return true, nil
+	case "not_exists":
+		return false, nil
+	default:
+		return false, fmt.Errorf("unsupported assertion operator %q", operator)
+	}
+}
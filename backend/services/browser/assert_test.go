@@ -0,0 +1,101 @@
+package browser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/browserwing/browserwing/models"
+)
+
+func TestValidateAssertOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    *models.AssertOptions
+		wantErr bool
+	}{
+		{name: "nil", opts: nil, wantErr: true},
+		{name: "visible", opts: &models.AssertOptions{Kind: models.AssertVisible}},
+		{name: "soft count", opts: &models.AssertOptions{Kind: models.AssertCount, Operator: ">=", Expected: "2", Mode: models.AssertModeSoft}},
+		{name: "count from variable", opts: &models.AssertOptions{Kind: models.AssertCount, Expected: "${total}"}},
+		{name: "unknown kind", opts: &models.AssertOptions{Kind: "enabled"}, wantErr: true},
+		{name: "invalid regex", opts: &models.AssertOptions{Kind: models.AssertURLMatches, Expected: "(unclosed"}, wantErr: true},
+		{name: "attribute without name", opts: &models.AssertOptions{Kind: models.AssertAttribute, Expected: "x"}, wantErr: true},
+		{name: "count not a number", opts: &models.AssertOptions{Kind: models.AssertCount, Expected: "many"}, wantErr: true},
+		{name: "variable without name", opts: &models.AssertOptions{Kind: models.AssertVariable}, wantErr: true},
+		{name: "unknown mode", opts: &models.AssertOptions{Kind: models.AssertVisible, Mode: "warn"}, wantErr: true},
+		{name: "unknown operator", opts: &models.AssertOptions{Kind: models.AssertVariable, Variable: "x", Operator: "~"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAssertOptions(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAssertOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckAssertion(t *testing.T) {
+	href := "/orders/42"
+	vars := map[string]string{"total": "12.50", "status": "paid"}
+
+	tests := []struct {
+		name   string
+		opts   models.AssertOptions
+		obs    assertObservation
+		passed bool
+		actual string
+	}{
+		{name: "visible", opts: models.AssertOptions{Kind: models.AssertVisible}, obs: assertObservation{found: true, visible: true, count: 1}, passed: true, actual: "visible"},
+		{name: "visible but missing", opts: models.AssertOptions{Kind: models.AssertVisible}, actual: "not found"},
+		{name: "hidden when missing", opts: models.AssertOptions{Kind: models.AssertHidden}, passed: true, actual: "not found"},
+		{name: "hidden but visible", opts: models.AssertOptions{Kind: models.AssertHidden}, obs: assertObservation{found: true, visible: true}, actual: "visible"},
+		{name: "text equals trims", opts: models.AssertOptions{Kind: models.AssertTextEquals, Expected: "Welcome"}, obs: assertObservation{found: true, text: "  Welcome\n"}, passed: true, actual: "Welcome"},
+		{name: "text contains", opts: models.AssertOptions{Kind: models.AssertTextContains, Expected: "Bob"}, obs: assertObservation{found: true, text: "Hello Alice"}, actual: "Hello Alice"},
+		{name: "text matches", opts: models.AssertOptions{Kind: models.AssertTextMatches, Expected: `^\d+ items?$`}, obs: assertObservation{found: true, text: "3 items"}, passed: true, actual: "3 items"},
+		{name: "attribute matches", opts: models.AssertOptions{Kind: models.AssertAttribute, Attribute: "href", Operator: "matches", Expected: `^/orders/\d+$`}, obs: assertObservation{found: true, attribute: &href}, passed: true, actual: href},
+		{name: "attribute absent", opts: models.AssertOptions{Kind: models.AssertAttribute, Attribute: "disabled", Expected: ""}, obs: assertObservation{found: true}, actual: "absent"},
+		{name: "attribute not exists", opts: models.AssertOptions{Kind: models.AssertAttribute, Attribute: "disabled", Operator: "not_exists"}, obs: assertObservation{found: true}, passed: true, actual: "absent"},
+		{name: "url matches", opts: models.AssertOptions{Kind: models.AssertURLMatches, Expected: "/dashboard"}, obs: assertObservation{url: "https://app.example.com/dashboard"}, passed: true, actual: "https://app.example.com/dashboard"},
+		{name: "title mismatch", opts: models.AssertOptions{Kind: models.AssertTitleMatches, Expected: "^Login"}, obs: assertObservation{title: "Dashboard"}, actual: "Dashboard"},
+		{name: "count default equals", opts: models.AssertOptions{Kind: models.AssertCount, Expected: "3"}, obs: assertObservation{found: true, count: 3}, passed: true, actual: "3"},
+		{name: "count greater", opts: models.AssertOptions{Kind: models.AssertCount, Operator: ">", Expected: "10"}, obs: assertObservation{found: true, count: 9}, actual: "9"},
+		{name: "variable numeric", opts: models.AssertOptions{Kind: models.AssertVariable, Variable: "total", Operator: ">=", Expected: "10"}, passed: true, actual: "12.50"},
+		{name: "variable in list", opts: models.AssertOptions{Kind: models.AssertVariable, Variable: "status", Operator: "in", Expected: "paid, shipped"}, passed: true, actual: "paid"},
+		{name: "variable unset", opts: models.AssertOptions{Kind: models.AssertVariable, Variable: "missing", Expected: "x"}, actual: "unset"},
+		{name: "variable not exists", opts: models.AssertOptions{Kind: models.AssertVariable, Variable: "missing", Operator: "not_exists"}, passed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := checkAssertion(&tt.opts, tt.obs, vars)
+			if result.Passed != tt.passed {
+				t.Errorf("Passed = %v, want %v (message: %s)", result.Passed, tt.passed, result.Message)
+			}
+			if result.Actual != tt.actual {
+				t.Errorf("Actual = %q, want %q", result.Actual, tt.actual)
+			}
+			if result.Mode != models.AssertModeHard {
+				t.Errorf("Mode = %q, want default hard", result.Mode)
+			}
+		})
+	}
+}
+
+func TestCheckAssertionMessage(t *testing.T) {
+	opts := &models.AssertOptions{Kind: models.AssertTextEquals, Expected: "Paid", Mode: models.AssertModeSoft, Message: "order status"}
+	result := checkAssertion(opts, assertObservation{found: true, text: "Pending"}, nil)
+	want := `order status: expected text to equal "Paid", got "Pending"`
+	if result.Passed || result.Message != want {
+		t.Errorf("Message = %q, want %q", result.Message, want)
+	}
+
+	err := &AssertionError{Result: result}
+	if !err.IsSoft() {
+		t.Error("expected soft assertion error")
+	}
+	if got, ok := asAssertionError(fmt.Errorf("nested step 1 (assert) failed: %w", err)); !ok || got != err {
+		t.Error("asAssertionError should unwrap wrapped assertion errors")
+	}
+}
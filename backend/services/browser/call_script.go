@@ -0,0 +1,119 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/browserwing/browserwing/models"
+	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/go-rod/rod"
+)
+
+// maxCallScriptDepth 子脚本最大嵌套调用深度
+const maxCallScriptDepth = 5
+
+// ScriptLoader 按 ID 加载已保存的脚本（用于 call_script）
+type ScriptLoader func(id string) (*models.Script, error)
+
+// SetScriptLoader 设置子脚本加载函数
+func (p *Player) SetScriptLoader(loader ScriptLoader) {
+	p.scriptLoader = loader
+}
+
+// ValidateCallScriptAction 校验 call_script 操作的配置
+// scriptID 为当前脚本的 ID，用于拒绝直接调用自身
+func ValidateCallScriptAction(scriptID string, action models.ScriptAction) error {
+	if action.Type != models.ActionTypeCallScript {
+		return nil
+	}
+	if strings.TrimSpace(action.ScriptID) == "" {
+		return fmt.Errorf("call_script requires script_id")
+	}
+	if scriptID != "" && action.ScriptID == scriptID {
+		return fmt.Errorf("call_script cannot call the script itself")
+	}
+	return nil
+}
+
+// executeCallScript 在当前页面中执行另一个已保存的脚本
+// 子脚本使用自己的预设变量，ScriptParams 覆盖同名变量；执行结束后子脚本抓取的数据合并回当前脚本
+func (p *Player) executeCallScript(ctx context.Context, page *rod.Page, action models.ScriptAction) error {
+	if err := ValidateCallScriptAction("", action); err != nil {
+		return err
+	}
+	if p.scriptLoader == nil {
+		return fmt.Errorf("call_script is not available: script loader not set")
+	}
+
+	for _, id := range p.callStack {
+		if id == action.ScriptID {
+			return fmt.Errorf("call_script cycle detected: %s -> %s", strings.Join(p.callStack, " -> "), action.ScriptID)
+		}
+	}
+	// callStack 包含顶层脚本，子脚本调用深度 = len(callStack)
+	if len(p.callStack) > maxCallScriptDepth {
+		return fmt.Errorf("call_script depth limit (%d) exceeded", maxCallScriptDepth)
+	}
+
+	callee, err := p.scriptLoader(action.ScriptID)
+	if err != nil {
+		return fmt.Errorf("failed to load script %s: %w", action.ScriptID, err)
+	}
+
+	// 子脚本作用域：子脚本预设变量 + 调用参数（参数中的 ${变量} 使用当前变量替换），按子脚本的变量声明校验
+	params := make(map[string]string, len(action.ScriptParams))
+	for k, v := range action.ScriptParams {
+		params[k] = models.SubstituteVariables(v, p.variables)
+	}
+	scope, err := callee.ResolveParams(params)
+	if err != nil {
+		return fmt.Errorf("script %s: %w", callee.Name, err)
+	}
+
+	logger.Info(ctx, "Calling script: %s (%d steps)", callee.Name, len(callee.Actions))
+
+	if callee.URL != "" {
+		// 起始 URL 可以引用子脚本作用域中的变量；日志只输出模板，避免泄露敏感变量
+		targetURL := models.SubstituteVariables(callee.URL, scope)
+		activePage := p.activeLoopPage(page)
+		if current := p.currentPageURL(); current != targetURL {
+			logger.Info(ctx, "Navigate to called script URL: %s", callee.URL)
+			if err := activePage.Navigate(targetURL); err != nil {
+				return fmt.Errorf("navigation failed: %w", err)
+			}
+			if err := activePage.WaitLoad(); err != nil {
+				logger.Warn(ctx, "Failed to wait for page to load: %v", err)
+			}
+		}
+	}
+
+	// 切换到子脚本的上下文，执行结束后恢复
+	callerScript, callerVariables, callerData := p.currentScript, p.variables, p.extractedData
+	calleeScript := callee.Copy()
+	calleeScript.Variables = scope
+	p.currentScript = calleeScript
+	p.variables = scope
+	p.extractedData = make(map[string]interface{})
+	p.callStack = append(p.callStack, action.ScriptID)
+
+	runErr := p.runNestedActions(ctx, page, callee.Actions, scope, "", make(map[string][]interface{}))
+
+	calleeData := p.extractedData
+	p.callStack = p.callStack[:len(p.callStack)-1]
+	p.currentScript, p.variables, p.extractedData = callerScript, callerVariables, callerData
+
+	// 即使子脚本失败，也保留已抓取的数据
+	for k, v := range calleeData {
+		p.extractedData[k] = v
+		if v != nil {
+			p.variables[k] = loopItemString(v)
+		}
+	}
+
+	if runErr != nil {
+		return fmt.Errorf("called script %s failed: %w", callee.Name, runErr)
+	}
+	logger.Info(ctx, "✓ Called script completed: %s (%d data item(s) merged)", callee.Name, len(calleeData))
+	return nil
+}
@@ -0,0 +1,56 @@
+package browser
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/browserwing/browserwing/models"
+)
+
+func TestValidateCallScriptAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  models.ScriptAction
+		wantErr bool
+	}{
+		{name: "not a call", action: models.ScriptAction{Type: "click"}},
+		{name: "valid", action: models.ScriptAction{Type: models.ActionTypeCallScript, ScriptID: "login"}},
+		{name: "missing script id", action: models.ScriptAction{Type: models.ActionTypeCallScript}, wantErr: true},
+		{name: "calls itself", action: models.ScriptAction{Type: models.ActionTypeCallScript, ScriptID: "main"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCallScriptAction("main", tt.action)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCallScriptAction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteCallScriptGuards(t *testing.T) {
+	loaded := 0
+	p := NewPlayer("en")
+	p.SetScriptLoader(func(id string) (*models.Script, error) {
+		loaded++
+		return &models.Script{ID: id}, nil
+	})
+
+	action := models.ScriptAction{Type: models.ActionTypeCallScript, ScriptID: "login"}
+
+	p.callStack = []string{"main", "login", "nested"}
+	if err := p.executeCallScript(context.Background(), nil, action); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected cycle error, got %v", err)
+	}
+
+	p.callStack = []string{"s0", "s1", "s2", "s3", "s4", "s5"}
+	if err := p.executeCallScript(context.Background(), nil, action); err == nil || !strings.Contains(err.Error(), "depth") {
+		t.Errorf("expected depth limit error, got %v", err)
+	}
+
+	if loaded != 0 {
+		t.Errorf("script loader called %d time(s), want 0", loaded)
+	}
+}
@@ -0,0 +1,621 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/browserwing/browserwing/models"
+	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/go-rod/rod"
+	"github.com/google/uuid"
+)
+
+// 调试命令
+const (
+	DebugCommandContinue    = "continue"    // 继续执行，直到下一个断点或失败
+	DebugCommandStep        = "step"        // 执行当前步骤后再次暂停
+	DebugCommandSkip        = "skip"        // 跳过当前步骤
+	DebugCommandRetry       = "retry"       // 执行（或重新执行）当前步骤，可附带修改后的步骤
+	DebugCommandAbort       = "abort"       // 终止回放
+	DebugCommandBreakpoints = "breakpoints" // 替换断点列表，运行中也可发送
+)
+
+// 调试会话状态
+const (
+	DebugStateRunning  = "running"
+	DebugStatePaused   = "paused"
+	DebugStateFinished = "finished"
+)
+
+// 调试事件类型
+const (
+	DebugEventPaused       = "paused"
+	DebugEventStepFinished = "step_finished"
+	DebugEventFinished     = "finished"
+)
+
+// 暂停原因
+const (
+	DebugReasonBreakpoint = "breakpoint"
+	DebugReasonStep       = "step"
+	DebugReasonFailure    = "failure"
+)
+
+const (
+	debugElementTimeout  = 2 * time.Second // 暂停时定位元素的超时，避免面板长时间无响应
+	debugTextLimit       = 200
+	debugHTMLLimit       = 1000
+	debugSessionTTL      = 10 * time.Minute // 结束后保留会话的时间，便于前端读取最终结果
+	debugEventBufferSize = 32
+	debugPauseTimeout    = 15 * time.Minute // 暂停后无命令的最长等待时间，超时终止回放并释放页面
+	debugDetachGrace     = 30 * time.Second // 最后一个事件流断开后等待重连的时间，超时终止回放
+)
+
+// ErrDebugAborted 调试回放被用户终止
+var ErrDebugAborted = errors.New("playback aborted by debugger")
+
+// DebugCommand 调试控制命令
+type DebugCommand struct {
+	Command     string               `json:"command"`
+	Action      *models.ScriptAction `json:"action,omitempty"`      // retry 时替换当前步骤
+	Breakpoints []int                `json:"breakpoints,omitempty"` // breakpoints 命令的新断点（步骤序号，从 1 开始）
+}
+
+// DebugElementBox 元素位置
+type DebugElementBox struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// DebugElement 暂停时当前步骤定位到的元素
+type DebugElement struct {
+	Found     bool             `json:"found"`
+	MatchedBy string           `json:"matched_by,omitempty"` // xpath / selector
+	Locator   string           `json:"locator,omitempty"`
+	Tag       string           `json:"tag,omitempty"`
+	Text      string           `json:"text,omitempty"`
+	HTML      string           `json:"html,omitempty"`
+	Visible   bool             `json:"visible"`
+	Box       *DebugElementBox `json:"box,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// DebugEvent 调试事件，通过 SSE 推送给前端
+type DebugEvent struct {
+	Seq           int                    `json:"seq"`
+	Type          string                 `json:"type"`
+	Step          int                    `json:"step,omitempty"` // 步骤序号，从 1 开始
+	Total         int                    `json:"total,omitempty"`
+	Reason        string                 `json:"reason,omitempty"` // 暂停原因
+	Status        string                 `json:"status,omitempty"` // 步骤结果或会话最终状态
+	Action        *models.ScriptAction   `json:"action,omitempty"`
+	Variables     map[string]string      `json:"variables,omitempty"`
+	ExtractedData map[string]interface{} `json:"extracted_data,omitempty"`
+	Element       *DebugElement          `json:"element,omitempty"`
+	Error         string                 `json:"error,omitempty"`
+	Result        *models.PlayResult     `json:"result,omitempty"`
+	Time          time.Time              `json:"time"`
+}
+
+// DebugEdit 调试中对步骤的修改，便于调试结束后写回脚本
+type DebugEdit struct {
+	Step   int                 `json:"step"`
+	Action models.ScriptAction `json:"action"`
+}
+
+// DebugSessionInfo 调试会话快照
+type DebugSessionInfo struct {
+	ID          string             `json:"id"`
+	ScriptID    string             `json:"script_id"`
+	State       string             `json:"state"`
+	Breakpoints []int              `json:"breakpoints"`
+	Paused      *DebugEvent        `json:"paused,omitempty"` // 当前暂停事件
+	Edits       []DebugEdit        `json:"edits"`
+	StartTime   time.Time          `json:"start_time"`
+	EndTime     *time.Time         `json:"end_time,omitempty"`
+	LastEvent   *DebugEvent        `json:"last_event,omitempty"`
+	Result      *models.PlayResult `json:"result,omitempty"`
+}
+
+// DebugSession 一次调试回放：记录断点、转发控制命令并广播调试事件
+type DebugSession struct {
+	ID       string
+	ScriptID string
+
+	mu          sync.Mutex
+	state       string
+	breakpoints map[int]bool
+	stepping    bool // 单步模式：每个步骤执行前都暂停
+	paused      *DebugEvent
+	commands    chan DebugCommand
+	events      []DebugEvent
+	subscribers map[chan DebugEvent]struct{}
+	edits       map[int]models.ScriptAction
+	startTime   time.Time
+	endTime     *time.Time
+	result      *models.PlayResult
+	cancel      context.CancelFunc
+	done        chan struct{}
+
+	pauseTimeout time.Duration
+	detachGrace  time.Duration
+	detachedAt   time.Time // 最后一个订阅者断开的时间
+}
+
+// NewDebugSession 创建调试会话，stepMode 为 true 时在第一步前即暂停
+func NewDebugSession(scriptID string, breakpoints []int, stepMode bool) *DebugSession {
+	s := &DebugSession{
+		ID:          uuid.New().String(),
+		ScriptID:    scriptID,
+		state:       DebugStateRunning,
+		stepping:    stepMode,
+		commands:    make(chan DebugCommand, 1),
+		subscribers: make(map[chan DebugEvent]struct{}),
+		edits:       make(map[int]models.ScriptAction),
+		startTime:   time.Now(),
+		done:        make(chan struct{}),
+
+		pauseTimeout: debugPauseTimeout,
+		detachGrace:  debugDetachGrace,
+	}
+	s.setBreakpoints(breakpoints)
+	return s
+}
+
+// Bind 绑定回放上下文的取消函数，终止或清理会话时调用
+func (s *DebugSession) Bind(cancel context.CancelFunc) {
+	s.mu.Lock()
+	s.cancel = cancel
+	s.mu.Unlock()
+}
+
+// Done 回放结束后关闭
+func (s *DebugSession) Done() <-chan struct{} {
+	return s.done
+}
+
+// Send 发送调试命令，除 breakpoints 外的命令只能在暂停时发送
+func (s *DebugSession) Send(cmd DebugCommand) error {
+	switch cmd.Command {
+	case DebugCommandBreakpoints:
+		s.setBreakpoints(cmd.Breakpoints)
+		return nil
+	case DebugCommandContinue, DebugCommandStep, DebugCommandSkip, DebugCommandAbort:
+	case DebugCommandRetry:
+		if cmd.Action != nil && cmd.Action.Type == "" {
+			return fmt.Errorf("edited action must have a type")
+		}
+	default:
+		return fmt.Errorf("unknown debug command: %s", cmd.Command)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == DebugStateFinished {
+		return fmt.Errorf("debug session has finished")
+	}
+	if s.state != DebugStatePaused {
+		// 运行中终止直接取消回放上下文，其余命令需要等待暂停
+		if cmd.Command == DebugCommandAbort && s.cancel != nil {
+			s.cancel()
+			return nil
+		}
+		return fmt.Errorf("debug session is not paused")
+	}
+	select {
+	case s.commands <- cmd:
+		return nil
+	default:
+		return fmt.Errorf("a command is already pending")
+	}
+}
+
+// Subscribe 订阅调试事件，返回已发生的事件和后续事件通道
+// 会话结束后通道被关闭；最后一个订阅者取消后若未在 detachGrace 内重新订阅，回放被终止
+func (s *DebugSession) Subscribe() ([]DebugEvent, <-chan DebugEvent, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := make([]DebugEvent, len(s.events))
+	copy(history, s.events)
+	ch := make(chan DebugEvent, debugEventBufferSize)
+	if s.state == DebugStateFinished {
+		close(ch)
+		return history, ch, func() {}
+	}
+	s.subscribers[ch] = struct{}{}
+	return history, ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subscribers[ch]; !ok {
+			return
+		}
+		delete(s.subscribers, ch)
+		close(ch)
+		if len(s.subscribers) == 0 && s.state != DebugStateFinished {
+			s.detachedAt = time.Now()
+			time.AfterFunc(s.detachGrace, s.abortIfDetached)
+		}
+	}
+}
+
+// abortIfDetached 所有事件流断开超过 detachGrace 且无人重连时终止回放
+func (s *DebugSession) abortIfDetached() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == DebugStateFinished || len(s.subscribers) > 0 || time.Since(s.detachedAt) < s.detachGrace {
+		return
+	}
+	logger.Warn(context.Background(), "[debug] Session %s has no event stream attached, aborting playback", s.ID)
+	s.abortLocked()
+}
+
+// abortLocked 终止回放：暂停中则投递 abort 命令，运行中则取消回放上下文（调用方持有 s.mu）
+func (s *DebugSession) abortLocked() {
+	if s.state == DebugStatePaused {
+		select {
+		case s.commands <- DebugCommand{Command: DebugCommandAbort}:
+		default:
+		}
+	}
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+// Info 返回调试会话快照
+func (s *DebugSession) Info() DebugSessionInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info := DebugSessionInfo{
+		ID:          s.ID,
+		ScriptID:    s.ScriptID,
+		State:       s.state,
+		Breakpoints: s.breakpointList(),
+		Paused:      s.paused,
+		Edits:       make([]DebugEdit, 0, len(s.edits)),
+		StartTime:   s.startTime,
+		EndTime:     s.endTime,
+		Result:      s.result,
+	}
+	for step, action := range s.edits {
+		info.Edits = append(info.Edits, DebugEdit{Step: step, Action: action})
+	}
+	sort.Slice(info.Edits, func(i, j int) bool { return info.Edits[i].Step < info.Edits[j].Step })
+	if n := len(s.events); n > 0 {
+		last := s.events[n-1]
+		info.LastEvent = &last
+	}
+	return info
+}
+
+// Finish 回放结束：推送 finished 事件并关闭所有订阅
+func (s *DebugSession) Finish(result *models.PlayResult, playErr error) {
+	event := DebugEvent{Type: DebugEventFinished, Status: "success", Result: result}
+	if playErr != nil {
+		event.Status = "failed"
+		event.Error = playErr.Error()
+		if errors.Is(playErr, ErrDebugAborted) || errors.Is(playErr, context.Canceled) {
+			event.Status = "aborted"
+		}
+	}
+	s.emit(event)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == DebugStateFinished {
+		return
+	}
+	now := time.Now()
+	s.state = DebugStateFinished
+	s.endTime = &now
+	s.paused = nil
+	s.result = result
+	for ch := range s.subscribers {
+		close(ch)
+	}
+	s.subscribers = make(map[chan DebugEvent]struct{})
+	close(s.done)
+}
+
+// pauseReason 返回步骤执行前的暂停原因，无需暂停时返回空字符串
+func (s *DebugSession) pauseReason(step int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.breakpoints[step] {
+		return DebugReasonBreakpoint
+	}
+	if s.stepping {
+		return DebugReasonStep
+	}
+	return ""
+}
+
+// pause 推送暂停事件并阻塞等待控制命令，回放上下文取消或等待超过 pauseTimeout 时视为终止
+func (s *DebugSession) pause(ctx context.Context, event DebugEvent) DebugCommand {
+	event.Type = DebugEventPaused
+	s.mu.Lock()
+	s.state = DebugStatePaused
+	s.mu.Unlock()
+	s.emit(event)
+
+	timer := time.NewTimer(s.pauseTimeout)
+	defer timer.Stop()
+
+	var cmd DebugCommand
+	select {
+	case cmd = <-s.commands:
+	case <-ctx.Done():
+		cmd = DebugCommand{Command: DebugCommandAbort}
+	case <-timer.C:
+		logger.Warn(ctx, "[debug] Session %s paused for more than %v without a command, aborting playback", s.ID, s.pauseTimeout)
+		cmd = DebugCommand{Command: DebugCommandAbort}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = DebugStateRunning
+	s.paused = nil
+	switch cmd.Command {
+	case DebugCommandStep:
+		s.stepping = true
+	case DebugCommandContinue:
+		s.stepping = false
+	}
+	if cmd.Command == DebugCommandRetry && cmd.Action != nil {
+		s.edits[event.Step] = *cmd.Action
+	}
+	return cmd
+}
+
+// emit 记录事件并广播给订阅者，订阅者消费过慢时丢弃该订阅者的事件
+func (s *DebugSession) emit(event DebugEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == DebugStateFinished {
+		return
+	}
+	event.Seq = len(s.events) + 1
+	event.Time = time.Now()
+	s.events = append(s.events, event)
+	if event.Type == DebugEventPaused {
+		s.paused = &event
+	}
+	for ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (s *DebugSession) setBreakpoints(steps []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.breakpoints = make(map[int]bool, len(steps))
+	for _, step := range steps {
+		if step > 0 {
+			s.breakpoints[step] = true
+		}
+	}
+}
+
+func (s *DebugSession) breakpointList() []int {
+	steps := make([]int, 0, len(s.breakpoints))
+	for step := range s.breakpoints {
+		steps = append(steps, step)
+	}
+	sort.Ints(steps)
+	return steps
+}
+
+// DebugSessions 调试会话注册表
+type DebugSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*DebugSession
+}
+
+// NewDebugSessions 创建调试会话注册表
+func NewDebugSessions() *DebugSessions {
+	return &DebugSessions{sessions: make(map[string]*DebugSession)}
+}
+
+// Add 注册会话，会话结束一段时间后自动移除
+func (r *DebugSessions) Add(s *DebugSession) {
+	r.mu.Lock()
+	r.sessions[s.ID] = s
+	r.mu.Unlock()
+
+	go func() {
+		<-s.Done()
+		time.AfterFunc(debugSessionTTL, func() {
+			r.mu.Lock()
+			delete(r.sessions, s.ID)
+			r.mu.Unlock()
+		})
+	}()
+}
+
+// Get 获取会话
+func (r *DebugSessions) Get(id string) (*DebugSession, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.sessions[id]
+	return s, ok
+}
+
+// debugBeforeStep 调试模式下在断点或单步时暂停，返回 false 表示跳过该步骤
+// 发送 retry 并附带修改后的步骤时，action 会被替换
+func (p *Player) debugBeforeStep(ctx context.Context, page *rod.Page, script *models.Script, index int, action *models.ScriptAction) (bool, error) {
+	if p.debugger == nil {
+		return true, nil
+	}
+	if p.debugResume {
+		// 失败后重试的步骤不再重复暂停
+		p.debugResume = false
+		return true, nil
+	}
+	reason := p.debugger.pauseReason(index + 1)
+	if reason == "" {
+		return true, nil
+	}
+
+	cmd := p.debugPause(ctx, page, script, index, action, reason, nil)
+	switch cmd.Command {
+	case DebugCommandAbort:
+		return false, ErrDebugAborted
+	case DebugCommandSkip:
+		return false, nil
+	}
+	return true, nil
+}
+
+// debugAfterFailure 调试模式下步骤失败后暂停，返回用户选择的命令
+// 返回 retry 时 action 可能已被替换，continue / step 按原失败策略处理
+func (p *Player) debugAfterFailure(ctx context.Context, page *rod.Page, script *models.Script, index int, action *models.ScriptAction, stepErr error) string {
+	if ctx.Err() != nil {
+		// 运行中被终止导致的失败，不再暂停
+		return DebugCommandAbort
+	}
+	cmd := p.debugPause(ctx, page, script, index, action, DebugReasonFailure, stepErr)
+	if cmd.Command == DebugCommandRetry {
+		p.debugResume = true
+	}
+	return cmd.Command
+}
+
+// debugPause 收集当前变量、抓取数据和目标元素后暂停
+func (p *Player) debugPause(ctx context.Context, page *rod.Page, script *models.Script, index int, action *models.ScriptAction, reason string, stepErr error) DebugCommand {
+	if p.currentPage != nil {
+		page = p.currentPage
+	}
+
+	// 事件中保存步骤副本：retry 会替换 action，而事件可能仍在被 SSE 编码
+	snapshot := *action
+	event := DebugEvent{
+		Step:          index + 1,
+		Total:         len(script.Actions),
+		Reason:        reason,
+		Action:        &snapshot,
+		Variables:     make(map[string]string, len(p.variables)),
+		ExtractedData: make(map[string]interface{}, len(p.extractedData)),
+	}
+	for k, v := range p.variables {
+		event.Variables[k] = script.MaskVariable(k, v)
+	}
+	for k, v := range p.extractedData {
+		event.ExtractedData[k] = v
+	}
+	if stepErr != nil {
+		event.Error = p.maskSecrets(stepErr.Error())
+	}
+
+	element, inspected := p.inspectDebugElement(ctx, page, *action)
+	event.Element = inspected
+	if element != nil {
+		p.highlightElement(ctx, element)
+		defer p.unhighlightElement(ctx, element)
+	}
+
+	logger.Info(ctx, "[debug] Paused before step %d (%s), reason: %s", index+1, action.Type, reason)
+	cmd := p.debugger.pause(ctx, event)
+	logger.Info(ctx, "[debug] Resumed at step %d with command: %s", index+1, cmd.Command)
+
+	if cmd.Command == DebugCommandRetry && cmd.Action != nil {
+		*action = *cmd.Action
+		script.Actions[index] = *cmd.Action
+	}
+	return cmd
+}
+
+// debugStepFinished 推送步骤执行结果
+func (p *Player) debugStepFinished(index int, action models.ScriptAction, status string, stepErr error) {
+	if p.debugger == nil {
+		return
+	}
+	event := DebugEvent{
+		Type:   DebugEventStepFinished,
+		Step:   index + 1,
+		Status: status,
+		Action: &action,
+	}
+	if p.currentScript != nil {
+		event.Total = len(p.currentScript.Actions)
+	}
+	if stepErr != nil {
+		event.Error = p.maskSecrets(stepErr.Error())
+	}
+	p.debugger.emit(event)
+}
+
+// inspectDebugElement 按步骤的 XPath / CSS 定位元素并读取其基本信息，仅用于展示，不触发自愈
+func (p *Player) inspectDebugElement(ctx context.Context, page *rod.Page, action models.ScriptAction) (*rod.Element, *DebugElement) {
+	if page == nil || (action.XPath == "" && action.Selector == "") {
+		return nil, nil
+	}
+
+	info := &DebugElement{}
+	lookupPage := page.Context(ctx).Timeout(debugElementTimeout)
+	var element *rod.Element
+	var err error
+	if action.XPath != "" {
+		element, err = findByLocator(lookupPage, action.XPath, true)
+		info.MatchedBy, info.Locator = "xpath", action.XPath
+	}
+	if element == nil && action.Selector != "" {
+		element, err = findByLocator(lookupPage, action.Selector, false)
+		info.MatchedBy, info.Locator = "selector", action.Selector
+	}
+	if element == nil {
+		info.MatchedBy, info.Locator = "", ""
+		if err != nil {
+			info.Error = err.Error()
+		}
+		return nil, info
+	}
+	element = element.CancelTimeout().Context(ctx)
+
+	info.Found = true
+	res, err := element.Eval(`() => {
+		const rect = this.getBoundingClientRect();
+		const style = window.getComputedStyle(this);
+		return {
+			tag: this.tagName.toLowerCase(),
+			text: (this.innerText || this.value || '').trim(),
+			html: this.outerHTML,
+			visible: rect.width > 0 && rect.height > 0 && style.visibility !== 'hidden' && style.display !== 'none',
+			x: rect.x, y: rect.y, width: rect.width, height: rect.height,
+		};
+	}`)
+	if err != nil {
+		info.Error = err.Error()
+		return element, info
+	}
+	v := res.Value
+	info.Tag = v.Get("tag").Str()
+	info.Text = p.maskSecrets(truncateDebugText(v.Get("text").Str(), debugTextLimit))
+	info.HTML = p.maskSecrets(truncateDebugText(v.Get("html").Str(), debugHTMLLimit))
+	info.Visible = v.Get("visible").Bool()
+	info.Box = &DebugElementBox{
+		X:      v.Get("x").Num(),
+		Y:      v.Get("y").Num(),
+		Width:  v.Get("width").Num(),
+		Height: v.Get("height").Num(),
+	}
+	return element, info
+}
+
+func truncateDebugText(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit]) + "..."
+}
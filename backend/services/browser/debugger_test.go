@@ -0,0 +1,197 @@
+package browser
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/browserwing/browserwing/models"
+	"github.com/browserwing/browserwing/pkg/logger"
+)
+
+func TestDebugSessionPauseReason(t *testing.T) {
+	s := NewDebugSession("script", []int{2, 0, -1}, false)
+	tests := []struct {
+		step int
+		want string
+	}{
+		{step: 1, want: ""},
+		{step: 2, want: DebugReasonBreakpoint},
+		{step: 3, want: ""},
+	}
+	for _, tt := range tests {
+		if got := s.pauseReason(tt.step); got != tt.want {
+			t.Errorf("pauseReason(%d) = %q, want %q", tt.step, got, tt.want)
+		}
+	}
+
+	if err := s.Send(DebugCommand{Command: DebugCommandBreakpoints, Breakpoints: []int{3}}); err != nil {
+		t.Fatalf("update breakpoints: %v", err)
+	}
+	if got := s.Info().Breakpoints; len(got) != 1 || got[0] != 3 {
+		t.Errorf("breakpoints = %v, want [3]", got)
+	}
+	if err := s.Send(DebugCommand{Command: DebugCommandContinue}); err == nil {
+		t.Error("continue while running should fail")
+	}
+	if err := s.Send(DebugCommand{Command: "jump"}); err == nil {
+		t.Error("unknown command should fail")
+	}
+}
+
+func TestDebugSessionCommands(t *testing.T) {
+	tests := []struct {
+		cmd          DebugCommand
+		wantStepping bool
+		wantEdits    int
+	}{
+		{cmd: DebugCommand{Command: DebugCommandStep}, wantStepping: true},
+		{cmd: DebugCommand{Command: DebugCommandContinue}, wantStepping: false},
+		{cmd: DebugCommand{Command: DebugCommandRetry, Action: &models.ScriptAction{Type: "click", XPath: "//button"}}, wantStepping: true, wantEdits: 1},
+		{cmd: DebugCommand{Command: DebugCommandSkip, Action: &models.ScriptAction{Type: "click"}}, wantStepping: true},
+	}
+	for _, tt := range tests {
+		s := NewDebugSession("script", nil, true)
+		_, events, unsubscribe := s.Subscribe()
+
+		got := make(chan DebugCommand, 1)
+		go func() {
+			got <- s.pause(context.Background(), DebugEvent{Step: 1, Reason: DebugReasonStep})
+		}()
+
+		select {
+		case event := <-events:
+			if event.Type != DebugEventPaused || event.Step != 1 || event.Seq != 1 {
+				t.Fatalf("%s: unexpected event %+v", tt.cmd.Command, event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: no paused event", tt.cmd.Command)
+		}
+		if info := s.Info(); info.State != DebugStatePaused || info.Paused == nil {
+			t.Fatalf("%s: state = %s, paused = %v", tt.cmd.Command, info.State, info.Paused)
+		}
+		if err := s.Send(tt.cmd); err != nil {
+			t.Fatalf("%s: send: %v", tt.cmd.Command, err)
+		}
+		if cmd := <-got; cmd.Command != tt.cmd.Command {
+			t.Errorf("pause returned %q, want %q", cmd.Command, tt.cmd.Command)
+		}
+
+		info := s.Info()
+		if info.State != DebugStateRunning || info.Paused != nil {
+			t.Errorf("%s: state after resume = %s", tt.cmd.Command, info.State)
+		}
+		if stepping := s.pauseReason(2) == DebugReasonStep; stepping != tt.wantStepping {
+			t.Errorf("%s: stepping = %v, want %v", tt.cmd.Command, stepping, tt.wantStepping)
+		}
+		if len(info.Edits) != tt.wantEdits {
+			t.Errorf("%s: edits = %+v", tt.cmd.Command, info.Edits)
+		}
+
+		s.Finish(nil, ErrDebugAborted)
+		if event := <-events; event.Type != DebugEventFinished || event.Status != "aborted" {
+			t.Errorf("%s: finish event = %+v", tt.cmd.Command, event)
+		}
+		if _, ok := <-events; ok {
+			t.Errorf("%s: events channel not closed after finish", tt.cmd.Command)
+		}
+		unsubscribe()
+	}
+}
+
+func TestDebugSessionAbortsWhenAbandoned(t *testing.T) {
+	logger.InitLogger(&logger.LoggerConfig{Level: "error"})
+
+	t.Run("pause timeout", func(t *testing.T) {
+		s := NewDebugSession("script", nil, true)
+		s.pauseTimeout = 20 * time.Millisecond
+		if cmd := s.pause(context.Background(), DebugEvent{Step: 1}); cmd.Command != DebugCommandAbort {
+			t.Errorf("pause returned %q, want abort", cmd.Command)
+		}
+	})
+
+	t.Run("last event stream detached", func(t *testing.T) {
+		s := NewDebugSession("script", nil, true)
+		s.detachGrace = 20 * time.Millisecond
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		s.Bind(cancel)
+
+		_, _, unsubscribeFirst := s.Subscribe()
+		_, _, unsubscribeSecond := s.Subscribe()
+		got := make(chan DebugCommand, 1)
+		go func() { got <- s.pause(ctx, DebugEvent{Step: 1}) }()
+
+		unsubscribeFirst()
+		time.Sleep(50 * time.Millisecond)
+		select {
+		case cmd := <-got:
+			t.Fatalf("aborted with a subscriber still attached: %+v", cmd)
+		default:
+		}
+
+		unsubscribeSecond()
+		select {
+		case cmd := <-got:
+			if cmd.Command != DebugCommandAbort {
+				t.Errorf("pause returned %q, want abort", cmd.Command)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("session was not aborted after the last stream detached")
+		}
+	})
+}
+
+func TestDebugRetryReplacesAction(t *testing.T) {
+	logger.InitLogger(&logger.LoggerConfig{Level: "error"})
+
+	s := NewDebugSession("script", nil, true)
+	script := &models.Script{Actions: []models.ScriptAction{{Type: "sleep", Duration: 100}}}
+	p := &Player{debugger: s}
+	_, events, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+
+	action := script.Actions[0]
+	done := make(chan DebugCommand, 1)
+	go func() {
+		done <- p.debugPause(context.Background(), nil, script, 0, &action, DebugReasonFailure, nil)
+	}()
+	paused := <-events
+
+	edited := models.ScriptAction{Type: "sleep", Duration: 500}
+	if err := s.Send(DebugCommand{Command: DebugCommandRetry, Action: &edited}); err != nil {
+		t.Fatalf("send retry: %v", err)
+	}
+	<-done
+
+	if action.Duration != 500 || script.Actions[0].Duration != 500 {
+		t.Errorf("action not replaced: local=%d script=%d", action.Duration, script.Actions[0].Duration)
+	}
+	if paused.Action == nil || paused.Action.Duration != 100 {
+		t.Errorf("paused event action changed after retry: %+v", paused.Action)
+	}
+	if history, _, _ := s.Subscribe(); history[0].Action.Duration != 100 {
+		t.Errorf("recorded paused event action = %+v", history[0].Action)
+	}
+}
+
+func TestDiscardLastStepTrace(t *testing.T) {
+	shot := filepath.Join(t.TempDir(), "step_1.png")
+	if err := os.WriteFile(shot, []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := &Player{stepTraces: []models.StepTrace{
+		{Index: 1, Status: models.StepStatusSuccess},
+		{Index: 2, Status: models.StepStatusFailed, ScreenshotPath: shot},
+	}}
+
+	p.discardLastStepTrace(context.Background())
+	if len(p.stepTraces) != 1 || p.stepTraces[0].Index != 1 {
+		t.Errorf("step traces = %+v", p.stepTraces)
+	}
+	if _, err := os.Stat(shot); !os.IsNotExist(err) {
+		t.Errorf("screenshot of discarded trace still exists: %v", err)
+	}
+}
@@ -0,0 +1,425 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/browserwing/browserwing/models"
+	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/devices"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// emulationDevices 可在仿真配置中按名称引用的预设设备（名称不区分大小写）
+var emulationDevices = []devices.Device{
+	devices.IPhone5orSE,
+	devices.IPhone6or7or8,
+	devices.IPhone6or7or8Plus,
+	devices.IPhoneX,
+	devices.IPad,
+	devices.IPadMini,
+	devices.IPadPro,
+	devices.Pixel2,
+	devices.Pixel2XL,
+	devices.GalaxyS5,
+	devices.GalaxyFold,
+	devices.MotoG4,
+	devices.SurfaceDuo,
+	devices.Nexus7,
+	devices.Nexus10,
+	devices.LaptopWithTouch,
+	devices.LaptopWithHiDPIScreen,
+	devices.LaptopWithMDPIScreen,
+}
+
+// EmulationDeviceNames 返回所有预设设备的名称
+func EmulationDeviceNames() []string {
+	names := make([]string, len(emulationDevices))
+	for i, device := range emulationDevices {
+		names[i] = device.Title
+	}
+	return names
+}
+
+// lookupEmulationDevice 按名称查找预设设备
+func lookupEmulationDevice(name string) (devices.Device, bool) {
+	name = strings.TrimSpace(name)
+	for _, device := range emulationDevices {
+		if strings.EqualFold(device.Title, name) {
+			return device, true
+		}
+	}
+	return devices.Device{}, false
+}
+
+// networkThrottle 网络限速参数，带宽单位为 kbit/s
+type networkThrottle struct {
+	latencyMs    int
+	downloadKbps int
+	uploadKbps   int
+}
+
+// emulationNetworkPresets 网络条件预设（与 Chrome DevTools 的限速预设一致）
+var emulationNetworkPresets = map[string]networkThrottle{
+	"slow-3g": {latencyMs: 2000, downloadKbps: 400, uploadKbps: 400},
+	"fast-3g": {latencyMs: 563, downloadKbps: 1440, uploadKbps: 675},
+	"4g":      {latencyMs: 20, downloadKbps: 4000, uploadKbps: 3000},
+}
+
+// ValidateEmulationProfile 校验仿真配置，nil 表示不仿真
+func ValidateEmulationProfile(profile *models.EmulationProfile) error {
+	if profile == nil {
+		return nil
+	}
+	if profile.Device != "" {
+		if _, ok := lookupEmulationDevice(profile.Device); !ok {
+			return fmt.Errorf("unknown emulation device %q (available: %s)", profile.Device, strings.Join(EmulationDeviceNames(), ", "))
+		}
+	}
+	if profile.Width < 0 || profile.Height < 0 || profile.DeviceScaleFactor < 0 {
+		return fmt.Errorf("width, height and device_scale_factor must not be negative")
+	}
+	if geo := profile.Geolocation; geo != nil {
+		if geo.Latitude < -90 || geo.Latitude > 90 || geo.Longitude < -180 || geo.Longitude > 180 {
+			return fmt.Errorf("geolocation out of range: latitude must be within [-90, 90] and longitude within [-180, 180]")
+		}
+		if geo.Accuracy < 0 {
+			return fmt.Errorf("geolocation accuracy must not be negative")
+		}
+	}
+	switch profile.ColorScheme {
+	case "", "light", "dark", "no-preference":
+	default:
+		return fmt.Errorf("unsupported color_scheme %q (expected light, dark or no-preference)", profile.ColorScheme)
+	}
+	switch profile.ReducedMotion {
+	case "", "reduce", "no-preference":
+	default:
+		return fmt.Errorf("unsupported reduced_motion %q (expected reduce or no-preference)", profile.ReducedMotion)
+	}
+	if _, ok := emulationNetworkPresets[profile.Network]; !ok {
+		switch profile.Network {
+		case "", models.EmulationNetworkOnline, models.EmulationNetworkOffline:
+		default:
+			return fmt.Errorf("unsupported network %q (expected online, offline, slow-3g, fast-3g or 4g)", profile.Network)
+		}
+	}
+	if profile.LatencyMs < 0 || profile.DownloadKbps < 0 || profile.UploadKbps < 0 {
+		return fmt.Errorf("latency_ms, download_kbps and upload_kbps must not be negative")
+	}
+	return nil
+}
+
+// MergeEmulationProfiles 合并仿真配置，override 中设置的字段覆盖 base
+func MergeEmulationProfiles(base, override *models.EmulationProfile) *models.EmulationProfile {
+	if base == nil && override == nil {
+		return nil
+	}
+	merged := models.EmulationProfile{}
+	if base != nil {
+		merged = *base
+	}
+	if override == nil {
+		return &merged
+	}
+
+	if override.Device != "" {
+		merged.Device = override.Device
+		merged.Landscape = override.Landscape
+	}
+	if override.Width > 0 {
+		merged.Width = override.Width
+	}
+	if override.Height > 0 {
+		merged.Height = override.Height
+	}
+	if override.DeviceScaleFactor > 0 {
+		merged.DeviceScaleFactor = override.DeviceScaleFactor
+	}
+	if override.Mobile != nil {
+		merged.Mobile = override.Mobile
+	}
+	if override.Touch != nil {
+		merged.Touch = override.Touch
+	}
+	if override.UserAgent != "" {
+		merged.UserAgent = override.UserAgent
+	}
+	if override.Locale != "" {
+		merged.Locale = override.Locale
+	}
+	if override.AcceptLanguage != "" {
+		merged.AcceptLanguage = override.AcceptLanguage
+	}
+	if override.Timezone != "" {
+		merged.Timezone = override.Timezone
+	}
+	if override.Geolocation != nil {
+		merged.Geolocation = override.Geolocation
+	}
+	if override.ColorScheme != "" {
+		merged.ColorScheme = override.ColorScheme
+	}
+	if override.ReducedMotion != "" {
+		merged.ReducedMotion = override.ReducedMotion
+	}
+	if override.Network != "" {
+		merged.Network = override.Network
+	}
+	if override.LatencyMs > 0 {
+		merged.LatencyMs = override.LatencyMs
+	}
+	if override.DownloadKbps > 0 {
+		merged.DownloadKbps = override.DownloadKbps
+	}
+	if override.UploadKbps > 0 {
+		merged.UploadKbps = override.UploadKbps
+	}
+	return &merged
+}
+
+// acceptLanguageForLocale 由区域设置推导 Accept-Language（如 de-DE -> "de-DE,de;q=0.9"）
+func acceptLanguageForLocale(locale string) string {
+	locale = strings.ReplaceAll(strings.TrimSpace(locale), "_", "-")
+	if locale == "" {
+		return ""
+	}
+	if lang, _, found := strings.Cut(locale, "-"); found && lang != "" {
+		return locale + "," + lang + ";q=0.9"
+	}
+	return locale
+}
+
+// networkConditions 返回仿真配置对应的网络条件，未配置网络仿真时返回 nil
+func networkConditions(profile *models.EmulationProfile) *proto.NetworkEmulateNetworkConditions {
+	if profile.Network == models.EmulationNetworkOffline {
+		return &proto.NetworkEmulateNetworkConditions{Offline: true, DownloadThroughput: -1, UploadThroughput: -1}
+	}
+
+	throttle, hasPreset := emulationNetworkPresets[profile.Network]
+	if profile.LatencyMs > 0 {
+		throttle.latencyMs = profile.LatencyMs
+	}
+	if profile.DownloadKbps > 0 {
+		throttle.downloadKbps = profile.DownloadKbps
+	}
+	if profile.UploadKbps > 0 {
+		throttle.uploadKbps = profile.UploadKbps
+	}
+	if !hasPreset && profile.LatencyMs == 0 && profile.DownloadKbps == 0 && profile.UploadKbps == 0 {
+		if profile.Network == models.EmulationNetworkOnline {
+			return &proto.NetworkEmulateNetworkConditions{DownloadThroughput: -1, UploadThroughput: -1}
+		}
+		return nil
+	}
+
+	// CDP 的带宽单位为 bytes/s，-1 表示不限速
+	toBytes := func(kbps int) float64 {
+		if kbps <= 0 {
+			return -1
+		}
+		return float64(kbps) * 1000 / 8
+	}
+	return &proto.NetworkEmulateNetworkConditions{
+		Latency:            float64(throttle.latencyMs),
+		DownloadThroughput: toBytes(throttle.downloadKbps),
+		UploadThroughput:   toBytes(throttle.uploadKbps),
+	}
+}
+
+// ApplyEmulation 通过 CDP Emulation 和 Network 域将仿真配置应用到页面
+// 应在导航前调用；对已加载的页面，部分设置（如 Accept-Language、时区）在刷新后才对页面脚本完全生效
+func ApplyEmulation(ctx context.Context, page *rod.Page, profile *models.EmulationProfile) error {
+	if profile == nil {
+		return nil
+	}
+	if err := ValidateEmulationProfile(profile); err != nil {
+		return err
+	}
+
+	var device devices.Device
+	hasDevice := false
+	if profile.Device != "" {
+		device, hasDevice = lookupEmulationDevice(profile.Device)
+		if profile.Landscape {
+			device = device.Landscape()
+		}
+	}
+
+	// 视口和设备像素比
+	if hasDevice || profile.Width > 0 || profile.Height > 0 || profile.DeviceScaleFactor > 0 || profile.Mobile != nil {
+		metrics := &proto.EmulationSetDeviceMetricsOverride{}
+		if hasDevice {
+			metrics = device.MetricsEmulation()
+		}
+		if profile.Width > 0 {
+			metrics.Width = profile.Width
+		}
+		if profile.Height > 0 {
+			metrics.Height = profile.Height
+		}
+		if profile.DeviceScaleFactor > 0 {
+			metrics.DeviceScaleFactor = profile.DeviceScaleFactor
+		}
+		if profile.Mobile != nil {
+			metrics.Mobile = *profile.Mobile
+		}
+		if metrics.Width == 0 || metrics.Height == 0 {
+			// 只修改 DPR 或移动端标记时保留当前视口尺寸
+			if res, err := page.Eval(`() => [window.innerWidth, window.innerHeight]`); err == nil {
+				if metrics.Width == 0 {
+					metrics.Width = res.Value.Get("0").Int()
+				}
+				if metrics.Height == 0 {
+					metrics.Height = res.Value.Get("1").Int()
+				}
+			}
+		}
+		if err := metrics.Call(page); err != nil {
+			return fmt.Errorf("failed to emulate viewport: %w", err)
+		}
+	}
+
+	// 触摸
+	if hasDevice || profile.Touch != nil {
+		touch := device.TouchEmulation()
+		if profile.Touch != nil {
+			maxTouchPoints := 5
+			touch = &proto.EmulationSetTouchEmulationEnabled{Enabled: *profile.Touch}
+			if *profile.Touch {
+				touch.MaxTouchPoints = &maxTouchPoints
+			}
+		}
+		if err := touch.Call(page); err != nil {
+			return fmt.Errorf("failed to emulate touch: %w", err)
+		}
+	}
+
+	// User Agent 和 Accept-Language
+	userAgent := profile.UserAgent
+	if userAgent == "" && hasDevice {
+		userAgent = device.UserAgent
+	}
+	acceptLanguage := profile.AcceptLanguage
+	if acceptLanguage == "" {
+		acceptLanguage = acceptLanguageForLocale(profile.Locale)
+	}
+	if userAgent == "" && acceptLanguage != "" {
+		// 只修改 Accept-Language 时沿用页面当前的 User Agent
+		if res, err := page.Eval(`() => navigator.userAgent`); err == nil {
+			userAgent = res.Value.String()
+		}
+	}
+	if userAgent != "" {
+		if err := (proto.EmulationSetUserAgentOverride{UserAgent: userAgent, AcceptLanguage: acceptLanguage}).Call(page); err != nil {
+			return fmt.Errorf("failed to emulate user agent: %w", err)
+		}
+	}
+
+	// 区域设置和时区
+	if profile.Locale != "" {
+		if err := (proto.EmulationSetLocaleOverride{Locale: profile.Locale}).Call(page); err != nil {
+			return fmt.Errorf("failed to emulate locale %s: %w", profile.Locale, err)
+		}
+	}
+	if profile.Timezone != "" {
+		if err := (proto.EmulationSetTimezoneOverride{TimezoneID: profile.Timezone}).Call(page); err != nil {
+			return fmt.Errorf("failed to emulate timezone %s: %w", profile.Timezone, err)
+		}
+	}
+
+	// 地理位置
+	if geo := profile.Geolocation; geo != nil {
+		accuracy := geo.Accuracy
+		if accuracy == 0 {
+			accuracy = 100
+		}
+		override := proto.EmulationSetGeolocationOverride{Latitude: &geo.Latitude, Longitude: &geo.Longitude, Accuracy: &accuracy}
+		if err := override.Call(page); err != nil {
+			return fmt.Errorf("failed to emulate geolocation: %w", err)
+		}
+		grant := proto.BrowserGrantPermissions{
+			Permissions:      []proto.BrowserPermissionType{proto.BrowserPermissionTypeGeolocation},
+			BrowserContextID: page.Browser().BrowserContextID,
+		}
+		if err := grant.Call(page.Browser()); err != nil {
+			logger.Warn(ctx, "Failed to grant geolocation permission: %v", err)
+		}
+	}
+
+	// 媒体特性
+	var features []*proto.EmulationMediaFeature
+	if profile.ColorScheme != "" {
+		features = append(features, &proto.EmulationMediaFeature{Name: "prefers-color-scheme", Value: profile.ColorScheme})
+	}
+	if profile.ReducedMotion != "" {
+		features = append(features, &proto.EmulationMediaFeature{Name: "prefers-reduced-motion", Value: profile.ReducedMotion})
+	}
+	if len(features) > 0 {
+		if err := (proto.EmulationSetEmulatedMedia{Features: features}).Call(page); err != nil {
+			return fmt.Errorf("failed to emulate media features: %w", err)
+		}
+	}
+
+	// 网络条件
+	if conditions := networkConditions(profile); conditions != nil {
+		if err := (proto.NetworkEnable{}).Call(page); err != nil {
+			return fmt.Errorf("failed to enable network domain: %w", err)
+		}
+		if err := conditions.Call(page); err != nil {
+			return fmt.Errorf("failed to emulate network conditions: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ResetEmulation 清除页面的视口、触摸、区域、时区、地理位置、媒体特性和网络仿真
+// User Agent 覆盖无法单独清除，保持当前值
+func ResetEmulation(page *rod.Page) error {
+	if err := (proto.EmulationClearDeviceMetricsOverride{}).Call(page); err != nil {
+		return fmt.Errorf("failed to clear viewport emulation: %w", err)
+	}
+	calls := []interface{ Call(proto.Client) error }{
+		proto.EmulationSetTouchEmulationEnabled{Enabled: false},
+		proto.EmulationSetLocaleOverride{},
+		proto.EmulationSetTimezoneOverride{},
+		proto.EmulationClearGeolocationOverride{},
+		proto.EmulationSetEmulatedMedia{Features: []*proto.EmulationMediaFeature{
+			{Name: "prefers-color-scheme", Value: ""},
+			{Name: "prefers-reduced-motion", Value: ""},
+		}},
+		proto.NetworkEmulateNetworkConditions{DownloadThroughput: -1, UploadThroughput: -1},
+	}
+	for _, call := range calls {
+		if err := call.Call(page); err != nil {
+			return fmt.Errorf("failed to reset emulation: %w", err)
+		}
+	}
+	return nil
+}
+
+// applyPageEmulation 为新打开的页面应用实例、网站配置和脚本中的仿真设置（脚本覆盖网站配置，网站配置覆盖实例配置）
+func applyPageEmulation(ctx context.Context, page *rod.Page, instance *models.BrowserInstance, config *models.BrowserConfig, script *models.EmulationProfile) {
+	var base, override *models.EmulationProfile
+	if instance != nil {
+		base = instance.Emulation
+	}
+	if config != nil {
+		override = config.Emulation
+	}
+	profile := MergeEmulationProfiles(base, override)
+	if script != nil {
+		profile = MergeEmulationProfiles(profile, script)
+	}
+	if profile == nil {
+		return
+	}
+	if err := ApplyEmulation(ctx, page, profile); err != nil {
+		logger.Warn(ctx, "Failed to apply emulation profile: %v", err)
+		return
+	}
+	logger.Info(ctx, "✓ Emulation profile applied (device: %q, locale: %q, timezone: %q)", profile.Device, profile.Locale, profile.Timezone)
+}
@@ -0,0 +1,70 @@
+package browser
+
+import (
+	"testing"
+
+	"github.com/browserwing/browserwing/models"
+)
+
+func TestValidateEmulationProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile *models.EmulationProfile
+		wantErr bool
+	}{
+		{name: "nil", profile: nil},
+		{name: "preset device", profile: &models.EmulationProfile{Device: "iphone x", Locale: "de-DE", Timezone: "Europe/Berlin"}},
+		{name: "network preset", profile: &models.EmulationProfile{Network: "slow-3g", ColorScheme: "dark", ReducedMotion: "reduce"}},
+		{name: "unknown device", profile: &models.EmulationProfile{Device: "Nokia 3310"}, wantErr: true},
+		{name: "unknown network", profile: &models.EmulationProfile{Network: "5g"}, wantErr: true},
+		{name: "bad color scheme", profile: &models.EmulationProfile{ColorScheme: "sepia"}, wantErr: true},
+		{name: "latitude out of range", profile: &models.EmulationProfile{Geolocation: &models.Geolocation{Latitude: 91}}, wantErr: true},
+		{name: "negative viewport", profile: &models.EmulationProfile{Width: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEmulationProfile(tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEmulationProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMergeEmulationProfiles(t *testing.T) {
+	touch := true
+	base := &models.EmulationProfile{Device: "Pixel 2", Locale: "en-US", Timezone: "America/New_York", Touch: &touch}
+	override := &models.EmulationProfile{Locale: "de-DE", Network: "offline"}
+
+	merged := MergeEmulationProfiles(base, override)
+	if merged.Device != "Pixel 2" || merged.Timezone != "America/New_York" || merged.Touch != &touch {
+		t.Errorf("base fields not kept: %+v", merged)
+	}
+	if merged.Locale != "de-DE" || merged.Network != "offline" {
+		t.Errorf("override fields not applied: %+v", merged)
+	}
+	if base.Locale != "en-US" {
+		t.Errorf("base profile was modified: %+v", base)
+	}
+	if MergeEmulationProfiles(nil, nil) != nil {
+		t.Error("merging two nil profiles should return nil")
+	}
+}
+
+func TestNetworkConditions(t *testing.T) {
+	if networkConditions(&models.EmulationProfile{}) != nil {
+		t.Error("no network emulation expected for an empty profile")
+	}
+	if c := networkConditions(&models.EmulationProfile{Network: "offline"}); c == nil || !c.Offline {
+		t.Errorf("offline conditions = %+v", c)
+	}
+
+	c := networkConditions(&models.EmulationProfile{Network: "fast-3g", LatencyMs: 100})
+	if c == nil || c.Latency != 100 || c.DownloadThroughput != 1440*1000/8 {
+		t.Errorf("fast-3g with custom latency = %+v", c)
+	}
+	if c := networkConditions(&models.EmulationProfile{DownloadKbps: 800}); c == nil || c.UploadThroughput != -1 {
+		t.Errorf("custom download only = %+v", c)
+	}
+}
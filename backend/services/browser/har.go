@@ -0,0 +1,306 @@
+package browser
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HAR 1.2 结构（http://www.softwareishard.com/blog/har-12-spec/）
+
+// HAR 根对象
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog 日志
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Pages   []HARPage  `json:"pages,omitempty"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator 生成工具信息
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HARPage 页面
+type HARPage struct {
+	StartedDateTime string         `json:"startedDateTime"`
+	ID              string         `json:"id"`
+	Title           string         `json:"title"`
+	PageTimings     HARPageTimings `json:"pageTimings"`
+}
+
+// HARPageTimings 页面耗时
+type HARPageTimings struct {
+	OnContentLoad float64 `json:"onContentLoad"`
+	OnLoad        float64 `json:"onLoad"`
+}
+
+// HAREntry 请求条目
+type HAREntry struct {
+	Pageref         string      `json:"pageref,omitempty"`
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	ResourceType    string      `json:"_resourceType,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+// HARRequest 请求
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse 响应
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARNameValue 名值对
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData 请求体
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARContent 响应内容
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// HARTimings 请求各阶段耗时（毫秒，-1 表示不适用）
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// BuildHAR 将网络日志条目转换为 HAR 1.2
+// bodies 可选，按请求 ID 提供响应体；page 可选，用于生成 pages 节点
+func BuildHAR(entries []NetworkEntry, bodies map[string]*NetworkBody, page *HARPage) *HAR {
+	har := &HAR{
+		Log: HARLog{
+			Version: "1.2",
+			Creator: HARCreator{Name: "BrowserWing", Version: "1.0"},
+			Entries: make([]HAREntry, 0, len(entries)),
+		},
+	}
+
+	pageRef := ""
+	if page != nil {
+		har.Log.Pages = []HARPage{*page}
+		pageRef = page.ID
+	}
+
+	for _, entry := range entries {
+		if entry.State == NetworkStatePending {
+			continue
+		}
+		harEntry := HAREntry{
+			Pageref:         pageRef,
+			StartedDateTime: entry.StartTime.Format(time.RFC3339Nano),
+			Request:         harRequest(entry),
+			Response:        harResponse(entry, bodies[entry.RequestID]),
+			Timings:         harTimings(entry),
+			ServerIPAddress: strings.Trim(entry.RemoteIPAddress, "[]"),
+			ResourceType:    strings.ToLower(entry.ResourceType),
+			Error:           entry.ErrorText,
+		}
+		harEntry.Time = harTotalTime(harEntry.Timings)
+		har.Log.Entries = append(har.Log.Entries, harEntry)
+	}
+
+	return har
+}
+
+func harRequest(entry NetworkEntry) HARRequest {
+	req := HARRequest{
+		Method:      entry.Method,
+		URL:         entry.URL,
+		HTTPVersion: harHTTPVersion(entry.Protocol),
+		Cookies:     []HARNameValue{},
+		Headers:     harHeaders(entry.RequestHeaders),
+		QueryString: []HARNameValue{},
+		HeadersSize: -1,
+		BodySize:    0,
+	}
+
+	if u, err := url.Parse(entry.URL); err == nil {
+		for name, values := range u.Query() {
+			for _, v := range values {
+				req.QueryString = append(req.QueryString, HARNameValue{Name: name, Value: v})
+			}
+		}
+		sort.Slice(req.QueryString, func(i, k int) bool { return req.QueryString[i].Name < req.QueryString[k].Name })
+	}
+
+	if entry.PostData != "" {
+		req.PostData = &HARPostData{
+			MimeType: headerValue(entry.RequestHeaders, "Content-Type"),
+			Text:     entry.PostData,
+		}
+		req.BodySize = len(entry.PostData)
+	}
+
+	return req
+}
+
+func harResponse(entry NetworkEntry, body *NetworkBody) HARResponse {
+	resp := HARResponse{
+		Status:      entry.Status,
+		StatusText:  entry.StatusText,
+		HTTPVersion: harHTTPVersion(entry.Protocol),
+		Cookies:     []HARNameValue{},
+		Headers:     harHeaders(entry.ResponseHeaders),
+		Content: HARContent{
+			Size:     -1,
+			MimeType: entry.MIMEType,
+		},
+		RedirectURL: entry.RedirectURL,
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+	if entry.State == NetworkStateFailed {
+		resp.Status = 0
+		resp.BodySize = -1
+	} else if entry.EncodedDataLength > 0 {
+		resp.BodySize = int(entry.EncodedDataLength)
+	}
+
+	if body != nil {
+		resp.Content.Text = body.Body
+		if body.Base64Encoded {
+			resp.Content.Encoding = "base64"
+		}
+		if text, err := body.Text(); err == nil {
+			resp.Content.Size = len(text)
+		}
+	}
+
+	return resp
+}
+
+// harTimings 根据 CDP ResourceTiming 计算各阶段耗时
+func harTimings(entry NetworkEntry) HARTimings {
+	t := entry.Timing
+	if t == nil {
+		return HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Send: 0, Wait: entry.DurationMs, Receive: 0}
+	}
+
+	timings := HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1}
+
+	// 发送请求前的阻塞时间：第一个有效阶段的起点
+	for _, start := range []float64{t.DNSStart, t.ConnectStart, t.SendStart} {
+		if start >= 0 {
+			timings.Blocked = start
+			break
+		}
+	}
+	if t.DNSStart >= 0 {
+		timings.DNS = t.DNSEnd - t.DNSStart
+	}
+	if t.ConnectStart >= 0 {
+		timings.Connect = t.ConnectEnd - t.ConnectStart
+	}
+	if t.SslStart >= 0 {
+		timings.SSL = t.SslEnd - t.SslStart
+	}
+	timings.Send = nonNegative(t.SendEnd - t.SendStart)
+	timings.Wait = nonNegative(t.ReceiveHeadersEnd - t.SendEnd)
+
+	// 接收时间：从响应头到请求结束
+	if entry.endMono > 0 && t.RequestTime > 0 {
+		total := (float64(entry.endMono) - t.RequestTime) * 1000
+		timings.Receive = nonNegative(total - t.ReceiveHeadersEnd)
+	}
+
+	return timings
+}
+
+// harTotalTime 总耗时为各有效阶段之和（ssl 已包含在 connect 中）
+func harTotalTime(t HARTimings) float64 {
+	total := 0.0
+	for _, v := range []float64{t.Blocked, t.DNS, t.Connect, t.Send, t.Wait, t.Receive} {
+		if v > 0 {
+			total += v
+		}
+	}
+	return total
+}
+
+func harHeaders(headers map[string]string) []HARNameValue {
+	result := make([]HARNameValue, 0, len(headers))
+	for name, value := range headers {
+		result = append(result, HARNameValue{Name: name, Value: value})
+	}
+	sort.Slice(result, func(i, k int) bool { return result[i].Name < result[k].Name })
+	return result
+}
+
+func harHTTPVersion(protocol string) string {
+	switch strings.ToLower(protocol) {
+	case "h2", "http/2", "http/2.0":
+		return "HTTP/2"
+	case "h3", "http/3", "quic":
+		return "HTTP/3"
+	case "http/1.0":
+		return "HTTP/1.0"
+	default:
+		return "HTTP/1.1"
+	}
+}
+
+// headerValue 不区分大小写地读取头部
+func headerValue(headers map[string]string, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+func nonNegative(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,424 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/browserwing/browserwing/models"
+	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/go-rod/rod"
+)
+
+const (
+	defaultLoopMaxIterations = 100
+	maxLoopIterations        = 1000
+)
+
+// elementXPathScript 计算元素的绝对 XPath（用于循环中定位当前元素）
+const elementXPathScript = `() => {
+	const parts = [];
+	let current = this;
+	while (current && current.nodeType === Node.ELEMENT_NODE && current !== document.documentElement) {
+		let index = 1;
+		let sibling = current.previousElementSibling;
+		while (sibling) {
+			if (sibling.tagName === current.tagName) index++;
+			sibling = sibling.previousElementSibling;
+		}
+		parts.unshift(current.tagName.toLowerCase() + '[' + index + ']');
+		current = current.parentElement;
+	}
+	return '/html/' + parts.join('/');
+}`
+
+// loopSettings 循环实际生效的配置
+type loopSettings struct {
+	maxIterations int
+	itemVar       string
+	indexVar      string
+}
+
+func resolveLoopSettings(loop *models.ActionLoop) loopSettings {
+	settings := loopSettings{maxIterations: defaultLoopMaxIterations, itemVar: "item", indexVar: "index"}
+	if loop.MaxIterations > 0 {
+		settings.maxIterations = loop.MaxIterations
+	}
+	if settings.maxIterations > maxLoopIterations {
+		settings.maxIterations = maxLoopIterations
+	}
+	if loop.ItemVariable != "" {
+		settings.itemVar = loop.ItemVariable
+	}
+	if loop.IndexVariable != "" {
+		settings.indexVar = loop.IndexVariable
+	}
+	return settings
+}
+
+// ValidateLoopAction 校验循环操作的配置（包括嵌套循环）
+func ValidateLoopAction(action models.ScriptAction) error {
+	if !models.IsLoopAction(action.Type) {
+		return nil
+	}
+	loop := action.Loop
+	if loop == nil {
+		return fmt.Errorf("%s requires loop settings", action.Type)
+	}
+	if len(loop.Actions) == 0 {
+		return fmt.Errorf("%s requires at least one action in the loop body", action.Type)
+	}
+	switch action.Type {
+	case models.ActionTypeLoopElements:
+		if action.Selector == "" && action.XPath == "" {
+			return fmt.Errorf("loop_elements requires selector or xpath")
+		}
+	case models.ActionTypeLoopList:
+		if loop.ListVariable == "" {
+			return fmt.Errorf("loop_list requires loop.list_variable")
+		}
+	case models.ActionTypeLoopWhile:
+		if loop.Condition == nil && action.Selector == "" && action.XPath == "" {
+			return fmt.Errorf("loop_while requires loop.condition or a selector")
+		}
+	}
+	for i, body := range loop.Actions {
+		if err := ValidateActionPolicy(body.OnError); err != nil {
+			return fmt.Errorf("loop action %d: %w", i+1, err)
+		}
+		if err := ValidateLoopAction(body); err != nil {
+			return fmt.Errorf("loop action %d: %w", i+1, err)
+		}
+		if err := ValidateCallScriptAction("", body); err != nil {
+			return fmt.Errorf("loop action %d: %w", i+1, err)
+		}
+		if err := ValidateAssertAction(body, action.Type != models.ActionTypeLoopElements); err != nil {
+			return fmt.Errorf("loop action %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// executeLoop 执行循环操作
+func (p *Player) executeLoop(ctx context.Context, page *rod.Page, action models.ScriptAction) error {
+	if err := ValidateLoopAction(action); err != nil {
+		return err
+	}
+	settings := resolveLoopSettings(action.Loop)
+	if p.variables == nil {
+		p.variables = make(map[string]string)
+	}
+	if p.extractedData == nil {
+		p.extractedData = make(map[string]interface{})
+	}
+
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+
+	collected := make(map[string][]interface{})
+	iterations := 0
+	var err error
+
+	switch action.Type {
+	case models.ActionTypeLoopElements:
+		iterations, err = p.loopElements(ctx, page, action, settings, collected)
+	case models.ActionTypeLoopList:
+		iterations, err = p.loopList(ctx, page, action, settings, collected)
+	case models.ActionTypeLoopWhile:
+		iterations, err = p.loopWhile(ctx, page, action, settings, collected)
+	}
+
+	if p.loopDepth == 1 {
+		p.loopIterations = iterations
+	}
+	if action.Loop.Collect {
+		for name, values := range collected {
+			p.extractedData[name] = values
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s stopped at iteration %d: %w", action.Type, iterations, err)
+	}
+
+	logger.Info(ctx, "✓ %s completed after %d iteration(s)", action.Type, iterations)
+	return nil
+}
+
+// loopElements 遍历选择器匹配的元素
+// 每次迭代都会重新查询元素，循环体中的操作可能改变页面结构
+func (p *Player) loopElements(ctx context.Context, page *rod.Page, action models.ScriptAction, settings loopSettings, collected map[string][]interface{}) (int, error) {
+	loopPage := p.activeLoopPage(page)
+	elements, err := queryLoopElements(loopPage, action)
+	if err != nil {
+		return 0, err
+	}
+	total := len(elements)
+	if total > settings.maxIterations {
+		logger.Warn(ctx, "loop_elements matched %d elements, limited to %d", total, settings.maxIterations)
+		total = settings.maxIterations
+	}
+	logger.Info(ctx, "loop_elements: %d element(s) to iterate", total)
+
+	for i := 0; i < total; i++ {
+		elements, err := queryLoopElements(loopPage, action)
+		if err != nil {
+			return i, err
+		}
+		if i >= len(elements) {
+			logger.Warn(ctx, "loop_elements: only %d element(s) left, stopping", len(elements))
+			return i, nil
+		}
+		element := elements[i]
+
+		itemXPath := ""
+		xpathScript := elementXPathScript
+		if IsShadowLocator(action.XPath) || IsShadowLocator(action.Selector) {
+			xpathScript = ShadowXPathScript // shadowRoot 内的元素需要穿透路径
+		}
+		if res, err := element.Eval(xpathScript); err == nil {
+			itemXPath = res.Value.Str()
+		}
+		text, _ := element.Text()
+
+		scope := p.loopScope(settings, i, strings.TrimSpace(text))
+		scope[settings.itemVar+"_xpath"] = itemXPath
+		if err := p.runNestedActions(ctx, page, action.Loop.Actions, scope, itemXPath, collected); err != nil {
+			return i + 1, err
+		}
+	}
+	return total, nil
+}
+
+// loopList 遍历列表变量
+func (p *Player) loopList(ctx context.Context, page *rod.Page, action models.ScriptAction, settings loopSettings, collected map[string][]interface{}) (int, error) {
+	name := action.Loop.ListVariable
+	var raw interface{}
+	if value, ok := p.extractedData[name]; ok {
+		raw = value
+	} else if value, ok := p.variables[name]; ok {
+		raw = value
+	} else {
+		return 0, fmt.Errorf("list variable not found: %s", name)
+	}
+
+	items, err := parseLoopList(raw)
+	if err != nil {
+		return 0, fmt.Errorf("list variable %s: %w", name, err)
+	}
+	total := len(items)
+	if total > settings.maxIterations {
+		logger.Warn(ctx, "loop_list has %d items, limited to %d", total, settings.maxIterations)
+		total = settings.maxIterations
+	}
+	logger.Info(ctx, "loop_list: %d item(s) in %s", total, name)
+
+	for i := 0; i < total; i++ {
+		scope := p.loopScope(settings, i, loopItemString(items[i]))
+		// 对象元素可以通过 ${item.字段名} 访问字段
+		if obj, ok := items[i].(map[string]interface{}); ok {
+			for key, value := range obj {
+				scope[settings.itemVar+"."+key] = loopItemString(value)
+			}
+		}
+		if err := p.runNestedActions(ctx, page, action.Loop.Actions, scope, "", collected); err != nil {
+			return i + 1, err
+		}
+	}
+	return total, nil
+}
+
+// loopWhile 条件满足时重复执行循环体
+func (p *Player) loopWhile(ctx context.Context, page *rod.Page, action models.ScriptAction, settings loopSettings, collected map[string][]interface{}) (int, error) {
+	for i := 0; i < settings.maxIterations; i++ {
+		if err := ctx.Err(); err != nil {
+			return i, err
+		}
+
+		scope := p.loopScope(settings, i, "")
+		ok, err := p.loopWhileHolds(ctx, page, action, scope)
+		if err != nil {
+			return i, err
+		}
+		if !ok {
+			return i, nil
+		}
+
+		if err := p.runNestedActions(ctx, page, action.Loop.Actions, scope, "", collected); err != nil {
+			return i + 1, err
+		}
+	}
+
+	logger.Warn(ctx, "loop_while reached max iterations (%d), stopping", settings.maxIterations)
+	return settings.maxIterations, nil
+}
+
+// loopWhileHolds 判断 loop_while 是否继续
+func (p *Player) loopWhileHolds(ctx context.Context, page *rod.Page, action models.ScriptAction, scope map[string]string) (bool, error) {
+	if cond := action.Loop.Condition; cond != nil {
+		resolved := *cond
+		resolved.Value = models.SubstituteVariables(cond.Value, scope)
+		ok, err := p.evaluateCondition(ctx, &resolved, scope)
+		if err != nil {
+			// 变量尚不存在时视为条件不满足
+			logger.Warn(ctx, "loop_while condition evaluation failed, stopping: %v", err)
+			return false, nil
+		}
+		return ok, nil
+	}
+
+	elements, err := queryLoopElements(p.activeLoopPage(page).Timeout(3*time.Second), action)
+	if err != nil {
+		return false, nil
+	}
+	return len(elements) > 0, nil
+}
+
+// runNestedActions 执行一组嵌套步骤（循环体或子脚本）
+// 每个步骤执行前用当前作用域替换 ${变量}；未设置选择器的步骤在 loop_elements 中作用于当前元素
+func (p *Player) runNestedActions(ctx context.Context, page *rod.Page, body []models.ScriptAction, scope map[string]string, itemXPath string, collected map[string][]interface{}) error {
+	for j, bodyAction := range body {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		step := bodyAction.WithVariables(scope)
+		if itemXPath != "" && step.Selector == "" && step.XPath == "" && !models.IsLoopAction(step.Type) {
+			step.XPath = itemXPath
+		}
+
+		if step.Condition != nil && step.Condition.Enabled {
+			ok, err := p.evaluateCondition(ctx, step.Condition, scope)
+			if err != nil {
+				logger.Warn(ctx, "Failed to evaluate nested step condition: %v", err)
+			} else if !ok {
+				continue
+			}
+		}
+
+		policy := resolveActionPolicy(p.currentScript, step)
+		p.nestedSteps = append(p.nestedSteps, j)
+		_, _, err := p.runActionWithPolicy(ctx, page, step, policy)
+		p.nestedSteps = p.nestedSteps[:len(p.nestedSteps)-1]
+		if err != nil {
+			assertErr, isAssertion := asAssertionError(err)
+			if isAssertion && assertErr.IsSoft() {
+				p.recordSoftAssertion(ctx, fmt.Sprintf("nested step %d", j+1), assertErr)
+				continue
+			}
+			if policy.OnError == models.OnErrorContinue && !isAssertion {
+				logger.Warn(ctx, "Nested step %d (%s) failed (continuing): %v", j+1, step.Type, err)
+				continue
+			}
+			return fmt.Errorf("nested step %d (%s) failed: %w", j+1, step.Type, err)
+		}
+
+		// 抓取的数据同时更新循环作用域和回放变量，后续步骤可以引用
+		if step.VariableName != "" {
+			if value, ok := p.extractedData[step.VariableName]; ok && value != nil {
+				str := fmt.Sprintf("%v", value)
+				scope[step.VariableName] = str
+				p.variables[step.VariableName] = str
+				collected[step.VariableName] = append(collected[step.VariableName], value)
+			}
+		}
+	}
+	return nil
+}
+
+// loopScope 创建一次迭代的变量作用域（继承回放变量）
+func (p *Player) loopScope(settings loopSettings, index int, item string) map[string]string {
+	scope := make(map[string]string, len(p.variables)+2)
+	for k, v := range p.variables {
+		scope[k] = v
+	}
+	scope[settings.indexVar] = fmt.Sprintf("%d", index)
+	scope[settings.itemVar] = item
+	return scope
+}
+
+// activeLoopPage 循环中使用的页面（跨标签页操作后以当前标签页为准）
+func (p *Player) activeLoopPage(page *rod.Page) *rod.Page {
+	if p.currentPage != nil {
+		return p.currentPage
+	}
+	return page
+}
+
+// queryLoopElements 查询循环选择器匹配的所有元素（XPath 优先）
+func queryLoopElements(page *rod.Page, action models.ScriptAction) (rod.Elements, error) {
+	locator := action.XPath
+	if locator == "" {
+		locator = action.Selector
+	}
+	if IsShadowLocator(locator) {
+		return FindShadowElements(page, locator)
+	}
+	if action.XPath != "" {
+		return page.ElementsX(action.XPath)
+	}
+	return page.Elements(action.Selector)
+}
+
+// parseLoopList 将列表变量解析为元素数组
+// 支持抓取得到的数组、JSON 数组字符串以及逗号分隔的字符串
+func parseLoopList(raw interface{}) ([]interface{}, error) {
+	switch v := raw.(type) {
+	case []interface{}:
+		return v, nil
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return items, nil
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if trimmed == "" {
+			return nil, nil
+		}
+		if strings.HasPrefix(trimmed, "[") {
+			var items []interface{}
+			if err := json.Unmarshal([]byte(trimmed), &items); err != nil {
+				return nil, fmt.Errorf("invalid JSON array: %w", err)
+			}
+			return items, nil
+		}
+		parts := strings.Split(trimmed, ",")
+		items := make([]interface{}, 0, len(parts))
+		for _, part := range parts {
+			items = append(items, strings.TrimSpace(part))
+		}
+		return items, nil
+	default:
+		// 其他类型（例如 execute_js 返回的结构）尝试通过 JSON 转换
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported list type %T", raw)
+		}
+		var items []interface{}
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, fmt.Errorf("unsupported list type %T", raw)
+		}
+		return items, nil
+	}
+}
+
+// loopItemString 将列表元素转换为字符串（对象和数组使用 JSON）
+func loopItemString(item interface{}) string {
+	switch v := item.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
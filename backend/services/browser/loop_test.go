@@ -0,0 +1,66 @@
+package browser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/browserwing/browserwing/models"
+)
+
+func TestParseLoopList(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  interface{}
+		want []interface{}
+	}{
+		{name: "array", raw: []interface{}{"a", float64(1)}, want: []interface{}{"a", float64(1)}},
+		{name: "string slice", raw: []string{"x", "y"}, want: []interface{}{"x", "y"}},
+		{name: "json array", raw: `["go", "rod"]`, want: []interface{}{"go", "rod"}},
+		{name: "comma separated", raw: "a, b ,c", want: []interface{}{"a", "b", "c"}},
+		{name: "empty", raw: "  ", want: nil},
+		{name: "objects", raw: []map[string]interface{}{{"id": "1"}}, want: []interface{}{map[string]interface{}{"id": "1"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLoopList(tt.raw)
+			if err != nil {
+				t.Fatalf("parseLoopList(%v) error: %v", tt.raw, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLoopList(%v) = %#v, want %#v", tt.raw, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseLoopList("[not json"); err == nil {
+		t.Error("parseLoopList should reject an invalid JSON array")
+	}
+}
+
+func TestValidateLoopAction(t *testing.T) {
+	body := []models.ScriptAction{{Type: "click"}}
+	tests := []struct {
+		name    string
+		action  models.ScriptAction
+		wantErr bool
+	}{
+		{name: "not a loop", action: models.ScriptAction{Type: "click"}},
+		{name: "elements", action: models.ScriptAction{Type: models.ActionTypeLoopElements, XPath: "//li", Loop: &models.ActionLoop{Actions: body}}},
+		{name: "elements without selector", action: models.ScriptAction{Type: models.ActionTypeLoopElements, Loop: &models.ActionLoop{Actions: body}}, wantErr: true},
+		{name: "list without variable", action: models.ScriptAction{Type: models.ActionTypeLoopList, Loop: &models.ActionLoop{Actions: body}}, wantErr: true},
+		{name: "while with condition", action: models.ScriptAction{Type: models.ActionTypeLoopWhile, Loop: &models.ActionLoop{Condition: &models.ActionCondition{Variable: "page", Operator: "<", Value: "5"}, Actions: body}}},
+		{name: "missing loop", action: models.ScriptAction{Type: models.ActionTypeLoopWhile, Selector: ".next"}, wantErr: true},
+		{name: "empty body", action: models.ScriptAction{Type: models.ActionTypeLoopList, Loop: &models.ActionLoop{ListVariable: "ids"}}, wantErr: true},
+		{name: "invalid nested policy", action: models.ScriptAction{Type: models.ActionTypeLoopList, Loop: &models.ActionLoop{ListVariable: "ids", Actions: []models.ScriptAction{{Type: "click", OnError: "explode"}}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateLoopAction(tt.action)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateLoopAction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
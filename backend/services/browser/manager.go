@@ -132,6 +132,19 @@ type Manager struct {
 	instances         map[string]*BrowserInstanceRuntime // 实例 ID -> 运行时信息
 	currentInstanceID string                             // 当前活动实例 ID
 
+	// 页面观测（控制台、网络）和请求拦截，按 TargetID 索引
+	pageMonitorMu sync.Mutex
+	pageMonitors  map[proto.TargetTargetID]*PageMonitor
+	pageRouters   map[proto.TargetTargetID]*RequestRouter
+
+	// 回放并发控制（按实例）和无痕上下文（按回放页面 TargetID）
+	playbackMu        sync.Mutex
+	playbackPools     map[string]*playbackPool
+	incognitoContexts map[proto.TargetTargetID]*rod.Browser
+	activePageMu      sync.Mutex // 并发回放中需要独占 activePage 的步骤（如 AI 控制）依次执行
+
+	debugSessions *DebugSessions // 调试回放会话
+
 	// 共享配置
 	defaultBrowserConfig   *models.BrowserConfig   // 默认浏览器配置
 	siteConfigs            []*models.BrowserConfig // 网站特定配置列表
@@ -169,11 +182,17 @@ func NewManager(cfg *config.Config, db *storage.BoltDB, llmManager *llm.Manager)
 	}
 
 	return &Manager{
-		config:     cfg,
-		db:         db,
-		llmManager: llmManager,
-		recorder:   recorder,
-		instances:  make(map[string]*BrowserInstanceRuntime),
+		config:       cfg,
+		db:           db,
+		llmManager:   llmManager,
+		recorder:     recorder,
+		instances:    make(map[string]*BrowserInstanceRuntime),
+		pageMonitors: make(map[proto.TargetTargetID]*PageMonitor),
+		pageRouters:  make(map[proto.TargetTargetID]*RequestRouter),
+
+		playbackPools:     make(map[string]*playbackPool),
+		incognitoContexts: make(map[proto.TargetTargetID]*rod.Browser),
+		debugSessions:     NewDebugSessions(),
 	}
 }
 
@@ -447,26 +466,12 @@ func (m *Manager) Start(ctx context.Context) error {
 	if m.db != nil {
 		cookieStore, err := m.db.GetCookies("browser")
 		if err == nil && cookieStore != nil && len(cookieStore.Cookies) > 0 {
-			// 将 NetworkCookie 转换为 NetworkCookieParam
-			cookieParams := make([]*proto.NetworkCookieParam, 0, len(cookieStore.Cookies))
-			for _, cookie := range cookieStore.Cookies {
-				cookieParams = append(cookieParams, &proto.NetworkCookieParam{
-					Name:     cookie.Name,
-					Value:    cookie.Value,
-					Domain:   cookie.Domain,
-					Path:     cookie.Path,
-					Secure:   cookie.Secure,
-					HTTPOnly: cookie.HTTPOnly,
-					SameSite: cookie.SameSite,
-					Expires:  cookie.Expires,
-				})
-			}
-
-			// 设置 Cookie 到浏览器
-			if err := browser.SetCookies(cookieParams); err != nil {
+			// 将 NetworkCookie 转换为 NetworkCookieParam 并设置到浏览器
+			params := cookieParams(cookieStore.Cookies)
+			if err := browser.SetCookies(params); err != nil {
 				logger.Warn(ctx, "Failed to set Cookie: %v", err)
 			} else {
-				logger.Info(ctx, "Loaded %d saved Cookies", len(cookieParams))
+				logger.Info(ctx, "Loaded %d saved Cookies", len(params))
 			}
 		} else {
 			logger.Info(ctx, "No saved Cookies found")
@@ -667,8 +672,15 @@ func (m *Manager) CloseActivePage(ctx context.Context, page *rod.Page) error {
 	}
 
 	logger.Info(ctx, "Closing active page...")
-	if err := page.Close(); err != nil {
-		return fmt.Errorf("failed to close active page: %w", err)
+	// 关闭失败（如页面已随浏览器上下文销毁）也要释放无痕上下文和观测器
+	closeErr := page.Close()
+	m.releasePlaybackContext(page)
+	m.releasePageMonitor(page.TargetID)
+	if m.activePage == page {
+		m.activePage = nil
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to close active page: %w", closeErr)
 	}
 
 	logger.Info(ctx, "Active page closed")
@@ -829,6 +841,9 @@ func (m *Manager) OpenPage(url string, language string, instanceID string, norec
 
 	m.setPageWindow(page)
 
+	// 在导航前挂载页面观测器，保留页面加载期间的控制台消息
+	m.monitorPage(instanceID, page)
+
 	// 设置 User Agent
 	userAgent := config.UserAgent
 	if userAgent == "" {
@@ -838,6 +853,9 @@ func (m *Manager) OpenPage(url string, language string, instanceID string, norec
 		UserAgent: userAgent,
 	})
 
+	// 应用仿真配置（设备、语言、时区、地理位置、网络条件等）
+	applyPageEmulation(ctx, page, m.playbackSettings(instanceID, instance), config, nil)
+
 	// 导航到目标 URL（设置60秒超时）- 这是耗时操作，不持有锁
 	if err := page.Timeout(60 * time.Second).Navigate(url); err != nil {
 		return fmt.Errorf("failed to navigate to page: %w", err)
@@ -1075,9 +1093,31 @@ func (m *Manager) ClearInPageRecordingState() {
 	m.mu.Unlock()
 }
 
+// PlayOptions 单次回放的附加选项
+type PlayOptions struct {
+	RouteRules     []models.RouteRule // 本次回放的请求拦截规则，优先于脚本自带规则匹配
+	StorageStateID string             // 回放前恢复的存储状态，覆盖脚本设置的 storage_state_id
+	Debugger       *DebugSession      // 调试会话，非空时按断点/单步暂停并等待调试命令
+}
+
+// DebugSessions 返回调试回放会话注册表
+func (m *Manager) DebugSessions() *DebugSessions {
+	return m.debugSessions
+}
+
 // PlayScript 回放脚本
 // instanceID: 指定实例ID，空字符串表示使用当前实例
-func (m *Manager) PlayScript(ctx context.Context, script *models.Script, instanceID string) (result *models.PlayResult, page *rod.Page, err error) {
+func (m *Manager) PlayScript(ctx context.Context, script *models.Script, instanceID string) (*models.PlayResult, *rod.Page, error) {
+	return m.PlayScriptWithOptions(ctx, script, instanceID, nil)
+}
+
+// PlayScriptWithOptions 使用附加选项回放脚本
+// 回放失败（包括 panic）时页面已在内部关闭并释放无痕上下文，返回的 page 为 nil；成功时由调用方关闭页面
+func (m *Manager) PlayScriptWithOptions(ctx context.Context, script *models.Script, instanceID string, opts *PlayOptions) (result *models.PlayResult, page *rod.Page, err error) {
+	if opts == nil {
+		opts = &PlayOptions{}
+	}
+
 	// 捕获 panic 并转换为错误
 	defer func() {
 		if r := recover(); r != nil {
@@ -1088,8 +1128,12 @@ func (m *Manager) PlayScript(ctx context.Context, script *models.Script, instanc
 		}
 	}()
 
-	// 获取指定实例的浏览器
+	// 获取指定实例的浏览器（需要锁保护）
+	m.mu.Lock()
 	browser, _, instance, err := m.getInstanceBrowser(instanceID)
+	currentInstanceID := m.currentInstanceID
+	currentLanguage := m.currentLanguage
+	m.mu.Unlock()
 	if err != nil {
 		return nil, nil, err
 	}
@@ -1108,20 +1152,29 @@ func (m *Manager) PlayScript(ctx context.Context, script *models.Script, instanc
 		instanceName = instance.Name
 	} else if usedInstanceID == "" {
 		// 向后兼容：如果没有 instance 对象，使用 currentInstanceID
-		usedInstanceID = m.currentInstanceID
+		usedInstanceID = currentInstanceID
 	}
 
+	// 按实例的并发上限排队，每次回放使用独立的页面和 Player 状态
+	settings := m.playbackSettings(usedInstanceID, instance)
+	release, err := m.playbackPool(usedInstanceID, settings).acquire(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cancelled while waiting for a playback slot: %w", err)
+	}
+	defer release()
+
 	// 创建执行记录
 	executionID := fmt.Sprintf("%s-%d", script.ID, time.Now().UnixNano())
 	execution := &models.ScriptExecution{
-		ID:           executionID,
-		ScriptID:     script.ID,
-		ScriptName:   script.Name,
-		InstanceID:   usedInstanceID,
-		InstanceName: instanceName,
-		StartTime:    time.Now(),
-		TotalSteps:   len(script.Actions),
-		CreatedAt:    time.Now(),
+		ID:             executionID,
+		ScriptID:       script.ID,
+		ScriptName:     script.Name,
+		ScriptRevision: script.Revision,
+		InstanceID:     usedInstanceID,
+		InstanceName:   instanceName,
+		StartTime:      time.Now(),
+		TotalSteps:     len(script.Actions),
+		CreatedAt:      time.Now(),
 	}
 
 	// 根据脚本的URL匹配配置
@@ -1141,16 +1194,46 @@ func (m *Manager) PlayScript(ctx context.Context, script *models.Script, instanc
 		useStealth = *config.UseStealth
 	}
 
-	if useStealth {
-		page = stealth.MustPage(browser)
-		logger.Info(ctx, "Replay using Stealth mode")
-	} else {
-		page = browser.MustPage()
-		logger.Info(ctx, "Replay not using Stealth mode")
+	page, pageBrowser, err := m.newPlaybackPage(ctx, browser, settings, useStealth)
+	if err != nil {
+		return nil, nil, err
 	}
+	playbackPage := page
+	succeeded := false
+	defer func() {
+		if !succeeded {
+			m.closePlaybackPage(ctx, playbackPage)
+		}
+	}()
 
 	m.setPageWindow(page)
 
+	// 挂载页面观测器，回放期间的控制台消息可通过执行器查询
+	m.monitorPage(usedInstanceID, page)
+
+	// 在导航前恢复存储状态（Cookie、localStorage、sessionStorage、IndexedDB）
+	storageStateID := opts.StorageStateID
+	if storageStateID == "" {
+		storageStateID = script.StorageStateID
+	}
+	if storageStateID != "" {
+		state, err := m.LoadStorageState(storageStateID)
+		if err == nil {
+			err = m.ApplyPageStorageState(ctx, page, state)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to apply storage state %s: %w", storageStateID, err)
+		}
+	}
+
+	// 在导航前启用请求拦截规则（本次回放的规则优先）
+	routeRules := append(append([]models.RouteRule{}, opts.RouteRules...), script.RouteRules...)
+	if len(routeRules) > 0 {
+		if err := m.SetPageRouteRules(page, routeRules); err != nil {
+			return nil, nil, fmt.Errorf("invalid route rules: %w", err)
+		}
+	}
+
 	// 设置 User Agent
 	userAgent := config.UserAgent
 	if userAgent == "" {
@@ -1160,6 +1243,9 @@ func (m *Manager) PlayScript(ctx context.Context, script *models.Script, instanc
 		UserAgent: userAgent,
 	})
 
+	// 应用仿真配置（设备、语言、时区、地理位置、网络条件等），脚本中的设置优先
+	applyPageEmulation(ctx, page, settings, config, script.Emulation)
+
 	// 为回放页面授予剪贴板权限
 	if scriptURL != "" {
 		grantPlayPermissions := &proto.BrowserGrantPermissions{
@@ -1168,6 +1254,7 @@ func (m *Manager) PlayScript(ctx context.Context, script *models.Script, instanc
 				proto.BrowserPermissionTypeClipboardReadWrite,
 				proto.BrowserPermissionTypeClipboardSanitizedWrite,
 			},
+			BrowserContextID: pageBrowser.BrowserContextID,
 		}
 		if err := grantPlayPermissions.Call(browser); err != nil {
 			logger.Warn(ctx, "Failed to grant clipboard permissions for playback: %v", err)
@@ -1177,18 +1264,28 @@ func (m *Manager) PlayScript(ctx context.Context, script *models.Script, instanc
 	}
 
 	// 创建播放器，传入当前语言设置
-	currentLang := m.currentLanguage
+	currentLang := currentLanguage
 	if currentLang == "" {
 		currentLang = "zh-CN" // 默认简体中文
 	}
 	player := NewPlayer(currentLang)
 	player.agentManager = m.agentManager // 设置 Agent 管理器用于 AI 控制功能
 	player.browserManager = m            // 设置 Browser 管理器用于同步活跃页面
+	player.routeRules = routeRules       // 回放中新打开的标签页沿用拦截规则
+	player.debugger = opts.Debugger
+	if m.db != nil {
+		player.SetScriptLoader(m.db.GetScript) // call_script 从数据库加载被调用的脚本
+	}
+
+	// 开启失败截图时，截图保存到下载目录下按执行记录划分的子目录
+	if script.ScreenshotOnFailure && m.downloadPath != "" {
+		player.SetTraceScreenshotDir(filepath.Join(m.downloadPath, "traces", executionID))
+	}
 
 	// 设置下载路径并启动下载监听
 	if m.downloadPath != "" {
 		player.SetDownloadPath(m.downloadPath)
-		player.StartDownloadListener(ctx, browser)
+		player.StartDownloadListener(ctx, browser, page)
 		logger.Info(ctx, "Download tracking enabled for playback, path: %s", m.downloadPath)
 	}
 
@@ -1228,7 +1325,7 @@ func (m *Manager) PlayScript(ctx context.Context, script *models.Script, instanc
 	}
 
 	// 执行回放
-	playErr := player.PlayScript(ctx, page, script, m.currentLanguage)
+	playErr := player.PlayScript(ctx, page, script, currentLanguage)
 
 	// 停止下载监听
 	if m.downloadPath != "" {
@@ -1254,6 +1351,16 @@ func (m *Manager) PlayScript(ctx context.Context, script *models.Script, instanc
 	execution.SuccessSteps = player.GetSuccessCount()
 	execution.FailedSteps = player.GetFailCount()
 	execution.ExtractedData = player.GetExtractedData()
+	execution.AbortedStep = player.GetAbortedStep()
+	execution.Steps = player.GetStepTraces()
+
+	// 按脚本配置将自愈后的定位写回
+	if healed := player.GetHealedLocators(); len(healed) > 0 {
+		logger.Info(ctx, "Self-healed %d step(s) during playback", len(healed))
+		if script.SaveHealedSelectors {
+			m.persistHealedLocators(ctx, script.ID, healed)
+		}
+	}
 
 	// 判断是否成功
 	if playErr != nil {
@@ -1276,11 +1383,14 @@ func (m *Manager) PlayScript(ctx context.Context, script *models.Script, instanc
 
 	// 如果执行失败，返回错误
 	if playErr != nil {
+		// 软断言失败信息在前，最后是导致回放失败的错误
+		errs := append(append([]string{}, player.GetAssertionFailures()...), playErr.Error())
 		return &models.PlayResult{
-			Success: false,
-			Message: playErr.Error(),
-			Errors:  []string{playErr.Error()},
-		}, page, playErr
+			Success:     false,
+			Message:     playErr.Error(),
+			Errors:      errs,
+			AbortedStep: player.GetAbortedStep(),
+		}, nil, playErr
 	}
 
 	// 返回回放结果，包含抓取的数据
@@ -1304,6 +1414,7 @@ func (m *Manager) PlayScript(ctx context.Context, script *models.Script, instanc
 		}
 	}
 
+	succeeded = true
 	return &models.PlayResult{
 		Success:       true,
 		Message:       "Script replay completed",
@@ -2270,6 +2381,9 @@ func (m *Manager) watchForNewPagesXHR(ctx context.Context, browser *rod.Browser,
 				// 标记为已处理
 				processedPages[targetID] = true
 
+				// 挂载页面观测器（由页面自身打开的新标签页等）
+				m.monitorPage(instanceID, page)
+
 				logger.Info(ctx, "Injecting XHR interceptor into new page: %s (URL: %s)", targetID, pageInfo.URL)
 
 				// 为新页面设置EvalOnNewDocument（影响该页面内的iframe和导航）
@@ -0,0 +1,381 @@
+package browser
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// defaultNetworkJournalSize 每个页面保留的网络请求条目上限
+const defaultNetworkJournalSize = 1000
+
+// 网络请求状态
+const (
+	NetworkStatePending  = "pending"
+	NetworkStateFinished = "finished"
+	NetworkStateFailed   = "failed"
+)
+
+// NetworkEntry 一次网络请求的完整记录（由 requestWillBeSent / responseReceived / loadingFinished / loadingFailed 关联而成）
+type NetworkEntry struct {
+	Seq               int64                        `json:"seq"`        // 单调递增序号，可作为增量拉取游标
+	RequestID         string                       `json:"request_id"` // CDP 请求 ID（重定向链共享同一 ID）
+	URL               string                       `json:"url"`
+	Method            string                       `json:"method"`
+	ResourceType      string                       `json:"resource_type,omitempty"` // Document, XHR, Fetch, Script ...
+	State             string                       `json:"state"`                   // pending, finished, failed
+	RequestHeaders    map[string]string            `json:"request_headers,omitempty"`
+	PostData          string                       `json:"post_data,omitempty"`
+	HasPostData       bool                         `json:"has_post_data,omitempty"`
+	Status            int                          `json:"status,omitempty"`
+	StatusText        string                       `json:"status_text,omitempty"`
+	ResponseHeaders   map[string]string            `json:"response_headers,omitempty"`
+	MIMEType          string                       `json:"mime_type,omitempty"`
+	Protocol          string                       `json:"protocol,omitempty"`
+	RemoteIPAddress   string                       `json:"remote_ip_address,omitempty"`
+	FromCache         bool                         `json:"from_cache,omitempty"`
+	EncodedDataLength int64                        `json:"encoded_data_length,omitempty"`
+	RedirectURL       string                       `json:"redirect_url,omitempty"` // 该请求被重定向到的地址
+	ErrorText         string                       `json:"error_text,omitempty"`
+	Canceled          bool                         `json:"canceled,omitempty"`
+	StartTime         time.Time                    `json:"start_time"`
+	DurationMs        float64                      `json:"duration_ms,omitempty"`
+	Timing            *proto.NetworkResourceTiming `json:"timing,omitempty"`
+
+	startMono proto.MonotonicTime // 请求发出时的单调时间，用于计算耗时
+	endMono   proto.MonotonicTime
+}
+
+// NetworkQuery 网络请求查询条件
+type NetworkQuery struct {
+	URL           string   // URL 子串匹配（不区分大小写）
+	Method        string   // 请求方法
+	StatusMin     int      // 状态码下限（0 表示不限）
+	StatusMax     int      // 状态码上限（0 表示不限）
+	ResourceTypes []string // 资源类型过滤（为空表示全部）
+	State         string   // 请求状态过滤
+	AfterSeq      int64    // 只返回序号大于该值的条目（游标）
+	Limit         int      // 最多返回条数，超出时保留最新的
+}
+
+// NetworkBody 响应体
+type NetworkBody struct {
+	Body          string `json:"body"`
+	Base64Encoded bool   `json:"base64_encoded"`
+}
+
+// NetworkJournal 页面网络请求日志，容量有限，超出时淘汰最旧条目
+type NetworkJournal struct {
+	mu        sync.Mutex
+	entries   []*NetworkEntry
+	byRequest map[proto.NetworkRequestID]*NetworkEntry // 请求 ID -> 最新条目
+	lastSeq   int64
+	dropped   int64
+	capacity  int
+}
+
+// NewNetworkJournal 创建网络请求日志
+func NewNetworkJournal(capacity int) *NetworkJournal {
+	if capacity <= 0 {
+		capacity = defaultNetworkJournalSize
+	}
+	return &NetworkJournal{
+		byRequest: make(map[proto.NetworkRequestID]*NetworkEntry),
+		capacity:  capacity,
+	}
+}
+
+// OnRequestWillBeSent 记录请求发出；如果携带重定向响应，先结束上一跳
+func (j *NetworkJournal) OnRequestWillBeSent(e *proto.NetworkRequestWillBeSent) {
+	if e.Request == nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if e.RedirectResponse != nil {
+		if prev, ok := j.byRequest[e.RequestID]; ok {
+			applyNetworkResponse(prev, e.RedirectResponse)
+			prev.State = NetworkStateFinished
+			prev.RedirectURL = e.Request.URL
+			prev.endMono = e.Timestamp
+			prev.DurationMs = monoDurationMs(prev.startMono, e.Timestamp)
+		}
+	}
+
+	startTime := time.Now()
+	if e.WallTime > 0 {
+		startTime = e.WallTime.Time()
+	}
+
+	j.lastSeq++
+	entry := &NetworkEntry{
+		Seq:            j.lastSeq,
+		RequestID:      string(e.RequestID),
+		URL:            e.Request.URL + e.Request.URLFragment,
+		Method:         e.Request.Method,
+		ResourceType:   string(e.Type),
+		State:          NetworkStatePending,
+		RequestHeaders: networkHeadersToMap(e.Request.Headers),
+		PostData:       e.Request.PostData,
+		HasPostData:    e.Request.HasPostData,
+		StartTime:      startTime,
+		startMono:      e.Timestamp,
+	}
+
+	j.entries = append(j.entries, entry)
+	j.byRequest[e.RequestID] = entry
+
+	if len(j.entries) > j.capacity {
+		oldest := j.entries[0]
+		j.entries = j.entries[1:]
+		if current, ok := j.byRequest[proto.NetworkRequestID(oldest.RequestID)]; ok && current == oldest {
+			delete(j.byRequest, proto.NetworkRequestID(oldest.RequestID))
+		}
+		j.dropped++
+	}
+}
+
+// OnResponseReceived 记录响应头
+func (j *NetworkJournal) OnResponseReceived(e *proto.NetworkResponseReceived) {
+	if e.Response == nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry, ok := j.byRequest[e.RequestID]
+	if !ok {
+		return
+	}
+	applyNetworkResponse(entry, e.Response)
+	if entry.ResourceType == "" {
+		entry.ResourceType = string(e.Type)
+	}
+}
+
+// OnLoadingFinished 记录请求完成
+func (j *NetworkJournal) OnLoadingFinished(e *proto.NetworkLoadingFinished) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry, ok := j.byRequest[e.RequestID]
+	if !ok {
+		return
+	}
+	entry.State = NetworkStateFinished
+	entry.EncodedDataLength = int64(e.EncodedDataLength)
+	entry.endMono = e.Timestamp
+	entry.DurationMs = monoDurationMs(entry.startMono, e.Timestamp)
+}
+
+// OnLoadingFailed 记录请求失败
+func (j *NetworkJournal) OnLoadingFailed(e *proto.NetworkLoadingFailed) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry, ok := j.byRequest[e.RequestID]
+	if !ok {
+		return
+	}
+	entry.State = NetworkStateFailed
+	entry.ErrorText = e.ErrorText
+	if e.BlockedReason != "" {
+		entry.ErrorText = fmt.Sprintf("%s (blocked: %s)", e.ErrorText, e.BlockedReason)
+	}
+	entry.Canceled = e.Canceled
+	entry.endMono = e.Timestamp
+	entry.DurationMs = monoDurationMs(entry.startMono, e.Timestamp)
+}
+
+// Query 按条件查询条目（返回副本），同时返回当前游标
+func (j *NetworkJournal) Query(q NetworkQuery) ([]NetworkEntry, int64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	types := make(map[string]bool, len(q.ResourceTypes))
+	for _, t := range q.ResourceTypes {
+		if t = strings.TrimSpace(t); t != "" {
+			types[strings.ToLower(t)] = true
+		}
+	}
+	urlFilter := strings.ToLower(q.URL)
+
+	result := make([]NetworkEntry, 0)
+	for _, entry := range j.entries {
+		if entry.Seq <= q.AfterSeq {
+			continue
+		}
+		if urlFilter != "" && !strings.Contains(strings.ToLower(entry.URL), urlFilter) {
+			continue
+		}
+		if q.Method != "" && !strings.EqualFold(entry.Method, q.Method) {
+			continue
+		}
+		if q.StatusMin > 0 && entry.Status < q.StatusMin {
+			continue
+		}
+		if q.StatusMax > 0 && entry.Status > q.StatusMax {
+			continue
+		}
+		if len(types) > 0 && !types[strings.ToLower(entry.ResourceType)] {
+			continue
+		}
+		if q.State != "" && entry.State != q.State {
+			continue
+		}
+		result = append(result, *entry)
+	}
+
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[len(result)-q.Limit:]
+	}
+
+	return result, j.lastSeq
+}
+
+// FindLatest 返回满足条件的最新条目
+func (j *NetworkJournal) FindLatest(match func(entry *NetworkEntry) bool) (NetworkEntry, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for i := len(j.entries) - 1; i >= 0; i-- {
+		if match(j.entries[i]) {
+			return *j.entries[i], true
+		}
+	}
+	return NetworkEntry{}, false
+}
+
+// Get 按请求 ID 获取最新条目
+func (j *NetworkJournal) Get(requestID string) (NetworkEntry, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry, ok := j.byRequest[proto.NetworkRequestID(requestID)]
+	if !ok {
+		return NetworkEntry{}, false
+	}
+	return *entry, true
+}
+
+// Clear 清空日志（序号不重置）
+func (j *NetworkJournal) Clear() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = nil
+	j.byRequest = make(map[proto.NetworkRequestID]*NetworkEntry)
+}
+
+// Stats 返回当前条目数和累计丢弃数
+func (j *NetworkJournal) Stats() (size int, dropped int64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.entries), j.dropped
+}
+
+// FetchResponseBody 按需获取响应体（浏览器仅在资源仍在缓冲区时可用）
+func FetchResponseBody(page *rod.Page, requestID string) (*NetworkBody, error) {
+	res, err := proto.NetworkGetResponseBody{RequestID: proto.NetworkRequestID(requestID)}.Call(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get response body for %s: %w", requestID, err)
+	}
+	return &NetworkBody{Body: res.Body, Base64Encoded: res.Base64Encoded}, nil
+}
+
+// Text 返回解码后的响应体文本
+func (b *NetworkBody) Text() (string, error) {
+	if !b.Base64Encoded {
+		return b.Body, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(b.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ParseStatusFilter 解析状态码过滤：404、4xx、400-499
+func ParseStatusFilter(filter string) (min int, max int, err error) {
+	filter = strings.ToLower(strings.TrimSpace(filter))
+	if filter == "" {
+		return 0, 0, nil
+	}
+
+	if len(filter) == 3 && strings.HasSuffix(filter, "xx") {
+		class, err := strconv.Atoi(filter[:1])
+		if err != nil || class < 1 || class > 5 {
+			return 0, 0, fmt.Errorf("invalid status filter %q", filter)
+		}
+		return class * 100, class*100 + 99, nil
+	}
+
+	if lo, hi, ok := strings.Cut(filter, "-"); ok {
+		min, err1 := strconv.Atoi(strings.TrimSpace(lo))
+		max, err2 := strconv.Atoi(strings.TrimSpace(hi))
+		if err1 != nil || err2 != nil || min > max {
+			return 0, 0, fmt.Errorf("invalid status filter %q", filter)
+		}
+		return min, max, nil
+	}
+
+	code, err := strconv.Atoi(filter)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid status filter %q", filter)
+	}
+	return code, code, nil
+}
+
+// URLDomainAndPath 返回 URL 的 origin + path（去掉查询参数和 hash）
+func URLDomainAndPath(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return strings.SplitN(strings.SplitN(rawURL, "?", 2)[0], "#", 2)[0]
+	}
+	return u.Scheme + "://" + u.Host + u.Path
+}
+
+// applyNetworkResponse 将响应信息写入条目
+func applyNetworkResponse(entry *NetworkEntry, resp *proto.NetworkResponse) {
+	entry.Status = resp.Status
+	entry.StatusText = resp.StatusText
+	entry.ResponseHeaders = networkHeadersToMap(resp.Headers)
+	entry.MIMEType = resp.MIMEType
+	entry.Protocol = resp.Protocol
+	entry.RemoteIPAddress = resp.RemoteIPAddress
+	entry.FromCache = resp.FromDiskCache || resp.FromPrefetchCache || resp.FromServiceWorker
+	entry.Timing = resp.Timing
+	if len(resp.RequestHeaders) > 0 {
+		// 实际发送的请求头（包含浏览器追加的 Cookie 等）
+		entry.RequestHeaders = networkHeadersToMap(resp.RequestHeaders)
+	}
+}
+
+// networkHeadersToMap 将 CDP 头部转换为字符串 map
+func networkHeadersToMap(headers proto.NetworkHeaders) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(headers))
+	for name, value := range headers {
+		result[name] = value.Str()
+	}
+	return result
+}
+
+// monoDurationMs 计算两个单调时间之间的毫秒数
+func monoDurationMs(start, end proto.MonotonicTime) float64 {
+	if start <= 0 || end < start {
+		return 0
+	}
+	return float64(end-start) * 1000
+}
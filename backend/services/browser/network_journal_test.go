@@ -0,0 +1,107 @@
+package browser
+
+import (
+	"testing"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func TestNetworkJournalLinksEvents(t *testing.T) {
+	j := NewNetworkJournal(10)
+
+	j.OnRequestWillBeSent(&proto.NetworkRequestWillBeSent{
+		RequestID: "1",
+		Request:   &proto.NetworkRequest{URL: "http://example.com/login", Method: "GET"},
+		Timestamp: 10,
+		Type:      proto.NetworkResourceTypeDocument,
+	})
+	// 重定向：同一请求 ID 的新请求携带上一跳的响应
+	j.OnRequestWillBeSent(&proto.NetworkRequestWillBeSent{
+		RequestID:        "1",
+		Request:          &proto.NetworkRequest{URL: "http://example.com/home", Method: "GET"},
+		RedirectResponse: &proto.NetworkResponse{Status: 302, StatusText: "Found"},
+		Timestamp:        10.5,
+		Type:             proto.NetworkResourceTypeDocument,
+	})
+	j.OnResponseReceived(&proto.NetworkResponseReceived{
+		RequestID: "1",
+		Response:  &proto.NetworkResponse{Status: 200, MIMEType: "text/html"},
+	})
+	j.OnLoadingFinished(&proto.NetworkLoadingFinished{RequestID: "1", Timestamp: 11, EncodedDataLength: 512})
+
+	j.OnRequestWillBeSent(&proto.NetworkRequestWillBeSent{
+		RequestID: "2",
+		Request:   &proto.NetworkRequest{URL: "http://example.com/api/items?page=2", Method: "POST"},
+		Timestamp: 12,
+		Type:      proto.NetworkResourceTypeXHR,
+	})
+	j.OnLoadingFailed(&proto.NetworkLoadingFailed{RequestID: "2", Timestamp: 12.25, ErrorText: "net::ERR_FAILED"})
+
+	all, cursor := j.Query(NetworkQuery{})
+	if cursor != 3 || len(all) != 3 {
+		t.Fatalf("got %d entries with cursor %d, want 3 and 3", len(all), cursor)
+	}
+
+	redirect := all[0]
+	if redirect.Status != 302 || redirect.RedirectURL != "http://example.com/home" || redirect.State != NetworkStateFinished {
+		t.Errorf("redirect entry = %+v", redirect)
+	}
+	final := all[1]
+	if final.Status != 200 || final.DurationMs != 500 || final.EncodedDataLength != 512 {
+		t.Errorf("final entry = %+v", final)
+	}
+	failed := all[2]
+	if failed.State != NetworkStateFailed || failed.ErrorText != "net::ERR_FAILED" || failed.DurationMs != 250 {
+		t.Errorf("failed entry = %+v", failed)
+	}
+
+	if got, _ := j.Query(NetworkQuery{Method: "post"}); len(got) != 1 || got[0].RequestID != "2" {
+		t.Errorf("method filter returned %+v", got)
+	}
+	if got, _ := j.Query(NetworkQuery{StatusMin: 300, StatusMax: 399}); len(got) != 1 || got[0].Status != 302 {
+		t.Errorf("status filter returned %+v", got)
+	}
+	if got, _ := j.Query(NetworkQuery{URL: "/API/"}); len(got) != 1 {
+		t.Errorf("url filter returned %+v", got)
+	}
+	if entry, ok := j.Get("1"); !ok || entry.URL != "http://example.com/home" {
+		t.Errorf("Get(1) = %+v, %v", entry, ok)
+	}
+
+	har := BuildHAR(all, nil, nil)
+	if har.Log.Version != "1.2" || len(har.Log.Entries) != 3 {
+		t.Fatalf("unexpected HAR: %+v", har.Log)
+	}
+	if qs := har.Log.Entries[2].Request.QueryString; len(qs) != 1 || qs[0].Name != "page" || qs[0].Value != "2" {
+		t.Errorf("HAR query string = %+v", qs)
+	}
+}
+
+func TestParseStatusFilter(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantMin int
+		wantMax int
+		wantErr bool
+	}{
+		{input: "", wantMin: 0, wantMax: 0},
+		{input: "404", wantMin: 404, wantMax: 404},
+		{input: "4xx", wantMin: 400, wantMax: 499},
+		{input: "200-299", wantMin: 200, wantMax: 299},
+		{input: "9xx", wantErr: true},
+		{input: "500-400", wantErr: true},
+		{input: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			min, max, err := ParseStatusFilter(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStatusFilter(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && (min != tt.wantMin || max != tt.wantMax) {
+				t.Errorf("ParseStatusFilter(%q) = (%d, %d), want (%d, %d)", tt.input, min, max, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
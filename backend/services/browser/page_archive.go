@@ -0,0 +1,142 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/browserwing/browserwing/models"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// paperSizes 预设纸张规格（宽 x 高，英寸）
+var paperSizes = map[string][2]float64{
+	"a3":      {11.69, 16.54},
+	"a4":      {8.27, 11.69},
+	"a5":      {5.83, 8.27},
+	"letter":  {8.5, 11},
+	"legal":   {8.5, 14},
+	"tabloid": {11, 17},
+}
+
+// ValidatePDFOptions 校验 PDF 打印选项，nil 表示使用默认值
+func ValidatePDFOptions(opts *models.PDFOptions) error {
+	if opts == nil {
+		return nil
+	}
+	if opts.PaperFormat != "" {
+		if _, ok := paperSizes[strings.ToLower(opts.PaperFormat)]; !ok {
+			return fmt.Errorf("unsupported paper_format %q (expected A3, A4, A5, Letter, Legal or Tabloid)", opts.PaperFormat)
+		}
+	}
+	if opts.PaperWidth < 0 || opts.PaperHeight < 0 {
+		return fmt.Errorf("paper_width and paper_height must not be negative")
+	}
+	if opts.Scale != 0 && (opts.Scale < 0.1 || opts.Scale > 2) {
+		return fmt.Errorf("scale must be between 0.1 and 2")
+	}
+	if opts.MarginTop < 0 || opts.MarginBottom < 0 || opts.MarginLeft < 0 || opts.MarginRight < 0 {
+		return fmt.Errorf("margins must not be negative")
+	}
+	return nil
+}
+
+// pdfRequest 根据打印选项构建 Page.printToPDF 请求
+func pdfRequest(opts *models.PDFOptions) (*proto.PagePrintToPDF, error) {
+	if err := ValidatePDFOptions(opts); err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &models.PDFOptions{}
+	}
+
+	size := paperSizes["a4"]
+	if opts.PaperFormat != "" {
+		size = paperSizes[strings.ToLower(opts.PaperFormat)]
+	}
+	if opts.PaperWidth > 0 {
+		size[0] = opts.PaperWidth
+	}
+	if opts.PaperHeight > 0 {
+		size[1] = opts.PaperHeight
+	}
+
+	req := &proto.PagePrintToPDF{
+		Landscape:           opts.Landscape,
+		DisplayHeaderFooter: opts.HeaderTemplate != "" || opts.FooterTemplate != "",
+		PrintBackground:     opts.PrintBackground,
+		PaperWidth:          &size[0],
+		PaperHeight:         &size[1],
+		PageRanges:          opts.PageRanges,
+		HeaderTemplate:      opts.HeaderTemplate,
+		FooterTemplate:      opts.FooterTemplate,
+		PreferCSSPageSize:   opts.PreferCSSPageSize,
+	}
+	if req.DisplayHeaderFooter {
+		// 只设置了其中一个模板时，另一个使用空白模板，避免显示 Chrome 默认的页眉或页脚
+		if req.HeaderTemplate == "" {
+			req.HeaderTemplate = "<span></span>"
+		}
+		if req.FooterTemplate == "" {
+			req.FooterTemplate = "<span></span>"
+		}
+	}
+	if opts.Scale > 0 {
+		req.Scale = &opts.Scale
+	}
+	// 未设置的边距使用 Chrome 默认值（约 0.4 英寸）
+	if opts.MarginTop > 0 {
+		req.MarginTop = &opts.MarginTop
+	}
+	if opts.MarginBottom > 0 {
+		req.MarginBottom = &opts.MarginBottom
+	}
+	if opts.MarginLeft > 0 {
+		req.MarginLeft = &opts.MarginLeft
+	}
+	if opts.MarginRight > 0 {
+		req.MarginRight = &opts.MarginRight
+	}
+	return req, nil
+}
+
+// PrintToPDF 将页面打印为 PDF（Chrome 仅在无头模式下支持打印）
+func PrintToPDF(ctx context.Context, page *rod.Page, opts *models.PDFOptions) ([]byte, error) {
+	req, err := pdfRequest(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := page.Context(ctx).PDF(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to print page to PDF (printing requires a headless browser): %w", err)
+	}
+	defer stream.Close()
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF stream: %w", err)
+	}
+	return data, nil
+}
+
+// CaptureMHTML 将页面（含样式、图片和 iframe）保存为单个 MHTML 存档
+func CaptureMHTML(ctx context.Context, page *rod.Page) ([]byte, error) {
+	res, err := proto.PageCaptureSnapshot{Format: proto.PageCaptureSnapshotFormatMhtml}.Call(page.Context(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture MHTML snapshot: %w", err)
+	}
+	return []byte(res.Data), nil
+}
+
+// sanitizeFileName 将变量名等转换为可用作文件名的字符串，非法字符替换为下划线
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, name)
+}
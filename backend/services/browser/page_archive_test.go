@@ -0,0 +1,59 @@
+package browser
+
+import (
+	"testing"
+
+	"github.com/browserwing/browserwing/models"
+)
+
+func TestValidatePDFOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    *models.PDFOptions
+		wantErr bool
+	}{
+		{name: "nil", opts: nil},
+		{name: "letter landscape", opts: &models.PDFOptions{PaperFormat: "Letter", Landscape: true, Scale: 0.8}},
+		{name: "custom size", opts: &models.PDFOptions{PaperWidth: 4, PaperHeight: 6, MarginTop: 0.4}},
+		{name: "unknown paper format", opts: &models.PDFOptions{PaperFormat: "B5"}, wantErr: true},
+		{name: "scale too large", opts: &models.PDFOptions{Scale: 3}, wantErr: true},
+		{name: "negative margin", opts: &models.PDFOptions{MarginLeft: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePDFOptions(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePDFOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPDFRequest(t *testing.T) {
+	req, err := pdfRequest(nil)
+	if err != nil {
+		t.Fatalf("pdfRequest(nil) error = %v", err)
+	}
+	if *req.PaperWidth != 8.27 || *req.PaperHeight != 11.69 || req.Scale != nil || req.MarginTop != nil || req.DisplayHeaderFooter {
+		t.Errorf("default request = %+v", req)
+	}
+
+	req, err = pdfRequest(&models.PDFOptions{PaperFormat: "legal", PaperHeight: 13, Scale: 1.5, FooterTemplate: "<span class='pageNumber'></span>"})
+	if err != nil {
+		t.Fatalf("pdfRequest() error = %v", err)
+	}
+	if *req.PaperWidth != 8.5 || *req.PaperHeight != 13 || *req.Scale != 1.5 {
+		t.Errorf("paper size or scale not applied: %+v", req)
+	}
+	if !req.DisplayHeaderFooter || req.HeaderTemplate != "<span></span>" {
+		t.Errorf("header/footer not enabled with blank header: %+v", req)
+	}
+	if paperSizes["legal"][1] != 14 {
+		t.Error("paper size presets were modified")
+	}
+
+	if _, err := pdfRequest(&models.PDFOptions{PaperFormat: "B5"}); err == nil {
+		t.Error("expected error for unknown paper format")
+	}
+}
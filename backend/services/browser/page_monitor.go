@@ -0,0 +1,411 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// defaultConsoleBufferSize 每个页面保留的控制台条目上限
+const defaultConsoleBufferSize = 1000
+
+// ConsoleEntry 页面控制台条目（console API 调用、未捕获异常、浏览器日志）
+type ConsoleEntry struct {
+	Seq        int64     `json:"seq"`                   // 单调递增序号，可作为增量拉取游标
+	Source     string    `json:"source"`                // console, exception, log
+	Level      string    `json:"level"`                 // debug, log, info, warning, error
+	Text       string    `json:"text"`                  // 消息文本
+	URL        string    `json:"url,omitempty"`         // 来源脚本 URL
+	Line       int       `json:"line,omitempty"`        // 行号
+	Column     int       `json:"column,omitempty"`      // 列号
+	StackTrace []string  `json:"stack_trace,omitempty"` // 调用栈（函数名@url:行:列）
+	Timestamp  time.Time `json:"timestamp"`             // 产生时间
+}
+
+// ConsoleQuery 控制台条目查询条件
+type ConsoleQuery struct {
+	Levels   []string  // 级别过滤（为空表示全部）
+	Sources  []string  // 来源过滤（为空表示全部）
+	Since    time.Time // 只返回该时间之后的条目
+	AfterSeq int64     // 只返回序号大于该值的条目（游标）
+	Limit    int       // 最多返回条数，超出时保留最新的
+	Clear    bool      // 查询后清空缓冲区（与查询在同一锁内完成，不会丢失查询之后写入的条目）
+}
+
+// ConsoleBuffer 有界环形缓冲区，保存一个页面的控制台条目
+type ConsoleBuffer struct {
+	mu       sync.Mutex
+	entries  []ConsoleEntry
+	start    int   // 最旧条目所在位置
+	count    int   // 当前条目数
+	lastSeq  int64 // 最后分配的序号
+	dropped  int64 // 因容量限制被丢弃的条目数
+	capacity int
+}
+
+// NewConsoleBuffer 创建控制台缓冲区
+func NewConsoleBuffer(capacity int) *ConsoleBuffer {
+	if capacity <= 0 {
+		capacity = defaultConsoleBufferSize
+	}
+	return &ConsoleBuffer{
+		entries:  make([]ConsoleEntry, capacity),
+		capacity: capacity,
+	}
+}
+
+// Add 追加一条记录，缓冲区满时覆盖最旧的记录
+func (b *ConsoleBuffer) Add(entry ConsoleEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastSeq++
+	entry.Seq = b.lastSeq
+	entry.Level = normalizeConsoleLevel(entry.Level)
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	if b.count < b.capacity {
+		b.entries[(b.start+b.count)%b.capacity] = entry
+		b.count++
+		return
+	}
+
+	b.entries[b.start] = entry
+	b.start = (b.start + 1) % b.capacity
+	b.dropped++
+}
+
+// Query 按条件查询条目，返回结果和当前游标（最后分配的序号）
+func (b *ConsoleBuffer) Query(q ConsoleQuery) ([]ConsoleEntry, int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	levels := make(map[string]bool, len(q.Levels))
+	for _, l := range q.Levels {
+		if l = strings.TrimSpace(l); l != "" {
+			levels[normalizeConsoleLevel(l)] = true
+		}
+	}
+	sources := make(map[string]bool, len(q.Sources))
+	for _, s := range q.Sources {
+		if s = strings.TrimSpace(s); s != "" {
+			sources[strings.ToLower(s)] = true
+		}
+	}
+
+	result := make([]ConsoleEntry, 0)
+	for i := 0; i < b.count; i++ {
+		entry := b.entries[(b.start+i)%b.capacity]
+		if entry.Seq <= q.AfterSeq {
+			continue
+		}
+		if !q.Since.IsZero() && entry.Timestamp.Before(q.Since) {
+			continue
+		}
+		if len(levels) > 0 && !levels[entry.Level] {
+			continue
+		}
+		if len(sources) > 0 && !sources[entry.Source] {
+			continue
+		}
+		result = append(result, entry)
+	}
+
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[len(result)-q.Limit:]
+	}
+	if q.Clear {
+		b.start = 0
+		b.count = 0
+	}
+
+	return result, b.lastSeq
+}
+
+// Clear 清空缓冲区（序号不重置，已有游标仍然有效）
+func (b *ConsoleBuffer) Clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.start = 0
+	b.count = 0
+}
+
+// Stats 返回当前条目数和累计丢弃数
+func (b *ConsoleBuffer) Stats() (size int, dropped int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.count, b.dropped
+}
+
+// normalizeConsoleLevel 统一不同 CDP 事件中的级别命名
+func normalizeConsoleLevel(level string) string {
+	switch strings.ToLower(level) {
+	case "warn", "warning":
+		return "warning"
+	case "error", "assert":
+		return "error"
+	case "debug", "verbose", "trace":
+		return "debug"
+	case "info":
+		return "info"
+	default:
+		return "log"
+	}
+}
+
+// PageMonitor 页面级观测数据（控制台、网络请求），从页面创建起持续采集
+type PageMonitor struct {
+	TargetID   proto.TargetTargetID
+	InstanceID string
+	CreatedAt  time.Time
+	Console    *ConsoleBuffer
+	Network    *NetworkJournal
+
+	stop context.CancelFunc // 停止事件监听
+}
+
+// MonitorPage 为页面挂载观测器（幂等），使用当前实例
+// 应在页面创建后、导航前调用，以便保留页面生命周期内的全部消息
+func (m *Manager) MonitorPage(page *rod.Page) *PageMonitor {
+	m.mu.Lock()
+	instanceID := m.currentInstanceID
+	m.mu.Unlock()
+	return m.monitorPage(instanceID, page)
+}
+
+// GetPageMonitor 获取页面的观测器，不存在时自动挂载
+func (m *Manager) GetPageMonitor(page *rod.Page) *PageMonitor {
+	if page == nil {
+		return nil
+	}
+	m.pageMonitorMu.Lock()
+	monitor, exists := m.pageMonitors[page.TargetID]
+	m.pageMonitorMu.Unlock()
+	if exists {
+		return monitor
+	}
+	return m.MonitorPage(page)
+}
+
+// monitorPage 挂载观测器并启动事件监听，页面关闭（TargetDestroyed）后释放
+func (m *Manager) monitorPage(instanceID string, page *rod.Page) *PageMonitor {
+	if page == nil {
+		return nil
+	}
+
+	m.pageMonitorMu.Lock()
+	if m.pageMonitors == nil {
+		m.pageMonitors = make(map[proto.TargetTargetID]*PageMonitor)
+	}
+	if monitor, exists := m.pageMonitors[page.TargetID]; exists {
+		m.pageMonitorMu.Unlock()
+		return monitor
+	}
+	m.pageMonitorMu.Unlock()
+
+	// 已关闭的页面收不到 TargetDestroyed，挂载后网络日志会一直保留，直接拒绝
+	if _, err := (proto.TargetGetTargetInfo{TargetID: page.TargetID}).Call(page.Browser()); err != nil {
+		logger.Warn(context.Background(), "Skip monitoring closed page %s: %v", page.TargetID, err)
+		return nil
+	}
+
+	m.pageMonitorMu.Lock()
+	if monitor, exists := m.pageMonitors[page.TargetID]; exists {
+		m.pageMonitorMu.Unlock()
+		return monitor
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	monitor := &PageMonitor{
+		TargetID:   page.TargetID,
+		InstanceID: instanceID,
+		CreatedAt:  time.Now(),
+		Console:    NewConsoleBuffer(defaultConsoleBufferSize),
+		Network:    NewNetworkJournal(defaultNetworkJournalSize),
+		stop:       stop,
+	}
+	m.pageMonitors[page.TargetID] = monitor
+	m.pageMonitorMu.Unlock()
+
+	// EachEvent 会自动启用 Runtime/Log/Network 域；页面关闭不会取消 page 的 context，
+	// 监听只在 releasePageMonitor 取消 ctx 后退出
+	targetID := page.TargetID
+	wait := page.Context(ctx).EachEvent(
+		func(e *proto.RuntimeConsoleAPICalled) {
+			monitor.Console.Add(consoleEntryFromAPICall(e))
+		},
+		func(e *proto.RuntimeExceptionThrown) {
+			monitor.Console.Add(consoleEntryFromException(e))
+		},
+		func(e *proto.LogEntryAdded) {
+			if e.Entry != nil {
+				monitor.Console.Add(consoleEntryFromLog(e.Entry))
+			}
+		},
+		func(e *proto.NetworkRequestWillBeSent) {
+			monitor.Network.OnRequestWillBeSent(e)
+		},
+		func(e *proto.NetworkResponseReceived) {
+			monitor.Network.OnResponseReceived(e)
+		},
+		func(e *proto.NetworkLoadingFinished) {
+			monitor.Network.OnLoadingFinished(e)
+		},
+		func(e *proto.NetworkLoadingFailed) {
+			monitor.Network.OnLoadingFailed(e)
+		},
+	)
+
+	// 页面级事件收不到 TargetDestroyed，需要在浏览器级别监听
+	waitDestroyed := page.Browser().Context(ctx).EachEvent(func(e *proto.TargetTargetDestroyed) bool {
+		return e.TargetID == targetID
+	})
+
+	for _, w := range []func(){wait, waitDestroyed} {
+		go func(w func()) {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Warn(context.Background(), "Page monitor for %s stopped unexpectedly: %v", targetID, r)
+				}
+				m.releasePageMonitor(targetID)
+			}()
+			w()
+		}(w)
+	}
+
+	logger.Info(context.Background(), "✓ Page monitor attached: %s", page.TargetID)
+	return monitor
+}
+
+// releasePageMonitor 停止页面的事件监听并移除观测器（幂等）
+// 同时清空控制台和网络日志，避免仍持有观测器引用的调用方保留已关闭页面的请求头和请求体
+func (m *Manager) releasePageMonitor(targetID proto.TargetTargetID) {
+	m.pageMonitorMu.Lock()
+	monitor, exists := m.pageMonitors[targetID]
+	delete(m.pageMonitors, targetID)
+	m.pageMonitorMu.Unlock()
+
+	if !exists {
+		return
+	}
+	if monitor.stop != nil {
+		monitor.stop()
+	}
+	if monitor.Console != nil {
+		monitor.Console.Clear()
+	}
+	if monitor.Network != nil {
+		monitor.Network.Clear()
+	}
+}
+
+// consoleEntryFromAPICall 将 console.* 调用转换为控制台条目
+func consoleEntryFromAPICall(e *proto.RuntimeConsoleAPICalled) ConsoleEntry {
+	parts := make([]string, 0, len(e.Args))
+	for _, arg := range e.Args {
+		parts = append(parts, remoteObjectText(arg))
+	}
+
+	entry := ConsoleEntry{
+		Source:    "console",
+		Level:     string(e.Type),
+		Text:      strings.Join(parts, " "),
+		Timestamp: runtimeTimestampToTime(e.Timestamp),
+	}
+	applyStackTrace(&entry, e.StackTrace)
+	return entry
+}
+
+// consoleEntryFromException 将未捕获异常转换为控制台条目
+func consoleEntryFromException(e *proto.RuntimeExceptionThrown) ConsoleEntry {
+	entry := ConsoleEntry{
+		Source:    "exception",
+		Level:     "error",
+		Timestamp: runtimeTimestampToTime(e.Timestamp),
+	}
+	details := e.ExceptionDetails
+	if details == nil {
+		return entry
+	}
+
+	entry.Text = details.Text
+	if details.Exception != nil && details.Exception.Description != "" {
+		entry.Text = details.Exception.Description
+	}
+	entry.URL = details.URL
+	entry.Line = details.LineNumber
+	entry.Column = details.ColumnNumber
+	applyStackTrace(&entry, details.StackTrace)
+	return entry
+}
+
+// consoleEntryFromLog 将浏览器日志（网络错误、安全告警等）转换为控制台条目
+func consoleEntryFromLog(e *proto.LogLogEntry) ConsoleEntry {
+	entry := ConsoleEntry{
+		Source:    "log",
+		Level:     string(e.Level),
+		Text:      e.Text,
+		URL:       e.URL,
+		Timestamp: runtimeTimestampToTime(e.Timestamp),
+	}
+	if e.LineNumber != nil {
+		entry.Line = *e.LineNumber
+	}
+	applyStackTrace(&entry, e.StackTrace)
+	return entry
+}
+
+// applyStackTrace 填充调用栈，并在缺少位置信息时使用栈顶帧
+func applyStackTrace(entry *ConsoleEntry, trace *proto.RuntimeStackTrace) {
+	if trace == nil || len(trace.CallFrames) == 0 {
+		return
+	}
+	for _, frame := range trace.CallFrames {
+		name := frame.FunctionName
+		if name == "" {
+			name = "(anonymous)"
+		}
+		entry.StackTrace = append(entry.StackTrace, fmt.Sprintf("%s@%s:%d:%d", name, frame.URL, frame.LineNumber, frame.ColumnNumber))
+	}
+	if entry.URL == "" {
+		top := trace.CallFrames[0]
+		entry.URL = top.URL
+		entry.Line = top.LineNumber
+		entry.Column = top.ColumnNumber
+	}
+}
+
+// remoteObjectText 将 console 参数转换为可读文本
+func remoteObjectText(obj *proto.RuntimeRemoteObject) string {
+	if obj == nil {
+		return ""
+	}
+	if obj.UnserializableValue != "" {
+		return string(obj.UnserializableValue)
+	}
+	if val := obj.Value.Val(); val != nil {
+		if s, ok := val.(string); ok {
+			return s
+		}
+		return obj.Value.JSON("", "")
+	}
+	if obj.Description != "" {
+		return obj.Description
+	}
+	return string(obj.Type)
+}
+
+// runtimeTimestampToTime CDP 时间戳（毫秒）转换为 time.Time
+func runtimeTimestampToTime(ts proto.RuntimeTimestamp) time.Time {
+	if ts <= 0 {
+		return time.Now()
+	}
+	return time.UnixMilli(int64(ts))
+}
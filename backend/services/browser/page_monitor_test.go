@@ -0,0 +1,153 @@
+package browser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func TestConsoleBufferQuery(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	buf := NewConsoleBuffer(3)
+	buf.Add(ConsoleEntry{Source: "console", Level: "log", Text: "a", Timestamp: base})
+	buf.Add(ConsoleEntry{Source: "console", Level: "warn", Text: "b", Timestamp: base.Add(time.Second)})
+	buf.Add(ConsoleEntry{Source: "exception", Level: "error", Text: "c", Timestamp: base.Add(2 * time.Second)})
+	buf.Add(ConsoleEntry{Source: "log", Level: "verbose", Text: "d", Timestamp: base.Add(3 * time.Second)})
+
+	tests := []struct {
+		name      string
+		query     ConsoleQuery
+		wantTexts []string
+	}{
+		{
+			name:      "all entries after overflow",
+			query:     ConsoleQuery{},
+			wantTexts: []string{"b", "c", "d"},
+		},
+		{
+			name:      "level filter normalizes aliases",
+			query:     ConsoleQuery{Levels: []string{"warning", "debug"}},
+			wantTexts: []string{"b", "d"},
+		},
+		{
+			name:      "since filter",
+			query:     ConsoleQuery{Since: base.Add(2 * time.Second)},
+			wantTexts: []string{"c", "d"},
+		},
+		{
+			name:      "cursor filter",
+			query:     ConsoleQuery{AfterSeq: 3},
+			wantTexts: []string{"d"},
+		},
+		{
+			name:      "limit keeps newest",
+			query:     ConsoleQuery{Limit: 2},
+			wantTexts: []string{"c", "d"},
+		},
+		{
+			name:      "source filter",
+			query:     ConsoleQuery{Sources: []string{"exception"}},
+			wantTexts: []string{"c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, cursor := buf.Query(tt.query)
+			if cursor != 4 {
+				t.Errorf("cursor = %d, want 4", cursor)
+			}
+			if len(got) != len(tt.wantTexts) {
+				t.Fatalf("got %d entries, want %d", len(got), len(tt.wantTexts))
+			}
+			for i, entry := range got {
+				if entry.Text != tt.wantTexts[i] {
+					t.Errorf("entry[%d].Text = %q, want %q", i, entry.Text, tt.wantTexts[i])
+				}
+			}
+		})
+	}
+
+	if size, dropped := buf.Stats(); size != 3 || dropped != 1 {
+		t.Errorf("Stats() = (%d, %d), want (3, 1)", size, dropped)
+	}
+
+	buf.Clear()
+	buf.Add(ConsoleEntry{Text: "e"})
+	got, cursor := buf.Query(ConsoleQuery{})
+	if len(got) != 1 || got[0].Seq != 5 || cursor != 5 {
+		t.Errorf("after Clear got %+v cursor %d, want single entry with seq 5", got, cursor)
+	}
+
+	// 查询并清空：返回的条目与游标一致，之后写入的条目从游标之后继续
+	buf.Add(ConsoleEntry{Text: "f"})
+	got, cursor = buf.Query(ConsoleQuery{AfterSeq: 4, Clear: true})
+	if len(got) != 2 || cursor != 6 {
+		t.Errorf("query with clear got %d entries cursor %d, want 2 entries cursor 6", len(got), cursor)
+	}
+	buf.Add(ConsoleEntry{Text: "g"})
+	got, cursor = buf.Query(ConsoleQuery{AfterSeq: 6})
+	if len(got) != 1 || got[0].Text != "g" || cursor != 7 {
+		t.Errorf("after query with clear got %+v cursor %d, want only g", got, cursor)
+	}
+}
+
+func TestReleasePageMonitor(t *testing.T) {
+	stopped := 0
+	journal := NewNetworkJournal(10)
+	journal.OnRequestWillBeSent(&proto.NetworkRequestWillBeSent{
+		RequestID: "1",
+		Request:   &proto.NetworkRequest{URL: "https://example.com/api", Method: "POST", PostData: "secret"},
+	})
+	m := &Manager{pageMonitors: map[proto.TargetTargetID]*PageMonitor{
+		"page-1": {TargetID: "page-1", Console: NewConsoleBuffer(10), Network: journal, stop: func() { stopped++ }},
+		"page-2": {TargetID: "page-2", stop: func() {}},
+	}}
+
+	m.releasePageMonitor("page-1")
+	m.releasePageMonitor("page-1")
+	if _, ok := m.pageMonitors["page-1"]; ok || stopped != 1 {
+		t.Errorf("after release: present=%v stopped=%d, want removed and stopped once", ok, stopped)
+	}
+	if size, _ := journal.Stats(); size != 0 {
+		t.Errorf("network journal still holds %d entries after release", size)
+	}
+	if _, ok := m.pageMonitors["page-2"]; !ok {
+		t.Error("unrelated monitor was released")
+	}
+}
+
+func TestPageMonitorReleasedOnClose(t *testing.T) {
+	bin, ok := launcher.LookPath()
+	if !ok {
+		t.Skip("no local browser available")
+	}
+	u, err := launcher.New().Bin(bin).Headless(true).NoSandbox(true).Launch()
+	if err != nil {
+		t.Skipf("failed to launch browser: %v", err)
+	}
+	b := rod.New().ControlURL(u).MustConnect()
+	defer b.MustClose()
+
+	m := &Manager{pageMonitors: make(map[proto.TargetTargetID]*PageMonitor)}
+	page := b.MustPage("")
+	if m.monitorPage("default", page) == nil {
+		t.Fatal("monitorPage returned nil")
+	}
+	page.MustClose()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		m.pageMonitorMu.Lock()
+		_, exists := m.pageMonitors[page.TargetID]
+		m.pageMonitorMu.Unlock()
+		if !exists {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Errorf("monitor for closed page %s was not released", page.TargetID)
+}
@@ -0,0 +1,297 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/browserwing/browserwing/models"
+	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/go-rod/stealth"
+)
+
+// defaultMaxConcurrentPlaybacks 每个实例默认允许同时执行的回放数量
+const defaultMaxConcurrentPlaybacks = 4
+
+// playbackPool 单个浏览器实例的回放并发控制
+// 超出并发上限的回放按到达顺序排队，直到有空闲名额或调用方取消
+type playbackPool struct {
+	mu        sync.Mutex
+	limit     int
+	isolation string
+	running   int
+	queued    int
+	waiters   []chan struct{} // 排队中的回放，获得名额时关闭
+}
+
+func newPlaybackPool(limit int) *playbackPool {
+	if limit <= 0 {
+		limit = defaultMaxConcurrentPlaybacks
+	}
+	return &playbackPool{limit: limit}
+}
+
+// setLimit 修改并发上限：调大时立即放行排队的回放；调小时已在执行的回放不受影响，
+// 直到执行数降到新上限以下才会开始新的回放
+func (pp *playbackPool) setLimit(limit int) {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	pp.limit = limit
+	pp.grantLocked()
+}
+
+// grantLocked 按排队顺序放行回放，直到达到并发上限（调用方持有锁）
+func (pp *playbackPool) grantLocked() {
+	for pp.running < pp.limit && len(pp.waiters) > 0 {
+		ready := pp.waiters[0]
+		pp.waiters = pp.waiters[1:]
+		pp.queued--
+		pp.running++
+		close(ready)
+	}
+}
+
+// acquire 获取一个回放名额，返回释放函数
+func (pp *playbackPool) acquire(ctx context.Context) (func(), error) {
+	pp.mu.Lock()
+	if pp.running < pp.limit && len(pp.waiters) == 0 {
+		pp.running++
+		pp.mu.Unlock()
+		return pp.releaseFunc(), nil
+	}
+
+	ready := make(chan struct{})
+	pp.waiters = append(pp.waiters, ready)
+	pp.queued++
+	position, limit := pp.queued, pp.limit
+	pp.mu.Unlock()
+	logger.Info(ctx, "Playback queued (position %d, limit %d)", position, limit)
+
+	select {
+	case <-ready:
+		return pp.releaseFunc(), nil
+	case <-ctx.Done():
+		pp.mu.Lock()
+		for i, waiter := range pp.waiters {
+			if waiter == ready {
+				pp.waiters = append(pp.waiters[:i], pp.waiters[i+1:]...)
+				pp.queued--
+				pp.mu.Unlock()
+				return nil, ctx.Err()
+			}
+		}
+		pp.mu.Unlock()
+		// 取消的同时已获得名额，归还给下一个排队的回放
+		pp.releaseFunc()()
+		return nil, ctx.Err()
+	}
+}
+
+// releaseFunc 返回归还名额的函数（重复调用只归还一次）
+func (pp *playbackPool) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			pp.mu.Lock()
+			pp.running--
+			pp.grantLocked()
+			pp.mu.Unlock()
+		})
+	}
+}
+
+// PlaybackPoolStats 实例的回放并发状态
+type PlaybackPoolStats struct {
+	InstanceID string `json:"instance_id"`
+	Isolation  string `json:"isolation"` // page 或 incognito
+	Limit      int    `json:"limit"`     // 并发上限
+	Running    int    `json:"running"`   // 正在执行的回放数量
+	Queued     int    `json:"queued"`    // 排队等待的回放数量
+}
+
+// ValidatePlaybackSettings 校验实例的回放并发配置
+func ValidatePlaybackSettings(instance *models.BrowserInstance) error {
+	if instance.MaxConcurrentPlaybacks < 0 {
+		return fmt.Errorf("max_concurrent_playbacks must not be negative")
+	}
+	switch strings.ToLower(strings.TrimSpace(instance.PlaybackIsolation)) {
+	case "", models.PlaybackIsolationPage, models.PlaybackIsolationIncognito:
+		return nil
+	default:
+		return fmt.Errorf("unsupported playback_isolation %q (expected page or incognito)", instance.PlaybackIsolation)
+	}
+}
+
+// playbackIsolation 返回实例的回放隔离方式
+func playbackIsolation(instance *models.BrowserInstance) string {
+	if instance != nil && strings.EqualFold(strings.TrimSpace(instance.PlaybackIsolation), models.PlaybackIsolationIncognito) {
+		return models.PlaybackIsolationIncognito
+	}
+	return models.PlaybackIsolationPage
+}
+
+// playbackSettings 获取实例最新的回放配置（实例修改后无需重启即可生效）
+func (m *Manager) playbackSettings(instanceID string, instance *models.BrowserInstance) *models.BrowserInstance {
+	if m.db != nil && instanceID != "" {
+		if saved, err := m.db.GetBrowserInstance(instanceID); err == nil && saved != nil {
+			return saved
+		}
+	}
+	return instance
+}
+
+// playbackPool 获取实例的回放池，实例的并发上限修改后调整同一个池的上限
+// 正在执行和排队的回放继续按同一个计数，不会因上限修改而超出并发上限
+func (m *Manager) playbackPool(instanceID string, settings *models.BrowserInstance) *playbackPool {
+	limit := defaultMaxConcurrentPlaybacks
+	if settings != nil && settings.MaxConcurrentPlaybacks > 0 {
+		limit = settings.MaxConcurrentPlaybacks
+	}
+
+	m.playbackMu.Lock()
+	defer m.playbackMu.Unlock()
+
+	pool, ok := m.playbackPools[instanceID]
+	if !ok {
+		pool = newPlaybackPool(limit)
+		m.playbackPools[instanceID] = pool
+	} else {
+		pool.setLimit(limit)
+	}
+	pool.mu.Lock()
+	pool.isolation = playbackIsolation(settings)
+	pool.mu.Unlock()
+	return pool
+}
+
+// newPlaybackPage 按实例的隔离方式创建回放页面
+// incognito 模式下返回的 *rod.Browser 为新建的无痕上下文，页面关闭时一并销毁
+func (m *Manager) newPlaybackPage(ctx context.Context, browser *rod.Browser, instance *models.BrowserInstance, useStealth bool) (*rod.Page, *rod.Browser, error) {
+	target := browser
+	if playbackIsolation(instance) == models.PlaybackIsolationIncognito {
+		incognito, err := browser.Incognito()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create incognito context: %w", err)
+		}
+		target = incognito
+		logger.Info(ctx, "Replay using isolated incognito context")
+
+		// 无痕上下文需要单独设置下载行为
+		if m.downloadPath != "" {
+			downloadBehavior := &proto.BrowserSetDownloadBehavior{
+				Behavior:         proto.BrowserSetDownloadBehaviorBehaviorAllow,
+				BrowserContextID: incognito.BrowserContextID,
+				DownloadPath:     m.downloadPath,
+				EventsEnabled:    true,
+			}
+			if err := downloadBehavior.Call(incognito); err != nil {
+				logger.Warn(ctx, "Failed to set download behavior for incognito context: %v", err)
+			}
+		}
+	}
+
+	var page *rod.Page
+	var err error
+	if useStealth {
+		page, err = stealth.Page(target)
+		logger.Info(ctx, "Replay using Stealth mode")
+	} else {
+		page, err = target.Page(proto.TargetCreateTarget{})
+		logger.Info(ctx, "Replay not using Stealth mode")
+	}
+	if err != nil {
+		if target != browser {
+			disposeBrowserContext(target)
+		}
+		return nil, nil, fmt.Errorf("failed to create playback page: %w", err)
+	}
+
+	if target != browser {
+		m.playbackMu.Lock()
+		m.incognitoContexts[page.TargetID] = target
+		m.playbackMu.Unlock()
+	}
+	return page, target, nil
+}
+
+// releasePlaybackContext 页面关闭后销毁对应的无痕上下文
+func (m *Manager) releasePlaybackContext(page *rod.Page) {
+	m.playbackMu.Lock()
+	incognito, ok := m.incognitoContexts[page.TargetID]
+	delete(m.incognitoContexts, page.TargetID)
+	m.playbackMu.Unlock()
+
+	if ok {
+		disposeBrowserContext(incognito)
+	}
+}
+
+// closePlaybackPage 关闭回放页面并释放无痕上下文和观测器，页面关闭失败时同样释放
+// 回放的 ctx 可能已被取消，关闭时使用独立的 context
+func (m *Manager) closePlaybackPage(ctx context.Context, page *rod.Page) {
+	if page == nil {
+		return
+	}
+	if err := page.Context(context.Background()).Close(); err != nil {
+		logger.Warn(ctx, "Failed to close playback page %s: %v", page.TargetID, err)
+	}
+	m.releasePlaybackContext(page)
+	m.releasePageMonitor(page.TargetID)
+}
+
+// disposeBrowserContext 销毁无痕浏览器上下文（会关闭其中的所有页面）
+func disposeBrowserContext(b *rod.Browser) {
+	if b.BrowserContextID == "" {
+		return
+	}
+	_ = proto.TargetDisposeBrowserContext{BrowserContextID: b.BrowserContextID}.Call(b)
+}
+
+// BorrowActivePage 独占地将 page 设为活动页面，返回的函数恢复原来的活动页面
+// 用于并发回放中依赖 activePage 的步骤，避免多个回放互相覆盖
+func (m *Manager) BorrowActivePage(page *rod.Page) func() {
+	m.activePageMu.Lock()
+
+	m.mu.Lock()
+	previous := m.activePage
+	m.activePage = page
+	m.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			if m.activePage == page {
+				m.activePage = previous
+			}
+			m.mu.Unlock()
+			m.activePageMu.Unlock()
+		})
+	}
+}
+
+// GetPlaybackStats 获取各实例的回放并发状态
+func (m *Manager) GetPlaybackStats() []PlaybackPoolStats {
+	m.playbackMu.Lock()
+	defer m.playbackMu.Unlock()
+
+	stats := make([]PlaybackPoolStats, 0, len(m.playbackPools))
+	for id, pool := range m.playbackPools {
+		pool.mu.Lock()
+		stat := PlaybackPoolStats{
+			InstanceID: id,
+			Isolation:  pool.isolation,
+			Limit:      pool.limit,
+			Running:    pool.running,
+			Queued:     pool.queued,
+		}
+		pool.mu.Unlock()
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].InstanceID < stats[j].InstanceID })
+	return stats
+}
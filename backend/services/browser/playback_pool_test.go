@@ -0,0 +1,183 @@
+package browser
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/browserwing/browserwing/models"
+	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func TestPlaybackPoolAcquire(t *testing.T) {
+	logger.InitLogger(&logger.LoggerConfig{Level: "error"})
+	pool := newPlaybackPool(1)
+
+	release, err := pool.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	// 名额用尽时排队，调用方取消后退出队列
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := pool.acquire(ctx); err == nil {
+		t.Fatal("acquire() should fail when the context is cancelled while queued")
+	}
+	if pool.queued != 0 {
+		t.Errorf("queued = %d after cancellation, want 0", pool.queued)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		next, err := pool.acquire(context.Background())
+		if err == nil {
+			acquired <- next
+		}
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second playback should wait for the first to finish")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	release() // 重复释放不应多归还名额
+
+	select {
+	case next := <-acquired:
+		if pool.running != 1 {
+			t.Errorf("running = %d, want 1", pool.running)
+		}
+		next()
+	case <-time.After(time.Second):
+		t.Fatal("queued playback was not started after release")
+	}
+
+	if pool.running != 0 || len(pool.waiters) != 0 {
+		t.Errorf("pool not drained: running = %d, waiters = %d", pool.running, len(pool.waiters))
+	}
+}
+
+func TestPlaybackPoolSetLimit(t *testing.T) {
+	logger.InitLogger(&logger.LoggerConfig{Level: "error"})
+	pool := newPlaybackPool(2)
+	first, _ := pool.acquire(context.Background())
+	second, _ := pool.acquire(context.Background())
+
+	// 调小上限后，已在执行的回放结束前不会开始新的回放
+	pool.setLimit(1)
+	acquired := make(chan func(), 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			if release, err := pool.acquire(context.Background()); err == nil {
+				acquired <- release
+			}
+		}()
+	}
+	waitQueued := func(n int) {
+		deadline := time.Now().Add(time.Second)
+		for {
+			pool.mu.Lock()
+			queued := pool.queued
+			pool.mu.Unlock()
+			if queued == n || time.Now().After(deadline) {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	waitQueued(2)
+
+	first()
+	select {
+	case <-acquired:
+		t.Fatal("playback started while running exceeded the reduced limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+	second()
+
+	var third func()
+	select {
+	case third = <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("queued playback was not started after running dropped below the limit")
+	}
+
+	// 调大上限立即放行排队的回放
+	pool.setLimit(2)
+	select {
+	case fourth := <-acquired:
+		fourth()
+	case <-time.After(time.Second):
+		t.Fatal("queued playback was not started after the limit was raised")
+	}
+	third()
+
+	if pool.running != 0 || pool.queued != 0 {
+		t.Errorf("pool not drained: running = %d, queued = %d", pool.running, pool.queued)
+	}
+}
+
+func TestValidatePlaybackSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		instance models.BrowserInstance
+		wantErr  bool
+	}{
+		{name: "defaults", instance: models.BrowserInstance{}},
+		{name: "incognito", instance: models.BrowserInstance{MaxConcurrentPlaybacks: 2, PlaybackIsolation: "Incognito"}},
+		{name: "negative limit", instance: models.BrowserInstance{MaxConcurrentPlaybacks: -1}, wantErr: true},
+		{name: "unknown isolation", instance: models.BrowserInstance{PlaybackIsolation: "window"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePlaybackSettings(&tt.instance)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePlaybackSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDownloadOwnership(t *testing.T) {
+	tree := &proto.PageFrameTree{
+		Frame: &proto.PageFrame{ID: "main"},
+		ChildFrames: []*proto.PageFrameTree{
+			{Frame: &proto.PageFrame{ID: "ad"}},
+			{Frame: &proto.PageFrame{ID: "pay"}, ChildFrames: []*proto.PageFrameTree{{Frame: &proto.PageFrame{ID: "card"}}}},
+		},
+	}
+	tests := []struct {
+		frameID proto.PageFrameID
+		want    bool
+	}{
+		{frameID: "main", want: true},
+		{frameID: "ad", want: true},
+		{frameID: "card", want: true},
+		{frameID: "other-playback", want: false},
+	}
+	for _, tt := range tests {
+		if got := frameTreeContains(tree, tt.frameID); got != tt.want {
+			t.Errorf("frameTreeContains(%q) = %v, want %v", tt.frameID, got, tt.want)
+		}
+	}
+	if frameTreeContains(nil, "main") {
+		t.Error("frameTreeContains(nil) should be false")
+	}
+
+	// 主框架 ID 即页面的 TargetID，回放打开的页面直接匹配
+	player := NewPlayer("en")
+	player.trackDownloadPage(&rod.Page{TargetID: "page-1"})
+	player.trackDownloadPage(nil)
+	if !player.ownsDownloadFrame(nil, "page-1") {
+		t.Error("download from the playback's own page was not recorded")
+	}
+	if got := player.GetDownloadedFiles(); len(got) != 0 {
+		t.Errorf("GetDownloadedFiles() = %v, want empty", got)
+	}
+}
@@ -2,7 +2,9 @@ package browser
 
 import (
 	"context"
+	"crypto/sha256"
 	_ "embed"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"image"
@@ -17,6 +19,7 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/browserwing/browserwing/models"
@@ -35,28 +38,63 @@ var indicatorScript string
 type BrowserManagerInterface interface {
 	SetActivePage(page *rod.Page)
 	GetActivePage() *rod.Page
+	BorrowActivePage(page *rod.Page) func()
+	MonitorPage(page *rod.Page) *PageMonitor
+	GetPageMonitor(page *rod.Page) *PageMonitor
+	SetPageRouteRules(page *rod.Page, rules []models.RouteRule) error
 }
 
 type Player struct {
-	extractedData     map[string]interface{}          // 存储抓取的数据
-	successCount      int                             // 成功步骤数
-	failCount         int                             // 失败步骤数
-	recordingPage     *rod.Page                       // 录制的页面
-	recordingOutputs  chan *proto.PageScreencastFrame // 录制帧通道
-	recordingDone     chan bool                       // 录制完成信号
-	pages             map[int]*rod.Page               // 多标签页支持 (key: tab index)
-	currentPage       *rod.Page                       // 当前活动页面
-	tabCounter        int                             // 标签页计数器
-	downloadedFiles   []string                        // 下载的文件路径列表
-	downloadPath      string                          // 下载目录路径
-	downloadCtx       context.Context                 // 下载监听上下文
-	downloadCancel    context.CancelFunc              // 取消下载监听
-	currentScriptName string                          // 当前执行的脚本名称
-	currentLang       string                          // 当前语言设置
-	currentActions    []models.ScriptAction           // 当前执行的脚本动作列表
-	currentStepIndex  int                             // 当前执行到的步骤索引
-	agentManager      AgentManagerInterface           // Agent 管理器（用于 AI 控制功能）
-	browserManager    BrowserManagerInterface         // Browser 管理器（用于同步活跃页面）
+	extractedData      map[string]interface{}          // 存储抓取的数据
+	successCount       int                             // 成功步骤数
+	failCount          int                             // 失败步骤数
+	recordingPage      *rod.Page                       // 录制的页面
+	recordingOutputs   chan *proto.PageScreencastFrame // 录制帧通道
+	recordingDone      chan bool                       // 录制完成信号
+	pages              map[int]*rod.Page               // 多标签页支持 (key: tab index)
+	currentPage        *rod.Page                       // 当前活动页面
+	tabCounter         int                             // 标签页计数器
+	downloadedFiles    []string                        // 下载的文件路径列表
+	downloadPath       string                          // 下载目录路径
+	downloadCtx        context.Context                 // 下载监听上下文
+	downloadCancel     context.CancelFunc              // 取消下载监听
+	currentScriptName  string                          // 当前执行的脚本名称
+	currentLang        string                          // 当前语言设置
+	currentActions     []models.ScriptAction           // 当前执行的脚本动作列表
+	currentStepIndex   int                             // 当前执行到的步骤索引
+	agentManager       AgentManagerInterface           // Agent 管理器（用于 AI 控制功能）
+	browserManager     BrowserManagerInterface         // Browser 管理器（用于同步活跃页面）
+	routeRules         []models.RouteRule              // 请求拦截规则（应用到回放中新打开的标签页）
+	abortedStep        *models.StepFailure             // 按失败策略终止回放的步骤
+	stepTraces         []models.StepTrace              // 步骤执行轨迹
+	matchedBy          string                          // 当前步骤实际命中的定位方式
+	matchedLocator     string                          // 当前步骤实际命中的定位表达式
+	traceScreenshotDir string                          // 失败截图保存目录（为空则不截图）
+	matchConfidence    float64                         // 语义自愈的匹配置信度
+	healedLocators     []HealedLocator                 // 本次回放中语义自愈找到的定位
+	currentScript      *models.Script                  // 当前执行的脚本（循环体步骤解析失败策略）
+	variables          map[string]string               // 回放变量上下文（循环体中抓取的变量会写回）
+	loopDepth          int                             // 当前循环嵌套深度
+	nestedSteps        []int                           // 正在执行的循环体或子脚本步骤相对顶层步骤的索引路径
+	loopIterations     int                             // 最近一次顶层循环的迭代次数
+	scriptLoader       ScriptLoader                    // 加载 call_script 调用的脚本
+	callStack          []string                        // 当前脚本调用链（用于检测循环调用）
+	assertionFailures  []string                        // 软断言失败信息（回放结束后整体判定为失败）
+	debugger           *DebugSession                   // 调试会话，非空时按断点/单步暂停
+	debugResume        bool                            // 调试中重试失败步骤时跳过下一次断点暂停
+
+	// 下载事件是浏览器级别的，在独立的 goroutine 中处理；downloadMu 保护 downloadedFiles 和以下字段
+	downloadMu    sync.Mutex
+	downloadGUIDs map[string]string                  // 本次回放发起的下载 GUID -> 建议文件名
+	downloadPages map[proto.TargetTargetID]*rod.Page // 本次回放打开的页面，只记录这些页面发起的下载
+}
+
+// maskSecrets 隐藏日志中的敏感变量值
+func (p *Player) maskSecrets(text string) string {
+	if p.currentScript == nil {
+		return text
+	}
+	return p.currentScript.MaskSecrets(text)
 }
 
 // highlightElement 高亮显示元素
@@ -511,7 +549,8 @@ func (p *Player) SetDownloadPath(downloadPath string) {
 }
 
 // StartDownloadListener 启动下载事件监听
-func (p *Player) StartDownloadListener(ctx context.Context, browser *rod.Browser) {
+// 下载事件是浏览器级别的，并发回放时只记录 page（及回放中打开的页面）发起的下载
+func (p *Player) StartDownloadListener(ctx context.Context, browser *rod.Browser, page *rod.Page) {
 	if p.downloadPath == "" {
 		logger.Warn(ctx, "Download path not set, skipping download listener")
 		return
@@ -519,73 +558,136 @@ func (p *Player) StartDownloadListener(ctx context.Context, browser *rod.Browser
 
 	// 创建可取消的上下文
 	p.downloadCtx, p.downloadCancel = context.WithCancel(ctx)
+	p.downloadMu.Lock()
+	p.downloadGUIDs = make(map[string]string)
+	p.downloadMu.Unlock()
+	p.trackDownloadPage(page)
 
 	logger.Info(ctx, "Starting download event listener for path: %s", p.downloadPath)
 
-	// 记录每个下载的 GUID 到文件名的映射
-	downloadMap := make(map[string]string)
-
 	// 监听下载开始事件 (BrowserDownloadWillBegin)
 	go browser.Context(p.downloadCtx).EachEvent(func(e *proto.BrowserDownloadWillBegin) {
+		if !p.ownsDownloadFrame(browser, e.FrameID) {
+			return
+		}
 		// 记录 GUID 和建议的文件名
-		downloadMap[e.GUID] = e.SuggestedFilename
+		p.downloadMu.Lock()
+		p.downloadGUIDs[e.GUID] = e.SuggestedFilename
+		p.downloadMu.Unlock()
 		logger.Info(ctx, "📥 Download will begin: %s (GUID: %s)", e.SuggestedFilename, e.GUID)
 	})()
 
 	// 监听下载进度事件 (BrowserDownloadProgress)
 	go browser.Context(p.downloadCtx).EachEvent(func(e *proto.BrowserDownloadProgress) {
-		if e.State == proto.BrowserDownloadProgressStateCompleted {
-			// 下载完成，从映射中获取文件名
-			fileName, exists := downloadMap[e.GUID]
-			if !exists {
-				logger.Warn(ctx, "Download completed but filename not found (GUID: %s)", e.GUID)
-				return
-			}
-			// 如果是截图，则也不进行在这里监听返回，包含 browserwing_screenshot_ 前缀
-			if strings.Contains(fileName, "browserwing_screenshot_") {
-				return
-			}
+		if e.State != proto.BrowserDownloadProgressStateCompleted && e.State != proto.BrowserDownloadProgressStateCanceled {
+			return
+		}
+		// 从映射中获取文件名，其他回放发起的下载不在映射中
+		p.downloadMu.Lock()
+		fileName, exists := p.downloadGUIDs[e.GUID]
+		delete(p.downloadGUIDs, e.GUID)
+		p.downloadMu.Unlock()
+		if !exists {
+			return
+		}
+		if e.State == proto.BrowserDownloadProgressStateCanceled {
+			logger.Warn(ctx, "Download canceled (GUID: %s)", e.GUID)
+			return
+		}
 
-			// 构建完整路径
-			fullPath := filepath.Join(p.downloadPath, fileName)
+		// 如果是截图，则也不进行在这里监听返回，包含 browserwing_screenshot_ 前缀
+		if strings.Contains(fileName, "browserwing_screenshot_") {
+			return
+		}
 
-			// 检查文件是否实际存在（可能浏览器自动重命名了）
-			if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-				// 文件不存在，可能被重命名了（如 file.pdf -> file (1).pdf）
-				// 尝试查找类似的文件
-				if actualFile := p.findSimilarFile(fileName); actualFile != "" {
-					fullPath = filepath.Join(p.downloadPath, actualFile)
-					fileName = actualFile
-					logger.Info(ctx, "File was renamed by browser: %s -> %s", downloadMap[e.GUID], actualFile)
-				}
-			}
+		// 构建完整路径
+		fullPath := filepath.Join(p.downloadPath, fileName)
 
-			// 检查是否已经记录过这个文件
-			alreadyRecorded := false
-			for _, existing := range p.downloadedFiles {
-				if existing == fullPath {
-					alreadyRecorded = true
-					break
-				}
+		// 检查文件是否实际存在（可能浏览器自动重命名了）
+		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+			// 文件不存在，可能被重命名了（如 file.pdf -> file (1).pdf）
+			// 尝试查找类似的文件
+			if actualFile := p.findSimilarFile(fileName); actualFile != "" {
+				logger.Info(ctx, "File was renamed by browser: %s -> %s", fileName, actualFile)
+				fullPath = filepath.Join(p.downloadPath, actualFile)
 			}
+		}
 
-			if !alreadyRecorded {
-				p.downloadedFiles = append(p.downloadedFiles, fullPath)
-				logger.Info(ctx, "✓ Download completed: %s (%.2f MB, GUID: %s)",
-					fullPath, float64(e.TotalBytes)/(1024*1024), e.GUID)
+		// 检查是否已经记录过这个文件
+		p.downloadMu.Lock()
+		defer p.downloadMu.Unlock()
+		for _, existing := range p.downloadedFiles {
+			if existing == fullPath {
+				return
 			}
-
-			// 清理映射
-			delete(downloadMap, e.GUID)
-		} else if e.State == proto.BrowserDownloadProgressStateCanceled {
-			logger.Warn(ctx, "Download canceled (GUID: %s)", e.GUID)
-			delete(downloadMap, e.GUID)
 		}
+		p.downloadedFiles = append(p.downloadedFiles, fullPath)
+		logger.Info(ctx, "✓ Download completed: %s (%.2f MB, GUID: %s)",
+			fullPath, float64(e.TotalBytes)/(1024*1024), e.GUID)
 	})()
 
 	logger.Info(ctx, "Download event listener started")
 }
 
+// trackDownloadPage 记录本次回放打开的页面，其中发起的下载归属本次回放
+func (p *Player) trackDownloadPage(page *rod.Page) {
+	if page == nil {
+		return
+	}
+	p.downloadMu.Lock()
+	defer p.downloadMu.Unlock()
+	if p.downloadPages == nil {
+		p.downloadPages = make(map[proto.TargetTargetID]*rod.Page)
+	}
+	p.downloadPages[page.TargetID] = page
+}
+
+// ownsDownloadFrame 下载是否由本次回放的页面发起：页面主框架、页面中的 iframe，或由这些页面打开的弹窗
+func (p *Player) ownsDownloadFrame(browser *rod.Browser, frameID proto.PageFrameID) bool {
+	p.downloadMu.Lock()
+	if _, ok := p.downloadPages[proto.TargetTargetID(frameID)]; ok {
+		p.downloadMu.Unlock()
+		return true
+	}
+	pages := make([]*rod.Page, 0, len(p.downloadPages))
+	for _, page := range p.downloadPages {
+		pages = append(pages, page)
+	}
+	p.downloadMu.Unlock()
+
+	for _, page := range pages {
+		if tree, err := (proto.PageGetFrameTree{}).Call(page); err == nil && frameTreeContains(tree.FrameTree, frameID) {
+			return true
+		}
+	}
+
+	// 弹窗的主框架 ID 即其 TargetID，由回放页面打开的弹窗同样归属本次回放
+	info, err := proto.TargetGetTargetInfo{TargetID: proto.TargetTargetID(frameID)}.Call(browser)
+	if err != nil || info.TargetInfo.OpenerID == "" {
+		return false
+	}
+	p.downloadMu.Lock()
+	defer p.downloadMu.Unlock()
+	_, ok := p.downloadPages[info.TargetInfo.OpenerID]
+	return ok
+}
+
+// frameTreeContains 框架树中是否包含指定框架
+func frameTreeContains(tree *proto.PageFrameTree, frameID proto.PageFrameID) bool {
+	if tree == nil {
+		return false
+	}
+	if tree.Frame != nil && tree.Frame.ID == frameID {
+		return true
+	}
+	for _, child := range tree.ChildFrames {
+		if frameTreeContains(child, frameID) {
+			return true
+		}
+	}
+	return false
+}
+
 // findSimilarFile 查找相似的文件名（处理浏览器自动重命名的情况）
 func (p *Player) findSimilarFile(originalName string) string {
 	entries, err := os.ReadDir(p.downloadPath)
@@ -627,9 +729,9 @@ func (p *Player) StopDownloadListener(ctx context.Context) {
 	}
 
 	// 记录最终下载的文件
-	if len(p.downloadedFiles) > 0 {
-		logger.Info(ctx, "✓ Total downloaded files: %d", len(p.downloadedFiles))
-		for i, file := range p.downloadedFiles {
+	if downloadedFiles := p.GetDownloadedFiles(); len(downloadedFiles) > 0 {
+		logger.Info(ctx, "✓ Total downloaded files: %d", len(downloadedFiles))
+		for i, file := range downloadedFiles {
 			logger.Info(ctx, "  #%d: %s", i+1, file)
 		}
 	} else {
@@ -639,7 +741,9 @@ func (p *Player) StopDownloadListener(ctx context.Context) {
 
 // GetDownloadedFiles 获取下载的文件列表
 func (p *Player) GetDownloadedFiles() []string {
-	return p.downloadedFiles
+	p.downloadMu.Lock()
+	defer p.downloadMu.Unlock()
+	return append([]string(nil), p.downloadedFiles...)
 }
 
 // GetExtractedData 获取抓取的数据
@@ -657,10 +761,26 @@ func (p *Player) GetFailCount() int {
 	return p.failCount
 }
 
+// GetAbortedStep 获取导致回放终止的步骤（未终止时为 nil）
+func (p *Player) GetAbortedStep() *models.StepFailure {
+	return p.abortedStep
+}
+
+// GetAssertionFailures 获取软断言失败信息
+func (p *Player) GetAssertionFailures() []string {
+	return p.assertionFailures
+}
+
 // ResetStats 重置统计信息
 func (p *Player) ResetStats() {
 	p.successCount = 0
 	p.failCount = 0
+	p.abortedStep = nil
+	p.assertionFailures = nil
+	p.stepTraces = nil
+	p.healedLocators = nil
+	p.nestedSteps = nil
+	p.loopIterations = 0
 	p.extractedData = make(map[string]interface{})
 	// 注意：不清空录制相关字段，因为录制可能在 PlayScript 之前就已经启动
 	// 录制字段只在 StopVideoRecording 中清空
@@ -977,9 +1097,12 @@ func (p *Player) PlayScript(ctx context.Context, page *rod.Page, script *models.
 	if script.Variables != nil {
 		for k, v := range script.Variables {
 			variables[k] = v
-			logger.Info(ctx, "Initialize variable: %s = %s", k, v)
+			logger.Info(ctx, "Initialize variable: %s = %s", k, script.MaskVariable(k, v))
 		}
 	}
+	p.variables = variables
+	p.currentScript = script
+	p.callStack = []string{script.ID}
 
 	// 初始化多标签页支持
 	p.pages = make(map[int]*rod.Page)
@@ -1015,20 +1138,31 @@ func (p *Player) PlayScript(ctx context.Context, page *rod.Page, script *models.
 	// 初始化步骤列表
 	p.initAIControlSteps(ctx, page, script.Actions)
 
-	// 预先注入XHR拦截器，监听脚本中所有需要捕获的XHR请求
-	// 这样可以避免在执行到capture_xhr action时才开始监听，导致漏掉前面的请求
-	if err := p.injectXHRInterceptorForScript(ctx, page, script.Actions); err != nil {
-		logger.Warn(ctx, "Failed to inject XHR interceptor: %v", err)
-	}
-
-	// 执行每个操作
-	for i, action := range script.Actions {
+	// 执行每个操作（调试模式下重试失败步骤会回退索引）
+	for i := 0; i < len(script.Actions); i++ {
+		action := script.Actions[i]
 		p.currentStepIndex = i
 		logger.Info(ctx, "[%d/%d] Execute action: %s", i+1, len(script.Actions), action.Type)
 
 		// 更新 AI 控制状态显示（标记为执行中）
 		p.updateAIControlStatus(ctx, page, i+1, len(script.Actions), action.Type)
 
+		// 调试模式：在断点处或单步时暂停，等待调试命令
+		execute, err := p.debugBeforeStep(ctx, page, script, i, &action)
+		if err != nil {
+			return err
+		}
+		if !execute {
+			logger.Info(ctx, "Skipping action by debugger: %s", action.Type)
+			p.markStepCompleted(ctx, page, i+1, true)
+			p.finishStepTrace(ctx, p.beginStepTrace(i, action), models.StepStatusSkipped, 0, nil)
+			p.debugStepFinished(i, action, models.StepStatusSkipped, nil)
+			continue
+		}
+
+		trace := p.beginStepTrace(i, action)
+		p.loopIterations = 0
+
 		// 检查条件执行
 		if action.Condition != nil && action.Condition.Enabled {
 			shouldExecute, err := p.evaluateCondition(ctx, action.Condition, variables)
@@ -1039,6 +1173,8 @@ func (p *Player) PlayScript(ctx context.Context, page *rod.Page, script *models.
 					action.Condition.Variable, action.Condition.Operator, action.Condition.Value)
 				// 标记为跳过（视为成功）
 				p.markStepCompleted(ctx, page, i+1, true)
+				p.finishStepTrace(ctx, trace, models.StepStatusSkipped, 0, nil)
+				p.debugStepFinished(i, action, models.StepStatusSkipped, nil)
 				continue
 			}
 			logger.Info(ctx, "Condition met, executing action: %s %s %s",
@@ -1048,20 +1184,70 @@ func (p *Player) PlayScript(ctx context.Context, page *rod.Page, script *models.
 		// 执行操作前临时禁用指示器面板的鼠标事件，避免遮挡目标元素导致点击失败
 		p.disableIndicatorInteraction(ctx, page)
 
-		if err := p.executeAction(ctx, page, action); err != nil {
-			logger.Warn(ctx, "Action execution failed (continuing with subsequent steps): %v", err)
+		policy := resolveActionPolicy(script, action)
+		if attempts, timedOut, err := p.runActionWithPolicy(ctx, page, action, policy); err != nil {
 			p.failCount++
 			// 恢复指示器交互
 			p.enableIndicatorInteraction(ctx, page)
 			// 标记步骤为失败
 			p.markStepCompleted(ctx, page, i+1, false)
-			// 不要中断，继续执行下一步
+			p.finishStepTrace(ctx, trace, models.StepStatusFailed, attempts, err)
+
+			// 调试模式：失败后暂停，可修改步骤后重试、跳过或终止；continue / step 按失败策略处理
+			if p.debugger != nil {
+				failed := action
+				cmd := p.debugAfterFailure(ctx, page, script, i, &action, err)
+				if cmd == DebugCommandRetry {
+					// 重试的结果替换本次失败记录
+					p.failCount--
+					p.discardLastStepTrace(ctx)
+					i--
+					continue
+				}
+				p.debugStepFinished(i, failed, models.StepStatusFailed, err)
+				switch cmd {
+				case DebugCommandSkip:
+					continue
+				case DebugCommandAbort:
+					return ErrDebugAborted
+				}
+			}
+
+			// 软断言失败：记录后继续；硬断言失败：无论失败策略如何都终止回放
+			assertErr, isAssertion := asAssertionError(err)
+			if isAssertion && assertErr.IsSoft() {
+				p.recordSoftAssertion(ctx, fmt.Sprintf("step %d", i+1), assertErr)
+				continue
+			}
+			if policy.OnError == models.OnErrorContinue && !isAssertion {
+				// 默认策略：不中断，继续执行下一步
+				logger.Warn(ctx, "Action execution failed (continuing with subsequent steps): %v", err)
+				continue
+			}
+
+			// abort / retry 耗尽 / 硬断言失败：终止回放并记录导致终止的步骤
+			selector := action.XPath
+			if selector == "" {
+				selector = action.Selector
+			}
+			p.abortedStep = &models.StepFailure{
+				Index:    i + 1,
+				Type:     action.Type,
+				Selector: selector,
+				Error:    err.Error(),
+				Attempts: attempts,
+				TimedOut: timedOut,
+			}
+			logger.Error(ctx, "Action execution failed after %d attempt(s), aborting playback (on_error=%s): %v", attempts, policy.OnError, err)
+			return fmt.Errorf("step %d (%s) failed, playback aborted: %w", i+1, action.Type, err)
 		} else {
 			p.successCount++
 			// 恢复指示器交互
 			p.enableIndicatorInteraction(ctx, page)
 			// 标记步骤为成功
 			p.markStepCompleted(ctx, page, i+1, true)
+			p.finishStepTrace(ctx, trace, models.StepStatusSuccess, attempts, nil)
+			p.debugStepFinished(i, action, models.StepStatusSuccess, nil)
 
 			// 如果 action 提取了数据，更新变量上下文
 			if action.VariableName != "" && p.extractedData[action.VariableName] != nil {
@@ -1081,9 +1267,21 @@ func (p *Player) PlayScript(ctx context.Context, page *rod.Page, script *models.
 		return fmt.Errorf("all operations failed")
 	}
 
+	// 软断言失败不中断回放，但整体判定为失败
+	if n := len(p.assertionFailures); n > 0 {
+		return fmt.Errorf("%d soft assertion(s) failed", n)
+	}
+
 	return nil
 }
 
+// recordSoftAssertion 记录软断言失败，回放结束后写入 PlayResult.Errors
+func (p *Player) recordSoftAssertion(ctx context.Context, step string, assertErr *AssertionError) {
+	message := fmt.Sprintf("%s: %s", step, assertErr.Error())
+	p.assertionFailures = append(p.assertionFailures, message)
+	logger.Warn(ctx, "Soft assertion failed (continuing with subsequent steps): %s", message)
+}
+
 // evaluateCondition 评估操作执行条件
 func (p *Player) evaluateCondition(ctx context.Context, condition *models.ActionCondition, variables map[string]string) (bool, error) {
 	if condition == nil {
@@ -1241,10 +1439,20 @@ func (p *Player) executeAction(ctx context.Context, page *rod.Page, action model
 		return p.executeKeyboard(ctx, activePage, action)
 	case "screenshot":
 		return p.executeScreenshot(ctx, activePage, action)
+	case models.ActionTypePDF:
+		return p.executePDF(ctx, activePage, action)
+	case models.ActionTypeArchive:
+		return p.executeArchive(ctx, activePage, action)
+	case models.ActionTypeAssert:
+		return p.executeAssert(ctx, activePage, action)
 	case "capture_xhr":
 		return p.executeCaptureXHR(ctx, activePage, action)
 	case "ai_control":
 		return p.executeAIControl(ctx, activePage, action)
+	case models.ActionTypeLoopElements, models.ActionTypeLoopList, models.ActionTypeLoopWhile:
+		return p.executeLoop(ctx, page, action)
+	case models.ActionTypeCallScript:
+		return p.executeCallScript(ctx, page, action)
 	default:
 		logger.Warn(ctx, "Unknown action type: %s", action.Type)
 		return nil
@@ -1348,9 +1556,9 @@ func (p *Player) executeInput(ctx context.Context, page *rod.Page, action models
 	selector := action.Selector
 	if action.XPath != "" {
 		selector = action.XPath
-		logger.Info(ctx, "Input text (XPath): %s -> %s", selector, action.Value)
+		logger.Info(ctx, "Input text (XPath): %s -> %s", selector, p.maskSecrets(action.Value))
 	} else {
-		logger.Info(ctx, "Input text (CSS): %s -> %s", selector, action.Value)
+		logger.Info(ctx, "Input text (CSS): %s -> %s", selector, p.maskSecrets(action.Value))
 	}
 
 	// 使用新的 findElement 方法（支持 iframe）
@@ -1775,6 +1983,11 @@ func (p *Player) findElementWithContext(ctx context.Context, page *rod.Page, act
 
 			if findErr == nil && element != nil {
 				logger.Info(ctx, "✓ Found element in iframe #%d", i)
+				if innerXPath != "" {
+					p.recordMatch(MatchedByIframeXPath, fmt.Sprintf("iframe[%d] %s", i, innerXPath))
+				} else {
+					p.recordMatch(MatchedByIframeCSS, fmt.Sprintf("iframe[%d] %s", i, innerCSS))
+				}
 				// 返回元素及其所在的 frame 作为页面上下文
 				return &elementContext{
 					element: element,
@@ -1793,17 +2006,41 @@ func (p *Player) findElementWithContext(ctx context.Context, page *rod.Page, act
 	var err error
 
 	if xpath != "" {
-		element, err = page.Timeout(5 * time.Second).ElementX(xpath)
-		if err != nil && selector != "" && selector != "unknown" {
+		element, err = findByLocator(page.Timeout(5*time.Second), xpath, true)
+		if err == nil {
+			p.recordMatch(MatchedByXPath, xpath)
+		} else if selector != "" && selector != "unknown" {
 			logger.Warn(ctx, "XPath lookup failed, trying CSS: %v", err)
-			element, err = page.Timeout(5 * time.Second).Element(selector)
+			element, err = findByLocator(page.Timeout(5*time.Second), selector, false)
+			if err == nil {
+				p.recordMatch(MatchedByCSSFallback, selector)
+			}
 		}
 	} else if selector != "" && selector != "unknown" {
-		element, err = page.Timeout(5 * time.Second).Element(selector)
-	} else {
+		element, err = findByLocator(page.Timeout(5*time.Second), selector, false)
+		if err == nil {
+			p.recordMatch(MatchedByCSS, selector)
+		}
+	} else if !hasSemanticInfo(action) {
 		return nil, fmt.Errorf("missing valid selector")
 	}
 
+	// XPath 和 CSS 都未命中时，使用录制的语义信息自愈定位
+	if element == nil && hasSemanticInfo(action) {
+		healed, candidate, healErr := p.healElement(ctx, page, action)
+		if healErr == nil {
+			element, err = healed, nil
+			p.recordMatch(MatchedBySemantic, candidate.features.XPath)
+			p.matchConfidence = candidate.score
+			p.recordHealed(action, candidate)
+		} else {
+			logger.Warn(ctx, "[SelfHeal] Semantic fallback failed: %v", healErr)
+			if err == nil {
+				err = healErr
+			}
+		}
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -2755,12 +2992,7 @@ func (p *Player) executeScreenshot(ctx context.Context, page *rod.Page, action m
 	// 如果有自定义变量名，使用它作为文件名前缀
 	if action.VariableName != "" {
 		// 清理变量名，移除非法字符
-		cleanName := strings.Map(func(r rune) rune {
-			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
-				return r
-			}
-			return '_'
-		}, action.VariableName)
+		cleanName := sanitizeFileName(action.VariableName)
 		fileName = fmt.Sprintf("%s_%s_%s.png", cleanName, mode, timestamp)
 	}
 
@@ -2793,6 +3025,97 @@ func (p *Player) executeScreenshot(ctx context.Context, page *rod.Page, action m
 	return nil
 }
 
+// executePDF 将当前页面打印为 PDF 并保存到下载目录
+func (p *Player) executePDF(ctx context.Context, page *rod.Page, action models.ScriptAction) error {
+	logger.Info(ctx, "Printing page to PDF")
+
+	data, err := PrintToPDF(ctx, page, action.PDF)
+	if err != nil {
+		return err
+	}
+	return p.savePageCapture(ctx, page, action, "pdf", "pdf", data)
+}
+
+// executeArchive 将当前页面保存为 MHTML 存档并保存到下载目录
+func (p *Player) executeArchive(ctx context.Context, page *rod.Page, action models.ScriptAction) error {
+	logger.Info(ctx, "Archiving page as MHTML")
+
+	data, err := CaptureMHTML(ctx, page)
+	if err != nil {
+		return err
+	}
+	return p.savePageCapture(ctx, page, action, "archive", "mhtml", data)
+}
+
+// savePageCapture 将 PDF 或 MHTML 存档保存到下载目录，并把文件信息（含页面地址和 SHA-256）存入抓取数据
+func (p *Player) savePageCapture(ctx context.Context, page *rod.Page, action models.ScriptAction, kind, ext string, data []byte) error {
+	if p.downloadPath == "" {
+		return fmt.Errorf("download path not set")
+	}
+	if err := os.MkdirAll(p.downloadPath, 0o755); err != nil {
+		return fmt.Errorf("failed to create download directory: %w", err)
+	}
+
+	timestamp := time.Now().Format("20060102_150405")
+	fileName := fmt.Sprintf("browserwing_%s_%s.%s", kind, timestamp, ext)
+	if action.VariableName != "" {
+		fileName = fmt.Sprintf("%s_%s.%s", sanitizeFileName(action.VariableName), timestamp, ext)
+	}
+	fullPath := filepath.Join(p.downloadPath, fileName)
+	if err := os.WriteFile(fullPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to save %s to file: %w", kind, err)
+	}
+
+	varName := action.VariableName
+	if varName == "" {
+		varName = fmt.Sprintf("%s_%d", kind, len(p.extractedData))
+	}
+
+	sum := sha256.Sum256(data)
+	captureData := map[string]interface{}{
+		"path":      fullPath,
+		"fileName":  fileName,
+		"format":    ext,
+		"size":      len(data),
+		"sha256":    hex.EncodeToString(sum[:]),
+		"timestamp": time.Now().Format(time.RFC3339),
+	}
+	if info, err := page.Info(); err == nil {
+		captureData["url"] = info.URL
+		captureData["title"] = info.Title
+	}
+	p.extractedData[varName] = captureData
+
+	logger.Info(ctx, "✓ Page %s saved successfully: %s (path: %s, size: %d bytes)", kind, varName, fullPath, len(data))
+	return nil
+}
+
+// executeAssert 执行断言，未通过时返回 AssertionError
+// 断言直接使用录制的定位器，不做语义自愈，避免自愈掩盖页面变化
+func (p *Player) executeAssert(ctx context.Context, page *rod.Page, action models.ScriptAction) error {
+	if err := ValidateAssertOptions(action.Assert); err != nil {
+		return err
+	}
+
+	var query ElementQuery
+	if action.XPath != "" {
+		query = LocatorQuery(action.XPath, true)
+	} else if action.Selector != "" {
+		query = LocatorQuery(action.Selector, false)
+	}
+
+	result, err := EvaluateAssertion(ctx, page, action.Assert, p.variables, query)
+	if err != nil {
+		return err
+	}
+	if !result.Passed {
+		return &AssertionError{Result: result}
+	}
+
+	logger.Info(ctx, "✓ Assertion passed: %s (actual: %s)", result.Kind, result.Actual)
+	return nil
+}
+
 // executeOpenTab 执行打开新标签页操作
 func (p *Player) executeOpenTab(ctx context.Context, page *rod.Page, action models.ScriptAction) error {
 	url := action.URL
@@ -2805,11 +3128,22 @@ func (p *Player) executeOpenTab(ctx context.Context, page *rod.Page, action mode
 	// 获取浏览器实例
 	browser := page.Browser()
 
-	// 创建新页面（新标签页）
-	newPage, err := browser.Page(proto.TargetCreateTarget{URL: url})
+	// 创建新页面（新标签页），先挂载页面观测器再导航
+	newPage, err := browser.Page(proto.TargetCreateTarget{})
 	if err != nil {
 		return fmt.Errorf("failed to create new tab: %w", err)
 	}
+	if p.browserManager != nil {
+		p.browserManager.MonitorPage(newPage)
+		if len(p.routeRules) > 0 {
+			if err := p.browserManager.SetPageRouteRules(newPage, p.routeRules); err != nil {
+				logger.Warn(ctx, "Failed to apply route rules to new tab: %v", err)
+			}
+		}
+	}
+	if err := newPage.Navigate(url); err != nil {
+		return fmt.Errorf("failed to navigate new tab: %w", err)
+	}
 
 	// 等待新页面加载
 	if err := newPage.WaitLoad(); err != nil {
@@ -2817,6 +3151,7 @@ func (p *Player) executeOpenTab(ctx context.Context, page *rod.Page, action mode
 	}
 
 	// 将新页面添加到 pages map
+	p.trackDownloadPage(newPage)
 	p.tabCounter++
 	tabIndex := p.tabCounter
 	p.pages[tabIndex] = newPage
@@ -2966,248 +3301,63 @@ func (p *Player) executeSwitchTab(ctx context.Context, action models.ScriptActio
 	return nil
 }
 
-// injectXHRInterceptorForScript 在脚本开始执行时注入XHR拦截器，提前监听所有需要捕获的请求
-func (p *Player) injectXHRInterceptorForScript(ctx context.Context, page *rod.Page, actions []models.ScriptAction) error {
-	// 收集所有需要监听的 capture_xhr action
-	var captureTargets []map[string]string
-	for _, action := range actions {
-		if action.Type == "capture_xhr" && action.URL != "" && action.Method != "" {
-			captureTargets = append(captureTargets, map[string]string{
-				"method": action.Method,
-				"url":    action.URL,
-			})
-		}
-	}
-
-	// 如果没有 capture_xhr action，不需要注入
-	if len(captureTargets) == 0 {
-		logger.Info(ctx, "No capture_xhr actions found, skipping XHR interceptor injection")
-		return nil
-	}
-
-	logger.Info(ctx, "Injecting XHR interceptor for %d capture targets", len(captureTargets))
-
-	// 将目标列表转换为JSON
-	targetsJSON, err := json.Marshal(captureTargets)
-	if err != nil {
-		return fmt.Errorf("failed to marshal capture targets: %w", err)
-	}
-
-	// 注入拦截器脚本
-	_, err = page.Eval(`(captureTargetsJSON) => {
-		if (window.__xhrCaptureInstalled__) {
-			console.log('[BrowserWing Player] XHR interceptor already installed');
-			return true;
-		}
-		
-		window.__xhrCaptureInstalled__ = true;
-		window.__capturedXHRData__ = {};
-		
-		// 解析需要监听的目标列表
-		var captureTargets = JSON.parse(captureTargetsJSON);
-		var targetKeys = new Set();
-		captureTargets.forEach(function(target) {
-			var key = target.method + '|' + target.url;
-			targetKeys.add(key);
-			console.log('[BrowserWing Player] Will capture:', key);
-		});
-		
-		// 辅助函数：提取域名+路径（不带参数）
-		var extractDomainAndPath = function(url) {
-			try {
-				var fullUrl = url;
-				
-				// 处理 // 开头的协议相对URL（如 //cdn.example.com/api）
-				if (url.indexOf('//') === 0) {
-					fullUrl = window.location.protocol + url;
-				}
-				// 处理相对路径（不包含域名的路径）
-				else if (url.indexOf('http') !== 0 && url.indexOf('//') !== 0) {
-					// 拼接当前页面的origin
-					if (url.startsWith('/')) {
-						fullUrl = window.location.origin + url;
-					} else {
-						fullUrl = window.location.origin + '/' + url;
-					}
-				}
-				
-				var urlObj = new URL(fullUrl);
-				// 返回 域名+路径（不带参数和hash）
-				return urlObj.origin + urlObj.pathname;
-			} catch (e) {
-				console.warn('[BrowserWing Player] Failed to parse URL:', url, e);
-				return url.split('?')[0].split('#')[0];
-			}
-		};
-		
-		// 拦截XMLHttpRequest
-		var originalXHROpen = XMLHttpRequest.prototype.open;
-		var originalXHRSend = XMLHttpRequest.prototype.send;
-		
-		XMLHttpRequest.prototype.open = function(method, url) {
-			this.__xhrInfo__ = {
-				method: method,
-				url: url,
-				domainAndPath: extractDomainAndPath(url)
-			};
-			return originalXHROpen.apply(this, arguments);
-		};
-		
-		XMLHttpRequest.prototype.send = function(body) {
-			var xhr = this;
-			var xhrInfo = xhr.__xhrInfo__;
-			
-			if (xhrInfo) {
-				xhr.addEventListener('readystatechange', function() {
-					if (xhr.readyState === 4) {
-						var key = xhrInfo.method + '|' + xhrInfo.domainAndPath;
-						
-						// 只存储我们需要监听的请求
-						if (!targetKeys.has(key)) {
-							return;
-						}
-						
-						var response = null;
-						
-						try {
-							if (xhr.responseType === '' || xhr.responseType === 'text') {
-								response = xhr.responseText;
-							} else if (xhr.responseType === 'json') {
-								response = xhr.response;
-							} else {
-								response = '[Binary Data]';
-							}
-						} catch (e) {
-							response = '[Error reading response]';
-						}
-						
-						window.__capturedXHRData__[key] = {
-							method: xhrInfo.method,
-							url: xhrInfo.domainAndPath,
-							status: xhr.status,
-							statusText: xhr.statusText,
-							response: response,
-							timestamp: Date.now()
-						};
-						console.log('[BrowserWing Player] Captured XHR:', key, 'Status:', xhr.status);
-					}
-				});
-			}
-			
-			return originalXHRSend.apply(this, arguments);
-		};
-		
-		// 拦截Fetch API
-		var originalFetch = window.fetch;
-		window.fetch = function(input, init) {
-			var url = typeof input === 'string' ? input : input.url;
-			var method = (init && init.method) || 'GET';
-			var domainAndPath = extractDomainAndPath(url);
-			var key = method.toUpperCase() + '|' + domainAndPath;
-			
-			// 只拦截我们需要监听的请求
-			if (!targetKeys.has(key)) {
-				return originalFetch.apply(this, arguments);
-			}
-			
-			return originalFetch.apply(this, arguments).then(function(response) {
-				var clonedResponse = response.clone();
-				var contentType = response.headers.get('content-type') || '';
-				
-				if (contentType.indexOf('application/json') !== -1) {
-					clonedResponse.json().then(function(data) {
-						window.__capturedXHRData__[key] = {
-							method: method.toUpperCase(),
-							url: domainAndPath,
-							status: response.status,
-							statusText: response.statusText,
-							response: data,
-							timestamp: Date.now()
-						};
-						console.log('[BrowserWing Player] Captured Fetch:', key);
-					}).catch(function(e) {
-						console.warn('[BrowserWing Player] Failed to parse Fetch response:', e);
-					});
-				} else if (contentType.indexOf('text/') !== -1) {
-					clonedResponse.text().then(function(text) {
-						window.__capturedXHRData__[key] = {
-							method: method.toUpperCase(),
-							url: domainAndPath,
-							status: response.status,
-							statusText: response.statusText,
-							response: text,
-							timestamp: Date.now()
-						};
-						console.log('[BrowserWing Player] Captured Fetch:', key);
-					}).catch(function(e) {
-						console.warn('[BrowserWing Player] Failed to read Fetch response:', e);
-					});
-				}
-				
-				return response;
-			});
-		};
-		
-		console.log('[BrowserWing Player] XHR capture script installed, monitoring', targetKeys.size, 'targets');
-		return true;
-	}`, string(targetsJSON))
-	if err != nil {
-		return fmt.Errorf("failed to inject XHR interceptor: %w", err)
-	}
-
-	logger.Info(ctx, "✓ XHR interceptor injected successfully")
-	return nil
-}
-
 // executeCaptureXHR 执行捕获XHR请求操作（回放时等待并获取匹配的XHR响应数据）
+// 匹配数据来自页面网络日志（页面创建时即开始记录），无需向页面注入拦截脚本
 func (p *Player) executeCaptureXHR(ctx context.Context, page *rod.Page, action models.ScriptAction) error {
 	domainAndPath := action.URL
 	method := action.Method
 	if domainAndPath == "" || method == "" {
 		return fmt.Errorf("capture_xhr action requires url and method")
 	}
+	if p.browserManager == nil {
+		return fmt.Errorf("network journal is not available for capture_xhr")
+	}
+
+	monitor := p.browserManager.GetPageMonitor(page)
+	if monitor == nil {
+		return fmt.Errorf("network journal is not available for the current page")
+	}
 
 	logger.Info(ctx, "Capturing XHR request: %s %s (domain+path, ignoring query params)", method, domainAndPath)
 
-	// XHR拦截器已经在脚本开始时注入（injectXHRInterceptorForScript），
-	// 这里只需要等待和查找数据，不再重复注入
+	// 使用 method + domainAndPath（不带参数）匹配最近一次完成的 XHR/Fetch 请求
+	match := func(entry *NetworkEntry) bool {
+		if entry.State != NetworkStateFinished {
+			return false
+		}
+		if entry.ResourceType != string(proto.NetworkResourceTypeXHR) && entry.ResourceType != string(proto.NetworkResourceTypeFetch) {
+			return false
+		}
+		return strings.EqualFold(entry.Method, method) && URLDomainAndPath(entry.URL) == domainAndPath
+	}
 
-	// 等待指定的XHR请求完成（轮询检查）
-	// 使用method + domainAndPath（不带参数）作为匹配key
-	key := method + "|" + domainAndPath
 	maxWaitTime := 30 * time.Second
 	pollInterval := 500 * time.Millisecond
 	startTime := time.Now()
 
-	logger.Info(ctx, "Waiting for XHR request to complete (domain+path matching): %s", key)
+	logger.Info(ctx, "Waiting for XHR request to complete (domain+path matching): %s|%s", method, domainAndPath)
 
 	for {
-		// 检查是否超时
-		if time.Since(startTime) > maxWaitTime {
-			return fmt.Errorf("timeout waiting for XHR request: %s %s", method, domainAndPath)
-		}
-
-		// 检查是否已捕获到目标请求
-		result, err := page.Eval(`(key) => {
-			if (window.__capturedXHRData__ && window.__capturedXHRData__[key]) {
-				return window.__capturedXHRData__[key];
+		entry, found := monitor.Network.FindLatest(match)
+		if found {
+			body, err := FetchResponseBody(page, entry.RequestID)
+			if err != nil {
+				return fmt.Errorf("failed to read XHR response: %w", err)
+			}
+			text, err := body.Text()
+			if err != nil {
+				return fmt.Errorf("failed to decode XHR response: %w", err)
 			}
-			return null;
-		}`, key)
-		if err != nil {
-			logger.Warn(ctx, "Failed to check captured XHR data: %v", err)
-			time.Sleep(pollInterval)
-			continue
-		}
 
-		// 如果找到了匹配的请求
-		if result != nil && !result.Value.Nil() {
-			// 解析响应数据
-			var xhrData map[string]interface{}
-			jsonData, _ := json.Marshal(result.Value)
-			if err := json.Unmarshal(jsonData, &xhrData); err != nil {
-				logger.Warn(ctx, "Failed to parse XHR data: %v", err)
-				return fmt.Errorf("failed to parse XHR response: %w", err)
+			// JSON 响应解析为对象，其余保留文本
+			var response interface{} = text
+			if strings.Contains(strings.ToLower(entry.MIMEType), "json") {
+				var parsed interface{}
+				if err := json.Unmarshal([]byte(text), &parsed); err == nil {
+					response = parsed
+				} else {
+					logger.Warn(ctx, "Failed to parse XHR response as JSON, keeping text: %v", err)
+				}
 			}
 
 			// 存储抓取的数据
@@ -3215,15 +3365,22 @@ func (p *Player) executeCaptureXHR(ctx context.Context, page *rod.Page, action m
 			if varName == "" {
 				varName = fmt.Sprintf("xhr_data_%d", len(p.extractedData))
 			}
-			p.extractedData[varName] = xhrData["response"]
+			p.extractedData[varName] = response
 
-			logger.Info(ctx, "✓ XHR request captured successfully: %s = %v", varName, xhrData["status"])
-			logger.Info(ctx, "Response status: %v %v", xhrData["status"], xhrData["statusText"])
+			logger.Info(ctx, "✓ XHR request captured successfully: %s = %d", varName, entry.Status)
+			logger.Info(ctx, "Response status: %d %s", entry.Status, entry.StatusText)
 			return nil
 		}
 
-		// 等待后重试
-		time.Sleep(pollInterval)
+		if time.Since(startTime) > maxWaitTime {
+			return fmt.Errorf("timeout waiting for XHR request: %s %s", method, domainAndPath)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
 	}
 }
 
@@ -3244,16 +3401,11 @@ func (p *Player) executeAIControl(ctx context.Context, page *rod.Page, action mo
 
 	// 关键修复：同步当前页面到 Browser Manager 的 activePage
 	// 这样 Executor 的 GetActivePage() 才能获取到正确的页面
+	// 并发回放时 AI 控制步骤依次占用 activePage，结束后恢复原来的页面
 	if p.browserManager != nil {
 		logger.Info(ctx, "[executeAIControl] Syncing current page to Browser Manager's activePage")
-		p.browserManager.SetActivePage(page)
-
-		// 验证设置是否成功
-		if activePage := p.browserManager.GetActivePage(); activePage == page {
-			logger.Info(ctx, "[executeAIControl] ✓ Successfully synced activePage to Browser Manager")
-		} else {
-			logger.Warn(ctx, "[executeAIControl] ⚠️  Failed to sync activePage - Executor tools may not work correctly")
-		}
+		restore := p.browserManager.BorrowActivePage(page)
+		defer restore()
 	} else {
 		logger.Warn(ctx, "[executeAIControl] ⚠️  Browser Manager not set - Executor tools may not work correctly")
 	}
@@ -0,0 +1,488 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/browserwing/browserwing/models"
+	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// RouteRuleStatus 规则及其命中次数
+type RouteRuleStatus struct {
+	models.RouteRule
+	Hits int64 `json:"hits"`
+}
+
+// routeRuleState 运行中的规则
+type routeRuleState struct {
+	rule models.RouteRule
+	hits int64
+}
+
+// RequestRouter 页面级请求拦截器，基于 CDP Fetch 域按规则处理请求
+type RequestRouter struct {
+	page *rod.Page
+	// 页面关闭后取消，结束仍在延迟中的请求处理
+	ctx   context.Context
+	stop  context.CancelFunc
+	mu    sync.Mutex
+	rules []*routeRuleState
+	// 需要在响应阶段修改响应头的请求
+	responseRules map[proto.FetchRequestID]*routeRuleState
+	// 暂停期间 Fetch 域由其他拦截器（如加载空白源文档）临时接管，不处理被暂停的请求
+	suspended bool
+}
+
+// errRouterStopped 拦截器已停止（页面已关闭），被暂停的请求无需再处理
+var errRouterStopped = errors.New("request router stopped")
+
+// newRequestRouter 创建拦截器并开始监听 Fetch.requestPaused
+// 返回的 wait 在页面关闭后结束，调用方随后应调用 Stop
+func newRequestRouter(page *rod.Page) (*RequestRouter, func()) {
+	ctx, stop := context.WithCancel(context.Background())
+	r := &RequestRouter{
+		page:          page,
+		ctx:           ctx,
+		stop:          stop,
+		responseRules: make(map[proto.FetchRequestID]*routeRuleState),
+	}
+
+	// EachEvent 会自动启用 Fetch 域（拦截全部请求），SetRules 会随后设置实际的匹配模式
+	wait := page.EachEvent(func(e *proto.FetchRequestPaused) {
+		// 每个请求单独处理，延迟规则不会阻塞其他请求
+		go r.handle(e)
+	})
+
+	return r, wait
+}
+
+// SetRules 替换拦截规则；规则为空时关闭拦截
+func (r *RequestRouter) SetRules(rules []models.RouteRule) error {
+	for i, rule := range rules {
+		if err := ValidateRouteRule(rule); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+
+	r.mu.Lock()
+	r.rules = make([]*routeRuleState, 0, len(rules))
+	for _, rule := range rules {
+		r.rules = append(r.rules, &routeRuleState{rule: rule})
+	}
+	r.mu.Unlock()
+
+	return r.enable()
+}
+
+// enable 按当前规则设置 Fetch 域的匹配模式；没有规则时关闭拦截
+func (r *RequestRouter) enable() error {
+	patterns := r.patterns()
+	if len(patterns) == 0 {
+		return proto.FetchDisable{}.Call(r.page)
+	}
+	return proto.FetchEnable{Patterns: patterns}.Call(r.page)
+}
+
+// patterns 返回当前规则对应的 Fetch 匹配模式
+func (r *RequestRouter) patterns() []*proto.FetchRequestPattern {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	patterns := make([]*proto.FetchRequestPattern, 0, len(r.rules))
+	for _, state := range r.rules {
+		resourceType, _ := lookupResourceType(state.rule.ResourceType)
+		patterns = append(patterns, &proto.FetchRequestPattern{
+			URLPattern:   state.rule.URLPattern,
+			ResourceType: resourceType,
+			RequestStage: proto.FetchRequestStageRequest,
+		})
+	}
+	return patterns
+}
+
+// suspend 暂停拦截，其他拦截器临时接管页面的 Fetch 域
+func (r *RequestRouter) suspend() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.suspended = true
+}
+
+// resume 恢复拦截并重新启用 Fetch 域（其他拦截器停止时会关闭 Fetch 域）
+func (r *RequestRouter) resume() error {
+	r.mu.Lock()
+	r.suspended = false
+	r.mu.Unlock()
+	return r.enable()
+}
+
+func (r *RequestRouter) isSuspended() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.suspended
+}
+
+// Stop 停止拦截器，正在延迟的请求立即结束
+func (r *RequestRouter) Stop() {
+	r.stop()
+}
+
+// Rules 返回当前规则及命中次数
+func (r *RequestRouter) Rules() []RouteRuleStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]RouteRuleStatus, 0, len(r.rules))
+	for _, state := range r.rules {
+		result = append(result, RouteRuleStatus{RouteRule: state.rule, Hits: state.hits})
+	}
+	return result
+}
+
+// match 返回第一条匹配的规则并记录命中
+func (r *RequestRouter) match(req *proto.NetworkRequest, resourceType proto.NetworkResourceType) *routeRuleState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, state := range r.rules {
+		rule := state.rule
+		if rule.Method != "" && !strings.EqualFold(rule.Method, req.Method) {
+			continue
+		}
+		if rule.ResourceType != "" && !strings.EqualFold(rule.ResourceType, string(resourceType)) {
+			continue
+		}
+		if !MatchURLPattern(rule.URLPattern, req.URL+req.URLFragment) {
+			continue
+		}
+		state.hits++
+		return state
+	}
+	return nil
+}
+
+// handle 处理被暂停的请求
+// 处理失败时请求仍处于暂停状态，必须让它结束，否则页面会一直等待该请求
+func (r *RequestRouter) handle(e *proto.FetchRequestPaused) {
+	if r.isSuspended() {
+		return
+	}
+	err := r.dispatch(e)
+	if err == nil || errors.Is(err, errRouterStopped) {
+		return
+	}
+	logger.Warn(r.ctx, "Request interception failed for %s: %v", e.Request.URL, err)
+
+	if e.ResponseStatusCode != nil || e.ResponseErrorReason != "" {
+		err = proto.FetchContinueResponse{RequestID: e.RequestID}.Call(r.page)
+	} else {
+		err = proto.FetchFailRequest{RequestID: e.RequestID, ErrorReason: proto.NetworkErrorReasonFailed}.Call(r.page)
+	}
+	if err != nil {
+		logger.Warn(r.ctx, "Failed to release intercepted request %s: %v", e.Request.URL, err)
+	}
+}
+
+func (r *RequestRouter) dispatch(e *proto.FetchRequestPaused) error {
+	// 响应阶段：仅用于修改响应头
+	if e.ResponseStatusCode != nil || e.ResponseErrorReason != "" {
+		r.mu.Lock()
+		state := r.responseRules[e.RequestID]
+		delete(r.responseRules, e.RequestID)
+		r.mu.Unlock()
+
+		if state == nil || e.ResponseErrorReason != "" {
+			return proto.FetchContinueResponse{RequestID: e.RequestID}.Call(r.page)
+		}
+		return proto.FetchContinueResponse{
+			RequestID:       e.RequestID,
+			ResponseCode:    e.ResponseStatusCode,
+			ResponsePhrase:  e.ResponseStatusText,
+			ResponseHeaders: mergeFetchHeaders(e.ResponseHeaders, state.rule.ResponseHeaders),
+		}.Call(r.page)
+	}
+
+	state := r.match(e.Request, e.ResourceType)
+	if state == nil {
+		return proto.FetchContinueRequest{RequestID: e.RequestID}.Call(r.page)
+	}
+	rule := state.rule
+
+	if rule.DelayMs > 0 {
+		timer := time.NewTimer(time.Duration(rule.DelayMs) * time.Millisecond)
+		select {
+		case <-timer.C:
+		case <-r.ctx.Done():
+			timer.Stop()
+			return errRouterStopped
+		}
+	}
+
+	switch rule.Action {
+	case models.RouteActionBlock:
+		reason := proto.NetworkErrorReasonBlockedByClient
+		if rule.ErrorReason != "" {
+			reason, _ = lookupErrorReason(rule.ErrorReason)
+		}
+		return proto.FetchFailRequest{RequestID: e.RequestID, ErrorReason: reason}.Call(r.page)
+
+	case models.RouteActionFulfill:
+		body := []byte(rule.Body)
+		if rule.BodyBase64 {
+			decoded, err := base64.StdEncoding.DecodeString(rule.Body)
+			if err != nil {
+				return fmt.Errorf("invalid base64 body: %w", err)
+			}
+			body = decoded
+		}
+		status := rule.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		headers := make(map[string]string, len(rule.Headers)+1)
+		for name, value := range rule.Headers {
+			headers[name] = value
+		}
+		if rule.ContentType != "" {
+			headers["Content-Type"] = rule.ContentType
+		}
+		headers["Content-Length"] = strconv.Itoa(len(body))
+		return proto.FetchFulfillRequest{
+			RequestID:       e.RequestID,
+			ResponseCode:    status,
+			ResponseHeaders: mergeFetchHeaders(nil, headers),
+			Body:            body,
+			ResponsePhrase:  http.StatusText(status),
+		}.Call(r.page)
+
+	case models.RouteActionModifyHeaders:
+		req := proto.FetchContinueRequest{RequestID: e.RequestID}
+		if len(rule.RequestHeaders) > 0 {
+			req.Headers = mergeFetchHeaders(networkHeadersToEntries(e.Request.Headers), rule.RequestHeaders)
+		}
+		if len(rule.ResponseHeaders) > 0 {
+			r.mu.Lock()
+			r.responseRules[e.RequestID] = state
+			r.mu.Unlock()
+			req.InterceptResponse = true
+		}
+		return req.Call(r.page)
+
+	default: // delay
+		return proto.FetchContinueRequest{RequestID: e.RequestID}.Call(r.page)
+	}
+}
+
+// ValidateRouteRule 校验规则
+func ValidateRouteRule(rule models.RouteRule) error {
+	if rule.URLPattern == "" {
+		return fmt.Errorf("url_pattern is required")
+	}
+	switch rule.Action {
+	case models.RouteActionBlock, models.RouteActionFulfill, models.RouteActionDelay:
+	case models.RouteActionModifyHeaders:
+		if len(rule.RequestHeaders) == 0 && len(rule.ResponseHeaders) == 0 {
+			return fmt.Errorf("modify_headers requires request_headers or response_headers")
+		}
+	default:
+		return fmt.Errorf("unsupported action %q", rule.Action)
+	}
+	if rule.DelayMs < 0 {
+		return fmt.Errorf("delay_ms must not be negative")
+	}
+	if rule.ResourceType != "" {
+		if _, ok := lookupResourceType(rule.ResourceType); !ok {
+			return fmt.Errorf("unsupported resource_type %q", rule.ResourceType)
+		}
+	}
+	if rule.Status != 0 && (rule.Status < 100 || rule.Status > 599) {
+		return fmt.Errorf("invalid status %d", rule.Status)
+	}
+	if rule.ErrorReason != "" {
+		if _, ok := lookupErrorReason(rule.ErrorReason); !ok {
+			return fmt.Errorf("unsupported error_reason %q", rule.ErrorReason)
+		}
+	}
+	if rule.BodyBase64 {
+		if _, err := base64.StdEncoding.DecodeString(rule.Body); err != nil {
+			return fmt.Errorf("invalid base64 body: %w", err)
+		}
+	}
+	return nil
+}
+
+// routeErrorReasons 可用于 block 规则的网络错误原因
+var routeErrorReasons = []proto.NetworkErrorReason{
+	proto.NetworkErrorReasonFailed, proto.NetworkErrorReasonAborted, proto.NetworkErrorReasonTimedOut,
+	proto.NetworkErrorReasonAccessDenied, proto.NetworkErrorReasonConnectionClosed, proto.NetworkErrorReasonConnectionReset,
+	proto.NetworkErrorReasonConnectionRefused, proto.NetworkErrorReasonConnectionAborted, proto.NetworkErrorReasonConnectionFailed,
+	proto.NetworkErrorReasonNameNotResolved, proto.NetworkErrorReasonInternetDisconnected, proto.NetworkErrorReasonAddressUnreachable,
+	proto.NetworkErrorReasonBlockedByClient, proto.NetworkErrorReasonBlockedByResponse,
+}
+
+// lookupErrorReason 将错误原因转换为 CDP 枚举值（不区分大小写）
+func lookupErrorReason(reason string) (proto.NetworkErrorReason, bool) {
+	for _, r := range routeErrorReasons {
+		if strings.EqualFold(string(r), reason) {
+			return r, true
+		}
+	}
+	return "", false
+}
+
+// routeResourceTypes 可用于拦截规则的资源类型
+var routeResourceTypes = []proto.NetworkResourceType{
+	proto.NetworkResourceTypeDocument, proto.NetworkResourceTypeStylesheet, proto.NetworkResourceTypeImage,
+	proto.NetworkResourceTypeMedia, proto.NetworkResourceTypeFont, proto.NetworkResourceTypeScript,
+	proto.NetworkResourceTypeTextTrack, proto.NetworkResourceTypeXHR, proto.NetworkResourceTypeFetch,
+	proto.NetworkResourceTypePrefetch, proto.NetworkResourceTypeEventSource, proto.NetworkResourceTypeWebSocket,
+	proto.NetworkResourceTypeManifest, proto.NetworkResourceTypeSignedExchange, proto.NetworkResourceTypePing,
+	proto.NetworkResourceTypeCSPViolationReport, proto.NetworkResourceTypePreflight, proto.NetworkResourceTypeOther,
+}
+
+// lookupResourceType 将资源类型转换为 CDP 枚举值（不区分大小写）
+func lookupResourceType(resourceType string) (proto.NetworkResourceType, bool) {
+	for _, t := range routeResourceTypes {
+		if strings.EqualFold(string(t), resourceType) {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+// MatchURLPattern 通配符匹配（与 Fetch.RequestPattern 一致）：* 匹配任意字符，? 匹配单个字符，\ 转义
+func MatchURLPattern(pattern, url string) bool {
+	p, s := []rune(pattern), []rune(url)
+	pi, si := 0, 0
+	starP, starS := -1, 0
+
+	for si < len(s) {
+		if pi < len(p) {
+			switch {
+			case p[pi] == '*':
+				starP, starS = pi, si
+				pi++
+				continue
+			case p[pi] == '\\' && pi+1 < len(p) && p[pi+1] == s[si]:
+				pi += 2
+				si++
+				continue
+			case p[pi] == '?' || (p[pi] != '\\' && p[pi] == s[si]):
+				pi++
+				si++
+				continue
+			}
+		}
+		if starP < 0 {
+			return false
+		}
+		// 回溯：让上一个 * 多匹配一个字符
+		starS++
+		pi, si = starP+1, starS
+	}
+
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+	return pi == len(p)
+}
+
+// mergeFetchHeaders 在原有头部上设置/删除（值为空）指定头部，名称不区分大小写
+func mergeFetchHeaders(base []*proto.FetchHeaderEntry, changes map[string]string) []*proto.FetchHeaderEntry {
+	result := make([]*proto.FetchHeaderEntry, 0, len(base)+len(changes))
+	for _, h := range base {
+		if _, changed := lookupHeader(changes, h.Name); changed {
+			continue
+		}
+		result = append(result, h)
+	}
+	for name, value := range changes {
+		if value == "" {
+			continue
+		}
+		result = append(result, &proto.FetchHeaderEntry{Name: name, Value: value})
+	}
+	return result
+}
+
+func lookupHeader(headers map[string]string, name string) (string, bool) {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func networkHeadersToEntries(headers proto.NetworkHeaders) []*proto.FetchHeaderEntry {
+	result := make([]*proto.FetchHeaderEntry, 0, len(headers))
+	for name, value := range headers {
+		result = append(result, &proto.FetchHeaderEntry{Name: name, Value: value.Str()})
+	}
+	return result
+}
+
+// SetPageRouteRules 为页面设置请求拦截规则（替换已有规则，空规则表示关闭拦截）
+func (m *Manager) SetPageRouteRules(page *rod.Page, rules []models.RouteRule) error {
+	if page == nil {
+		return fmt.Errorf("page is nil")
+	}
+
+	m.pageMonitorMu.Lock()
+	router, exists := m.pageRouters[page.TargetID]
+	if !exists {
+		if len(rules) == 0 {
+			m.pageMonitorMu.Unlock()
+			return nil
+		}
+		if m.pageRouters == nil {
+			m.pageRouters = make(map[proto.TargetTargetID]*RequestRouter)
+		}
+		var wait func()
+		router, wait = newRequestRouter(page)
+		m.pageRouters[page.TargetID] = router
+
+		go func(targetID proto.TargetTargetID) {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Warn(context.Background(), "Request router for %s stopped unexpectedly: %v", targetID, r)
+				}
+				router.Stop()
+				m.pageMonitorMu.Lock()
+				delete(m.pageRouters, targetID)
+				m.pageMonitorMu.Unlock()
+			}()
+			wait()
+		}(page.TargetID)
+	}
+	m.pageMonitorMu.Unlock()
+
+	if err := router.SetRules(rules); err != nil {
+		return err
+	}
+	logger.Info(context.Background(), "✓ %d route rules applied to page %s", len(rules), page.TargetID)
+	return nil
+}
+
+// GetPageRouteRules 获取页面当前的拦截规则及命中次数
+func (m *Manager) GetPageRouteRules(page *rod.Page) []RouteRuleStatus {
+	if page == nil {
+		return nil
+	}
+	m.pageMonitorMu.Lock()
+	router, exists := m.pageRouters[page.TargetID]
+	m.pageMonitorMu.Unlock()
+	if !exists {
+		return []RouteRuleStatus{}
+	}
+	return router.Rules()
+}
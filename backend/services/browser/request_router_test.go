@@ -0,0 +1,134 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/browserwing/browserwing/models"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func TestMatchURLPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		url     string
+		want    bool
+	}{
+		{pattern: "*", url: "https://example.com/", want: true},
+		{pattern: "*google-analytics.com*", url: "https://www.google-analytics.com/collect?v=1", want: true},
+		{pattern: "https://example.com/api/*", url: "https://example.com/api/users/1", want: true},
+		{pattern: "https://example.com/api/*", url: "https://example.com/static/app.js", want: false},
+		{pattern: "*.png", url: "https://cdn.example.com/logo.png", want: true},
+		{pattern: "*.png", url: "https://cdn.example.com/logo.png?v=2", want: false},
+		{pattern: "https://example.com/v?/items", url: "https://example.com/v2/items", want: true},
+		{pattern: "https://example.com/v?/items", url: "https://example.com/v10/items", want: false},
+		{pattern: `*\?debug=1`, url: "https://example.com/page?debug=1", want: true},
+		{pattern: `*\?debug=1`, url: "https://example.com/pagexdebug=1", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.url, func(t *testing.T) {
+			if got := MatchURLPattern(tt.pattern, tt.url); got != tt.want {
+				t.Errorf("MatchURLPattern(%q, %q) = %v, want %v", tt.pattern, tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRouteRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    models.RouteRule
+		wantErr bool
+	}{
+		{name: "block", rule: models.RouteRule{URLPattern: "*ads*", Action: models.RouteActionBlock}},
+		{name: "fulfill", rule: models.RouteRule{URLPattern: "*/api/*", Action: models.RouteActionFulfill, Status: 404}},
+		{name: "resource type is case insensitive", rule: models.RouteRule{URLPattern: "*", ResourceType: "xhr", Action: models.RouteActionDelay, DelayMs: 100}},
+		{name: "missing pattern", rule: models.RouteRule{Action: models.RouteActionBlock}, wantErr: true},
+		{name: "unknown action", rule: models.RouteRule{URLPattern: "*", Action: "redirect"}, wantErr: true},
+		{name: "modify headers without headers", rule: models.RouteRule{URLPattern: "*", Action: models.RouteActionModifyHeaders}, wantErr: true},
+		{name: "unknown resource type", rule: models.RouteRule{URLPattern: "*", ResourceType: "Video", Action: models.RouteActionBlock}, wantErr: true},
+		{name: "invalid status", rule: models.RouteRule{URLPattern: "*", Action: models.RouteActionFulfill, Status: 999}, wantErr: true},
+		{name: "error reason is case insensitive", rule: models.RouteRule{URLPattern: "*", Action: models.RouteActionBlock, ErrorReason: "timedout"}},
+		{name: "unknown error reason", rule: models.RouteRule{URLPattern: "*", Action: models.RouteActionBlock, ErrorReason: "Refused"}, wantErr: true},
+		{name: "base64 body", rule: models.RouteRule{URLPattern: "*", Action: models.RouteActionFulfill, Body: "aGVsbG8=", BodyBase64: true}},
+		{name: "invalid base64 body", rule: models.RouteRule{URLPattern: "*", Action: models.RouteActionFulfill, Body: "not base64!", BodyBase64: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRouteRule(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRouteRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMergeFetchHeaders(t *testing.T) {
+	base := []*proto.FetchHeaderEntry{
+		{Name: "Accept", Value: "*/*"},
+		{Name: "Cookie", Value: "a=1"},
+		{Name: "User-Agent", Value: "test"},
+	}
+	got := mergeFetchHeaders(base, map[string]string{"cookie": "", "user-agent": "custom", "X-Debug": "1"})
+
+	want := map[string]string{"Accept": "*/*", "user-agent": "custom", "X-Debug": "1"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d headers, want %d: %+v", len(got), len(want), got)
+	}
+	for _, h := range got {
+		if want[h.Name] != h.Value {
+			t.Errorf("header %s = %q, want %q", h.Name, h.Value, want[h.Name])
+		}
+	}
+}
+
+func TestRequestRouterDelayStopsWithRouter(t *testing.T) {
+	ctx, stop := context.WithCancel(context.Background())
+	r := &RequestRouter{
+		ctx:   ctx,
+		stop:  stop,
+		rules: []*routeRuleState{{rule: models.RouteRule{URLPattern: "*", Action: models.RouteActionDelay, DelayMs: 3600000}}},
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- r.dispatch(&proto.FetchRequestPaused{Request: &proto.NetworkRequest{URL: "https://example.com/api", Method: "GET"}})
+	}()
+	r.Stop()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, errRouterStopped) {
+			t.Errorf("dispatch error = %v, want errRouterStopped", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("delayed request outlived Stop")
+	}
+}
+
+func TestRequestRouterSuspend(t *testing.T) {
+	r := &RequestRouter{
+		ctx: context.Background(),
+		rules: []*routeRuleState{
+			{rule: models.RouteRule{URLPattern: "*/api/*", Action: models.RouteActionBlock}},
+			{rule: models.RouteRule{URLPattern: "*.png", ResourceType: "image", Action: models.RouteActionBlock}},
+		},
+	}
+
+	// 暂停期间其他拦截器接管 Fetch 域，被暂停的请求不经过规则（也不会计入命中）
+	r.suspend()
+	r.handle(&proto.FetchRequestPaused{Request: &proto.NetworkRequest{URL: "https://example.com/api/users", Method: "GET"}})
+	if hits := r.Rules()[0].Hits; hits != 0 {
+		t.Errorf("suspended router matched %d requests", hits)
+	}
+
+	// 恢复时按现有规则重新启用 Fetch 域
+	patterns := r.patterns()
+	if len(patterns) != 2 || patterns[0].URLPattern != "*/api/*" || patterns[1].ResourceType != proto.NetworkResourceTypeImage {
+		t.Errorf("patterns = %+v", patterns)
+	}
+}
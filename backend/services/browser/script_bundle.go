@@ -0,0 +1,526 @@
+package browser
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/browserwing/browserwing/models"
+	"github.com/google/uuid"
+)
+
+// 脚本包（zip）格式
+const (
+	BundleFormat  = "browserwing-bundle"
+	BundleVersion = 1
+
+	bundleManifestEntry = "manifest.json"
+	bundleScriptsEntry  = "scripts.json"
+	bundleToolsEntry    = "tool_configs.json"
+	bundleTasksEntry    = "scheduled_tasks.json"
+	bundleFilesDir      = "files/"
+
+	maxBundleEntrySize = 100 << 20 // 单个条目最大 100MB，防止解压炸弹
+)
+
+// 导入时的冲突处理策略
+const (
+	BundleConflictRename    = "rename"    // 同名脚本/任务作为新数据导入，名称加后缀（默认）
+	BundleConflictSkip      = "skip"      // 跳过同名脚本/任务，引用改为指向已有脚本
+	BundleConflictOverwrite = "overwrite" // 覆盖同名脚本（保留原 ID，生成新版本）和同名任务
+)
+
+// BundleManifest 脚本包清单（manifest.json）
+type BundleManifest struct {
+	Format      string             `json:"format"`
+	Version     int                `json:"version"`
+	CreatedAt   time.Time          `json:"created_at"`
+	Source      string             `json:"source,omitempty"` // 导出来源（服务地址）
+	Scripts     []BundleScriptInfo `json:"scripts"`
+	ToolConfigs int                `json:"tool_configs"`
+	Tasks       int                `json:"scheduled_tasks"`
+	Files       []BundleFile       `json:"files,omitempty"`
+	Missing     []string           `json:"missing_files,omitempty"` // 导出时读取失败的本地文件
+	Entries     []BundleEntry      `json:"entries"`                 // 除清单外每个条目的校验值
+	Checksum    string             `json:"checksum"`                // 所有条目校验值汇总后的 SHA-256
+}
+
+// BundleScriptInfo 包内脚本概要
+type BundleScriptInfo struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Revision int    `json:"revision,omitempty"`
+}
+
+// BundleFile 脚本引用的文件（上传文件、录制时下载的文件）
+type BundleFile struct {
+	Path     string `json:"path"`     // 包内路径
+	Original string `json:"original"` // 导出时的本地路径
+	Size     int64  `json:"size"`
+}
+
+// BundleEntry 包内条目校验值
+type BundleEntry struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// ScriptBundle 脚本包内容
+type ScriptBundle struct {
+	Manifest    BundleManifest
+	Scripts     []*models.Script
+	ToolConfigs []*models.ToolConfig
+	Tasks       []*models.ScheduledTask
+	Files       map[string][]byte // 原始路径 -> 文件内容
+}
+
+// BundleFilePaths 脚本引用的本地文件：upload_file 的文件路径（不含 URL 和 ${变量}）和录制时下载的文件
+func BundleFilePaths(scripts []*models.Script) []string {
+	seen := map[string]bool{}
+	for _, script := range scripts {
+		walkBundleActions(script.Actions, func(action *models.ScriptAction) {
+			for _, p := range action.FilePaths {
+				if isLocalBundlePath(p) {
+					seen[p] = true
+				}
+			}
+		})
+		for _, file := range script.DownloadedFiles {
+			if isLocalBundlePath(file.FilePath) {
+				seen[file.FilePath] = true
+			}
+		}
+	}
+	return sortedBoolKeys(seen)
+}
+
+// WriteScriptBundle 写入 zip 格式的脚本包，清单中的文件列表、条目校验值和汇总校验值由此处生成
+func WriteScriptBundle(w io.Writer, bundle *ScriptBundle) error {
+	entries := map[string][]byte{}
+	add := func(name string, v interface{}) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", name, err)
+		}
+		entries[name] = data
+		return nil
+	}
+	if err := add(bundleScriptsEntry, bundle.Scripts); err != nil {
+		return err
+	}
+	if err := add(bundleToolsEntry, bundle.ToolConfigs); err != nil {
+		return err
+	}
+	if err := add(bundleTasksEntry, bundle.Tasks); err != nil {
+		return err
+	}
+
+	manifest := bundle.Manifest
+	manifest.Format = BundleFormat
+	manifest.Version = BundleVersion
+	manifest.Scripts = nil
+	for _, script := range bundle.Scripts {
+		manifest.Scripts = append(manifest.Scripts, BundleScriptInfo{ID: script.ID, Name: script.Name, Revision: script.Revision})
+	}
+	manifest.ToolConfigs = len(bundle.ToolConfigs)
+	manifest.Tasks = len(bundle.Tasks)
+	manifest.Files = nil
+	for _, original := range sortedByteKeys(bundle.Files) {
+		data := bundle.Files[original]
+		name := bundleFilesDir + sha256Hex(data)[:12] + "/" + filepath.Base(original)
+		entries[name] = data
+		manifest.Files = append(manifest.Files, BundleFile{Path: name, Original: original, Size: int64(len(data))})
+	}
+	manifest.Entries = nil
+	for _, name := range sortedByteKeys(entries) {
+		manifest.Entries = append(manifest.Entries, BundleEntry{Path: name, SHA256: sha256Hex(entries[name]), Size: int64(len(entries[name]))})
+	}
+	manifest.Checksum = bundleChecksum(manifest.Entries)
+	bundle.Manifest = manifest
+
+	zw := zip.NewWriter(w)
+	write := func(name string, data []byte) error {
+		fw, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = fw.Write(data)
+		return err
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := write(bundleManifestEntry, manifestData); err != nil {
+		return err
+	}
+	for _, entry := range manifest.Entries {
+		if err := write(entry.Path, entries[entry.Path]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// ReadScriptBundle 读取脚本包并校验清单、条目校验值和汇总校验值
+func ReadScriptBundle(data []byte) (*ScriptBundle, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle archive: %w", err)
+	}
+	entries := map[string][]byte{}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if f.UncompressedSize64 > maxBundleEntrySize {
+			return nil, fmt.Errorf("bundle entry %s is too large", f.Name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open bundle entry %s: %w", f.Name, err)
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, maxBundleEntrySize+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle entry %s: %w", f.Name, err)
+		}
+		entries[f.Name] = content
+	}
+
+	bundle := &ScriptBundle{Files: map[string][]byte{}}
+	manifestData, ok := entries[bundleManifestEntry]
+	if !ok {
+		return nil, fmt.Errorf("bundle has no %s", bundleManifestEntry)
+	}
+	if err := json.Unmarshal(manifestData, &bundle.Manifest); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	manifest := &bundle.Manifest
+	if manifest.Format != BundleFormat {
+		return nil, fmt.Errorf("not a BrowserWing bundle (format %q)", manifest.Format)
+	}
+	if manifest.Version > BundleVersion {
+		return nil, fmt.Errorf("bundle version %d is newer than supported version %d", manifest.Version, BundleVersion)
+	}
+	if bundleChecksum(manifest.Entries) != manifest.Checksum {
+		return nil, fmt.Errorf("bundle checksum mismatch")
+	}
+	// 只读取清单中列出并通过校验的条目
+	verified := map[string][]byte{}
+	for _, entry := range manifest.Entries {
+		content, ok := entries[entry.Path]
+		if !ok {
+			return nil, fmt.Errorf("bundle entry %s is missing", entry.Path)
+		}
+		if sha256Hex(content) != entry.SHA256 {
+			return nil, fmt.Errorf("bundle entry %s is corrupted (checksum mismatch)", entry.Path)
+		}
+		verified[entry.Path] = content
+	}
+
+	decode := func(name string, v interface{}) error {
+		if content, ok := verified[name]; ok {
+			if err := json.Unmarshal(content, v); err != nil {
+				return fmt.Errorf("invalid bundle entry %s: %w", name, err)
+			}
+		}
+		return nil
+	}
+	if err := decode(bundleScriptsEntry, &bundle.Scripts); err != nil {
+		return nil, err
+	}
+	if err := decode(bundleToolsEntry, &bundle.ToolConfigs); err != nil {
+		return nil, err
+	}
+	if err := decode(bundleTasksEntry, &bundle.Tasks); err != nil {
+		return nil, err
+	}
+	for _, file := range manifest.Files {
+		content, ok := verified[file.Path]
+		if !ok || !strings.HasPrefix(file.Path, bundleFilesDir) {
+			return nil, fmt.Errorf("bundle file %s is missing", file.Path)
+		}
+		bundle.Files[file.Original] = content
+	}
+	if len(bundle.Scripts) == 0 {
+		return nil, fmt.Errorf("bundle contains no scripts")
+	}
+	return bundle, nil
+}
+
+// BundleImportOptions 导入选项
+type BundleImportOptions struct {
+	OnConflict  string // rename（默认）、skip、overwrite
+	EnableTasks bool   // 是否按包内状态启用定时任务，默认全部以禁用状态导入
+	Group       string // 非空时覆盖脚本分组
+	FilesDir    string // 附带文件的保存目录
+}
+
+// BundleImportState 当前安装中已有的数据，用于检测冲突
+type BundleImportState struct {
+	Scripts []*models.Script
+	Tasks   []models.ScheduledTask
+}
+
+// BundleConflict 导入冲突及处理结果
+type BundleConflict struct {
+	Kind       string `json:"kind"` // script, mcp_command, task
+	Name       string `json:"name"`
+	ExistingID string `json:"existing_id,omitempty"`
+	Resolution string `json:"resolution"` // renamed, skipped, overwritten, mcp_disabled
+	NewName    string `json:"new_name,omitempty"`
+}
+
+// BundleReference 包内脚本引用了既不在包中、也不在当前安装中的脚本（call_script）
+type BundleReference struct {
+	Script   string `json:"script"`    // 引用方脚本名称（导入后）
+	ScriptID string `json:"script_id"` // 无法解析的脚本 ID
+}
+
+// BundleImportPlan 导入计划，ID、文件路径和引用关系均已重新映射
+type BundleImportPlan struct {
+	Scripts     []*models.Script        `json:"scripts"`
+	ToolConfigs []*models.ToolConfig    `json:"tool_configs"`
+	Tasks       []*models.ScheduledTask `json:"scheduled_tasks"`
+	IDMap       map[string]string       `json:"id_map"` // 包内脚本 ID -> 导入后的脚本 ID
+	Conflicts   []BundleConflict        `json:"conflicts"`
+	Unresolved  []BundleReference       `json:"unresolved_references"`
+	Files       map[string][]byte       `json:"-"` // 目标路径 -> 文件内容
+	Replaces    map[string]bool         `json:"-"` // 覆盖已有数据的脚本/任务 ID
+}
+
+// PlanBundleImport 根据已有数据生成导入计划：分配新 ID、处理同名冲突、改写文件路径和脚本间引用
+func PlanBundleImport(bundle *ScriptBundle, state BundleImportState, opts BundleImportOptions) *BundleImportPlan {
+	now := time.Now()
+	plan := &BundleImportPlan{
+		Unresolved: []BundleReference{},
+		IDMap:      map[string]string{},
+		Files:      map[string][]byte{},
+		Replaces:   map[string]bool{},
+	}
+
+	existingByName := map[string]*models.Script{}
+	existingIDs := map[string]bool{}
+	usedNames := map[string]bool{}
+	for _, script := range state.Scripts {
+		existingIDs[script.ID] = true
+		if _, ok := existingByName[script.Name]; !ok {
+			existingByName[script.Name] = script
+		}
+		usedNames[script.Name] = true
+	}
+
+	// 第一轮：确定每个脚本导入后的 ID
+	var imported []*models.Script
+	for _, source := range bundle.Scripts {
+		script := source.Copy()
+		existing := existingByName[script.Name]
+		switch {
+		case existing == nil:
+			script.ID = uuid.New().String()
+			script.CreatedAt = now
+		case opts.OnConflict == BundleConflictSkip:
+			plan.IDMap[source.ID] = existing.ID
+			plan.Conflicts = append(plan.Conflicts, BundleConflict{Kind: "script", Name: script.Name, ExistingID: existing.ID, Resolution: "skipped"})
+			continue
+		case opts.OnConflict == BundleConflictOverwrite:
+			script.ID = existing.ID
+			script.CreatedAt = existing.CreatedAt
+			plan.Replaces[existing.ID] = true
+			plan.Conflicts = append(plan.Conflicts, BundleConflict{Kind: "script", Name: script.Name, ExistingID: existing.ID, Resolution: "overwritten"})
+		default:
+			script.ID = uuid.New().String()
+			script.CreatedAt = now
+			script.Name = uniqueBundleName(script.Name, usedNames)
+			plan.Conflicts = append(plan.Conflicts, BundleConflict{Kind: "script", Name: source.Name, ExistingID: existing.ID, Resolution: "renamed", NewName: script.Name})
+		}
+		usedNames[script.Name] = true
+		script.UpdatedAt = now
+		if opts.Group != "" {
+			script.Group = opts.Group
+		}
+		plan.IDMap[source.ID] = script.ID
+		imported = append(imported, script)
+	}
+
+	// 第二轮：改写脚本间引用和文件路径，检查 MCP 命令名称
+	filePaths := map[string]string{}
+	for original, content := range bundle.Files {
+		target := filepath.Join(opts.FilesDir, sha256Hex(content)[:12], filepath.Base(original))
+		filePaths[original] = target
+	}
+	mcpNames := map[string]string{}
+	for _, script := range state.Scripts {
+		if script.IsMCPCommand && script.MCPCommandName != "" && !plan.Replaces[script.ID] {
+			mcpNames[script.MCPCommandName] = script.ID
+		}
+	}
+	for _, script := range imported {
+		walkBundleActions(script.Actions, func(action *models.ScriptAction) {
+			if action.Type == models.ActionTypeCallScript && action.ScriptID != "" {
+				if id, ok := plan.IDMap[action.ScriptID]; ok {
+					action.ScriptID = id
+				} else if !existingIDs[action.ScriptID] {
+					plan.Unresolved = append(plan.Unresolved, BundleReference{Script: script.Name, ScriptID: action.ScriptID})
+				}
+			}
+			action.FilePaths = append([]string(nil), action.FilePaths...)
+			for i, p := range action.FilePaths {
+				if target, ok := filePaths[p]; ok {
+					action.FilePaths[i] = target
+					plan.Files[target] = bundle.Files[p]
+				}
+			}
+		})
+		for i, file := range script.DownloadedFiles {
+			if target, ok := filePaths[file.FilePath]; ok {
+				script.DownloadedFiles[i].FilePath = target
+				plan.Files[target] = bundle.Files[file.FilePath]
+			}
+		}
+
+		if script.IsMCPCommand && script.MCPCommandName != "" {
+			if ownerID, used := mcpNames[script.MCPCommandName]; used {
+				script.IsMCPCommand = false
+				plan.Conflicts = append(plan.Conflicts, BundleConflict{Kind: "mcp_command", Name: script.MCPCommandName, ExistingID: ownerID, Resolution: "mcp_disabled"})
+			} else {
+				mcpNames[script.MCPCommandName] = script.ID
+			}
+		}
+		plan.Scripts = append(plan.Scripts, script)
+	}
+
+	// 工具配置跟随脚本（被跳过的脚本保留已有配置）
+	importedIDs := map[string]*models.Script{}
+	for _, script := range imported {
+		importedIDs[script.ID] = script
+	}
+	for _, source := range bundle.ToolConfigs {
+		id, ok := plan.IDMap[source.ScriptID]
+		if !ok || importedIDs[id] == nil {
+			continue
+		}
+		config := *source
+		config.ID = strings.Replace(config.ID, source.ScriptID, id, 1)
+		config.ScriptID = id
+		config.CreatedAt = now
+		config.UpdatedAt = now
+		plan.ToolConfigs = append(plan.ToolConfigs, &config)
+	}
+
+	// 定时任务：重置执行统计，默认禁用
+	existingTasks := map[string]*models.ScheduledTask{}
+	usedTaskNames := map[string]bool{}
+	for i := range state.Tasks {
+		task := &state.Tasks[i]
+		if _, ok := existingTasks[task.Name]; !ok {
+			existingTasks[task.Name] = task
+		}
+		usedTaskNames[task.Name] = true
+	}
+	for _, source := range bundle.Tasks {
+		scriptID, ok := plan.IDMap[source.ScriptID]
+		if !ok {
+			continue
+		}
+		task := *source
+		task.ScriptID = scriptID
+		if script := importedIDs[scriptID]; script != nil {
+			task.ScriptName = script.Name
+		}
+		task.Enabled = opts.EnableTasks && source.Enabled
+		task.LastExecutionTime = nil
+		task.NextExecutionTime = nil
+		task.LastExecutionStatus = ""
+		task.ExecutionCount, task.SuccessCount, task.FailedCount = 0, 0, 0
+		task.CreatedAt = now
+		task.UpdatedAt = now
+		task.ID = uuid.New().String()
+
+		if existing := existingTasks[task.Name]; existing != nil {
+			switch opts.OnConflict {
+			case BundleConflictSkip:
+				plan.Conflicts = append(plan.Conflicts, BundleConflict{Kind: "task", Name: task.Name, ExistingID: existing.ID, Resolution: "skipped"})
+				continue
+			case BundleConflictOverwrite:
+				// 保留原任务的 ID、启用状态和执行统计
+				task.ID = existing.ID
+				task.Enabled = existing.Enabled
+				task.CreatedAt = existing.CreatedAt
+				task.LastExecutionTime = existing.LastExecutionTime
+				task.LastExecutionStatus = existing.LastExecutionStatus
+				task.ExecutionCount, task.SuccessCount, task.FailedCount = existing.ExecutionCount, existing.SuccessCount, existing.FailedCount
+				plan.Replaces[existing.ID] = true
+				plan.Conflicts = append(plan.Conflicts, BundleConflict{Kind: "task", Name: task.Name, ExistingID: existing.ID, Resolution: "overwritten"})
+			default:
+				task.Name = uniqueBundleName(task.Name, usedTaskNames)
+				plan.Conflicts = append(plan.Conflicts, BundleConflict{Kind: "task", Name: source.Name, ExistingID: existing.ID, Resolution: "renamed", NewName: task.Name})
+			}
+		}
+		usedTaskNames[task.Name] = true
+		plan.Tasks = append(plan.Tasks, &task)
+	}
+
+	return plan
+}
+
+// walkBundleActions 遍历步骤（包括循环中的嵌套步骤）
+func walkBundleActions(actions []models.ScriptAction, fn func(action *models.ScriptAction)) {
+	for i := range actions {
+		fn(&actions[i])
+		if actions[i].Loop != nil {
+			walkBundleActions(actions[i].Loop.Actions, fn)
+		}
+	}
+}
+
+// uniqueBundleName 生成不冲突的名称：name (imported)、name (imported 2) ...
+func uniqueBundleName(name string, used map[string]bool) string {
+	candidate := name + " (imported)"
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s (imported %d)", name, i)
+	}
+	return candidate
+}
+
+func isLocalBundlePath(p string) bool {
+	lower := strings.ToLower(p)
+	return p != "" && !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") && !strings.Contains(p, "${")
+}
+
+// bundleChecksum 按路径顺序汇总各条目的校验值
+func bundleChecksum(entries []BundleEntry) string {
+	sorted := make([]BundleEntry, len(entries))
+	copy(sorted, entries)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+	h := sha256.New()
+	for _, entry := range sorted {
+		fmt.Fprintf(h, "%s %s %d\n", path.Clean(entry.Path), entry.SHA256, entry.Size)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func sortedByteKeys(values map[string][]byte) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,203 @@
+package browser
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/browserwing/browserwing/models"
+)
+
+func testBundle() *ScriptBundle {
+	login := &models.Script{
+		ID:             "s-login",
+		Name:           "login",
+		IsMCPCommand:   true,
+		MCPCommandName: "login",
+		Actions: []models.ScriptAction{
+			{Type: "upload_file", Selector: "input[type=file]", FilePaths: []string{"/data/avatar.png", "https://example.com/a.png"}},
+		},
+		DownloadedFiles: []models.DownloadedFile{{FileName: "report.csv", FilePath: "/downloads/report.csv"}},
+	}
+	report := &models.Script{
+		ID:      "s-report",
+		Name:    "report",
+		Actions: []models.ScriptAction{{Type: models.ActionTypeCallScript, ScriptID: "s-login"}},
+	}
+	return &ScriptBundle{
+		Scripts:     []*models.Script{login, report},
+		ToolConfigs: []*models.ToolConfig{{ID: "script_s-login", Name: "login", Type: models.ToolTypeScript, ScriptID: "s-login", Enabled: true}},
+		Tasks:       []*models.ScheduledTask{{ID: "t1", Name: "nightly report", Enabled: true, ScriptID: "s-report", ExecutionCount: 9}},
+		Files: map[string][]byte{
+			"/data/avatar.png":      []byte("png"),
+			"/downloads/report.csv": []byte("a,b\n1,2\n"),
+		},
+	}
+}
+
+func TestScriptBundleRoundTrip(t *testing.T) {
+	if got := BundleFilePaths(testBundle().Scripts); strings.Join(got, ",") != "/data/avatar.png,/downloads/report.csv" {
+		t.Errorf("BundleFilePaths = %v", got)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteScriptBundle(&buf, testBundle()); err != nil {
+		t.Fatalf("WriteScriptBundle: %v", err)
+	}
+	bundle, err := ReadScriptBundle(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ReadScriptBundle: %v", err)
+	}
+	if len(bundle.Scripts) != 2 || len(bundle.ToolConfigs) != 1 || len(bundle.Tasks) != 1 {
+		t.Fatalf("bundle = %d scripts, %d tools, %d tasks", len(bundle.Scripts), len(bundle.ToolConfigs), len(bundle.Tasks))
+	}
+	if string(bundle.Files["/downloads/report.csv"]) != "a,b\n1,2\n" || len(bundle.Manifest.Files) != 2 {
+		t.Errorf("files = %v, manifest files = %+v", bundle.Files, bundle.Manifest.Files)
+	}
+
+	// 篡改任意条目都应被拒绝
+	tampered := rewriteZipEntry(t, buf.Bytes(), bundleScriptsEntry, []byte("[]"))
+	if _, err := ReadScriptBundle(tampered); err == nil || !strings.Contains(err.Error(), "corrupted") {
+		t.Errorf("tampered bundle error = %v", err)
+	}
+}
+
+func rewriteZipEntry(t *testing.T, data []byte, name string, content []byte) []byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		original, _ := io.ReadAll(rc)
+		rc.Close()
+		if f.Name == name {
+			original = content
+		}
+		w, _ := zw.Create(f.Name)
+		w.Write(original)
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestPlanBundleImport(t *testing.T) {
+	state := BundleImportState{
+		Scripts: []*models.Script{
+			{ID: "old-login", Name: "login"},
+			{ID: "other", Name: "other", IsMCPCommand: true, MCPCommandName: "login"},
+		},
+		Tasks: []models.ScheduledTask{{ID: "old-task", Name: "nightly report", Enabled: true, ExecutionCount: 3}},
+	}
+
+	tests := []struct {
+		onConflict  string
+		scripts     []string
+		loginID     string
+		taskID      string
+		taskEnabled bool
+		resolutions []string
+	}{
+		{
+			onConflict:  BundleConflictRename,
+			scripts:     []string{"login (imported)", "report"},
+			resolutions: []string{"renamed", "mcp_disabled", "renamed"},
+		},
+		{
+			onConflict:  BundleConflictSkip,
+			scripts:     []string{"report"},
+			loginID:     "old-login",
+			resolutions: []string{"skipped", "skipped"},
+		},
+		{
+			onConflict:  BundleConflictOverwrite,
+			scripts:     []string{"login", "report"},
+			loginID:     "old-login",
+			taskID:      "old-task",
+			taskEnabled: true,
+			resolutions: []string{"overwritten", "mcp_disabled", "overwritten"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.onConflict, func(t *testing.T) {
+			plan := PlanBundleImport(testBundle(), state, BundleImportOptions{OnConflict: tt.onConflict, FilesDir: "/assets"})
+
+			var names []string
+			for _, script := range plan.Scripts {
+				names = append(names, script.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.scripts, ",") {
+				t.Errorf("scripts = %v, want %v", names, tt.scripts)
+			}
+			var resolutions []string
+			for _, conflict := range plan.Conflicts {
+				resolutions = append(resolutions, conflict.Resolution)
+			}
+			if strings.Join(resolutions, ",") != strings.Join(tt.resolutions, ",") {
+				t.Errorf("resolutions = %v, want %v", resolutions, tt.resolutions)
+			}
+
+			loginID := plan.IDMap["s-login"]
+			if tt.loginID != "" && loginID != tt.loginID {
+				t.Errorf("login id = %s, want %s", loginID, tt.loginID)
+			}
+			if loginID == "" || loginID == "s-login" || plan.IDMap["s-report"] == "s-report" {
+				t.Errorf("ids not remapped: %v", plan.IDMap)
+			}
+			report := plan.Scripts[len(plan.Scripts)-1]
+			if report.Actions[0].ScriptID != loginID {
+				t.Errorf("call_script target = %s, want %s", report.Actions[0].ScriptID, loginID)
+			}
+
+			if len(plan.Tasks) == 1 {
+				task := plan.Tasks[0]
+				if task.ScriptID != report.ID || task.Enabled != tt.taskEnabled || (tt.taskID != "" && task.ID != tt.taskID) {
+					t.Errorf("task = %+v", task)
+				}
+			} else if tt.onConflict != BundleConflictSkip {
+				t.Errorf("got %d tasks", len(plan.Tasks))
+			}
+
+			if tt.onConflict == BundleConflictSkip {
+				if len(plan.ToolConfigs) != 0 || len(plan.Files) != 0 {
+					t.Errorf("skipped script should not import tool configs or files: %+v", plan.ToolConfigs)
+				}
+				return
+			}
+			if len(plan.ToolConfigs) != 1 || plan.ToolConfigs[0].ID != "script_"+loginID || plan.ToolConfigs[0].ScriptID != loginID {
+				t.Errorf("tool configs = %+v", plan.ToolConfigs)
+			}
+			login := plan.Scripts[0]
+			upload := login.Actions[0].FilePaths
+			if filepath.Dir(filepath.Dir(upload[0])) != "/assets" || upload[1] != "https://example.com/a.png" {
+				t.Errorf("upload paths = %v", upload)
+			}
+			if _, ok := plan.Files[login.DownloadedFiles[0].FilePath]; !ok || len(plan.Files) != 2 {
+				t.Errorf("files = %v, downloaded = %s", plan.Files, login.DownloadedFiles[0].FilePath)
+			}
+		})
+	}
+}
+
+func TestPlanBundleImportUnresolvedReferences(t *testing.T) {
+	bundle := testBundle()
+	bundle.Scripts[1].Actions = append(bundle.Scripts[1].Actions,
+		models.ScriptAction{Type: models.ActionTypeCallScript, ScriptID: "installed"},
+		models.ScriptAction{Type: models.ActionTypeLoopList, Loop: &models.ActionLoop{ListVariable: "ids", Actions: []models.ScriptAction{
+			{Type: models.ActionTypeCallScript, ScriptID: "missing"},
+		}}},
+	)
+	state := BundleImportState{Scripts: []*models.Script{{ID: "installed", Name: "helper"}}}
+
+	plan := PlanBundleImport(bundle, state, BundleImportOptions{FilesDir: "/assets"})
+	if len(plan.Unresolved) != 1 || plan.Unresolved[0] != (BundleReference{Script: "report", ScriptID: "missing"}) {
+		t.Errorf("unresolved = %+v, want only the missing script", plan.Unresolved)
+	}
+}
@@ -0,0 +1,1373 @@
+package browser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/browserwing/browserwing/models"
+)
+
+// 支持导出的代码目标
+const (
+	ExportTargetPlaywrightTS     = "playwright-ts"     // Playwright（TypeScript）
+	ExportTargetPlaywrightPython = "playwright-python" // Playwright（Python 同步 API）
+	ExportTargetPuppeteer        = "puppeteer"         // Puppeteer（Node.js）
+	ExportTargetGoRod            = "go-rod"            // 使用 go-rod 的 Go 程序
+)
+
+// ExportTargets 所有支持的导出目标
+var ExportTargets = []string{ExportTargetPlaywrightTS, ExportTargetPlaywrightPython, ExportTargetPuppeteer, ExportTargetGoRod}
+
+// CodeExport 导出的独立可运行代码
+type CodeExport struct {
+	Target   string `json:"target"`
+	FileName string `json:"file_name"`
+	Content  string `json:"content"`
+	TODOs    int    `json:"todos"` // 无法自动转换、需要手动补充的步骤数（代码中以 TODO 注释标出）
+}
+
+// codeDialect 目标语言的代码生成规则，返回的代码行不含外层缩进
+type codeDialect interface {
+	fileExt() string
+	indent() string
+	literal(s string) string
+	comment(text string) string
+	ifOpen(cond string) string
+	ifClose() string // Python 没有结束语句，返回空字符串
+	action(g *codeGenerator, step int, a models.ScriptAction) ([]string, bool)
+	program(g *codeGenerator, body []string) string
+}
+
+var codeDialects = map[string]codeDialect{
+	ExportTargetPlaywrightTS:     playwrightTSDialect{},
+	ExportTargetPlaywrightPython: playwrightPythonDialect{},
+	ExportTargetPuppeteer:        puppeteerDialect{},
+	ExportTargetGoRod:            goRodDialect{},
+}
+
+// codeGenerator 生成过程中的状态
+type codeGenerator struct {
+	dialect codeDialect
+	script  *models.Script
+	uses    map[string]bool // 用到的运行时辅助函数（fill、compare、when、check）和 Go 导入包
+	todos   int
+}
+
+// ExportScriptCode 将脚本转换为目标语言的独立可运行代码
+// 变量在运行时从同名环境变量读取（未设置时使用脚本的预设值），抓取结果在结束时以 JSON 输出
+func ExportScriptCode(script *models.Script, target string) (*CodeExport, error) {
+	dialect, ok := codeDialects[target]
+	if !ok {
+		return nil, fmt.Errorf("unsupported export target: %s (supported: %s)", target, strings.Join(ExportTargets, ", "))
+	}
+
+	g := &codeGenerator{dialect: dialect, script: script, uses: map[string]bool{}}
+	var body []string
+	if len(script.RouteRules) > 0 {
+		body = append(body, g.todo(fmt.Sprintf("%d request interception rule(s) are not exported", len(script.RouteRules))))
+	}
+	if script.Emulation != nil {
+		body = append(body, g.todo("emulation settings (viewport, device, locale) are not exported"))
+	}
+	if script.URL != "" {
+		code, _ := dialect.action(g, 0, models.ScriptAction{Type: "navigate", URL: script.URL})
+		body = append(body, code...)
+	}
+	for i, action := range script.Actions {
+		body = append(body, "")
+		body = append(body, g.step(i+1, action)...)
+	}
+
+	content := dialect.program(g, body)
+	if target == ExportTargetGoRod {
+		formatted, err := format.Source([]byte(content))
+		if err != nil {
+			return nil, fmt.Errorf("generated Go code is invalid: %w", err)
+		}
+		content = string(formatted)
+	}
+
+	return &CodeExport{
+		Target:   target,
+		FileName: exportFileName(script.Name, dialect.fileExt()),
+		Content:  content,
+		TODOs:    g.todos,
+	}, nil
+}
+
+// step 生成单个步骤的代码（步骤说明注释 + 执行条件）
+func (g *codeGenerator) step(index int, action models.ScriptAction) []string {
+	title := fmt.Sprintf("Step %d: %s", index, action.Type)
+	if action.Remark != "" {
+		title += " - " + action.Remark
+	} else if action.Description != "" {
+		title += " - " + action.Description
+	}
+	lines := []string{g.dialect.comment(title)}
+
+	code, ok := g.dialect.action(g, index, action)
+	if !ok {
+		code = []string{g.todo(unsupportedExportReason(action))}
+	}
+
+	if action.Condition == nil || !action.Condition.Enabled {
+		return append(lines, code...)
+	}
+	g.uses["when"] = true
+	g.uses["compare"] = true
+	cond := fmt.Sprintf("when(%s, %s, %s)", g.dialect.literal(action.Condition.Variable), g.dialect.literal(action.Condition.Operator), g.str(action.Condition.Value))
+	lines = append(lines, g.dialect.ifOpen(cond))
+	for _, line := range code {
+		lines = append(lines, g.dialect.indent()+line)
+	}
+	if end := g.dialect.ifClose(); end != "" {
+		lines = append(lines, end)
+	}
+	return lines
+}
+
+// str 字符串表达式，包含 ${变量} 时在运行时替换
+func (g *codeGenerator) str(s string) string {
+	if strings.Contains(s, "${") {
+		g.uses["fill"] = true
+		return "fill(" + g.dialect.literal(s) + ")"
+	}
+	return g.dialect.literal(s)
+}
+
+// todo 需要手动补充的代码注释
+func (g *codeGenerator) todo(text string) string {
+	g.todos++
+	return g.dialect.comment("TODO: " + text)
+}
+
+// uploadFiles 上传文件路径，远程文件需要先下载到本地
+func (g *codeGenerator) uploadFiles(a models.ScriptAction) (files, notes []string) {
+	for _, path := range a.FilePaths {
+		if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+			notes = append(notes, g.todo("download "+path+" to a local file before uploading"))
+		}
+		files = append(files, g.str(path))
+	}
+	return files, notes
+}
+
+// assertMessage 断言失败信息
+func (g *codeGenerator) assertMessage(opts *models.AssertOptions) string {
+	if opts.Message != "" {
+		return g.str(opts.Message)
+	}
+	return g.dialect.literal(strings.Join(strings.Fields(opts.Kind+" "+opts.Operator+" "+opts.Expected), " "))
+}
+
+// variableNames 可通过环境变量覆盖的变量：预设变量、变量声明和步骤中引用的 ${变量}
+func (g *codeGenerator) variableNames() []string {
+	names := map[string]bool{}
+	for name := range g.script.Variables {
+		names[name] = true
+	}
+	for _, def := range g.script.VariableDefs {
+		names[def.Name] = true
+	}
+	if data, err := json.Marshal(g.script.Actions); err == nil {
+		for _, match := range exportPlaceholderPattern.FindAllStringSubmatch(string(data), -1) {
+			names[match[1]] = true
+		}
+	}
+	for _, match := range exportPlaceholderPattern.FindAllStringSubmatch(g.script.URL, -1) {
+		names[match[1]] = true
+	}
+	return sortedBoolKeys(names)
+}
+
+// variableDefaults 变量的默认值（预设变量优先于变量声明中的默认值）
+func (g *codeGenerator) variableDefaults() (names []string, values map[string]string) {
+	values = map[string]string{}
+	for _, def := range g.script.VariableDefs {
+		if def.Default != "" {
+			values[def.Name] = def.Default
+		}
+	}
+	for name, value := range g.script.Variables {
+		values[name] = value
+	}
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, values
+}
+
+// helperCode 按依赖顺序拼接用到的辅助函数
+func (g *codeGenerator) helperCode(helpers map[string]string) string {
+	if g.uses["when"] {
+		g.uses["compare"] = true
+	}
+	var parts []string
+	for _, name := range []string{"fill", "compare", "when", "check"} {
+		if g.uses[name] {
+			parts = append(parts, strings.TrimSpace(helpers[name]))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, "\n\n") + "\n\n"
+}
+
+// exportPlaceholderPattern ${变量} 占位符
+var exportPlaceholderPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// unsupportedExportReason 无法导出的步骤说明
+func unsupportedExportReason(action models.ScriptAction) string {
+	switch {
+	case action.Type == "ai_control":
+		prompt := action.AIControlPrompt
+		if action.AIControlXPath != "" {
+			prompt += " (element: " + action.AIControlXPath + ")"
+		}
+		return "AI control step, implement manually: " + oneLine(prompt)
+	case action.Type == "capture_xhr":
+		return fmt.Sprintf("capture the %s response of %s (for example by waiting for the response)", action.Method, action.URL)
+	case models.IsLoopAction(action.Type):
+		count := 0
+		if action.Loop != nil {
+			count = len(action.Loop.Actions)
+		}
+		return fmt.Sprintf("%s with %d nested action(s) is not exported, implement the loop manually", action.Type, count)
+	case action.Type == models.ActionTypeCallScript:
+		return fmt.Sprintf("inline the steps of called script %s", action.ScriptID)
+	case action.Type == models.ActionTypeArchive:
+		return "save an MHTML archive of the page (CDP Page.captureSnapshot)"
+	case action.Type == "switch_tab":
+		return fmt.Sprintf("switch to tab %q", action.Value)
+	case action.Selector == "" && action.XPath == "" && exportNeedsElement(action.Type):
+		return fmt.Sprintf("%s step has no selector", action.Type)
+	case action.Type == "keyboard":
+		return fmt.Sprintf("press key %q", action.Key)
+	case action.Type == models.ActionTypeAssert && action.Assert == nil:
+		return "assert step has no assertion settings"
+	case action.XPath == "" && IsShadowLocator(action.Selector):
+		return fmt.Sprintf("rewrite shadow DOM locator %q for this target", action.Selector)
+	}
+	return fmt.Sprintf("action type %q is not supported by the exporter", action.Type)
+}
+
+// exportNeedsElement 需要定位元素的操作
+func exportNeedsElement(actionType string) bool {
+	switch actionType {
+	case "click", "input", "select", "extract_text", "extract_html", "extract_attribute", "upload_file":
+		return true
+	}
+	return false
+}
+
+// exportKey 键盘步骤按键：Playwright / Puppeteer 键名、go-rod input 常量，修饰键组合时 ctrl 为 true
+func exportKey(key string) (name, rodKey string, ctrl, ok bool) {
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case "enter":
+		return "Enter", "input.Enter", false, true
+	case "tab":
+		return "Tab", "input.Tab", false, true
+	case "backspace":
+		return "Backspace", "input.Backspace", false, true
+	case "ctrl+a":
+		return "A", "input.KeyA", true, true
+	case "ctrl+c":
+		return "C", "input.KeyC", true, true
+	case "ctrl+v":
+		return "V", "input.KeyV", true, true
+	}
+	return "", "", false, false
+}
+
+// exportJSFunction 将 execute_js 的代码转换为函数表达式（规则与回放时一致）
+func exportJSFunction(code string) string {
+	code = strings.TrimSpace(code)
+	switch {
+	case strings.HasPrefix(code, "() =>") || strings.HasPrefix(code, "function"):
+		return code
+	case strings.HasPrefix(code, "(() =>") && (strings.HasSuffix(code, ")()") || strings.HasSuffix(code, ")();")):
+		code = strings.TrimSuffix(strings.TrimSuffix(code, ";"), "()")
+		return code[1 : len(code)-1]
+	case strings.Contains(code, "return") || strings.Contains(code, "\n") || strings.Contains(code, ";"):
+		return "() => {\n" + code + "\n}"
+	}
+	return "() => (" + code + ")"
+}
+
+// exportVariable 抓取结果保存的变量名，未设置时按步骤编号命名
+func exportVariable(step int, action models.ScriptAction) string {
+	if action.VariableName != "" {
+		return action.VariableName
+	}
+	return fmt.Sprintf("step_%d", step)
+}
+
+// exportFileName 由脚本名称生成文件名
+func exportFileName(name, ext string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "_"):
+			b.WriteByte('_')
+		}
+	}
+	base := strings.Trim(b.String(), "_")
+	if base == "" {
+		base = "script"
+	}
+	return base + ext
+}
+
+// jsonLiteral JSON 字符串字面量，同时是合法的 JavaScript / Python 字符串
+func jsonLiteral(s string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// jsLiteral 单引号的 JavaScript 字符串字面量
+func jsLiteral(s string) string {
+	quoted := jsonLiteral(s)
+	quoted = strings.ReplaceAll(quoted[1:len(quoted)-1], `\"`, `"`)
+	return "'" + strings.ReplaceAll(quoted, "'", `\'`) + "'"
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func indentLines(lines []string, prefix string) string {
+	var b strings.Builder
+	for _, line := range lines {
+		if line != "" {
+			b.WriteString(prefix)
+			b.WriteString(line)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func sortedBoolKeys(values map[string]bool) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ============= Playwright（TypeScript） =============
+
+type playwrightTSDialect struct{}
+
+func (playwrightTSDialect) fileExt() string            { return ".ts" }
+func (playwrightTSDialect) indent() string             { return "  " }
+func (playwrightTSDialect) literal(s string) string    { return jsLiteral(s) }
+func (playwrightTSDialect) comment(text string) string { return "// " + oneLine(text) }
+func (playwrightTSDialect) ifOpen(cond string) string  { return "if (" + cond + ") {" }
+func (playwrightTSDialect) ifClose() string            { return "}" }
+
+// playwrightSelector Playwright 选择器（CSS 默认穿透开放的 Shadow DOM）
+func playwrightSelector(action models.ScriptAction) string {
+	if action.XPath != "" {
+		return "xpath=" + action.XPath
+	}
+	selector := strings.TrimSpace(action.Selector)
+	if strings.HasPrefix(strings.ToLower(selector), ShadowLocatorPrefix) {
+		return strings.TrimSpace(selector[len(ShadowLocatorPrefix):])
+	}
+	return strings.ReplaceAll(selector, " "+ShadowPierceSeparator+" ", " ")
+}
+
+func (d playwrightTSDialect) action(g *codeGenerator, step int, a models.ScriptAction) ([]string, bool) {
+	if exportNeedsElement(a.Type) && a.Selector == "" && a.XPath == "" {
+		return nil, false
+	}
+	loc := "page.locator(" + g.str(playwrightSelector(a)) + ").first()"
+	store := func(expr string) []string {
+		name := d.literal(exportVariable(step, a))
+		return []string{
+			"variables[" + name + "] = " + expr + ";",
+			"extracted[" + name + "] = variables[" + name + "];",
+		}
+	}
+
+	switch a.Type {
+	case "navigate":
+		return []string{"await page.goto(" + g.str(a.URL) + ");"}, true
+	case "click":
+		return []string{"await " + loc + ".click();"}, true
+	case "input":
+		return []string{"await " + loc + ".fill(" + g.str(a.Value) + ");"}, true
+	case "select":
+		return []string{"await " + loc + ".selectOption({ label: " + g.str(a.Value) + " });"}, true
+	case "keyboard":
+		name, _, ctrl, ok := exportKey(a.Key)
+		if !ok {
+			return nil, false
+		}
+		if ctrl {
+			name = "Control+" + name
+		}
+		var lines []string
+		if a.Selector != "" || a.XPath != "" {
+			lines = append(lines, "await "+loc+".focus();")
+		}
+		return append(lines, "await page.keyboard.press("+d.literal(name)+");"), true
+	case "scroll":
+		return []string{fmt.Sprintf("await page.evaluate(() => window.scrollTo(%d, %d));", a.ScrollX, a.ScrollY)}, true
+	case "sleep":
+		return []string{fmt.Sprintf("await page.waitForTimeout(%d);", a.Duration)}, true
+	case "wait":
+		return []string{fmt.Sprintf("await page.waitForTimeout(%d);", a.Timestamp)}, true
+	case "extract_text":
+		return store("(await " + loc + ".innerText()).trim()"), true
+	case "extract_html":
+		return store("await " + loc + ".evaluate((el) => el.outerHTML)"), true
+	case "extract_attribute":
+		return store("(await " + loc + ".getAttribute(" + d.literal(a.AttributeName) + ")) ?? ''"), true
+	case "execute_js":
+		call := "await page.evaluate(" + g.str(exportJSFunction(a.JSCode)) + ")"
+		if a.VariableName == "" {
+			return []string{call + ";"}, true
+		}
+		return store("String(" + call + ")"), true
+	case "upload_file":
+		files, notes := g.uploadFiles(a)
+		return append(notes, "await "+loc+".setInputFiles(["+strings.Join(files, ", ")+"]);"), true
+	case "screenshot":
+		return []string{fmt.Sprintf("await page.screenshot({ path: 'step-%d.png', fullPage: %t });", step, a.ScreenshotMode == "fullpage")}, true
+	case models.ActionTypePDF:
+		landscape, background := pdfFlags(a)
+		return []string{fmt.Sprintf("await page.pdf({ path: 'step-%d.pdf', landscape: %t, printBackground: %t });", step, landscape, background)}, true
+	case "open_tab":
+		return []string{"page = await context.newPage();", "pages.push(page);", "await page.goto(" + g.str(a.URL) + ");"}, true
+	case "switch_tab":
+		index, err := strconv.Atoi(strings.TrimSpace(a.Value))
+		if err != nil {
+			return nil, false
+		}
+		return []string{fmt.Sprintf("page = pages[%d];", index), "await page.bringToFront();"}, true
+	case "switch_active_tab":
+		return []string{"// 回放时切换到浏览器当前激活的标签页，这里使用最后打开的标签页", "page = pages[pages.length - 1];", "await page.bringToFront();"}, true
+	case models.ActionTypeAssert:
+		return jsAssert(g, a, "page.locator("+g.str(playwrightSelector(a))+").count()", "page.url()", "await page.title()", loc+".innerText()", func(attr string) string {
+			return "(await " + loc + ".getAttribute(" + attr + ")) ?? ''"
+		}, func(state string, timeout int) string {
+			return fmt.Sprintf("await %s.waitFor({ state: '%s', timeout: %d }).catch(() => {});", loc, state, timeout)
+		}, func(state string) string {
+			if state == "visible" {
+				return "await " + loc + ".isVisible()"
+			}
+			return "await " + loc + ".isHidden()"
+		})
+	}
+	return nil, false
+}
+
+// jsAssert 生成 JavaScript 断言（Playwright 与 Puppeteer 共用，页面访问方式由参数提供）
+func jsAssert(g *codeGenerator, a models.ScriptAction, countExpr, urlExpr, titleExpr, textExpr string,
+	attrExpr func(attr string) string, waitFor func(state string, timeout int) string, stateExpr func(state string) string) ([]string, bool) {
+	opts := a.Assert
+	if opts == nil {
+		return nil, false
+	}
+	needsElement := opts.Kind != models.AssertURLMatches && opts.Kind != models.AssertTitleMatches && opts.Kind != models.AssertVariable
+	if needsElement && a.Selector == "" && a.XPath == "" {
+		return nil, false
+	}
+
+	operator := opts.Operator
+	if operator == "" {
+		operator = "="
+	}
+	expected := g.str(opts.Expected)
+	var lines []string
+	var cond string
+	switch opts.Kind {
+	case models.AssertVisible, models.AssertHidden:
+		if opts.WaitMs > 0 {
+			lines = append(lines, waitFor(opts.Kind, opts.WaitMs))
+		}
+		cond = stateExpr(opts.Kind)
+	case models.AssertTextEquals:
+		cond = "compare((await " + textExpr + ").trim(), '=', " + expected + ")"
+	case models.AssertTextContains:
+		cond = "compare(await " + textExpr + ", 'contains', " + expected + ")"
+	case models.AssertTextMatches:
+		cond = "new RegExp(" + expected + ").test(await " + textExpr + ")"
+	case models.AssertAttribute:
+		cond = "compare(" + attrExpr(jsLiteral(opts.Attribute)) + ", " + jsLiteral(operator) + ", " + expected + ")"
+	case models.AssertURLMatches:
+		cond = "new RegExp(" + expected + ").test(" + urlExpr + ")"
+	case models.AssertTitleMatches:
+		cond = "new RegExp(" + expected + ").test(" + titleExpr + ")"
+	case models.AssertCount:
+		cond = "compare(String(await " + countExpr + "), " + jsLiteral(operator) + ", " + expected + ")"
+	case models.AssertVariable:
+		cond = "compare(variables[" + jsLiteral(opts.Variable) + "] ?? '', " + jsLiteral(operator) + ", " + expected + ")"
+	default:
+		return nil, false
+	}
+	if strings.HasPrefix(cond, "compare(") {
+		g.uses["compare"] = true
+	}
+	g.uses["check"] = true
+	return append(lines, fmt.Sprintf("check(%s, %s, %t);", cond, g.assertMessage(opts), opts.IsSoft())), true
+}
+
+const playwrightTSHelpers = `function fill(text: string): string {
+  return text.replace(/\$\{([^}]+)\}/g, (match, name) => (name in variables ? variables[name] : match));
+}
+`
+
+const tsCompareHelper = `function compare(actual: string, op: string, expected: string): boolean {
+  const a = Number(actual);
+  const e = Number(expected);
+  const numeric = actual.trim() !== '' && expected.trim() !== '' && !isNaN(a) && !isNaN(e);
+  const list = expected.split(',').map((v) => v.trim());
+  switch (op) {
+    case '':
+    case '=':
+    case '==':
+      return actual === expected;
+    case '!=':
+      return actual !== expected;
+    case '>':
+      return numeric ? a > e : actual > expected;
+    case '<':
+      return numeric ? a < e : actual < expected;
+    case '>=':
+      return numeric ? a >= e : actual >= expected;
+    case '<=':
+      return numeric ? a <= e : actual <= expected;
+    case 'in':
+      return list.includes(actual);
+    case 'not_in':
+      return !list.includes(actual);
+    case 'contains':
+      return actual.includes(expected);
+    case 'not_contains':
+      return !actual.includes(expected);
+    case 'matches':
+      return new RegExp(expected).test(actual);
+    case 'exists':
+      return actual !== '';
+    case 'not_exists':
+      return actual === '';
+  }
+  throw new Error('unsupported operator: ' + op);
+}
+`
+
+const tsWhenHelper = `function when(name: string, op: string, expected: string): boolean {
+  if (op === 'exists') return name in variables;
+  if (op === 'not_exists') return !(name in variables);
+  return name in variables && compare(variables[name], op, expected);
+}
+`
+
+const tsCheckHelper = `function check(ok: boolean, message: string, soft: boolean): void {
+  if (ok) return;
+  if (!soft) throw new Error('assertion failed: ' + message);
+  console.warn('soft assertion failed: ' + message);
+}
+`
+
+// stripTSTypes 去掉辅助函数中的类型标注，得到 JavaScript 版本
+var (
+	tsReturnType = regexp.MustCompile(`\): (string|boolean|void) \{`)
+	tsParamType  = regexp.MustCompile(`: (string|boolean)([,)])`)
+)
+
+func stripTSTypes(code string) string {
+	return tsParamType.ReplaceAllString(tsReturnType.ReplaceAllString(code, ") {"), "$2")
+}
+
+func (d playwrightTSDialect) program(g *codeGenerator, body []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s\n", oneLine(g.script.Name))
+	b.WriteString("// Generated by BrowserWing. Run: npm install playwright && npx playwright install chromium && npx tsx " + exportFileName(g.script.Name, ".ts") + "\n")
+	b.WriteString("// Variables can be overridden with environment variables of the same name.\n")
+	b.WriteString("import { chromium } from 'playwright';\n\n")
+	b.WriteString(jsVariables(g, "const variables: Record<string, string> = {", "const extracted: Record<string, string> = {};"))
+	b.WriteString(g.helperCode(map[string]string{"fill": playwrightTSHelpers, "compare": tsCompareHelper, "when": tsWhenHelper, "check": tsCheckHelper}))
+	b.WriteString("async function main() {\n")
+	b.WriteString("  const browser = await chromium.launch();\n")
+	b.WriteString("  const context = await browser.newContext();\n")
+	b.WriteString("  let page = await context.newPage();\n")
+	b.WriteString("  const pages = [page];\n\n")
+	b.WriteString(indentLines(body, "  "))
+	b.WriteString("\n  console.log(JSON.stringify(extracted, null, 2));\n")
+	b.WriteString("  await browser.close();\n")
+	b.WriteString("}\n\n")
+	b.WriteString(jsMain)
+	return b.String()
+}
+
+const jsMain = `main().catch((err) => {
+  console.error(err);
+  process.exit(1);
+});
+`
+
+// jsVariables JavaScript / TypeScript 的变量声明和环境变量覆盖
+func jsVariables(g *codeGenerator, declare, extracted string) string {
+	var b strings.Builder
+	names, values := g.variableDefaults()
+	b.WriteString(declare + "\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %s: %s,\n", jsLiteral(name), jsLiteral(values[name]))
+	}
+	b.WriteString("};\n")
+	if overridable := g.variableNames(); len(overridable) > 0 {
+		quoted := make([]string, len(overridable))
+		for i, name := range overridable {
+			quoted[i] = jsLiteral(name)
+		}
+		fmt.Fprintf(&b, "for (const name of [%s]) {\n", strings.Join(quoted, ", "))
+		b.WriteString("  const value = process.env[name];\n")
+		b.WriteString("  if (value !== undefined) variables[name] = value;\n")
+		b.WriteString("}\n")
+	}
+	b.WriteString(extracted + "\n\n")
+	return b.String()
+}
+
+func pdfFlags(a models.ScriptAction) (landscape, background bool) {
+	if a.PDF == nil {
+		return false, false
+	}
+	return a.PDF.Landscape, a.PDF.PrintBackground
+}
+
+// ============= Playwright（Python） =============
+
+type playwrightPythonDialect struct{}
+
+func (playwrightPythonDialect) fileExt() string            { return ".py" }
+func (playwrightPythonDialect) indent() string             { return "    " }
+func (playwrightPythonDialect) literal(s string) string    { return jsonLiteral(s) }
+func (playwrightPythonDialect) comment(text string) string { return "# " + oneLine(text) }
+func (playwrightPythonDialect) ifOpen(cond string) string  { return "if " + cond + ":" }
+func (playwrightPythonDialect) ifClose() string            { return "" }
+
+func pyBool(v bool) string {
+	if v {
+		return "True"
+	}
+	return "False"
+}
+
+func (d playwrightPythonDialect) action(g *codeGenerator, step int, a models.ScriptAction) ([]string, bool) {
+	if exportNeedsElement(a.Type) && a.Selector == "" && a.XPath == "" {
+		return nil, false
+	}
+	loc := "page.locator(" + g.str(playwrightSelector(a)) + ").first"
+	store := func(expr string) []string {
+		name := d.literal(exportVariable(step, a))
+		return []string{
+			"variables[" + name + "] = " + expr,
+			"extracted[" + name + "] = variables[" + name + "]",
+		}
+	}
+
+	switch a.Type {
+	case "navigate":
+		return []string{"page.goto(" + g.str(a.URL) + ")"}, true
+	case "click":
+		return []string{loc + ".click()"}, true
+	case "input":
+		return []string{loc + ".fill(" + g.str(a.Value) + ")"}, true
+	case "select":
+		return []string{loc + ".select_option(label=" + g.str(a.Value) + ")"}, true
+	case "keyboard":
+		name, _, ctrl, ok := exportKey(a.Key)
+		if !ok {
+			return nil, false
+		}
+		if ctrl {
+			name = "Control+" + name
+		}
+		var lines []string
+		if a.Selector != "" || a.XPath != "" {
+			lines = append(lines, loc+".focus()")
+		}
+		return append(lines, "page.keyboard.press("+d.literal(name)+")"), true
+	case "scroll":
+		return []string{fmt.Sprintf(`page.evaluate("() => window.scrollTo(%d, %d)")`, a.ScrollX, a.ScrollY)}, true
+	case "sleep":
+		return []string{fmt.Sprintf("page.wait_for_timeout(%d)", a.Duration)}, true
+	case "wait":
+		return []string{fmt.Sprintf("page.wait_for_timeout(%d)", a.Timestamp)}, true
+	case "extract_text":
+		return store(loc + ".inner_text().strip()"), true
+	case "extract_html":
+		return store(loc + `.evaluate("(el) => el.outerHTML")`), true
+	case "extract_attribute":
+		return store(loc + ".get_attribute(" + d.literal(a.AttributeName) + `) or ""`), true
+	case "execute_js":
+		call := "page.evaluate(" + g.str(exportJSFunction(a.JSCode)) + ")"
+		if a.VariableName == "" {
+			return []string{call}, true
+		}
+		return store("str(" + call + ")"), true
+	case "upload_file":
+		files, notes := g.uploadFiles(a)
+		return append(notes, loc+".set_input_files(["+strings.Join(files, ", ")+"])"), true
+	case "screenshot":
+		return []string{fmt.Sprintf(`page.screenshot(path="step-%d.png", full_page=%s)`, step, pyBool(a.ScreenshotMode == "fullpage"))}, true
+	case models.ActionTypePDF:
+		landscape, background := pdfFlags(a)
+		return []string{fmt.Sprintf(`page.pdf(path="step-%d.pdf", landscape=%s, print_background=%s)`, step, pyBool(landscape), pyBool(background))}, true
+	case "open_tab":
+		return []string{"page = context.new_page()", "pages.append(page)", "page.goto(" + g.str(a.URL) + ")"}, true
+	case "switch_tab":
+		index, err := strconv.Atoi(strings.TrimSpace(a.Value))
+		if err != nil {
+			return nil, false
+		}
+		return []string{fmt.Sprintf("page = pages[%d]", index), "page.bring_to_front()"}, true
+	case "switch_active_tab":
+		return []string{"# 回放时切换到浏览器当前激活的标签页，这里使用最后打开的标签页", "page = pages[-1]", "page.bring_to_front()"}, true
+	case models.ActionTypeAssert:
+		return d.assert(g, a, loc)
+	}
+	return nil, false
+}
+
+func (d playwrightPythonDialect) assert(g *codeGenerator, a models.ScriptAction, loc string) ([]string, bool) {
+	opts := a.Assert
+	if opts == nil {
+		return nil, false
+	}
+	if opts.Kind != models.AssertURLMatches && opts.Kind != models.AssertTitleMatches && opts.Kind != models.AssertVariable && a.Selector == "" && a.XPath == "" {
+		return nil, false
+	}
+	operator := opts.Operator
+	if operator == "" {
+		operator = "="
+	}
+	expected := g.str(opts.Expected)
+	var lines []string
+	var cond string
+	switch opts.Kind {
+	case models.AssertVisible, models.AssertHidden:
+		if opts.WaitMs > 0 {
+			lines = append(lines,
+				"try:",
+				fmt.Sprintf(`    %s.wait_for(state="%s", timeout=%d)`, loc, opts.Kind, opts.WaitMs),
+				"except Exception:",
+				"    pass",
+			)
+		}
+		cond = loc + ".is_visible()"
+		if opts.Kind == models.AssertHidden {
+			cond = loc + ".is_hidden()"
+		}
+	case models.AssertTextEquals:
+		cond = "compare(" + loc + ".inner_text().strip(), \"=\", " + expected + ")"
+	case models.AssertTextContains:
+		cond = "compare(" + loc + ".inner_text(), \"contains\", " + expected + ")"
+	case models.AssertTextMatches:
+		cond = "re.search(" + expected + ", " + loc + ".inner_text()) is not None"
+	case models.AssertAttribute:
+		cond = "compare(" + loc + ".get_attribute(" + d.literal(opts.Attribute) + ") or \"\", " + d.literal(operator) + ", " + expected + ")"
+	case models.AssertURLMatches:
+		cond = "re.search(" + expected + ", page.url) is not None"
+	case models.AssertTitleMatches:
+		cond = "re.search(" + expected + ", page.title()) is not None"
+	case models.AssertCount:
+		cond = "compare(str(page.locator(" + g.str(playwrightSelector(a)) + ").count()), " + d.literal(operator) + ", " + expected + ")"
+	case models.AssertVariable:
+		cond = "compare(variables.get(" + d.literal(opts.Variable) + ", \"\"), " + d.literal(operator) + ", " + expected + ")"
+	default:
+		return nil, false
+	}
+	if strings.HasPrefix(cond, "compare(") {
+		g.uses["compare"] = true
+	}
+	g.uses["check"] = true
+	return append(lines, fmt.Sprintf("check(%s, %s, %s)", cond, g.assertMessage(opts), pyBool(opts.IsSoft()))), true
+}
+
+const pyFillHelper = `def fill(text):
+    return re.sub(r"\$\{([^}]+)\}", lambda m: variables.get(m.group(1), m.group(0)), text)
+`
+
+const pyCompareHelper = `def compare(actual, op, expected):
+    try:
+        a, e = float(actual), float(expected)
+        numeric = True
+    except ValueError:
+        a, e, numeric = actual, expected, False
+    items = [v.strip() for v in expected.split(",")]
+    if op in ("", "=", "=="):
+        return actual == expected
+    if op == "!=":
+        return actual != expected
+    if op == ">":
+        return a > e if numeric else actual > expected
+    if op == "<":
+        return a < e if numeric else actual < expected
+    if op == ">=":
+        return a >= e if numeric else actual >= expected
+    if op == "<=":
+        return a <= e if numeric else actual <= expected
+    if op == "in":
+        return actual in items
+    if op == "not_in":
+        return actual not in items
+    if op == "contains":
+        return expected in actual
+    if op == "not_contains":
+        return expected not in actual
+    if op == "matches":
+        return re.search(expected, actual) is not None
+    if op == "exists":
+        return actual != ""
+    if op == "not_exists":
+        return actual == ""
+    raise ValueError("unsupported operator: " + op)
+`
+
+const pyWhenHelper = `def when(name, op, expected):
+    if op == "exists":
+        return name in variables
+    if op == "not_exists":
+        return name not in variables
+    return name in variables and compare(variables[name], op, expected)
+`
+
+const pyCheckHelper = `def check(ok, message, soft):
+    if ok:
+        return
+    if not soft:
+        raise AssertionError(message)
+    print("soft assertion failed: " + message, file=sys.stderr)
+`
+
+func (d playwrightPythonDialect) program(g *codeGenerator, body []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", oneLine(g.script.Name))
+	b.WriteString("# Generated by BrowserWing. Run: pip install playwright && playwright install chromium && python " + exportFileName(g.script.Name, ".py") + "\n")
+	b.WriteString("# Variables can be overridden with environment variables of the same name.\n")
+	b.WriteString("import json\nimport os\nimport re\nimport sys\n\n")
+	b.WriteString("from playwright.sync_api import sync_playwright\n\n")
+
+	names, values := g.variableDefaults()
+	b.WriteString("variables = {\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "    %s: %s,\n", jsonLiteral(name), jsonLiteral(values[name]))
+	}
+	b.WriteString("}\n")
+	if overridable := g.variableNames(); len(overridable) > 0 {
+		quoted := make([]string, len(overridable))
+		for i, name := range overridable {
+			quoted[i] = jsonLiteral(name)
+		}
+		fmt.Fprintf(&b, "for name in [%s]:\n", strings.Join(quoted, ", "))
+		b.WriteString("    if name in os.environ:\n")
+		b.WriteString("        variables[name] = os.environ[name]\n")
+	}
+	b.WriteString("extracted = {}\n\n\n")
+
+	if helpers := g.helperCode(map[string]string{"fill": pyFillHelper, "compare": pyCompareHelper, "when": pyWhenHelper, "check": pyCheckHelper}); helpers != "" {
+		b.WriteString(strings.ReplaceAll(helpers, "\n\n", "\n\n\n"))
+	}
+	b.WriteString("def main():\n")
+	b.WriteString("    with sync_playwright() as playwright:\n")
+	b.WriteString("        browser = playwright.chromium.launch()\n")
+	b.WriteString("        context = browser.new_context()\n")
+	b.WriteString("        page = context.new_page()\n")
+	b.WriteString("        pages = [page]\n\n")
+	b.WriteString(indentLines(body, "        "))
+	b.WriteString("\n        print(json.dumps(extracted, ensure_ascii=False, indent=2))\n")
+	b.WriteString("        browser.close()\n\n\n")
+	b.WriteString("if __name__ == \"__main__\":\n")
+	b.WriteString("    main()\n")
+	return b.String()
+}
+
+// ============= Puppeteer =============
+
+type puppeteerDialect struct{ playwrightTSDialect }
+
+func (puppeteerDialect) fileExt() string { return ".js" }
+
+// puppeteerSelector Puppeteer 选择器（xpath/ 和 pierce/ 前缀，>>> 原生支持）
+func puppeteerSelector(action models.ScriptAction) string {
+	if action.XPath != "" {
+		return "xpath/" + action.XPath
+	}
+	selector := strings.TrimSpace(action.Selector)
+	if strings.HasPrefix(strings.ToLower(selector), ShadowLocatorPrefix) {
+		return "pierce/" + strings.TrimSpace(selector[len(ShadowLocatorPrefix):])
+	}
+	return selector
+}
+
+func (d puppeteerDialect) action(g *codeGenerator, step int, a models.ScriptAction) ([]string, bool) {
+	if exportNeedsElement(a.Type) && a.Selector == "" && a.XPath == "" {
+		return nil, false
+	}
+	selector := g.str(puppeteerSelector(a))
+	element := "(await page.waitForSelector(" + selector + "))"
+	store := func(expr string) []string {
+		name := d.literal(exportVariable(step, a))
+		return []string{
+			"variables[" + name + "] = " + expr + ";",
+			"extracted[" + name + "] = variables[" + name + "];",
+		}
+	}
+
+	switch a.Type {
+	case "navigate":
+		return []string{"await page.goto(" + g.str(a.URL) + ", { waitUntil: 'load' });"}, true
+	case "click":
+		return []string{"await page.locator(" + selector + ").click();"}, true
+	case "input":
+		return []string{"await page.locator(" + selector + ").fill(" + g.str(a.Value) + ");"}, true
+	case "select":
+		return []string{
+			"await " + element + ".evaluate((el, label) => {",
+			"  const option = [...el.options].find((o) => o.text.trim() === label);",
+			"  if (!option) throw new Error('option not found: ' + label);",
+			"  el.value = option.value;",
+			"  el.dispatchEvent(new Event('change', { bubbles: true }));",
+			"}, " + g.str(a.Value) + ");",
+		}, true
+	case "keyboard":
+		name, _, ctrl, ok := exportKey(a.Key)
+		if !ok {
+			return nil, false
+		}
+		var lines []string
+		if a.Selector != "" || a.XPath != "" {
+			lines = append(lines, "await "+element+".focus();")
+		}
+		if ctrl {
+			return append(lines,
+				"await page.keyboard.down('Control');",
+				"await page.keyboard.press("+d.literal(strings.ToLower(name))+");",
+				"await page.keyboard.up('Control');",
+			), true
+		}
+		return append(lines, "await page.keyboard.press("+d.literal(name)+");"), true
+	case "scroll":
+		return []string{fmt.Sprintf("await page.evaluate(() => window.scrollTo(%d, %d));", a.ScrollX, a.ScrollY)}, true
+	case "sleep":
+		return []string{fmt.Sprintf("await new Promise((resolve) => setTimeout(resolve, %d));", a.Duration)}, true
+	case "wait":
+		return []string{fmt.Sprintf("await new Promise((resolve) => setTimeout(resolve, %d));", a.Timestamp)}, true
+	case "extract_text":
+		return store("await " + element + ".evaluate((el) => el.innerText.trim())"), true
+	case "extract_html":
+		return store("await " + element + ".evaluate((el) => el.outerHTML)"), true
+	case "extract_attribute":
+		return store("await " + element + ".evaluate((el, name) => el.getAttribute(name) ?? '', " + d.literal(a.AttributeName) + ")"), true
+	case "execute_js":
+		// 字符串形式的 evaluate 按表达式求值，这里包装为立即调用
+		call := "await page.evaluate(" + g.str("("+exportJSFunction(a.JSCode)+")()") + ")"
+		if a.VariableName == "" {
+			return []string{call + ";"}, true
+		}
+		return store("String(" + call + ")"), true
+	case "upload_file":
+		files, notes := g.uploadFiles(a)
+		return append(notes, "await "+element+".uploadFile("+strings.Join(files, ", ")+");"), true
+	case "screenshot":
+		return []string{fmt.Sprintf("await page.screenshot({ path: 'step-%d.png', fullPage: %t });", step, a.ScreenshotMode == "fullpage")}, true
+	case models.ActionTypePDF:
+		landscape, background := pdfFlags(a)
+		return []string{fmt.Sprintf("await page.pdf({ path: 'step-%d.pdf', landscape: %t, printBackground: %t });", step, landscape, background)}, true
+	case "open_tab":
+		return []string{"page = await browser.newPage();", "pages.push(page);", "await page.goto(" + g.str(a.URL) + ", { waitUntil: 'load' });"}, true
+	case "switch_tab":
+		index, err := strconv.Atoi(strings.TrimSpace(a.Value))
+		if err != nil {
+			return nil, false
+		}
+		return []string{fmt.Sprintf("page = pages[%d];", index), "await page.bringToFront();"}, true
+	case "switch_active_tab":
+		return []string{"// 回放时切换到浏览器当前激活的标签页，这里使用最后打开的标签页", "page = pages[pages.length - 1];", "await page.bringToFront();"}, true
+	case models.ActionTypeAssert:
+		return jsAssert(g, a, "page.$$("+selector+").then((els) => els.length)", "page.url()", "await page.title()",
+			"page.$eval("+selector+", (el) => el.innerText)",
+			func(attr string) string {
+				return "await page.$eval(" + selector + ", (el, name) => el.getAttribute(name) ?? '', " + attr + ")"
+			},
+			func(state string, timeout int) string {
+				return fmt.Sprintf("await page.waitForSelector(%s, { %s: true, timeout: %d }).catch(() => {});", selector, state, timeout)
+			},
+			func(state string) string {
+				visible := "await page.$(" + selector + ").then((el) => (el ? el.isVisible() : false))"
+				if state == "visible" {
+					return visible
+				}
+				return "!(" + visible + ")"
+			})
+	}
+	return nil, false
+}
+
+func (d puppeteerDialect) program(g *codeGenerator, body []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s\n", oneLine(g.script.Name))
+	b.WriteString("// Generated by BrowserWing. Run: npm install puppeteer && node " + exportFileName(g.script.Name, ".js") + "\n")
+	b.WriteString("// Variables can be overridden with environment variables of the same name.\n")
+	b.WriteString("const puppeteer = require('puppeteer');\n\n")
+	b.WriteString(jsVariables(g, "const variables = {", "const extracted = {};"))
+	b.WriteString(stripTSTypes(g.helperCode(map[string]string{"fill": playwrightTSHelpers, "compare": tsCompareHelper, "when": tsWhenHelper, "check": tsCheckHelper})))
+	b.WriteString("async function main() {\n")
+	b.WriteString("  const browser = await puppeteer.launch();\n")
+	b.WriteString("  let page = await browser.newPage();\n")
+	b.WriteString("  const pages = [page];\n\n")
+	b.WriteString(indentLines(body, "  "))
+	b.WriteString("\n  console.log(JSON.stringify(extracted, null, 2));\n")
+	b.WriteString("  await browser.close();\n")
+	b.WriteString("}\n\n")
+	b.WriteString(jsMain)
+	return b.String()
+}
+
+// ============= go-rod =============
+
+type goRodDialect struct{}
+
+func (goRodDialect) fileExt() string { return ".go" }
+func (goRodDialect) indent() string  { return "\t" }
+
+// literal 不含反引号的多行文本使用原始字符串
+func (goRodDialect) literal(s string) string {
+	if strings.Contains(s, "\n") && !strings.Contains(s, "`") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+func (goRodDialect) comment(text string) string { return "// " + oneLine(text) }
+func (goRodDialect) ifOpen(cond string) string  { return "if " + cond + " {" }
+func (goRodDialect) ifClose() string            { return "}" }
+
+// element 查找元素的表达式（XPath 优先，与回放一致）
+func (d goRodDialect) element(g *codeGenerator, a models.ScriptAction) string {
+	if a.XPath != "" {
+		return "page.MustElementX(" + g.str(a.XPath) + ")"
+	}
+	return "page.MustElement(" + g.str(a.Selector) + ")"
+}
+
+func (d goRodDialect) action(g *codeGenerator, step int, a models.ScriptAction) ([]string, bool) {
+	if exportNeedsElement(a.Type) && a.Selector == "" && a.XPath == "" {
+		return nil, false
+	}
+	if a.XPath == "" && IsShadowLocator(a.Selector) {
+		return nil, false
+	}
+	element := d.element(g, a)
+	store := func(expr string) []string {
+		name := d.literal(exportVariable(step, a))
+		return []string{
+			"variables[" + name + "] = " + expr,
+			"extracted[" + name + "] = variables[" + name + "]",
+		}
+	}
+
+	switch a.Type {
+	case "navigate":
+		return []string{"page.MustNavigate(" + g.str(a.URL) + ").MustWaitLoad()"}, true
+	case "click":
+		return []string{element + ".MustClick()"}, true
+	case "input":
+		return []string{element + ".MustSelectAllText().MustInput(" + g.str(a.Value) + ")"}, true
+	case "select":
+		return []string{element + ".MustSelect(" + g.str(a.Value) + ")"}, true
+	case "keyboard":
+		_, key, ctrl, ok := exportKey(a.Key)
+		if !ok {
+			return nil, false
+		}
+		g.uses["input"] = true
+		var lines []string
+		if a.Selector != "" || a.XPath != "" {
+			lines = append(lines, element+".MustFocus()")
+		}
+		if ctrl {
+			return append(lines, "page.KeyActions().Press(input.ControlLeft).Type("+key+").MustDo()"), true
+		}
+		return append(lines, "page.Keyboard.MustType("+key+")"), true
+	case "scroll":
+		return []string{fmt.Sprintf(`page.MustEval("() => window.scrollTo(%d, %d)")`, a.ScrollX, a.ScrollY)}, true
+	case "sleep", "wait":
+		duration := a.Duration
+		if a.Type == "wait" {
+			duration = int(a.Timestamp)
+		}
+		g.uses["time"] = true
+		return []string{fmt.Sprintf("time.Sleep(%d * time.Millisecond)", duration)}, true
+	case "extract_text":
+		g.uses["strings"] = true
+		return store("strings.TrimSpace(" + element + ".MustText())"), true
+	case "extract_html":
+		return store(element + ".MustHTML()"), true
+	case "extract_attribute":
+		name := d.literal(exportVariable(step, a))
+		return []string{
+			"if value := " + element + ".MustAttribute(" + d.literal(a.AttributeName) + "); value != nil {",
+			"\tvariables[" + name + "] = *value",
+			"\textracted[" + name + "] = *value",
+			"}",
+		}, true
+	case "execute_js":
+		call := "page.MustEval(" + g.str(exportJSFunction(a.JSCode)) + ")"
+		if a.VariableName == "" {
+			return []string{call}, true
+		}
+		return store(call + ".String()"), true
+	case "upload_file":
+		files, notes := g.uploadFiles(a)
+		return append(notes, element+".MustSetFiles("+strings.Join(files, ", ")+")"), true
+	case "screenshot":
+		if a.ScreenshotMode == "fullpage" {
+			return []string{fmt.Sprintf(`page.MustScreenshotFullPage("step-%d.png")`, step)}, true
+		}
+		return []string{fmt.Sprintf(`page.MustScreenshot("step-%d.png")`, step)}, true
+	case models.ActionTypePDF:
+		return []string{fmt.Sprintf(`page.MustPDF("step-%d.pdf")`, step)}, true
+	case "open_tab":
+		g.uses["pages"] = true
+		return []string{"page = browser.MustPage(" + g.str(a.URL) + ").MustWaitLoad()", "pages = append(pages, page)"}, true
+	case "switch_tab":
+		index, err := strconv.Atoi(strings.TrimSpace(a.Value))
+		if err != nil {
+			return nil, false
+		}
+		g.uses["pages"] = true
+		return []string{fmt.Sprintf("page = pages[%d]", index), "page.MustActivate()"}, true
+	case "switch_active_tab":
+		g.uses["pages"] = true
+		return []string{"// 回放时切换到浏览器当前激活的标签页，这里使用最后打开的标签页", "page = pages[len(pages)-1]", "page.MustActivate()"}, true
+	case models.ActionTypeAssert:
+		return d.assert(g, a, element)
+	}
+	return nil, false
+}
+
+func (d goRodDialect) assert(g *codeGenerator, a models.ScriptAction, element string) ([]string, bool) {
+	opts := a.Assert
+	if opts == nil {
+		return nil, false
+	}
+	if opts.Kind != models.AssertURLMatches && opts.Kind != models.AssertTitleMatches && opts.Kind != models.AssertVariable && a.Selector == "" && a.XPath == "" {
+		return nil, false
+	}
+	operator := opts.Operator
+	if operator == "" {
+		operator = "="
+	}
+	expected := g.str(opts.Expected)
+	query, suffix := g.str(a.Selector), ""
+	if a.XPath != "" {
+		query, suffix = g.str(a.XPath), "X"
+	}
+	check := func(cond string) string {
+		return fmt.Sprintf("check(%s, %s, %t)", cond, g.assertMessage(opts), opts.IsSoft())
+	}
+	g.uses["check"] = true
+
+	switch opts.Kind {
+	case models.AssertVisible, models.AssertHidden:
+		cond := "has && el.MustVisible()"
+		if opts.Kind == models.AssertHidden {
+			cond = "!has || !el.MustVisible()"
+		}
+		return []string{"{", "\thas, el, _ := page.Has" + suffix + "(" + query + ")", "\t" + check(cond), "}"}, true
+	case models.AssertTextEquals:
+		g.uses["compare"], g.uses["strings"] = true, true
+		return []string{check("compare(strings.TrimSpace(" + element + ".MustText()), \"=\", " + expected + ")")}, true
+	case models.AssertTextContains:
+		g.uses["compare"] = true
+		return []string{check("compare(" + element + ".MustText(), \"contains\", " + expected + ")")}, true
+	case models.AssertTextMatches:
+		g.uses["regexp"] = true
+		return []string{check("regexp.MustCompile(" + expected + ").MatchString(" + element + ".MustText())")}, true
+	case models.AssertAttribute:
+		g.uses["compare"] = true
+		return []string{
+			"{",
+			"\tvalue := \"\"",
+			"\tif v := " + element + ".MustAttribute(" + d.literal(opts.Attribute) + "); v != nil {",
+			"\t\tvalue = *v",
+			"\t}",
+			"\t" + check("compare(value, "+d.literal(operator)+", "+expected+")"),
+			"}",
+		}, true
+	case models.AssertURLMatches:
+		g.uses["regexp"] = true
+		return []string{check("regexp.MustCompile(" + expected + ").MatchString(page.MustInfo().URL)")}, true
+	case models.AssertTitleMatches:
+		g.uses["regexp"] = true
+		return []string{check("regexp.MustCompile(" + expected + ").MatchString(page.MustInfo().Title)")}, true
+	case models.AssertCount:
+		g.uses["compare"] = true
+		return []string{check("compare(strconv.Itoa(len(page.MustElements" + suffix + "(" + query + "))), " + d.literal(operator) + ", " + expected + ")")}, true
+	case models.AssertVariable:
+		g.uses["compare"] = true
+		return []string{check("compare(variables[" + d.literal(opts.Variable) + "], " + d.literal(operator) + ", " + expected + ")")}, true
+	}
+	return nil, false
+}
+
+const goFillHelper = "var placeholder = regexp.MustCompile(`\\$\\{([^}]+)\\}`)\n\n" + `func fill(text string) string {
+	return placeholder.ReplaceAllStringFunc(text, func(match string) string {
+		if value, ok := variables[match[2:len(match)-1]]; ok {
+			return value
+		}
+		return match
+	})
+}
+`
+
+const goCompareHelper = `func compare(actual, op, expected string) bool {
+	a, errA := strconv.ParseFloat(strings.TrimSpace(actual), 64)
+	e, errE := strconv.ParseFloat(strings.TrimSpace(expected), 64)
+	numeric := errA == nil && errE == nil
+	switch op {
+	case "", "=", "==":
+		return actual == expected
+	case "!=":
+		return actual != expected
+	case ">":
+		return numeric && a > e || !numeric && actual > expected
+	case "<":
+		return numeric && a < e || !numeric && actual < expected
+	case ">=":
+		return numeric && a >= e || !numeric && actual >= expected
+	case "<=":
+		return numeric && a <= e || !numeric && actual <= expected
+	case "in", "not_in":
+		found := false
+		for _, item := range strings.Split(expected, ",") {
+			if strings.TrimSpace(item) == actual {
+				found = true
+			}
+		}
+		return found == (op == "in")
+	case "contains":
+		return strings.Contains(actual, expected)
+	case "not_contains":
+		return !strings.Contains(actual, expected)
+	case "matches":
+		return regexp.MustCompile(expected).MatchString(actual)
+	case "exists":
+		return actual != ""
+	case "not_exists":
+		return actual == ""
+	}
+	panic("unsupported operator: " + op)
+}
+`
+
+const goWhenHelper = `func when(name, op, expected string) bool {
+	actual, ok := variables[name]
+	switch op {
+	case "exists":
+		return ok
+	case "not_exists":
+		return !ok
+	}
+	return ok && compare(actual, op, expected)
+}
+`
+
+const goCheckHelper = `func check(ok bool, message string, soft bool) {
+	if ok {
+		return
+	}
+	if !soft {
+		panic("assertion failed: " + message)
+	}
+	fmt.Fprintln(os.Stderr, "soft assertion failed:", message)
+}
+`
+
+func (d goRodDialect) program(g *codeGenerator, body []string) string {
+	helpers := g.helperCode(map[string]string{"fill": goFillHelper, "compare": goCompareHelper, "when": goWhenHelper, "check": goCheckHelper})
+	if g.uses["fill"] {
+		g.uses["regexp"] = true
+	}
+	if g.uses["compare"] {
+		g.uses["regexp"], g.uses["strconv"], g.uses["strings"] = true, true, true
+	}
+
+	imports := []string{`"encoding/json"`, `"fmt"`, `"os"`}
+	for _, pkg := range []string{"regexp", "strconv", "strings", "time"} {
+		if g.uses[pkg] {
+			imports = append(imports, strconv.Quote(pkg))
+		}
+	}
+	sort.Strings(imports)
+	imports = append(imports, "", `"github.com/go-rod/rod"`)
+	if g.uses["input"] {
+		imports = append(imports, `"github.com/go-rod/rod/lib/input"`)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s\n", oneLine(g.script.Name))
+	b.WriteString("// Generated by BrowserWing. Run: go mod init example && go get github.com/go-rod/rod && go run .\n")
+	b.WriteString("// Variables can be overridden with environment variables of the same name.\n")
+	b.WriteString("package main\n\nimport (\n")
+	b.WriteString(indentLines(imports, "\t"))
+	b.WriteString(")\n\n")
+
+	names, values := g.variableDefaults()
+	b.WriteString("var variables = map[string]string{\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "\t%s: %s,\n", strconv.Quote(name), d.literal(values[name]))
+	}
+	b.WriteString("}\n\n")
+	b.WriteString("var extracted = map[string]string{}\n\n")
+	b.WriteString(helpers)
+
+	b.WriteString("func main() {\n")
+	if overridable := g.variableNames(); len(overridable) > 0 {
+		quoted := make([]string, len(overridable))
+		for i, name := range overridable {
+			quoted[i] = strconv.Quote(name)
+		}
+		fmt.Fprintf(&b, "\tfor _, name := range []string{%s} {\n", strings.Join(quoted, ", "))
+		b.WriteString("\t\tif value, ok := os.LookupEnv(name); ok {\n")
+		b.WriteString("\t\t\tvariables[name] = value\n")
+		b.WriteString("\t\t}\n")
+		b.WriteString("\t}\n\n")
+	}
+	b.WriteString("\tbrowser := rod.New().MustConnect()\n")
+	b.WriteString("\tdefer browser.MustClose()\n")
+	b.WriteString("\tpage := browser.MustPage()\n")
+	if g.uses["pages"] {
+		b.WriteString("\tpages := []*rod.Page{page}\n")
+	}
+	if len(body) == 0 {
+		b.WriteString("\t_ = page\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(indentLines(body, "\t"))
+	b.WriteString("\n\toutput, _ := json.MarshalIndent(extracted, \"\", \"  \")\n")
+	b.WriteString("\tfmt.Println(string(output))\n")
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,117 @@
+package browser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/browserwing/browserwing/models"
+)
+
+func TestExportScriptCode(t *testing.T) {
+	script := &models.Script{
+		Name:      "Search Docs",
+		URL:       "https://example.com",
+		Variables: map[string]string{"keyword": "golang"},
+		Actions: []models.ScriptAction{
+			{Type: "input", XPath: "//input[@name='q']", Value: "${keyword}"},
+			{Type: "keyboard", Key: "enter"},
+			{Type: "extract_text", Selector: "h1", VariableName: "title"},
+			{Type: "click", Selector: "a.next", Condition: &models.ActionCondition{Enabled: true, Variable: "title", Operator: "contains", Value: "Go"}},
+			{Type: "open_tab", URL: "https://example.com/docs"},
+			{Type: "switch_tab", Value: "0"},
+			{Type: "upload_file", Selector: "input[type=file]", FilePaths: []string{"https://example.com/a.txt"}},
+			{Type: "assert", Assert: &models.AssertOptions{Kind: models.AssertURLMatches, Expected: "example"}},
+			{Type: "ai_control", AIControlPrompt: "log in\nwith the test account"},
+		},
+	}
+
+	tests := []struct {
+		target   string
+		fileName string
+		contains []string
+	}{
+		{
+			target:   ExportTargetPlaywrightTS,
+			fileName: "search_docs.ts",
+			contains: []string{
+				"await page.locator('xpath=//input[@name=\\'q\\']').first().fill(fill('${keyword}'));",
+				"if (when('title', 'contains', 'Go')) {\n    await page.locator('a.next').first().click();\n  }",
+				"variables['title'] = (await page.locator('h1').first().innerText()).trim();",
+				"page = pages[0];",
+				"check(new RegExp('example').test(page.url()), 'url_matches example', false);",
+			},
+		},
+		{
+			target:   ExportTargetPlaywrightPython,
+			fileName: "search_docs.py",
+			contains: []string{
+				`page.locator("xpath=//input[@name='q']").first.fill(fill("${keyword}"))`,
+				"        if when(\"title\", \"contains\", \"Go\"):\n            page.locator(\"a.next\").first.click()",
+				`page.keyboard.press("Enter")`,
+				`check(re.search("example", page.url) is not None, "url_matches example", False)`,
+			},
+		},
+		{
+			target:   ExportTargetPuppeteer,
+			fileName: "search_docs.js",
+			contains: []string{
+				"await page.locator('xpath///input[@name=\\'q\\']').fill(fill('${keyword}'));",
+				"function fill(text) {",
+				"(await page.waitForSelector('input[type=file]')).uploadFile('https://example.com/a.txt');",
+			},
+		},
+		{
+			target:   ExportTargetGoRod,
+			fileName: "search_docs.go",
+			contains: []string{
+				`page.MustElementX("//input[@name='q']").MustSelectAllText().MustInput(fill("${keyword}"))`,
+				"\"github.com/go-rod/rod/lib/input\"",
+				"page.Keyboard.MustType(input.Enter)",
+				"pages := []*rod.Page{page}",
+				`if value, ok := os.LookupEnv(name); ok {`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			export, err := ExportScriptCode(script, tt.target)
+			if err != nil {
+				t.Fatalf("ExportScriptCode: %v", err)
+			}
+			if export.FileName != tt.fileName {
+				t.Errorf("file name = %q, want %q", export.FileName, tt.fileName)
+			}
+			// AI 控制步骤和远程上传文件需要手动补充
+			if export.TODOs != 2 || !strings.Contains(export.Content, "TODO: AI control step, implement manually: log in with the test account") {
+				t.Errorf("todos = %d", export.TODOs)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(export.Content, want) {
+					t.Errorf("missing %q in:\n%s", want, export.Content)
+				}
+			}
+		})
+	}
+
+	if _, err := ExportScriptCode(script, "cypress"); err == nil {
+		t.Error("expected error for unsupported target")
+	}
+}
+
+func TestExportJSFunction(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{code: "document.title", want: "() => (document.title)"},
+		{code: "return document.title", want: "() => {\nreturn document.title\n}"},
+		{code: "() => 1", want: "() => 1"},
+		{code: "(() => { return 2 })();", want: "() => { return 2 }"},
+	}
+	for _, tt := range tests {
+		if got := exportJSFunction(tt.code); got != tt.want {
+			t.Errorf("exportJSFunction(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
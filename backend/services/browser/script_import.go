@@ -0,0 +1,704 @@
+package browser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/browserwing/browserwing/models"
+)
+
+// 支持导入的脚本格式
+const (
+	ImportFormatChromeRecorder = "chrome_recorder" // Chrome DevTools Recorder 导出的 JSON
+	ImportFormatSeleniumIDE    = "selenium_ide"    // Selenium IDE 的 .side 工程文件
+)
+
+// defaultImportWaitMs 导入的等待类步骤默认的最长等待时间（毫秒）
+const defaultImportWaitMs = 5000
+
+// ImportIssue 导入时无法转换（或被忽略）的步骤
+type ImportIssue struct {
+	Script string `json:"script,omitempty"` // 所属脚本名称（Selenium IDE 中为测试名称）
+	Step   int    `json:"step"`             // 步骤在原文件中的序号（从 1 开始）
+	Type   string `json:"type"`             // 原步骤类型或命令
+	Reason string `json:"reason"`
+}
+
+// ImportResult 导入结果，脚本尚未保存（ID 为空）
+type ImportResult struct {
+	Format      string           `json:"format"`
+	Scripts     []*models.Script `json:"scripts"`
+	Unsupported []ImportIssue    `json:"unsupported"`
+}
+
+// DetectImportFormat 根据文件内容判断导入格式
+func DetectImportFormat(data []byte) (string, error) {
+	var probe struct {
+		Steps json.RawMessage `json:"steps"`
+		Tests json.RawMessage `json:"tests"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return "", fmt.Errorf("invalid JSON: %w", err)
+	}
+	switch {
+	case len(probe.Steps) > 0:
+		return ImportFormatChromeRecorder, nil
+	case len(probe.Tests) > 0:
+		return ImportFormatSeleniumIDE, nil
+	}
+	return "", fmt.Errorf("unrecognized format: expected a Chrome Recorder flow (steps) or a Selenium IDE project (tests)")
+}
+
+// ImportScripts 将 Chrome Recorder 或 Selenium IDE 文件转换为脚本，format 为空时自动识别
+func ImportScripts(data []byte, format string) (*ImportResult, error) {
+	data = bytes.TrimSpace(data)
+	if format == "" {
+		detected, err := DetectImportFormat(data)
+		if err != nil {
+			return nil, err
+		}
+		format = detected
+	}
+
+	var (
+		result *ImportResult
+		err    error
+	)
+	switch format {
+	case ImportFormatChromeRecorder:
+		result, err = importChromeRecorder(data)
+	case ImportFormatSeleniumIDE:
+		result, err = importSeleniumIDE(data)
+	default:
+		return nil, fmt.Errorf("unsupported import format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Format = format
+	if result.Unsupported == nil {
+		result.Unsupported = []ImportIssue{}
+	}
+	return result, nil
+}
+
+// ============= Chrome DevTools Recorder =============
+
+type recorderFlow struct {
+	Title   string         `json:"title"`
+	Timeout int            `json:"timeout"`
+	Steps   []recorderStep `json:"steps"`
+}
+
+type recorderStep struct {
+	Type              string              `json:"type"`
+	URL               string              `json:"url"`
+	Value             string              `json:"value"`
+	Key               string              `json:"key"`
+	Button            string              `json:"button"`
+	Selectors         []recorderSelector  `json:"selectors"`
+	X                 float64             `json:"x"` // 滚动位置，Recorder 可能导出小数
+	Y                 float64             `json:"y"`
+	Width             int                 `json:"width"`
+	Height            int                 `json:"height"`
+	DeviceScaleFactor float64             `json:"deviceScaleFactor"`
+	IsMobile          bool                `json:"isMobile"`
+	HasTouch          bool                `json:"hasTouch"`
+	Timeout           int                 `json:"timeout"`
+	Operator          string              `json:"operator"`
+	Count             *int                `json:"count"`
+	Visible           *bool               `json:"visible"`
+	Attributes        map[string]string   `json:"attributes"`
+	AssertedEvents    []recorderAssertion `json:"assertedEvents"`
+}
+
+type recorderAssertion struct {
+	Type  string `json:"type"`
+	URL   string `json:"url"`
+	Title string `json:"title"`
+}
+
+// recorderSelector Recorder 的选择器，可以是字符串或逐层穿透 Shadow DOM 的字符串数组
+type recorderSelector []string
+
+func (s *recorderSelector) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = recorderSelector{single}
+		return nil
+	}
+	var parts []string
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return fmt.Errorf("invalid selector: %s", string(data))
+	}
+	*s = parts
+	return nil
+}
+
+// recorderKeys Recorder 按键名与 keyboard 步骤按键的对应关系
+var recorderKeys = map[string]string{
+	"Enter":     "enter",
+	"Tab":       "tab",
+	"Backspace": "backspace",
+}
+
+func importChromeRecorder(data []byte) (*ImportResult, error) {
+	var flow recorderFlow
+	if err := json.Unmarshal(data, &flow); err != nil {
+		return nil, fmt.Errorf("invalid Chrome Recorder flow: %w", err)
+	}
+
+	name := flow.Title
+	if name == "" {
+		name = "Imported recording"
+	}
+	script := &models.Script{
+		Name:        name,
+		Description: "Imported from Chrome DevTools Recorder",
+		Actions:     []models.ScriptAction{},
+	}
+	result := &ImportResult{Scripts: []*models.Script{script}}
+	unsupported := func(index int, step recorderStep, reason string) {
+		result.Unsupported = append(result.Unsupported, ImportIssue{Step: index + 1, Type: step.Type, Reason: reason})
+	}
+
+	waitMs := flow.Timeout
+	if waitMs <= 0 {
+		waitMs = defaultImportWaitMs
+	}
+	modifier := "" // 当前按下的修饰键（ctrl）
+
+	for i, step := range flow.Steps {
+		switch step.Type {
+		case "navigate":
+			if script.URL == "" {
+				script.URL = step.URL
+			}
+			script.Actions = append(script.Actions, models.ScriptAction{Type: "navigate", URL: step.URL})
+			for _, event := range step.AssertedEvents {
+				if event.Type == "navigation" && event.Title != "" {
+					script.Actions = append(script.Actions, assertAction(models.AssertOptions{
+						Kind:     models.AssertTitleMatches,
+						Expected: "^" + regexp.QuoteMeta(event.Title) + "$",
+						WaitMs:   waitMs,
+					}))
+				}
+			}
+
+		case "click":
+			if step.Button != "" && step.Button != "primary" {
+				unsupported(i, step, fmt.Sprintf("%s mouse button is not supported", step.Button))
+				continue
+			}
+			action, ok := recorderTarget(step.Selectors)
+			if !ok {
+				unsupported(i, step, "no CSS or XPath selector")
+				continue
+			}
+			action.Type = "click"
+			script.Actions = append(script.Actions, action)
+
+		case "change":
+			action, ok := recorderTarget(step.Selectors)
+			if !ok {
+				unsupported(i, step, "no CSS or XPath selector")
+				continue
+			}
+			action.Type = "input"
+			action.Value = step.Value
+			script.Actions = append(script.Actions, action)
+
+		case "keyDown":
+			switch {
+			case step.Key == "Control" || step.Key == "Meta":
+				modifier = "ctrl"
+			case modifier != "" && len(step.Key) == 1 && strings.Contains("acv", strings.ToLower(step.Key)):
+				script.Actions = append(script.Actions, models.ScriptAction{Type: "keyboard", Key: modifier + "+" + strings.ToLower(step.Key)})
+			case recorderKeys[step.Key] != "" && modifier == "":
+				script.Actions = append(script.Actions, models.ScriptAction{Type: "keyboard", Key: recorderKeys[step.Key]})
+			default:
+				key := step.Key
+				if modifier != "" {
+					key = modifier + "+" + key
+				}
+				unsupported(i, step, fmt.Sprintf("key %q is not supported by keyboard actions", key))
+			}
+
+		case "keyUp":
+			// 按键在 keyDown 时已经执行，keyUp 只用于释放修饰键
+			if step.Key == "Control" || step.Key == "Meta" {
+				modifier = ""
+			}
+
+		case "scroll":
+			if len(step.Selectors) > 0 {
+				unsupported(i, step, "scrolling inside an element is not supported, only window scroll")
+				continue
+			}
+			script.Actions = append(script.Actions, models.ScriptAction{
+				Type:    "scroll",
+				ScrollX: int(math.Round(step.X)),
+				ScrollY: int(math.Round(step.Y)),
+			})
+
+		case "waitForElement":
+			action, ok := recorderTarget(step.Selectors)
+			if !ok {
+				unsupported(i, step, "no CSS or XPath selector")
+				continue
+			}
+			timeout := step.Timeout
+			if timeout <= 0 {
+				timeout = waitMs
+			}
+			options := models.AssertOptions{Kind: models.AssertVisible, WaitMs: timeout}
+			switch {
+			case step.Count != nil:
+				operator := step.Operator
+				if operator == "" {
+					operator = "=="
+				}
+				options.Kind = models.AssertCount
+				options.Operator = operator
+				options.Expected = strconv.Itoa(*step.Count)
+			case step.Visible != nil && !*step.Visible:
+				options.Kind = models.AssertHidden
+			}
+			script.Actions = append(script.Actions, withAssert(action, options))
+			for _, attribute := range sortedKeys(step.Attributes) {
+				script.Actions = append(script.Actions, withAssert(action, models.AssertOptions{
+					Kind:      models.AssertAttribute,
+					Attribute: attribute,
+					Expected:  step.Attributes[attribute],
+					WaitMs:    timeout,
+				}))
+			}
+
+		case "setViewport":
+			// 视口写入脚本的仿真配置，在回放页面打开时应用；回放中途改变视口不支持
+			if script.Emulation != nil || len(script.Actions) > 0 {
+				unsupported(i, step, fmt.Sprintf("viewport %dx%d can only be set before the first step", step.Width, step.Height))
+				continue
+			}
+			script.Emulation = recorderViewport(step)
+
+		default:
+			unsupported(i, step, "step type is not supported")
+		}
+	}
+	return result, nil
+}
+
+// recorderViewport 将 setViewport 步骤转换为仿真配置
+func recorderViewport(step recorderStep) *models.EmulationProfile {
+	mobile, touch := step.IsMobile, step.HasTouch
+	return &models.EmulationProfile{
+		Width:             step.Width,
+		Height:            step.Height,
+		DeviceScaleFactor: step.DeviceScaleFactor,
+		Mobile:            &mobile,
+		Touch:             &touch,
+	}
+}
+
+// recorderTarget 将 Recorder 的选择器列表转换为步骤的定位字段
+// 取第一个 CSS 和第一个 XPath；aria/ 选择器写入无障碍信息供语义自愈使用，text/ 在没有 XPath 时转换为 XPath
+func recorderTarget(selectors []recorderSelector) (models.ScriptAction, bool) {
+	var action models.ScriptAction
+	var text string
+	for _, selector := range selectors {
+		if len(selector) == 0 {
+			continue
+		}
+		if len(selector) > 1 {
+			// 逐层穿透 Shadow DOM
+			if action.Selector == "" {
+				action.Selector = strings.Join(selector, " "+ShadowPierceSeparator+" ")
+			}
+			continue
+		}
+		value := selector[0]
+		switch {
+		case strings.HasPrefix(value, "aria/"):
+			if action.Accessibility == nil {
+				action.Accessibility = parseAriaSelector(strings.TrimPrefix(value, "aria/"))
+			}
+		case strings.HasPrefix(value, "xpath/"):
+			if action.XPath == "" {
+				action.XPath = strings.TrimPrefix(value, "xpath/")
+			}
+		case strings.HasPrefix(value, "text/"):
+			if text == "" {
+				text = strings.TrimPrefix(value, "text/")
+			}
+		case strings.HasPrefix(value, "pierce/"):
+			if action.Selector == "" {
+				action.Selector = ShadowLocatorPrefix + strings.TrimPrefix(value, "pierce/")
+			}
+		default:
+			if action.Selector == "" || IsShadowLocator(action.Selector) {
+				action.Selector = value
+			}
+		}
+	}
+	if action.XPath == "" && text != "" {
+		action.XPath = "//*[normalize-space(text())=" + xpathLiteral(text) + "]"
+		action.Text = text
+	}
+	return action, action.Selector != "" || action.XPath != ""
+}
+
+// ariaSelectorPattern aria/名称[role="角色"]
+var ariaSelectorPattern = regexp.MustCompile(`^(.*?)(?:\[role="([^"]+)"\])?$`)
+
+func parseAriaSelector(value string) *models.AccessibilityInfo {
+	match := ariaSelectorPattern.FindStringSubmatch(value)
+	info := &models.AccessibilityInfo{Name: match[1], Role: match[2]}
+	if info.Name == "" && info.Role == "" {
+		return nil
+	}
+	return info
+}
+
+// ============= Selenium IDE =============
+
+type sideProject struct {
+	Name  string     `json:"name"`
+	URL   string     `json:"url"`
+	Tests []sideTest `json:"tests"`
+}
+
+type sideTest struct {
+	Name     string        `json:"name"`
+	Commands []sideCommand `json:"commands"`
+}
+
+type sideCommand struct {
+	Command string     `json:"command"`
+	Target  string     `json:"target"`
+	Targets [][]string `json:"targets"`
+	Value   string     `json:"value"`
+	Comment string     `json:"comment"`
+}
+
+// sideKeyPattern sendKeys 中的按键占位符，如 ${KEY_ENTER}
+var sideKeyPattern = regexp.MustCompile(`\$\{KEY_([A-Z_]+)\}`)
+
+// sideKeys Selenium 按键名与 keyboard 步骤按键的对应关系
+var sideKeys = map[string]string{
+	"ENTER":     "enter",
+	"TAB":       "tab",
+	"BACKSPACE": "backspace",
+	"BKSP":      "backspace",
+}
+
+func importSeleniumIDE(data []byte) (*ImportResult, error) {
+	var project sideProject
+	if err := json.Unmarshal(data, &project); err != nil {
+		return nil, fmt.Errorf("invalid Selenium IDE project: %w", err)
+	}
+	if len(project.Tests) == 0 {
+		return nil, fmt.Errorf("Selenium IDE project has no tests")
+	}
+
+	result := &ImportResult{Scripts: []*models.Script{}}
+	for _, test := range project.Tests {
+		name := test.Name
+		if name == "" {
+			name = project.Name
+		}
+		script := &models.Script{
+			Name:        name,
+			Description: "Imported from Selenium IDE project " + project.Name,
+			URL:         project.URL,
+			Actions:     []models.ScriptAction{},
+		}
+		unsupported := func(index int, command sideCommand, reason string) {
+			result.Unsupported = append(result.Unsupported, ImportIssue{Script: name, Step: index + 1, Type: command.Command, Reason: reason})
+		}
+		navigated := false
+
+		for i, command := range test.Commands {
+			// 以 // 开头的命令在 Selenium IDE 中已被禁用
+			if command.Command == "" || strings.HasPrefix(command.Command, "//") {
+				continue
+			}
+
+			// 需要元素定位的命令
+			target := func() (models.ScriptAction, bool) {
+				action, ok := sideTarget(command)
+				if !ok {
+					unsupported(i, command, fmt.Sprintf("unsupported locator %q", command.Target))
+				}
+				action.Remark = command.Comment
+				return action, ok
+			}
+			add := func(action models.ScriptAction) {
+				script.Actions = append(script.Actions, action)
+			}
+
+			switch command.Command {
+			case "open":
+				pageURL := resolveSideURL(project.URL, command.Target)
+				if !navigated {
+					script.URL = pageURL
+					navigated = true
+				}
+				add(models.ScriptAction{Type: "navigate", URL: pageURL, Remark: command.Comment})
+
+			case "click", "clickAt":
+				if action, ok := target(); ok {
+					action.Type = "click"
+					add(action)
+				}
+
+			case "type":
+				if action, ok := target(); ok {
+					action.Type = "input"
+					action.Value = command.Value
+					add(action)
+				}
+
+			case "sendKeys":
+				rest := strings.TrimSpace(sideKeyPattern.ReplaceAllString(command.Value, ""))
+				keys := sideKeyPattern.FindAllStringSubmatch(command.Value, -1)
+				if rest != "" || len(keys) == 0 {
+					unsupported(i, command, "only ${KEY_ENTER}, ${KEY_TAB} and ${KEY_BACKSPACE} can be sent; use type for text")
+					continue
+				}
+				action, ok := target()
+				if !ok {
+					continue
+				}
+				for _, key := range keys {
+					if sideKeys[key[1]] == "" {
+						unsupported(i, command, fmt.Sprintf("key %s is not supported by keyboard actions", key[1]))
+						continue
+					}
+					action.Type = "keyboard"
+					action.Key = sideKeys[key[1]]
+					add(action)
+				}
+
+			case "select":
+				option := command.Value
+				switch {
+				case strings.HasPrefix(option, "label="):
+					option = strings.TrimPrefix(option, "label=")
+				case strings.HasPrefix(option, "index=") || strings.HasPrefix(option, "value=") || strings.HasPrefix(option, "id="):
+					unsupported(i, command, "options can only be selected by label")
+					continue
+				}
+				if action, ok := target(); ok {
+					action.Type = "select"
+					action.Value = option
+					add(action)
+				}
+
+			case "pause":
+				value := command.Target
+				if value == "" {
+					value = command.Value
+				}
+				duration, err := strconv.Atoi(strings.TrimSpace(value))
+				if err != nil {
+					unsupported(i, command, fmt.Sprintf("invalid pause duration %q", value))
+					continue
+				}
+				add(models.ScriptAction{Type: "sleep", Duration: duration, Remark: command.Comment})
+
+			case "runScript", "executeScript":
+				action := models.ScriptAction{Type: "execute_js", JSCode: command.Target, Remark: command.Comment}
+				if command.Command == "executeScript" {
+					action.VariableName = command.Value
+				}
+				add(action)
+
+			case "storeText":
+				if action, ok := target(); ok {
+					action.Type = "extract_text"
+					action.VariableName = command.Value
+					add(action)
+				}
+
+			case "storeAttribute":
+				// 目标格式为 locator@attribute
+				at := strings.LastIndex(command.Target, "@")
+				if at <= 0 {
+					unsupported(i, command, "target must be locator@attribute")
+					continue
+				}
+				attribute := command.Target[at+1:]
+				command.Target = command.Target[:at]
+				command.Targets = nil
+				if action, ok := target(); ok {
+					action.Type = "extract_attribute"
+					action.AttributeName = attribute
+					action.VariableName = command.Value
+					add(action)
+				}
+
+			case "store":
+				if script.Variables == nil {
+					script.Variables = map[string]string{}
+				}
+				script.Variables[command.Value] = command.Target
+
+			case "waitForElementPresent", "waitForElementVisible", "waitForElementNotPresent", "waitForElementNotVisible":
+				kind := models.AssertVisible
+				if strings.Contains(command.Command, "Not") {
+					kind = models.AssertHidden
+				}
+				waitMs, err := strconv.Atoi(strings.TrimSpace(command.Value))
+				if err != nil || waitMs <= 0 {
+					waitMs = defaultImportWaitMs
+				}
+				if action, ok := target(); ok {
+					add(withAssert(action, models.AssertOptions{Kind: kind, WaitMs: waitMs}))
+				}
+
+			case "assertText", "verifyText":
+				if action, ok := target(); ok {
+					add(withAssert(action, sideAssert(command, models.AssertOptions{Kind: models.AssertTextEquals, Expected: command.Value})))
+				}
+
+			case "assertElementPresent", "verifyElementPresent":
+				if action, ok := target(); ok {
+					add(withAssert(action, sideAssert(command, models.AssertOptions{Kind: models.AssertCount, Operator: ">=", Expected: "1"})))
+				}
+
+			case "assertElementNotPresent", "verifyElementNotPresent":
+				if action, ok := target(); ok {
+					add(withAssert(action, sideAssert(command, models.AssertOptions{Kind: models.AssertCount, Operator: "=", Expected: "0"})))
+				}
+
+			case "assertTitle", "verifyTitle":
+				add(assertAction(sideAssert(command, models.AssertOptions{
+					Kind:     models.AssertTitleMatches,
+					Expected: "^" + regexp.QuoteMeta(command.Target) + "$",
+				})))
+
+			case "assert", "verify":
+				add(assertAction(sideAssert(command, models.AssertOptions{
+					Kind:     models.AssertVariable,
+					Variable: command.Target,
+					Expected: command.Value,
+				})))
+
+			case "setWindowSize":
+				unsupported(i, command, fmt.Sprintf("window size %s should be set through the emulation profile of the browser instance or site config", command.Target))
+
+			default:
+				unsupported(i, command, "command is not supported")
+			}
+		}
+		result.Scripts = append(result.Scripts, script)
+	}
+	return result, nil
+}
+
+// sideAssert verify* 命令失败后继续执行，对应软断言
+func sideAssert(command sideCommand, options models.AssertOptions) models.AssertOptions {
+	if strings.HasPrefix(command.Command, "verify") {
+		options.Mode = models.AssertModeSoft
+	}
+	if options.Message == "" {
+		options.Message = command.Comment
+	}
+	return options
+}
+
+// sideTarget 从 target 和备选 targets 中取第一个 CSS 和第一个 XPath 定位器
+func sideTarget(command sideCommand) (models.ScriptAction, bool) {
+	var action models.ScriptAction
+	locators := []string{command.Target}
+	for _, candidate := range command.Targets {
+		if len(candidate) > 0 {
+			locators = append(locators, candidate[0])
+		}
+	}
+	for _, locator := range locators {
+		css, xpath := sideLocator(locator)
+		if action.Selector == "" {
+			action.Selector = css
+		}
+		if action.XPath == "" {
+			action.XPath = xpath
+		}
+	}
+	return action, action.Selector != "" || action.XPath != ""
+}
+
+// cssIdentifierPattern 可以直接写成 #id 的 ID
+var cssIdentifierPattern = regexp.MustCompile(`^[A-Za-z_][\w-]*$`)
+
+// sideLocator 将 Selenium 定位器（id=、name=、css=、xpath=、linkText=、partialLinkText=）转换为 CSS 或 XPath
+func sideLocator(locator string) (css, xpath string) {
+	if strings.HasPrefix(locator, "/") || strings.HasPrefix(locator, "(") {
+		return "", locator
+	}
+	strategy, value, found := strings.Cut(locator, "=")
+	if !found {
+		strategy, value = "id", locator
+	}
+	switch strategy {
+	case "css":
+		return value, ""
+	case "xpath":
+		return "", value
+	case "id":
+		if cssIdentifierPattern.MatchString(value) {
+			return "#" + value, ""
+		}
+		return "[id=" + strconv.Quote(value) + "]", ""
+	case "name":
+		return "[name=" + strconv.Quote(value) + "]", ""
+	case "linkText":
+		return "", "//a[normalize-space(.)=" + xpathLiteral(value) + "]"
+	case "partialLinkText":
+		return "", "//a[contains(normalize-space(.), " + xpathLiteral(value) + ")]"
+	}
+	return "", ""
+}
+
+// resolveSideURL open 命令的目标相对于工程的基础 URL
+func resolveSideURL(base, target string) string {
+	baseURL, err := url.Parse(base)
+	if err != nil || base == "" {
+		return target
+	}
+	ref, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+	return baseURL.ResolveReference(ref).String()
+}
+
+// ============= 公共方法 =============
+
+func assertAction(options models.AssertOptions) models.ScriptAction {
+	return withAssert(models.ScriptAction{}, options)
+}
+
+func withAssert(action models.ScriptAction, options models.AssertOptions) models.ScriptAction {
+	action.Type = "assert"
+	action.Assert = &options
+	return action
+}
+
+// sortedKeys 按字母顺序返回 map 的键，保证转换结果稳定
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
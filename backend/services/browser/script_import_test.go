@@ -0,0 +1,202 @@
+package browser
+
+import (
+	"testing"
+
+	"github.com/browserwing/browserwing/models"
+)
+
+func TestDetectImportFormat(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{data: `{"title": "t", "steps": []}`, want: ImportFormatChromeRecorder},
+		{data: `{"steps": [{"type": "navigate"}]}`, want: ImportFormatChromeRecorder},
+		{data: `{"version": "2.0", "tests": [{"name": "t"}]}`, want: ImportFormatSeleniumIDE},
+		{data: `{"name": "x"}`, wantErr: true},
+		{data: `not json`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := DetectImportFormat([]byte(tt.data))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("DetectImportFormat(%s) error = %v, wantErr %v", tt.data, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("DetectImportFormat(%s) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestImportChromeRecorder(t *testing.T) {
+	flow := `{
+		"title": "Login",
+		"steps": [
+			{"type": "setViewport", "width": 1280, "height": 720, "deviceScaleFactor": 2, "isMobile": false, "hasTouch": true, "isLandscape": false},
+			{"type": "navigate", "url": "https://example.com/login", "assertedEvents": [{"type": "navigation", "title": "Sign in"}]},
+			{"type": "click", "selectors": [["aria/Email[role=\"textbox\"]"], ["#email"], ["xpath///*[@id=\"email\"]"]]},
+			{"type": "change", "value": "alice@example.com", "selectors": [["my-app", "input.email"]]},
+			{"type": "keyDown", "key": "Enter"},
+			{"type": "keyUp", "key": "Enter"},
+			{"type": "keyDown", "key": "Control"},
+			{"type": "keyDown", "key": "a"},
+			{"type": "keyUp", "key": "a"},
+			{"type": "keyUp", "key": "Control"},
+			{"type": "scroll", "x": 0, "y": 600.6},
+			{"type": "waitForElement", "selectors": [["text/Welcome"]], "count": 1, "operator": ">="},
+			{"type": "hover", "selectors": [["#menu"]]},
+			{"type": "setViewport", "width": 375, "height": 667}
+		]
+	}`
+
+	result, err := ImportScripts([]byte(flow), "")
+	if err != nil {
+		t.Fatalf("ImportScripts: %v", err)
+	}
+	if result.Format != ImportFormatChromeRecorder || len(result.Scripts) != 1 {
+		t.Fatalf("unexpected result: format=%s scripts=%d", result.Format, len(result.Scripts))
+	}
+	script := result.Scripts[0]
+	if script.Name != "Login" || script.URL != "https://example.com/login" {
+		t.Errorf("script name/url = %q/%q", script.Name, script.URL)
+	}
+
+	want := []string{"navigate", "assert", "click", "input", "keyboard", "keyboard", "scroll", "assert"}
+	if len(script.Actions) != len(want) {
+		t.Fatalf("got %d actions, want %d: %+v", len(script.Actions), len(want), script.Actions)
+	}
+	for i, action := range script.Actions {
+		if action.Type != want[i] {
+			t.Errorf("action %d type = %s, want %s", i, action.Type, want[i])
+		}
+	}
+
+	click := script.Actions[2]
+	if click.Selector != "#email" || click.XPath != `//*[@id="email"]` {
+		t.Errorf("click selector/xpath = %q/%q", click.Selector, click.XPath)
+	}
+	if click.Accessibility == nil || click.Accessibility.Name != "Email" || click.Accessibility.Role != "textbox" {
+		t.Errorf("click accessibility = %+v", click.Accessibility)
+	}
+	if got := script.Actions[3].Selector; got != "my-app >>> input.email" {
+		t.Errorf("shadow selector = %q", got)
+	}
+	if script.Actions[4].Key != "enter" || script.Actions[5].Key != "ctrl+a" {
+		t.Errorf("keys = %q, %q", script.Actions[4].Key, script.Actions[5].Key)
+	}
+	if scroll := script.Actions[6]; scroll.ScrollX != 0 || scroll.ScrollY != 601 {
+		t.Errorf("scroll = %d, %d", scroll.ScrollX, scroll.ScrollY)
+	}
+	viewport := script.Emulation
+	if viewport == nil || viewport.Width != 1280 || viewport.Height != 720 || viewport.DeviceScaleFactor != 2 ||
+		viewport.Mobile == nil || *viewport.Mobile || viewport.Touch == nil || !*viewport.Touch {
+		t.Errorf("emulation = %+v", viewport)
+	}
+	wait := script.Actions[7]
+	if wait.Assert == nil || wait.Assert.Kind != models.AssertCount || wait.Assert.Operator != ">=" || wait.Assert.Expected != "1" {
+		t.Errorf("wait assert = %+v", wait.Assert)
+	}
+	if wait.XPath != `//*[normalize-space(text())="Welcome"]` {
+		t.Errorf("text selector xpath = %q", wait.XPath)
+	}
+
+	// 回放中途改变视口不支持
+	if len(result.Unsupported) != 2 || result.Unsupported[0].Step != 13 || result.Unsupported[1].Type != "setViewport" {
+		t.Errorf("unsupported = %+v", result.Unsupported)
+	}
+}
+
+func TestImportSeleniumIDE(t *testing.T) {
+	project := `{
+		"name": "Shop",
+		"url": "https://shop.example.com",
+		"tests": [{
+			"name": "Checkout",
+			"commands": [
+				{"command": "open", "target": "/cart", "value": ""},
+				{"command": "click", "target": "id=checkout", "targets": [["css=#checkout", "css:finder"], ["xpath=//button[@id='checkout']", "xpath:attributes"]], "value": ""},
+				{"command": "type", "target": "name=q", "value": "${keyword}"},
+				{"command": "sendKeys", "target": "name=q", "value": "${KEY_ENTER}"},
+				{"command": "select", "target": "css=select#size", "value": "label=Large"},
+				{"command": "pause", "target": "500", "value": ""},
+				{"command": "//click", "target": "id=disabled", "value": ""},
+				{"command": "verifyText", "target": "linkText=Orders", "value": "Orders"},
+				{"command": "assertTitle", "target": "Cart (1)", "value": ""},
+				{"command": "storeAttribute", "target": "css=a.order@href", "value": "orderURL"},
+				{"command": "mouseOver", "target": "id=menu", "value": ""}
+			]
+		}]
+	}`
+
+	result, err := ImportScripts([]byte(project), ImportFormatSeleniumIDE)
+	if err != nil {
+		t.Fatalf("ImportScripts: %v", err)
+	}
+	if len(result.Scripts) != 1 {
+		t.Fatalf("got %d scripts", len(result.Scripts))
+	}
+	script := result.Scripts[0]
+	if script.Name != "Checkout" || script.URL != "https://shop.example.com/cart" {
+		t.Errorf("script name/url = %q/%q", script.Name, script.URL)
+	}
+
+	want := []string{"navigate", "click", "input", "keyboard", "select", "sleep", "assert", "assert", "extract_attribute"}
+	if len(script.Actions) != len(want) {
+		t.Fatalf("got %d actions, want %d: %+v", len(script.Actions), len(want), script.Actions)
+	}
+	for i, action := range script.Actions {
+		if action.Type != want[i] {
+			t.Errorf("action %d type = %s, want %s", i, action.Type, want[i])
+		}
+	}
+
+	click := script.Actions[1]
+	if click.Selector != "#checkout" || click.XPath != "//button[@id='checkout']" {
+		t.Errorf("click selector/xpath = %q/%q", click.Selector, click.XPath)
+	}
+	if got := script.Actions[2]; got.Selector != `[name="q"]` || got.Value != "${keyword}" {
+		t.Errorf("type action = %+v", got)
+	}
+	if got := script.Actions[4].Value; got != "Large" {
+		t.Errorf("select value = %q", got)
+	}
+	verify := script.Actions[6]
+	if verify.Assert.Kind != models.AssertTextEquals || !verify.Assert.IsSoft() || verify.XPath != `//a[normalize-space(.)="Orders"]` {
+		t.Errorf("verifyText = %+v %+v", verify, verify.Assert)
+	}
+	if title := script.Actions[7].Assert; title.Expected != `^Cart \(1\)$` || title.IsSoft() {
+		t.Errorf("assertTitle = %+v", title)
+	}
+	if attr := script.Actions[8]; attr.Selector != "a.order" || attr.AttributeName != "href" || attr.VariableName != "orderURL" {
+		t.Errorf("storeAttribute = %+v", attr)
+	}
+
+	if len(result.Unsupported) != 1 || result.Unsupported[0].Type != "mouseOver" || result.Unsupported[0].Script != "Checkout" {
+		t.Errorf("unsupported = %+v", result.Unsupported)
+	}
+}
+
+func TestSideLocator(t *testing.T) {
+	tests := []struct {
+		locator string
+		css     string
+		xpath   string
+	}{
+		{locator: "id=login", css: "#login"},
+		{locator: "id=user.name", css: `[id="user.name"]`},
+		{locator: "name=q", css: `[name="q"]`},
+		{locator: "css=div > a", css: "div > a"},
+		{locator: "xpath=//a[1]", xpath: "//a[1]"},
+		{locator: "//div[@id='x']", xpath: "//div[@id='x']"},
+		{locator: "partialLinkText=More", xpath: `//a[contains(normalize-space(.), "More")]`},
+		{locator: "dom=document.forms[0]"},
+	}
+	for _, tt := range tests {
+		css, xpath := sideLocator(tt.locator)
+		if css != tt.css || xpath != tt.xpath {
+			t.Errorf("sideLocator(%q) = %q, %q; want %q, %q", tt.locator, css, xpath, tt.css, tt.xpath)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+() => {
+	// 提取元素的语义特征，逻辑与 recorder.js 中的 enrichActionWithSemantics 保持一致
+	const el = this;
+
+	const implicitRole = (node) => {
+		const tag = node.tagName.toLowerCase();
+		const type = (node.type || '').toLowerCase();
+		if (tag === 'button') return 'button';
+		if (tag === 'a') return 'link';
+		if (tag === 'input') {
+			if (type === 'search') return 'searchbox';
+			if (type === 'checkbox') return 'checkbox';
+			if (type === 'radio') return 'radio';
+			if (type === 'submit' || type === 'button' || type === 'file') return 'button';
+			return 'textbox';
+		}
+		if (tag === 'textarea') return 'textbox';
+		if (tag === 'select') return 'combobox';
+		if (tag === 'img') return 'img';
+		if (/^h[1-6]$/.test(tag)) return 'heading';
+		if (tag === 'li') return 'listitem';
+		return 'generic';
+	};
+
+	const accessibleName = (node) => {
+		const ariaLabel = node.getAttribute('aria-label');
+		if (ariaLabel) return ariaLabel.trim();
+		const labelledby = node.getAttribute('aria-labelledby');
+		if (labelledby) {
+			const labelElement = document.getElementById(labelledby);
+			if (labelElement) return labelElement.innerText.trim();
+		}
+		if (node.id) {
+			const label = document.querySelector('label[for="' + CSS.escape(node.id) + '"]');
+			if (label) return label.innerText.trim();
+		}
+		if (node.innerText) {
+			let text = node.innerText.trim();
+			if (text.length > 50) text = text.substring(0, 50) + '...';
+			if (text) return text;
+		}
+		if (node.placeholder) return node.placeholder.trim();
+		if (node.title) return node.title.trim();
+		if (node.alt) return node.alt.trim();
+		if (node.value && (node.tagName === 'BUTTON' || node.tagName === 'INPUT')) return String(node.value).trim();
+		return '';
+	};
+
+	const nearbyText = (node, maxDistance) => {
+		const rect = node.getBoundingClientRect();
+		const cx = rect.left + rect.width / 2;
+		const cy = rect.top + rect.height / 2;
+		const texts = [];
+		const walker = document.createTreeWalker(document.body, NodeFilter.SHOW_TEXT);
+		let textNode;
+		while ((textNode = walker.nextNode()) && texts.length < 5) {
+			const parent = textNode.parentElement;
+			if (!parent || node.contains(parent)) continue;
+			const tag = parent.tagName.toLowerCase();
+			if (tag === 'script' || tag === 'style' || tag === 'noscript') continue;
+			let text = textNode.textContent.trim();
+			if (!text) continue;
+			const range = document.createRange();
+			range.selectNodeContents(textNode);
+			const r = range.getBoundingClientRect();
+			const distance = Math.hypot(r.left + r.width / 2 - cx, r.top + r.height / 2 - cy);
+			if (distance > maxDistance) continue;
+			if (text.length > 30) text = text.substring(0, 30) + '...';
+			if (texts.indexOf(text) === -1) texts.push(text);
+		}
+		return texts;
+	};
+
+	const ancestorTags = (node) => {
+		const tags = [];
+		let current = node.parentElement;
+		while (current && tags.length < 10) {
+			const tag = current.tagName.toLowerCase();
+			tags.push(tag);
+			if (tag === 'body') break;
+			current = current.parentElement;
+		}
+		return tags;
+	};
+
+	const formHint = (node) => {
+		const form = node.closest('form');
+		if (!form) return '';
+		const combined = ((form.id || '') + ' ' + (form.getAttribute('name') || '') + ' ' + (form.className || '')).toLowerCase();
+		if (combined.indexOf('login') !== -1 || combined.indexOf('signin') !== -1) return 'login';
+		if (combined.indexOf('register') !== -1 || combined.indexOf('signup') !== -1) return 'register';
+		if (combined.indexOf('search') !== -1) return 'search';
+		if (combined.indexOf('checkout') !== -1 || combined.indexOf('payment') !== -1) return 'checkout';
+		if (combined.indexOf('contact') !== -1) return 'contact';
+		const inputs = Array.from(form.querySelectorAll('input'));
+		const hasPassword = inputs.some((i) => (i.type || '').toLowerCase() === 'password');
+		const hasEmail = inputs.some((i) => (i.type || '').toLowerCase() === 'email');
+		if (hasPassword && hasEmail) return 'login';
+		if (hasPassword) return 'auth';
+		return 'generic';
+	};
+
+	const xpathOf = (node) => {
+		if (node.id && document.querySelectorAll('#' + CSS.escape(node.id)).length === 1) {
+			return '//*[@id="' + node.id + '"]';
+		}
+		const parts = [];
+		let current = node;
+		while (current && current.nodeType === Node.ELEMENT_NODE && current !== document.documentElement) {
+			const tag = current.tagName.toLowerCase();
+			let index = 1;
+			let sibling = current.previousElementSibling;
+			while (sibling) {
+				if (sibling.tagName === current.tagName) index++;
+				sibling = sibling.previousElementSibling;
+			}
+			parts.unshift(tag + '[' + index + ']');
+			current = current.parentElement;
+		}
+		return '/html/' + parts.join('/');
+	};
+
+	const rect = el.getBoundingClientRect();
+	const style = window.getComputedStyle(el);
+
+	return {
+		tag: el.tagName.toLowerCase(),
+		role: el.getAttribute('role') || implicitRole(el),
+		name: accessibleName(el),
+		nearby_text: nearbyText(el, 100),
+		ancestor_tags: ancestorTags(el),
+		form_hint: formHint(el),
+		visible: rect.width > 0 && rect.height > 0 && style.visibility !== 'hidden' && style.display !== 'none',
+		xpath: xpathOf(el),
+	};
+}
@@ -3258,12 +3258,39 @@ if (window.__browserwingRecorder__) {
 		console.log('[BrowserWing] Recorded extraction:', extractType, variableName);
 	};
 	
+	// 获取事件的真实目标：开放 shadowRoot 内的元素在 document 上会被重定向为宿主元素
+	var getEventTarget = function(e) {
+		if (e.composedPath) {
+			var path = e.composedPath();
+			if (path.length > 0 && path[0] && path[0].nodeType === 1) {
+				return path[0];
+			}
+		}
+		return e.target || e.srcElement;
+	};
+	
 	// 生成更精确和可靠的选择器（支持 CSS 和 XPath）
+	// shadowRoot 内的元素生成 "宿主选择器 >>> 内部选择器" 形式的穿透定位器，内部 XPath 为相对路径
 	var getSelector = function(element) {
 		if (!element || !element.tagName) {
 			return { css: 'unknown', xpath: '//*' };
 		}
 		
+		var root = element.getRootNode ? element.getRootNode() : document;
+		if (typeof ShadowRoot !== 'undefined' && root instanceof ShadowRoot && root.host) {
+			var host = getSelector(root.host);
+			var inner = getLocalSelector(element, root);
+			var innerXPath = inner.xpath.charAt(0) === '/' ? '.' + inner.xpath : inner.xpath;
+			return {
+				css: (host.css && host.css !== 'unknown' && inner.css) ? host.css + ' >>> ' + inner.css : '',
+				xpath: host.xpath + ' >>> ' + innerXPath
+			};
+		}
+		return getLocalSelector(element, document);
+	};
+	
+	// 在所在的文档或 shadowRoot 内生成选择器
+	var getLocalSelector = function(element, root) {
 		try {
 			var css = '';
 			var xpath = '';
@@ -3357,7 +3384,7 @@ if (window.__browserwingRecorder__) {
 					
 					var hasDuplicates = false;
 					try {
-						var result = document.evaluate(textXPath, document, null, XPathResult.ORDERED_NODE_SNAPSHOT_TYPE, null);
+						var result = document.evaluate(root === document ? textXPath : '.' + textXPath, root, null, XPathResult.ORDERED_NODE_SNAPSHOT_TYPE, null);
 						if (result.snapshotLength > 1) {
 							hasDuplicates = true;
 							console.log('[BrowserWing] Found ' + result.snapshotLength + ' elements with text "' + textContent.substring(0, 20) + '", using full XPath');
@@ -4007,7 +4034,7 @@ if (window.__browserwingRecorder__) {
 		if (!window.__isRecordingActive__) return;
 		
 		try {
-			var target = e.target || e.srcElement;
+			var target = getEventTarget(e);
 			if (!target || !target.tagName) return;
 			
 		// 忽略录制器 UI 自身
@@ -4049,7 +4076,7 @@ if (window.__browserwingRecorder__) {
 		if (!window.__isRecordingActive__) return;
 		
 		try {
-			var target = e.target || e.srcElement;
+			var target = getEventTarget(e);
 			if (!target || !target.tagName) return;
 			
 		// 忽略录制器 UI 自身的点击
@@ -4297,7 +4324,7 @@ if (window.__browserwingRecorder__) {
 		}
 		
 		try {
-			var target = e.target || e.srcElement;
+			var target = getEventTarget(e);
 			if (!target) return;
 			
 			// 忽略AI控制面板的输入框
@@ -4366,7 +4393,7 @@ if (window.__browserwingRecorder__) {
 		if (!window.__isRecordingActive__) return;
 		
 		try {
-			var target = e.target || e.srcElement;
+			var target = getEventTarget(e);
 			if (!target) return;
 			
 			// 忽略AI控制面板的输入框
@@ -4481,7 +4508,7 @@ if (window.__browserwingRecorder__) {
 		}
 		
 		try {
-			var target = e.target || e.srcElement;
+			var target = getEventTarget(e);
 			if (!target) return;
 			
 			var tagName = target.tagName ? target.tagName.toUpperCase() : '';
@@ -4560,7 +4587,7 @@ if (window.__browserwingRecorder__) {
 	document.addEventListener('contextmenu', function(e) {
 		if (!window.__extractMode__) return;
 		
-		var target = e.target || e.srcElement;
+		var target = getEventTarget(e);
 		if (!target || !target.tagName) return;
 		
 		// 忽略录制器 UI 自身
@@ -4592,7 +4619,7 @@ if (window.__browserwingRecorder__) {
 		if (!window.__isRecordingActive__) return;
 		
 		try {
-			var target = e.target || e.srcElement;
+			var target = getEventTarget(e);
 			if (!target) return;
 			
 		// 忽略录制器 UI 自身的键盘事件
@@ -0,0 +1,498 @@
+package browser
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/browserwing/browserwing/models"
+	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+//go:embed scripts/element_features.js
+var elementFeaturesScript string
+
+const (
+	healMinConfidence   = 0.6  // 自愈结果的最低置信度
+	healAmbiguityMargin = 0.05 // 最优与次优候选的最小分差，低于该值视为无法区分
+	healCertainScore    = 0.9  // 达到该分数时不再检查歧义
+	healMaxCandidates   = 15   // 参与特征评分的最大候选数
+)
+
+// elementFeatures 候选元素的语义特征（由 element_features.js 提取）
+type elementFeatures struct {
+	Tag          string   `json:"tag"`
+	Role         string   `json:"role"`
+	Name         string   `json:"name"`
+	NearbyText   []string `json:"nearby_text"`
+	AncestorTags []string `json:"ancestor_tags"`
+	FormHint     string   `json:"form_hint"`
+	Visible      bool     `json:"visible"`
+	XPath        string   `json:"xpath"`
+}
+
+// HealedLocator 语义自愈找到的新定位信息
+type HealedLocator struct {
+	StepIndex  int     `json:"step_index"`          // 顶层步骤索引（从 0 开始）
+	StepPath   []int   `json:"step_path,omitempty"` // 循环体或子脚本中的步骤相对顶层步骤的索引路径，顶层步骤为空
+	ScriptID   string  `json:"script_id,omitempty"` // 步骤所属的脚本（call_script 调用的子脚本时为子脚本 ID）
+	ActionType string  `json:"action_type"`
+	OldXPath   string  `json:"old_xpath,omitempty"`
+	OldCSS     string  `json:"old_css,omitempty"`
+	XPath      string  `json:"xpath"`      // 自愈后元素的 XPath
+	Confidence float64 `json:"confidence"` // 匹配置信度（0-1）
+}
+
+// healCandidate 自愈候选元素
+type healCandidate struct {
+	backendID proto.DOMBackendNodeID
+	element   *rod.Element
+	features  elementFeatures
+	score     float64
+}
+
+// hasSemanticInfo 判断步骤是否录制了可用于自愈的语义信息
+func hasSemanticInfo(action models.ScriptAction) bool {
+	if action.Accessibility != nil && (action.Accessibility.Name != "" || (action.Accessibility.Role != "" && action.Accessibility.Role != "generic")) {
+		return true
+	}
+	return action.Intent != nil && action.Intent.Object != ""
+}
+
+// semanticTarget 返回录制时的角色和可访问名称
+func semanticTarget(action models.ScriptAction) (role, name string) {
+	if action.Accessibility != nil {
+		role = action.Accessibility.Role
+		name = action.Accessibility.Name
+	}
+	if name == "" && action.Intent != nil {
+		name = action.Intent.Object
+	}
+	return normalizeRole(role), name
+}
+
+// healElement 在 XPath 和 CSS 都失效时，通过 Accessibility Tree 和录制的上下文重新定位元素
+func (p *Player) healElement(ctx context.Context, page *rod.Page, action models.ScriptAction) (*rod.Element, *healCandidate, error) {
+	role, name := semanticTarget(action)
+	logger.Info(ctx, "[SelfHeal] Trying semantic fallback: role=%q name=%q", role, name)
+
+	candidates, err := collectHealCandidates(page, action, role, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, nil, fmt.Errorf("no semantic candidates found for role=%q name=%q", role, name)
+	}
+
+	scored := make([]*healCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		element := candidate.element
+		if element == nil {
+			element, err = page.ElementFromNode(&proto.DOMNode{BackendNodeID: candidate.backendID})
+			if err != nil {
+				continue
+			}
+		}
+		res, err := element.Eval(elementFeaturesScript)
+		if err != nil {
+			continue
+		}
+		if err := res.Value.Unmarshal(&candidate.features); err != nil {
+			continue
+		}
+		candidate.element = element
+		candidate.score = scoreHealCandidate(action, candidate.features)
+		scored = append(scored, candidate)
+	}
+	if len(scored) == 0 {
+		return nil, nil, fmt.Errorf("failed to evaluate semantic candidates")
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].score > scored[j].score })
+	for i, c := range scored {
+		if i >= 3 {
+			break
+		}
+		logger.Info(ctx, "[SelfHeal] Candidate #%d: score=%.2f tag=%s role=%s name=%q xpath=%s",
+			i+1, c.score, c.features.Tag, c.features.Role, c.features.Name, c.features.XPath)
+	}
+
+	best := scored[0]
+	if best.score < healMinConfidence {
+		return nil, nil, fmt.Errorf("best semantic candidate confidence %.2f is below %.2f", best.score, healMinConfidence)
+	}
+	if len(scored) > 1 && best.score < healCertainScore && best.score-scored[1].score < healAmbiguityMargin {
+		return nil, nil, fmt.Errorf("semantic candidates are ambiguous (%.2f vs %.2f)", best.score, scored[1].score)
+	}
+
+	logger.Info(ctx, "[SelfHeal] ✓ Healed element with confidence %.2f: %s", best.score, best.features.XPath)
+	return best.element, best, nil
+}
+
+// collectHealCandidates 从 Accessibility Tree（以及按标签和文本的 DOM 查询）收集候选元素
+func collectHealCandidates(page *rod.Page, action models.ScriptAction, role, name string) ([]*healCandidate, error) {
+	_ = proto.AccessibilityEnable{}.Call(page)
+	defer func() { _ = proto.AccessibilityDisable{}.Call(page) }()
+
+	tree, err := proto.AccessibilityGetFullAXTree{}.Call(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accessibility tree: %w", err)
+	}
+
+	type preScored struct {
+		id    proto.DOMBackendNodeID
+		score float64
+	}
+	pre := make([]preScored, 0)
+	seen := make(map[proto.DOMBackendNodeID]bool)
+	for _, node := range tree.Nodes {
+		if node.Ignored || node.BackendDOMNodeID == 0 || seen[node.BackendDOMNodeID] {
+			continue
+		}
+		roleScore := roleSimilarity(role, normalizeRole(axValueString(node.Role)))
+		nameScore := nameSimilarity(name, axValueString(node.Name))
+		if roleScore < 1 && nameScore < 0.5 {
+			continue
+		}
+		seen[node.BackendDOMNodeID] = true
+		pre = append(pre, preScored{id: node.BackendDOMNodeID, score: roleScore + 2*nameScore})
+	}
+	sort.SliceStable(pre, func(i, j int) bool { return pre[i].score > pre[j].score })
+
+	candidates := make([]*healCandidate, 0, healMaxCandidates)
+	for _, c := range pre {
+		if len(candidates) >= healMaxCandidates {
+			break
+		}
+		candidates = append(candidates, &healCandidate{backendID: c.id})
+	}
+
+	// 通用元素（如可点击的 div）在 AX 树中通常没有名称，按标签和文本补充候选
+	tag := strings.ToLower(action.TagName)
+	text := strings.TrimSuffix(strings.TrimSpace(name), "...")
+	if tag != "" && text != "" && isSimpleTagName(tag) {
+		xpath := fmt.Sprintf("//%s[contains(normalize-space(.), %s)]", tag, xpathLiteral(text))
+		if elements, err := page.ElementsX(xpath); err == nil {
+			for i, element := range elements {
+				if i >= healMaxCandidates {
+					break
+				}
+				node, err := element.Describe(0, false)
+				if err != nil || seen[node.BackendNodeID] {
+					continue
+				}
+				seen[node.BackendNodeID] = true
+				candidates = append(candidates, &healCandidate{backendID: node.BackendNodeID, element: element})
+			}
+		}
+	}
+
+	return candidates, nil
+}
+
+// scoreHealCandidate 根据录制的语义信息为候选元素打分（0-1）
+// 只对录制时存在的信息计分，并按实际参与的权重归一化
+func scoreHealCandidate(action models.ScriptAction, f elementFeatures) float64 {
+	role, name := semanticTarget(action)
+
+	var total, weight float64
+	add := func(w, s float64) {
+		total += w * s
+		weight += w
+	}
+
+	if name != "" {
+		add(0.4, nameSimilarity(name, f.Name))
+	}
+	if role != "" && role != "generic" {
+		add(0.25, roleSimilarity(role, normalizeRole(f.Role)))
+	}
+	if action.TagName != "" {
+		s := 0.0
+		if strings.EqualFold(action.TagName, f.Tag) {
+			s = 1
+		}
+		add(0.1, s)
+	}
+	if action.Context != nil {
+		if len(action.Context.NearbyText) > 0 {
+			add(0.1, overlapRatio(action.Context.NearbyText, f.NearbyText))
+		}
+		if len(action.Context.AncestorTags) > 0 {
+			add(0.1, ancestorSimilarity(action.Context.AncestorTags, f.AncestorTags))
+		}
+		if action.Context.FormHint != "" {
+			s := 0.0
+			if action.Context.FormHint == f.FormHint {
+				s = 1
+			}
+			add(0.05, s)
+		}
+	}
+
+	if weight == 0 {
+		return 0
+	}
+	score := total / weight
+	if !f.Visible {
+		score *= 0.5
+	}
+	return score
+}
+
+// roleGroups 改版中经常互换的角色
+var roleGroups = [][]string{
+	{"textbox", "searchbox", "combobox"},
+	{"button", "link", "menuitem", "tab"},
+	{"checkbox", "switch", "radio"},
+}
+
+// normalizeRole 统一不同来源的角色名称（录制脚本与 Chrome AX 树）
+func normalizeRole(role string) string {
+	role = strings.ToLower(strings.TrimSpace(role))
+	switch role {
+	case "img":
+		return "image"
+	case "genericcontainer", "none", "presentation":
+		return "generic"
+	}
+	return role
+}
+
+// roleSimilarity 角色相同为 1，同组角色为 0.5
+func roleSimilarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	for _, group := range roleGroups {
+		inA, inB := false, false
+		for _, r := range group {
+			inA = inA || r == a
+			inB = inB || r == b
+		}
+		if inA && inB {
+			return 0.5
+		}
+	}
+	return 0
+}
+
+// normalizeText 统一大小写和空白，并去掉录制时的截断标记
+func normalizeText(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(s, "...")
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// nameSimilarity 可访问名称的相似度（0-1）
+func nameSimilarity(a, b string) float64 {
+	a, b = normalizeText(a), normalizeText(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	// 录制时名称超过 50 个字符会被截断
+	if strings.HasPrefix(a, b) || strings.HasPrefix(b, a) {
+		return 0.9
+	}
+	if strings.Contains(a, b) || strings.Contains(b, a) {
+		return 0.8
+	}
+
+	tokensA := strings.Fields(a)
+	tokensB := make(map[string]bool)
+	for _, t := range strings.Fields(b) {
+		tokensB[t] = true
+	}
+	common := 0
+	union := len(tokensB)
+	for _, t := range tokensA {
+		if tokensB[t] {
+			common++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return 0.7 * float64(common) / float64(union)
+}
+
+// overlapRatio 录制文本中仍能在候选附近找到的比例
+func overlapRatio(recorded, current []string) float64 {
+	if len(recorded) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(current))
+	for _, s := range current {
+		set[normalizeText(s)] = true
+	}
+	hits := 0
+	for _, s := range recorded {
+		if set[normalizeText(s)] {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(recorded))
+}
+
+// ancestorSimilarity 从最近的祖先开始逐层比较标签
+func ancestorSimilarity(recorded, current []string) float64 {
+	if len(recorded) == 0 {
+		return 0
+	}
+	matched := 0
+	for i := 0; i < len(recorded) && i < len(current); i++ {
+		if strings.EqualFold(recorded[i], current[i]) {
+			matched++
+		}
+	}
+	longest := len(recorded)
+	if len(current) > longest {
+		longest = len(current)
+	}
+	return float64(matched) / float64(longest)
+}
+
+// axValueString 读取 AX 属性的字符串值
+func axValueString(value *proto.AccessibilityAXValue) string {
+	if value == nil {
+		return ""
+	}
+	return strings.Trim(value.Value.String(), `"`)
+}
+
+// isSimpleTagName 标签名只包含字母、数字和连字符
+func isSimpleTagName(tag string) bool {
+	for _, r := range tag {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '-' {
+			return false
+		}
+	}
+	return tag != ""
+}
+
+// xpathLiteral 将文本转换为 XPath 字符串字面量（处理引号）
+func xpathLiteral(s string) string {
+	if !strings.Contains(s, `"`) {
+		return `"` + s + `"`
+	}
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'"
+	}
+	parts := strings.Split(s, `"`)
+	quoted := make([]string, len(parts))
+	for i, part := range parts {
+		quoted[i] = `"` + part + `"`
+	}
+	return "concat(" + strings.Join(quoted, `, '"', `) + ")"
+}
+
+// GetHealedLocators 获取本次回放中通过语义自愈找到的定位信息
+func (p *Player) GetHealedLocators() []HealedLocator {
+	return p.healedLocators
+}
+
+// recordHealed 记录步骤的自愈结果（同一步骤只保留最后一次）
+// 循环体和子脚本中的步骤带有嵌套路径，与所在的顶层步骤区分
+func (p *Player) recordHealed(action models.ScriptAction, candidate *healCandidate) {
+	healed := HealedLocator{
+		StepIndex:  p.currentStepIndex,
+		StepPath:   append([]int(nil), p.nestedSteps...),
+		ActionType: action.Type,
+		OldXPath:   action.XPath,
+		OldCSS:     action.Selector,
+		XPath:      candidate.features.XPath,
+		Confidence: candidate.score,
+	}
+	if p.currentScript != nil {
+		healed.ScriptID = p.currentScript.ID
+	}
+	for i := range p.healedLocators {
+		if sameHealedStep(p.healedLocators[i], healed) {
+			p.healedLocators[i] = healed
+			return
+		}
+	}
+	p.healedLocators = append(p.healedLocators, healed)
+}
+
+// sameHealedStep 两条自愈记录是否对应同一步骤
+func sameHealedStep(a, b HealedLocator) bool {
+	if a.StepIndex != b.StepIndex || a.ScriptID != b.ScriptID || len(a.StepPath) != len(b.StepPath) {
+		return false
+	}
+	for i := range a.StepPath {
+		if a.StepPath[i] != b.StepPath[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// applyHealedLocators 将自愈结果应用到脚本，返回更新的步骤数
+// 只处理脚本自身的顶层步骤：循环体和子脚本中的步骤在回放时经过变量替换，写回会覆盖错误的步骤
+func applyHealedLocators(script *models.Script, healed []HealedLocator) int {
+	updated := 0
+	for _, h := range healed {
+		if len(h.StepPath) > 0 || (h.ScriptID != "" && h.ScriptID != script.ID) {
+			continue
+		}
+		if h.StepIndex < 0 || h.StepIndex >= len(script.Actions) || h.XPath == "" {
+			continue
+		}
+		action := &script.Actions[h.StepIndex]
+		if action.Type != h.ActionType || action.XPath != h.OldXPath || action.Selector != h.OldCSS {
+			continue
+		}
+		if strings.Contains(action.XPath, "${") || strings.Contains(action.Selector, "${") {
+			continue
+		}
+		action.XPath = h.XPath
+		if action.Evidence == nil {
+			action.Evidence = &models.ActionEvidence{}
+		}
+		action.Evidence.Confidence = h.Confidence
+		updated++
+	}
+	return updated
+}
+
+// persistHealedLocators 将自愈后的 XPath 写回数据库中的脚本
+// 只更新仍与回放时一致的步骤，包含变量占位符的定位不会被覆盖
+func (m *Manager) persistHealedLocators(ctx context.Context, scriptID string, healed []HealedLocator) {
+	if m.db == nil || scriptID == "" || len(healed) == 0 {
+		return
+	}
+	stored, err := m.db.GetScript(scriptID)
+	if err != nil {
+		logger.Warn(ctx, "[SelfHeal] Failed to load script for write-back: %v", err)
+		return
+	}
+
+	updated := applyHealedLocators(stored, healed)
+	if updated == 0 {
+		return
+	}
+
+	info := models.RevisionInfo{
+		Author: "self-heal",
+		Note:   fmt.Sprintf("healed %d selector(s)", updated),
+		Source: models.RevisionSourceSelfHeal,
+	}
+	if err := m.db.UpdateScriptWithRevision(stored, info); err != nil {
+		logger.Warn(ctx, "[SelfHeal] Failed to write healed selectors back: %v", err)
+		return
+	}
+	logger.Info(ctx, "[SelfHeal] Wrote %d healed selector(s) back to script %s", updated, scriptID)
+}
@@ -0,0 +1,160 @@
+package browser
+
+import (
+	"testing"
+
+	"github.com/browserwing/browserwing/models"
+)
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{a: "Sign In", b: "sign  in", want: 1},
+		{a: "Read the full article about...", b: "Read the full article about browser automation", want: 0.9},
+		{a: "Search", b: "Search products", want: 0.9},
+		{a: "Submit order", b: "Place order now", want: 0.7 * 1 / 4},
+		{a: "Login", b: "", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"|"+tt.b, func(t *testing.T) {
+			if got := nameSimilarity(tt.a, tt.b); got != tt.want {
+				t.Errorf("nameSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreHealCandidate(t *testing.T) {
+	action := models.ScriptAction{
+		Type:          "click",
+		TagName:       "BUTTON",
+		Accessibility: &models.AccessibilityInfo{Role: "button", Name: "Sign In"},
+		Context: &models.ActionContext{
+			NearbyText:   []string{"Forgot password?", "Remember me"},
+			AncestorTags: []string{"div", "form", "main", "body"},
+			FormHint:     "login",
+		},
+	}
+
+	exact := elementFeatures{
+		Tag: "button", Role: "button", Name: "Sign in",
+		NearbyText:   []string{"Remember me", "Forgot password?"},
+		AncestorTags: []string{"div", "form", "main", "body"},
+		FormHint:     "login", Visible: true,
+	}
+	// 改版后按钮变成了链接，文案和位置基本不变
+	redesigned := elementFeatures{
+		Tag: "a", Role: "link", Name: "Sign in",
+		NearbyText:   []string{"Remember me"},
+		AncestorTags: []string{"span", "form", "main", "body"},
+		FormHint:     "login", Visible: true,
+	}
+	unrelated := elementFeatures{
+		Tag: "button", Role: "button", Name: "Subscribe",
+		AncestorTags: []string{"footer", "body"},
+		Visible:      true,
+	}
+	hidden := exact
+	hidden.Visible = false
+
+	scoreExact := scoreHealCandidate(action, exact)
+	scoreRedesigned := scoreHealCandidate(action, redesigned)
+	scoreUnrelated := scoreHealCandidate(action, unrelated)
+	scoreHidden := scoreHealCandidate(action, hidden)
+
+	if scoreExact != 1 {
+		t.Errorf("exact match score = %v, want 1", scoreExact)
+	}
+	if scoreRedesigned < healMinConfidence || scoreRedesigned >= scoreExact {
+		t.Errorf("redesigned score = %v, want in [%v, %v)", scoreRedesigned, healMinConfidence, scoreExact)
+	}
+	if scoreUnrelated >= healMinConfidence {
+		t.Errorf("unrelated score = %v, want below %v", scoreUnrelated, healMinConfidence)
+	}
+	if scoreHidden != scoreExact/2 {
+		t.Errorf("hidden score = %v, want %v", scoreHidden, scoreExact/2)
+	}
+}
+
+func TestXPathLiteral(t *testing.T) {
+	tests := map[string]string{
+		`Sign in`:       `"Sign in"`,
+		`Say "hi"`:      `'Say "hi"'`,
+		`It's "quoted"`: `concat("It's ", '"', "quoted", '"', "")`,
+	}
+	for input, want := range tests {
+		if got := xpathLiteral(input); got != want {
+			t.Errorf("xpathLiteral(%q) = %s, want %s", input, got, want)
+		}
+	}
+}
+
+func TestRecordHealedNestedSteps(t *testing.T) {
+	p := &Player{currentScript: &models.Script{ID: "parent"}, currentStepIndex: 2}
+	candidate := func(xpath string) *healCandidate {
+		return &healCandidate{features: elementFeatures{XPath: xpath}, score: 0.9}
+	}
+	action := models.ScriptAction{Type: "click", XPath: "//old"}
+
+	p.recordHealed(action, candidate("//top"))
+	p.nestedSteps = []int{1}
+	p.recordHealed(action, candidate("//loop-body"))
+	p.currentScript = &models.Script{ID: "child"}
+	p.nestedSteps = []int{0}
+	p.recordHealed(action, candidate("//child"))
+	p.nestedSteps = nil
+	p.currentScript = &models.Script{ID: "parent"}
+	p.recordHealed(action, candidate("//top-again"))
+
+	healed := p.GetHealedLocators()
+	if len(healed) != 3 {
+		t.Fatalf("healed = %+v, want 3 separate steps", healed)
+	}
+	if healed[0].XPath != "//top-again" || len(healed[0].StepPath) != 0 {
+		t.Errorf("top-level heal = %+v", healed[0])
+	}
+	if healed[1].StepIndex != 2 || len(healed[1].StepPath) != 1 || healed[1].StepPath[0] != 1 {
+		t.Errorf("loop body heal = %+v", healed[1])
+	}
+	if healed[2].ScriptID != "child" {
+		t.Errorf("called script heal = %+v", healed[2])
+	}
+}
+
+func TestApplyHealedLocators(t *testing.T) {
+	newScript := func() *models.Script {
+		return &models.Script{ID: "s1", Actions: []models.ScriptAction{
+			{Type: "click", XPath: "//old"},
+			{Type: "loop_elements", XPath: "//li"},
+			{Type: "input", XPath: "//input[@name='${field}']"},
+		}}
+	}
+	tests := []struct {
+		name    string
+		healed  HealedLocator
+		want    int
+		wantNew string
+	}{
+		{name: "top-level step", healed: HealedLocator{StepIndex: 0, ScriptID: "s1", ActionType: "click", OldXPath: "//old", XPath: "//new"}, want: 1, wantNew: "//new"},
+		{name: "nested loop step", healed: HealedLocator{StepIndex: 1, StepPath: []int{0}, ScriptID: "s1", ActionType: "loop_elements", OldXPath: "//li", XPath: "//li/a"}, want: 0},
+		{name: "called script step", healed: HealedLocator{StepIndex: 0, ScriptID: "s2", ActionType: "click", OldXPath: "//old", XPath: "//new"}, want: 0},
+		{name: "script changed since playback", healed: HealedLocator{StepIndex: 0, ScriptID: "s1", ActionType: "click", OldXPath: "//other", XPath: "//new"}, want: 0},
+		{name: "variable locator", healed: HealedLocator{StepIndex: 2, ScriptID: "s1", ActionType: "input", OldXPath: "//input[@name='${field}']", XPath: "//input"}, want: 0},
+		{name: "out of range", healed: HealedLocator{StepIndex: 5, ScriptID: "s1", ActionType: "click", XPath: "//new"}, want: 0},
+	}
+	for _, tt := range tests {
+		script := newScript()
+		if got := applyHealedLocators(script, []HealedLocator{tt.healed}); got != tt.want {
+			t.Errorf("%s: updated = %d, want %d", tt.name, got, tt.want)
+		}
+		if tt.wantNew != "" && script.Actions[tt.healed.StepIndex].XPath != tt.wantNew {
+			t.Errorf("%s: xpath = %q", tt.name, script.Actions[tt.healed.StepIndex].XPath)
+		}
+		if tt.want == 0 && script.Actions[1].XPath != "//li" {
+			t.Errorf("%s: loop action overwritten: %q", tt.name, script.Actions[1].XPath)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/browserwing/browserwing/models"
+	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/go-rod/rod"
+)
+
+// NewSessionPage 在指定实例中为执行器会话创建独立的空白页面（不会成为活动页面）
+// isolation 为 incognito 时页面位于新的无痕上下文中，与其他会话不共享 Cookie 和存储
+// instanceID 为空时使用当前实例
+func (m *Manager) NewSessionPage(ctx context.Context, instanceID, isolation string) (*rod.Page, error) {
+	m.mu.Lock()
+	browser, _, instance, err := m.getInstanceBrowser(instanceID)
+	if instanceID == "" {
+		instanceID = m.currentInstanceID
+	}
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if browser == nil {
+		return nil, fmt.Errorf("browser is not running")
+	}
+
+	config := m.getConfigForURL("")
+	useStealth := true // 默认使用stealth
+	if config != nil && config.UseStealth != nil {
+		useStealth = *config.UseStealth
+	}
+
+	page, _, err := m.newPlaybackPage(ctx, browser, &models.BrowserInstance{PlaybackIsolation: isolation}, useStealth)
+	if err != nil {
+		return nil, err
+	}
+
+	m.setPageWindow(page)
+	m.monitorPage(instanceID, page)
+	applyPageEmulation(ctx, page, m.playbackSettings(instanceID, instance), config, nil)
+	logger.Info(ctx, "Created session page %s in instance %s (isolation: %s)", page.TargetID, instanceID, playbackIsolation(&models.BrowserInstance{PlaybackIsolation: isolation}))
+	return page, nil
+}
+
+// CloseSessionPage 关闭会话页面，页面位于无痕上下文时一并销毁该上下文
+func (m *Manager) CloseSessionPage(page *rod.Page) error {
+	if page == nil {
+		return nil
+	}
+	err := page.Close()
+	m.releasePlaybackContext(page)
+	return err
+}
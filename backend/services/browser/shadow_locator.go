@@ -0,0 +1,181 @@
+package browser
+
+import (
+	"strings"
+
+	"github.com/go-rod/rod"
+)
+
+// 穿透 Shadow DOM 的定位语法：
+//
+//	my-app >>> #login >>> button.submit   逐层进入开放的 shadowRoot，每一段可以是 CSS 或 XPath
+//	shadow:button.submit                  在文档和所有开放的 shadowRoot 中深度查找 CSS
+//
+// shadowRoot 内的 XPath 以该 shadowRoot 为上下文节点求值，"/div" 与 "//div" 会自动转换为相对路径
+const (
+	ShadowPierceSeparator = ">>>"
+	ShadowLocatorPrefix   = "shadow:"
+)
+
+// IsShadowLocator 判断定位器是否使用了穿透 Shadow DOM 的语法
+func IsShadowLocator(locator string) bool {
+	locator = strings.TrimSpace(locator)
+	return strings.Contains(locator, ShadowPierceSeparator) ||
+		strings.HasPrefix(strings.ToLower(locator), ShadowLocatorPrefix)
+}
+
+// shadowQuery 解析后的穿透定位器，作为 shadowQueryScript 的参数
+type shadowQuery struct {
+	Deep     bool            `json:"deep"`     // shadow: 前缀，在所有开放的 shadowRoot 中深度查找 CSS
+	Segments []shadowSegment `json:"segments"` // 逐层进入 shadowRoot 的各段（Deep 时只有一段）
+}
+
+// shadowSegment 穿透定位器中的一段
+type shadowSegment struct {
+	Value string `json:"value"`
+	XPath bool   `json:"xpath"`
+}
+
+// parseShadowLocator 解析穿透 Shadow DOM 的定位器，shadowRoot 内以 "/" 开头的 XPath 转换为相对路径
+func parseShadowLocator(locator string) shadowQuery {
+	locator = strings.TrimSpace(locator)
+	if strings.HasPrefix(strings.ToLower(locator), ShadowLocatorPrefix) {
+		css := strings.TrimSpace(locator[len(ShadowLocatorPrefix):])
+		return shadowQuery{Deep: true, Segments: []shadowSegment{{Value: css}}}
+	}
+
+	query := shadowQuery{Segments: []shadowSegment{}}
+	for _, part := range strings.Split(locator, ShadowPierceSeparator) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		segment := shadowSegment{Value: part, XPath: isXPathSegment(part)}
+		if segment.XPath && len(query.Segments) > 0 && strings.HasPrefix(part, "/") {
+			segment.Value = "." + part
+		}
+		query.Segments = append(query.Segments, segment)
+	}
+	return query
+}
+
+// isXPathSegment 判断定位器的一段是否为 XPath
+func isXPathSegment(segment string) bool {
+	for _, prefix := range []string{"/", "(", "./", "../"} {
+		if strings.HasPrefix(segment, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// shadowQueryScript 按解析后的穿透定位器（shadowQuery）查找元素，all 为 false 时只返回第一个匹配（未找到返回 null）
+const shadowQueryScript = `(locator, all) => {
+	const query = (root, segment) => {
+		if (segment.xpath) {
+			const result = document.evaluate(segment.value, root, null, XPathResult.ORDERED_NODE_SNAPSHOT_TYPE, null);
+			const nodes = [];
+			for (let i = 0; i < result.snapshotLength; i++) {
+				const node = result.snapshotItem(i);
+				nodes.push(node.nodeType === Node.ELEMENT_NODE ? node : node.parentElement);
+			}
+			return nodes.filter(Boolean);
+		}
+		return Array.from(root.querySelectorAll(segment.value));
+	};
+	const deepQuery = (root, css) => {
+		const found = [];
+		const roots = [root];
+		while (roots.length > 0) {
+			const current = roots.shift();
+			found.push(...current.querySelectorAll(css));
+			for (const el of current.querySelectorAll('*')) {
+				if (el.shadowRoot) roots.push(el.shadowRoot);
+			}
+		}
+		return found;
+	};
+
+	let matches = [];
+	if (locator.deep) {
+		matches = deepQuery(document, locator.segments[0].value);
+	} else {
+		const segments = locator.segments;
+		let roots = [document];
+		segments.forEach((segment, i) => {
+			matches = [];
+			for (const root of roots) {
+				for (const el of query(root, segment)) {
+					if (!matches.includes(el)) matches.push(el);
+				}
+			}
+			if (i < segments.length - 1) {
+				roots = matches.map((el) => el.shadowRoot).filter(Boolean);
+			}
+		});
+	}
+	return all ? matches : (matches[0] || null);
+}`
+
+// FindShadowElement 按穿透 Shadow DOM 的语法查找第一个匹配的元素
+// 未找到时按页面的超时设置重试（与 rod 的 Element 查询一致）
+func FindShadowElement(page *rod.Page, locator string) (*rod.Element, error) {
+	return page.ElementByJS(rod.Eval(shadowQueryScript, parseShadowLocator(locator), false))
+}
+
+// FindShadowElements 按穿透 Shadow DOM 的语法查找所有匹配的元素
+func FindShadowElements(page *rod.Page, locator string) (rod.Elements, error) {
+	return page.ElementsByJS(rod.Eval(shadowQueryScript, parseShadowLocator(locator), true))
+}
+
+// findByLocator 按 XPath 或 CSS 查找元素，定位器使用穿透语法时在 Shadow DOM 中查找
+func findByLocator(page *rod.Page, locator string, xpath bool) (*rod.Element, error) {
+	if IsShadowLocator(locator) {
+		return FindShadowElement(page, locator)
+	}
+	if xpath {
+		return page.ElementX(locator)
+	}
+	return page.Element(locator)
+}
+
+// ShadowXPathScript 生成元素的 XPath，元素位于 shadowRoot 内时生成带 ">>>" 的穿透路径
+// 可作为 rod Element.Eval 的参数（this 为目标元素）
+const ShadowXPathScript = `() => {
+	const pathInRoot = (el, root) => {
+		if (el.id && root.querySelectorAll('#' + CSS.escape(el.id)).length === 1) {
+			return '//*[@id="' + el.id + '"]';
+		}
+		const parts = [];
+		while (el && el.nodeType === Node.ELEMENT_NODE) {
+			let idx = 0;
+			let total = 0;
+			for (let sib = el.previousElementSibling; sib; sib = sib.previousElementSibling) {
+				if (sib.nodeName === el.nodeName) idx++;
+			}
+			for (let sib = el.nextElementSibling; sib; sib = sib.nextElementSibling) {
+				if (sib.nodeName === el.nodeName) total++;
+			}
+			let part = el.nodeName.toLowerCase();
+			if (idx > 0 || total > 0) part += '[' + (idx + 1) + ']';
+			parts.unshift(part);
+			el = el.parentNode;
+		}
+		return '/' + parts.join('/');
+	};
+
+	const segments = [];
+	let el = this;
+	while (el && el.nodeType === Node.ELEMENT_NODE) {
+		const root = el.getRootNode();
+		const path = pathInRoot(el, root);
+		if (root instanceof ShadowRoot) {
+			segments.unshift('.' + path);
+			el = root.host;
+		} else {
+			segments.unshift(path);
+			break;
+		}
+	}
+	return segments.join(' >>> ');
+}`
@@ -0,0 +1,94 @@
+package browser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsShadowLocator(t *testing.T) {
+	tests := []struct {
+		locator string
+		want    bool
+	}{
+		{locator: "my-app >>> button.submit", want: true},
+		{locator: `//*[@id="app"] >>> ./div/button[2]`, want: true},
+		{locator: "  Shadow:button.submit", want: true},
+		{locator: "div > button", want: false},
+		{locator: "//div[@class='shadow:x']", want: false},
+		{locator: "", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsShadowLocator(tt.locator); got != tt.want {
+			t.Errorf("IsShadowLocator(%q) = %v, want %v", tt.locator, got, tt.want)
+		}
+	}
+}
+
+func TestParseShadowLocator(t *testing.T) {
+	css := func(value string) shadowSegment { return shadowSegment{Value: value} }
+	xpath := func(value string) shadowSegment { return shadowSegment{Value: value, XPath: true} }
+
+	tests := []struct {
+		locator string
+		want    shadowQuery
+	}{
+		{
+			locator: "my-app >>> #login >>> button.submit",
+			want:    shadowQuery{Segments: []shadowSegment{css("my-app"), css("#login"), css("button.submit")}},
+		},
+		{
+			// ShadowXPathScript 生成的穿透路径：shadowRoot 内的段已是相对路径
+			locator: `//*[@id="app"] >>> ./div/button[2]`,
+			want:    shadowQuery{Segments: []shadowSegment{xpath(`//*[@id="app"]`), xpath("./div/button[2]")}},
+		},
+		{
+			// 手写的绝对路径在 shadowRoot 内转换为相对路径，第一段仍在文档中求值
+			locator: "/html/body/my-app >>> //button >>> (//span)[1]",
+			want:    shadowQuery{Segments: []shadowSegment{xpath("/html/body/my-app"), xpath(".//button"), xpath("(//span)[1]")}},
+		},
+		{
+			locator: "my-app >>> ../slot",
+			want:    shadowQuery{Segments: []shadowSegment{css("my-app"), xpath("../slot")}},
+		},
+		{
+			locator: " my-app >>>  >>> button ",
+			want:    shadowQuery{Segments: []shadowSegment{css("my-app"), css("button")}},
+		},
+		{
+			locator: "  SHADOW: button.submit ",
+			want:    shadowQuery{Deep: true, Segments: []shadowSegment{css("button.submit")}},
+		},
+		{
+			// 深度查找只支持 CSS，">>>" 不再拆分
+			locator: "shadow:my-app >>> button",
+			want:    shadowQuery{Deep: true, Segments: []shadowSegment{css("my-app >>> button")}},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := parseShadowLocator(tt.locator); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseShadowLocator(%q) = %+v, want %+v", tt.locator, got, tt.want)
+		}
+	}
+}
+
+func TestShadowScripts(t *testing.T) {
+	// 脚本读取的字段与 Go 侧的序列化一致，生成的穿透路径使用相同的分隔符
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{name: "query", script: shadowQueryScript, want: []string{"locator.deep", "locator.segments", "segment.xpath", "segment.value"}},
+		{name: "xpath", script: ShadowXPathScript, want: []string{"' " + ShadowPierceSeparator + " '", "'.' + path", "root instanceof ShadowRoot"}},
+	}
+	for _, tt := range tests {
+		for _, want := range tt.want {
+			if !strings.Contains(tt.script, want) {
+				t.Errorf("%s script missing %q", tt.name, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/browserwing/browserwing/models"
+	"github.com/browserwing/browserwing/pkg/logger"
+)
+
+// 元素实际命中的定位方式
+const (
+	MatchedByXPath       = "xpath"
+	MatchedByCSS         = "css"
+	MatchedByCSSFallback = "css_fallback" // XPath 未命中，回退到 CSS
+	MatchedByIframeXPath = "iframe_xpath"
+	MatchedByIframeCSS   = "iframe_css"
+	MatchedBySemantic    = "semantic" // 选择器失效，通过语义信息自愈定位
+)
+
+// recordMatch 记录当前步骤实际命中的定位方式
+func (p *Player) recordMatch(by, locator string) {
+	p.matchedBy = by
+	p.matchedLocator = locator
+}
+
+// beginStepTrace 开始记录一个步骤的执行轨迹
+func (p *Player) beginStepTrace(index int, action models.ScriptAction) *models.StepTrace {
+	p.matchedBy = ""
+	p.matchedLocator = ""
+	p.matchConfidence = 0
+	return &models.StepTrace{
+		Index:     index + 1,
+		Type:      action.Type,
+		Remark:    action.Remark,
+		StartTime: time.Now(),
+		URLBefore: p.currentPageURL(),
+	}
+}
+
+// finishStepTrace 结束步骤轨迹记录，失败时按需保存截图
+func (p *Player) finishStepTrace(ctx context.Context, trace *models.StepTrace, status string, attempts int, err error) {
+	trace.Status = status
+	trace.Attempts = attempts
+	// 循环和子脚本步骤的定位信息来自内部步骤，不记录到该步骤上
+	switch {
+	case models.IsLoopAction(trace.Type):
+		trace.Iterations = p.loopIterations
+	case trace.Type == models.ActionTypeCallScript:
+	default:
+		trace.MatchedBy = p.matchedBy
+		trace.MatchedLocator = p.matchedLocator
+		trace.MatchConfidence = p.matchConfidence
+	}
+	if err != nil {
+		trace.Error = err.Error()
+	}
+	if status != models.StepStatusSkipped {
+		trace.URLAfter = p.currentPageURL()
+	}
+	if status == models.StepStatusFailed && p.traceScreenshotDir != "" {
+		path, shotErr := p.saveFailureScreenshot(trace.Index)
+		if shotErr != nil {
+			logger.Warn(ctx, "Failed to capture failure screenshot for step %d: %v", trace.Index, shotErr)
+		} else {
+			trace.ScreenshotPath = path
+		}
+	}
+	trace.EndTime = time.Now()
+	trace.Duration = trace.EndTime.Sub(trace.StartTime).Milliseconds()
+	p.stepTraces = append(p.stepTraces, *trace)
+}
+
+// GetStepTraces 获取本次回放的步骤执行轨迹
+func (p *Player) GetStepTraces() []models.StepTrace {
+	return p.stepTraces
+}
+
+// SetTraceScreenshotDir 设置失败截图的保存目录（为空则不截图）
+func (p *Player) SetTraceScreenshotDir(dir string) {
+	p.traceScreenshotDir = dir
+}
+
+// currentPageURL 获取当前活动页面的 URL（获取失败时返回空字符串）
+func (p *Player) currentPageURL() string {
+	page := p.currentPage
+	if page == nil {
+		return ""
+	}
+	info, err := page.Timeout(2 * time.Second).Info()
+	if err != nil {
+		return ""
+	}
+	return info.URL
+}
+
+// saveFailureScreenshot 保存当前活动页面的截图
+func (p *Player) saveFailureScreenshot(stepIndex int) (string, error) {
+	page := p.currentPage
+	if page == nil {
+		return "", fmt.Errorf("no active page")
+	}
+	if err := os.MkdirAll(p.traceScreenshotDir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create trace directory: %w", err)
+	}
+
+	data, err := page.Timeout(5*time.Second).Screenshot(false, nil)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(p.traceScreenshotDir, fmt.Sprintf("step_%03d.png", stepIndex))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to save screenshot: %w", err)
+	}
+	return path, nil
+}
+
+// discardLastStepTrace 丢弃最后一条步骤轨迹及其失败截图（调试中重试失败步骤时由重试结果替换）
+func (p *Player) discardLastStepTrace(ctx context.Context) {
+	n := len(p.stepTraces)
+	if n == 0 {
+		return
+	}
+	if path := p.stepTraces[n-1].ScreenshotPath; path != "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logger.Warn(ctx, "Failed to remove screenshot of discarded step trace: %v", err)
+		}
+	}
+	p.stepTraces = p.stepTraces[:n-1]
+}
@@ -0,0 +1,467 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/browserwing/browserwing/models"
+	"github.com/browserwing/browserwing/pkg/logger"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/google/uuid"
+)
+
+// storageOriginTimeout 加载单个源（用于读写其存储）的超时时间
+const storageOriginTimeout = 15 * time.Second
+
+// blankOriginDocument 加载源时返回的空白文档，读写存储时不会真正请求目标站点
+const blankOriginDocument = "<!DOCTYPE html><html><head></head><body></body></html>"
+
+// captureOriginStorageJS 读取当前源的 localStorage、sessionStorage 和 IndexedDB
+const captureOriginStorageJS = `async (includeSession, includeIndexedDB) => {
+	const items = (storage) => {
+		const out = [];
+		for (let i = 0; i < storage.length; i++) {
+			const name = storage.key(i);
+			out.push({ name, value: storage.getItem(name) });
+		}
+		return out;
+	};
+	const request = (r) => new Promise((resolve, reject) => {
+		r.onsuccess = () => resolve(r.result);
+		r.onerror = () => reject(r.error);
+	});
+
+	const result = { origin: location.origin, local_storage: items(localStorage) };
+	if (includeSession) {
+		result.session_storage = items(sessionStorage);
+	}
+	if (includeIndexedDB && indexedDB.databases) {
+		result.indexed_db = [];
+		for (const info of await indexedDB.databases()) {
+			if (!info.name) continue;
+			const db = await request(indexedDB.open(info.name));
+			const dbOut = { name: db.name, version: db.version, stores: [] };
+			for (const storeName of Array.from(db.objectStoreNames)) {
+				const store = db.transaction(storeName, 'readonly').objectStore(storeName);
+				const storeOut = { name: storeName, key_path: store.keyPath, auto_increment: store.autoIncrement, indexes: [], records: [] };
+				for (const indexName of Array.from(store.indexNames)) {
+					const index = store.index(indexName);
+					storeOut.indexes.push({ name: index.name, key_path: index.keyPath, unique: index.unique, multi_entry: index.multiEntry });
+				}
+				const [keys, values] = await Promise.all([request(store.getAllKeys()), request(store.getAll())]);
+				storeOut.records = values.map((value, i) => store.keyPath === null ? { key: keys[i], value } : { value });
+				dbOut.stores.push(storeOut);
+			}
+			db.close();
+			result.indexed_db.push(dbOut);
+		}
+	}
+	return result;
+}`
+
+// applyOriginStorageJS 写入当前源的 localStorage、sessionStorage 和 IndexedDB
+// IndexedDB 中缺少的对象仓库会在升级时创建，已有仓库的数据会被替换
+const applyOriginStorageJS = `async (data) => {
+	for (const item of data.local_storage || []) localStorage.setItem(item.name, item.value);
+	for (const item of data.session_storage || []) sessionStorage.setItem(item.name, item.value);
+
+	for (const dbData of data.indexed_db || []) {
+		const stores = dbData.stores || [];
+		const open = (version) => new Promise((resolve, reject) => {
+			const r = version ? indexedDB.open(dbData.name, version) : indexedDB.open(dbData.name);
+			r.onupgradeneeded = () => {
+				const db = r.result;
+				for (const s of stores) {
+					if (db.objectStoreNames.contains(s.name)) continue;
+					const options = { autoIncrement: !!s.auto_increment };
+					if (s.key_path !== undefined && s.key_path !== null) options.keyPath = s.key_path;
+					const store = db.createObjectStore(s.name, options);
+					for (const index of s.indexes || []) {
+						store.createIndex(index.name, index.key_path, { unique: !!index.unique, multiEntry: !!index.multi_entry });
+					}
+				}
+			};
+			r.onsuccess = () => resolve(r.result);
+			r.onerror = () => reject(r.error);
+		});
+
+		let db;
+		try {
+			db = await open(dbData.version);
+		} catch (e) {
+			// 页面中的数据库版本更高时按现有版本打开
+			if (!e || e.name !== 'VersionError') throw e;
+			db = await open();
+		}
+
+		const names = stores.map((s) => s.name).filter((name) => db.objectStoreNames.contains(name));
+		if (names.length > 0) {
+			const tx = db.transaction(names, 'readwrite');
+			for (const s of stores) {
+				if (!names.includes(s.name)) continue;
+				const store = tx.objectStore(s.name);
+				store.clear();
+				for (const record of s.records || []) {
+					if (store.keyPath === null && record.key !== undefined && record.key !== null) {
+						store.put(record.value, record.key);
+					} else {
+						store.put(record.value);
+					}
+				}
+			}
+			await new Promise((resolve, reject) => {
+				tx.oncomplete = () => resolve();
+				tx.onerror = () => reject(tx.error);
+				tx.onabort = () => reject(tx.error);
+			});
+		}
+		db.close();
+	}
+	return true;
+}`
+
+// StorageCaptureOptions 捕获存储状态的选项
+type StorageCaptureOptions struct {
+	Origins   []string // 除当前页面外需要捕获的源（localStorage 和 IndexedDB）
+	IndexedDB bool     // 是否捕获 IndexedDB
+}
+
+// NormalizeOrigin 将 URL 或源规范化为 scheme://host[:port] 形式，只支持 http 和 https
+func NormalizeOrigin(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("invalid origin %q: %w", raw, err)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", fmt.Errorf("invalid origin %q: only http and https origins are supported", raw)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid origin %q: missing host", raw)
+	}
+	return scheme + "://" + strings.ToLower(u.Host), nil
+}
+
+// ValidateStorageState 校验存储状态
+func ValidateStorageState(state *models.StorageState) error {
+	if state == nil {
+		return fmt.Errorf("storage state is required")
+	}
+	for i, cookie := range state.Cookies {
+		if cookie == nil || cookie.Name == "" || cookie.Domain == "" {
+			return fmt.Errorf("cookie %d: name and domain are required", i)
+		}
+	}
+	seen := make(map[string]bool, len(state.Origins))
+	for i, origin := range state.Origins {
+		normalized, err := NormalizeOrigin(origin.Origin)
+		if err != nil {
+			return fmt.Errorf("origin %d: %w", i, err)
+		}
+		if seen[normalized] {
+			return fmt.Errorf("origin %d: duplicate origin %s", i, normalized)
+		}
+		seen[normalized] = true
+		for _, db := range origin.IndexedDB {
+			if db.Name == "" {
+				return fmt.Errorf("origin %s: indexed_db name is required", normalized)
+			}
+			for _, store := range db.Stores {
+				if store.Name == "" {
+					return fmt.Errorf("origin %s: indexed_db %s: store name is required", normalized, db.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// cookieParams 将保存的 Cookie 转换为设置 Cookie 的参数
+func cookieParams(cookies []*proto.NetworkCookie) []*proto.NetworkCookieParam {
+	params := make([]*proto.NetworkCookieParam, 0, len(cookies))
+	for _, cookie := range cookies {
+		if cookie == nil {
+			continue
+		}
+		params = append(params, &proto.NetworkCookieParam{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   cookie.Domain,
+			Path:     cookie.Path,
+			Secure:   cookie.Secure,
+			HTTPOnly: cookie.HTTPOnly,
+			SameSite: cookie.SameSite,
+			Expires:  cookie.Expires,
+		})
+	}
+	return params
+}
+
+// pageOrigin 返回页面 URL 对应的源，非 http(s) 页面返回空字符串
+func pageOrigin(page *rod.Page) string {
+	info, err := page.Info()
+	if err != nil {
+		return ""
+	}
+	origin, err := NormalizeOrigin(info.URL)
+	if err != nil {
+		return ""
+	}
+	return origin
+}
+
+// isEmptyOriginStorage 判断源的存储是否为空
+func isEmptyOriginStorage(origin *models.OriginStorage) bool {
+	return len(origin.LocalStorage) == 0 && len(origin.SessionStorage) == 0 && len(origin.IndexedDB) == 0
+}
+
+// loadBlankOrigin 让页面加载指定源的空白文档（请求被拦截，不会访问目标站点），之后可以读写该源的存储
+// 加载期间接管页面的 Fetch 域并在结束时关闭，页面已有的拦截规则由 Manager.ApplyPageStorageState 恢复
+func loadBlankOrigin(ctx context.Context, page *rod.Page, origin string) error {
+	router := page.HijackRequests()
+	if err := router.Add("*", "", func(h *rod.Hijack) {
+		h.Response.SetHeader("Content-Type", "text/html; charset=utf-8")
+		h.Response.SetBody(blankOriginDocument)
+	}); err != nil {
+		return err
+	}
+	go router.Run()
+	defer func() { _ = router.Stop() }()
+
+	p := page.Context(ctx).Timeout(storageOriginTimeout)
+	if err := p.Navigate(origin + "/"); err != nil {
+		return fmt.Errorf("failed to load origin %s: %w", origin, err)
+	}
+	if err := p.WaitLoad(); err != nil {
+		return fmt.Errorf("failed to load origin %s: %w", origin, err)
+	}
+	return nil
+}
+
+// withOriginPage 在与 page 相同的浏览器上下文中打开临时后台页面并加载指定源，执行 fn 后关闭
+func withOriginPage(ctx context.Context, page *rod.Page, origin string, fn func(*rod.Page) error) error {
+	tmp, err := page.Browser().Page(proto.TargetCreateTarget{URL: "about:blank", Background: true})
+	if err != nil {
+		return fmt.Errorf("failed to create page for origin %s: %w", origin, err)
+	}
+	defer func() { _ = tmp.Close() }()
+
+	if err := loadBlankOrigin(ctx, tmp, origin); err != nil {
+		return err
+	}
+	return fn(tmp)
+}
+
+// captureOrigin 读取页面当前源的存储
+func captureOrigin(ctx context.Context, page *rod.Page, includeSession, includeIndexedDB bool) (*models.OriginStorage, error) {
+	res, err := page.Context(ctx).Timeout(storageOriginTimeout).Eval(captureOriginStorageJS, includeSession, includeIndexedDB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage: %w", err)
+	}
+	var origin models.OriginStorage
+	if err := res.Value.Unmarshal(&origin); err != nil {
+		return nil, fmt.Errorf("failed to decode storage: %w", err)
+	}
+	return &origin, nil
+}
+
+// applyOrigin 写入页面当前源的存储
+func applyOrigin(ctx context.Context, page *rod.Page, origin *models.OriginStorage) error {
+	if _, err := page.Context(ctx).Timeout(storageOriginTimeout).Eval(applyOriginStorageJS, origin); err != nil {
+		return fmt.Errorf("failed to write storage of %s: %w", origin.Origin, err)
+	}
+	return nil
+}
+
+// CaptureStorageState 捕获页面所在浏览器上下文的存储状态：
+// 全部 Cookie、当前页面源的 localStorage/sessionStorage/IndexedDB，以及 opts.Origins 中其他源的 localStorage/IndexedDB
+func CaptureStorageState(ctx context.Context, page *rod.Page, opts StorageCaptureOptions) (*models.StorageState, error) {
+	cookies, err := page.Browser().GetCookies()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cookies: %w", err)
+	}
+	state := &models.StorageState{Cookies: cookies, Origins: []models.OriginStorage{}}
+
+	current := pageOrigin(page)
+	if current != "" {
+		origin, err := captureOrigin(ctx, page, true, opts.IndexedDB)
+		if err != nil {
+			return nil, fmt.Errorf("origin %s: %w", current, err)
+		}
+		origin.Origin = current
+		if !isEmptyOriginStorage(origin) {
+			state.Origins = append(state.Origins, *origin)
+		}
+	}
+
+	seen := map[string]bool{current: true}
+	for _, raw := range opts.Origins {
+		originURL, err := NormalizeOrigin(raw)
+		if err != nil {
+			return nil, err
+		}
+		if seen[originURL] {
+			continue
+		}
+		seen[originURL] = true
+
+		err = withOriginPage(ctx, page, originURL, func(p *rod.Page) error {
+			// sessionStorage 属于单个标签页，临时页面中总是为空
+			origin, err := captureOrigin(ctx, p, false, opts.IndexedDB)
+			if err != nil {
+				return err
+			}
+			origin.Origin = originURL
+			if !isEmptyOriginStorage(origin) {
+				state.Origins = append(state.Origins, *origin)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("origin %s: %w", originURL, err)
+		}
+	}
+
+	logger.Info(ctx, "Captured storage state: %d cookies, %d origins", len(state.Cookies), len(state.Origins))
+	return state, nil
+}
+
+// ApplyStorageState 将存储状态写入页面所在的浏览器上下文
+// 空白页面（如新建的回放页面）会依次加载每个源的空白文档写入全部存储，最后回到 about:blank；
+// 已打开网页的页面直接写入当前源，其他源在临时页面中写入（sessionStorage 只能写入当前源）
+func ApplyStorageState(ctx context.Context, page *rod.Page, state *models.StorageState) error {
+	if err := ValidateStorageState(state); err != nil {
+		return err
+	}
+
+	if len(state.Cookies) > 0 {
+		if err := (proto.NetworkSetCookies{Cookies: cookieParams(state.Cookies)}).Call(page); err != nil {
+			return fmt.Errorf("failed to set cookies: %w", err)
+		}
+	}
+	if len(state.Origins) == 0 {
+		return nil
+	}
+
+	current := pageOrigin(page)
+	blank := current == ""
+	for i := range state.Origins {
+		origin := state.Origins[i]
+		origin.Origin, _ = NormalizeOrigin(origin.Origin)
+
+		switch {
+		case origin.Origin == current:
+			if err := applyOrigin(ctx, page, &origin); err != nil {
+				return err
+			}
+		case blank:
+			if err := loadBlankOrigin(ctx, page, origin.Origin); err != nil {
+				return err
+			}
+			if err := applyOrigin(ctx, page, &origin); err != nil {
+				return err
+			}
+		default:
+			if len(origin.SessionStorage) > 0 {
+				logger.Warn(ctx, "Skipping sessionStorage of %s: it can only be restored into the current origin", origin.Origin)
+				origin.SessionStorage = nil
+			}
+			if err := withOriginPage(ctx, page, origin.Origin, func(p *rod.Page) error {
+				return applyOrigin(ctx, p, &origin)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if blank {
+		if err := page.Context(ctx).Navigate("about:blank"); err != nil {
+			return fmt.Errorf("failed to reset page after applying storage state: %w", err)
+		}
+	}
+
+	logger.Info(ctx, "Applied storage state: %d cookies, %d origins", len(state.Cookies), len(state.Origins))
+	return nil
+}
+
+// ApplyPageStorageState 将存储状态写入页面，页面上的请求拦截规则在写入期间暂停、写入后恢复
+// （空白页面加载源文档时会临时接管并在结束时关闭 Fetch 域）
+func (m *Manager) ApplyPageStorageState(ctx context.Context, page *rod.Page, state *models.StorageState) error {
+	m.pageMonitorMu.Lock()
+	router := m.pageRouters[page.TargetID]
+	m.pageMonitorMu.Unlock()
+	if router == nil {
+		return ApplyStorageState(ctx, page, state)
+	}
+
+	router.suspend()
+	err := ApplyStorageState(ctx, page, state)
+	if resumeErr := router.resume(); resumeErr != nil {
+		logger.Warn(ctx, "Failed to restore route rules of page %s: %v", page.TargetID, resumeErr)
+		if err == nil {
+			err = fmt.Errorf("failed to restore route rules: %w", resumeErr)
+		}
+	}
+	return err
+}
+
+// CaptureInstanceStorageState 捕获实例的存储状态（当前活动页面所在的默认上下文）
+// instanceID 为空时使用当前实例
+func (m *Manager) CaptureInstanceStorageState(ctx context.Context, instanceID string, opts StorageCaptureOptions) (*models.StorageState, error) {
+	m.mu.Lock()
+	browser, activePage, _, err := m.getInstanceBrowser(instanceID)
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if browser == nil {
+		return nil, fmt.Errorf("browser is not running")
+	}
+
+	if activePage == nil {
+		// 没有活动页面时仍可捕获 Cookie 和指定源的存储
+		tmp, err := browser.Page(proto.TargetCreateTarget{URL: "about:blank", Background: true})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create page: %w", err)
+		}
+		defer func() { _ = tmp.Close() }()
+		activePage = tmp
+	}
+	return CaptureStorageState(ctx, activePage, opts)
+}
+
+// LoadStorageState 从数据库加载已保存的存储状态
+func (m *Manager) LoadStorageState(id string) (*models.StorageState, error) {
+	if m.db == nil {
+		return nil, fmt.Errorf("storage is not available")
+	}
+	return m.db.GetStorageState(id)
+}
+
+// SaveStorageState 保存存储状态；未指定 ID 时按名称覆盖同名的存储状态，否则新建
+func (m *Manager) SaveStorageState(state *models.StorageState) error {
+	if m.db == nil {
+		return fmt.Errorf("storage is not available")
+	}
+	if state.ID == "" {
+		existing, err := m.db.ListStorageStates()
+		if err != nil {
+			return err
+		}
+		for _, s := range existing {
+			if state.Name != "" && s.Name == state.Name {
+				state.ID = s.ID
+				state.CreatedAt = s.CreatedAt
+				break
+			}
+		}
+	}
+	if state.ID == "" {
+		state.ID = uuid.New().String()
+	}
+	return m.db.SaveStorageState(state)
+}
@@ -0,0 +1,93 @@
+package browser
+
+import (
+	"testing"
+
+	"github.com/browserwing/browserwing/models"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func TestNormalizeOrigin(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "https://example.com", want: "https://example.com"},
+		{raw: "HTTPS://Example.com:8443/login?next=/", want: "https://example.com:8443"},
+		{raw: " http://localhost:3000/ ", want: "http://localhost:3000"},
+		{raw: "about:blank", wantErr: true},
+		{raw: "chrome://settings", wantErr: true},
+		{raw: "example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := NormalizeOrigin(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeOrigin() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeOrigin() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateStorageState(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   *models.StorageState
+		wantErr bool
+	}{
+		{name: "nil", state: nil, wantErr: true},
+		{name: "empty", state: &models.StorageState{}},
+		{
+			name: "cookies and origins",
+			state: &models.StorageState{
+				Cookies: []*proto.NetworkCookie{{Name: "sid", Value: "1", Domain: ".example.com"}},
+				Origins: []models.OriginStorage{
+					{Origin: "https://example.com", LocalStorage: []models.StorageItem{{Name: "token", Value: "abc"}}},
+					{Origin: "https://app.example.com", IndexedDB: []models.IndexedDBDatabase{{Name: "keyval", Stores: []models.IndexedDBStore{{Name: "store"}}}}},
+				},
+			},
+		},
+		{name: "cookie without domain", state: &models.StorageState{Cookies: []*proto.NetworkCookie{{Name: "sid"}}}, wantErr: true},
+		{name: "invalid origin", state: &models.StorageState{Origins: []models.OriginStorage{{Origin: "file:///tmp"}}}, wantErr: true},
+		{
+			name:    "duplicate origin",
+			state:   &models.StorageState{Origins: []models.OriginStorage{{Origin: "https://example.com"}, {Origin: "https://example.com/"}}},
+			wantErr: true,
+		},
+		{
+			name:    "unnamed store",
+			state:   &models.StorageState{Origins: []models.OriginStorage{{Origin: "https://example.com", IndexedDB: []models.IndexedDBDatabase{{Name: "db", Stores: []models.IndexedDBStore{{}}}}}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStorageState(tt.state)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateStorageState() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCookieParams(t *testing.T) {
+	cookies := []*proto.NetworkCookie{
+		{Name: "sid", Value: "1", Domain: ".example.com", Path: "/", Secure: true, HTTPOnly: true, SameSite: proto.NetworkCookieSameSiteLax, Expires: 1735689600},
+		nil,
+	}
+
+	params := cookieParams(cookies)
+	if len(params) != 1 {
+		t.Fatalf("expected 1 cookie param, got %d", len(params))
+	}
+	p := params[0]
+	if p.Name != "sid" || p.Domain != ".example.com" || !p.Secure || !p.HTTPOnly || p.SameSite != proto.NetworkCookieSameSiteLax || p.Expires != 1735689600 {
+		t.Errorf("unexpected cookie param: %+v", p)
+	}
+}
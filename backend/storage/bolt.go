@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"path/filepath"
@@ -30,6 +31,8 @@ var (
 	apiKeysBucket           = []byte("api_keys")
 	scheduledTasksBucket    = []byte("scheduled_tasks")
 	taskExecutionsBucket    = []byte("task_executions")
+	storageStatesBucket     = []byte("storage_states")
+	scriptRevisionsBucket   = []byte("script_revisions")
 )
 
 type BoltDB struct {
@@ -110,6 +113,14 @@ func NewBoltDB(dbPath string) (*BoltDB, error) {
 			return err
 		}
 		_, err = tx.CreateBucketIfNotExists(taskExecutionsBucket)
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists(storageStatesBucket)
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists(scriptRevisionsBucket)
 		return err
 	})
 	if err != nil {
@@ -168,16 +179,190 @@ func (b *BoltDB) DeleteCookies(id string) error {
 	})
 }
 
-// SaveScript 保存脚本
-func (b *BoltDB) SaveScript(script *models.Script) error {
+// SaveStorageState 保存存储状态
+func (b *BoltDB) SaveStorageState(state *models.StorageState) error {
+	state.UpdatedAt = time.Now()
+	if state.CreatedAt.IsZero() {
+		state.CreatedAt = time.Now()
+	}
+
 	return b.db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket(scriptsBucket)
-		data, err := json.Marshal(script)
+		bucket := tx.Bucket(storageStatesBucket)
+		data, err := json.Marshal(state)
 		if err != nil {
 			return err
 		}
-		return bucket.Put([]byte(script.ID), data)
+		return bucket.Put([]byte(state.ID), data)
+	})
+}
+
+// GetStorageState 获取存储状态
+func (b *BoltDB) GetStorageState(id string) (*models.StorageState, error) {
+	var state models.StorageState
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(storageStatesBucket)
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("storage state not found: %s", id)
+		}
+		return json.Unmarshal(data, &state)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// ListStorageStates 列出所有存储状态
+func (b *BoltDB) ListStorageStates() ([]*models.StorageState, error) {
+	var states []*models.StorageState
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(storageStatesBucket)
+		return bucket.ForEach(func(k, v []byte) error {
+			var state models.StorageState
+			if err := json.Unmarshal(v, &state); err != nil {
+				return nil // 跳过无效数据
+			}
+			states = append(states, &state)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 按更新时间降序排序
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].UpdatedAt.After(states[j].UpdatedAt)
+	})
+
+	return states, nil
+}
+
+// DeleteStorageState 删除存储状态
+func (b *BoltDB) DeleteStorageState(id string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(storageStatesBucket)
+		return bucket.Delete([]byte(id))
+	})
+}
+
+// SaveScript 保存脚本（内容变化时创建新版本）
+func (b *BoltDB) SaveScript(script *models.Script) error {
+	return b.SaveScriptWithRevision(script, models.RevisionInfo{})
+}
+
+// SaveScriptWithRevision 保存脚本，并在内容与最新版本不同时创建一个新版本
+func (b *BoltDB) SaveScriptWithRevision(script *models.Script, info models.RevisionInfo) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return putScriptWithRevision(tx, script, info)
+	})
+}
+
+// putScriptWithRevision 在事务中保存脚本，内容变化时创建新版本
+func putScriptWithRevision(tx *bolt.Tx, script *models.Script, info models.RevisionInfo) error {
+	revisions := tx.Bucket(scriptRevisionsBucket)
+	latest, err := latestScriptRevision(revisions, script.ID)
+	if err != nil {
+		return err
+	}
+	if latest == nil {
+		// 启用版本历史前保存的脚本没有版本记录，先把已保存的内容补记为版本 1，避免首次修改后原内容丢失
+		if latest, err = putBaselineRevision(tx, revisions, script); err != nil {
+			return err
+		}
+	}
+
+	createRevision := latest == nil || !models.SameScriptContent(latest.Script, script)
+	if createRevision {
+		script.Revision = 1
+		if latest != nil {
+			script.Revision = latest.Revision + 1
+		}
+	} else {
+		script.Revision = latest.Revision
+	}
+
+	data, err := json.Marshal(script)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(scriptsBucket).Put([]byte(script.ID), data); err != nil {
+		return err
+	}
+	if !createRevision {
+		return nil
+	}
+
+	revision := &models.ScriptRevision{
+		ScriptID:    script.ID,
+		Revision:    script.Revision,
+		Author:      info.Author,
+		Note:        info.Note,
+		Source:      info.Source,
+		ActionCount: len(script.Actions),
+		Script:      script,
+		CreatedAt:   time.Now(),
+	}
+	data, err = json.Marshal(revision)
+	if err != nil {
+		return err
+	}
+	return revisions.Put(scriptRevisionKey(script.ID, script.Revision), data)
+}
+
+// ImportScripts 在同一事务中保存导入的脚本（带版本）、工具配置和定时任务，任一写入失败时全部回滚
+// replaceTasks 中的任务必须已存在（覆盖导入），其余任务作为新任务创建
+func (b *BoltDB) ImportScripts(scripts []*models.Script, info models.RevisionInfo, toolConfigs []*models.ToolConfig, tasks []*models.ScheduledTask, replaceTasks map[string]bool) error {
+	revisions := make(map[*models.Script]int, len(scripts))
+	for _, script := range scripts {
+		revisions[script] = script.Revision
+	}
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		for _, script := range scripts {
+			if err := putScriptWithRevision(tx, script, info); err != nil {
+				return fmt.Errorf("failed to save script %s: %w", script.Name, err)
+			}
+		}
+
+		now := time.Now()
+		configs := tx.Bucket(toolConfigsBucket)
+		for _, config := range toolConfigs {
+			config.UpdatedAt = now
+			if config.CreatedAt.IsZero() {
+				config.CreatedAt = now
+			}
+			data, err := json.Marshal(config)
+			if err != nil {
+				return err
+			}
+			if err := configs.Put([]byte(config.ID), data); err != nil {
+				return fmt.Errorf("failed to save tool config %s: %w", config.ID, err)
+			}
+		}
+
+		taskBucket := tx.Bucket(scheduledTasksBucket)
+		for _, task := range tasks {
+			if replaceTasks[task.ID] && taskBucket.Get([]byte(task.ID)) == nil {
+				return fmt.Errorf("failed to update task %s: scheduled task not found", task.Name)
+			}
+			data, err := json.Marshal(task)
+			if err != nil {
+				return err
+			}
+			if err := taskBucket.Put([]byte(task.ID), data); err != nil {
+				return fmt.Errorf("failed to save task %s: %w", task.Name, err)
+			}
+		}
+		return nil
 	})
+	if err != nil {
+		// 事务已回滚，恢复调用方脚本的版本号
+		for script, revision := range revisions {
+			script.Revision = revision
+		}
+	}
+	return err
 }
 
 // GetScript 获取脚本
@@ -229,16 +414,147 @@ func (b *BoltDB) ListScripts() ([]*models.Script, error) {
 
 // UpdateScript 更新脚本
 func (b *BoltDB) UpdateScript(script *models.Script) error {
+	return b.UpdateScriptWithRevision(script, models.RevisionInfo{})
+}
+
+// UpdateScriptWithRevision 更新脚本并记录版本信息
+func (b *BoltDB) UpdateScriptWithRevision(script *models.Script, info models.RevisionInfo) error {
 	script.UpdatedAt = time.Now()
-	return b.SaveScript(script)
+	return b.SaveScriptWithRevision(script, info)
 }
 
-// DeleteScript 删除脚本
+// DeleteScript 删除脚本及其历史版本
 func (b *BoltDB) DeleteScript(id string) error {
 	return b.db.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(scriptsBucket)
-		return bucket.Delete([]byte(id))
+		if err := bucket.Delete([]byte(id)); err != nil {
+			return err
+		}
+
+		revisions := tx.Bucket(scriptRevisionsBucket)
+		prefix := scriptRevisionPrefix(id)
+		var keys [][]byte
+		c := revisions.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			keys = append(keys, append([]byte(nil), k...))
+		}
+		for _, k := range keys {
+			if err := revisions.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListScriptRevisions 列出脚本的所有版本（版本号降序，不含脚本内容）
+func (b *BoltDB) ListScriptRevisions(scriptID string) ([]*models.ScriptRevision, error) {
+	revisions := []*models.ScriptRevision{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		prefix := scriptRevisionPrefix(scriptID)
+		c := tx.Bucket(scriptRevisionsBucket).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var revision models.ScriptRevision
+			if err := json.Unmarshal(v, &revision); err != nil {
+				continue // 跳过无效数据
+			}
+			revision.Script = nil
+			revisions = append(revisions, &revision)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision > revisions[j].Revision
 	})
+	return revisions, nil
+}
+
+// GetScriptRevision 获取脚本的指定版本（含脚本内容）
+func (b *BoltDB) GetScriptRevision(scriptID string, revision int) (*models.ScriptRevision, error) {
+	var result models.ScriptRevision
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(scriptRevisionsBucket).Get(scriptRevisionKey(scriptID, revision))
+		if data == nil {
+			return fmt.Errorf("script revision not found: %s@%d", scriptID, revision)
+		}
+		return json.Unmarshal(data, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// latestScriptRevision 脚本的最新版本，没有任何版本时返回 nil
+func latestScriptRevision(bucket *bolt.Bucket, scriptID string) (*models.ScriptRevision, error) {
+	prefix := scriptRevisionPrefix(scriptID)
+	c := bucket.Cursor()
+	// 版本号定长编码，前缀范围内最后一个键即为最新版本
+	k, v := c.Seek(append(append([]byte(nil), prefix...), 0xff))
+	if k == nil {
+		k, v = c.Last()
+	} else {
+		k, v = c.Prev()
+	}
+	if k == nil || !bytes.HasPrefix(k, prefix) {
+		return nil, nil
+	}
+	var revision models.ScriptRevision
+	if err := json.Unmarshal(v, &revision); err != nil {
+		return nil, fmt.Errorf("invalid script revision %s: %w", k, err)
+	}
+	return &revision, nil
+}
+
+// putBaselineRevision 将已保存但没有版本记录、且与待保存内容不同的脚本写为版本 1
+// 脚本不存在或内容未变化时返回 nil
+func putBaselineRevision(tx *bolt.Tx, revisions *bolt.Bucket, script *models.Script) (*models.ScriptRevision, error) {
+	data := tx.Bucket(scriptsBucket).Get([]byte(script.ID))
+	if data == nil {
+		return nil, nil
+	}
+	var stored models.Script
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("invalid stored script %s: %w", script.ID, err)
+	}
+	if models.SameScriptContent(&stored, script) {
+		return nil, nil
+	}
+
+	stored.Revision = 1
+	createdAt := stored.UpdatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	baseline := &models.ScriptRevision{
+		ScriptID:    stored.ID,
+		Revision:    1,
+		Source:      models.RevisionSourceBaseline,
+		Note:        "content before revision history was enabled",
+		ActionCount: len(stored.Actions),
+		Script:      &stored,
+		CreatedAt:   createdAt,
+	}
+	data, err := json.Marshal(baseline)
+	if err != nil {
+		return nil, err
+	}
+	if err := revisions.Put(scriptRevisionKey(stored.ID, 1), data); err != nil {
+		return nil, err
+	}
+	return baseline, nil
+}
+
+func scriptRevisionPrefix(scriptID string) []byte {
+	return []byte(scriptID + "/")
+}
+
+func scriptRevisionKey(scriptID string, revision int) []byte {
+	return []byte(fmt.Sprintf("%s/%010d", scriptID, revision))
 }
 
 // ============= LLM 配置相关方法 =============
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/browserwing/browserwing/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestSaveScriptWithRevisionBaseline(t *testing.T) {
+	tests := []struct {
+		name       string
+		legacy     *models.Script // 启用版本历史前已保存、没有版本记录的脚本
+		wantURLs   []string       // 各版本内容（按版本号）
+		wantSource string         // 版本 1 的来源
+	}{
+		{
+			name:       "new script",
+			wantURLs:   []string{"https://example.com/new"},
+			wantSource: models.RevisionSourceAPI,
+		},
+		{
+			name:       "legacy script keeps original content as revision 1",
+			legacy:     &models.Script{ID: "s1", Name: "login", URL: "https://example.com/old"},
+			wantURLs:   []string{"https://example.com/old", "https://example.com/new"},
+			wantSource: models.RevisionSourceBaseline,
+		},
+		{
+			name:       "legacy script saved unchanged",
+			legacy:     &models.Script{ID: "s1", Name: "login", URL: "https://example.com/new"},
+			wantURLs:   []string{"https://example.com/new"},
+			wantSource: models.RevisionSourceAPI,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatalf("NewBoltDB: %v", err)
+			}
+			defer db.Close()
+
+			if tt.legacy != nil {
+				data, _ := json.Marshal(tt.legacy)
+				err := db.db.Update(func(tx *bolt.Tx) error {
+					return tx.Bucket(scriptsBucket).Put([]byte(tt.legacy.ID), data)
+				})
+				if err != nil {
+					t.Fatalf("write legacy script: %v", err)
+				}
+			}
+
+			script := &models.Script{ID: "s1", Name: "login", URL: "https://example.com/new"}
+			if err := db.SaveScriptWithRevision(script, models.RevisionInfo{Source: models.RevisionSourceAPI}); err != nil {
+				t.Fatalf("SaveScriptWithRevision: %v", err)
+			}
+
+			revisions, err := db.ListScriptRevisions("s1")
+			if err != nil {
+				t.Fatalf("ListScriptRevisions: %v", err)
+			}
+			if len(revisions) != len(tt.wantURLs) {
+				t.Fatalf("got %d revisions, want %d", len(revisions), len(tt.wantURLs))
+			}
+			for i, url := range tt.wantURLs {
+				rev, err := db.GetScriptRevision("s1", i+1)
+				if err != nil {
+					t.Fatalf("GetScriptRevision(%d): %v", i+1, err)
+				}
+				if rev.Script.URL != url {
+					t.Errorf("revision %d url = %q, want %q", i+1, rev.Script.URL, url)
+				}
+			}
+			if first, _ := db.GetScriptRevision("s1", 1); first.Source != tt.wantSource {
+				t.Errorf("revision 1 source = %q, want %q", first.Source, tt.wantSource)
+			}
+			if script.Revision != len(tt.wantURLs) {
+				t.Errorf("script.Revision = %d, want %d", script.Revision, len(tt.wantURLs))
+			}
+		})
+	}
+}
+
+func TestImportScriptsRollsBack(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewBoltDB: %v", err)
+	}
+	defer db.Close()
+
+	info := models.RevisionInfo{Source: models.RevisionSourceImport}
+	newImport := func() ([]*models.Script, []*models.ToolConfig, []*models.ScheduledTask) {
+		return []*models.Script{{ID: "s1", Name: "login"}, {ID: "s2", Name: "report"}},
+			[]*models.ToolConfig{{ID: "script_s1", ScriptID: "s1"}},
+			[]*models.ScheduledTask{{ID: "t1", Name: "nightly", ScriptID: "s2"}}
+	}
+
+	// 覆盖的任务不存在：整个导入回滚
+	scripts, configs, tasks := newImport()
+	if err := db.ImportScripts(scripts, info, configs, tasks, map[string]bool{"t1": true}); err == nil {
+		t.Fatal("ImportScripts should fail when a replaced task does not exist")
+	}
+	if _, err := db.GetScript("s1"); err == nil {
+		t.Error("script saved although the import failed")
+	}
+	if _, err := db.GetToolConfig("script_s1"); err == nil {
+		t.Error("tool config saved although the import failed")
+	}
+	if revisions, _ := db.ListScriptRevisions("s1"); len(revisions) != 0 || scripts[0].Revision != 0 {
+		t.Errorf("revisions = %d, script.Revision = %d after rollback", len(revisions), scripts[0].Revision)
+	}
+
+	scripts, configs, tasks = newImport()
+	if err := db.ImportScripts(scripts, info, configs, tasks, nil); err != nil {
+		t.Fatalf("ImportScripts: %v", err)
+	}
+	for _, id := range []string{"s1", "s2"} {
+		if script, err := db.GetScript(id); err != nil || script.Revision != 1 {
+			t.Errorf("GetScript(%s) = %+v, %v", id, script, err)
+		}
+	}
+	if _, err := db.GetScheduledTask("t1"); err != nil {
+		t.Errorf("GetScheduledTask: %v", err)
+	}
+}
@@ -132,6 +132,46 @@ export interface PublisherConfig {
   publish_params: Record<string, string>
 }
 
+// 页面仿真配置，所有字段可选
+export interface EmulationProfile {
+  device?: string               // 预设设备名称，如 "iPhone X"、"Pixel 2"
+  landscape?: boolean
+  width?: number
+  height?: number
+  device_scale_factor?: number
+  mobile?: boolean
+  touch?: boolean
+  user_agent?: string
+  locale?: string               // 如 de-DE，同时设置 Accept-Language
+  accept_language?: string
+  timezone?: string             // IANA 时区，如 Europe/Berlin
+  geolocation?: { latitude: number; longitude: number; accuracy?: number }
+  color_scheme?: 'light' | 'dark' | 'no-preference'
+  reduced_motion?: 'reduce' | 'no-preference'
+  network?: 'online' | 'offline' | 'slow-3g' | 'fast-3g' | '4g'
+  latency_ms?: number
+  download_kbps?: number
+  upload_kbps?: number
+}
+
+// 存储状态：Cookie 加上按源划分的 localStorage、sessionStorage 和 IndexedDB
+export interface OriginStorage {
+  origin: string  // 如 https://example.com
+  local_storage?: Array<{ name: string; value: string }>
+  session_storage?: Array<{ name: string; value: string }>
+  indexed_db?: any[]
+}
+
+export interface StorageState {
+  id: string
+  name: string
+  description?: string
+  cookies: any[]
+  origins: OriginStorage[]
+  created_at: string
+  updated_at: string
+}
+
 export interface BrowserConfig {
   id: string
   name: string
@@ -142,6 +182,7 @@ export interface BrowserConfig {
   use_stealth: boolean | null  // null表示使用默认值
   headless: boolean | null     // null表示使用默认值(false)
   launch_args: string[]
+  emulation?: EmulationProfile  // 匹配页面的仿真配置，覆盖实例配置中的同名字段
   is_default: boolean
   created_at: string
   updated_at: string
@@ -165,10 +206,22 @@ export interface BrowserInstance {
   headless?: boolean | null
   launch_args?: string[]
   proxy?: string
+  emulation?: EmulationProfile  // 实例中新打开页面的仿真配置
+  // 回放并发配置
+  max_concurrent_playbacks?: number  // 同时执行的回放上限，默认 4
+  playback_isolation?: 'page' | 'incognito'  // 每次回放使用独立页面或独立无痕上下文
   created_at: string
   updated_at: string
 }
 
+export interface PlaybackPoolStats {
+  instance_id: string
+  isolation: 'page' | 'incognito'
+  limit: number
+  running: number
+  queued: number
+}
+
 export interface GenerateRequest {
   fetcher_name: string
   fetch_params: Record<string, string>
@@ -302,6 +355,78 @@ export interface ScriptAction {
     value: string         // 比较值
     enabled?: boolean     // 是否启用条件
   }
+
+  // 失败处理策略
+  on_error?: 'continue' | 'abort' | 'retry'  // 默认 continue
+  retry_count?: number       // retry 策略的重试次数（默认 3）
+  retry_backoff_ms?: number  // 首次重试前的等待时间（毫秒，之后每次翻倍）
+  timeout_ms?: number        // 单步超时（毫秒）
+
+  // 循环配置（type 为 loop_elements, loop_list, loop_while 时使用）
+  loop?: ActionLoop
+
+  // 子脚本调用（type 为 call_script 时使用）
+  script_id?: string                       // 被调用脚本的 ID
+  script_params?: Record<string, string>  // 传入子脚本的变量，值中可以使用 ${变量}
+
+  // PDF 打印选项（type 为 pdf 时使用）
+  pdf?: PDFOptions
+
+  // 断言配置（type 为 assert 时使用，元素类断言使用 selector / xpath 定位）
+  assert?: AssertOptions
+}
+
+// 断言配置
+export interface AssertOptions {
+  kind: 'visible' | 'hidden' | 'text_equals' | 'text_contains' | 'text_matches' | 'attribute' | 'url_matches' | 'title_matches' | 'count' | 'variable'
+  expected?: string          // 期望值（文本、正则、数量或比较值），可以使用 ${变量}
+  attribute?: string         // kind 为 attribute 时的属性名
+  variable?: string          // kind 为 variable 时的变量名
+  operator?: string          // attribute、count、variable 的比较操作符（默认 =）
+  mode?: 'hard' | 'soft'     // hard（默认）失败即终止回放；soft 记录失败并继续
+  message?: string           // 自定义失败信息
+  wait_ms?: number           // 等待断言成立的最长时间（毫秒）
+}
+
+// PDF 打印选项，尺寸和边距单位为英寸
+export interface PDFOptions {
+  paper_format?: 'A3' | 'A4' | 'A5' | 'Letter' | 'Legal' | 'Tabloid'
+  paper_width?: number
+  paper_height?: number
+  landscape?: boolean
+  scale?: number             // 0.1 - 2
+  margin_top?: number
+  margin_bottom?: number
+  margin_left?: number
+  margin_right?: number
+  print_background?: boolean
+  page_ranges?: string       // 如 "1-5, 8"
+  header_template?: string
+  footer_template?: string
+  prefer_css_page_size?: boolean
+}
+
+export interface ActionLoop {
+  list_variable?: string    // loop_list: 列表变量名（数组、JSON 数组字符串或逗号分隔）
+  condition?: {             // loop_while: 继续循环的条件（不设置时按 selector/xpath 是否存在判断）
+    variable: string
+    operator: string
+    value: string
+  }
+  max_iterations?: number   // 最大迭代次数（默认 100，上限 1000）
+  item_variable?: string    // 当前元素变量名（默认 item）
+  index_variable?: string   // 当前序号变量名（默认 index，从 0 开始）
+  collect?: boolean         // 将循环体抓取的变量按迭代收集为数组
+  actions: ScriptAction[]   // 循环体
+}
+
+export interface StepFailure {
+  index: number
+  type: string
+  selector?: string
+  error: string
+  attempts: number
+  timed_out?: boolean
 }
 
 export interface Script {
@@ -322,6 +447,43 @@ export interface Script {
   mcp_command_description?: string
   mcp_input_schema?: Record<string, any>
   variables?: Record<string, string>  // 预设变量
+  variable_defs?: VariableDefinition[]  // 变量声明（设置后自动生成 mcp_input_schema）
+  route_rules?: RouteRule[]  // 请求拦截规则
+  default_on_error?: 'continue' | 'abort' | 'retry'  // 默认失败处理策略
+  default_timeout_ms?: number  // 默认单步超时（毫秒）
+  screenshot_on_failure?: boolean  // 步骤失败时截图
+  save_healed_selectors?: boolean  // 语义自愈成功后将新的 XPath 写回脚本
+  storage_state_id?: string  // 回放前恢复的存储状态
+  emulation?: EmulationProfile  // 回放时的仿真配置，覆盖实例和网站配置中的同名字段
+  revision?: number  // 当前版本号
+}
+
+export interface VariableDefinition {
+  name: string
+  type?: 'string' | 'number' | 'integer' | 'boolean'  // 默认 string
+  description?: string
+  required?: boolean
+  default?: string
+  enum?: string[]
+  pattern?: string   // 取值需要匹配的正则表达式
+  secret?: boolean   // 敏感变量，日志中不输出明文
+}
+
+export interface RouteRule {
+  id?: string
+  url_pattern: string
+  method?: string
+  resource_type?: string
+  action: 'block' | 'fulfill' | 'modify_headers' | 'delay'
+  error_reason?: string
+  status?: number
+  headers?: Record<string, string>
+  content_type?: string
+  body?: string
+  body_base64?: boolean
+  request_headers?: Record<string, string>
+  response_headers?: Record<string, string>
+  delay_ms?: number
 }
 
 export interface SaveScriptRequest {
@@ -334,6 +496,148 @@ export interface SaveScriptRequest {
   can_publish?: boolean
   can_fetch?: boolean
   variables?: Record<string, string>  // 预设变量
+  change_note?: string  // 本次修改说明（记录到版本历史）
+}
+
+export interface ScriptRevision {
+  script_id: string
+  revision: number
+  author?: string
+  note?: string
+  source?: 'api' | 'batch' | 'mcp' | 'self_heal' | 'restore' | 'explore' | 'import' | 'baseline'
+  action_count: number
+  script?: Script  // 列表接口中省略
+  created_at: string
+}
+
+export interface ScriptActionChange {
+  change: 'added' | 'removed' | 'modified' | 'unchanged'
+  type: string
+  from_index?: number
+  to_index?: number
+  fields?: string[]
+  before?: ScriptAction
+  after?: ScriptAction
+}
+
+export interface ScriptDiff {
+  from_revision: number
+  to_revision: number
+  fields: { field: string; before: any; after: any }[]
+  actions: ScriptActionChange[]
+  added: number
+  removed: number
+  modified: number
+  unchanged: number
+}
+
+export interface ScriptImportIssue {
+  script?: string  // 所属脚本名称（Selenium IDE 中为测试名称）
+  step: number     // 步骤在原文件中的序号（从 1 开始）
+  type: string     // 原步骤类型或命令
+  reason: string
+}
+
+export interface ImportScriptsRequest {
+  format?: 'chrome_recorder' | 'selenium_ide'  // 为空时自动识别
+  content: any  // 文件内容（JSON 对象或字符串）
+  group?: string
+  tags?: string[]
+  dry_run?: boolean  // 只返回转换结果，不保存
+}
+
+export type CodeExportTarget = 'playwright-ts' | 'playwright-python' | 'puppeteer' | 'go-rod'
+
+export interface CodeExport {
+  target: CodeExportTarget
+  file_name: string
+  content: string
+  todos: number  // 无法自动转换、需要手动补充的步骤数
+}
+
+export type BundleConflictStrategy = 'rename' | 'skip' | 'overwrite'
+
+export interface BundleConflict {
+  kind: 'script' | 'mcp_command' | 'task'
+  name: string
+  existing_id?: string
+  resolution: 'renamed' | 'skipped' | 'overwritten' | 'mcp_disabled'
+  new_name?: string
+}
+
+export interface ImportScriptBundleOptions {
+  on_conflict?: BundleConflictStrategy  // 默认 rename
+  enable_tasks?: boolean  // 定时任务默认以禁用状态导入
+  group?: string
+  dry_run?: boolean  // 只返回导入计划，不保存
+}
+
+export interface ImportScriptBundleResult {
+  message: string
+  manifest: Record<string, any>
+  scripts: Script[]
+  tool_configs: any[]
+  scheduled_tasks: any[]
+  id_map: Record<string, string>  // 包内脚本 ID -> 导入后的脚本 ID
+  conflicts: BundleConflict[]
+  unresolved_references: { script: string; script_id: string }[]  // call_script 引用的脚本既不在包中也不在当前安装中
+  saved: boolean
+}
+
+export type DebugCommandName = 'continue' | 'step' | 'skip' | 'retry' | 'abort' | 'breakpoints'
+
+export interface DebugCommand {
+  command: DebugCommandName
+  action?: ScriptAction  // retry 时替换当前步骤
+  breakpoints?: number[]  // breakpoints 命令的新断点（步骤序号，从 1 开始）
+}
+
+export interface DebugElement {
+  found: boolean
+  matched_by?: string
+  locator?: string
+  tag?: string
+  text?: string
+  html?: string
+  visible: boolean
+  box?: { x: number; y: number; width: number; height: number }
+  error?: string
+}
+
+export interface DebugEvent {
+  seq: number
+  type: 'paused' | 'step_finished' | 'finished'
+  step?: number
+  total?: number
+  reason?: 'breakpoint' | 'step' | 'failure'
+  status?: string  // 步骤结果（success / failed / skipped）或会话最终状态（success / failed / aborted）
+  action?: ScriptAction
+  variables?: Record<string, string>
+  extracted_data?: Record<string, any>
+  element?: DebugElement
+  error?: string
+  result?: PlayResult
+  time: string
+}
+
+export interface DebugSessionInfo {
+  id: string
+  script_id: string
+  state: 'running' | 'paused' | 'finished'
+  breakpoints: number[]
+  paused?: DebugEvent
+  edits: { step: number; action: ScriptAction }[]  // 调试中修改过的步骤，可写回脚本
+  start_time: string
+  end_time?: string
+  last_event?: DebugEvent
+  result?: PlayResult
+}
+
+export interface StartScriptDebugOptions {
+  params?: Record<string, string>
+  instance_id?: string
+  breakpoints?: number[]
+  step?: boolean  // 单步模式：第一步前即暂停
 }
 
 export interface PlayResult {
@@ -341,12 +645,14 @@ export interface PlayResult {
   message: string
   extracted_data?: Record<string, any>
   errors?: string[]
+  aborted_step?: StepFailure
 }
 
 export interface ScriptExecution {
   id: string
   script_id: string
   script_name: string
+  script_revision?: number  // 执行时的脚本版本
   start_time: string
   end_time: string
   duration: number
@@ -356,11 +662,32 @@ export interface ScriptExecution {
   total_steps: number
   success_steps: number
   failed_steps: number
+  aborted_step?: StepFailure
+  steps?: StepTrace[]  // 步骤执行轨迹
   extracted_data?: Record<string, any>
   video_path?: string  // 录制视频路径
   created_at: string
 }
 
+export interface StepTrace {
+  index: number
+  type: string
+  remark?: string
+  status: 'success' | 'failed' | 'skipped'
+  matched_by?: string       // xpath, css, css_fallback, iframe_xpath, iframe_css, semantic
+  matched_locator?: string
+  match_confidence?: number // 语义自愈的匹配置信度（0-1）
+  attempts?: number
+  iterations?: number       // 循环步骤的迭代次数
+  start_time: string
+  end_time: string
+  duration: number
+  error?: string
+  url_before?: string
+  url_after?: string
+  screenshot_path?: string  // 通过 /script-executions/:id/steps/:index/screenshot 获取
+}
+
 export interface RecordingConfig {
   id: string
   enabled: boolean
@@ -614,6 +941,22 @@ export const api = {
   batchDeleteCookies: (data: { id: string; cookies: Array<{ name: string; domain: string; path: string }> }) =>
     client.post<{ message: string; deleted_count: number; remaining: number }>('/browser/cookies/batch/delete', data),
 
+  // 存储状态管理
+  listStorageStates: () =>
+    client.get<{ storage_states: StorageState[] }>('/storage-states'),
+
+  getStorageState: (id: string) =>
+    client.get<StorageState>(`/storage-states/${id}`),
+
+  captureStorageState: (data: { id?: string; name: string; description?: string; instance_id?: string; origins?: string[]; include_indexed_db?: boolean }) =>
+    client.post<{ message: string; storage_state: StorageState }>('/storage-states/capture', data),
+
+  importStorageState: (data: Partial<StorageState>) =>
+    client.post<{ message: string; storage_state: StorageState }>('/storage-states', data),
+
+  deleteStorageState: (id: string) =>
+    client.delete<{ message: string }>(`/storage-states/${id}`),
+
   // 浏览器实例管理
   createBrowserInstance: (data: Partial<BrowserInstance>) =>
     client.post<{ message: string; instance: BrowserInstance }>('/browser/instances', data),
@@ -642,6 +985,9 @@ export const api = {
   getCurrentBrowserInstance: () =>
     client.get<{ instance: BrowserInstance }>('/browser/instances/current'),
 
+  getPlaybackStats: () =>
+    client.get<{ playbacks: PlaybackPoolStats[] }>('/browser/playbacks'),
+
   // 录制相关
   startRecording: (instanceId?: string) =>
     client.post<{ message: string }>('/browser/record/start', { instance_id: instanceId }),
@@ -674,6 +1020,22 @@ export const api = {
   deleteScript: (id: string) =>
     client.delete<{ message: string }>(`/scripts/${id}`),
 
+  importScripts: (data: ImportScriptsRequest) =>
+    client.post<{ message: string; format: string; scripts: Script[]; unsupported: ScriptImportIssue[]; saved: boolean }>('/scripts/import', data),
+
+  // 脚本版本历史
+  getScriptRevisions: (id: string) =>
+    client.get<{ revisions: ScriptRevision[]; current_revision: number }>(`/scripts/${id}/revisions`),
+
+  getScriptRevision: (id: string, revision: number) =>
+    client.get<ScriptRevision>(`/scripts/${id}/revisions/${revision}`),
+
+  diffScriptRevisions: (id: string, params?: { from?: number; to?: number }) =>
+    client.get<ScriptDiff>(`/scripts/${id}/diff`, { params }),
+
+  restoreScriptRevision: (id: string, revision: number, note?: string) =>
+    client.post<{ message: string; script: Script }>(`/scripts/${id}/revisions/${revision}/restore`, { note }),
+
   playScript: (id: string, params?: Record<string, string>, instanceId?: string) =>
     client.post<{ message: string; script: string; result: PlayResult }>(`/scripts/${id}/play`, { 
       params,
@@ -694,6 +1056,38 @@ export const api = {
   exportScriptsSkill: (scriptIds?: string[]) =>
     client.post('/scripts/export/skill', { script_ids: scriptIds || [] }, { responseType: 'blob' }),
 
+  // 导出为 Playwright / Puppeteer / go-rod 代码
+  exportScriptCode: (id: string, target: CodeExportTarget) =>
+    client.get(`/scripts/${id}/export/code`, { params: { target }, responseType: 'blob' }),
+
+  previewScriptCode: (id: string, target: CodeExportTarget) =>
+    client.get<CodeExport>(`/scripts/${id}/export/code`, { params: { target, format: 'json' } }),
+
+  // 脚本包（zip，包含引用文件、工具配置、MCP 设置和定时任务）
+  exportScriptBundle: (scriptIds: string[]) =>
+    client.post('/scripts/bundle/export', { script_ids: scriptIds }, { responseType: 'blob' }),
+
+  importScriptBundle: (file: File | Blob, options?: ImportScriptBundleOptions) => {
+    const formData = new FormData()
+    formData.append('file', file)
+    return client.post<ImportScriptBundleResult>('/scripts/bundle/import', formData, {
+      params: options,
+      headers: { 'Content-Type': 'multipart/form-data' },
+    })
+  },
+
+  // 调试回放（事件通过 SSE 推送，使用 scriptDebugEventsUrl 连接）
+  startScriptDebug: (id: string, options?: StartScriptDebugOptions) =>
+    client.post<{ message: string; session: DebugSessionInfo }>(`/scripts/${id}/debug`, options || {}),
+
+  getScriptDebugSession: (sessionId: string) =>
+    client.get<DebugSessionInfo>(`/scripts/debug/${sessionId}`),
+
+  sendScriptDebugCommand: (sessionId: string, command: DebugCommand) =>
+    client.post<{ message: string; session: DebugSessionInfo }>(`/scripts/debug/${sessionId}/command`, command),
+
+  scriptDebugEventsUrl: (sessionId: string) => `${API_BASE_URL}/scripts/debug/${sessionId}/events`,
+
   // AI 提取相关
   generateExtractionJS: (data: { html: string; description?: string }) =>
     client.post<{ javascript: string; used_model: string; message: string }>('/browser/generate-extraction-js', data),
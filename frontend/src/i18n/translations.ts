@@ -53,11 +53,20 @@ export const translations = {
     'error.browserNotRunning': '浏览器未运行',
     'error.stopBrowserFailed': '停止浏览器失败',
     'error.invalidParams': '无效的请求参数',
+    'error.invalidScriptParams': '脚本参数校验失败',
     'error.openPageFailed': '打开页面失败',
     'error.getCookiesFailed': '获取Cookie失败',
     'error.saveCookiesFailed': '保存Cookie失败',
     'error.noValidCookies': '没有有效的Cookie可以解析',
     'error.scriptNotFound': '脚本未找到',
+    'error.scriptRevisionNotFound': '脚本版本未找到',
+    'error.importScriptsFailed': '导入脚本失败',
+    'error.exportScriptCodeFailed': '导出代码失败',
+    'error.exportBundleFailed': '导出脚本包失败',
+    'error.debugSessionNotFound': '调试会话不存在或已过期',
+    'error.debugCommandFailed': '调试命令发送失败',
+    'error.importBundleFailed': '导入脚本包失败',
+    'error.listScriptRevisionsFailed': '获取脚本版本历史失败',
     'error.updateScriptFailed': '更新脚本失败',
     'error.playScriptFailed': '脚本播放失败',
     'error.getLLMConfigsFailed': '获取LLM配置失败',
@@ -90,6 +99,7 @@ export const translations = {
     'error.deletePromptFailed': '删除提示词失败',
     'error.getExecutionRecordsFailed': '获取执行记录失败',
     'error.executionRecordNotFound': '执行记录未找到',
+    'error.screenshotNotFound': '步骤截图未找到',
     'error.deleteExecutionRecordFailed': '删除执行记录失败',
     'error.selectExecutionRecords': '请选择要删除的执行记录',
     'error.taskNameRequired': '任务名称不能为空',
@@ -145,6 +155,11 @@ export const translations = {
     'success.cookiesLoaded': 'Cookie已加载',
     'success.cookiesImported': 'Cookie已导入',
     'success.scriptUpdated': '脚本已更新',
+    'success.scriptRestored': '脚本已回滚',
+    'success.scriptsImported': '脚本已导入',
+    'success.bundleImported': '脚本包已导入',
+    'success.debugSessionStarted': '调试回放已开始',
+    'success.debugCommandSent': '调试命令已发送',
     'success.scriptDeleted': '脚本已删除',
     'success.scriptPlaybackCompleted': '脚本播放完成',
     'success.llmConfigCreated': 'LLM配置已创建',
@@ -1293,11 +1308,20 @@ export const translations = {
     'error.browserNotRunning': '瀏覽器未執行',
     'error.stopBrowserFailed': '停止瀏覽器失敗',
     'error.invalidParams': '無效的請求參數',
+    'error.invalidScriptParams': '腳本參數校驗失敗',
     'error.openPageFailed': '開啟頁面失敗',
     'error.getCookiesFailed': '取得Cookie失敗',
     'error.saveCookiesFailed': '儲存Cookie失敗',
     'error.noValidCookies': '沒有有效的Cookie可以解析',
     'error.scriptNotFound': '腳本未找到',
+    'error.scriptRevisionNotFound': '腳本版本未找到',
+    'error.importScriptsFailed': '導入腳本失敗',
+    'error.exportScriptCodeFailed': '導出代碼失敗',
+    'error.exportBundleFailed': '導出腳本包失敗',
+    'error.debugSessionNotFound': '調試會話不存在或已過期',
+    'error.debugCommandFailed': '調試命令發送失敗',
+    'error.importBundleFailed': '導入腳本包失敗',
+    'error.listScriptRevisionsFailed': '獲取腳本版本歷史失敗',
     'error.updateScriptFailed': '更新腳本失敗',
     'error.playScriptFailed': '腳本播放失敗',
     'error.getLLMConfigsFailed': '取得LLM設定失敗',
@@ -1325,6 +1349,7 @@ export const translations = {
     'error.deletePromptFailed': '刪除提示詞失敗',
     'error.getExecutionRecordsFailed': '取得執行記錄失敗',
     'error.executionRecordNotFound': '執行記錄未找到',
+    'error.screenshotNotFound': '步驟截圖未找到',
     'error.deleteExecutionRecordFailed': '刪除執行記錄失敗',
     'error.selectExecutionRecords': '請選擇要刪除的執行記錄',
     'error.taskNameRequired': '任務名稱不能為空',
@@ -1380,6 +1405,11 @@ export const translations = {
     'success.cookiesLoaded': 'Cookie已載入',
     'success.cookiesImported': 'Cookie已匯入',
     'success.scriptUpdated': '腳本已更新',
+    'success.scriptRestored': '腳本已回滾',
+    'success.scriptsImported': '腳本已導入',
+    'success.bundleImported': '腳本包已導入',
+    'success.debugSessionStarted': '調試回放已開始',
+    'success.debugCommandSent': '調試命令已發送',
     'success.scriptDeleted': '腳本已刪除',
     'success.scriptPlaybackCompleted': '腳本播放完成',
     'success.llmConfigCreated': 'LLM設定已建立',
@@ -2559,11 +2589,20 @@ export const translations = {
     'error.browserNotRunning': 'Browser is not running',
     'error.stopBrowserFailed': 'Failed to stop browser',
     'error.invalidParams': 'Invalid request parameters',
+    'error.invalidScriptParams': 'Script parameters are invalid',
     'error.openPageFailed': 'Failed to open page',
     'error.getCookiesFailed': 'Failed to get cookies',
     'error.saveCookiesFailed': 'Failed to save cookies',
     'error.noValidCookies': 'No valid cookies to parse',
     'error.scriptNotFound': 'Script not found',
+    'error.scriptRevisionNotFound': 'Script revision not found',
+    'error.importScriptsFailed': 'Failed to import scripts',
+    'error.exportScriptCodeFailed': 'Failed to export script code',
+    'error.exportBundleFailed': 'Failed to export script bundle',
+    'error.debugSessionNotFound': 'Debug session not found or expired',
+    'error.debugCommandFailed': 'Failed to send debug command',
+    'error.importBundleFailed': 'Failed to import script bundle',
+    'error.listScriptRevisionsFailed': 'Failed to list script revisions',
     'error.updateScriptFailed': 'Failed to update script',
     'error.playScriptFailed': 'Failed to play script',
     'error.getLLMConfigsFailed': 'Failed to get LLM configs',
@@ -2596,6 +2635,7 @@ export const translations = {
     'error.deletePromptFailed': 'Failed to delete prompt',
     'error.getExecutionRecordsFailed': 'Failed to get execution records',
     'error.executionRecordNotFound': 'Execution record not found',
+    'error.screenshotNotFound': 'Step screenshot not found',
     'error.deleteExecutionRecordFailed': 'Failed to delete execution record',
     'error.selectExecutionRecords': 'Please select execution records to delete',
     'error.taskNameRequired': 'Task name is required',
@@ -2651,6 +2691,11 @@ export const translations = {
     'success.cookiesLoaded': 'Cookies loaded',
     'success.cookiesImported': 'Cookies imported',
     'success.scriptUpdated': 'Script updated',
+    'success.scriptRestored': 'Script restored',
+    'success.scriptsImported': 'Scripts imported',
+    'success.bundleImported': 'Script bundle imported',
+    'success.debugSessionStarted': 'Debug playback started',
+    'success.debugCommandSent': 'Debug command sent',
     'success.scriptDeleted': 'Script deleted',
     'success.scriptPlaybackCompleted': 'Script playback completed',
     'success.llmConfigCreated': 'LLM config created',
@@ -3801,11 +3846,20 @@ export const translations = {
     'error.browserNotRunning': 'El navegador no está en ejecución',
     'error.stopBrowserFailed': 'Error al detener el navegador',
     'error.invalidParams': 'Parámetros de solicitud no válidos',
+    'error.invalidScriptParams': 'Los parámetros del script no son válidos',
     'error.openPageFailed': 'Error al abrir la página',
     'error.getCookiesFailed': 'Error al obtener cookies',
     'error.saveCookiesFailed': 'Error al guardar cookies',
     'error.noValidCookies': 'No hay cookies válidas para analizar',
     'error.scriptNotFound': 'Script no encontrado',
+    'error.scriptRevisionNotFound': 'Revisión del script no encontrada',
+    'error.importScriptsFailed': 'Error al importar los scripts',
+    'error.exportScriptCodeFailed': 'Error al exportar el código del script',
+    'error.exportBundleFailed': 'Error al exportar el paquete de scripts',
+    'error.debugSessionNotFound': 'Sesión de depuración no encontrada o caducada',
+    'error.debugCommandFailed': 'Error al enviar el comando de depuración',
+    'error.importBundleFailed': 'Error al importar el paquete de scripts',
+    'error.listScriptRevisionsFailed': 'Error al listar las revisiones del script',
     'error.updateScriptFailed': 'Error al actualizar el script',
     'error.playScriptFailed': 'Error al reproducir el script',
     'error.getLLMConfigsFailed': 'Error al obtener configuraciones LLM',
@@ -3833,6 +3887,7 @@ export const translations = {
     'error.deletePromptFailed': 'Error al eliminar el prompt',
     'error.getExecutionRecordsFailed': 'Error al obtener registros de ejecución',
     'error.executionRecordNotFound': 'Registro de ejecución no encontrado',
+    'error.screenshotNotFound': 'Captura del paso no encontrada',
     'error.deleteExecutionRecordFailed': 'Error al eliminar el registro de ejecución',
     'error.selectExecutionRecords': 'Por favor, seleccione los registros de ejecución para eliminar',
     'error.taskNameRequired': 'El nombre de la tarea es obligatorio',
@@ -3888,6 +3943,11 @@ export const translations = {
     'success.cookiesLoaded': 'Cookies cargadas',
     'success.cookiesImported': 'Cookies importadas',
     'success.scriptUpdated': 'Script actualizado',
+    'success.scriptRestored': 'Script restaurado',
+    'success.scriptsImported': 'Scripts importados',
+    'success.bundleImported': 'Paquete de scripts importado',
+    'success.debugSessionStarted': 'Reproducción de depuración iniciada',
+    'success.debugCommandSent': 'Comando de depuración enviado',
     'success.scriptDeleted': 'Script eliminado',
     'success.scriptPlaybackCompleted': 'Reproducción de script completada',
     'success.llmConfigCreated': 'Configuración LLM creada',
@@ -5059,11 +5119,20 @@ export const translations = {
     'error.browserNotRunning': 'ブラウザは実行されていません',
     'error.stopBrowserFailed': 'ブラウザの停止に失敗しました',
     'error.invalidParams': '無効なリクエストパラメータ',
+    'error.invalidScriptParams': 'スクリプトのパラメータが無効です',
     'error.openPageFailed': 'ページの開きに失敗しました',
     'error.getCookiesFailed': 'Cookieの取得に失敗しました',
     'error.saveCookiesFailed': 'Cookieの保存に失敗しました',
     'error.noValidCookies': '解析できる有効なCookieがありません',
     'error.scriptNotFound': 'スクリプトが見つかりません',
+    'error.scriptRevisionNotFound': 'スクリプトのリビジョンが見つかりません',
+    'error.importScriptsFailed': 'スクリプトのインポートに失敗しました',
+    'error.exportScriptCodeFailed': 'スクリプトのコード出力に失敗しました',
+    'error.exportBundleFailed': 'スクリプトバンドルのエクスポートに失敗しました',
+    'error.debugSessionNotFound': 'デバッグセッションが見つからないか期限切れです',
+    'error.debugCommandFailed': 'デバッグコマンドの送信に失敗しました',
+    'error.importBundleFailed': 'スクリプトバンドルのインポートに失敗しました',
+    'error.listScriptRevisionsFailed': 'スクリプトのリビジョン一覧の取得に失敗しました',
     'error.updateScriptFailed': 'スクリプトの更新に失敗しました',
     'error.playScriptFailed': 'スクリプトの再生に失敗しました',
     'error.getLLMConfigsFailed': 'LLM設定の取得に失敗しました',
@@ -5091,6 +5160,7 @@ export const translations = {
     'error.deletePromptFailed': 'プロンプトの削除に失敗しました',
     'error.getExecutionRecordsFailed': '実行記録の取得に失敗しました',
     'error.executionRecordNotFound': '実行記録が見つかりません',
+    'error.screenshotNotFound': 'ステップのスクリーンショットが見つかりません',
     'error.deleteExecutionRecordFailed': '実行記録の削除に失敗しました',
     'error.selectExecutionRecords': '削除する実行記録を選択してください',
     'error.taskNameRequired': 'タスク名は必須です',
@@ -5146,6 +5216,11 @@ export const translations = {
     'success.cookiesLoaded': 'Cookieが読み込まれました',
     'success.cookiesImported': 'Cookieがインポートされました',
     'success.scriptUpdated': 'スクリプトが更新されました',
+    'success.scriptRestored': 'スクリプトを復元しました',
+    'success.scriptsImported': 'スクリプトをインポートしました',
+    'success.bundleImported': 'スクリプトバンドルをインポートしました',
+    'success.debugSessionStarted': 'デバッグ再生を開始しました',
+    'success.debugCommandSent': 'デバッグコマンドを送信しました',
     'success.scriptDeleted': 'スクリプトが削除されました',
     'success.scriptPlaybackCompleted': 'スクリプトの再生が完了しました',
     'success.llmConfigCreated': 'LLM設定が作成されました',